@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MinAPIKeyLength is the shortest API key the agent accepts
+const MinAPIKeyLength = 32
+
+// APIKeyCredential is the systemd credential holding the API key
+// (LoadCredential=api-key:/path in the unit)
+const APIKeyCredential = "api-key"
+
+// Where the API key came from
+const (
+	APIKeySourceEnv        = "env"
+	APIKeySourceFile       = "file"
+	APIKeySourceCredential = "credential"
+)
+
+// loadAPIKey fills in the API key when API_KEY is unset, from APIKeyFile
+// and then the systemd credential. A missing APIKeyFile is an error; a
+// missing credential is not.
+func (c *Config) loadAPIKey() error {
+	if c.APIKey != "" {
+		c.APIKeySource = APIKeySourceEnv
+		return nil
+	}
+
+	if c.APIKeyFile != "" {
+		key, err := readAPIKey(c.APIKeyFile)
+		if err != nil {
+			return fmt.Errorf("API_KEY_FILE: %w", err)
+		}
+		c.APIKey, c.APIKeySource = key, APIKeySourceFile
+		return nil
+	}
+
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		key, err := readAPIKey(filepath.Join(dir, APIKeyCredential))
+		if err == nil {
+			c.APIKey, c.APIKeySource = key, APIKeySourceCredential
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("credential %s: %w", APIKeyCredential, err)
+		}
+	}
+	return nil
+}
+
+func readAPIKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if err := ValidateAPIKey(key); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ValidateAPIKey checks that key is long enough and fits on one line
+func ValidateAPIKey(key string) error {
+	if len(key) < MinAPIKeyLength {
+		return fmt.Errorf("API key must be at least %d characters", MinAPIKeyLength)
+	}
+	if strings.ContainsAny(key, "\r\n") {
+		return fmt.Errorf("API key must be a single line")
+	}
+	return nil
+}
+
+// Bootstrap configures the API key without the setup page, saving key, or
+// a generated one when key is empty, to the .env file. An agent that
+// already has a key keeps it unless force is set. It returns the key in
+// effect and whether it was saved.
+func (c *Config) Bootstrap(key string, force bool) (string, bool, error) {
+	if c.APIKey != "" && !force {
+		return c.APIKey, false, nil
+	}
+
+	if key == "" {
+		generated, err := GenerateAPIKey()
+		if err != nil {
+			return "", false, err
+		}
+		key = generated
+	}
+	if err := ValidateAPIKey(key); err != nil {
+		return "", false, err
+	}
+
+	if err := c.SaveAPIKey(key); err != nil {
+		return "", false, fmt.Errorf("failed to save API key to %s: %w", c.EnvFile, err)
+	}
+	c.APIKeySource = APIKeySourceEnv
+	return key, true, nil
+}
@@ -28,25 +28,252 @@ type Config struct {
 	Host         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	Listeners    []Listener // Replaces Host:Port when set
+
+	// Protocol and connection tuning
+	TLSCertFile       string // Serve HTTPS when both files are set
+	TLSKeyFile        string
+	HTTP2             bool          // Offer HTTP/2 over TLS
+	H2C               bool          // Accept unencrypted HTTP/2, for trusted proxies
+	HTTP2MaxStreams   int           // Concurrent streams per connection, 0 for the default
+	HTTP2PingInterval time.Duration // Ping idle HTTP/2 connections, 0 disables
+	IdleTimeout       time.Duration // Keep-alive connections are closed after this long idle
+	ReadHeaderTimeout time.Duration
+	TCPKeepAlive      time.Duration // TCP keep-alive probe interval, negative disables
+
+	// Labels describe the host to fleet dashboards, e.g. role=nas
+	Labels map[string]string
+
+	// CloudMetadata queries the cloud provider's metadata service, when
+	// DMI names one, for /api/info
+	CloudMetadata bool
 
 	// Authentication
-	APIKey    string
-	JWTSecret string
+	APIKey       string
+	APIKeyFile   string // Read the key from this file when API_KEY is unset
+	APIKeySource string // env, file or credential
+	JWTSecret    string
+	JWTLeeway    time.Duration // Clock skew tolerated on JWT exp, iat and nbf
 
 	// Security
 	AllowedOrigins []string
+	CORSRoutes     []CORSRoute   // Override AllowedOrigins below a path
+	CORSMaxAge     time.Duration // How long browsers cache a preflight; 0 omits Access-Control-Max-Age
 	RateLimitRPS   int
 
+	// Privilege separation: service and power actions run through
+	// "hivedeck-agent helper" under sudo so the agent can run unprivileged
+	PrivilegeHelper bool
+	HelperSudo      string
+
+	// Self-sandboxing with landlock and seccomp once the agent is running
+	SandboxEnabled    bool
+	SandboxReadPaths  []string // Readable in addition to the defaults
+	SandboxWritePaths []string // Writable in addition to the defaults
+
 	// Features
-	DockerEnabled bool
+	DockerEnabled        bool
+	DockerHost           string        // Daemon URL or socket path; empty finds Docker or Podman
+	RegistryAuth         string        // registry=username:password pairs; a string so it can be encrypted
+	DockerCheckInterval  time.Duration // How often to check that the daemon is up
+	DockerUpdateInterval time.Duration // How often to check for image updates; 0 on request only
+	DockerAutoUpdate     []string      // Containers the docker-update maintenance step updates, besides labelled ones
+	DockerHealthTimeout  time.Duration // How long an updated container has to become healthy
+	ServiceActionTimeout time.Duration // How long service actions wait for systemd
+	FilesDeleteEnabled   bool
+	DeployEnabled        bool          // POST /api/deploy applies compose files
+	DockerCreateEnabled  bool          // POST /api/docker/containers creates containers
+	DeployTimeout        time.Duration // How long pull and up may take together
+
+	// Modules (disabled modules are rejected at the routing level)
+	FilesEnabled     bool
+	TasksEnabled     bool
+	ProcessesEnabled bool
+	ServicesEnabled  bool
+	LogsEnabled      bool
+
+	// Storage
+	DataDir      string
+	StoragePrune time.Duration // How often to prune DATA_DIR; 0 on request only
+	StorageLimit int64         // Bytes DATA_DIR may use before a warning event; 0 for no limit
+
+	// Speed test
+	SpeedtestBackend string
+	SpeedtestServer  string
+
+	// Approvals queue for destructive actions
+	ApprovalsEnabled bool
+	ApprovalTTL      time.Duration
+
+	// Trash retention for deleted files
+	TrashRetention time.Duration
+
+	// Restic/borg repositories shown read-only under /api/backups
+	BackupRepos         map[string]string // name=restic:<repo> or name=borg:<repo>
+	BackupPasswordFiles map[string]string // name=path
+	BackupCacheTTL      time.Duration
+
+	// Git checkouts shown under /api/git
+	GitRepos       map[string]string // name=path
+	GitPullAllowed []string          // Names POST /api/git/:name/pull may update, or *
+
+	// Database health checks: name=URL pairs (postgres://, mysql://,
+	// redis://). Kept as one string so it can be stored encrypted.
+	DatabaseURLs string
+
+	// DNS servers (Pi-hole, AdGuard Home, unbound): name=type:address
+	// pairs. One string so it can be stored encrypted.
+	DNSServers string
+
+	// Reverse-proxy upstream health checks
+	Upstreams        map[string]string // name=URL
+	UpstreamInterval time.Duration
+	UpstreamTimeout  time.Duration
+	UpstreamInsecure bool // Skip TLS verification
+
+	// Auto-maintenance windows
+	MaintenanceEnabled  bool
+	MaintenanceSchedule string        // e.g. "sun 03:30" or "daily 02:00"
+	MaintenanceWindow   time.Duration // Steps not started by the end are skipped
+	MaintenanceSteps    []string      // apt-upgrade, docker-prune, docker-update, journal-vacuum
+	MaintenanceReboot   bool          // Reboot afterwards if an upgrade requires it
+	JournalVacuumSize   string        // journalctl --vacuum-size argument
+
+	// Temperature alerts and fan hooks (°C)
+	ThermalEnabled    bool
+	ThermalWarning    int
+	ThermalCritical   int // 0 disables the critical level
+	ThermalHysteresis int
+	ThermalInterval   time.Duration
+	ThermalSensors    []string // Sensor key substrings; empty watches all
+	ThermalHotTask    string   // Task run when the warning level is reached
+	ThermalCoolTask   string   // Task run when back to normal
+
+	// POST /api/system/cpu/governor switches the CPU governor
+	CPUGovernorControl bool
+
+	// Follow the kernel log for OOM kills
+	OOMWatch bool
+
+	// Record host shells, su and sudo in the audit log
+	HostAudit         bool
+	HostAuditInterval time.Duration // /proc scan interval
+
+	// Zombie process alerts
+	ZombieThreshold int           // Total zombies; 0 disables
+	ZombieMaxAge    time.Duration // Oldest unreaped zombie; 0 disables
+	ZombieInterval  time.Duration
+
+	// Power metrics: estimates for devices without sensors, as name=watts
+	// or name=idle-max (scaled with CPU usage)
+	PowerEstimates map[string]string
+	PowerInterval  time.Duration
+
+	// Named GPIO inputs: name=pin, or name=pin:low when active low
+	GPIOInputs map[string]string
+
+	// MQTT publishing of metrics and events
+	MQTTBroker          string
+	MQTTUsername        string
+	MQTTPassword        string
+	MQTTClientID        string
+	MQTTTopicPrefix     string
+	MQTTInterval        time.Duration
+	MQTTDiscovery       bool
+	MQTTDiscoveryPrefix string
+
+	// Log forwarding to syslog or Loki
+	LogForwardURL       string
+	LogForwardUsername  string
+	LogForwardPassword  string
+	LogForwardUnits     []string
+	LogForwardFiles     []string
+	LogForwardLabels    map[string]string
+	LogForwardBatchSize int
+	LogForwardFlush     time.Duration
+
+	// Dead-man-switch heartbeat
+	HeartbeatURL      string
+	HeartbeatInterval time.Duration
+
+	// Pull mode: poll the dashboard for a signed command queue
+	PullURL      string
+	PullSecret   string
+	PullInterval time.Duration
+	PullAllowed  []string
+
+	// Inbound webhooks mapped to deploy and task actions
+	Webhooks       map[string]string // name=deploy:<compose file> or name=task:<task>
+	WebhookSecrets string            // name=secret pairs; a string so it can be encrypted
+
+	// Alert rules (name=metric[:target][op threshold][@duration]) and
+	// notification sinks (name=type:address, one string so it can be
+	// encrypted). Rules can also be added through /api/alerts.
+	AlertRules    map[string]string
+	AlertSinks    string
+	AlertInterval time.Duration
+
+	// Metrics cache. CacheTTLs overrides CacheTTL for single keys such
+	// as metrics:disk.
+	CacheTTL  time.Duration
+	CacheTTLs map[string]time.Duration
+
+	// Prometheus exporter, served outside /api
+	PrometheusEnabled bool
+	PrometheusPath    string
+	PrometheusAuth    bool // Require the API key or a token to scrape
+
+	// File integrity monitoring
+	IntegrityPaths    []string
+	IntegrityInterval time.Duration
+
+	// Files and directories hashed into the host profile
+	ProfileFiles []string
+
+	// Listening port exposure scans; 0 only scans on request
+	ExposureInterval time.Duration
+
+	// Scheduled vulnerability scans; 0 only scans on request
+	VulnScanInterval time.Duration
+
+	// Request limits. Route groups are named by the first path segment
+	// after /api/ (metrics, logs, tasks, ...).
+	RequestTimeout  time.Duration            // Default for groups not in RouteTimeouts
+	RouteTimeouts   map[string]time.Duration // Zero means no timeout
+	MaxBodyBytes    int64                    // Default for groups not in RouteBodyLimits
+	RouteBodyLimits map[string]int64
+	MaxRequests     int // Concurrent API requests, 0 for no limit
+	MaxStreams      int // Concurrent SSE and WebSocket streams, 0 for no limit
+
+	// Security headers. ContentSecurityPolicy applies to the HTML pages;
+	// API responses always get a deny-all policy.
+	SecurityHeaders       bool
+	HSTSMaxAge            int // Seconds; sent only over HTTPS, 0 disables
+	FrameOptions          string
+	ReferrerPolicy        string
+	ContentSecurityPolicy string
 
 	// Logging
 	LogLevel string
 
+	// Crash reporting
+	SentryDSN       string
+	CrashWebhookURL string
+
+	// Encryption of secrets in .env (see SecretKeys)
+	EncryptSecrets bool
+	SecretsKeyFile string
+
+	// Build information, set by main
+	Version   string
+	BuildTime string
+
 	// Allowed operations
-	AllowedServices []string
-	AllowedTasks    map[string]Task
-	AllowedPaths    []string
+	AllowedServices  []string
+	AllowedTasks     map[string]Task
+	AllowedPaths     []string
+	AllowedProcesses []string // Process names that may be killed or limited
+	RedactKeywords   []string // Added to the words that mark command-line values as secret
 
 	// Setup mode
 	SetupMode bool
@@ -106,12 +333,6 @@ func DefaultTasks() map[string]Task {
 			Description: "Pi temperature",
 			Dangerous:   false,
 		},
-		"reboot": {
-			Name:        "reboot",
-			Command:     "reboot",
-			Description: "Reboot system",
-			Dangerous:   true,
-		},
 	}
 }
 
@@ -124,16 +345,79 @@ func Load() (*Config, error) {
 	_ = godotenv.Load(envFile)
 
 	cfg := &Config{
-		Port:           getEnvInt("PORT", 8091),
-		Host:           getEnv("HOST", "0.0.0.0"),
-		ReadTimeout:    time.Duration(getEnvInt("READ_TIMEOUT_SECONDS", 30)) * time.Second,
-		WriteTimeout:   time.Duration(getEnvInt("WRITE_TIMEOUT_SECONDS", 86400)) * time.Second, // 24h for SSE
-		APIKey:         getEnv("API_KEY", ""),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		AllowedOrigins: getEnvSlice("ALLOWED_ORIGINS", []string{"*"}),
-		RateLimitRPS:   getEnvInt("RATE_LIMIT_RPS", 100),
-		DockerEnabled:  getEnvBool("DOCKER_ENABLED", true),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
+		Port:                  getEnvInt("PORT", 8091),
+		Host:                  getEnv("HOST", "0.0.0.0"),
+		ReadTimeout:           time.Duration(getEnvInt("READ_TIMEOUT_SECONDS", 30)) * time.Second,
+		WriteTimeout:          time.Duration(getEnvInt("WRITE_TIMEOUT_SECONDS", 86400)) * time.Second, // 24h for SSE
+		Listeners:             getEnvListeners("LISTENERS"),
+		TLSCertFile:           getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:            getEnv("TLS_KEY_FILE", ""),
+		HTTP2:                 getEnvBool("HTTP2_ENABLED", true),
+		H2C:                   getEnvBool("H2C_ENABLED", false),
+		HTTP2MaxStreams:       getEnvInt("HTTP2_MAX_CONCURRENT_STREAMS", 0),
+		HTTP2PingInterval:     time.Duration(getEnvInt("HTTP2_PING_SECONDS", 0)) * time.Second,
+		IdleTimeout:           time.Duration(getEnvInt("IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+		ReadHeaderTimeout:     time.Duration(getEnvInt("READ_HEADER_TIMEOUT_SECONDS", 10)) * time.Second,
+		TCPKeepAlive:          time.Duration(getEnvInt("TCP_KEEPALIVE_SECONDS", 15)) * time.Second,
+		Labels:                getEnvMap("LABELS"),
+		CloudMetadata:         getEnvBool("CLOUD_METADATA", true),
+		APIKey:                getEnv("API_KEY", ""),
+		APIKeyFile:            getEnv("API_KEY_FILE", ""),
+		JWTSecret:             getEnv("JWT_SECRET", ""),
+		JWTLeeway:             time.Duration(getEnvInt("JWT_LEEWAY_SECONDS", 60)) * time.Second,
+		AllowedOrigins:        getEnvSlice("ALLOWED_ORIGINS", []string{"*"}),
+		CORSRoutes:            getEnvCORSRoutes("CORS_ROUTES"),
+		CORSMaxAge:            time.Duration(getEnvInt("CORS_MAX_AGE", 86400)) * time.Second,
+		RateLimitRPS:          getEnvInt("RATE_LIMIT_RPS", 100),
+		PrivilegeHelper:       getEnvBool("PRIVILEGE_HELPER", false),
+		HelperSudo:            getEnv("HELPER_SUDO", "sudo"),
+		SandboxEnabled:        getEnvBool("SANDBOX_ENABLED", false),
+		SandboxReadPaths:      getEnvSlice("SANDBOX_READ_PATHS", []string{}),
+		SandboxWritePaths:     getEnvSlice("SANDBOX_WRITE_PATHS", []string{}),
+		DockerEnabled:         getEnvBool("DOCKER_ENABLED", true),
+		DockerHost:            getEnv("DOCKER_HOST", ""),
+		RegistryAuth:          getEnv("REGISTRY_AUTH", ""),
+		DockerCheckInterval:   time.Duration(getEnvInt("DOCKER_CHECK_SECONDS", 30)) * time.Second,
+		DockerUpdateInterval:  time.Duration(getEnvInt("DOCKER_UPDATE_INTERVAL_HOURS", 6)) * time.Hour,
+		DockerAutoUpdate:      getEnvSlice("DOCKER_AUTO_UPDATE", []string{}),
+		DockerHealthTimeout:   time.Duration(getEnvInt("DOCKER_AUTO_UPDATE_HEALTH_SECONDS", 120)) * time.Second,
+		ServiceActionTimeout:  time.Duration(getEnvInt("SERVICE_ACTION_TIMEOUT_SECONDS", 30)) * time.Second,
+		FilesDeleteEnabled:    getEnvBool("FILES_DELETE_ENABLED", false),
+		DeployEnabled:         getEnvBool("DEPLOY_ENABLED", false),
+		DockerCreateEnabled:   getEnvBool("DOCKER_CREATE_ENABLED", false),
+		DeployTimeout:         time.Duration(getEnvInt("DEPLOY_TIMEOUT_MINUTES", 15)) * time.Minute,
+		FilesEnabled:          getEnvBool("FILES_ENABLED", true),
+		TasksEnabled:          getEnvBool("TASKS_ENABLED", true),
+		ProcessesEnabled:      getEnvBool("PROCESSES_ENABLED", true),
+		ServicesEnabled:       getEnvBool("SERVICES_ENABLED", true),
+		LogsEnabled:           getEnvBool("LOGS_ENABLED", true),
+		TrashRetention:        time.Duration(getEnvInt("TRASH_RETENTION_DAYS", 7)) * 24 * time.Hour,
+		ApprovalsEnabled:      getEnvBool("APPROVALS_ENABLED", false),
+		ApprovalTTL:           time.Duration(getEnvInt("APPROVAL_TTL_MINUTES", 30)) * time.Minute,
+		DataDir:               getEnv("DATA_DIR", "/var/lib/hivedeck-agent"),
+		StoragePrune:          time.Duration(getEnvInt("STORAGE_PRUNE_INTERVAL_HOURS", 24)) * time.Hour,
+		StorageLimit:          int64(getEnvInt("STORAGE_LIMIT_MB", 0)) << 20,
+		CacheTTL:              getEnvDuration("CACHE_TTL", DefaultCacheTTL),
+		CacheTTLs:             getEnvDurationMap("CACHE_TTLS", map[string]time.Duration{}),
+		PrometheusEnabled:     getEnvBool("PROMETHEUS_ENABLED", false),
+		PrometheusPath:        getEnv("PROMETHEUS_PATH", "/metrics/prometheus"),
+		PrometheusAuth:        getEnvBool("PROMETHEUS_AUTH", true),
+		RequestTimeout:        time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 30)) * time.Second,
+		RouteTimeouts:         getEnvDurationMap("ROUTE_TIMEOUTS", DefaultRouteTimeouts()),
+		MaxBodyBytes:          int64(getEnvInt("MAX_BODY_BYTES", 1<<20)),
+		RouteBodyLimits:       getEnvInt64Map("ROUTE_BODY_LIMITS", DefaultRouteBodyLimits()),
+		MaxRequests:           getEnvInt("MAX_CONCURRENT_REQUESTS", 64),
+		MaxStreams:            getEnvInt("MAX_STREAMS", 16),
+		SecurityHeaders:       getEnvBool("SECURITY_HEADERS", true),
+		HSTSMaxAge:            getEnvInt("HSTS_MAX_AGE_SECONDS", 31536000),
+		FrameOptions:          getEnv("FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        getEnv("REFERRER_POLICY", "no-referrer"),
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", DefaultContentSecurityPolicy),
+		LogLevel:              getEnv("LOG_LEVEL", "info"),
+		SentryDSN:             getEnv("SENTRY_DSN", ""),
+		CrashWebhookURL:       getEnv("CRASH_WEBHOOK_URL", ""),
+		EncryptSecrets:        getEnvBool("ENCRYPT_SECRETS", false),
+		SecretsKeyFile:        getEnv("SECRETS_KEY_FILE", ""),
 		AllowedServices: getEnvSlice("ALLOWED_SERVICES", []string{
 			"routerctl-agent",
 			"hivedeck-agent",
@@ -142,7 +426,86 @@ func Load() (*Config, error) {
 			"ssh",
 			"tailscaled",
 		}),
-		AllowedTasks: DefaultTasks(),
+		SpeedtestBackend: getEnv("SPEEDTEST_BACKEND", ""),
+		SpeedtestServer:  getEnv("SPEEDTEST_SERVER", ""),
+		IntegrityPaths: getEnvSlice("INTEGRITY_PATHS", []string{
+			"/etc/ssh/sshd_config",
+			"/etc/systemd/system",
+			"/etc/sudoers",
+		}),
+		IntegrityInterval:   time.Duration(getEnvInt("INTEGRITY_INTERVAL_SECONDS", 300)) * time.Second,
+		ProfileFiles:        getEnvSlice("PROFILE_FILES", DefaultProfileFiles()),
+		ExposureInterval:    time.Duration(getEnvInt("EXPOSURE_INTERVAL_MINUTES", 15)) * time.Minute,
+		VulnScanInterval:    time.Duration(getEnvInt("VULN_SCAN_INTERVAL_HOURS", 24)) * time.Hour,
+		BackupRepos:         getEnvMap("BACKUP_REPOS"),
+		DatabaseURLs:        getEnv("DATABASE_URLS", ""),
+		DNSServers:          getEnv("DNS_SERVERS", ""),
+		Upstreams:           getEnvMap("UPSTREAMS"),
+		UpstreamInterval:    time.Duration(getEnvInt("UPSTREAM_INTERVAL_SECONDS", 30)) * time.Second,
+		UpstreamTimeout:     time.Duration(getEnvInt("UPSTREAM_TIMEOUT_SECONDS", 5)) * time.Second,
+		UpstreamInsecure:    getEnvBool("UPSTREAM_INSECURE", false),
+		BackupPasswordFiles: getEnvMap("BACKUP_PASSWORD_FILES"),
+		BackupCacheTTL:      time.Duration(getEnvInt("BACKUP_CACHE_MINUTES", 10)) * time.Minute,
+		GitRepos:            getEnvMap("GIT_REPOS"),
+		GitPullAllowed:      getEnvSlice("GIT_PULL_ALLOWED", []string{}),
+		MaintenanceEnabled:  getEnvBool("MAINTENANCE_ENABLED", false),
+		MaintenanceSchedule: getEnv("MAINTENANCE_SCHEDULE", "sun 03:30"),
+		MaintenanceWindow:   time.Duration(getEnvInt("MAINTENANCE_WINDOW_MINUTES", 60)) * time.Minute,
+		MaintenanceReboot:   getEnvBool("MAINTENANCE_REBOOT", false),
+		JournalVacuumSize:   getEnv("MAINTENANCE_JOURNAL_SIZE", "500M"),
+		MaintenanceSteps: getEnvSlice("MAINTENANCE_STEPS", []string{
+			"apt-upgrade",
+			"docker-prune",
+			"journal-vacuum",
+		}),
+		ThermalEnabled:      getEnvBool("THERMAL_ENABLED", false),
+		ThermalWarning:      getEnvInt("THERMAL_WARNING", 70),
+		ThermalCritical:     getEnvInt("THERMAL_CRITICAL", 80),
+		ThermalHysteresis:   getEnvInt("THERMAL_HYSTERESIS", 5),
+		ThermalInterval:     time.Duration(getEnvInt("THERMAL_INTERVAL_SECONDS", 15)) * time.Second,
+		ThermalSensors:      getEnvSlice("THERMAL_SENSORS", []string{}),
+		ThermalHotTask:      getEnv("THERMAL_HOT_TASK", ""),
+		ThermalCoolTask:     getEnv("THERMAL_COOL_TASK", ""),
+		CPUGovernorControl:  getEnvBool("CPU_GOVERNOR_CONTROL", false),
+		GPIOInputs:          getEnvMap("GPIO_INPUTS"),
+		PowerEstimates:      getEnvMap("POWER_ESTIMATES"),
+		OOMWatch:            getEnvBool("OOM_WATCH", true),
+		HostAudit:           getEnvBool("HOST_AUDIT", false),
+		HostAuditInterval:   time.Duration(getEnvInt("HOST_AUDIT_INTERVAL_SECONDS", 2)) * time.Second,
+		ZombieThreshold:     getEnvInt("ZOMBIE_THRESHOLD", 20),
+		ZombieMaxAge:        time.Duration(getEnvInt("ZOMBIE_MAX_AGE_MINUTES", 30)) * time.Minute,
+		ZombieInterval:      time.Duration(getEnvInt("ZOMBIE_INTERVAL_SECONDS", 60)) * time.Second,
+		PowerInterval:       time.Duration(getEnvInt("POWER_INTERVAL_SECONDS", 30)) * time.Second,
+		MQTTBroker:          getEnv("MQTT_BROKER", ""),
+		MQTTUsername:        getEnv("MQTT_USERNAME", ""),
+		MQTTPassword:        getEnv("MQTT_PASSWORD", ""),
+		MQTTClientID:        getEnv("MQTT_CLIENT_ID", ""),
+		MQTTTopicPrefix:     getEnv("MQTT_TOPIC_PREFIX", ""),
+		MQTTInterval:        time.Duration(getEnvInt("MQTT_INTERVAL_SECONDS", 30)) * time.Second,
+		MQTTDiscovery:       getEnvBool("MQTT_DISCOVERY", false),
+		MQTTDiscoveryPrefix: getEnv("MQTT_DISCOVERY_PREFIX", "homeassistant"),
+		LogForwardURL:       getEnv("LOG_FORWARD_URL", ""),
+		LogForwardUsername:  getEnv("LOG_FORWARD_USERNAME", ""),
+		LogForwardPassword:  getEnv("LOG_FORWARD_PASSWORD", ""),
+		LogForwardUnits:     getEnvSlice("LOG_FORWARD_UNITS", []string{}),
+		LogForwardFiles:     getEnvSlice("LOG_FORWARD_FILES", []string{}),
+		LogForwardLabels:    getEnvMap("LOG_FORWARD_LABELS"),
+		LogForwardBatchSize: getEnvInt("LOG_FORWARD_BATCH_SIZE", 100),
+		LogForwardFlush:     time.Duration(getEnvInt("LOG_FORWARD_FLUSH_SECONDS", 5)) * time.Second,
+		HeartbeatURL:        getEnv("HEARTBEAT_URL", ""),
+		HeartbeatInterval:   time.Duration(getEnvInt("HEARTBEAT_INTERVAL_SECONDS", 60)) * time.Second,
+		PullURL:             getEnv("PULL_URL", ""),
+		PullSecret:          getEnv("PULL_SECRET", ""),
+		PullInterval:        time.Duration(getEnvInt("PULL_INTERVAL_SECONDS", 30)) * time.Second,
+		PullAllowed:         getEnvSlice("PULL_ALLOWED_COMMANDS", []string{"GET /api/"}),
+		Webhooks:            getEnvMap("WEBHOOKS"),
+		WebhookSecrets:      getEnv("WEBHOOK_SECRETS", ""),
+		AlertRules:          getEnvMap("ALERT_RULES"),
+		AlertSinks:          getEnv("ALERT_SINKS", ""),
+		AlertInterval:       time.Duration(getEnvInt("ALERT_INTERVAL_SECONDS", 30)) * time.Second,
+		AllowedTasks:        getEnvTasks("CUSTOM_TASKS", DefaultTasks()),
+		AllowedProcesses:    getEnvSlice("ALLOWED_PROCESSES", []string{}),
+		RedactKeywords:      getEnvSlice("REDACT_KEYWORDS", []string{}),
 		AllowedPaths: getEnvSlice("ALLOWED_PATHS", []string{
 			"/var/log",
 			"/etc",
@@ -154,6 +517,14 @@ func Load() (*Config, error) {
 		EnvFile:   envFile,
 	}
 
+	if err := cfg.decryptSecrets(); err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets: %w", err)
+	}
+
+	if err := cfg.loadAPIKey(); err != nil {
+		return nil, err
+	}
+
 	// Check if API key is configured
 	if cfg.APIKey == "" {
 		cfg.SetupMode = true
@@ -196,88 +567,236 @@ func getEnvFile() string {
 // SaveAPIKey saves the API key to the .env file
 func (c *Config) SaveAPIKey(apiKey string) error {
 	updates := map[string]string{"API_KEY": apiKey}
-	if err := UpdateEnvFile(c.EnvFile, updates); err != nil {
+	if err := c.SaveEnv(updates); err != nil {
 		return err
 	}
 
-	// Update config
+	// Update config. The JWT secret follows the key unless JWT_SECRET set
+	// its own, matching Load.
+	if c.JWTSecret == "" || c.JWTSecret == c.APIKey {
+		c.JWTSecret = apiKey
+	}
 	c.APIKey = apiKey
-	c.JWTSecret = apiKey
 	c.SetupMode = false
 
 	return nil
 }
 
-// UpdateEnvFile updates or adds environment variables in a .env file
-func UpdateEnvFile(envFile string, updates map[string]string) error {
-	// Read existing .env content
-	existingContent := ""
-	if data, err := os.ReadFile(envFile); err == nil {
-		existingContent = string(data)
+// LoadWithDefaults loads config with defaults for testing
+func LoadWithDefaults() *Config {
+	return &Config{
+		Port:                  8091,
+		Host:                  "0.0.0.0",
+		ReadTimeout:           30 * time.Second,
+		WriteTimeout:          86400 * time.Second, // 24h for SSE
+		HTTP2:                 true,
+		IdleTimeout:           120 * time.Second,
+		ReadHeaderTimeout:     10 * time.Second,
+		TCPKeepAlive:          15 * time.Second,
+		Labels:                map[string]string{},
+		APIKey:                "test-api-key",
+		JWTSecret:             "test-jwt-secret",
+		JWTLeeway:             time.Minute,
+		AllowedOrigins:        []string{"*"},
+		CORSMaxAge:            24 * time.Hour,
+		RateLimitRPS:          100,
+		HelperSudo:            "sudo",
+		SandboxReadPaths:      []string{},
+		SandboxWritePaths:     []string{},
+		DockerEnabled:         true,
+		DockerCheckInterval:   30 * time.Second,
+		DockerAutoUpdate:      []string{},
+		DockerHealthTimeout:   120 * time.Second,
+		ServiceActionTimeout:  30 * time.Second,
+		DeployTimeout:         15 * time.Minute,
+		FilesEnabled:          true,
+		TasksEnabled:          true,
+		ProcessesEnabled:      true,
+		ServicesEnabled:       true,
+		LogsEnabled:           true,
+		DataDir:               "",
+		StoragePrune:          24 * time.Hour,
+		CacheTTL:              DefaultCacheTTL,
+		CacheTTLs:             map[string]time.Duration{},
+		PrometheusPath:        "/metrics/prometheus",
+		PrometheusAuth:        true,
+		RequestTimeout:        30 * time.Second,
+		RouteTimeouts:         DefaultRouteTimeouts(),
+		MaxBodyBytes:          1 << 20,
+		RouteBodyLimits:       DefaultRouteBodyLimits(),
+		MaxRequests:           64,
+		MaxStreams:            16,
+		SecurityHeaders:       true,
+		HSTSMaxAge:            31536000,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		ContentSecurityPolicy: DefaultContentSecurityPolicy,
+		LogLevel:              "info",
+		Version:               "dev",
+		AllowedServices:       []string{"test-service"},
+		AllowedTasks:          DefaultTasks(),
+		AllowedPaths:          []string{"/tmp", "/var/log"},
+		AllowedProcesses:      []string{},
+		RedactKeywords:        []string{},
+		IntegrityPaths:        []string{},
+		ProfileFiles:          []string{},
+		BackupRepos:           map[string]string{},
+		BackupPasswordFiles:   map[string]string{},
+		BackupCacheTTL:        10 * time.Minute,
+		GitRepos:              map[string]string{},
+		GitPullAllowed:        []string{},
+		Upstreams:             map[string]string{},
+		Webhooks:              map[string]string{},
+		AlertRules:            map[string]string{},
+		AlertInterval:         30 * time.Second,
+		UpstreamInterval:      30 * time.Second,
+		UpstreamTimeout:       5 * time.Second,
+		MaintenanceSchedule:   "sun 03:30",
+		MaintenanceWindow:     time.Hour,
+		MaintenanceSteps:      []string{"apt-upgrade", "docker-prune", "journal-vacuum"},
+		JournalVacuumSize:     "500M",
+		ThermalWarning:        70,
+		ThermalCritical:       80,
+		ThermalHysteresis:     5,
+		ThermalInterval:       15 * time.Second,
+		ThermalSensors:        []string{},
+		GPIOInputs:            map[string]string{},
+		PowerEstimates:        map[string]string{},
+		PowerInterval:         30 * time.Second,
+		ZombieThreshold:       20,
+		ZombieMaxAge:          30 * time.Minute,
+		ZombieInterval:        time.Minute,
+		HostAuditInterval:     2 * time.Second,
 	}
+}
 
-	// Parse existing lines
-	lines := strings.Split(existingContent, "\n")
-	found := make(map[string]bool)
+// PullEnabled reports whether pull mode is configured
+func (c *Config) PullEnabled() bool {
+	return c.PullURL != "" && c.PullSecret != "" && c.APIKey != ""
+}
 
-	// Update existing keys
-	for i, line := range lines {
-		for key, value := range updates {
-			if strings.HasPrefix(line, key+"=") {
-				lines[i] = key + "=" + value
-				found[key] = true
-				break
-			}
-		}
+// Addr returns the server address string
+func (c *Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// TLSEnabled reports whether the agent serves HTTPS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// Listener is an address the agent serves on. Paths limits it to requests
+// under those path prefixes; empty serves everything.
+type Listener struct {
+	Addr  string   // host:port; the host may be an interface name such as tailscale0
+	Paths []string // e.g. /health, /api/metrics
+}
+
+// CORSRoute sets the origins allowed cross-origin on paths under Prefix.
+// No origins closes the paths to cross-origin requests.
+type CORSRoute struct {
+	Prefix  string   // e.g. /health or /api/metrics
+	Origins []string // Exact origins, *.example.com patterns or *
+}
+
+// ListenAddrs returns the configured listeners, or a single unrestricted
+// listener on Host:Port
+func (c *Config) ListenAddrs() []Listener {
+	if len(c.Listeners) > 0 {
+		return c.Listeners
 	}
+	return []Listener{{Addr: c.Addr()}}
+}
 
-	// Add missing keys at the beginning
-	var newLines []string
-	for key, value := range updates {
-		if !found[key] {
-			newLines = append(newLines, key+"="+value)
-		}
+// DefaultContentSecurityPolicy allows the setup and settings pages their
+// inline script and style and nothing from other origins
+const DefaultContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline'; img-src 'self' data:; connect-src 'self'; " +
+	"frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+
+// DefaultCacheTTL is how long metrics are cached unless CACHE_TTL says otherwise
+const DefaultCacheTTL = 2 * time.Second
+
+// DefaultRouteTimeouts returns the request timeouts for route groups that
+// differ from REQUEST_TIMEOUT_SECONDS
+func DefaultRouteTimeouts() map[string]time.Duration {
+	return map[string]time.Duration{
+		"metrics":     10 * time.Second,
+		"logs":        30 * time.Second,
+		"services":    2 * time.Minute,
+		"docker":      2 * time.Minute,
+		"tasks":       30 * time.Minute,
+		"system":      time.Minute,
+		"integrity":   5 * time.Minute,
+		"batch":       5 * time.Minute,
+		"backups":     5 * time.Minute,
+		"maintenance": 2 * time.Hour,
 	}
-	if len(newLines) > 0 {
-		lines = append(newLines, lines...)
+}
+
+// DefaultProfileFiles returns the configuration hashed into the host
+// profile by default
+func DefaultProfileFiles() []string {
+	return []string{
+		"/etc/ssh/sshd_config",
+		"/etc/sysctl.conf",
+		"/etc/sysctl.d",
+		"/etc/apt/sources.list",
+		"/etc/apt/sources.list.d",
+		"/etc/docker/daemon.json",
 	}
+}
 
-	// Remove empty lines at the end
-	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
-		lines = lines[:len(lines)-1]
+// DefaultRouteBodyLimits returns the body size limits for route groups that
+// differ from MAX_BODY_BYTES
+func DefaultRouteBodyLimits() map[string]int64 {
+	return map[string]int64{
+		"files":   4 << 20, // Diffs post the content of both sides
+		"profile": 4 << 20, // A compared profile lists every package
 	}
+}
 
-	// Write back to .env file
-	content := strings.Join(lines, "\n") + "\n"
-	if err := os.WriteFile(envFile, []byte(content), 0600); err != nil {
-		return fmt.Errorf("failed to write .env file: %w", err)
+// RouteTimeout returns the request timeout for a route group
+func (c *Config) RouteTimeout(group string) time.Duration {
+	if d, ok := c.RouteTimeouts[group]; ok {
+		return d
 	}
+	return c.RequestTimeout
+}
 
-	return nil
+// RouteBodyLimit returns the maximum request body size for a route group
+func (c *Config) RouteBodyLimit(group string) int64 {
+	if n, ok := c.RouteBodyLimits[group]; ok {
+		return n
+	}
+	return c.MaxBodyBytes
 }
 
-// LoadWithDefaults loads config with defaults for testing
-func LoadWithDefaults() *Config {
-	return &Config{
-		Port:            8091,
-		Host:            "0.0.0.0",
-		ReadTimeout:     30 * time.Second,
-		WriteTimeout:    86400 * time.Second, // 24h for SSE
-		APIKey:          "test-api-key",
-		JWTSecret:       "test-jwt-secret",
-		AllowedOrigins:  []string{"*"},
-		RateLimitRPS:    100,
-		DockerEnabled:   true,
-		LogLevel:        "info",
-		AllowedServices: []string{"test-service"},
-		AllowedTasks:    DefaultTasks(),
-		AllowedPaths:    []string{"/tmp", "/var/log"},
+// Module names that can be disabled
+const (
+	ModuleFiles     = "files"
+	ModuleTasks     = "tasks"
+	ModuleProcesses = "processes"
+	ModuleServices  = "services"
+	ModuleLogs      = "logs"
+	ModuleDocker    = "docker"
+)
+
+// Modules returns the enabled state of every module
+func (c *Config) Modules() map[string]bool {
+	return map[string]bool{
+		ModuleFiles:     c.FilesEnabled,
+		ModuleTasks:     c.TasksEnabled,
+		ModuleProcesses: c.ProcessesEnabled,
+		ModuleServices:  c.ServicesEnabled,
+		ModuleLogs:      c.LogsEnabled,
+		ModuleDocker:    c.DockerEnabled,
 	}
 }
 
-// Addr returns the server address string
-func (c *Config) Addr() string {
-	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+// ModuleEnabled reports whether a module is enabled
+func (c *Config) ModuleEnabled(module string) bool {
+	return c.Modules()[module]
 }
 
 // IsServiceAllowed checks if a service can be managed
@@ -321,9 +840,141 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
 	}
 	return defaultValue
 }
+
+// getEnvListeners parses semicolon-separated listeners, each an address
+// optionally followed by = and comma-separated path prefixes:
+// tailscale0:8091;192.168.1.10:8091=/health,/api/metrics
+func getEnvListeners(key string) []Listener {
+	var listeners []Listener
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		addr, paths, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+
+		l := Listener{Addr: addr}
+		for _, p := range strings.Split(paths, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				l.Paths = append(l.Paths, p)
+			}
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// getEnvCORSRoutes parses semicolon-separated path prefixes, each followed
+// by = and comma-separated origins:
+// /health=*;/api/metrics=https://grafana.example.com,https://*.example.com
+func getEnvCORSRoutes(key string) []CORSRoute {
+	var routes []CORSRoute
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		prefix, origins, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		if prefix = strings.TrimSpace(prefix); prefix == "" {
+			continue
+		}
+
+		r := CORSRoute{Prefix: prefix}
+		for _, o := range strings.Split(origins, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				r.Origins = append(r.Origins, o)
+			}
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// Databases returns the configured database URLs by name
+func (c *Config) Databases() map[string]string {
+	return parseMap(c.DatabaseURLs)
+}
+
+// RegistryLogins returns the registry credentials as username:password by
+// registry host
+func (c *Config) RegistryLogins() map[string]string {
+	return parseMap(c.RegistryAuth)
+}
+
+// HookSecrets returns the webhook secrets by webhook name
+func (c *Config) HookSecrets() map[string]string {
+	return parseMap(c.WebhookSecrets)
+}
+
+// AlertSinkSpecs returns the alert sinks by name
+func (c *Config) AlertSinkSpecs() map[string]string {
+	return parseMap(c.AlertSinks)
+}
+
+// DNS returns the configured DNS servers by name
+func (c *Config) DNS() map[string]string {
+	return parseMap(c.DNSServers)
+}
+
+// getEnvTasks adds semicolon-separated name=command tasks to tasks:
+// fan-on=/usr/local/bin/fan on;fan-off=/usr/local/bin/fan off
+func getEnvTasks(key string, tasks map[string]Task) map[string]Task {
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		name, command, _ := strings.Cut(entry, "=")
+		name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+		if name == "" || command == "" {
+			continue
+		}
+		tasks[name] = Task{Name: name, Command: command, Description: "Custom task"}
+	}
+	return tasks
+}
+
+// getEnvMap parses a comma-separated list of key=value pairs
+func getEnvMap(key string) map[string]string {
+	return parseMap(os.Getenv(key))
+}
+
+func parseMap(value string) map[string]string {
+	result := make(map[string]string)
+	if value == "" {
+		return result
+	}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if ok && strings.TrimSpace(k) != "" {
+			result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return result
+}
+
+// getEnvDurationMap overrides defaults with group=duration pairs
+func getEnvDurationMap(key string, defaults map[string]time.Duration) map[string]time.Duration {
+	for k, v := range getEnvMap(key) {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			defaults[k] = d
+		}
+	}
+	return defaults
+}
+
+// getEnvInt64Map overrides defaults with group=number pairs
+func getEnvInt64Map(key string, defaults map[string]int64) map[string]int64 {
+	for k, v := range getEnvMap(key) {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			defaults[k] = n
+		}
+	}
+	return defaults
+}
@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,6 +42,79 @@ func TestGenerateAPIKey(t *testing.T) {
 	assert.NotEqual(t, key, key2)
 }
 
+func TestLoadAPIKeyFromFile(t *testing.T) {
+	dir := t.TempDir()
+	key := strings.Repeat("k", MinAPIKeyLength)
+	t.Setenv("API_KEY", "")
+	t.Setenv("ENV_FILE", filepath.Join(dir, ".env"))
+
+	// The systemd credential applies when API_KEY_FILE is unset
+	require.NoError(t, os.WriteFile(filepath.Join(dir, APIKeyCredential), []byte(key+"\n"), 0600))
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.False(t, cfg.SetupMode)
+	assert.Equal(t, key, cfg.APIKey)
+	assert.Equal(t, APIKeySourceCredential, cfg.APIKeySource)
+	assert.Equal(t, key, cfg.JWTSecret)
+
+	fileKey := strings.Repeat("f", MinAPIKeyLength)
+	path := filepath.Join(dir, "api-key.txt")
+	require.NoError(t, os.WriteFile(path, []byte(fileKey), 0600))
+	t.Setenv("API_KEY_FILE", path)
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, fileKey, cfg.APIKey)
+	assert.Equal(t, APIKeySourceFile, cfg.APIKeySource)
+
+	// API_KEY wins over both
+	t.Setenv("API_KEY", "env-key")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "env-key", cfg.APIKey)
+	assert.Equal(t, APIKeySourceEnv, cfg.APIKeySource)
+
+	t.Setenv("API_KEY", "")
+	require.NoError(t, os.WriteFile(path, []byte("short"), 0600))
+	_, err = Load()
+	assert.ErrorContains(t, err, "at least 32 characters")
+
+	t.Setenv("API_KEY_FILE", filepath.Join(dir, "missing"))
+	_, err = Load()
+	assert.ErrorContains(t, err, "API_KEY_FILE")
+}
+
+func TestBootstrap(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	cfg := &Config{EnvFile: envFile, SetupMode: true}
+
+	key, saved, err := cfg.Bootstrap("", false)
+	require.NoError(t, err)
+	assert.True(t, saved)
+	assert.Len(t, key, 64)
+	assert.False(t, cfg.SetupMode)
+	assert.Equal(t, key, cfg.JWTSecret)
+	data, err := os.ReadFile(envFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "API_KEY="+key)
+
+	// Running it again keeps the key
+	again, saved, err := cfg.Bootstrap("", false)
+	require.NoError(t, err)
+	assert.False(t, saved)
+	assert.Equal(t, key, again)
+
+	_, _, err = cfg.Bootstrap("short", true)
+	assert.Error(t, err)
+
+	replacement := strings.Repeat("r", MinAPIKeyLength)
+	again, saved, err = cfg.Bootstrap(replacement, true)
+	require.NoError(t, err)
+	assert.True(t, saved)
+	assert.Equal(t, replacement, again)
+	assert.Equal(t, replacement, cfg.APIKey)
+}
+
 func TestLoadWithEnvVars(t *testing.T) {
 	// Set environment variables
 	os.Setenv("API_KEY", "my-test-key")
@@ -75,6 +151,25 @@ func TestIsServiceAllowed(t *testing.T) {
 	assert.False(t, cfg.IsServiceAllowed("mysql"))
 }
 
+func TestModuleEnabled(t *testing.T) {
+	os.Setenv("API_KEY", "my-test-key")
+	os.Setenv("FILES_ENABLED", "false")
+	os.Setenv("TASKS_ENABLED", "false")
+	defer func() {
+		os.Unsetenv("API_KEY")
+		os.Unsetenv("FILES_ENABLED")
+		os.Unsetenv("TASKS_ENABLED")
+	}()
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.False(t, cfg.ModuleEnabled(ModuleFiles))
+	assert.False(t, cfg.ModuleEnabled(ModuleTasks))
+	assert.True(t, cfg.ModuleEnabled(ModuleProcesses))
+	assert.False(t, cfg.ModuleEnabled("unknown"))
+}
+
 func TestGetTask(t *testing.T) {
 	cfg := LoadWithDefaults()
 
@@ -83,9 +178,9 @@ func TestGetTask(t *testing.T) {
 	assert.Equal(t, "df -h", task.Command)
 	assert.False(t, task.Dangerous)
 
-	task, ok = cfg.GetTask("reboot")
-	assert.True(t, ok)
-	assert.True(t, task.Dangerous)
+	// Reboot is handled by the system power endpoints, not the task runner
+	_, ok = cfg.GetTask("reboot")
+	assert.False(t, ok)
 
 	_, ok = cfg.GetTask("nonexistent")
 	assert.False(t, ok)
@@ -98,9 +193,54 @@ func TestDefaultTasks(t *testing.T) {
 	assert.Contains(t, tasks, "df")
 	assert.Contains(t, tasks, "free")
 	assert.Contains(t, tasks, "uptime")
-	assert.Contains(t, tasks, "reboot")
+	assert.NotContains(t, tasks, "reboot")
 
-	// Verify reboot is marked as dangerous
-	assert.True(t, tasks["reboot"].Dangerous)
+	// Verify default tasks are safe
 	assert.False(t, tasks["df"].Dangerous)
 }
+
+func TestListeners(t *testing.T) {
+	t.Setenv("LISTENERS", "tailscale0:8091; 192.168.1.10:8091=/health, /api/metrics ;")
+	listeners := getEnvListeners("LISTENERS")
+	assert.Equal(t, []Listener{
+		{Addr: "tailscale0:8091"},
+		{Addr: "192.168.1.10:8091", Paths: []string{"/health", "/api/metrics"}},
+	}, listeners)
+
+	cfg := LoadWithDefaults()
+	assert.Equal(t, []Listener{{Addr: "0.0.0.0:8091"}}, cfg.ListenAddrs())
+	cfg.Listeners = listeners
+	assert.Equal(t, listeners, cfg.ListenAddrs())
+}
+
+func TestCORSRoutes(t *testing.T) {
+	t.Setenv("CORS_ROUTES", "/health=*; /api/metrics=https://grafana.lan, https://*.example.com ;/api/files=")
+	assert.Equal(t, []CORSRoute{
+		{Prefix: "/health", Origins: []string{"*"}},
+		{Prefix: "/api/metrics", Origins: []string{"https://grafana.lan", "https://*.example.com"}},
+		{Prefix: "/api/files"},
+	}, getEnvCORSRoutes("CORS_ROUTES"))
+}
+
+func TestCacheTTLs(t *testing.T) {
+	t.Setenv("CACHE_TTL", "10s")
+	t.Setenv("CACHE_TTLS", "metrics:disk=1m,metrics:cpu=bogus")
+	assert.Equal(t, 10*time.Second, getEnvDuration("CACHE_TTL", DefaultCacheTTL))
+	assert.Equal(t, map[string]time.Duration{"metrics:disk": time.Minute},
+		getEnvDurationMap("CACHE_TTLS", map[string]time.Duration{}))
+
+	t.Setenv("CACHE_TTL", "-1s")
+	assert.Equal(t, DefaultCacheTTL, getEnvDuration("CACHE_TTL", DefaultCacheTTL))
+}
+
+func TestCustomTasks(t *testing.T) {
+	t.Setenv("CUSTOM_TASKS", "fan-on=/usr/local/bin/fan on; fan-off = /usr/local/bin/fan off;broken;df=df -h /")
+	tasks := getEnvTasks("CUSTOM_TASKS", DefaultTasks())
+
+	assert.Equal(t, Task{Name: "fan-on", Command: "/usr/local/bin/fan on", Description: "Custom task"}, tasks["fan-on"])
+	assert.Equal(t, "/usr/local/bin/fan off", tasks["fan-off"].Command)
+	assert.NotContains(t, tasks, "broken")
+	// Custom tasks replace defaults with the same name
+	assert.Equal(t, "df -h /", tasks["df"].Command)
+	assert.Contains(t, tasks, "uptime")
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// plainValue matches values that godotenv reads back unchanged without quotes
+var plainValue = regexp.MustCompile(`^[A-Za-z0-9_./:,@%+=-]*$`)
+
+// UpdateEnvFile updates or adds environment variables in a .env file.
+//
+// Comments, blank lines and unrelated keys are kept as they are. Values are
+// quoted as needed so they read back unchanged. The file is replaced
+// atomically under an exclusive lock, so concurrent saves cannot lose each
+// other's changes or leave a partly written file.
+func UpdateEnvFile(envFile string, updates map[string]string) error {
+	// Write through a symlink rather than replacing it
+	if resolved, err := filepath.EvalSymlinks(envFile); err == nil {
+		envFile = resolved
+	}
+
+	unlock, err := lockEnvFile(envFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	mode := fs.FileMode(0600)
+	if info, err := os.Stat(envFile); err == nil {
+		mode = info.Mode().Perm()
+	}
+	data, err := os.ReadFile(envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read .env file: %w", err)
+	}
+
+	content, err := applyEnvUpdates(string(data), updates)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(envFile, []byte(content), mode); err != nil {
+		return fmt.Errorf("failed to write .env file: %w", err)
+	}
+	return nil
+}
+
+// applyEnvUpdates rewrites the lines that set an updated key and appends
+// keys that were not present
+func applyEnvUpdates(content string, updates map[string]string) (string, error) {
+	quoted := make(map[string]string, len(updates))
+	for key, value := range updates {
+		q, err := quoteEnvValue(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+		quoted[key] = q
+	}
+
+	lines := strings.Split(content, "\n")
+	found := make(map[string]bool)
+
+	for i, line := range lines {
+		key, export, ok := envLineKey(line)
+		if !ok {
+			continue
+		}
+		value, ok := quoted[key]
+		if !ok {
+			continue
+		}
+		lines[i] = export + key + "=" + value
+		found[key] = true
+	}
+
+	// Remove empty lines at the end
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// Add missing keys at the end, in a stable order
+	var missing []string
+	for key := range updates {
+		if !found[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		lines = append(lines, key+"="+quoted[key])
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// envLineKey returns the key set by a line, and the "export " prefix if it
+// has one. Comments and blank lines have no key.
+func envLineKey(line string) (key, export string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	if rest, found := strings.CutPrefix(trimmed, "export "); found {
+		trimmed, export = strings.TrimSpace(rest), "export "
+	}
+
+	name, _, found := strings.Cut(trimmed, "=")
+	if !found {
+		return "", "", false
+	}
+	return strings.TrimSpace(name), export, true
+}
+
+// quoteEnvValue quotes a value the way godotenv reads it back: plain when
+// that is safe, single quotes (no escapes or expansion) when possible, and
+// double quotes with escapes otherwise
+func quoteEnvValue(value string) (string, error) {
+	if plainValue.MatchString(value) {
+		return value, nil
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'", nil
+	}
+
+	// godotenv misreads an escaped quote or backslash right before the
+	// closing quote
+	if strings.HasSuffix(value, `"`) || strings.HasSuffix(value, `\`) {
+		return "", errors.New(`values containing a single quote or newline cannot end with " or \`)
+	}
+
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"$", `\$`,
+		"\n", `\n`,
+		"\r", `\r`,
+	)
+	return `"` + r.Replace(value) + `"`, nil
+}
+
+// lockEnvFile takes an exclusive lock on a file next to the .env file. The
+// .env file itself cannot be locked because it is replaced on every write.
+func lockEnvFile(envFile string) (func(), error) {
+	f, err := os.OpenFile(envFile+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock .env file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock .env file: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path
+func writeFileAtomic(path string, data []byte, mode fs.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateEnvFile_PreservesComments(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	original := "# Hivedeck agent\nAPI_KEY=old\n\n# Paths\nexport ALLOWED_PATHS=/var/log\nLOG_LEVEL = info\n"
+	require.NoError(t, os.WriteFile(envFile, []byte(original), 0640))
+
+	require.NoError(t, UpdateEnvFile(envFile, map[string]string{
+		"API_KEY":       "new",
+		"ALLOWED_PATHS": "/var/log,/etc",
+		"LOG_LEVEL":     "debug",
+		"PORT":          "9000",
+	}))
+
+	data, err := os.ReadFile(envFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# Hivedeck agent\nAPI_KEY=new\n\n# Paths\nexport ALLOWED_PATHS=/var/log,/etc\nLOG_LEVEL=debug\nPORT=9000\n", string(data))
+
+	info, err := os.Stat(envFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestUpdateEnvFile_QuotesValues(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	values := map[string]string{
+		"PLAIN":     "tcp://broker.lan:1883",
+		"SPACES":    "default-src 'self'; script-src 'self'",
+		"DOLLAR":    "pa$$word",
+		"HASH":      "abc #not-a-comment",
+		"QUOTES":    `it's "quoted" here`,
+		"BACKSLASH": `C:\path\n`,
+		"NEWLINE":   "line one\nline two",
+		"EMPTY":     "",
+	}
+	require.NoError(t, UpdateEnvFile(envFile, values))
+
+	parsed, err := godotenv.Read(envFile)
+	require.NoError(t, err)
+	assert.Equal(t, values, parsed)
+}
+
+func TestUpdateEnvFile_RejectsUnreadableValue(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(envFile, []byte("API_KEY=old\n"), 0600))
+
+	err := UpdateEnvFile(envFile, map[string]string{"API_KEY": "new", "BAD": `it's "quoted"`})
+	assert.Error(t, err)
+
+	// Nothing is written when any value is rejected
+	data, err := os.ReadFile(envFile)
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=old\n", string(data))
+}
+
+func TestUpdateEnvFile_ConcurrentUpdates(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, UpdateEnvFile(envFile, map[string]string{fmt.Sprintf("KEY_%d", i): "value"}))
+		}(i)
+	}
+	wg.Wait()
+
+	parsed, err := godotenv.Read(envFile)
+	require.NoError(t, err)
+	assert.Len(t, parsed, 20)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(envFile))
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.Contains(t, []string{".env", ".env.lock"}, e.Name())
+	}
+}
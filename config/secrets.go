@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/joho/godotenv"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/secrets"
+)
+
+// SecretKeys are the settings that hold credentials. They may be stored
+// encrypted in .env. The API key stays in plain text so setup can write it.
+var SecretKeys = []string{
+	"JWT_SECRET",
+	"PULL_SECRET",
+	"MQTT_PASSWORD",
+	"LOG_FORWARD_PASSWORD",
+	"SENTRY_DSN",
+	"CRASH_WEBHOOK_URL",
+	"HEARTBEAT_URL",
+	"DATABASE_URLS",
+	"DNS_SERVERS",
+	"WEBHOOK_SECRETS",
+	"ALERT_SINKS",
+	"REGISTRY_AUTH",
+}
+
+// secretFields maps each secret key to its config field
+func (c *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		"JWT_SECRET":           &c.JWTSecret,
+		"PULL_SECRET":          &c.PullSecret,
+		"MQTT_PASSWORD":        &c.MQTTPassword,
+		"LOG_FORWARD_PASSWORD": &c.LogForwardPassword,
+		"SENTRY_DSN":           &c.SentryDSN,
+		"CRASH_WEBHOOK_URL":    &c.CrashWebhookURL,
+		"HEARTBEAT_URL":        &c.HeartbeatURL,
+		"DATABASE_URLS":        &c.DatabaseURLs,
+		"DNS_SERVERS":          &c.DNSServers,
+		"WEBHOOK_SECRETS":      &c.WebhookSecrets,
+		"ALERT_SINKS":          &c.AlertSinks,
+		"REGISTRY_AUTH":        &c.RegistryAuth,
+	}
+}
+
+// decryptSecrets replaces encrypted secret values with their plaintext. The
+// key is only loaded when there is something to decrypt.
+func (c *Config) decryptSecrets() error {
+	var box *secrets.Box
+	for key, field := range c.secretFields() {
+		if !secrets.IsEncrypted(*field) {
+			continue
+		}
+		if box == nil {
+			var err error
+			if box, err = secrets.Open(c.SecretsKeyFile); err != nil {
+				return fmt.Errorf("%s is encrypted: %w", key, err)
+			}
+		}
+		plain, err := box.Decrypt(*field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		*field = plain
+	}
+	return nil
+}
+
+// SaveEnv writes updates to the .env file. With ENCRYPT_SECRETS on, secret
+// values are encrypted first.
+func (c *Config) SaveEnv(updates map[string]string) error {
+	if c.EncryptSecrets {
+		box, err := secrets.Open(c.SecretsKeyFile)
+		if err != nil {
+			return err
+		}
+		encrypted := make(map[string]string, len(updates))
+		for key, value := range updates {
+			encrypted[key] = value
+			if _, ok := c.secretFields()[key]; ok && value != "" {
+				if encrypted[key], err = box.Encrypt(value); err != nil {
+					return err
+				}
+			}
+		}
+		updates = encrypted
+	}
+	return UpdateEnvFile(c.EnvFile, updates)
+}
+
+// EncryptEnvFile encrypts every secret set in the .env file in place and
+// returns the keys it encrypted
+func (c *Config) EncryptEnvFile() ([]string, error) {
+	box, err := secrets.Open(c.SecretsKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := godotenv.Read(c.EnvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]string)
+	var keys []string
+	for _, key := range SecretKeys {
+		value := raw[key]
+		if value == "" || secrets.IsEncrypted(value) {
+			continue
+		}
+		if updates[key], err = box.Encrypt(value); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	return keys, UpdateEnvFile(c.EnvFile, updates)
+}
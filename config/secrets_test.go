@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/secrets"
+)
+
+func writeKeyFile(t *testing.T) string {
+	keyFile := filepath.Join(t.TempDir(), "secrets.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("test-machine-secret"), 0600))
+	return keyFile
+}
+
+func TestLoadDecryptsSecrets(t *testing.T) {
+	keyFile := writeKeyFile(t)
+	box, err := secrets.Open(keyFile)
+	require.NoError(t, err)
+	enc, err := box.Encrypt("mqtt-pass")
+	require.NoError(t, err)
+
+	t.Setenv("API_KEY", "my-test-key")
+	t.Setenv("SECRETS_KEY_FILE", keyFile)
+	t.Setenv("MQTT_PASSWORD", enc)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "mqtt-pass", cfg.MQTTPassword)
+
+	// Without the key the agent refuses to start rather than use ciphertext
+	t.Setenv("SECRETS_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestSaveEnvEncryptsSecrets(t *testing.T) {
+	cfg := LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	cfg.SecretsKeyFile = writeKeyFile(t)
+	cfg.EncryptSecrets = true
+
+	require.NoError(t, cfg.SaveEnv(map[string]string{
+		"MQTT_PASSWORD": "mqtt-pass",
+		"MQTT_BROKER":   "tcp://broker.lan:1883",
+	}))
+
+	raw, err := godotenv.Read(cfg.EnvFile)
+	require.NoError(t, err)
+	assert.True(t, secrets.IsEncrypted(raw["MQTT_PASSWORD"]))
+	assert.Equal(t, "tcp://broker.lan:1883", raw["MQTT_BROKER"])
+}
+
+func TestEncryptEnvFile(t *testing.T) {
+	cfg := LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	cfg.SecretsKeyFile = writeKeyFile(t)
+	require.NoError(t, os.WriteFile(cfg.EnvFile, []byte("# Agent\nAPI_KEY=key\nPULL_SECRET=hmac\nSENTRY_DSN=\n"), 0600))
+
+	keys, err := cfg.EncryptEnvFile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PULL_SECRET"}, keys)
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "# Agent\nAPI_KEY=key\nPULL_SECRET=enc:v1:"))
+	assert.NotContains(t, string(data), "hmac")
+
+	// Running it again finds nothing left to encrypt
+	keys, err = cfg.EncryptEnvFile()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
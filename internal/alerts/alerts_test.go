@@ -0,0 +1,175 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("high-cpu", "cpu>90@5m")
+	require.NoError(t, err)
+	assert.Equal(t, Rule{ID: "high-cpu", Name: "high-cpu", Metric: MetricCPU, Operator: ">", Threshold: 90, For: "5m", Severity: SeverityWarning, Source: SourceConfig}, rule)
+
+	rule, err = ParseRule("root", "disk:/>=95.5")
+	require.NoError(t, err)
+	assert.Equal(t, "/", rule.Target)
+	assert.Equal(t, ">=", rule.Operator)
+	assert.Equal(t, 95.5, rule.Threshold)
+
+	rule, err = ParseRule("tty", "service:getty@tty1@1m")
+	require.NoError(t, err)
+	assert.Equal(t, "getty@tty1", rule.Target)
+	assert.Equal(t, "1m", rule.For)
+
+	for _, spec := range []string{"cpu", "cpu>x", "service:nginx>1", "container:", "fan>1", "cpu>90@48h"} {
+		_, err := ParseRule("bad", spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseSinks(t *testing.T) {
+	sinks, err := ParseSinks(map[string]string{
+		"ops":   "slack:https://hooks.slack.com/services/x",
+		"phone": "telegram:123:ABC/-1001",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "123:ABC", sinks["phone"].token)
+	assert.Equal(t, "-1001", sinks["phone"].chatID)
+
+	_, err = ParseSinks(map[string]string{"x": "email:root@localhost"})
+	assert.Error(t, err)
+	_, err = ParseSinks(map[string]string{"x": "ntfy:ntfy.sh/topic"})
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	received := make(chan Notification, 4)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		received <- n
+	}))
+	defer ts.Close()
+
+	sinks, err := ParseSinks(map[string]string{"hook": "webhook:" + ts.URL})
+	require.NoError(t, err)
+	rules, err := ParseRules(map[string]string{
+		"high-cpu": "cpu>90",
+		"slow":     "memory>50@1h",
+		"web":      "container:web",
+	})
+	require.NoError(t, err)
+
+	cpu := 95.0
+	containerErr := errors.New("docker is not available")
+	bus := events.NewBus(10)
+	m := NewManager(Options{Rules: rules, Sinks: sinks}, Sources{
+		Metrics: func() (*system.AllMetrics, error) {
+			return &system.AllMetrics{CPU: system.CPUInfo{UsageTotal: cpu}, Memory: system.MemoryInfo{UsedPercent: 80}}, nil
+		},
+		ContainerState: func(ctx context.Context, name string) (string, error) { return "", containerErr },
+	}, bus)
+
+	m.Check(context.Background())
+	n := <-received
+	assert.Equal(t, "high-cpu", n.Rule)
+	assert.Equal(t, StateFiring, n.State)
+	assert.Equal(t, "CPU usage is 95.0% (> 90.0%)", n.Message)
+
+	list := m.List()
+	assert.Equal(t, 3, list.Total)
+	assert.Equal(t, 1, list.Firing)
+	assert.Equal(t, StateFiring, list.Alerts[0].State)
+	assert.Equal(t, StatePending, list.Alerts[1].State) // Not for an hour yet
+	assert.Equal(t, StateOK, list.Alerts[2].State)
+	assert.Equal(t, "docker is not available", list.Alerts[2].Error)
+
+	// Still firing: no second notification
+	m.Check(context.Background())
+	cpu = 10
+	m.Check(context.Background())
+	n = <-received
+	assert.Equal(t, "resolved", n.State)
+	assert.Equal(t, "CPU usage is 10.0% (> 90.0%)", n.Message)
+
+	recent := bus.Recent(10, "alert.").Events
+	require.Len(t, recent, 2)
+	assert.Equal(t, "alert.resolved", recent[0].Type)
+	assert.Equal(t, "alert.firing", recent[1].Type)
+}
+
+func TestSinkFormats(t *testing.T) {
+	var path, body, title string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		path, body, title = r.URL.Path, string(data), r.Header.Get("Title")
+	}))
+	defer ts.Close()
+	orig := telegramAPI
+	telegramAPI = ts.URL
+	defer func() { telegramAPI = orig }()
+
+	n := Notification{Rule: "root", State: StateFiring, Severity: SeverityCritical, Host: "nas", Message: "Disk usage of / is 97.0%"}
+	send := func(spec string) {
+		sinks, err := ParseSinks(map[string]string{"s": spec})
+		require.NoError(t, err)
+		require.NoError(t, sinks["s"].Send(context.Background(), ts.Client(), n))
+	}
+
+	send("slack:" + ts.URL + "/slack")
+	assert.JSONEq(t, `{"text":"[CRITICAL] root on nas\nDisk usage of / is 97.0%"}`, body)
+
+	send("telegram:123:ABC/42")
+	assert.Equal(t, "/bot123:ABC/sendMessage", path)
+	assert.JSONEq(t, `{"chat_id":"42","text":"[CRITICAL] root on nas\nDisk usage of / is 97.0%"}`, body)
+
+	send("ntfy:" + ts.URL + "/alerts")
+	assert.Equal(t, "Disk usage of / is 97.0%", body)
+	assert.Equal(t, "[CRITICAL] root on nas", title)
+}
+
+func TestManagerRules(t *testing.T) {
+	dir := t.TempDir()
+	configured, err := ParseRules(map[string]string{"high-cpu": "cpu>90"})
+	require.NoError(t, err)
+	opts := Options{Rules: configured, DataDir: dir}
+	m := NewManager(opts, Sources{}, events.NewBus(10))
+
+	rule, err := m.Add(Rule{Name: "nginx", Metric: MetricService, Target: "nginx", Severity: SeverityCritical})
+	require.NoError(t, err)
+	assert.Equal(t, SourceAPI, rule.Source)
+
+	_, err = m.Add(Rule{Name: "high-cpu", Metric: MetricCPU, Operator: ">", Threshold: 1})
+	assert.ErrorIs(t, err, apierror.ErrConflict)
+	_, err = m.Add(Rule{Name: "x", Metric: MetricCPU, Operator: ">", Sinks: []string{"nowhere"}})
+	assert.ErrorIs(t, err, apierror.ErrInvalid)
+
+	rule.Disabled = true
+	_, err = m.Update(rule.ID, *rule)
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.Delete("high-cpu"), apierror.ErrNotAllowed)
+	assert.ErrorIs(t, m.Delete("missing"), apierror.ErrNotFound)
+
+	// Added rules survive a restart
+	reloaded := NewManager(opts, Sources{}, events.NewBus(10))
+	status, err := reloaded.Get(rule.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Disabled)
+	assert.Equal(t, 2, reloaded.List().Total)
+
+	require.NoError(t, reloaded.Delete(rule.ID))
+	_, err = reloaded.Get(rule.ID)
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
@@ -0,0 +1,488 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// sendTimeout bounds one notification
+const sendTimeout = 10 * time.Second
+
+// Sources read what rules watch
+type Sources struct {
+	Metrics        func() (*system.AllMetrics, error)
+	ServiceState   func(ctx context.Context, name string) (string, error) // systemd ActiveState
+	ContainerState func(ctx context.Context, name string) (string, error) // Docker state
+}
+
+// Options configures a Manager
+type Options struct {
+	Rules    []Rule // From ALERT_RULES
+	Sinks    map[string]*Sink
+	Interval time.Duration
+	DataDir  string // Rules added through the API are kept here
+}
+
+// Manager checks alert rules every interval and notifies sinks when one
+// fires or resolves
+type Manager struct {
+	sources  Sources
+	sinks    map[string]*Sink
+	interval time.Duration
+	bus      *events.Bus
+	http     *http.Client
+	host     string
+	file     string
+
+	mu     sync.Mutex
+	config []Rule // Read-only
+	custom []Rule
+	states map[string]*Status
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewManager creates a manager, loading rules saved in the data directory.
+// Fired and resolved alerts are also published to bus.
+func NewManager(opts Options, sources Sources, bus *events.Bus) *Manager {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	host, _ := os.Hostname()
+
+	m := &Manager{
+		sources:  sources,
+		sinks:    opts.Sinks,
+		interval: opts.Interval,
+		bus:      bus,
+		http:     &http.Client{Timeout: sendTimeout},
+		host:     host,
+		config:   opts.Rules,
+		states:   make(map[string]*Status),
+		stop:     make(chan struct{}),
+	}
+	if m.sinks == nil {
+		m.sinks = map[string]*Sink{}
+	}
+	for _, rule := range m.config {
+		if err := m.checkSinks(rule); err != nil {
+			log.Printf("Alert rule %s: %v", rule.Name, err)
+		}
+	}
+	if opts.DataDir != "" {
+		m.file = filepath.Join(opts.DataDir, "alerts.json")
+		m.load()
+	}
+	return m
+}
+
+// Start checks every interval until Stop is called
+func (m *Manager) Start() {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+				m.Check(ctx)
+				cancel()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the checks
+func (m *Manager) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// List returns every rule with its state
+func (m *Manager) List() *AlertList {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := &AlertList{
+		Alerts:   []Status{},
+		Sinks:    sortedSinks(m.sinks),
+		Interval: m.interval.String(),
+	}
+	for _, rule := range m.rules() {
+		status := m.status(rule)
+		list.Alerts = append(list.Alerts, status)
+		if status.State == StateFiring {
+			list.Firing++
+		}
+	}
+	list.Total = len(list.Alerts)
+	return list
+}
+
+// Get returns one rule with its state
+func (m *Manager) Get(id string) (*Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rule := range m.rules() {
+		if rule.ID == id {
+			status := m.status(rule)
+			return &status, nil
+		}
+	}
+	return nil, apierror.NotFound("alert rule %s not found", id)
+}
+
+// Add stores a new rule
+func (m *Manager) Add(rule Rule) (*Rule, error) {
+	if err := validate(&rule); err != nil {
+		return nil, err
+	}
+	if err := m.checkSinks(rule); err != nil {
+		return nil, err
+	}
+	rule.ID, rule.Source, rule.CreatedAt = newID(), SourceAPI, time.Now().UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.custom) >= MaxRules {
+		return nil, apierror.Invalid("at most %d alert rules can be added", MaxRules)
+	}
+	if err := m.checkName(rule); err != nil {
+		return nil, err
+	}
+	m.custom = append(m.custom, rule)
+	return &rule, m.save()
+}
+
+// Update replaces a rule added through the API. Its state starts over.
+func (m *Manager) Update(id string, rule Rule) (*Rule, error) {
+	if err := validate(&rule); err != nil {
+		return nil, err
+	}
+	if err := m.checkSinks(rule); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.customIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	rule.ID, rule.Source, rule.CreatedAt = id, SourceAPI, m.custom[i].CreatedAt
+	if err := m.checkName(rule); err != nil {
+		return nil, err
+	}
+	m.custom[i] = rule
+	delete(m.states, id)
+	return &rule, m.save()
+}
+
+// Delete removes a rule added through the API
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.customIndex(id)
+	if err != nil {
+		return err
+	}
+	m.custom = append(m.custom[:i], m.custom[i+1:]...)
+	delete(m.states, id)
+	return m.save()
+}
+
+// Test sends a test notification to a sink
+func (m *Manager) Test(ctx context.Context, name string) error {
+	sink, ok := m.sinks[name]
+	if !ok {
+		return apierror.NotFound("alert sink %s not configured", name)
+	}
+	return sink.Send(ctx, m.http, Notification{
+		RuleID:   "test",
+		Rule:     "test",
+		State:    StateFiring,
+		Severity: SeverityWarning,
+		Host:     m.host,
+		Message:  "Test notification from hivedeck-agent",
+		Time:     time.Now().UTC(),
+	})
+}
+
+// Check evaluates every enabled rule once and notifies sinks of changes
+func (m *Manager) Check(ctx context.Context) {
+	m.mu.Lock()
+	rules := m.rules()
+	m.mu.Unlock()
+
+	var metrics *system.AllMetrics
+	var metricsErr error
+	collected := false
+
+	var notifications []Notification
+	for _, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+
+		var value *float64
+		var breach bool
+		var detail string
+		var err error
+
+		switch rule.Metric {
+		case MetricService, MetricContainer:
+			breach, detail, err = m.observeState(ctx, rule)
+		default:
+			if !collected {
+				metrics, metricsErr = m.sources.Metrics()
+				collected = true
+			}
+			if err = metricsErr; err == nil {
+				var v float64
+				var label string
+				if v, label, err = rule.metricValue(metrics); err == nil {
+					value, breach = &v, rule.compare(v)
+					detail = fmt.Sprintf("%s is %s (%s %s)", label, rule.format(v), rule.Operator, rule.format(rule.Threshold))
+				}
+			}
+		}
+
+		if n := m.transition(rule, value, breach, detail, err); n != nil {
+			notifications = append(notifications, *n)
+		}
+	}
+
+	for _, n := range notifications {
+		m.notify(n)
+	}
+}
+
+// observeState checks a service or container
+func (m *Manager) observeState(ctx context.Context, rule Rule) (bool, string, error) {
+	if rule.Metric == MetricService {
+		if m.sources.ServiceState == nil {
+			return false, "", fmt.Errorf("services are not available")
+		}
+		state, err := m.sources.ServiceState(ctx, rule.Target)
+		if err != nil {
+			return false, "", err
+		}
+		return state != "active", fmt.Sprintf("Service %s is %s", rule.Target, state), nil
+	}
+
+	if m.sources.ContainerState == nil {
+		return false, "", fmt.Errorf("docker is not available")
+	}
+	state, err := m.sources.ContainerState(ctx, rule.Target)
+	if err != nil {
+		return false, "", err
+	}
+	return state != "running", fmt.Sprintf("Container %s is %s", rule.Target, state), nil
+}
+
+// transition moves a rule through ok, pending and firing, returning a
+// notification when it fires or resolves. A failed check keeps the state.
+func (m *Manager) transition(rule Rule, value *float64, breach bool, detail string, err error) *Notification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.states[rule.ID]
+	if !ok {
+		st = &Status{State: StateOK}
+		m.states[rule.ID] = st
+	}
+	now := time.Now().UTC()
+	st.CheckedAt = &now
+	if err != nil {
+		st.Error = err.Error()
+		return nil
+	}
+	st.Error, st.Value, st.Detail = "", value, detail
+
+	var changed string
+	if breach {
+		if st.State == StateOK {
+			st.State, st.Since = StatePending, &now
+		}
+		if st.State == StatePending && now.Sub(*st.Since) >= rule.duration() {
+			st.State, st.Since = StateFiring, &now
+			changed = StateFiring
+		}
+	} else if st.State != StateOK {
+		if st.State == StateFiring {
+			changed = "resolved"
+		}
+		st.State, st.Since = StateOK, &now
+	}
+
+	if changed == "" {
+		return nil
+	}
+	return &Notification{
+		RuleID:   rule.ID,
+		Rule:     rule.Name,
+		State:    changed,
+		Severity: rule.Severity,
+		Host:     m.host,
+		Message:  detail,
+		Value:    value,
+		Time:     now,
+		sinks:    rule.Sinks,
+	}
+}
+
+// notify publishes an event and sends to the rule's sinks in the
+// background
+func (m *Manager) notify(n Notification) {
+	event := events.Event{
+		Type:     "alert.firing",
+		Severity: n.Severity,
+		Source:   n.Rule,
+		Message:  fmt.Sprintf("%s: %s", n.Rule, n.Message),
+		Data:     n,
+	}
+	if n.State != StateFiring {
+		event.Type, event.Severity = "alert.resolved", events.SeverityInfo
+	}
+	m.bus.Publish(event)
+
+	for _, sink := range m.targets(n.sinks) {
+		go func(sink *Sink) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := sink.Send(ctx, m.http, n); err != nil {
+				log.Printf("Alert %s: %v", n.Rule, err)
+			}
+		}(sink)
+	}
+}
+
+// targets returns the named sinks, or all of them
+func (m *Manager) targets(names []string) []*Sink {
+	if len(names) == 0 {
+		return sortedSinks(m.sinks)
+	}
+	var sinks []*Sink
+	for _, name := range names {
+		if sink, ok := m.sinks[name]; ok {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// rules returns configured rules followed by added ones. The caller holds mu.
+func (m *Manager) rules() []Rule {
+	rules := make([]Rule, 0, len(m.config)+len(m.custom))
+	rules = append(rules, m.config...)
+	return append(rules, m.custom...)
+}
+
+// status returns a rule with its state. The caller holds mu.
+func (m *Manager) status(rule Rule) Status {
+	status := Status{Rule: rule, State: StateOK}
+	if st, ok := m.states[rule.ID]; ok {
+		status.State, status.Value, status.Detail = st.State, st.Value, st.Detail
+		status.Since, status.Error, status.CheckedAt = st.Since, st.Error, st.CheckedAt
+	}
+	return status
+}
+
+func (m *Manager) checkSinks(rule Rule) error {
+	for _, name := range rule.Sinks {
+		if _, ok := m.sinks[name]; !ok {
+			return apierror.Invalid("unknown sink %q", name)
+		}
+	}
+	return nil
+}
+
+// checkName rejects a name another rule has. The caller holds mu.
+func (m *Manager) checkName(rule Rule) error {
+	for _, other := range m.rules() {
+		if other.Name == rule.Name && other.ID != rule.ID {
+			return apierror.Conflict("an alert rule named %s exists", rule.Name)
+		}
+	}
+	return nil
+}
+
+// customIndex finds a rule added through the API. The caller holds mu.
+func (m *Manager) customIndex(id string) (int, error) {
+	for i, rule := range m.custom {
+		if rule.ID == id {
+			return i, nil
+		}
+	}
+	for _, rule := range m.config {
+		if rule.ID == id {
+			return -1, apierror.NotAllowed("alert rule %s is set in ALERT_RULES", id)
+		}
+	}
+	return -1, apierror.NotFound("alert rule %s not found", id)
+}
+
+// save writes the added rules to a temporary file and renames it into
+// place. The caller holds mu.
+func (m *Manager) save() error {
+	if m.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.custom, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.file), 0750); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	tmp := m.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return fmt.Errorf("failed to write alert rules: %w", err)
+	}
+	if err := os.Rename(tmp, m.file); err != nil {
+		return fmt.Errorf("failed to write alert rules: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) load() {
+	data, err := os.ReadFile(m.file)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &m.custom); err != nil {
+		log.Printf("Failed to read alert rules: %v", err)
+		m.custom = nil
+		return
+	}
+	sort.SliceStable(m.custom, func(i, j int) bool { return m.custom[i].CreatedAt.Before(m.custom[j].CreatedAt) })
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
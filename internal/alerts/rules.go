@@ -0,0 +1,188 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+const (
+	// MaxRules bounds the rules added through the API
+	MaxRules = 100
+	// MaxFor bounds how long a condition can be required to hold
+	MaxFor = 24 * time.Hour
+
+	maxNameLength = 64
+)
+
+var numericMetrics = map[string]string{
+	MetricCPU:    "CPU usage",
+	MetricMemory: "Memory usage",
+	MetricSwap:   "Swap usage",
+	MetricDisk:   "Disk usage",
+	MetricLoad:   "Load average",
+}
+
+var operators = []string{">=", "<=", ">", "<"}
+
+// ParseRules builds rules from name=spec pairs, as in ALERT_RULES. A spec
+// is metric[:target][operator threshold][@duration], e.g. cpu>90@5m,
+// disk:/>95 or service:nginx@1m.
+func ParseRules(specs map[string]string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(specs))
+	for name, spec := range specs {
+		rule, err := ParseRule(name, spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+// ParseRule builds one configured rule
+func ParseRule(name, spec string) (Rule, error) {
+	rule := Rule{ID: name, Name: name, Source: SourceConfig}
+
+	// Template units contain @ too, so only a valid duration counts
+	if i := strings.LastIndexByte(spec, '@'); i >= 0 {
+		if _, err := time.ParseDuration(spec[i+1:]); err == nil {
+			spec, rule.For = spec[:i], spec[i+1:]
+		}
+	}
+
+	if i := strings.IndexAny(spec, "<>"); i >= 0 {
+		rule.Operator = spec[i : i+1]
+		if strings.HasPrefix(spec[i+1:], "=") {
+			rule.Operator += "="
+		}
+		threshold, err := strconv.ParseFloat(spec[i+len(rule.Operator):], 64)
+		if err != nil {
+			return Rule{}, fmt.Errorf("alert rule %s: invalid threshold in %q", name, spec)
+		}
+		spec, rule.Threshold = spec[:i], threshold
+	}
+	rule.Metric, rule.Target, _ = strings.Cut(spec, ":")
+
+	if err := validate(&rule); err != nil {
+		return Rule{}, fmt.Errorf("alert rule %s: %w", name, err)
+	}
+	return rule, nil
+}
+
+// validate checks a rule and fills in defaults
+func validate(rule *Rule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return apierror.Invalid("name is required")
+	}
+	if len(rule.Name) > maxNameLength {
+		return apierror.Invalid("name exceeds %d characters", maxNameLength)
+	}
+
+	switch _, numeric := numericMetrics[rule.Metric]; {
+	case numeric:
+		if !validOperator(rule.Operator) {
+			return apierror.Invalid("metric %s needs an operator: >, >=, < or <=", rule.Metric)
+		}
+	case rule.Metric == MetricService || rule.Metric == MetricContainer:
+		if rule.Target == "" {
+			return apierror.Invalid("metric %s needs a target", rule.Metric)
+		}
+		if rule.Operator != "" {
+			return apierror.Invalid("metric %s takes no threshold", rule.Metric)
+		}
+	default:
+		return apierror.Invalid("unknown metric %q: use cpu, memory, swap, disk, load, service or container", rule.Metric)
+	}
+
+	if rule.For != "" {
+		d, err := time.ParseDuration(rule.For)
+		if err != nil || d < 0 || d > MaxFor {
+			return apierror.Invalid("for must be a duration up to %s, e.g. 5m", MaxFor)
+		}
+	}
+
+	switch rule.Severity {
+	case "":
+		rule.Severity = SeverityWarning
+	case SeverityWarning, SeverityCritical:
+	default:
+		return apierror.Invalid("severity must be warning or critical")
+	}
+	return nil
+}
+
+func validOperator(op string) bool {
+	for _, o := range operators {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// duration returns how long the condition must hold
+func (r *Rule) duration() time.Duration {
+	d, _ := time.ParseDuration(r.For)
+	return d
+}
+
+// compare reports whether value breaches the threshold
+func (r *Rule) compare(value float64) bool {
+	switch r.Operator {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// metricValue reads a numeric metric from a snapshot
+func (r *Rule) metricValue(metrics *system.AllMetrics) (float64, string, error) {
+	label := numericMetrics[r.Metric]
+	switch r.Metric {
+	case MetricCPU:
+		return metrics.CPU.UsageTotal, label, nil
+	case MetricMemory:
+		return metrics.Memory.UsedPercent, label, nil
+	case MetricSwap:
+		return metrics.Memory.SwapPercent, label, nil
+	case MetricLoad:
+		return metrics.CPU.LoadAvg1, label, nil
+	case MetricDisk:
+		var fullest *system.DiskPartition
+		for i, p := range metrics.Disk.Partitions {
+			if r.Target != "" && p.Mountpoint == r.Target {
+				return p.UsedPercent, label + " of " + p.Mountpoint, nil
+			}
+			if r.Target == "" && (fullest == nil || p.UsedPercent > fullest.UsedPercent) {
+				fullest = &metrics.Disk.Partitions[i]
+			}
+		}
+		if fullest == nil {
+			return 0, "", fmt.Errorf("no disk mounted at %q", r.Target)
+		}
+		return fullest.UsedPercent, label + " of " + fullest.Mountpoint, nil
+	}
+	return 0, "", fmt.Errorf("metric %s is not numeric", r.Metric)
+}
+
+// format renders a metric value with its unit
+func (r *Rule) format(value float64) string {
+	if r.Metric == MetricLoad {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + "%"
+}
@@ -0,0 +1,151 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Sink types
+const (
+	SinkWebhook  = "webhook"  // POST the notification as JSON
+	SinkSlack    = "slack"    // Slack incoming webhook
+	SinkTelegram = "telegram" // Telegram bot: <token>/<chat id>
+	SinkNtfy     = "ntfy"     // ntfy topic URL
+)
+
+// telegramAPI is the Bot API base URL; replaced in tests
+var telegramAPI = "https://api.telegram.org"
+
+// Sink is a notification destination. Its address holds credentials, so
+// only the name and type are exposed.
+type Sink struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	url    string // Webhook, Slack or ntfy URL
+	token  string // Telegram bot token
+	chatID string
+}
+
+// ParseSinks builds sinks from name=type:address pairs, as in ALERT_SINKS
+func ParseSinks(specs map[string]string) (map[string]*Sink, error) {
+	sinks := make(map[string]*Sink, len(specs))
+	for name, spec := range specs {
+		kind, address, _ := strings.Cut(spec, ":")
+		sink := &Sink{Name: name, Type: kind}
+
+		switch kind {
+		case SinkWebhook, SinkSlack, SinkNtfy:
+			u, err := url.Parse(address)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("alert sink %s: invalid URL", name)
+			}
+			sink.url = address
+		case SinkTelegram:
+			sink.token, sink.chatID, _ = strings.Cut(address, "/")
+			if sink.token == "" || sink.chatID == "" {
+				return nil, fmt.Errorf("alert sink %s: use telegram:<bot token>/<chat id>", name)
+			}
+		default:
+			return nil, fmt.Errorf("alert sink %s: type must be webhook, slack, telegram or ntfy", name)
+		}
+		sinks[name] = sink
+	}
+	return sinks, nil
+}
+
+// sortedSinks returns sinks sorted by name
+func sortedSinks(sinks map[string]*Sink) []*Sink {
+	list := make([]*Sink, 0, len(sinks))
+	for _, s := range sinks {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Send delivers a notification
+func (s *Sink) Send(ctx context.Context, client *http.Client, n Notification) error {
+	var req *http.Request
+	var err error
+
+	switch s.Type {
+	case SinkWebhook:
+		req, err = jsonRequest(ctx, s.url, n)
+	case SinkSlack:
+		req, err = jsonRequest(ctx, s.url, map[string]string{"text": text(n)})
+	case SinkTelegram:
+		req, err = jsonRequest(ctx, telegramAPI+"/bot"+s.token+"/sendMessage", map[string]string{
+			"chat_id": s.chatID,
+			"text":    text(n),
+		})
+	case SinkNtfy:
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(n.Message))
+		if err == nil {
+			req.Header.Set("Title", title(n))
+			req.Header.Set("Tags", ntfyTag(n))
+			if n.State == StateFiring && n.Severity == SeverityCritical {
+				req.Header.Set("Priority", "high")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q", s.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("sink %s: %w", s.Name, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error includes the URL, which may hold a token
+		return fmt.Errorf("sink %s: request failed", s.Name)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sink %s: HTTP %d", s.Name, resp.StatusCode)
+	}
+	return nil
+}
+
+func jsonRequest(ctx context.Context, target string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func title(n Notification) string {
+	if n.State == StateFiring {
+		return fmt.Sprintf("[%s] %s on %s", strings.ToUpper(n.Severity), n.Rule, n.Host)
+	}
+	return fmt.Sprintf("[RESOLVED] %s on %s", n.Rule, n.Host)
+}
+
+func text(n Notification) string {
+	return title(n) + "\n" + n.Message
+}
+
+func ntfyTag(n Notification) string {
+	switch {
+	case n.State != StateFiring:
+		return "white_check_mark"
+	case n.Severity == SeverityCritical:
+		return "rotating_light"
+	}
+	return "warning"
+}
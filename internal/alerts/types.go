@@ -0,0 +1,84 @@
+package alerts
+
+import "time"
+
+// Metrics a rule can watch
+const (
+	MetricCPU       = "cpu"       // CPU usage percent
+	MetricMemory    = "memory"    // Memory used percent
+	MetricSwap      = "swap"      // Swap used percent
+	MetricDisk      = "disk"      // Used percent of the target mountpoint, or the fullest
+	MetricLoad      = "load"      // 1-minute load average
+	MetricService   = "service"   // The target service is not active
+	MetricContainer = "container" // The target container is not running
+)
+
+// Severities
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Where a rule comes from
+const (
+	SourceConfig = "config" // ALERT_RULES; read-only through the API
+	SourceAPI    = "api"
+)
+
+// States of a rule
+const (
+	StateOK      = "ok"
+	StatePending = "pending" // Breached, but not for the rule's duration yet
+	StateFiring  = "firing"
+)
+
+// Rule is an alert condition
+type Rule struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Metric    string    `json:"metric"`
+	Target    string    `json:"target,omitempty"`    // Mountpoint, service or container
+	Operator  string    `json:"operator,omitempty"`  // >, >=, < or <= for numeric metrics
+	Threshold float64   `json:"threshold,omitempty"` // Compared with the metric
+	For       string    `json:"for,omitempty"`       // How long the condition must hold, e.g. 5m
+	Severity  string    `json:"severity"`
+	Sinks     []string  `json:"sinks,omitempty"` // Empty notifies every sink
+	Disabled  bool      `json:"disabled,omitempty"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// Status is a rule and its current state
+type Status struct {
+	Rule
+	State     string     `json:"state"`
+	Value     *float64   `json:"value,omitempty"`  // Latest value of a numeric metric
+	Detail    string     `json:"detail,omitempty"` // Latest observation, e.g. "CPU usage is 93.2%"
+	Since     *time.Time `json:"since,omitempty"`  // When State last changed
+	Error     string     `json:"error,omitempty"`  // Why the latest check failed
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// AlertList is every rule's status
+type AlertList struct {
+	Alerts   []Status `json:"alerts"`
+	Total    int      `json:"total"`
+	Firing   int      `json:"firing"`
+	Sinks    []*Sink  `json:"sinks"`
+	Interval string   `json:"interval"`
+}
+
+// Notification is sent to sinks when a rule fires or resolves. Webhook
+// sinks receive it as JSON.
+type Notification struct {
+	RuleID   string    `json:"rule_id"`
+	Rule     string    `json:"rule"`
+	State    string    `json:"state"` // firing or resolved
+	Severity string    `json:"severity"`
+	Host     string    `json:"host"`
+	Message  string    `json:"message"`
+	Value    *float64  `json:"value,omitempty"`
+	Time     time.Time `json:"time"`
+
+	sinks []string // The rule's sinks
+}
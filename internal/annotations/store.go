@@ -0,0 +1,240 @@
+package annotations
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// MaxAnnotations is the number kept; the oldest are dropped beyond it
+	MaxAnnotations = 10000
+	// MaxTextLength bounds a note's text
+	MaxTextLength = 2000
+	// MaxTags bounds the tags on one note
+	MaxTags = 10
+)
+
+// Store keeps annotations in memory and in annotations.json under the data
+// directory
+type Store struct {
+	file  string
+	items []Annotation // Sorted by Time
+	mu    sync.RWMutex
+}
+
+// NewStore creates a store, loading saved annotations from dataDir. With an
+// empty dataDir annotations last until restart.
+func NewStore(dataDir string) *Store {
+	s := &Store{}
+	if dataDir != "" {
+		s.file = filepath.Join(dataDir, "annotations.json")
+		s.load()
+	}
+	return s
+}
+
+// List returns annotations matching f, oldest first
+func (s *Store) List(f Filter) *AnnotationList {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := []Annotation{}
+	for _, a := range s.items {
+		if (!f.From.IsZero() && a.Time.Before(f.From)) || (!f.To.IsZero() && a.Time.After(f.To)) {
+			continue
+		}
+		if f.Tag != "" && !hasTag(a.Tags, f.Tag) {
+			continue
+		}
+		matched = append(matched, a)
+	}
+
+	total := len(matched)
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+	return &AnnotationList{Annotations: matched, Total: total}
+}
+
+// Get returns one annotation
+func (s *Store) Get(id string) (*Annotation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return nil, apierror.NotFound("annotation %s not found", id)
+	}
+	a := s.items[i]
+	return &a, nil
+}
+
+// Add stores a new annotation. A zero Time means now.
+func (s *Store) Add(text string, at time.Time, tags []string) (*Annotation, error) {
+	text, tags, err := validate(text, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if at.IsZero() {
+		at = now
+	}
+	a := Annotation{ID: newID(), Time: at, Text: text, Tags: tags, CreatedAt: now}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items = append(s.items, a)
+	s.sort()
+	if len(s.items) > MaxAnnotations {
+		s.items = s.items[len(s.items)-MaxAnnotations:]
+	}
+	return &a, s.save()
+}
+
+// Update changes an annotation's time, text or tags
+func (s *Store) Update(id string, u Update) (*Annotation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return nil, apierror.NotFound("annotation %s not found", id)
+	}
+
+	a := s.items[i]
+	if u.Text != nil {
+		a.Text = *u.Text
+	}
+	if u.Tags != nil {
+		a.Tags = *u.Tags
+	}
+	if u.Time != nil && !u.Time.IsZero() {
+		a.Time = *u.Time
+	}
+	text, tags, err := validate(a.Text, a.Tags)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	a.Text, a.Tags, a.UpdatedAt = text, tags, &now
+
+	s.items[i] = a
+	s.sort()
+	return &a, s.save()
+}
+
+// Delete removes an annotation
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return apierror.NotFound("annotation %s not found", id)
+	}
+	s.items = append(s.items[:i], s.items[i+1:]...)
+	return s.save()
+}
+
+// index returns the position of id, or -1. The caller holds mu.
+func (s *Store) index(id string) int {
+	for i, a := range s.items {
+		if a.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) sort() {
+	sort.SliceStable(s.items, func(i, j int) bool { return s.items[i].Time.Before(s.items[j].Time) })
+}
+
+// save writes all annotations to a temporary file and renames it into
+// place. The caller holds mu.
+func (s *Store) save() error {
+	if s.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), 0750); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	if err := os.Rename(tmp, s.file); err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) load() {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.items); err != nil {
+		log.Printf("Failed to read annotations: %v", err)
+		s.items = nil
+		return
+	}
+	s.sort()
+}
+
+// validate trims text and tags and checks their limits
+func validate(text string, tags []string) (string, []string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", nil, apierror.Invalid("text is required")
+	}
+	if len(text) > MaxTextLength {
+		return "", nil, apierror.Invalid("text exceeds %d characters", MaxTextLength)
+	}
+
+	var clean []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !hasTag(clean, tag) {
+			clean = append(clean, tag)
+		}
+	}
+	if len(clean) > MaxTags {
+		return "", nil, apierror.Invalid("at most %d tags are allowed", MaxTags)
+	}
+	return text, clean, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+package annotations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+
+	jan := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	mar := time.Date(2024, 3, 2, 9, 30, 0, 0, time.UTC)
+
+	psu, err := s.Add("  replaced PSU ", mar, []string{"hardware", "hardware", " "})
+	require.NoError(t, err)
+	assert.Equal(t, "replaced PSU", psu.Text)
+	assert.Equal(t, []string{"hardware"}, psu.Tags)
+
+	_, err = s.Add("upgraded to bookworm", jan, []string{"os"})
+	require.NoError(t, err)
+
+	// Listed by when they happened, not when they were added
+	list := s.List(Filter{})
+	require.Equal(t, 2, list.Total)
+	assert.Equal(t, "upgraded to bookworm", list.Annotations[0].Text)
+
+	assert.Equal(t, 1, s.List(Filter{From: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}).Total)
+	assert.Equal(t, 1, s.List(Filter{Tag: "os"}).Total)
+	assert.Equal(t, "replaced PSU", s.List(Filter{Limit: 1}).Annotations[0].Text)
+
+	text := "replaced PSU with a 650W unit"
+	updated, err := s.Update(psu.ID, Update{Text: &text})
+	require.NoError(t, err)
+	assert.Equal(t, text, updated.Text)
+	assert.Equal(t, mar, updated.Time)
+	assert.NotNil(t, updated.UpdatedAt)
+
+	// Saved annotations are loaded again
+	reloaded := NewStore(dir)
+	got, err := reloaded.Get(psu.ID)
+	require.NoError(t, err)
+	assert.Equal(t, text, got.Text)
+
+	require.NoError(t, reloaded.Delete(psu.ID))
+	assert.Equal(t, 1, NewStore(dir).List(Filter{}).Total)
+	assert.True(t, errors.Is(reloaded.Delete(psu.ID), apierror.ErrNotFound))
+}
+
+func TestStore_Validation(t *testing.T) {
+	s := NewStore("")
+
+	_, err := s.Add("   ", time.Time{}, nil)
+	assert.True(t, errors.Is(err, apierror.ErrInvalid))
+
+	a, err := s.Add("note", time.Time{}, nil)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), a.Time, time.Second)
+
+	empty := ""
+	_, err = s.Update(a.ID, Update{Text: &empty})
+	assert.True(t, errors.Is(err, apierror.ErrInvalid))
+	got, _ := s.Get(a.ID)
+	assert.Equal(t, "note", got.Text)
+}
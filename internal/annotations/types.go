@@ -0,0 +1,35 @@
+package annotations
+
+import "time"
+
+// Annotation is an operator's note about something that happened on the
+// host, such as "replaced PSU", for overlaying on metric history
+type Annotation struct {
+	ID        string     `json:"id"`
+	Time      time.Time  `json:"time"` // When the noted change happened
+	Text      string     `json:"text"`
+	Tags      []string   `json:"tags,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Update changes an annotation. Nil fields are left as they are.
+type Update struct {
+	Time *time.Time `json:"time"`
+	Text *string    `json:"text"`
+	Tags *[]string  `json:"tags"`
+}
+
+// Filter selects annotations. Zero values match everything.
+type Filter struct {
+	From  time.Time
+	To    time.Time
+	Tag   string
+	Limit int // Most recent first when set
+}
+
+// AnnotationList contains a list of annotations
+type AnnotationList struct {
+	Annotations []Annotation `json:"annotations"`
+	Total       int          `json:"total"`
+}
@@ -0,0 +1,152 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error kinds. Managers return errors that match one of these with errors.Is
+// so handlers can pick a status without inspecting the message.
+var (
+	ErrInvalid     = errors.New("invalid request")
+	ErrNotAllowed  = errors.New("not allowed")
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrUnsupported = errors.New("unsupported")
+	ErrUnavailable = errors.New("unavailable")
+)
+
+var kinds = []struct {
+	kind   error
+	code   Code
+	status int
+}{
+	{ErrInvalid, CodeBadRequest, http.StatusBadRequest},
+	{ErrNotAllowed, CodeNotAllowed, http.StatusForbidden},
+	{ErrNotFound, CodeNotFound, http.StatusNotFound},
+	{ErrConflict, CodeConflict, http.StatusConflict},
+	{ErrUnsupported, CodeUnsupported, http.StatusUnsupportedMediaType},
+	{ErrUnavailable, CodeUnavailable, http.StatusServiceUnavailable},
+	{context.DeadlineExceeded, CodeTimeout, http.StatusGatewayTimeout},
+}
+
+// Error is an error of a given kind with a human-readable message and
+// optional structured details
+type Error struct {
+	kind    error
+	err     error
+	Details map[string]interface{}
+}
+
+func (e *Error) Error() string { return e.err.Error() }
+
+// Unwrap exposes both the kind and any error wrapped with %w
+func (e *Error) Unwrap() []error { return []error{e.kind, e.err} }
+
+// WithDetail returns a copy of e with an extra detail field
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	details := make(map[string]interface{}, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	details[key] = value
+	return &Error{kind: e.kind, err: e.err, Details: details}
+}
+
+func newError(kind error, format string, args ...interface{}) *Error {
+	return &Error{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+// Invalid reports a malformed or out-of-range request
+func Invalid(format string, args ...interface{}) *Error {
+	return newError(ErrInvalid, format, args...)
+}
+
+// NotAllowed reports an action the agent's configuration does not permit
+func NotAllowed(format string, args ...interface{}) *Error {
+	return newError(ErrNotAllowed, format, args...)
+}
+
+// NotFound reports a missing resource
+func NotFound(format string, args ...interface{}) *Error {
+	return newError(ErrNotFound, format, args...)
+}
+
+// Conflict reports a request that clashes with the current state
+func Conflict(format string, args ...interface{}) *Error {
+	return newError(ErrConflict, format, args...)
+}
+
+// Unsupported reports a resource the endpoint cannot handle
+func Unsupported(format string, args ...interface{}) *Error {
+	return newError(ErrUnsupported, format, args...)
+}
+
+// Unavailable reports a missing or unreachable dependency (docker, dbus, ...)
+func Unavailable(format string, args ...interface{}) *Error {
+	return newError(ErrUnavailable, format, args...)
+}
+
+// Status returns the HTTP status for err's kind, or fallback if it has none
+func Status(err error, fallback int) int {
+	for _, k := range kinds {
+		if errors.Is(err, k.kind) {
+			return k.status
+		}
+	}
+	return fallback
+}
+
+// CodeForStatus returns the default code for an HTTP status
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeNotAllowed
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupported
+	case http.StatusPreconditionRequired:
+		return CodeConfirmationRequired
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	default:
+		return CodeInternal
+	}
+}
+
+// New builds an envelope with the default code for status
+func New(status int, message string, details map[string]interface{}) Response {
+	return Response{
+		Code:    CodeForStatus(status),
+		Message: message,
+		Details: details,
+		Error:   message,
+	}
+}
+
+// From converts err into a status and envelope. Errors without a kind use
+// fallback as their status.
+func From(err error, fallback int) (int, Response) {
+	status := Status(err, fallback)
+	resp := New(status, err.Error(), nil)
+
+	var e *Error
+	if errors.As(err, &e) {
+		resp.Details = e.Details
+	}
+
+	return status, resp
+}
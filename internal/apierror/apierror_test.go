@@ -0,0 +1,56 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom_TypedErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   Code
+	}{
+		{Invalid("bad port"), http.StatusBadRequest, CodeBadRequest},
+		{NotAllowed("access denied"), http.StatusForbidden, CodeNotAllowed},
+		{NotFound("task 'x' not found"), http.StatusNotFound, CodeNotFound},
+		{Conflict("busy"), http.StatusConflict, CodeConflict},
+		{Unsupported("no preview"), http.StatusUnsupportedMediaType, CodeUnsupported},
+		{Unavailable("docker not available"), http.StatusServiceUnavailable, CodeUnavailable},
+		{fmt.Errorf("outer: %w", NotFound("inner")), http.StatusNotFound, CodeNotFound},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+		{errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		status, resp := From(tt.err, http.StatusInternalServerError)
+		assert.Equal(t, tt.status, status, tt.err.Error())
+		assert.Equal(t, tt.code, resp.Code, tt.err.Error())
+		assert.Equal(t, tt.err.Error(), resp.Message)
+		assert.Equal(t, resp.Message, resp.Error)
+	}
+}
+
+func TestError_WrapsCause(t *testing.T) {
+	err := NotFound("failed to stat file: %w", os.ErrNotExist)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, "failed to stat file: file does not exist", err.Error())
+}
+
+func TestError_Details(t *testing.T) {
+	base := Conflict("already running")
+	err := base.WithDetail("job", "abc")
+
+	_, resp := From(err, http.StatusInternalServerError)
+	assert.Equal(t, map[string]interface{}{"job": "abc"}, resp.Details)
+	assert.Nil(t, base.Details)
+	assert.ErrorIs(t, err, ErrConflict)
+}
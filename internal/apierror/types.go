@@ -0,0 +1,27 @@
+package apierror
+
+// Code is a machine-readable error code clients can branch on
+type Code string
+
+const (
+	CodeBadRequest           Code = "bad_request"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeNotAllowed           Code = "not_allowed"
+	CodeNotFound             Code = "not_found"
+	CodeConflict             Code = "conflict"
+	CodeUnsupported          Code = "unsupported"
+	CodeConfirmationRequired Code = "confirmation_required"
+	CodeRateLimited          Code = "rate_limited"
+	CodeUnavailable          Code = "unavailable"
+	CodeTimeout              Code = "timeout"
+	CodeInternal             Code = "internal"
+)
+
+// Response is the error envelope returned by every API endpoint. Error
+// repeats Message for clients written against the older {"error": "..."} shape.
+type Response struct {
+	Code    Code                   `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Error   string                 `json:"error"`
+}
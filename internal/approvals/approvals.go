@@ -0,0 +1,234 @@
+package approvals
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// DefaultTTL is how long a request waits for approval before expiring
+	DefaultTTL = 30 * time.Minute
+	// MaxDecided is the number of decided approvals retained for history
+	MaxDecided = 200
+)
+
+// Approval errors
+var (
+	ErrNotFound     = apierror.NotFound("approval not found")
+	ErrNotPending   = apierror.Conflict("approval is no longer pending")
+	ErrSelfApproval = apierror.NotAllowed("approval must come from a different credential than the request")
+)
+
+type entry struct {
+	approval Approval
+	fn       Func
+	timeout  time.Duration
+}
+
+// Queue holds destructive actions until a second party approves them
+type Queue struct {
+	ttl     time.Duration
+	entries map[string]*entry
+	mu      sync.Mutex
+}
+
+// NewQueue creates a new approval queue
+func NewQueue(ttl time.Duration) *Queue {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Queue{
+		ttl:     ttl,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Submit queues fn for approval. Once approved it runs with the given timeout.
+func (q *Queue) Submit(action, target, impact string, requester Party, timeout time.Duration, fn Func) *Approval {
+	now := time.Now()
+	e := &entry{
+		approval: Approval{
+			ID:          newID(),
+			Action:      action,
+			Target:      target,
+			Impact:      impact,
+			Status:      StatusPending,
+			RequestedBy: requester,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(q.ttl),
+		},
+		fn:      fn,
+		timeout: timeout,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.expire()
+	q.entries[e.approval.ID] = e
+	q.prune()
+
+	snapshot := e.approval
+	return &snapshot
+}
+
+// Get returns an approval by ID
+func (q *Queue) Get(id string) (*Approval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.expire()
+	e, ok := q.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	snapshot := e.approval
+	return &snapshot, nil
+}
+
+// List returns approvals, newest first, optionally filtered by status
+func (q *Queue) List(status Status) *ApprovalList {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.expire()
+	approvals := []Approval{}
+	for _, e := range q.entries {
+		if status == "" || e.approval.Status == status {
+			approvals = append(approvals, e.approval)
+		}
+	}
+
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].CreatedAt.After(approvals[j].CreatedAt)
+	})
+
+	return &ApprovalList{
+		Approvals: approvals,
+		Total:     len(approvals),
+	}
+}
+
+// Approve executes a pending action. The approver must use a different
+// credential from the requester.
+func (q *Queue) Approve(id string, approver Party, reason string) (*Approval, error) {
+	q.mu.Lock()
+	q.expire()
+	e, ok := q.entries[id]
+	if !ok {
+		q.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	if e.approval.Status != StatusPending {
+		q.mu.Unlock()
+		return nil, ErrNotPending
+	}
+	if samePrincipal(e.approval.RequestedBy, approver) {
+		q.mu.Unlock()
+		return nil, ErrSelfApproval
+	}
+
+	// Mark as decided before running so a concurrent approval cannot run it twice
+	now := time.Now()
+	e.approval.Status = StatusApproved
+	e.approval.DecidedBy = &approver
+	e.approval.DecidedAt = &now
+	e.approval.Reason = reason
+	q.mu.Unlock()
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if e.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	result, err := e.fn(ctx)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e.approval.Result = result
+	if err != nil {
+		e.approval.Status = StatusFailed
+		e.approval.Error = err.Error()
+	}
+
+	snapshot := e.approval
+	return &snapshot, nil
+}
+
+// Reject declines a pending action. Requesters may reject (cancel) their own requests.
+func (q *Queue) Reject(id string, decider Party, reason string) (*Approval, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.expire()
+	e, ok := q.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if e.approval.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+
+	now := time.Now()
+	e.approval.Status = StatusRejected
+	e.approval.DecidedBy = &decider
+	e.approval.DecidedAt = &now
+	e.approval.Reason = reason
+
+	snapshot := e.approval
+	return &snapshot, nil
+}
+
+// samePrincipal reports whether two parties used the same credential. A
+// party without one matches, so it can never approve.
+func samePrincipal(a, b Party) bool {
+	return a.Principal == "" || b.Principal == "" || a.Principal == b.Principal
+}
+
+// expire marks pending approvals past their deadline as expired
+func (q *Queue) expire() {
+	now := time.Now()
+	for _, e := range q.entries {
+		if e.approval.Status == StatusPending && now.After(e.approval.ExpiresAt) {
+			e.approval.Status = StatusExpired
+		}
+	}
+}
+
+// prune drops the oldest decided approvals beyond MaxDecided
+func (q *Queue) prune() {
+	var decided []*entry
+	for _, e := range q.entries {
+		if e.approval.Status != StatusPending {
+			decided = append(decided, e)
+		}
+	}
+	if len(decided) <= MaxDecided {
+		return
+	}
+
+	sort.Slice(decided, func(i, j int) bool {
+		return decided[i].approval.CreatedAt.Before(decided[j].approval.CreatedAt)
+	})
+	for _, e := range decided[:len(decided)-MaxDecided] {
+		delete(q.entries, e.approval.ID)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
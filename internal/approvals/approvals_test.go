@@ -0,0 +1,99 @@
+package approvals
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	laptop = Party{Actor: "api_key", Principal: "api_key:1111", ClientIP: "10.0.0.2", UserAgent: "laptop"}
+	phone  = Party{Actor: "session", Principal: "session:2222", ClientIP: "10.0.0.3", UserAgent: "phone"}
+)
+
+func TestQueue_ApproveRunsAction(t *testing.T) {
+	q := NewQueue(time.Minute)
+	ran := 0
+	a := q.Submit("system.reboot", "now", "Reboots host", laptop, time.Second, func(ctx context.Context) (interface{}, error) {
+		ran++
+		return "done", nil
+	})
+	assert.Equal(t, StatusPending, a.Status)
+	assert.Equal(t, 0, ran)
+
+	_, err := q.Approve(a.ID, laptop, "")
+	assert.ErrorIs(t, err, ErrSelfApproval)
+	assert.Equal(t, 0, ran)
+
+	// Another IP and user agent with the same credential is still the requester
+	_, err = q.Approve(a.ID, Party{Principal: laptop.Principal, ClientIP: "192.0.2.9", UserAgent: "phone"}, "")
+	assert.ErrorIs(t, err, ErrSelfApproval)
+	_, err = q.Approve(a.ID, Party{ClientIP: "192.0.2.9"}, "")
+	assert.ErrorIs(t, err, ErrSelfApproval)
+	assert.Equal(t, 0, ran)
+
+	approved, err := q.Approve(a.ID, phone, "looks fine")
+	require.NoError(t, err)
+	assert.Equal(t, StatusApproved, approved.Status)
+	assert.Equal(t, "done", approved.Result)
+	assert.Equal(t, "looks fine", approved.Reason)
+	assert.Equal(t, 1, ran)
+
+	_, err = q.Approve(a.ID, phone, "")
+	assert.ErrorIs(t, err, ErrNotPending)
+	assert.Equal(t, 1, ran)
+}
+
+func TestQueue_FailedAction(t *testing.T) {
+	q := NewQueue(time.Minute)
+	a := q.Submit("task.run", "wipe", "", laptop, time.Second, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	failed, err := q.Approve(a.ID, phone, "")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, "boom", failed.Error)
+}
+
+func TestQueue_Reject(t *testing.T) {
+	q := NewQueue(time.Minute)
+	a := q.Submit("task.run", "wipe", "", laptop, time.Second, func(ctx context.Context) (interface{}, error) {
+		t.Fatal("rejected action must not run")
+		return nil, nil
+	})
+
+	// Requesters may cancel their own requests
+	rejected, err := q.Reject(a.ID, laptop, "changed my mind")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRejected, rejected.Status)
+
+	_, err = q.Approve(a.ID, phone, "")
+	assert.ErrorIs(t, err, ErrNotPending)
+
+	_, err = q.Reject("missing", phone, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestQueue_Expiry(t *testing.T) {
+	q := NewQueue(10 * time.Millisecond)
+	a := q.Submit("system.shutdown", "now", "", laptop, time.Second, func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+
+	time.Sleep(20 * time.Millisecond)
+
+	got, err := q.Get(a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusExpired, got.Status)
+
+	_, err = q.Approve(a.ID, phone, "")
+	assert.ErrorIs(t, err, ErrNotPending)
+
+	assert.Equal(t, 1, q.List(StatusExpired).Total)
+	assert.Equal(t, 0, q.List(StatusPending).Total)
+}
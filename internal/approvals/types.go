@@ -0,0 +1,58 @@
+package approvals
+
+import (
+	"context"
+	"time"
+)
+
+// Status represents the state of an approval request
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved" // Approved and executed successfully
+	StatusFailed   Status = "failed"   // Approved but execution failed
+	StatusRejected Status = "rejected"
+	StatusExpired  Status = "expired"
+)
+
+// Func performs the queued action once it is approved
+type Func func(ctx context.Context) (interface{}, error)
+
+// Party identifies who requested or decided an approval. Principal names the
+// credential that authenticated the request; client IP and user agent are
+// informational, since the client controls both.
+type Party struct {
+	Actor     string `json:"actor,omitempty"`
+	Principal string `json:"-"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// Approval is a destructive action waiting for a second party to approve it
+type Approval struct {
+	ID          string      `json:"id"`
+	Action      string      `json:"action"`
+	Target      string      `json:"target"`
+	Impact      string      `json:"impact"`
+	Status      Status      `json:"status"`
+	RequestedBy Party       `json:"requested_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+	DecidedBy   *Party      `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time  `json:"decided_at,omitempty"`
+	Reason      string      `json:"reason,omitempty"`
+	Result      interface{} `json:"result,omitempty"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// ApprovalList contains a list of approvals
+type ApprovalList struct {
+	Approvals []Approval `json:"approvals"`
+	Total     int        `json:"total"`
+}
+
+// DecisionRequest is the optional body when approving or rejecting
+type DecisionRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of entries kept in memory
+const DefaultCapacity = 500
+
+// Entry sources for List
+const (
+	SourceAPI  = "api"  // Actions taken through the agent
+	SourceHost = "host" // Shells, su and sudo seen on the host
+)
+
+// Logger records agent-initiated actions in a bounded in-memory log
+type Logger struct {
+	entries  []Entry
+	capacity int
+	mu       sync.RWMutex
+}
+
+// NewLogger creates a new audit logger
+func NewLogger(capacity int) *Logger {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Logger{
+		capacity: capacity,
+	}
+}
+
+// Record adds an entry to the audit log
+func (l *Logger) Record(entry Entry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	log.Printf("[AUDIT] %s %s | Success: %v | Actor: %s | Client: %s | %s",
+		entry.Action, entry.Target, entry.Success, entry.Actor, entry.ClientIP, entry.Message)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > l.capacity {
+		l.entries = l.entries[len(l.entries)-l.capacity:]
+	}
+}
+
+// List returns the most recent entries, newest first. A non-empty source
+// keeps only SourceAPI or SourceHost entries. Total counts every entry that
+// matches source, not just those returned.
+func (l *Logger) List(limit int, source string) *EntryList {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if limit <= 0 || limit > len(l.entries) {
+		limit = len(l.entries)
+	}
+
+	entries := make([]Entry, 0, limit)
+	total := 0
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if source != "" && entrySource(l.entries[i]) != source {
+			continue
+		}
+		total++
+		if len(entries) < limit {
+			entries = append(entries, l.entries[i])
+		}
+	}
+
+	return &EntryList{
+		Entries: entries,
+		Total:   total,
+	}
+}
+
+// Merge combines lists that are each newest first into one, keeping the
+// newest limit entries. Totals are added up.
+func Merge(limit int, lists ...*EntryList) *EntryList {
+	merged := &EntryList{Entries: []Entry{}}
+	for _, list := range lists {
+		merged.Entries = append(merged.Entries, list.Entries...)
+		merged.Total += list.Total
+	}
+
+	sort.SliceStable(merged.Entries, func(i, j int) bool {
+		return merged.Entries[i].Timestamp.After(merged.Entries[j].Timestamp)
+	})
+	if limit > 0 && len(merged.Entries) > limit {
+		merged.Entries = merged.Entries[:limit]
+	}
+	return merged
+}
+
+func entrySource(e Entry) string {
+	if e.Source == "" {
+		return SourceAPI
+	}
+	return e.Source
+}
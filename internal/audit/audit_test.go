@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList_TotalCountsSource(t *testing.T) {
+	l := NewLogger(10)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		l.Record(Entry{Timestamp: start.Add(time.Duration(i) * time.Second), Action: "service.restart"})
+	}
+	l.Record(Entry{Timestamp: start.Add(time.Minute), Action: "host.sudo", Source: SourceHost})
+
+	all := l.List(2, "")
+	assert.Equal(t, 4, all.Total)
+	assert.Len(t, all.Entries, 2)
+	assert.Equal(t, "host.sudo", all.Entries[0].Action)
+
+	api := l.List(10, SourceAPI)
+	assert.Equal(t, 3, api.Total)
+	assert.Len(t, api.Entries, 3)
+
+	host := l.List(10, SourceHost)
+	assert.Equal(t, 1, host.Total)
+}
+
+func TestMerge(t *testing.T) {
+	api, host := NewLogger(10), NewLogger(10)
+	start := time.Now()
+	api.Record(Entry{Timestamp: start, Action: "a1"})
+	host.Record(Entry{Timestamp: start.Add(time.Second), Action: "h1", Source: SourceHost})
+	api.Record(Entry{Timestamp: start.Add(2 * time.Second), Action: "a2"})
+
+	merged := Merge(2, api.List(2, ""), host.List(2, ""))
+	assert.Equal(t, 3, merged.Total)
+	if assert.Len(t, merged.Entries, 2) {
+		assert.Equal(t, "a2", merged.Entries[0].Action)
+		assert.Equal(t, "h1", merged.Entries[1].Action)
+	}
+}
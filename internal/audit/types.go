@@ -0,0 +1,21 @@
+package audit
+
+import "time"
+
+// Entry represents a single audited action
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Success   bool      `json:"success"`
+	Message   string    `json:"message,omitempty"`
+	Source    string    `json:"source,omitempty"` // SourceHost for activity seen on the host; empty for API actions
+}
+
+// EntryList contains a list of audit entries
+type EntryList struct {
+	Entries []Entry `json:"entries"`
+	Total   int     `json:"total"`
+}
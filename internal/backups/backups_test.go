@@ -0,0 +1,115 @@
+package backups
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const resticSnapshots = `[
+  {"time":"2024-03-01T03:00:00.123456789+00:00","paths":["/home"],"hostname":"nas","tags":["nightly"],"id":"aaaaaaaa11","short_id":"aaaaaaaa"},
+  {"time":"2024-03-02T03:00:00+00:00","paths":["/home"],"hostname":"nas","id":"bbbbbbbb22","short_id":"bbbbbbbb"}
+]`
+
+const borgList = `{"archives":[
+  {"archive":"nas-2024-03-01","id":"0123456789abcdef","name":"nas-2024-03-01","start":"2024-03-01T03:00:00.000000","time":"2024-03-01T03:00:00.000000"},
+  {"archive":"nas-2024-03-02","id":"fedcba9876543210","name":"nas-2024-03-02","start":"2024-03-02T03:00:00.000000","time":"2024-03-02T03:00:00.000000"}
+],"repository":{"id":"r","location":"/mnt/borg"}}`
+
+const borgInfo = `{"cache":{"stats":{"total_chunks":10,"total_csize":5000,"total_size":9000,"total_unique_chunks":4,"unique_csize":1234,"unique_size":2000}},"repository":{"id":"r"}}`
+
+func TestParseRepos(t *testing.T) {
+	repos, err := ParseRepos(
+		map[string]string{"offsite": "borg:ssh://u@host/./repo", "home": "restic:/srv/restic"},
+		map[string]string{"home": "/etc/restic.pass"},
+	)
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+	assert.Equal(t, Repo{Name: "home", Type: TypeRestic, Location: "/srv/restic", PasswordFile: "/etc/restic.pass"}, repos[0])
+	assert.Equal(t, Repo{Name: "offsite", Type: TypeBorg, Location: "ssh://u@host/./repo"}, repos[1])
+
+	_, err = ParseRepos(map[string]string{"x": "duplicity:/srv"}, nil)
+	assert.Error(t, err)
+	_, err = ParseRepos(map[string]string{"x": "restic:"}, nil)
+	assert.Error(t, err)
+}
+
+func TestParseResticSnapshots(t *testing.T) {
+	snapshots, err := parseResticSnapshots([]byte(resticSnapshots))
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "bbbbbbbb", snapshots[0].ID)
+	assert.Equal(t, "aaaaaaaa", snapshots[1].ID)
+	assert.Equal(t, []string{"nightly"}, snapshots[1].Tags)
+}
+
+func TestParseBorg(t *testing.T) {
+	snapshots, err := parseBorgList([]byte(borgList))
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "nas-2024-03-02", snapshots[0].Name)
+	assert.Equal(t, "fedcba98", snapshots[0].ID)
+	assert.Equal(t, time.Date(2024, 3, 2, 3, 0, 0, 0, time.Local), snapshots[0].Time)
+
+	size, err := parseBorgInfo([]byte(borgInfo))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), size)
+}
+
+func TestMonitor(t *testing.T) {
+	calls := 0
+	runCommand = func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+		calls++
+		switch {
+		case name == "restic" && args[len(args)-1] == "snapshots":
+			assert.Contains(t, strings.Join(args, " "), "--password-file /etc/restic.pass")
+			return []byte(resticSnapshots), nil
+		case name == "restic":
+			return []byte(`{"total_size":4096,"total_file_count":3}`), nil
+		case name == "borg":
+			return nil, errors.New("borg: Repository /mnt/borg does not exist.")
+		}
+		return nil, errors.New("unexpected command")
+	}
+	defer func() { runCommand = defaultRunCommand }()
+
+	m := NewMonitor([]Repo{
+		{Name: "home", Type: TypeRestic, Location: "/srv/restic", PasswordFile: "/etc/restic.pass"},
+		{Name: "offsite", Type: TypeBorg, Location: "/mnt/borg"},
+	}, time.Minute)
+
+	statuses := m.Status(context.Background(), false)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, 2, statuses[0].Snapshots)
+	assert.Equal(t, int64(4096), statuses[0].SizeBytes)
+	require.NotNil(t, statuses[0].LastSnapshot)
+	assert.Equal(t, 2024, statuses[0].LastSnapshot.Year())
+	assert.Greater(t, statuses[0].LastSnapshotAge, 0.0)
+	assert.Empty(t, statuses[0].Error)
+	assert.Equal(t, "borg: Repository /mnt/borg does not exist.", statuses[1].Error)
+	assert.Equal(t, 3, calls)
+
+	// Cached until the TTL passes, unless refreshed
+	m.Status(context.Background(), false)
+	assert.Equal(t, 3, calls)
+	m.Status(context.Background(), true)
+	assert.Equal(t, 6, calls)
+
+	list, err := m.Snapshots(context.Background(), "home", 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+	require.Len(t, list.Snapshots, 1)
+	assert.Equal(t, "bbbbbbbb", list.Snapshots[0].ID)
+
+	_, err = m.Snapshots(context.Background(), "offsite", 0, false)
+	assert.Error(t, err)
+	_, err = m.Snapshots(context.Background(), "missing", 0, false)
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
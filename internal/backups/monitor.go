@@ -0,0 +1,145 @@
+package backups
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// checkTimeout bounds one repository check
+const checkTimeout = 5 * time.Minute
+
+// ParseRepos builds repositories from name=type:location pairs and
+// name=path password files
+func ParseRepos(repos, passwordFiles map[string]string) ([]Repo, error) {
+	var result []Repo
+	for name, spec := range repos {
+		kind, location, ok := strings.Cut(spec, ":")
+		if !ok || location == "" || (kind != TypeRestic && kind != TypeBorg) {
+			return nil, fmt.Errorf("repository %s: use restic:<repo> or borg:<repo>", name)
+		}
+		result = append(result, Repo{
+			Name:         name,
+			Type:         kind,
+			Location:     location,
+			PasswordFile: passwordFiles[name],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+type result struct {
+	status    RepoStatus
+	snapshots []Snapshot
+}
+
+// Monitor reports the state of backup repositories. Checking runs restic or
+// borg, which can take a while for large repositories, so results are kept
+// for the cache TTL.
+type Monitor struct {
+	repos []Repo
+	ttl   time.Duration
+
+	mu      sync.Mutex // Held during checks so concurrent requests share one
+	results map[string]*result
+}
+
+// NewMonitor creates a monitor for repos
+func NewMonitor(repos []Repo, ttl time.Duration) *Monitor {
+	return &Monitor{
+		repos:   repos,
+		ttl:     ttl,
+		results: make(map[string]*result),
+	}
+}
+
+// Status returns every repository's status. refresh ignores cached results.
+func (m *Monitor) Status(ctx context.Context, refresh bool) []RepoStatus {
+	statuses := make([]RepoStatus, 0, len(m.repos))
+	for _, repo := range m.repos {
+		r := m.check(ctx, repo, refresh)
+		statuses = append(statuses, withAge(r.status))
+	}
+	return statuses
+}
+
+// Snapshots returns a repository's snapshots, newest first. A limit of zero
+// returns all of them.
+func (m *Monitor) Snapshots(ctx context.Context, name string, limit int, refresh bool) (*SnapshotList, error) {
+	repo, ok := m.repo(name)
+	if !ok {
+		return nil, apierror.NotFound("backup repository %q not configured", name)
+	}
+
+	r := m.check(ctx, repo, refresh)
+	if r.status.Error != "" && r.snapshots == nil {
+		return nil, fmt.Errorf("%s", r.status.Error)
+	}
+
+	snapshots := r.snapshots
+	if limit > 0 && limit < len(snapshots) {
+		snapshots = snapshots[:limit]
+	}
+	return &SnapshotList{Repo: name, Snapshots: snapshots, Total: len(r.snapshots)}, nil
+}
+
+func (m *Monitor) repo(name string) (Repo, bool) {
+	for _, repo := range m.repos {
+		if repo.Name == name {
+			return repo, true
+		}
+	}
+	return Repo{}, false
+}
+
+func (m *Monitor) check(ctx context.Context, repo Repo, refresh bool) *result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.results[repo.Name]; ok && !refresh && time.Since(r.status.CheckedAt) < m.ttl {
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	snapshots, size, err := inspect(ctx, repo)
+	r := &result{
+		status: RepoStatus{
+			Name:       repo.Name,
+			Type:       repo.Type,
+			Repository: repo.Location,
+			Snapshots:  len(snapshots),
+			SizeBytes:  size,
+			CheckedAt:  time.Now(),
+		},
+		snapshots: snapshots,
+	}
+	if len(snapshots) > 0 {
+		last := snapshots[0].Time
+		r.status.LastSnapshot = &last
+	}
+	if err != nil {
+		r.status.Error = err.Error()
+		// A cancelled request should not leave a failure cached for others
+		if ctx.Err() != nil {
+			return r
+		}
+	}
+
+	m.results[repo.Name] = r
+	return r
+}
+
+func withAge(status RepoStatus) RepoStatus {
+	if status.LastSnapshot != nil {
+		status.LastSnapshotAge = time.Since(*status.LastSnapshot).Seconds()
+	}
+	return status
+}
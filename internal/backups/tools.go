@@ -0,0 +1,184 @@
+package backups
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runCommand runs a command with extra environment and returns its stdout;
+// replaced in tests
+var runCommand = defaultRunCommand
+
+func defaultRunCommand(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", name, lastLine(msg))
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// inspect reads a repository's snapshots and size without locking it
+func inspect(ctx context.Context, repo Repo) ([]Snapshot, int64, error) {
+	switch repo.Type {
+	case TypeRestic:
+		return inspectRestic(ctx, repo)
+	case TypeBorg:
+		return inspectBorg(ctx, repo)
+	}
+	return nil, 0, fmt.Errorf("unknown repository type %q", repo.Type)
+}
+
+func inspectRestic(ctx context.Context, repo Repo) ([]Snapshot, int64, error) {
+	// --no-cache keeps restic from writing outside the repository
+	base := []string{"--repo", repo.Location, "--no-lock", "--no-cache", "--json"}
+	if repo.PasswordFile != "" {
+		base = append(base, "--password-file", repo.PasswordFile)
+	}
+
+	out, err := runCommand(ctx, nil, "restic", append(base, "snapshots")...)
+	if err != nil {
+		return nil, 0, err
+	}
+	snapshots, err := parseResticSnapshots(out)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out, err = runCommand(ctx, nil, "restic", append(base, "stats", "--mode", "raw-data")...)
+	if err != nil {
+		return snapshots, 0, err
+	}
+	var stats struct {
+		TotalSize int64 `json:"total_size"`
+	}
+	if err := json.Unmarshal(out, &stats); err != nil {
+		return snapshots, 0, fmt.Errorf("failed to parse restic stats: %w", err)
+	}
+	return snapshots, stats.TotalSize, nil
+}
+
+func parseResticSnapshots(data []byte) ([]Snapshot, error) {
+	var raw []struct {
+		ID       string    `json:"id"`
+		ShortID  string    `json:"short_id"`
+		Time     time.Time `json:"time"`
+		Hostname string    `json:"hostname"`
+		Paths    []string  `json:"paths"`
+		Tags     []string  `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse restic snapshots: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(raw))
+	for _, s := range raw {
+		id := s.ShortID
+		if id == "" {
+			id = s.ID
+		}
+		snapshots = append(snapshots, Snapshot{
+			ID:       id,
+			Time:     s.Time,
+			Hostname: s.Hostname,
+			Paths:    s.Paths,
+			Tags:     s.Tags,
+		})
+	}
+	sortNewestFirst(snapshots)
+	return snapshots, nil
+}
+
+func inspectBorg(ctx context.Context, repo Repo) ([]Snapshot, int64, error) {
+	var env []string
+	if repo.PasswordFile != "" {
+		env = append(env, "BORG_PASSCOMMAND=cat "+repo.PasswordFile)
+	}
+
+	out, err := runCommand(ctx, env, "borg", "list", "--json", "--bypass-lock", repo.Location)
+	if err != nil {
+		return nil, 0, err
+	}
+	snapshots, err := parseBorgList(out)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out, err = runCommand(ctx, env, "borg", "info", "--json", "--bypass-lock", repo.Location)
+	if err != nil {
+		return snapshots, 0, err
+	}
+	size, err := parseBorgInfo(out)
+	return snapshots, size, err
+}
+
+// borgTime is the format of borg's archive times, in local time
+const borgTime = "2006-01-02T15:04:05.999999"
+
+func parseBorgList(data []byte) ([]Snapshot, error) {
+	var raw struct {
+		Archives []struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Start string `json:"start"`
+		} `json:"archives"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse borg list: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(raw.Archives))
+	for _, a := range raw.Archives {
+		t, err := time.ParseInLocation(borgTime, a.Start, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid borg archive time %q", a.Start)
+		}
+		id := a.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		snapshots = append(snapshots, Snapshot{ID: id, Name: a.Name, Time: t})
+	}
+	sortNewestFirst(snapshots)
+	return snapshots, nil
+}
+
+func parseBorgInfo(data []byte) (int64, error) {
+	var raw struct {
+		Cache struct {
+			Stats struct {
+				UniqueCSize int64 `json:"unique_csize"`
+			} `json:"stats"`
+		} `json:"cache"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return 0, fmt.Errorf("failed to parse borg info: %w", err)
+	}
+	return raw.Cache.Stats.UniqueCSize, nil
+}
+
+func sortNewestFirst(snapshots []Snapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+}
@@ -0,0 +1,47 @@
+package backups
+
+import "time"
+
+// Repository types
+const (
+	TypeRestic = "restic"
+	TypeBorg   = "borg"
+)
+
+// Repo is a configured backup repository
+type Repo struct {
+	Name         string
+	Type         string // restic or borg
+	Location     string // Repository path or URL as the tool expects it
+	PasswordFile string // Empty to use the agent's RESTIC_/BORG_ environment
+}
+
+// Snapshot is one restic snapshot or borg archive
+type Snapshot struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"` // Borg archive name
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname,omitempty"`
+	Paths    []string  `json:"paths,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+}
+
+// RepoStatus summarizes a repository
+type RepoStatus struct {
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	Repository      string     `json:"repository"`
+	Snapshots       int        `json:"snapshots"`
+	LastSnapshot    *time.Time `json:"last_snapshot,omitempty"`
+	LastSnapshotAge float64    `json:"last_snapshot_age_seconds,omitempty"`
+	SizeBytes       int64      `json:"size_bytes"` // Deduplicated size on disk
+	Error           string     `json:"error,omitempty"`
+	CheckedAt       time.Time  `json:"checked_at"`
+}
+
+// SnapshotList is a repository's snapshots, newest first
+type SnapshotList struct {
+	Repo      string     `json:"repo"`
+	Snapshots []Snapshot `json:"snapshots"`
+	Total     int        `json:"total"`
+}
@@ -1,28 +1,74 @@
 package cache
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // Item represents a cached item with expiration
 type Item struct {
 	Value      interface{}
 	Expiration int64
+	CreatedAt  int64
 }
 
 // Cache is a thread-safe in-memory cache
 type Cache struct {
 	items map[string]Item
+	ttls  map[string]time.Duration
 	mu    sync.RWMutex
 	ttl   time.Duration
+	group singleflight.Group
+
+	counters map[string]*counter
+	statsMu  sync.Mutex
+}
+
+// maxCounters bounds the keys with their own hit/miss counters. Lookups of
+// further keys are counted together under OtherKey.
+const maxCounters = 256
+
+// OtherKey collects the hits and misses of keys beyond maxCounters
+const OtherKey = "(other)"
+
+// counter tracks lookups for a single key
+type counter struct {
+	hits   uint64
+	misses uint64
+}
+
+// EntryStats describes a single cache key
+type EntryStats struct {
+	Key         string `json:"key"`
+	Cached      bool   `json:"cached"`
+	AgeMs       int64  `json:"age_ms,omitempty"`
+	ExpiresInMs int64  `json:"expires_in_ms,omitempty"`
+	TTLMs       int64  `json:"ttl_ms"`
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+}
+
+// Stats contains cache contents and hit/miss counters
+type Stats struct {
+	Entries    []EntryStats `json:"entries"`
+	Size       int          `json:"size"`
+	DefaultTTL int64        `json:"default_ttl_ms"`
+	Hits       uint64       `json:"hits"`
+	Misses     uint64       `json:"misses"`
+	HitRatio   float64      `json:"hit_ratio"`
 }
 
 // New creates a new cache with the specified default TTL
 func New(ttl time.Duration) *Cache {
 	c := &Cache{
-		items: make(map[string]Item),
-		ttl:   ttl,
+		items:    make(map[string]Item),
+		ttls:     make(map[string]time.Duration),
+		ttl:      ttl,
+		counters: make(map[string]*counter),
 	}
 
 	// Start cleanup goroutine
@@ -31,9 +77,54 @@ func New(ttl time.Duration) *Cache {
 	return c
 }
 
-// Set stores a value in the cache with the default TTL
+// Set stores a value in the cache with the key's TTL (or the default TTL)
 func (c *Cache) Set(key string, value interface{}) {
-	c.SetWithTTL(key, value, c.ttl)
+	c.SetWithTTL(key, value, c.TTL(key))
+}
+
+// TTL returns the TTL applied to a key by Set
+func (c *Cache) TTL(key string) time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if ttl, ok := c.ttls[key]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+// SetKeyTTL overrides the TTL used by Set for a specific key. A zero or
+// negative TTL removes the override.
+func (c *Cache) SetKeyTTL(key string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ttl <= 0 {
+		delete(c.ttls, key)
+		return
+	}
+	c.ttls[key] = ttl
+}
+
+// SetDefaultTTL changes the TTL used for keys without an override. Entries
+// already cached keep their expiry.
+func (c *Cache) SetDefaultTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+}
+
+// KeyTTLs returns the per-key TTL overrides
+func (c *Cache) KeyTTLs() map[string]time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ttls := make(map[string]time.Duration, len(c.ttls))
+	for key, ttl := range c.ttls {
+		ttls[key] = ttl
+	}
+	return ttls
 }
 
 // SetWithTTL stores a value in the cache with a custom TTL
@@ -41,14 +132,22 @@ func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	now := time.Now()
 	c.items[key] = Item{
 		Value:      value,
-		Expiration: time.Now().Add(ttl).UnixNano(),
+		Expiration: now.Add(ttl).UnixNano(),
+		CreatedAt:  now.UnixNano(),
 	}
 }
 
 // Get retrieves a value from the cache
 func (c *Cache) Get(key string) (interface{}, bool) {
+	value, found := c.lookup(key)
+	c.count(key, found)
+	return value, found
+}
+
+func (c *Cache) lookup(key string) (interface{}, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -64,27 +163,81 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	return item.Value, true
 }
 
-// GetOrSet retrieves a value from cache or sets it using the provided function
+func (c *Cache) count(key string, hit bool) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	ctr, ok := c.counters[key]
+	if !ok {
+		if len(c.counters) >= maxCounters {
+			key = OtherKey
+		}
+		if ctr, ok = c.counters[key]; !ok {
+			ctr = &counter{}
+			c.counters[key] = ctr
+		}
+	}
+	if hit {
+		ctr.hits++
+	} else {
+		ctr.misses++
+	}
+}
+
+// GetOrSet retrieves a value from cache or sets it using the provided function.
+// Concurrent misses for the same key are coalesced so fn runs only once and
+// all callers share its result.
 func (c *Cache) GetOrSet(key string, fn func() (interface{}, error)) (interface{}, error) {
 	if value, found := c.Get(key); found {
 		return value, nil
 	}
 
-	value, err := fn()
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		// Another caller may have populated the key while we waited
+		if value, found := c.lookup(key); found {
+			return value, nil
+		}
+
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Set(key, value)
+		return value, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	c.Set(key, value)
 	return value, nil
 }
 
-// Delete removes a value from the cache
-func (c *Cache) Delete(key string) {
+// Delete removes a value from the cache and reports whether an unexpired
+// value was there. Unlike Get, it does not count as a hit or miss.
+func (c *Cache) Delete(key string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	item, found := c.items[key]
 	delete(c.items, key)
+	return found && time.Now().UnixNano() <= item.Expiration
+}
+
+// DeletePrefix removes all values whose key starts with prefix and returns
+// the number of removed entries
+func (c *Cache) DeletePrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.items, key)
+			removed++
+		}
+	}
+	return removed
 }
 
 // Clear removes all items from the cache
@@ -95,6 +248,79 @@ func (c *Cache) Clear() {
 	c.items = make(map[string]Item)
 }
 
+// Stats returns the cached keys with their ages and hit/miss counters
+func (c *Cache) Stats() *Stats {
+	now := time.Now().UnixNano()
+
+	c.mu.RLock()
+	entries := make(map[string]EntryStats, len(c.items))
+	for key, item := range c.items {
+		ttl := c.ttl
+		if override, ok := c.ttls[key]; ok {
+			ttl = override
+		}
+		entry := EntryStats{
+			Key:   key,
+			TTLMs: ttl.Milliseconds(),
+		}
+		if now <= item.Expiration {
+			entry.Cached = true
+			entry.AgeMs = (now - item.CreatedAt) / int64(time.Millisecond)
+			entry.ExpiresInMs = (item.Expiration - now) / int64(time.Millisecond)
+		}
+		entries[key] = entry
+	}
+	for key, ttl := range c.ttls {
+		if _, ok := entries[key]; !ok {
+			entries[key] = EntryStats{Key: key, TTLMs: ttl.Milliseconds()}
+		}
+	}
+	defaultTTL := c.ttl
+	c.mu.RUnlock()
+
+	stats := &Stats{
+		DefaultTTL: defaultTTL.Milliseconds(),
+	}
+
+	c.statsMu.Lock()
+	for key, ctr := range c.counters {
+		entry, ok := entries[key]
+		if !ok {
+			entry = EntryStats{Key: key, TTLMs: defaultTTL.Milliseconds()}
+		}
+		entry.Hits = ctr.hits
+		entry.Misses = ctr.misses
+		entries[key] = entry
+		stats.Hits += ctr.hits
+		stats.Misses += ctr.misses
+	}
+	c.statsMu.Unlock()
+
+	for _, entry := range entries {
+		if entry.Cached {
+			stats.Size++
+		}
+		stats.Entries = append(stats.Entries, entry)
+	}
+	sort.Slice(stats.Entries, func(i, j int) bool {
+		return stats.Entries[i].Key < stats.Entries[j].Key
+	})
+
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+
+	return stats
+}
+
+// ResetStats clears the hit/miss counters
+func (c *Cache) ResetStats() {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.counters = make(map[string]*counter)
+}
+
 // cleanup removes expired items periodically
 func (c *Cache) cleanup() {
 	ticker := time.NewTicker(time.Minute)
@@ -120,6 +346,10 @@ const (
 	KeyNetwork = "metrics:network"
 	KeyHost    = "metrics:host"
 	KeyAll     = "metrics:all"
+	KeyDocker  = "metrics:docker" // Per-container usage for include=docker
+
+	KeyDatabases = "services:databases"
+	KeyDNS       = "dns"
 )
 
 // MetricsCache is a specialized cache for system metrics
@@ -127,9 +357,14 @@ type MetricsCache struct {
 	*Cache
 }
 
-// NewMetricsCache creates a new metrics cache with a short TTL
-func NewMetricsCache() *MetricsCache {
-	return &MetricsCache{
-		Cache: New(2 * time.Second), // Metrics cached for 2 seconds
+// NewMetricsCache creates a metrics cache with a default TTL and per-key
+// overrides
+func NewMetricsCache(ttl time.Duration, ttls map[string]time.Duration) *MetricsCache {
+	mc := &MetricsCache{
+		Cache: New(ttl),
+	}
+	for key, keyTTL := range ttls {
+		mc.SetKeyTTL(key, keyTTL)
 	}
+	return mc
 }
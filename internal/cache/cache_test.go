@@ -1,10 +1,15 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -70,10 +75,16 @@ func TestCache_Delete(t *testing.T) {
 	c := New(time.Hour)
 
 	c.Set("key", "value")
-	c.Delete("key")
+	assert.True(t, c.Delete("key"))
+	assert.False(t, c.Delete("key"))
 
 	_, found := c.Get("key")
 	assert.False(t, found)
+
+	// Deleting is not a lookup
+	stats := c.Stats()
+	assert.Equal(t, uint64(0), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
 }
 
 func TestCache_Clear(t *testing.T) {
@@ -111,8 +122,133 @@ func TestCache_GetOrSet(t *testing.T) {
 	assert.Equal(t, 1, callCount) // Function not called again
 }
 
+func TestCache_GetOrSetCoalescesConcurrentMisses(t *testing.T) {
+	c := New(time.Hour)
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "computed", nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make(chan interface{}, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := c.GetOrSet("key", fn)
+			assert.NoError(t, err)
+			results <- val
+		}()
+	}
+
+	// Give all callers time to block on the in-flight computation
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for val := range results {
+		assert.Equal(t, "computed", val)
+	}
+}
+
+func TestCache_GetOrSetError(t *testing.T) {
+	c := New(time.Hour)
+
+	_, err := c.GetOrSet("key", func() (interface{}, error) {
+		return nil, errors.New("collection failed")
+	})
+	assert.Error(t, err)
+
+	// Errors are not cached
+	_, found := c.Get("key")
+	assert.False(t, found)
+}
+
+func TestCache_KeyTTL(t *testing.T) {
+	c := New(time.Hour)
+
+	c.SetKeyTTL("short", 50*time.Millisecond)
+	assert.Equal(t, 50*time.Millisecond, c.TTL("short"))
+	assert.Equal(t, time.Hour, c.TTL("other"))
+
+	c.Set("short", "value")
+	time.Sleep(100 * time.Millisecond)
+	_, found := c.Get("short")
+	assert.False(t, found)
+
+	// Removing the override restores the default TTL
+	c.SetKeyTTL("short", 0)
+	assert.Equal(t, time.Hour, c.TTL("short"))
+}
+
+func TestCache_Stats(t *testing.T) {
+	c := New(time.Hour)
+
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Get("missing")
+
+	stats := c.Stats()
+	assert.Equal(t, 1, stats.Size)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.InDelta(t, 2.0/3.0, stats.HitRatio, 0.001)
+
+	require.Len(t, stats.Entries, 2)
+	assert.Equal(t, "a", stats.Entries[0].Key)
+	assert.True(t, stats.Entries[0].Cached)
+	assert.Equal(t, "missing", stats.Entries[1].Key)
+	assert.False(t, stats.Entries[1].Cached)
+
+	c.ResetStats()
+	stats = c.Stats()
+	assert.Equal(t, uint64(0), stats.Hits)
+}
+
+func TestCache_StatsBounded(t *testing.T) {
+	c := New(time.Hour)
+
+	for i := 0; i < maxCounters+50; i++ {
+		c.Get(fmt.Sprintf("key-%d", i))
+	}
+
+	stats := c.Stats()
+	assert.Len(t, stats.Entries, maxCounters+1)
+	assert.Equal(t, uint64(maxCounters+50), stats.Misses)
+	for _, entry := range stats.Entries {
+		if entry.Key == OtherKey {
+			assert.Equal(t, uint64(50), entry.Misses)
+		}
+	}
+}
+
+func TestCache_DeletePrefix(t *testing.T) {
+	c := New(time.Hour)
+
+	c.Set("metrics:cpu", 1)
+	c.Set("metrics:memory", 2)
+	c.Set("other", 3)
+
+	assert.Equal(t, 2, c.DeletePrefix("metrics:"))
+
+	_, found := c.Get("metrics:cpu")
+	assert.False(t, found)
+	_, found = c.Get("other")
+	assert.True(t, found)
+}
+
 func TestMetricsCache(t *testing.T) {
-	mc := NewMetricsCache()
+	mc := NewMetricsCache(2*time.Second, map[string]time.Duration{KeyDisk: time.Minute})
+	assert.Equal(t, 2*time.Second, mc.TTL(KeyCPU))
+	assert.Equal(t, time.Minute, mc.TTL(KeyDisk))
 
 	mc.Set(KeyCPU, "cpu-data")
 	mc.Set(KeyMemory, "memory-data")
@@ -151,3 +287,13 @@ func TestCache_ConcurrentAccess(t *testing.T) {
 	<-done
 	<-done
 }
+
+func TestCache_SetDefaultTTL(t *testing.T) {
+	c := New(time.Second)
+	c.SetKeyTTL("slow", time.Hour)
+
+	c.SetDefaultTTL(time.Minute)
+	assert.Equal(t, time.Minute, c.TTL("fast"))
+	assert.Equal(t, time.Hour, c.TTL("slow"))
+	assert.Equal(t, time.Minute.Milliseconds(), c.Stats().DefaultTTL)
+}
@@ -0,0 +1,173 @@
+package cloudmeta
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Providers recognised from DMI
+const (
+	ProviderAWS          = "aws"
+	ProviderGCP          = "gcp"
+	ProviderAzure        = "azure"
+	ProviderOracle       = "oracle"
+	ProviderDigitalOcean = "digitalocean"
+	ProviderHetzner      = "hetzner"
+	ProviderOpenStack    = "openstack"
+	ProviderVultr        = "vultr"
+	ProviderLinode       = "linode"
+	ProviderScaleway     = "scaleway"
+)
+
+// probeTimeout bounds the metadata service queries as a whole
+const probeTimeout = 3 * time.Second
+
+// azureAssetTag is the chassis asset tag of every Azure VM
+const azureAssetTag = "7783-7084-3265-9085-8269-3286-77"
+
+// Overridden in tests
+var (
+	dmiRoot        = "/sys/class/dmi/id"
+	deviceTreeRoot = "/sys/firmware/devicetree/base"
+	metadataURL    = "http://169.254.169.254"
+)
+
+// Metadata identifies the machine the agent runs on. Fields that could not
+// be determined are empty.
+type Metadata struct {
+	Provider     string `json:"provider,omitempty"`
+	InstanceType string `json:"instance_type,omitempty"`
+	InstanceID   string `json:"instance_id,omitempty"`
+	Region       string `json:"region,omitempty"`
+	Zone         string `json:"zone,omitempty"`
+	Vendor       string `json:"vendor,omitempty"`  // DMI system vendor
+	Product      string `json:"product,omitempty"` // DMI product name
+	Model        string `json:"model,omitempty"`   // Device-tree model, e.g. on ARM boards
+}
+
+// Detector finds the machine's metadata once and caches it
+type Detector struct {
+	query  bool
+	client *http.Client
+
+	once sync.Once
+	meta Metadata
+}
+
+// NewDetector creates a detector. query enables the cloud metadata
+// services; DMI and the device tree are always read.
+func NewDetector(query bool) *Detector {
+	return &Detector{
+		query:  query,
+		client: &http.Client{Timeout: probeTimeout},
+	}
+}
+
+// Get returns the metadata, detecting it on first use. A metadata service
+// is only queried when DMI names a provider that has one, so machines
+// outside a cloud never wait on it.
+func (d *Detector) Get() Metadata {
+	d.once.Do(func() {
+		d.meta = d.detect()
+	})
+	return d.meta
+}
+
+func (d *Detector) detect() Metadata {
+	dmi := readDMI()
+	meta := Metadata{
+		Provider: dmi.provider(),
+		Vendor:   dmi.vendor,
+		Product:  dmi.product,
+		Model:    readDeviceTree("model"),
+	}
+
+	if probe := probes[meta.Provider]; probe != nil && d.query {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		probe(ctx, &client{http: d.client}, &meta)
+	}
+	return meta
+}
+
+// dmi holds the DMI fields that identify a provider
+type dmi struct {
+	vendor, product, biosVendor, assetTag string
+}
+
+func readDMI() dmi {
+	return dmi{
+		vendor:     readDMIField("sys_vendor"),
+		product:    readDMIField("product_name"),
+		biosVendor: readDMIField("bios_vendor"),
+		assetTag:   readDMIField("chassis_asset_tag"),
+	}
+}
+
+func (d dmi) provider() string {
+	vendor := strings.ToLower(d.vendor)
+	switch {
+	case d.assetTag == azureAssetTag:
+		return ProviderAzure
+	case strings.HasPrefix(d.assetTag, "OracleCloud"):
+		return ProviderOracle
+	case strings.Contains(vendor, "amazon") || strings.Contains(strings.ToLower(d.biosVendor), "amazon"):
+		return ProviderAWS
+	case vendor == "google" || d.product == "Google Compute Engine":
+		return ProviderGCP
+	case vendor == "digitalocean":
+		return ProviderDigitalOcean
+	case vendor == "hetzner":
+		return ProviderHetzner
+	case vendor == "vultr":
+		return ProviderVultr
+	case vendor == "linode" || vendor == "akamai":
+		return ProviderLinode
+	case vendor == "scaleway":
+		return ProviderScaleway
+	case strings.Contains(vendor, "openstack") || strings.Contains(strings.ToLower(d.product), "openstack"):
+		return ProviderOpenStack
+	}
+	return ""
+}
+
+// placeholders are values firmware ships instead of a real vendor or
+// product name
+var placeholders = map[string]bool{
+	"":                       true,
+	"default string":         true,
+	"to be filled by o.e.m.": true,
+	"system manufacturer":    true,
+	"system product name":    true,
+	"not specified":          true,
+	"none":                   true,
+	"o.e.m.":                 true,
+	"unknown":                true,
+}
+
+func readDMIField(name string) string {
+	data, err := os.ReadFile(filepath.Join(dmiRoot, name))
+	if err != nil {
+		return ""
+	}
+	value := strings.TrimSpace(string(data))
+	if placeholders[strings.ToLower(value)] {
+		return ""
+	}
+	return value
+}
+
+// readDeviceTree reads a device-tree string property, which is NUL
+// terminated
+func readDeviceTree(name string) string {
+	data, err := os.ReadFile(filepath.Join(deviceTreeRoot, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+}
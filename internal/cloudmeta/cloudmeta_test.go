@@ -0,0 +1,114 @@
+package cloudmeta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMachine points the detector at temporary DMI and device-tree
+// directories and a fake metadata service
+func fakeMachine(t *testing.T, dmi map[string]string, model string, handler http.HandlerFunc) {
+	dir := t.TempDir()
+	origDMI, origDT, origURL := dmiRoot, deviceTreeRoot, metadataURL
+	dmiRoot = filepath.Join(dir, "dmi")
+	deviceTreeRoot = filepath.Join(dir, "devicetree")
+	t.Cleanup(func() { dmiRoot, deviceTreeRoot, metadataURL = origDMI, origDT, origURL })
+
+	require.NoError(t, os.MkdirAll(dmiRoot, 0755))
+	require.NoError(t, os.MkdirAll(deviceTreeRoot, 0755))
+	for name, value := range dmi {
+		require.NoError(t, os.WriteFile(filepath.Join(dmiRoot, name), []byte(value+"\n"), 0644))
+	}
+	if model != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(deviceTreeRoot, "model"), []byte(model+"\x00"), 0644))
+	}
+
+	if handler != nil {
+		srv := httptest.NewServer(handler)
+		t.Cleanup(srv.Close)
+		metadataURL = srv.URL
+	}
+}
+
+func TestDetect_EC2(t *testing.T) {
+	values := map[string]string{
+		"/latest/meta-data/instance-type":               "t4g.small",
+		"/latest/meta-data/instance-id":                 "i-0abc",
+		"/latest/meta-data/placement/region":            "eu-west-1",
+		"/latest/meta-data/placement/availability-zone": "eu-west-1b",
+	}
+	fakeMachine(t, map[string]string{"sys_vendor": "Amazon EC2", "product_name": "t4g.small"}, "", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			assert.Equal(t, http.MethodPut, r.Method)
+			w.Write([]byte("token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(values[r.URL.Path]))
+	})
+
+	meta := NewDetector(true).Get()
+	assert.Equal(t, Metadata{
+		Provider:     ProviderAWS,
+		InstanceType: "t4g.small",
+		InstanceID:   "i-0abc",
+		Region:       "eu-west-1",
+		Zone:         "eu-west-1b",
+		Vendor:       "Amazon EC2",
+		Product:      "t4g.small",
+	}, meta)
+}
+
+func TestDetect_GCP(t *testing.T) {
+	fakeMachine(t, map[string]string{"sys_vendor": "Google", "product_name": "Google Compute Engine"}, "", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/machine-type":
+			w.Write([]byte("projects/123/machineTypes/e2-medium"))
+		case "/computeMetadata/v1/instance/zone":
+			w.Write([]byte("projects/123/zones/us-central1-a"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	meta := NewDetector(true).Get()
+	assert.Equal(t, ProviderGCP, meta.Provider)
+	assert.Equal(t, "e2-medium", meta.InstanceType)
+	assert.Equal(t, "us-central1-a", meta.Zone)
+	assert.Equal(t, "us-central1", meta.Region)
+	assert.Empty(t, meta.InstanceID)
+}
+
+func TestDetect_Local(t *testing.T) {
+	// Placeholder DMI values name no provider, so nothing is queried
+	queried := false
+	fakeMachine(t, map[string]string{"sys_vendor": "To Be Filled By O.E.M.", "product_name": "Default string"}, "Raspberry Pi 4 Model B Rev 1.4", func(w http.ResponseWriter, r *http.Request) {
+		queried = true
+	})
+
+	meta := NewDetector(true).Get()
+	assert.Equal(t, Metadata{Model: "Raspberry Pi 4 Model B Rev 1.4"}, meta)
+	assert.False(t, queried)
+}
+
+func TestDetect_QueryDisabled(t *testing.T) {
+	fakeMachine(t, map[string]string{"chassis_asset_tag": azureAssetTag, "sys_vendor": "Microsoft Corporation"}, "", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("metadata service queried")
+	})
+
+	meta := NewDetector(false).Get()
+	assert.Equal(t, Metadata{Provider: ProviderAzure, Vendor: "Microsoft Corporation"}, meta)
+}
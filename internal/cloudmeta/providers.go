@@ -0,0 +1,152 @@
+package cloudmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// probe fills in what a provider's metadata service reports. Failures
+// leave fields empty.
+type probe func(ctx context.Context, c *client, meta *Metadata)
+
+var probes = map[string]probe{
+	ProviderAWS:          probeEC2,
+	ProviderOpenStack:    probeEC2, // OpenStack serves the EC2 paths too
+	ProviderGCP:          probeGCP,
+	ProviderAzure:        probeAzure,
+	ProviderOracle:       probeOracle,
+	ProviderDigitalOcean: probeDigitalOcean,
+	ProviderHetzner:      probeHetzner,
+}
+
+// client queries the link-local metadata service
+type client struct {
+	http *http.Client
+}
+
+func (c *client) do(ctx context.Context, method, p string, header map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, metadataURL+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", method, p, resp.Status)
+	}
+	return body, nil
+}
+
+// text returns a plain-text value, or "" if it could not be read
+func (c *client) text(ctx context.Context, p string, header map[string]string) string {
+	body, err := c.do(ctx, http.MethodGet, p, header)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func (c *client) json(ctx context.Context, p string, header map[string]string, v interface{}) bool {
+	body, err := c.do(ctx, http.MethodGet, p, header)
+	return err == nil && json.Unmarshal(body, v) == nil
+}
+
+// probeEC2 uses an IMDSv2 token when the service issues one and falls
+// back to IMDSv1
+func probeEC2(ctx context.Context, c *client, meta *Metadata) {
+	header := map[string]string{}
+	token, err := c.do(ctx, http.MethodPut, "/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err == nil {
+		header["X-aws-ec2-metadata-token"] = string(token)
+	}
+
+	meta.InstanceType = c.text(ctx, "/latest/meta-data/instance-type", header)
+	meta.InstanceID = c.text(ctx, "/latest/meta-data/instance-id", header)
+	meta.Region = c.text(ctx, "/latest/meta-data/placement/region", header)
+	meta.Zone = c.text(ctx, "/latest/meta-data/placement/availability-zone", header)
+}
+
+func probeGCP(ctx context.Context, c *client, meta *Metadata) {
+	header := map[string]string{"Metadata-Flavor": "Google"}
+	meta.InstanceID = c.text(ctx, "/computeMetadata/v1/instance/id", header)
+
+	// Both are reported as projects/<number>/<kind>/<name>
+	if machineType := c.text(ctx, "/computeMetadata/v1/instance/machine-type", header); machineType != "" {
+		meta.InstanceType = path.Base(machineType)
+	}
+	if zone := c.text(ctx, "/computeMetadata/v1/instance/zone", header); zone != "" {
+		meta.Zone = path.Base(zone)
+		if i := strings.LastIndex(meta.Zone, "-"); i > 0 {
+			meta.Region = meta.Zone[:i]
+		}
+	}
+}
+
+func probeAzure(ctx context.Context, c *client, meta *Metadata) {
+	var compute struct {
+		VMID     string `json:"vmId"`
+		VMSize   string `json:"vmSize"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}
+	if !c.json(ctx, "/metadata/instance/compute?api-version=2021-02-01", map[string]string{"Metadata": "true"}, &compute) {
+		return
+	}
+	meta.InstanceID = compute.VMID
+	meta.InstanceType = compute.VMSize
+	meta.Region = compute.Location
+	meta.Zone = compute.Zone
+}
+
+func probeOracle(ctx context.Context, c *client, meta *Metadata) {
+	var instance struct {
+		ID                 string `json:"id"`
+		Shape              string `json:"shape"`
+		CanonicalRegion    string `json:"canonicalRegionName"`
+		AvailabilityDomain string `json:"availabilityDomain"`
+	}
+	if !c.json(ctx, "/opc/v2/instance/", map[string]string{"Authorization": "Bearer Oracle"}, &instance) {
+		return
+	}
+	meta.InstanceID = instance.ID
+	meta.InstanceType = instance.Shape
+	meta.Region = instance.CanonicalRegion
+	meta.Zone = instance.AvailabilityDomain
+}
+
+// probeDigitalOcean reads the droplet's region; the metadata service does
+// not report its size
+func probeDigitalOcean(ctx context.Context, c *client, meta *Metadata) {
+	var droplet struct {
+		ID     json.Number `json:"droplet_id"`
+		Region string      `json:"region"`
+	}
+	if !c.json(ctx, "/metadata/v1.json", nil, &droplet) {
+		return
+	}
+	meta.InstanceID = droplet.ID.String()
+	meta.Region = droplet.Region
+}
+
+func probeHetzner(ctx context.Context, c *client, meta *Metadata) {
+	meta.InstanceID = c.text(ctx, "/hetzner/v1/metadata/instance-id", nil)
+	meta.Region = c.text(ctx, "/hetzner/v1/metadata/region", nil)
+	meta.Zone = c.text(ctx, "/hetzner/v1/metadata/availability-zone", nil)
+}
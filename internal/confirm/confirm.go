@@ -0,0 +1,86 @@
+package confirm
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// DefaultTTL is how long a confirmation token stays valid
+const DefaultTTL = 60 * time.Second
+
+// ErrInvalidToken is returned for unknown, expired, reused or mismatched tokens
+var ErrInvalidToken = apierror.NotAllowed("invalid or expired confirmation token")
+
+// Store issues single-use confirmation tokens bound to an action and target
+type Store struct {
+	ttl        time.Duration
+	challenges map[string]Challenge
+	mu         sync.Mutex
+}
+
+// NewStore creates a new confirmation token store
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{
+		ttl:        ttl,
+		challenges: make(map[string]Challenge),
+	}
+}
+
+// Issue creates a challenge for action on target with a human-readable impact
+func (s *Store) Issue(action, target, impact string) *Challenge {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	challenge := Challenge{
+		Token:     hex.EncodeToString(b),
+		Action:    action,
+		Target:    target,
+		Impact:    impact,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	s.challenges[challenge.Token] = challenge
+
+	return &challenge
+}
+
+// Consume validates a token for action on target. Tokens can only be used once.
+func (s *Store) Consume(token, action, target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, challenge := range s.challenges {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) != 1 {
+			continue
+		}
+
+		delete(s.challenges, key)
+		if time.Now().After(challenge.ExpiresAt) || challenge.Action != action || challenge.Target != target {
+			return ErrInvalidToken
+		}
+		return nil
+	}
+
+	return ErrInvalidToken
+}
+
+func (s *Store) prune() {
+	now := time.Now()
+	for token, challenge := range s.challenges {
+		if now.After(challenge.ExpiresAt) {
+			delete(s.challenges, token)
+		}
+	}
+}
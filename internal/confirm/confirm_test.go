@@ -0,0 +1,35 @@
+package confirm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_ConsumeOnce(t *testing.T) {
+	s := NewStore(time.Minute)
+
+	challenge := s.Issue("task.run", "wipe", "Runs `wipe`")
+	assert.NotEmpty(t, challenge.Token)
+	assert.Equal(t, "Runs `wipe`", challenge.Impact)
+
+	assert.NoError(t, s.Consume(challenge.Token, "task.run", "wipe"))
+	assert.ErrorIs(t, s.Consume(challenge.Token, "task.run", "wipe"), ErrInvalidToken)
+}
+
+func TestStore_RejectsMismatchAndExpiry(t *testing.T) {
+	s := NewStore(time.Minute)
+
+	challenge := s.Issue("system.reboot", "now", "")
+	assert.ErrorIs(t, s.Consume(challenge.Token, "system.reboot", "+5m"), ErrInvalidToken)
+	// A failed attempt burns the token
+	assert.ErrorIs(t, s.Consume(challenge.Token, "system.reboot", "now"), ErrInvalidToken)
+
+	short := NewStore(time.Millisecond)
+	expired := short.Issue("system.reboot", "now", "")
+	time.Sleep(5 * time.Millisecond)
+	assert.ErrorIs(t, short.Consume(expired.Token, "system.reboot", "now"), ErrInvalidToken)
+
+	assert.ErrorIs(t, s.Consume("guess", "system.reboot", "now"), ErrInvalidToken)
+}
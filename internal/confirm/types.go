@@ -0,0 +1,12 @@
+package confirm
+
+import "time"
+
+// Challenge is issued for a dangerous action and must be echoed back to execute it
+type Challenge struct {
+	Token     string    `json:"token"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Impact    string    `json:"impact"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,161 @@
+package coredumps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// runCommand runs a command and returns its output; replaced in tests
+var runCommand = defaultRunCommand
+
+func defaultRunCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// lookPath finds coredumpctl; replaced in tests
+var lookPath = exec.LookPath
+
+var errUnavailable = apierror.Unavailable("coredumpctl not found (install systemd-coredump)")
+
+// signalNames are the signals that produce a core dump
+var signalNames = map[int]string{
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	11: "SIGSEGV",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+	31: "SIGSYS",
+}
+
+// noMatch is how coredumpctl reports that nothing was found
+const noMatch = "No coredumps found"
+
+// List returns the recorded crashes, newest first, optionally since a
+// time coredumpctl understands such as "yesterday" or "2024-01-02 03:00".
+// limit <= 0 returns all of them.
+func List(ctx context.Context, since string, limit int) (*CoredumpList, error) {
+	if _, err := lookPath("coredumpctl"); err != nil {
+		return nil, errUnavailable
+	}
+
+	args := []string{"list", "--json=short", "--no-pager"}
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+
+	list := &CoredumpList{Coredumps: []Entry{}}
+	out, err := runCommand(ctx, "coredumpctl", args...)
+	if err != nil {
+		if strings.Contains(err.Error(), noMatch) {
+			return list, nil
+		}
+		return nil, err
+	}
+
+	entries, err := parseList(out)
+	if err != nil {
+		return nil, err
+	}
+	list.Total = len(entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	list.Coredumps = entries
+	return list, nil
+}
+
+// parseList parses coredumpctl list --json=short
+func parseList(out []byte) ([]Entry, error) {
+	var raw []struct {
+		Time     int64  `json:"time"` // Microseconds since the epoch
+		PID      int    `json:"pid"`
+		UID      int    `json:"uid"`
+		GID      int    `json:"gid"`
+		Sig      int    `json:"sig"`
+		Corefile string `json:"corefile"`
+		Exe      string `json:"exe"`
+		Size     *int64 `json:"size"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse coredumpctl output: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(raw))
+	for _, r := range raw {
+		e := Entry{
+			Time:       time.UnixMicro(r.Time),
+			PID:        r.PID,
+			UID:        r.UID,
+			GID:        r.GID,
+			Signal:     r.Sig,
+			SignalName: signalNames[r.Sig],
+			Process:    filepath.Base(r.Exe),
+			Executable: r.Exe,
+			Corefile:   r.Corefile,
+		}
+		if r.Size != nil {
+			e.Size = *r.Size
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	return entries, nil
+}
+
+// Get returns the metadata of the latest crash of a PID
+func Get(ctx context.Context, pid int) (*Info, error) {
+	if _, err := lookPath("coredumpctl"); err != nil {
+		return nil, errUnavailable
+	}
+
+	out, err := runCommand(ctx, "coredumpctl", "info", "-1", "--no-pager", strconv.Itoa(pid))
+	if err != nil {
+		if strings.Contains(err.Error(), noMatch) || strings.Contains(err.Error(), "No match found") {
+			return nil, apierror.NotFound("no core dump for PID %d", pid)
+		}
+		return nil, err
+	}
+	return parseInfo(pid, string(out)), nil
+}
+
+// parseInfo parses coredumpctl info, whose fields are right-aligned
+// "Name: value" lines. Message comes last and runs to the end.
+func parseInfo(pid int, out string) *Info {
+	info := &Info{PID: pid, Fields: make(map[string]string)}
+
+	lines := strings.Split(out, "\n")
+	for i, line := range lines {
+		key, value, ok := strings.Cut(line, ": ")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.Contains(key, "  ") {
+			continue
+		}
+		if key == "Message" {
+			stack := []string{strings.TrimSpace(value)}
+			for _, rest := range lines[i+1:] {
+				stack = append(stack, strings.TrimSpace(rest))
+			}
+			info.Stack = strings.TrimSpace(strings.Join(stack, "\n"))
+			break
+		}
+		info.Fields[key] = strings.TrimSpace(value)
+	}
+	return info
+}
@@ -0,0 +1,95 @@
+package coredumps
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const listOutput = `[
+{"time":1696216364000000,"pid":1234,"uid":1000,"gid":1000,"sig":11,"corefile":"present","exe":"/usr/bin/foo","size":126418},
+{"time":1696302764000000,"pid":5678,"uid":0,"gid":0,"sig":6,"corefile":"none","exe":"/usr/sbin/bar","size":null}
+]`
+
+const infoOutput = `           PID: 1234 (foo)
+           UID: 1000 (user)
+        Signal: 11 (SEGV)
+     Timestamp: Mon 2023-10-02 03:12:44 UTC (1 day ago)
+  Command Line: /usr/bin/foo --serve
+    Executable: /usr/bin/foo
+       Storage: /var/lib/systemd/coredump/core.foo.1000.zst (present)
+  Size on Disk: 123.4K
+       Message: Process 1234 (foo) of user 1000 dumped core.
+
+                Stack trace of thread 1234:
+                #0  0x00007f2c3e4a98ab raise (libc.so.6 + 0x3c8ab)
+`
+
+func fakeCoredumpctl(t *testing.T, run func(args []string) ([]byte, error)) {
+	origRun, origLook := runCommand, lookPath
+	t.Cleanup(func() { runCommand, lookPath = origRun, origLook })
+
+	lookPath = func(string) (string, error) { return "/usr/bin/coredumpctl", nil }
+	runCommand = func(_ context.Context, _ string, args ...string) ([]byte, error) { return run(args) }
+}
+
+func TestList(t *testing.T) {
+	var got []string
+	fakeCoredumpctl(t, func(args []string) ([]byte, error) {
+		got = args
+		return []byte(listOutput), nil
+	})
+
+	list, err := List(context.Background(), "yesterday", 1)
+	require.NoError(t, err)
+	assert.Contains(t, got, "--since=yesterday")
+	assert.Equal(t, 2, list.Total)
+	require.Len(t, list.Coredumps, 1)
+
+	// Newest first
+	e := list.Coredumps[0]
+	assert.Equal(t, 5678, e.PID)
+	assert.Equal(t, "bar", e.Process)
+	assert.Equal(t, "SIGABRT", e.SignalName)
+	assert.Equal(t, int64(0), e.Size)
+	assert.Equal(t, time.UnixMicro(1696302764000000), e.Time)
+}
+
+func TestList_Empty(t *testing.T) {
+	fakeCoredumpctl(t, func([]string) ([]byte, error) {
+		return nil, errors.New("coredumpctl: No coredumps found.")
+	})
+
+	list, err := List(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, list.Coredumps)
+
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	_, err = List(context.Background(), "", 0)
+	assert.True(t, errors.Is(err, apierror.ErrUnavailable))
+}
+
+func TestGet(t *testing.T) {
+	fakeCoredumpctl(t, func(args []string) ([]byte, error) {
+		if args[len(args)-1] != "1234" {
+			return nil, errors.New("coredumpctl: No match found.")
+		}
+		return []byte(infoOutput), nil
+	})
+
+	info, err := Get(context.Background(), 1234)
+	require.NoError(t, err)
+	assert.Equal(t, "11 (SEGV)", info.Fields["Signal"])
+	assert.Equal(t, "/usr/bin/foo --serve", info.Fields["Command Line"])
+	assert.Equal(t, "123.4K", info.Fields["Size on Disk"])
+	assert.Contains(t, info.Stack, "Stack trace of thread 1234:\n#0  0x00007f2c3e4a98ab raise")
+
+	_, err = Get(context.Background(), 99)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+}
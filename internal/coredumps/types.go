@@ -0,0 +1,30 @@
+package coredumps
+
+import "time"
+
+// Entry is one crash recorded by systemd-coredump
+type Entry struct {
+	Time       time.Time `json:"time"`
+	PID        int       `json:"pid"`
+	UID        int       `json:"uid"`
+	GID        int       `json:"gid"`
+	Signal     int       `json:"signal"`
+	SignalName string    `json:"signal_name,omitempty"` // e.g. SIGSEGV
+	Process    string    `json:"process"`               // Base name of Executable
+	Executable string    `json:"executable"`
+	Corefile   string    `json:"corefile"` // present, missing, truncated, none...
+	Size       int64     `json:"size"`     // Bytes; 0 when the dump was not kept
+}
+
+// CoredumpList is the recorded crashes, newest first
+type CoredumpList struct {
+	Coredumps []Entry `json:"coredumps"`
+	Total     int     `json:"total"`
+}
+
+// Info is coredumpctl's metadata for one crash
+type Info struct {
+	PID    int               `json:"pid"`
+	Fields map[string]string `json:"fields"`          // e.g. "Signal", "Command Line", "Storage"
+	Stack  string            `json:"stack,omitempty"` // The Message field, usually the stack trace
+}
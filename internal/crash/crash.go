@@ -0,0 +1,315 @@
+package crash
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/secrets"
+)
+
+const (
+	sendTimeout = 10 * time.Second
+	// dedupWindow suppresses repeats of the same panic so a handler that
+	// panics on every request does not flood the destination
+	dedupWindow = time.Minute
+)
+
+// Reporter sends crash reports to a Sentry DSN and/or a webhook
+type Reporter struct {
+	opts      Options
+	sentryURL string
+	sentryKey string
+	http      *http.Client
+
+	recent map[string]time.Time
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// NewReporter creates a reporter. It returns nil when no destination is
+// configured.
+func NewReporter(opts Options) (*Reporter, error) {
+	if opts.SentryDSN == "" && opts.WebhookURL == "" {
+		return nil, nil
+	}
+
+	r := &Reporter{
+		opts:   opts,
+		http:   &http.Client{Timeout: sendTimeout},
+		recent: make(map[string]time.Time),
+	}
+
+	if opts.SentryDSN != "" {
+		endpoint, key, err := parseDSN(opts.SentryDSN)
+		if err != nil {
+			return nil, err
+		}
+		r.sentryURL, r.sentryKey = endpoint, key
+	}
+
+	return r, nil
+}
+
+// OptionsFromEnv reads SENTRY_DSN and CRASH_WEBHOOK_URL from the
+// environment, for failures before the configuration has loaded. Encrypted
+// values need the configuration to decrypt, so they are left out.
+func OptionsFromEnv(version string) Options {
+	opts := Options{Version: version}
+	if dsn := os.Getenv("SENTRY_DSN"); !secrets.IsEncrypted(dsn) {
+		opts.SentryDSN = dsn
+	}
+	if url := os.Getenv("CRASH_WEBHOOK_URL"); !secrets.IsEncrypted(url) {
+		opts.WebhookURL = url
+	}
+	opts.Hostname, _ = os.Hostname()
+	return opts
+}
+
+// Panic reports a recovered panic in the background. stack is the output
+// of debug.Stack and req, if set, the request being served.
+func (r *Reporter) Panic(value interface{}, stack []byte, req *http.Request) {
+	rep := r.newReport(KindPanic, fmt.Sprint(value), stack)
+	if req != nil {
+		rep.Request = &Request{Method: req.Method, Path: req.URL.Path}
+	}
+
+	if r.duplicate(rep) {
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if err := r.send(rep); err != nil {
+			log.Printf("Crash report failed: %v", err)
+		}
+	}()
+}
+
+// Fatal reports an error that stops the agent. It blocks until the report
+// is sent so it can be called right before exiting.
+func (r *Reporter) Fatal(err error, stack []byte) {
+	rep := r.newReport(KindFatal, err.Error(), stack)
+	if err := r.send(rep); err != nil {
+		log.Printf("Crash report failed: %v", err)
+	}
+	r.Flush()
+}
+
+// Flush waits for panic reports still being sent
+func (r *Reporter) Flush() {
+	r.wg.Wait()
+}
+
+func (r *Reporter) newReport(kind, message string, stack []byte) Report {
+	return Report{
+		ID:        newEventID(),
+		Timestamp: time.Now().UTC(),
+		Kind:      kind,
+		Message:   message,
+		Stack:     string(stack),
+		Frames:    ParseStack(stack),
+		Version:   r.opts.Version,
+		Hostname:  r.opts.Hostname,
+	}
+}
+
+func (r *Reporter) duplicate(rep Report) bool {
+	key := rep.Message
+	if rep.Request != nil {
+		key = rep.Request.Method + " " + rep.Request.Path + " " + key
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for k, t := range r.recent {
+		if now.Sub(t) > dedupWindow {
+			delete(r.recent, k)
+		}
+	}
+	if _, ok := r.recent[key]; ok {
+		return true
+	}
+	r.recent[key] = now
+	return false
+}
+
+// send delivers a report to every configured destination
+func (r *Reporter) send(rep Report) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	var errs []error
+	if r.sentryURL != "" {
+		if err := r.post(ctx, r.sentryURL, sentryEvent(rep), r.sentryAuth()); err != nil {
+			errs = append(errs, fmt.Errorf("sentry: %w", err))
+		}
+	}
+	if r.opts.WebhookURL != "" {
+		if err := r.post(ctx, r.opts.WebhookURL, rep, nil); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Reporter) post(ctx context.Context, target string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (r *Reporter) sentryAuth() map[string]string {
+	return map[string]string{
+		"X-Sentry-Auth": fmt.Sprintf("Sentry sentry_version=7, sentry_client=hivedeck-agent/%s, sentry_key=%s",
+			r.opts.Version, r.sentryKey),
+	}
+}
+
+// parseDSN turns https://<key>@<host>/<project> into the store endpoint
+// and public key
+func parseDSN(dsn string) (endpoint, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+
+	key = u.User.Username()
+	project := strings.Trim(u.Path, "/")
+	if key == "" || project == "" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid Sentry DSN: expected https://<key>@<host>/<project>")
+	}
+
+	// Sentry may be hosted under a path prefix; the project is the last segment
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+
+	return fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project), key, nil
+}
+
+// sentryEvent converts a report to the Sentry event format
+func sentryEvent(rep Report) map[string]interface{} {
+	// Sentry lists frames oldest first
+	frames := make([]map[string]interface{}, 0, len(rep.Frames))
+	for i := len(rep.Frames) - 1; i >= 0; i-- {
+		f := rep.Frames[i]
+		frames = append(frames, map[string]interface{}{
+			"function": f.Function,
+			"filename": f.File,
+			"lineno":   f.Line,
+			"in_app":   strings.Contains(f.Function, "hivedeck-agent"),
+		})
+	}
+
+	level := "error"
+	if rep.Kind == KindFatal {
+		level = "fatal"
+	}
+
+	exception := map[string]interface{}{
+		"type":  rep.Kind,
+		"value": rep.Message,
+	}
+	if len(frames) > 0 {
+		exception["stacktrace"] = map[string]interface{}{"frames": frames}
+	}
+
+	event := map[string]interface{}{
+		"event_id":    rep.ID,
+		"timestamp":   rep.Timestamp.Format(time.RFC3339),
+		"level":       level,
+		"platform":    "go",
+		"logger":      "hivedeck-agent",
+		"release":     rep.Version,
+		"server_name": rep.Hostname,
+		"exception":   map[string]interface{}{"values": []interface{}{exception}},
+		"tags":        map[string]string{"kind": rep.Kind},
+	}
+	if rep.Request != nil {
+		event["request"] = map[string]string{"method": rep.Request.Method, "url": rep.Request.Path}
+	}
+	return event
+}
+
+// ParseStack extracts frames from debug.Stack output, skipping the frames
+// of the stack capture itself
+func ParseStack(stack []byte) []Frame {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	var frames []Frame
+
+	// The first line is the goroutine header; then function/location pairs
+	for i := 1; i+1 < len(lines); i += 2 {
+		function := strings.TrimSpace(lines[i])
+		location := strings.TrimSpace(lines[i+1])
+
+		function = strings.TrimPrefix(function, "created by ")
+		if j := strings.Index(function, " in goroutine"); j >= 0 {
+			function = function[:j]
+		}
+		if j := strings.LastIndex(function, "("); j > 0 && strings.HasSuffix(function, ")") {
+			function = function[:j]
+		}
+		if function == "runtime/debug.Stack" {
+			continue
+		}
+
+		if j := strings.LastIndex(location, " +0x"); j >= 0 {
+			location = location[:j]
+		}
+		file, line := location, 0
+		if j := strings.LastIndex(location, ":"); j >= 0 {
+			file = location[:j]
+			line, _ = strconv.Atoi(location[j+1:])
+		}
+
+		frames = append(frames, Frame{Function: function, File: file, Line: line})
+	}
+
+	return frames
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,127 @@
+package crash
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReporterDisabled(t *testing.T) {
+	r, err := NewReporter(Options{})
+	require.NoError(t, err)
+	assert.Nil(t, r)
+}
+
+func TestParseDSN(t *testing.T) {
+	endpoint, key, err := parseDSN("https://abc123@o1.ingest.sentry.io/42")
+	require.NoError(t, err)
+	assert.Equal(t, "https://o1.ingest.sentry.io/api/42/store/", endpoint)
+	assert.Equal(t, "abc123", key)
+
+	endpoint, _, err = parseDSN("https://abc123@sentry.lan/prefix/7")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sentry.lan/prefix/api/7/store/", endpoint)
+
+	_, _, err = parseDSN("https://sentry.lan/42")
+	assert.Error(t, err)
+}
+
+func TestParseStack(t *testing.T) {
+	frames := ParseStack(debug.Stack())
+	require.NotEmpty(t, frames)
+
+	assert.Equal(t, "github.com/ngenohkevin/hivedeck-agent/internal/crash.TestParseStack", frames[0].Function)
+	assert.True(t, strings.HasSuffix(frames[0].File, "crash_test.go"))
+	assert.Positive(t, frames[0].Line)
+}
+
+// collector records posted bodies and headers
+type collector struct {
+	mu      sync.Mutex
+	bodies  [][]byte
+	headers []http.Header
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	c := &collector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		c.mu.Lock()
+		c.bodies = append(c.bodies, body)
+		c.headers = append(c.headers, r.Header.Clone())
+		c.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func TestPanicReportsToWebhookOnce(t *testing.T) {
+	c, srv := newCollector(t)
+	r, err := NewReporter(Options{WebhookURL: srv.URL, Version: "v1.2.3", Hostname: "web-01"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/api/metrics", nil)
+	r.Panic("boom", debug.Stack(), req)
+	r.Panic("boom", debug.Stack(), req) // duplicate within the window
+	r.Flush()
+
+	require.Len(t, c.bodies, 1)
+	var rep Report
+	require.NoError(t, json.Unmarshal(c.bodies[0], &rep))
+	assert.Equal(t, KindPanic, rep.Kind)
+	assert.Equal(t, "boom", rep.Message)
+	assert.Equal(t, "v1.2.3", rep.Version)
+	assert.Equal(t, "web-01", rep.Hostname)
+	assert.Equal(t, "/api/metrics", rep.Request.Path)
+	assert.NotEmpty(t, rep.Frames)
+	assert.Len(t, rep.ID, 32)
+}
+
+func TestFatalReportsToSentry(t *testing.T) {
+	c, srv := newCollector(t)
+	dsn := strings.Replace(srv.URL, "http://", "http://pubkey@", 1) + "/42"
+	r, err := NewReporter(Options{SentryDSN: dsn, Version: "v1.2.3"})
+	require.NoError(t, err)
+
+	r.Fatal(errors.New("failed to start server: address in use"), nil)
+
+	require.Len(t, c.bodies, 1)
+	assert.Contains(t, c.headers[0].Get("X-Sentry-Auth"), "sentry_key=pubkey")
+
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(c.bodies[0], &event))
+	assert.Equal(t, "fatal", event["level"])
+	assert.Equal(t, "v1.2.3", event["release"])
+	values := event["exception"].(map[string]interface{})["values"].([]interface{})
+	assert.Equal(t, "failed to start server: address in use", values[0].(map[string]interface{})["value"])
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	c, srv := newCollector(t)
+	t.Setenv("SENTRY_DSN", "enc:v1:c2VjcmV0")
+	t.Setenv("CRASH_WEBHOOK_URL", srv.URL)
+
+	opts := OptionsFromEnv("v1.2.3")
+	assert.Empty(t, opts.SentryDSN, "encrypted values cannot be used before the config loads")
+	assert.Equal(t, srv.URL, opts.WebhookURL)
+
+	// A config that fails to load is still reported
+	r, err := NewReporter(opts)
+	require.NoError(t, err)
+	r.Fatal(errors.New("failed to load config: SENTRY_DSN is encrypted"), nil)
+
+	require.Len(t, c.bodies, 1)
+	var rep Report
+	require.NoError(t, json.Unmarshal(c.bodies[0], &rep))
+	assert.Equal(t, KindFatal, rep.Kind)
+	assert.Equal(t, "v1.2.3", rep.Version)
+}
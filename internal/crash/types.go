@@ -0,0 +1,43 @@
+package crash
+
+import "time"
+
+// Kinds of crash
+const (
+	KindPanic = "panic" // Recovered panic in a request handler
+	KindFatal = "fatal" // Error that stops the agent
+)
+
+// Options configures a Reporter. Either destination may be empty.
+type Options struct {
+	SentryDSN  string
+	WebhookURL string
+	Version    string
+	Hostname   string
+}
+
+// Frame is a single stack frame, innermost first
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Request describes the HTTP request that panicked
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// Report is a crash sent to the webhook
+type Report struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"`
+	Message   string    `json:"message"`
+	Stack     string    `json:"stack,omitempty"`
+	Frames    []Frame   `json:"frames,omitempty"`
+	Version   string    `json:"version"`
+	Hostname  string    `json:"hostname"`
+	Request   *Request  `json:"request,omitempty"`
+}
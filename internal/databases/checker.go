@@ -0,0 +1,190 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// checkTimeout bounds the checks of one database
+const checkTimeout = 5 * time.Second
+
+// Target is a database to check
+type Target struct {
+	Name    string
+	Type    string
+	Address string // host:port, shown in status
+
+	driver   string // database/sql driver and DSN for Postgres and MySQL
+	dsn      string
+	user     string // Redis credentials
+	password string
+	tls      bool
+}
+
+// ParseTarget reads a postgres://, mysql://, redis:// or rediss:// URL
+func ParseTarget(name, rawURL string) (Target, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return Target{}, fmt.Errorf("database %s: invalid URL", name)
+	}
+
+	t := Target{Name: name}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		t.Type, t.driver, t.dsn = TypePostgres, "postgres", rawURL
+		t.Address = withPort(u.Host, "5432")
+	case "mysql":
+		t.Type, t.driver = TypeMySQL, "mysql"
+		t.Address = withPort(u.Host, "3306")
+		cfg := mysql.NewConfig()
+		cfg.User = u.User.Username()
+		cfg.Passwd, _ = u.User.Password()
+		cfg.Net, cfg.Addr = "tcp", t.Address
+		if socket := u.Query().Get("socket"); socket != "" {
+			cfg.Net, cfg.Addr = "unix", socket
+		}
+		cfg.DBName = strings.TrimPrefix(u.Path, "/")
+		t.dsn = cfg.FormatDSN()
+	case "redis", "rediss":
+		t.Type, t.tls = TypeRedis, u.Scheme == "rediss"
+		t.Address = withPort(u.Host, "6379")
+		t.user = u.User.Username()
+		t.password, _ = u.User.Password()
+		// redis://:password@host has no user
+		if _, ok := u.User.Password(); !ok {
+			t.password, t.user = t.user, ""
+		}
+	default:
+		return Target{}, fmt.Errorf("database %s: unsupported scheme %q (use postgres, mysql or redis)", name, u.Scheme)
+	}
+	return t, nil
+}
+
+func withPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Checker reports the health of configured databases. Postgres and MySQL
+// connections are kept open between checks.
+type Checker struct {
+	targets []Target
+
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+// NewChecker creates a checker from name=URL pairs
+func NewChecker(urls map[string]string) (*Checker, error) {
+	c := &Checker{dbs: make(map[string]*sql.DB)}
+	for name, rawURL := range urls {
+		t, err := ParseTarget(name, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		c.targets = append(c.targets, t)
+	}
+	sort.Slice(c.targets, func(i, j int) bool { return c.targets[i].Name < c.targets[j].Name })
+	return c, nil
+}
+
+// Names returns the configured database names
+func (c *Checker) Names() []string {
+	names := make([]string, len(c.targets))
+	for i, t := range c.targets {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Check checks every database concurrently
+func (c *Checker) Check(ctx context.Context) *StatusList {
+	list := &StatusList{Databases: make([]Status, len(c.targets))}
+
+	var wg sync.WaitGroup
+	for i, t := range c.targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			list.Databases[i] = c.check(ctx, t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	list.Total = len(list.Databases)
+	for _, s := range list.Databases {
+		if !s.Up {
+			list.Down++
+		}
+	}
+	return list
+}
+
+func (c *Checker) check(ctx context.Context, t Target) Status {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	s := Status{Name: t.Name, Type: t.Type, Address: t.Address}
+	start := time.Now()
+
+	var err error
+	switch t.Type {
+	case TypeRedis:
+		err = checkRedis(ctx, t, &s)
+	case TypePostgres, TypeMySQL:
+		var db *sql.DB
+		if db, err = c.db(t); err == nil {
+			if t.Type == TypePostgres {
+				err = checkPostgres(ctx, db, &s)
+			} else {
+				err = checkMySQL(ctx, db, &s)
+			}
+		}
+	}
+
+	s.Latency = float64(time.Since(start).Microseconds()) / 1000
+	s.CheckedAt = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+func (c *Checker) db(t Target) (*sql.DB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if db, ok := c.dbs[t.Name]; ok {
+		return db, nil
+	}
+	db, err := sql.Open(t.driver, t.dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	c.dbs[t.Name] = db
+	return db, nil
+}
+
+// Close closes open database connections
+func (c *Checker) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, db := range c.dbs {
+		db.Close()
+		delete(c.dbs, name)
+	}
+}
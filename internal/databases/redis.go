@@ -0,0 +1,146 @@
+package databases
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// redisConn is a minimal RESP client, enough for AUTH and INFO
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialRedis(ctx context.Context, t Target) (*redisConn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", t.Address)
+	if err != nil {
+		return nil, err
+	}
+	if t.tls {
+		host, _, _ := net.SplitHostPort(t.Address)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if t.password != "" {
+		args := []string{"AUTH", t.password}
+		if t.user != "" {
+			args = []string{"AUTH", t.user, t.password}
+		}
+		if _, err := c.do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("auth failed: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *redisConn) do(args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return "", err
+	}
+	return c.reply()
+}
+
+func (c *redisConn) reply() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid bulk length %q", line[1:])
+		}
+		if n < 0 {
+			return "", nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	}
+	return "", fmt.Errorf("unexpected reply %q", line)
+}
+
+func checkRedis(ctx context.Context, t Target, s *Status) error {
+	c, err := dialRedis(ctx, t)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	info, err := c.do("INFO")
+	if err != nil {
+		return err
+	}
+	s.Up = true
+	return parseRedisInfo(info, s)
+}
+
+// parseRedisInfo fills s from the output of INFO
+func parseRedisInfo(info string, s *Status) error {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if k, v, ok := strings.Cut(line, ":"); ok && !strings.HasPrefix(line, "#") {
+			fields[k] = v
+		}
+	}
+
+	s.Version = fields["redis_version"]
+	s.Connections, _ = strconv.Atoi(fields["connected_clients"])
+	s.MaxConnections, _ = strconv.Atoi(fields["maxclients"])
+	s.MemoryBytes, _ = strconv.ParseInt(fields["used_memory"], 10, 64)
+
+	switch fields["role"] {
+	case "master":
+		s.Role = RolePrimary
+		s.Replicas, _ = strconv.Atoi(fields["connected_slaves"])
+	case "slave":
+		s.Role = RoleReplica
+		if fields["master_link_status"] != "up" {
+			return fmt.Errorf("replication link is %s", fields["master_link_status"])
+		}
+		if lag, err := strconv.ParseFloat(fields["master_last_io_seconds_ago"], 64); err == nil && lag >= 0 {
+			s.ReplicationLag = &lag
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+func checkPostgres(ctx context.Context, db *sql.DB, s *Status) error {
+	var recovery bool
+	err := db.QueryRowContext(ctx, `SELECT
+		current_setting('server_version'),
+		(SELECT count(*) FROM pg_stat_activity),
+		current_setting('max_connections')::int,
+		pg_is_in_recovery(),
+		pg_size_bytes(current_setting('shared_buffers'))`,
+	).Scan(&s.Version, &s.Connections, &s.MaxConnections, &recovery, &s.MemoryBytes)
+	if err != nil {
+		return err
+	}
+	s.Up = true
+
+	if !recovery {
+		s.Role = RolePrimary
+		return db.QueryRowContext(ctx, `SELECT count(*) FROM pg_stat_replication`).Scan(&s.Replicas)
+	}
+
+	// The last replayed transaction's age overstates the lag on an idle
+	// primary, so a replica that has replayed everything it received is
+	// reported as caught up
+	s.Role = RoleReplica
+	var lag sql.NullFloat64
+	err = db.QueryRowContext(ctx, `SELECT CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+		END`).Scan(&lag)
+	if err != nil {
+		return err
+	}
+	if lag.Valid {
+		s.ReplicationLag = &lag.Float64
+	}
+	return nil
+}
+
+func checkMySQL(ctx context.Context, db *sql.DB, s *Status) error {
+	err := db.QueryRowContext(ctx, `SELECT VERSION(), @@max_connections, @@innodb_buffer_pool_size`).
+		Scan(&s.Version, &s.MaxConnections, &s.MemoryBytes)
+	if err != nil {
+		return err
+	}
+	s.Up = true
+
+	var name string
+	if err := db.QueryRowContext(ctx, `SHOW GLOBAL STATUS LIKE 'Threads_connected'`).Scan(&name, &s.Connections); err != nil {
+		return err
+	}
+
+	// SHOW SLAVE STATUS was renamed in MySQL 8.0.22; MariaDB has both
+	replica, err := queryRow(ctx, db, `SHOW REPLICA STATUS`)
+	if err != nil {
+		if replica, err = queryRow(ctx, db, `SHOW SLAVE STATUS`); err != nil {
+			return err
+		}
+	}
+	if replica == nil {
+		s.Role = RolePrimary
+		// Needs the PROCESS privilege; without it replicas are not counted
+		_ = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.PROCESSLIST
+			WHERE COMMAND IN ('Binlog Dump', 'Binlog Dump GTID')`).Scan(&s.Replicas)
+		return nil
+	}
+
+	s.Role = RoleReplica
+	lag, ok := replica["Seconds_Behind_Source"]
+	if !ok {
+		lag = replica["Seconds_Behind_Master"]
+	}
+	if lag == nil {
+		return fmt.Errorf("replication is not running")
+	}
+	seconds, err := strconv.ParseFloat(*lag, 64)
+	if err != nil {
+		return fmt.Errorf("invalid replication lag %q", *lag)
+	}
+	s.ReplicationLag = &seconds
+	return nil
+}
+
+// queryRow returns the first row of a query by column name, or nil when
+// there are no rows. NULL columns are nil.
+func queryRow(ctx context.Context, db *sql.DB, query string) (map[string]*string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]*string, len(columns))
+	for i, column := range columns {
+		if values[i].Valid {
+			v := values[i].String
+			row[column] = &v
+		} else {
+			row[column] = nil
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,41 @@
+package databases
+
+import "time"
+
+// Database types
+const (
+	TypePostgres = "postgres"
+	TypeMySQL    = "mysql"
+	TypeRedis    = "redis"
+)
+
+// Roles reported for replication
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// Status is the health of one database server
+type Status struct {
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	Address        string    `json:"address"` // Host and port, without credentials
+	Up             bool      `json:"up"`
+	Version        string    `json:"version,omitempty"`
+	Connections    int       `json:"connections"`
+	MaxConnections int       `json:"max_connections,omitempty"`
+	Role           string    `json:"role,omitempty"`
+	Replicas       int       `json:"replicas,omitempty"`                // Connected replicas of a primary
+	ReplicationLag *float64  `json:"replication_lag_seconds,omitempty"` // Replicas only
+	MemoryBytes    int64     `json:"memory_bytes"`
+	Latency        float64   `json:"latency_ms"` // Time to run the checks
+	Error          string    `json:"error,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// StatusList is the health of every configured database
+type StatusList struct {
+	Databases []Status `json:"databases"`
+	Total     int      `json:"total"`
+	Down      int      `json:"down"`
+}
@@ -0,0 +1,260 @@
+package datadir
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+// Pruning triggers
+const (
+	TriggerManual    = "manual"
+	TriggerAutomatic = "automatic"
+)
+
+// other holds files no category claims
+const other = "other"
+
+// Manager accounts for the data directory by category and prunes it
+type Manager struct {
+	dir        string
+	categories []Category
+	opts       Options
+	bus        *events.Bus
+
+	mu        sync.Mutex
+	last      *PruneResult
+	overLimit bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewManager creates a manager for dir
+func NewManager(dir string, categories []Category, opts Options, bus *events.Bus) *Manager {
+	return &Manager{
+		dir:        dir,
+		categories: categories,
+		opts:       opts,
+		bus:        bus,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start prunes every Interval until Stop
+func (m *Manager) Start() {
+	if m.opts.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(m.opts.Interval)
+		defer ticker.Stop()
+
+		m.autoPrune()
+		for {
+			select {
+			case <-ticker.C:
+				m.autoPrune()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends automatic pruning
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+func (m *Manager) autoPrune() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	m.Prune(ctx, nil, TriggerAutomatic)
+	if report, err := m.Usage(); err == nil {
+		m.checkLimit(report)
+	}
+}
+
+// checkLimit raises an event when the data directory grows past the limit,
+// once until it drops back below
+func (m *Manager) checkLimit(report *Report) {
+	m.mu.Lock()
+	crossed := report.OverLimit && !m.overLimit
+	m.overLimit = report.OverLimit
+	m.mu.Unlock()
+
+	if crossed {
+		m.bus.Publish(events.Event{
+			Type:     "agent.storage_limit",
+			Severity: events.SeverityWarning,
+			Source:   "agent",
+			Message:  fmt.Sprintf("Agent data in %s uses %d MB, over the %d MB limit", m.dir, report.TotalBytes>>20, report.LimitBytes>>20),
+			Data:     report,
+		})
+	}
+}
+
+// Usage returns the size of each category, plus the files none claims
+func (m *Manager) Usage() (*Report, error) {
+	usage, err := m.usage()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Dir: m.dir, LimitBytes: m.opts.Limit, Categories: make([]Usage, 0, len(m.categories)+1)}
+	for _, c := range m.categories {
+		u := usage[c.Name]
+		u.Name, u.Description, u.Prunable = c.Name, c.Description, c.Prune != nil
+		report.Categories = append(report.Categories, u)
+		report.TotalBytes += u.Bytes
+	}
+	u := usage[other]
+	u.Name, u.Description = other, "Files the agent does not account for"
+	report.Categories = append(report.Categories, u)
+	report.TotalBytes += u.Bytes
+
+	report.OverLimit = m.opts.Limit > 0 && report.TotalBytes > m.opts.Limit
+	if fs, err := disk.Usage(m.dir); err == nil {
+		report.FreeBytes = fs.Free
+	}
+	if m.opts.Interval > 0 {
+		report.PruneInterval = m.opts.Interval.String()
+	}
+	m.mu.Lock()
+	report.LastPrune = m.last
+	m.mu.Unlock()
+	return report, nil
+}
+
+// usage walks the data directory, adding each file to the category that
+// claims its top-level entry
+func (m *Manager) usage() (map[string]Usage, error) {
+	owner := map[string]string{}
+	for _, c := range m.categories {
+		for _, p := range c.Paths {
+			owner[p] = c.Name
+		}
+	}
+
+	usage := map[string]Usage{}
+	err := filepath.WalkDir(m.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == m.dir {
+				if os.IsNotExist(err) {
+					return filepath.SkipAll
+				}
+				return err
+			}
+			return nil // Unreadable entries are skipped rather than failing the report
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(m.dir, path)
+		top, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		name, ok := owner[top]
+		if !ok {
+			name = other
+		}
+		u := usage[name]
+		u.Bytes += info.Size()
+		u.Files++
+		usage[name] = u
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	return usage, nil
+}
+
+// Prune prunes the named categories, or every prunable one when names is
+// empty. A category that fails does not stop the others.
+func (m *Manager) Prune(ctx context.Context, names []string, trigger string) (*PruneResult, error) {
+	selected, err := m.selectCategories(names)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := m.usage()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{Trigger: trigger, Freed: map[string]int64{}, Errors: map[string]string{}}
+	for _, c := range selected {
+		if err := c.Prune(ctx); err != nil {
+			result.Errors[c.Name] = err.Error()
+		}
+	}
+
+	after, err := m.usage()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range selected {
+		freed := before[c.Name].Bytes - after[c.Name].Bytes
+		if freed < 0 {
+			freed = 0 // Grew meanwhile
+		}
+		result.Freed[c.Name] = freed
+		result.FreedBytes += freed
+	}
+	result.Time = time.Now()
+
+	m.mu.Lock()
+	m.last = result
+	m.mu.Unlock()
+	return result, nil
+}
+
+func (m *Manager) selectCategories(names []string) ([]Category, error) {
+	if len(names) == 0 {
+		var selected []Category
+		for _, c := range m.categories {
+			if c.Prune != nil {
+				selected = append(selected, c)
+			}
+		}
+		return selected, nil
+	}
+
+	selected := make([]Category, 0, len(names))
+	for _, name := range names {
+		c, ok := m.category(name)
+		if !ok {
+			return nil, apierror.Invalid("unknown storage category %q", name)
+		}
+		if c.Prune == nil {
+			return nil, apierror.Invalid("storage category %q cannot be pruned", name)
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+func (m *Manager) category(name string) (Category, bool) {
+	for _, c := range m.categories {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Category{}, false
+}
@@ -0,0 +1,78 @@
+package datadir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+func write(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	history := filepath.Join(dir, "history.jsonl")
+	write(t, history, "1\n2\n3\n4\n")
+	write(t, filepath.Join(dir, "trash", "a"), "12345")
+	write(t, filepath.Join(dir, "trash", "sub", "b"), "123")
+	write(t, filepath.Join(dir, "stray"), "xy")
+
+	bus := events.NewBus(10)
+	m := NewManager(dir, []Category{
+		{Name: "history", Paths: []string{"history.jsonl"}, Prune: func(context.Context) error { return TrimLines(history, 2) }},
+		{Name: "trash", Paths: []string{"trash"}},
+	}, Options{Limit: 10}, bus)
+
+	report, err := m.Usage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(18), report.TotalBytes)
+	assert.True(t, report.OverLimit)
+	assert.Equal(t, Usage{Name: "history", Bytes: 8, Files: 1, Prunable: true}, report.Categories[0])
+	assert.Equal(t, Usage{Name: "trash", Bytes: 8, Files: 2}, report.Categories[1])
+	assert.Equal(t, "other", report.Categories[2].Name)
+	assert.Equal(t, int64(2), report.Categories[2].Bytes)
+
+	_, err = m.Prune(context.Background(), []string{"trash"}, TriggerManual)
+	assert.ErrorIs(t, err, apierror.ErrInvalid)
+	_, err = m.Prune(context.Background(), []string{"nope"}, TriggerManual)
+	assert.ErrorIs(t, err, apierror.ErrInvalid)
+
+	result, err := m.Prune(context.Background(), nil, TriggerManual)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.FreedBytes)
+	assert.Equal(t, map[string]int64{"history": 4}, result.Freed)
+	data, _ := os.ReadFile(history)
+	assert.Equal(t, "3\n4\n", string(data))
+
+	// The limit warning is raised once per crossing
+	m.autoPrune()
+	m.autoPrune()
+	raised := bus.Recent(10, "agent.storage_limit").Events
+	require.Len(t, raised, 1)
+	assert.Equal(t, events.SeverityWarning, raised[0].Severity)
+
+	report, _ = m.Usage()
+	assert.Equal(t, TriggerAutomatic, report.LastPrune.Trigger)
+}
+
+func TestTrimLines(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, TrimLines(filepath.Join(dir, "missing.jsonl"), 5))
+
+	path := filepath.Join(dir, "h.jsonl")
+	write(t, path, strings.Repeat("line\n", 3))
+	require.NoError(t, TrimLines(path, 5))
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, strings.Repeat("line\n", 3), string(data), "short files are left alone")
+}
@@ -0,0 +1,45 @@
+package datadir
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// TrimLines keeps the last keep lines of a JSON lines history file. The
+// agent only loads that many, so nothing it shows is lost. A missing file
+// is not an error.
+func TrimLines(path string, keep int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(lines) <= keep {
+		return nil
+	}
+
+	kept := append(bytes.Join(lines[len(lines)-keep:], []byte("\n")), '\n')
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
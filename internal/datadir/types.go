@@ -0,0 +1,50 @@
+package datadir
+
+import (
+	"context"
+	"time"
+)
+
+// Category is a kind of data the agent keeps in the data directory
+type Category struct {
+	Name        string
+	Description string
+	Paths       []string                        // Entries directly in the data directory; directories count in full
+	Prune       func(ctx context.Context) error // nil when the category is only reported
+}
+
+// Usage is one category's size on disk
+type Usage struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Bytes       int64  `json:"bytes"`
+	Files       int    `json:"files"`
+	Prunable    bool   `json:"prunable"`
+}
+
+// Report is what the agent stores on disk
+type Report struct {
+	Dir           string       `json:"dir"`
+	TotalBytes    int64        `json:"total_bytes"`
+	LimitBytes    int64        `json:"limit_bytes,omitempty"`
+	OverLimit     bool         `json:"over_limit"`
+	FreeBytes     uint64       `json:"filesystem_free_bytes"` // Left on the data directory's filesystem
+	Categories    []Usage      `json:"categories"`
+	PruneInterval string       `json:"prune_interval"` // Empty when pruning only runs on request
+	LastPrune     *PruneResult `json:"last_prune,omitempty"`
+}
+
+// PruneResult is the outcome of one pruning run
+type PruneResult struct {
+	Trigger    string            `json:"trigger"` // manual or automatic
+	FreedBytes int64             `json:"freed_bytes"`
+	Freed      map[string]int64  `json:"freed"` // Bytes by category
+	Errors     map[string]string `json:"errors,omitempty"`
+	Time       time.Time         `json:"time"`
+}
+
+// Options configures a Manager
+type Options struct {
+	Interval time.Duration // How often to prune; 0 on request only
+	Limit    int64         // Bytes the data directory may use before a warning; 0 for no limit
+}
@@ -0,0 +1,221 @@
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// MaxOutputs is the number of deployment outputs kept for streaming
+const MaxOutputs = 20
+
+// ErrDisabled is returned when DEPLOY_ENABLED is off
+var ErrDisabled = apierror.NotAllowed("deployments are disabled")
+
+// projectName is what docker compose accepts as a project name
+var projectName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// lookPath and newCommand are replaced in tests
+var (
+	lookPath   = exec.LookPath
+	newCommand = exec.CommandContext
+)
+
+// Deployer applies compose files with docker compose
+type Deployer struct {
+	dir     string // Inline compose files are kept in dir/<project>
+	allowed func(path string) bool
+
+	mu      sync.Mutex
+	compose []string // The compose command, found on first use
+	outputs map[string]*Output
+	order   []string
+}
+
+// NewDeployer creates a deployer that keeps inline compose files under
+// dataDir and accepts compose files on disk where allowed says so. Without
+// a dataDir only files on disk can be deployed.
+func NewDeployer(dataDir string, allowed func(path string) bool) *Deployer {
+	d := &Deployer{allowed: allowed, outputs: map[string]*Output{}}
+	if dataDir != "" {
+		d.dir = filepath.Join(dataDir, "deploy")
+	}
+	return d
+}
+
+// command returns "docker compose", or "docker-compose" when the plugin is
+// missing
+func (d *Deployer) command(ctx context.Context) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.compose != nil {
+		return d.compose, nil
+	}
+	if docker, err := lookPath("docker"); err == nil {
+		if newCommand(ctx, docker, "compose", "version").Run() == nil {
+			d.compose = []string{docker, "compose"}
+			return d.compose, nil
+		}
+	}
+	if legacy, err := lookPath("docker-compose"); err == nil {
+		d.compose = []string{legacy}
+		return d.compose, nil
+	}
+	return nil, apierror.Unavailable("docker compose is not installed")
+}
+
+// Prepare validates a request and the compose file it names. Inline files
+// are written to the data directory, replacing the project's previous file
+// only once the new one is valid.
+func (d *Deployer) Prepare(ctx context.Context, req Request) (*Plan, error) {
+	if (req.Compose == "") == (req.Path == "") {
+		return nil, apierror.Invalid("set exactly one of compose and path")
+	}
+
+	plan := &Plan{Project: req.Project}
+	if req.Path != "" {
+		path, err := filepath.Abs(req.Path)
+		if err != nil || !d.allowed(path) {
+			return nil, apierror.NotAllowed("path %s is outside the allowed paths", req.Path)
+		}
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			return nil, apierror.Invalid("compose file %s not found", path)
+		}
+		plan.File = path
+		if plan.Project == "" {
+			plan.Project = strings.ToLower(filepath.Base(filepath.Dir(path)))
+		}
+	}
+	if !projectName.MatchString(plan.Project) {
+		return nil, apierror.Invalid("project must be lowercase letters, digits, '-' and '_', starting with a letter or digit")
+	}
+
+	compose, err := d.command(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Compose == "" {
+		if err := validate(ctx, compose, plan.Project, plan.File); err != nil {
+			return nil, err
+		}
+		return plan, nil
+	}
+
+	if d.dir == "" {
+		return nil, apierror.Unavailable("inline compose files need DATA_DIR")
+	}
+	dir := filepath.Join(d.dir, plan.Project)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	plan.File = filepath.Join(dir, "compose.yaml")
+	pending := plan.File + ".new"
+	if err := os.WriteFile(pending, []byte(req.Compose), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write compose file: %w", err)
+	}
+	if err := validate(ctx, compose, plan.Project, pending); err != nil {
+		os.Remove(pending)
+		return nil, err
+	}
+	if err := os.Rename(pending, plan.File); err != nil {
+		return nil, fmt.Errorf("failed to save compose file: %w", err)
+	}
+	return plan, nil
+}
+
+// validate checks a compose file with "docker compose config"
+func validate(ctx context.Context, compose []string, project, file string) error {
+	args := append(compose[1:len(compose):len(compose)], "-f", file, "-p", project, "config", "--quiet")
+	cmd := newCommand(ctx, compose[0], args...)
+	cmd.Dir = filepath.Dir(file)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return apierror.Invalid("invalid compose file: %v", err).WithDetail("output", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Run pulls the plan's images and brings its services up, appending what
+// docker compose prints to out and finishing it at the end. Services no
+// longer in the file are removed.
+func (d *Deployer) Run(ctx context.Context, plan *Plan, out *Output) (_ *Result, err error) {
+	defer func() { out.Finish(err) }()
+
+	result := &Result{Project: plan.Project, File: plan.File}
+	compose, err := d.command(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range [][]string{{"pull"}, {"up", "-d", "--remove-orphans"}} {
+		args := append(compose[1:len(compose):len(compose)], "-f", plan.File, "-p", plan.Project)
+		args = append(args, step...)
+		out.Append("$ " + strings.Join(append([]string{filepath.Base(compose[0])}, args...), " "))
+
+		if err := stream(ctx, filepath.Dir(plan.File), out, compose[0], args...); err != nil {
+			result.Output = out.Lines()
+			return result, fmt.Errorf("%s failed: %w", step[0], err)
+		}
+	}
+
+	result.Output = out.Lines()
+	return result, nil
+}
+
+// stream runs a command, appending its stdout and stderr to out line by line
+func stream(ctx context.Context, dir string, out *Output, name string, args ...string) error {
+	pr, pw := io.Pipe()
+	cmd := newCommand(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			out.Append(scanner.Text())
+		}
+		// Keep draining so the command never blocks on a full pipe
+		io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Run()
+	pw.Close()
+	<-done
+	return err
+}
+
+// Track keeps out for streaming under a job ID, forgetting the oldest
+// beyond MaxOutputs
+func (d *Deployer) Track(id string, out *Output) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.outputs[id] = out
+	d.order = append(d.order, id)
+	for len(d.order) > MaxOutputs {
+		delete(d.outputs, d.order[0])
+		d.order = d.order[1:]
+	}
+}
+
+// Output returns the output of a deployment job
+func (d *Deployer) Output(id string) (*Output, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out, ok := d.outputs[id]
+	return out, ok
+}
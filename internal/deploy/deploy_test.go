@@ -0,0 +1,142 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// fakeCompose runs script with the command's arguments in place of docker
+func fakeCompose(t *testing.T, script string) {
+	origLook, origCommand := lookPath, newCommand
+	t.Cleanup(func() { lookPath, newCommand = origLook, origCommand })
+
+	lookPath = func(file string) (string, error) {
+		if file == "docker" {
+			return "/usr/bin/docker", nil
+		}
+		return "", errors.New("not found")
+	}
+	newCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", append([]string{"-c", script, name}, args...)...)
+	}
+}
+
+func TestPrepareInline(t *testing.T) {
+	fakeCompose(t, `case "$*" in *config*) grep -q services "$3" || { echo "services missing" >&2; exit 1; };; esac`)
+	d := NewDeployer(t.TempDir(), func(string) bool { return false })
+
+	plan, err := d.Prepare(context.Background(), Request{Compose: "services:\n  web:\n    image: nginx\n", Project: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "web", plan.Project)
+	content, err := os.ReadFile(plan.File)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "image: nginx")
+
+	// An invalid file leaves the previous one in place
+	_, err = d.Prepare(context.Background(), Request{Compose: "version: '3'\n", Project: "web"})
+	require.Error(t, err)
+	var apiErr *apierror.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "services missing", apiErr.Details["output"])
+	content, _ = os.ReadFile(plan.File)
+	assert.Contains(t, string(content), "image: nginx")
+	_, err = os.Stat(plan.File + ".new")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPrepareRejects(t *testing.T) {
+	fakeCompose(t, "true")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "App", "compose.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	require.NoError(t, os.WriteFile(file, []byte("services: {}\n"), 0644))
+	d := NewDeployer(t.TempDir(), func(path string) bool { return strings.HasPrefix(path, dir) })
+
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"neither", Request{}, "set exactly one"},
+		{"both", Request{Compose: "services: {}", Path: file}, "set exactly one"},
+		{"inline without project", Request{Compose: "services: {}"}, "project must be"},
+		{"bad project", Request{Compose: "services: {}", Project: "../etc"}, "project must be"},
+		{"outside allowed paths", Request{Path: "/etc/compose.yaml"}, "outside the allowed paths"},
+		{"missing file", Request{Path: filepath.Join(dir, "missing.yaml")}, "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := d.Prepare(context.Background(), tt.req)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	plan, err := d.Prepare(context.Background(), Request{Path: file})
+	require.NoError(t, err)
+	assert.Equal(t, "app", plan.Project, "defaults to the directory name")
+	assert.Equal(t, file, plan.File)
+}
+
+func TestRun(t *testing.T) {
+	fakeCompose(t, `echo "running $*"; case "$*" in *up*) echo "boom" >&2; exit 3;; esac`)
+	d := NewDeployer(t.TempDir(), nil)
+	file := filepath.Join(t.TempDir(), "compose.yaml")
+	plan := &Plan{Project: "web", File: file}
+	out := NewOutput()
+
+	result, err := d.Run(context.Background(), plan, out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "up failed")
+	assert.Equal(t, []string{
+		"$ docker compose -f " + file + " -p web pull",
+		"running compose -f " + file + " -p web pull",
+		"$ docker compose -f " + file + " -p web up -d --remove-orphans",
+		"running compose -f " + file + " -p web up -d --remove-orphans",
+		"boom",
+	}, result.Output)
+
+	_, done, _ := out.Since(5)
+	assert.True(t, done)
+	assert.Equal(t, err, out.Err())
+}
+
+func TestOutputSince(t *testing.T) {
+	out := NewOutput()
+	out.Append("one")
+	lines, done, wait := out.Since(0)
+	assert.Equal(t, []string{"one"}, lines)
+	assert.False(t, done)
+
+	out.Append("two")
+	<-wait
+	lines, _, wait = out.Since(1)
+	assert.Equal(t, []string{"two"}, lines)
+
+	out.Finish(nil)
+	<-wait
+	lines, done, _ = out.Since(2)
+	assert.Empty(t, lines)
+	assert.True(t, done)
+}
+
+func TestTrackForgetsOldest(t *testing.T) {
+	d := NewDeployer(t.TempDir(), nil)
+	for i := 0; i <= MaxOutputs; i++ {
+		d.Track(string(rune('a'+i)), NewOutput())
+	}
+	_, ok := d.Output("a")
+	assert.False(t, ok)
+	_, ok = d.Output(string(rune('a' + MaxOutputs)))
+	assert.True(t, ok)
+}
@@ -0,0 +1,67 @@
+package deploy
+
+import "sync"
+
+// Output collects the lines a deployment prints so they can be followed
+// while it runs
+type Output struct {
+	mu     sync.Mutex
+	lines  []string
+	done   bool
+	err    error
+	notify chan struct{} // Closed when a line is added or the output completes
+}
+
+// NewOutput creates an empty output
+func NewOutput() *Output {
+	return &Output{notify: make(chan struct{})}
+}
+
+// Append adds a line
+func (o *Output) Append(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.lines = append(o.lines, line)
+	close(o.notify)
+	o.notify = make(chan struct{})
+}
+
+// Finish marks the output complete, with the error the deployment failed
+// with if any
+func (o *Output) Finish(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.done {
+		o.done, o.err = true, err
+		close(o.notify)
+	}
+}
+
+// Err returns the error the deployment finished with
+func (o *Output) Err() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// Lines returns every line so far
+func (o *Output) Lines() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string{}, o.lines...)
+}
+
+// Since returns the lines after the first n, whether the output is
+// complete, and a channel that is closed when there is more
+func (o *Output) Since(n int) ([]string, bool, <-chan struct{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var lines []string
+	if n < len(o.lines) {
+		lines = append(lines, o.lines[n:]...)
+	}
+	return lines, o.done, o.notify
+}
@@ -0,0 +1,21 @@
+package deploy
+
+// Request is a deployment of an inline compose file or of one on disk
+type Request struct {
+	Compose string `json:"compose,omitempty"` // Compose file content
+	Path    string `json:"path,omitempty"`    // Compose file on the host, within the allowed paths
+	Project string `json:"project,omitempty"` // Required with compose; defaults to the file's directory name
+}
+
+// Plan is a validated deployment
+type Plan struct {
+	Project string `json:"project"`
+	File    string `json:"file"`
+}
+
+// Result is the outcome of a deployment
+type Result struct {
+	Project string   `json:"project"`
+	File    string   `json:"file"`
+	Output  []string `json:"output"`
+}
@@ -0,0 +1,175 @@
+package diagnostics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// DefaultTimeout is the default connect timeout for probes
+	DefaultTimeout = 5 * time.Second
+	// MaxTimeout is the upper bound for a single probe
+	MaxTimeout = 30 * time.Second
+)
+
+// tlsPorts are ports assumed to speak TLS when not specified explicitly
+var tlsPorts = map[int]bool{
+	443:  true,
+	465:  true,
+	636:  true,
+	853:  true,
+	993:  true,
+	995:  true,
+	6443: true,
+	8443: true,
+}
+
+// IsTLSPort reports whether a port conventionally serves TLS
+func IsTLSPort(port int) bool {
+	return tlsPorts[port]
+}
+
+// Prober performs network reachability checks
+type Prober struct{}
+
+// NewProber creates a new prober
+func NewProber() *Prober {
+	return &Prober{}
+}
+
+// Probe connects to host:port and reports reachability, latency and, when
+// requested, the TLS certificate chain served by the endpoint
+func (p *Prober) Probe(ctx context.Context, req ProbeRequest) (*ProbeResult, error) {
+	if req.Host == "" {
+		return nil, apierror.Invalid("host is required")
+	}
+	if req.Port <= 0 || req.Port > 65535 {
+		return nil, apierror.Invalid("invalid port: %d", req.Port)
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if timeout > MaxTimeout {
+		timeout = MaxTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	address := net.JoinHostPort(req.Host, strconv.Itoa(req.Port))
+	result := &ProbeResult{
+		Host:      req.Host,
+		Port:      req.Port,
+		Address:   address,
+		CheckedAt: time.Now(),
+	}
+
+	if net.ParseIP(req.Host) == nil {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, req.Host)
+		if err != nil {
+			result.Error = fmt.Sprintf("dns lookup failed: %v", err)
+			return result, nil
+		}
+		result.ResolvedAddrs = addrs
+	}
+
+	dialer := &net.Dialer{}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer conn.Close()
+
+	result.Reachable = true
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000.0
+
+	if !req.TLS {
+		return result, nil
+	}
+
+	serverName := req.ServerName
+	if serverName == "" {
+		serverName = req.Host
+	}
+
+	// Verification is done manually below so the chain is returned even
+	// when it would not pass validation (expired, self-signed, wrong name)
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2", "http/1.1"},
+	})
+
+	handshakeStart := time.Now()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		result.Error = fmt.Sprintf("tls handshake failed: %v", err)
+		return result, nil
+	}
+
+	state := tlsConn.ConnectionState()
+	result.TLS = &TLSInfo{
+		Version:        tls.VersionName(state.Version),
+		CipherSuite:    tls.CipherSuiteName(state.CipherSuite),
+		ServerName:     serverName,
+		HandshakeMs:    float64(time.Since(handshakeStart).Microseconds()) / 1000.0,
+		NegotiatedALPN: state.NegotiatedProtocol,
+		Certificates:   describeChain(state.PeerCertificates),
+	}
+
+	if err := verifyChain(state.PeerCertificates, serverName); err != nil {
+		result.TLS.VerifyError = err.Error()
+	} else {
+		result.TLS.Verified = true
+	}
+
+	return result, nil
+}
+
+func verifyChain(certs []*x509.Certificate, serverName string) error {
+	if len(certs) == 0 {
+		return fmt.Errorf("no certificates presented")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func describeChain(certs []*x509.Certificate) []CertificateInfo {
+	now := time.Now()
+	chain := make([]CertificateInfo, 0, len(certs))
+
+	for _, cert := range certs {
+		chain = append(chain, CertificateInfo{
+			Subject:         cert.Subject.String(),
+			Issuer:          cert.Issuer.String(),
+			SerialNumber:    cert.SerialNumber.String(),
+			DNSNames:        cert.DNSNames,
+			NotBefore:       cert.NotBefore,
+			NotAfter:        cert.NotAfter,
+			DaysUntilExpiry: int(cert.NotAfter.Sub(now).Hours() / 24),
+			Expired:         now.After(cert.NotAfter),
+			IsCA:            cert.IsCA,
+		})
+	}
+
+	return chain
+}
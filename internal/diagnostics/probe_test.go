@@ -0,0 +1,117 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// hostPort splits a listener address into the probe's host and port
+func hostPort(t *testing.T, addr string) (string, int) {
+	host, p, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(p)
+	require.NoError(t, err)
+	return host, port
+}
+
+func TestProbe_Reachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	host, port := hostPort(t, ln.Addr().String())
+
+	result, err := NewProber().Probe(context.Background(), ProbeRequest{Host: host, Port: port})
+	require.NoError(t, err)
+	assert.True(t, result.Reachable)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, ln.Addr().String(), result.Address)
+	assert.Nil(t, result.TLS)
+}
+
+func TestProbe_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	host, port := hostPort(t, ln.Addr().String())
+	ln.Close()
+
+	result, err := NewProber().Probe(context.Background(), ProbeRequest{Host: host, Port: port, Timeout: time.Second})
+	require.NoError(t, err)
+	assert.False(t, result.Reachable)
+	assert.NotEmpty(t, result.Error)
+}
+
+func TestProbe_Invalid(t *testing.T) {
+	p := NewProber()
+	for _, req := range []ProbeRequest{
+		{Port: 443},
+		{Host: "example.com"},
+		{Host: "example.com", Port: 70000},
+	} {
+		_, err := p.Probe(context.Background(), req)
+		assert.True(t, errors.Is(err, apierror.ErrInvalid), "%+v", req)
+	}
+}
+
+func TestProbe_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	host, port := hostPort(t, srv.Listener.Addr().String())
+
+	result, err := NewProber().Probe(context.Background(), ProbeRequest{
+		Host: host, Port: port, TLS: true, ServerName: "example.com",
+	})
+	require.NoError(t, err)
+	require.True(t, result.Reachable)
+	require.NotNil(t, result.TLS)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, "example.com", result.TLS.ServerName)
+	assert.NotEmpty(t, result.TLS.Version)
+	require.NotEmpty(t, result.TLS.Certificates)
+	assert.Contains(t, result.TLS.Certificates[0].DNSNames, "example.com")
+	assert.False(t, result.TLS.Certificates[0].Expired)
+
+	// The test certificate is self-signed, so the chain is returned but
+	// not verified
+	assert.False(t, result.TLS.Verified)
+	assert.NotEmpty(t, result.TLS.VerifyError)
+}
+
+func TestProbe_TLSHandshakeFails(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	host, port := hostPort(t, ln.Addr().String())
+
+	result, err := NewProber().Probe(context.Background(), ProbeRequest{Host: host, Port: port, TLS: true})
+	require.NoError(t, err)
+	assert.True(t, result.Reachable)
+	assert.Nil(t, result.TLS)
+	assert.Contains(t, result.Error, "tls handshake failed")
+}
+
+func TestIsTLSPort(t *testing.T) {
+	assert.True(t, IsTLSPort(443))
+	assert.True(t, IsTLSPort(8443))
+	assert.False(t, IsTLSPort(80))
+	assert.False(t, IsTLSPort(22))
+}
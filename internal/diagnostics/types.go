@@ -0,0 +1,50 @@
+package diagnostics
+
+import "time"
+
+// ProbeRequest represents parameters for a port reachability check
+type ProbeRequest struct {
+	Host       string        `json:"host"`
+	Port       int           `json:"port"`
+	TLS        bool          `json:"tls"`
+	ServerName string        `json:"server_name,omitempty"`
+	Timeout    time.Duration `json:"timeout,omitempty"`
+}
+
+// ProbeResult represents the outcome of a port reachability check
+type ProbeResult struct {
+	Host          string    `json:"host"`
+	Port          int       `json:"port"`
+	Address       string    `json:"address"`
+	Reachable     bool      `json:"reachable"`
+	LatencyMs     float64   `json:"latency_ms"`
+	Error         string    `json:"error,omitempty"`
+	TLS           *TLSInfo  `json:"tls,omitempty"`
+	CheckedAt     time.Time `json:"checked_at"`
+	ResolvedAddrs []string  `json:"resolved_addrs,omitempty"`
+}
+
+// TLSInfo describes the TLS session negotiated with the remote endpoint
+type TLSInfo struct {
+	Version        string            `json:"version"`
+	CipherSuite    string            `json:"cipher_suite"`
+	ServerName     string            `json:"server_name"`
+	HandshakeMs    float64           `json:"handshake_ms"`
+	Verified       bool              `json:"verified"`
+	VerifyError    string            `json:"verify_error,omitempty"`
+	NegotiatedALPN string            `json:"negotiated_alpn,omitempty"`
+	Certificates   []CertificateInfo `json:"certificates"`
+}
+
+// CertificateInfo describes a certificate in the served chain
+type CertificateInfo struct {
+	Subject         string    `json:"subject"`
+	Issuer          string    `json:"issuer"`
+	SerialNumber    string    `json:"serial_number"`
+	DNSNames        []string  `json:"dns_names,omitempty"`
+	NotBefore       time.Time `json:"not_before"`
+	NotAfter        time.Time `json:"not_after"`
+	DaysUntilExpiry int       `json:"days_until_expiry"`
+	Expired         bool      `json:"expired"`
+	IsCA            bool      `json:"is_ca"`
+}
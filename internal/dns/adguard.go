@@ -0,0 +1,46 @@
+package dns
+
+import (
+	"context"
+	"net/http"
+)
+
+// checkAdGuard reads AdGuard Home's status and statistics. Credentials
+// are sent with basic auth.
+func checkAdGuard(ctx context.Context, client *http.Client, s server, st *Status) error {
+	auth := func(req *http.Request) {
+		if s.user != "" || s.password != "" {
+			req.SetBasicAuth(s.user, s.password)
+		}
+	}
+
+	var status struct {
+		Running           bool   `json:"running"`
+		ProtectionEnabled bool   `json:"protection_enabled"`
+		Version           string `json:"version"`
+	}
+	if err := getJSON(ctx, client, s.endpoint("/control/status"), auth, &status); err != nil {
+		return err
+	}
+	st.Up = status.Running
+	st.Version = status.Version
+	st.Blocking = &status.ProtectionEnabled
+
+	var stats struct {
+		Queries           int64   `json:"num_dns_queries"`
+		Filtered          int64   `json:"num_blocked_filtering"`
+		SafeBrowsing      int64   `json:"num_replaced_safebrowsing"`
+		Parental          int64   `json:"num_replaced_parental"`
+		AvgProcessingTime float64 `json:"avg_processing_time"` // Seconds
+	}
+	if err := getJSON(ctx, client, s.endpoint("/control/stats"), auth, &stats); err != nil {
+		return err
+	}
+	st.Queries = stats.Queries
+	st.Blocked = stats.Filtered + stats.SafeBrowsing + stats.Parental
+	if stats.Queries > 0 {
+		st.BlockedPercent = float64(st.Blocked) * 100 / float64(stats.Queries)
+	}
+	st.AvgResponseMS = stats.AvgProcessingTime * 1000
+	return nil
+}
@@ -0,0 +1,173 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServer(t *testing.T) {
+	s, err := parseServer("pi", "pihole:http://:secret@127.0.0.1:8080/")
+	require.NoError(t, err)
+	assert.Equal(t, TypePihole, s.kind)
+	assert.Equal(t, "http://127.0.0.1:8080/", s.address)
+	assert.Equal(t, "secret", s.password)
+
+	s, err = parseServer("ub", "unbound")
+	require.NoError(t, err)
+	assert.Equal(t, TypeUnbound, s.kind)
+	assert.Empty(t, s.address)
+
+	_, err = parseServer("x", "bind:/etc/bind")
+	assert.Error(t, err)
+	_, err = parseServer("x", "adguard:127.0.0.1")
+	assert.Error(t, err)
+}
+
+func TestPiholeV6(t *testing.T) {
+	var loggedOut bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/auth" && r.Method == http.MethodPost:
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			valid := body["password"] == "secret"
+			json.NewEncoder(w).Encode(map[string]interface{}{"session": map[string]interface{}{"valid": valid, "sid": "sid1"}})
+		case r.URL.Path == "/api/auth" && r.Method == http.MethodDelete:
+			loggedOut = r.Header.Get("X-FTL-SID") == "sid1"
+		case r.Header.Get("X-FTL-SID") != "sid1":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/api/stats/summary":
+			w.Write([]byte(`{"queries":{"total":1000,"blocked":250,"percent_blocked":25.0,"cached":400,"frequency":2.5},"clients":{"active":7,"total":9},"gravity":{"domains_being_blocked":120000}}`))
+		case r.URL.Path == "/api/dns/blocking":
+			w.Write([]byte(`{"blocking":"enabled","timer":null}`))
+		case r.URL.Path == "/api/info/version":
+			w.Write([]byte(`{"version":{"core":{"local":{"version":"v6.0.4"}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	m, err := NewMonitor(map[string]string{
+		"pi":  "pihole:" + strings.Replace(srv.URL, "http://", "http://:secret@", 1),
+		"bad": "pihole:" + strings.Replace(srv.URL, "http://", "http://:wrong@", 1),
+	})
+	require.NoError(t, err)
+
+	list := m.Status(context.Background())
+	require.Len(t, list.Servers, 2)
+
+	bad := list.Servers[0]
+	assert.False(t, bad.Up)
+	assert.Contains(t, bad.Error, "rejected")
+
+	pi := list.Servers[1]
+	require.Empty(t, pi.Error)
+	assert.True(t, pi.Up)
+	assert.Equal(t, "v6.0.4", pi.Version)
+	assert.Equal(t, int64(1000), pi.Queries)
+	assert.Equal(t, int64(250), pi.Blocked)
+	assert.Equal(t, 25.0, pi.BlockedPercent)
+	assert.Equal(t, 150.0, pi.QueriesPerMinute)
+	assert.Equal(t, 7, pi.Clients)
+	require.NotNil(t, pi.Blocking)
+	assert.True(t, *pi.Blocking)
+	assert.NotContains(t, pi.Address, "secret")
+	assert.True(t, loggedOut)
+}
+
+func TestPiholeV5(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/api.php" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("auth") != "token" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`{"domains_being_blocked":90000,"dns_queries_today":500,"ads_blocked_today":50,"ads_percentage_today":10.0,"unique_clients":4,"queries_cached":100,"status":"disabled"}`))
+	}))
+	defer srv.Close()
+
+	m, err := NewMonitor(map[string]string{"pi": "pihole:" + strings.Replace(srv.URL, "http://", "http://:token@", 1)})
+	require.NoError(t, err)
+
+	pi := m.Status(context.Background()).Servers[0]
+	require.Empty(t, pi.Error)
+	assert.Equal(t, int64(500), pi.Queries)
+	assert.Equal(t, 10.0, pi.BlockedPercent)
+	require.NotNil(t, pi.Blocking)
+	assert.False(t, *pi.Blocking)
+}
+
+func TestAdGuard(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/control/status":
+			w.Write([]byte(`{"running":true,"protection_enabled":true,"version":"v0.107.43"}`))
+		case "/control/stats":
+			w.Write([]byte(`{"num_dns_queries":2000,"num_blocked_filtering":300,"num_replaced_safebrowsing":50,"num_replaced_parental":50,"avg_processing_time":0.012}`))
+		}
+	}))
+	defer srv.Close()
+
+	m, err := NewMonitor(map[string]string{"ag": "adguard:" + strings.Replace(srv.URL, "http://", "http://admin:pw@", 1)})
+	require.NoError(t, err)
+
+	ag := m.Status(context.Background()).Servers[0]
+	require.Empty(t, ag.Error)
+	assert.Equal(t, "v0.107.43", ag.Version)
+	assert.Equal(t, int64(400), ag.Blocked)
+	assert.Equal(t, 20.0, ag.BlockedPercent)
+	assert.InDelta(t, 12.0, ag.AvgResponseMS, 0.001)
+}
+
+func TestUnbound(t *testing.T) {
+	var calls [][]string
+	runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		switch args[len(args)-1] {
+		case "stats_noreset":
+			return []byte("thread0.num.queries=10\ntotal.num.queries=1200\ntotal.num.cachehits=900\ntotal.recursion.time.avg=0.045000\n"), nil
+		case "status":
+			return []byte("version: 1.17.1\nverbosity: 1\n"), nil
+		}
+		return nil, errors.New("unexpected")
+	}
+	defer func() { runCommand = defaultRunCommand }()
+
+	m, err := NewMonitor(map[string]string{"ub": "unbound:/etc/unbound/unbound.conf"})
+	require.NoError(t, err)
+
+	ub := m.Status(context.Background()).Servers[0]
+	require.Empty(t, ub.Error)
+	assert.True(t, ub.Up)
+	assert.Equal(t, "1.17.1", ub.Version)
+	assert.Equal(t, int64(1200), ub.Queries)
+	assert.Equal(t, int64(900), ub.CacheHits)
+	assert.InDelta(t, 45.0, ub.AvgResponseMS, 0.001)
+	assert.Equal(t, []string{"-c", "/etc/unbound/unbound.conf", "stats_noreset"}, calls[0])
+}
+
+func TestRate(t *testing.T) {
+	m, _ := NewMonitor(nil)
+	now := time.Now()
+	assert.Zero(t, m.rate("x", 100, now))
+	assert.Equal(t, 60.0, m.rate("x", 130, now.Add(30*time.Second)))
+	// A counter reset gives no rate
+	assert.Zero(t, m.rate("x", 5, now.Add(time.Minute)))
+}
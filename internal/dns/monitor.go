@@ -0,0 +1,143 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds the check of one server
+const checkTimeout = 5 * time.Second
+
+// server is a configured DNS server
+type server struct {
+	name     string
+	kind     string
+	address  string // Base URL without credentials, or unbound.conf path
+	user     string
+	password string
+}
+
+func (s server) endpoint(path string) string {
+	return strings.TrimRight(s.address, "/") + path
+}
+
+// parseServer reads type:address, e.g. pihole:http://:password@127.0.0.1,
+// adguard:http://admin:pw@127.0.0.1:3000 or unbound (optionally
+// unbound:/etc/unbound/unbound.conf)
+func parseServer(name, spec string) (server, error) {
+	kind, address, _ := strings.Cut(spec, ":")
+	s := server{name: name, kind: kind}
+
+	switch kind {
+	case TypeUnbound:
+		s.address = address
+		return s, nil
+	case TypePihole, TypeAdGuard:
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return server{}, fmt.Errorf("dns server %s: invalid URL", name)
+		}
+		s.user = u.User.Username()
+		s.password, _ = u.User.Password()
+		u.User = nil
+		s.address = u.String()
+		return s, nil
+	}
+	return server{}, fmt.Errorf("dns server %s: unknown type %q (use pihole, adguard or unbound)", name, kind)
+}
+
+type sample struct {
+	queries int64
+	at      time.Time
+}
+
+// Monitor reads statistics from Pi-hole, AdGuard Home and unbound
+type Monitor struct {
+	servers []server
+	http    *http.Client
+
+	mu   sync.Mutex
+	last map[string]sample // Previous query counts, for the query rate
+}
+
+// NewMonitor creates a monitor for name=type:address pairs
+func NewMonitor(specs map[string]string) (*Monitor, error) {
+	m := &Monitor{
+		http: &http.Client{Timeout: checkTimeout},
+		last: make(map[string]sample),
+	}
+	for name, spec := range specs {
+		s, err := parseServer(name, spec)
+		if err != nil {
+			return nil, err
+		}
+		m.servers = append(m.servers, s)
+	}
+	sort.Slice(m.servers, func(i, j int) bool { return m.servers[i].name < m.servers[j].name })
+	return m, nil
+}
+
+// Status checks every server concurrently
+func (m *Monitor) Status(ctx context.Context) *StatusList {
+	list := &StatusList{Servers: make([]Status, len(m.servers)), Total: len(m.servers)}
+
+	var wg sync.WaitGroup
+	for i, s := range m.servers {
+		wg.Add(1)
+		go func(i int, s server) {
+			defer wg.Done()
+			list.Servers[i] = m.check(ctx, s)
+		}(i, s)
+	}
+	wg.Wait()
+	return list
+}
+
+func (m *Monitor) check(ctx context.Context, s server) Status {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	st := Status{Name: s.name, Type: s.kind, Address: s.address}
+	var err error
+	switch s.kind {
+	case TypePihole:
+		err = checkPihole(ctx, m.http, s, &st)
+	case TypeAdGuard:
+		err = checkAdGuard(ctx, m.http, s, &st)
+	case TypeUnbound:
+		err = checkUnbound(ctx, s, &st)
+	}
+	st.CheckedAt = time.Now()
+	if err != nil {
+		st.Up = false
+		st.Error = err.Error()
+		return st
+	}
+
+	// Pi-hole v6 reports its own rate
+	if rate := m.rate(s.name, st.Queries, st.CheckedAt); st.QueriesPerMinute == 0 {
+		st.QueriesPerMinute = rate
+	}
+	return st
+}
+
+// rate returns queries per minute since the previous check. Counters
+// that went down (a reset or a rolling window) give no rate.
+func (m *Monitor) rate(name string, queries int64, at time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prev, ok := m.last[name]
+	m.last[name] = sample{queries: queries, at: at}
+	elapsed := at.Sub(prev.at).Minutes()
+	if !ok || queries < prev.queries || elapsed <= 0 {
+		return 0
+	}
+	return float64(queries-prev.queries) / elapsed
+}
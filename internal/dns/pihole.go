@@ -0,0 +1,198 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// checkPihole reads a Pi-hole's summary. Pi-hole v6 has a session-based
+// API under /api; older versions only have /admin/api.php, which is used
+// when /api/auth is missing.
+func checkPihole(ctx context.Context, client *http.Client, s server, st *Status) error {
+	sid, err := piholeLogin(ctx, client, s)
+	if err == errNotFound {
+		return checkPiholeV5(ctx, client, s, st)
+	}
+	if err != nil {
+		return err
+	}
+	// Sessions are limited, so each check ends its own
+	defer piholeLogout(client, s, sid)
+
+	var summary struct {
+		Queries struct {
+			Total          int64   `json:"total"`
+			Blocked        int64   `json:"blocked"`
+			PercentBlocked float64 `json:"percent_blocked"`
+			Cached         int64   `json:"cached"`
+			Frequency      float64 `json:"frequency"` // Queries per second
+		} `json:"queries"`
+		Clients struct {
+			Active int `json:"active"`
+		} `json:"clients"`
+		Gravity struct {
+			DomainsBeingBlocked int64 `json:"domains_being_blocked"`
+		} `json:"gravity"`
+	}
+	if err := getJSON(ctx, client, s.endpoint("/api/stats/summary"), piholeAuth(sid), &summary); err != nil {
+		return err
+	}
+	st.Up = true
+	st.Queries = summary.Queries.Total
+	st.Blocked = summary.Queries.Blocked
+	st.BlockedPercent = summary.Queries.PercentBlocked
+	st.CacheHits = summary.Queries.Cached
+	st.QueriesPerMinute = summary.Queries.Frequency * 60
+	st.Clients = summary.Clients.Active
+	st.DomainsBlocked = summary.Gravity.DomainsBeingBlocked
+
+	var blocking struct {
+		Blocking string `json:"blocking"`
+	}
+	if err := getJSON(ctx, client, s.endpoint("/api/dns/blocking"), piholeAuth(sid), &blocking); err == nil {
+		enabled := blocking.Blocking == "enabled"
+		st.Blocking = &enabled
+	}
+
+	var version struct {
+		Version struct {
+			Core struct {
+				Local struct {
+					Version string `json:"version"`
+				} `json:"local"`
+			} `json:"core"`
+		} `json:"version"`
+	}
+	if err := getJSON(ctx, client, s.endpoint("/api/info/version"), piholeAuth(sid), &version); err == nil {
+		st.Version = version.Version.Core.Local.Version
+	}
+	return nil
+}
+
+func piholeAuth(sid string) func(*http.Request) {
+	return func(req *http.Request) {
+		if sid != "" {
+			req.Header.Set("X-FTL-SID", sid)
+		}
+	}
+}
+
+// piholeLogin returns a session ID, or "" when the Pi-hole has no password
+func piholeLogin(ctx context.Context, client *http.Client, s server) (string, error) {
+	body, _ := json.Marshal(map[string]string{"password": s.password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint("/api/auth"), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var auth struct {
+		Session struct {
+			Valid bool   `json:"valid"`
+			SID   string `json:"sid"`
+		} `json:"session"`
+	}
+	if err := doJSON(client, req, &auth); err != nil {
+		return "", err
+	}
+	if !auth.Session.Valid {
+		return "", fmt.Errorf("pi-hole rejected the password")
+	}
+	return auth.Session.SID, nil
+}
+
+func piholeLogout(client *http.Client, s server, sid string) {
+	if sid == "" {
+		return
+	}
+	req, err := http.NewRequest(http.MethodDelete, s.endpoint("/api/auth"), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("X-FTL-SID", sid)
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func checkPiholeV5(ctx context.Context, client *http.Client, s server, st *Status) error {
+	endpoint := s.endpoint("/admin/api.php") + "?summaryRaw"
+	if s.password != "" {
+		endpoint += "&auth=" + url.QueryEscape(s.password)
+	}
+	var summary struct {
+		Queries        int64   `json:"dns_queries_today"`
+		Blocked        int64   `json:"ads_blocked_today"`
+		Percent        float64 `json:"ads_percentage_today"`
+		Cached         int64   `json:"queries_cached"`
+		Clients        int     `json:"unique_clients"`
+		DomainsBlocked int64   `json:"domains_being_blocked"`
+		Status         string  `json:"status"`
+	}
+	if err := getJSON(ctx, client, endpoint, nil, &summary); err != nil {
+		return err
+	}
+	// Without a valid token v5 answers with an empty array
+	if summary.Status == "" {
+		return fmt.Errorf("pi-hole rejected the API token")
+	}
+
+	st.Up = true
+	st.Queries = summary.Queries
+	st.Blocked = summary.Blocked
+	st.BlockedPercent = summary.Percent
+	st.CacheHits = summary.Cached
+	st.Clients = summary.Clients
+	st.DomainsBlocked = summary.DomainsBlocked
+	enabled := summary.Status == "enabled"
+	st.Blocking = &enabled
+	return nil
+}
+
+var errNotFound = errors.New("not found")
+
+func getJSON(ctx context.Context, client *http.Client, endpoint string, prepare func(*http.Request), v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	return doJSON(client, req, v)
+}
+
+func doJSON(client *http.Client, req *http.Request, v interface{}) error {
+	req.Header.Set("User-Agent", "hivedeck-agent")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", req.URL.Path, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	// Pi-hole v5 answers [] instead of an object without a valid token
+	if bytes.Equal(bytes.TrimSpace(data), []byte("[]")) {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package dns
+
+import "time"
+
+// Server types
+const (
+	TypePihole  = "pihole"
+	TypeAdGuard = "adguard"
+	TypeUnbound = "unbound"
+)
+
+// Status is the state of one DNS server. Counters cover the server's own
+// window: the last 24 hours for Pi-hole and AdGuard Home, and the time
+// since the last statistics reset for unbound.
+type Status struct {
+	Name             string    `json:"name"`
+	Type             string    `json:"type"`
+	Address          string    `json:"address,omitempty"` // Without credentials
+	Up               bool      `json:"up"`
+	Version          string    `json:"version,omitempty"`
+	Blocking         *bool     `json:"blocking,omitempty"` // Pi-hole and AdGuard Home
+	Queries          int64     `json:"queries"`
+	Blocked          int64     `json:"blocked"`
+	BlockedPercent   float64   `json:"blocked_percent"`
+	QueriesPerMinute float64   `json:"queries_per_minute"` // Between the last two checks
+	CacheHits        int64     `json:"cache_hits"`
+	Clients          int       `json:"clients,omitempty"`
+	DomainsBlocked   int64     `json:"domains_blocked,omitempty"`
+	AvgResponseMS    float64   `json:"avg_response_ms,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	CheckedAt        time.Time `json:"checked_at"`
+}
+
+// StatusList is the state of every configured DNS server
+type StatusList struct {
+	Servers []Status `json:"servers"`
+	Total   int      `json:"total"`
+}
@@ -0,0 +1,64 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// runCommand runs a command and returns its output; replaced in tests
+var runCommand = defaultRunCommand
+
+func defaultRunCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// checkUnbound reads unbound's counters with unbound-control without
+// resetting them. s.address is an optional unbound.conf path. Unbound has
+// no blocklist of its own, so nothing is counted as blocked.
+func checkUnbound(ctx context.Context, s server, st *Status) error {
+	control := func(command string) ([]byte, error) {
+		if s.address != "" {
+			return runCommand(ctx, "unbound-control", "-c", s.address, command)
+		}
+		return runCommand(ctx, "unbound-control", command)
+	}
+
+	out, err := control("stats_noreset")
+	if err != nil {
+		return err
+	}
+	st.Up = true
+	parseUnboundStats(string(out), st)
+
+	if out, err := control("status"); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			if v, ok := strings.CutPrefix(line, "version: "); ok {
+				st.Version = strings.TrimSpace(v)
+			}
+		}
+	}
+	return nil
+}
+
+// parseUnboundStats fills st from name=value lines
+func parseUnboundStats(out string, st *Status) {
+	stats := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			stats[k] = v
+		}
+	}
+
+	st.Queries, _ = strconv.ParseInt(stats["total.num.queries"], 10, 64)
+	st.CacheHits, _ = strconv.ParseInt(stats["total.num.cachehits"], 10, 64)
+	if avg, err := strconv.ParseFloat(stats["total.recursion.time.avg"], 64); err == nil {
+		st.AvgResponseMS = avg * 1000
+	}
+}
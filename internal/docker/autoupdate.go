@@ -0,0 +1,309 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// AutoUpdateLabel opts a container in ("true") or out ("false") of
+// automatic updates, overriding AutoUpdatePolicy.Containers
+const AutoUpdateLabel = "hivedeck.auto-update"
+
+// healthPoll is how often a new container's health is checked
+const healthPoll = 2 * time.Second
+
+// AutoUpdatePolicy selects the containers AutoUpdate recreates
+type AutoUpdatePolicy struct {
+	Containers    []string      // Container names to update in addition to labelled ones
+	HealthTimeout time.Duration // How long a new container has to become healthy
+}
+
+// selects reports whether the policy covers a container
+func (p AutoUpdatePolicy) selects(name string, labels map[string]string) bool {
+	if v, ok := labels[AutoUpdateLabel]; ok {
+		enabled, err := strconv.ParseBool(v)
+		return err == nil && enabled
+	}
+	for _, c := range p.Containers {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoUpdate pulls newer images for the running containers the policy
+// selects and recreates them with the same configuration. A new container
+// that does not become healthy is removed and the old one started again.
+func (m *Manager) AutoUpdate(ctx context.Context, policy AutoUpdatePolicy) (*AutoUpdateReport, error) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	report := &AutoUpdateReport{Results: []AutoUpdateResult{}}
+	remote := map[string]string{}
+	for _, c := range containers {
+		name := strings.TrimPrefix(c.Names[0], "/")
+		if !policy.selects(name, c.Labels) {
+			continue
+		}
+
+		old, err := m.client.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			report.Failed++
+			report.Results = append(report.Results, AutoUpdateResult{
+				Container: name, Image: c.Image, Status: AutoFailed,
+				Error: fmt.Sprintf("failed to inspect container: %v", err),
+			})
+			continue
+		}
+		result := m.autoUpdate(ctx, old, policy.HealthTimeout, remote)
+		if result == nil {
+			continue
+		}
+		switch result.Status {
+		case AutoUpdated:
+			report.Updated++
+		case AutoRolledBack:
+			report.RolledBack++
+		case AutoFailed:
+			report.Failed++
+		}
+		report.Results = append(report.Results, *result)
+	}
+
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Container < report.Results[j].Container
+	})
+	return report, nil
+}
+
+// UpdateContainer pulls a newer image for a running container's tag, if
+// there is one, and recreates the container from it as AutoUpdate does.
+// A container whose image is current, pinned to a digest or built locally
+// is left alone with the status up_to_date.
+func (m *Manager) UpdateContainer(ctx context.Context, id string, healthTimeout time.Duration) (*AutoUpdateResult, error) {
+	old, err := m.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, dockerError("failed to inspect container", err)
+	}
+	if old.State == nil || !old.State.Running {
+		return nil, apierror.Conflict("container %s is not running", id)
+	}
+
+	result := m.autoUpdate(ctx, old, healthTimeout, map[string]string{})
+	if result == nil {
+		result = &AutoUpdateResult{
+			Container: strings.TrimPrefix(old.Name, "/"),
+			Image:     old.Config.Image,
+			OldImage:  old.Image,
+			Status:    AutoCurrent,
+		}
+	}
+	return result, nil
+}
+
+// autoUpdate updates one container, returning nil when its image is current
+func (m *Manager) autoUpdate(ctx context.Context, old types.ContainerJSON, timeout time.Duration, remote map[string]string) *AutoUpdateResult {
+	result := &AutoUpdateResult{Container: strings.TrimPrefix(old.Name, "/"), Image: old.Config.Image, OldImage: old.Image}
+
+	update := ImageUpdate{Image: old.Config.Image}
+	m.checkImage(ctx, &update, old.Image, remote)
+	switch update.Status {
+	case UpdateAvailable:
+		if err := m.pull(ctx, old.Config.Image); err != nil {
+			result.Status, result.Error = AutoFailed, err.Error()
+			return result
+		}
+	case UpdatePulled:
+	case UpdateError:
+		result.Status, result.Error = AutoFailed, update.Error
+		return result
+	default:
+		return nil
+	}
+
+	image, _, err := m.client.ImageInspectWithRaw(ctx, old.Config.Image)
+	if err != nil {
+		result.Status, result.Error = AutoFailed, fmt.Sprintf("failed to inspect image: %v", err)
+		return result
+	}
+	result.NewImage = image.ID
+	if image.ID == old.Image {
+		result.Status = AutoCurrent
+		return result
+	}
+
+	rolledBack, err := m.recreate(ctx, old, timeout)
+	switch {
+	case err == nil:
+		result.Status = AutoUpdated
+	case rolledBack:
+		result.Status, result.Error = AutoRolledBack, err.Error()
+	default:
+		result.Status, result.Error = AutoFailed, err.Error()
+	}
+	return result
+}
+
+// pull pulls ref, returning the first error the daemon reports in the
+// progress stream
+func (m *Manager) pull(ctx context.Context, ref string) error {
+	_, err := m.PullImage(ctx, ref, nil)
+	return err
+}
+
+// recreate replaces old with a container from the same configuration and
+// the image its tag now points at. The old container is renamed and kept
+// stopped until the new one is healthy, so it can be started again.
+// rolledBack reports whether the old container runs again after a
+// failure.
+func (m *Manager) recreate(ctx context.Context, old types.ContainerJSON, timeout time.Duration) (rolledBack bool, err error) {
+	name := strings.TrimPrefix(old.Name, "/")
+	stopTimeout := 30
+	if err := m.client.ContainerStop(ctx, old.ID, container.StopOptions{Timeout: &stopTimeout}); err != nil {
+		return m.rollback(ctx, old, false, "", fmt.Errorf("failed to stop container: %w", err))
+	}
+	if err := m.client.ContainerRename(ctx, old.ID, name+"-hivedeck-old"); err != nil {
+		return m.rollback(ctx, old, false, "", fmt.Errorf("failed to rename container: %w", err))
+	}
+
+	config := *old.Config
+	if config.Hostname == old.ID[:12] {
+		config.Hostname = "" // Docker's default; the new container gets its own
+	}
+	primary, extra := endpoints(old)
+	created, err := m.client.ContainerCreate(ctx, &config, old.HostConfig, primary, nil, name)
+	if err != nil {
+		return m.rollback(ctx, old, true, "", fmt.Errorf("failed to create container: %w", err))
+	}
+	for net, settings := range extra {
+		if err := m.client.NetworkConnect(ctx, net, created.ID, settings); err != nil {
+			return m.rollback(ctx, old, true, created.ID, fmt.Errorf("failed to connect to network %s: %w", net, err))
+		}
+	}
+	if err := m.client.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return m.rollback(ctx, old, true, created.ID, fmt.Errorf("failed to start container: %w", err))
+	}
+	if err := m.waitHealthy(ctx, created.ID, timeout); err != nil {
+		return m.rollback(ctx, old, true, created.ID, err)
+	}
+
+	if err := m.client.ContainerRemove(ctx, old.ID, types.ContainerRemoveOptions{}); err != nil {
+		log.Printf("Updated container %s, but failed to remove the old one: %v", name, err)
+	}
+	return false, nil
+}
+
+// rollback removes the new container, gives the old one back its name if
+// it was renamed and starts it. cause is returned, joined with any error on
+// the way back; restored is false if there was one.
+func (m *Manager) rollback(ctx context.Context, old types.ContainerJSON, renamed bool, newID string, cause error) (restored bool, err error) {
+	// Restore even if the update was cancelled part way
+	ctx = context.WithoutCancel(ctx)
+
+	errs := []error{cause}
+	if newID != "" {
+		if err := m.client.ContainerRemove(ctx, newID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove the new container: %w", err))
+		}
+	}
+	if renamed {
+		if err := m.client.ContainerRename(ctx, old.ID, strings.TrimPrefix(old.Name, "/")); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore the container name: %w", err))
+		}
+	}
+	if err := m.client.ContainerStart(ctx, old.ID, types.ContainerStartOptions{}); err != nil {
+		errs = append(errs, fmt.Errorf("failed to start the old container: %w", err))
+	}
+	return len(errs) == 1, errors.Join(errs...)
+}
+
+// endpoints splits a container's networks into the one it is created on
+// and the ones it is connected to afterwards. Addresses are left to the
+// daemon unless they were configured.
+func endpoints(c types.ContainerJSON) (*network.NetworkingConfig, map[string]*network.EndpointSettings) {
+	mode := string(c.HostConfig.NetworkMode)
+	if mode == "default" {
+		mode = "bridge"
+	}
+
+	primary := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+	extra := map[string]*network.EndpointSettings{}
+	if c.NetworkSettings == nil {
+		return primary, extra
+	}
+	for net, ep := range c.NetworkSettings.Networks {
+		settings := &network.EndpointSettings{IPAMConfig: ep.IPAMConfig, Links: ep.Links}
+		for _, alias := range ep.Aliases {
+			if alias != c.ID[:12] {
+				settings.Aliases = append(settings.Aliases, alias)
+			}
+		}
+		if net == mode {
+			primary.EndpointsConfig[net] = settings
+		} else {
+			extra[net] = settings
+		}
+	}
+	return primary, extra
+}
+
+// waitHealthy waits for a started container to pass its healthcheck
+func (m *Manager) waitHealthy(ctx context.Context, id string, timeout time.Duration) error {
+	start := time.Now()
+	stable := min(10*time.Second, timeout)
+	for {
+		inspect, err := m.client.ContainerInspect(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to inspect the new container: %w", err)
+		}
+		if done, err := healthVerdict(inspect.State, time.Since(start), stable); done {
+			return err
+		}
+		if time.Since(start) >= timeout {
+			return fmt.Errorf("new container not healthy after %s", timeout)
+		}
+
+		select {
+		case <-time.After(healthPoll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// healthVerdict decides whether a new container is healthy. Containers
+// with a healthcheck must report healthy; others must keep running for
+// stable.
+func healthVerdict(state *types.ContainerState, elapsed, stable time.Duration) (bool, error) {
+	if state == nil {
+		return false, nil
+	}
+	if !state.Running || state.Restarting {
+		return true, fmt.Errorf("new container exited with code %d", state.ExitCode)
+	}
+	if state.Health != nil {
+		switch state.Health.Status {
+		case types.Healthy:
+			return true, nil
+		case types.Unhealthy:
+			return true, errors.New("new container is unhealthy")
+		}
+		return false, nil
+	}
+	return elapsed >= stable, nil
+}
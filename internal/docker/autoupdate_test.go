@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestAutoUpdatePolicySelects(t *testing.T) {
+	policy := AutoUpdatePolicy{Containers: []string{"web", "db"}}
+
+	assert.True(t, policy.selects("web", nil))
+	assert.False(t, policy.selects("cache", nil))
+	assert.True(t, policy.selects("cache", map[string]string{AutoUpdateLabel: "true"}))
+	assert.False(t, policy.selects("db", map[string]string{AutoUpdateLabel: "false"}), "the label opts out")
+	assert.False(t, policy.selects("web", map[string]string{AutoUpdateLabel: "sometimes"}))
+}
+
+func TestHealthVerdict(t *testing.T) {
+	stable := 10 * time.Second
+	health := func(status string) *types.ContainerState {
+		return &types.ContainerState{Running: true, Health: &types.Health{Status: status}}
+	}
+
+	done, err := healthVerdict(health(types.Starting), time.Minute, stable)
+	assert.False(t, done, "a healthcheck wins over the stable period")
+	assert.NoError(t, err)
+
+	done, err = healthVerdict(health(types.Healthy), time.Second, stable)
+	assert.True(t, done)
+	assert.NoError(t, err)
+
+	done, err = healthVerdict(health(types.Unhealthy), time.Second, stable)
+	assert.True(t, done)
+	assert.EqualError(t, err, "new container is unhealthy")
+
+	running := &types.ContainerState{Running: true}
+	done, _ = healthVerdict(running, 5*time.Second, stable)
+	assert.False(t, done)
+	done, err = healthVerdict(running, stable, stable)
+	assert.True(t, done)
+	assert.NoError(t, err)
+
+	done, err = healthVerdict(&types.ContainerState{ExitCode: 1}, time.Second, stable)
+	assert.True(t, done)
+	assert.EqualError(t, err, "new container exited with code 1")
+}
+
+func TestEndpoints(t *testing.T) {
+	c := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         "0123456789abcdef",
+			HostConfig: &container.HostConfig{NetworkMode: "app"},
+		},
+		NetworkSettings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"app":     {Aliases: []string{"web", "0123456789ab"}, IPAddress: "172.20.0.5"},
+			"metrics": {IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.30.0.9"}},
+		}},
+	}
+
+	primary, extra := endpoints(c)
+	assert.Equal(t, map[string]*network.EndpointSettings{"app": {Aliases: []string{"web"}}}, primary.EndpointsConfig)
+	assert.Equal(t, map[string]*network.EndpointSettings{
+		"metrics": {IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "172.30.0.9"}},
+	}, extra)
+}
+
+func TestUpdateContainer(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/containers/web/json": map[string]interface{}{
+			"Id": webID, "Name": "/web", "Image": "sha256:aaa",
+			"State":  map[string]interface{}{"Running": true},
+			"Config": map[string]interface{}{"Image": "nginx@sha256:bbb"},
+		},
+		"/containers/old/json": map[string]interface{}{
+			"Id": webID, "Name": "/old", "Image": "sha256:aaa",
+			"State":  map[string]interface{}{"Running": false},
+			"Config": map[string]interface{}{"Image": "nginx:latest"},
+		},
+	})
+
+	// A digest-pinned image has nothing to update to
+	result, err := m.UpdateContainer(context.Background(), "web", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, AutoUpdateResult{Container: "web", Image: "nginx@sha256:bbb", OldImage: "sha256:aaa", Status: AutoCurrent}, *result)
+
+	_, err = m.UpdateContainer(context.Background(), "old", time.Minute)
+	assert.ErrorIs(t, err, apierror.ErrConflict)
+
+	_, err = m.UpdateContainer(context.Background(), "missing", time.Minute)
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
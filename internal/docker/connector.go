@@ -0,0 +1,169 @@
+package docker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// retryAfter limits how often a request for an unavailable daemon triggers
+// a connection attempt between the periodic checks
+const retryAfter = 5 * time.Second
+
+// pingTimeout bounds a single availability check
+const pingTimeout = 3 * time.Second
+
+// Status describes the connection to the Docker daemon
+type Status struct {
+	Available bool      `json:"available"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Engine    *Engine   `json:"engine,omitempty"` // While available
+}
+
+// Connector holds the Docker client for a daemon that may start after the
+// agent or restart under it. It connects on first use and checks the daemon
+// periodically, dropping the client when the daemon goes away.
+type Connector struct {
+	interval time.Duration
+	host     string
+	auth     *RegistryAuth
+
+	mu      sync.Mutex
+	manager *Manager
+	status  Status
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewConnector creates a connector for the daemon at host (see NewManager)
+// that checks it every interval once started
+func NewConnector(interval time.Duration, host string, auth *RegistryAuth) *Connector {
+	return &Connector{
+		interval: interval,
+		host:     host,
+		auth:     auth,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start checks the daemon now and then every interval
+func (c *Connector) Start() {
+	go func() {
+		defer close(c.done)
+
+		c.check()
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.check()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic checks and closes the client. It must only be
+// called after Start.
+func (c *Connector) Stop() error {
+	close(c.stop)
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.manager == nil {
+		return nil
+	}
+	err := c.manager.Close()
+	c.manager = nil
+	return err
+}
+
+// Manager returns the client when the daemon is available, or nil. If the
+// daemon was unavailable at the last check, and that check is more than a few
+// seconds old, it tries to connect again first.
+func (c *Connector) Manager() *Manager {
+	c.mu.Lock()
+	manager, status := c.manager, c.status
+	c.mu.Unlock()
+
+	if status.Available {
+		return manager
+	}
+	if time.Since(status.LastCheck) < retryAfter {
+		return nil
+	}
+	if c.check().Available {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.manager
+	}
+	return nil
+}
+
+// Status returns the result of the last check
+func (c *Connector) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.status
+}
+
+// check pings the daemon, creating a client first if there is none
+func (c *Connector) check() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := Status{LastCheck: time.Now()}
+
+	if c.manager == nil {
+		manager, err := NewManager(c.host, c.auth)
+		if err != nil {
+			status.Error = err.Error()
+			c.setStatus(status)
+			return status
+		}
+		c.manager = manager
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	if _, err := c.manager.client.Ping(ctx); err != nil {
+		// Start from a new client next time so the API version is
+		// negotiated again with whichever daemon comes up
+		c.manager.Close()
+		c.manager = nil
+		status.Error = err.Error()
+	} else {
+		if c.manager.engine.Name == "" {
+			c.manager.engine = c.manager.detectEngine(ctx)
+		}
+		engine := c.manager.engine
+		status.Available, status.Engine = true, &engine
+	}
+
+	c.setStatus(status)
+	return status
+}
+
+// setStatus records a check result and logs availability changes. The
+// caller holds mu.
+func (c *Connector) setStatus(status Status) {
+	if status.Available != c.status.Available || c.status.LastCheck.IsZero() {
+		if status.Available {
+			log.Printf("Docker is available (%s %s at %s)", status.Engine.Name, status.Engine.Version, status.Engine.Host)
+		} else {
+			log.Printf("Docker is unavailable: %s", status.Error)
+		}
+	}
+	c.status = status
+}
@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDaemon answers Docker pings on addr
+func fakeDaemon(t *testing.T, addr string) *httptest.Server {
+	ln, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestConnectorReconnects(t *testing.T) {
+	// Reserve a port, then free it so nothing answers there yet
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+addr)
+
+	c := NewConnector(time.Hour, "", nil)
+	assert.Nil(t, c.Manager())
+	status := c.Status()
+	assert.False(t, status.Available)
+	assert.NotEmpty(t, status.Error)
+
+	// Within the retry window nothing is attempted
+	daemon := fakeDaemon(t, addr)
+	assert.Nil(t, c.Manager())
+
+	// A periodic check picks the daemon up
+	assert.True(t, c.check().Available)
+	assert.NotNil(t, c.Manager())
+
+	// And notices when it goes away
+	daemon.Close()
+	assert.False(t, c.check().Available)
+	assert.Nil(t, c.Manager())
+}
+
+func TestConnectorStartStop(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://"+fakeDaemon(t, "127.0.0.1:0").Listener.Addr().String())
+
+	c := NewConnector(time.Hour, "", nil)
+	c.Start()
+	require.Eventually(t, func() bool { return c.Status().Available }, 5*time.Second, 10*time.Millisecond)
+	assert.NotNil(t, c.Manager())
+	assert.NoError(t, c.Stop())
+}
@@ -0,0 +1,249 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// ErrCreateDisabled is returned when DOCKER_CREATE_ENABLED is off
+var ErrCreateDisabled = apierror.NotAllowed("container creation is disabled")
+
+// validName matches container and volume names as the daemon accepts them
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// CreateContainer creates a container from spec and starts it unless
+// spec.Start is false. The image is pulled first according to spec.Pull.
+// A container that fails to start is removed, so the request can be
+// retried with the same name.
+func (m *Manager) CreateContainer(ctx context.Context, spec ContainerSpec) (*CreatedContainer, error) {
+	config, hostConfig, err := spec.build()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CreatedContainer{Name: spec.Name, Image: config.Image}
+	if result.Pulled, err = m.ensureImage(ctx, config.Image, spec.Pull); err != nil {
+		return nil, err
+	}
+
+	created, err := m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, spec.Name)
+	if err != nil {
+		return nil, dockerError("failed to create container", err)
+	}
+	result.ID = created.ID[:12]
+	result.Warnings = created.Warnings
+
+	if spec.Start == nil || *spec.Start {
+		if err := m.client.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+			m.client.ContainerRemove(context.WithoutCancel(ctx), created.ID, types.ContainerRemoveOptions{Force: true})
+			return nil, dockerError("failed to start container", err)
+		}
+		result.Started = true
+	}
+
+	if result.Name == "" {
+		if inspect, err := m.client.ContainerInspect(ctx, created.ID); err == nil {
+			result.Name = strings.TrimPrefix(inspect.Name, "/")
+		}
+	}
+	return result, nil
+}
+
+// Validate reports the first problem with the spec, as CreateContainer
+// would
+func (s ContainerSpec) Validate() error {
+	_, _, err := s.build()
+	return err
+}
+
+// CheckHostAccess refuses bind mounts from outside the paths allowed says
+// may be shared, and network modes that share the host's or another
+// container's namespaces. Either would hand the container the host.
+func (s ContainerSpec) CheckHostAccess(allowed func(path string) bool) error {
+	switch mode := container.NetworkMode(s.Network); {
+	case mode.IsHost(), mode.IsContainer():
+		return apierror.NotAllowed("network mode %q is not allowed", s.Network)
+	}
+
+	for _, v := range s.Volumes {
+		if !path.IsAbs(v.Source) {
+			continue
+		}
+		// The daemon follows symlinks, so check where the source leads
+		source := path.Clean(v.Source)
+		if resolved, err := filepath.EvalSymlinks(source); err == nil {
+			source = resolved
+		}
+		if !allowed(source) {
+			return apierror.NotAllowed("bind mount source %s is outside the allowed paths", v.Source)
+		}
+	}
+	return nil
+}
+
+// ensureImage pulls ref when the policy asks for it, reporting whether it
+// did
+func (m *Manager) ensureImage(ctx context.Context, ref, policy string) (bool, error) {
+	switch policy {
+	case "never":
+		return false, nil
+	case "always":
+	default:
+		_, _, err := m.client.ImageInspectWithRaw(ctx, ref)
+		if err == nil {
+			return false, nil
+		}
+		if !client.IsErrNotFound(err) {
+			return false, dockerError("failed to inspect image "+ref, err)
+		}
+	}
+
+	if _, err := m.PullImage(ctx, ref, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// build validates the spec and turns it into the daemon's configuration
+func (s ContainerSpec) build() (*container.Config, *container.HostConfig, error) {
+	if s.Image == "" {
+		return nil, nil, apierror.Invalid("image is required")
+	}
+	image, err := NormalizeImage(s.Image)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.Name != "" && !validName.MatchString(s.Name) {
+		return nil, nil, apierror.Invalid("invalid container name %q", s.Name)
+	}
+	switch s.Pull {
+	case "", "missing", "always", "never":
+	default:
+		return nil, nil, apierror.Invalid("invalid pull policy %q: use missing, always or never", s.Pull)
+	}
+
+	restart, err := parseRestartPolicy(s.Restart)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &container.Config{
+		Image:        image,
+		Cmd:          s.Command,
+		Labels:       s.Labels,
+		ExposedPorts: nat.PortSet{},
+	}
+	hostConfig := &container.HostConfig{
+		RestartPolicy: restart,
+		PortBindings:  nat.PortMap{},
+		NetworkMode:   container.NetworkMode(s.Network),
+	}
+
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			return nil, nil, apierror.Invalid("invalid environment variable name %q", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		config.Env = append(config.Env, k+"="+s.Env[k])
+	}
+
+	for _, p := range s.Ports {
+		port, binding, err := p.build()
+		if err != nil {
+			return nil, nil, err
+		}
+		config.ExposedPorts[port] = struct{}{}
+		hostConfig.PortBindings[port] = append(hostConfig.PortBindings[port], binding)
+	}
+
+	for _, v := range s.Volumes {
+		m, err := v.build()
+		if err != nil {
+			return nil, nil, err
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, m)
+	}
+
+	return config, hostConfig, nil
+}
+
+func (p PortSpec) build() (nat.Port, nat.PortBinding, error) {
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return "", nat.PortBinding{}, apierror.Invalid("invalid protocol %q: use tcp or udp", p.Protocol)
+	}
+	if p.Container < 1 || p.Container > 65535 {
+		return "", nat.PortBinding{}, apierror.Invalid("invalid container port %d", p.Container)
+	}
+	if p.Host < 0 || p.Host > 65535 {
+		return "", nat.PortBinding{}, apierror.Invalid("invalid host port %d", p.Host)
+	}
+
+	binding := nat.PortBinding{HostIP: p.HostIP}
+	if p.Host > 0 {
+		binding.HostPort = strconv.Itoa(p.Host)
+	}
+	return nat.Port(fmt.Sprintf("%d/%s", p.Container, protocol)), binding, nil
+}
+
+func (v VolumeSpec) build() (mount.Mount, error) {
+	if !path.IsAbs(v.Target) {
+		return mount.Mount{}, apierror.Invalid("volume target %q must be an absolute path", v.Target)
+	}
+
+	m := mount.Mount{Type: mount.TypeVolume, Source: v.Source, Target: path.Clean(v.Target), ReadOnly: v.ReadOnly}
+	switch {
+	case path.IsAbs(v.Source):
+		m.Type, m.Source = mount.TypeBind, path.Clean(v.Source)
+	case !validName.MatchString(v.Source):
+		return mount.Mount{}, apierror.Invalid("invalid volume name %q", v.Source)
+	}
+	return m, nil
+}
+
+// parseRestartPolicy parses no, always, unless-stopped or on-failure[:N]
+func parseRestartPolicy(s string) (container.RestartPolicy, error) {
+	name, count, hasCount := strings.Cut(s, ":")
+	policy := container.RestartPolicy{Name: name}
+	switch name {
+	case "":
+		policy.Name = "no"
+	case "no", "always", "unless-stopped":
+	case "on-failure":
+		if hasCount {
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 0 {
+				return policy, apierror.Invalid("invalid restart count %q", count)
+			}
+			policy.MaximumRetryCount = n
+		}
+		return policy, nil
+	default:
+		return policy, apierror.Invalid("invalid restart policy %q: use no, always, unless-stopped or on-failure[:N]", s)
+	}
+	if hasCount {
+		return policy, apierror.Invalid("restart policy %s takes no count", name)
+	}
+	return policy, nil
+}
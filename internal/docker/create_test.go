@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestContainerSpecBuild(t *testing.T) {
+	spec := ContainerSpec{
+		Name:    "web",
+		Image:   "nginx",
+		Env:     map[string]string{"TZ": "UTC", "MODE": "prod"},
+		Ports:   []PortSpec{{Container: 80, Host: 8080}, {Container: 53, Protocol: "udp", HostIP: "127.0.0.1"}},
+		Volumes: []VolumeSpec{{Source: "webdata", Target: "/data"}, {Source: "/etc/nginx/", Target: "/etc/nginx", ReadOnly: true}},
+		Restart: "on-failure:3",
+		Labels:  map[string]string{"app": "web"},
+	}
+
+	config, hostConfig, err := spec.build()
+	require.NoError(t, err)
+	assert.Equal(t, "nginx:latest", config.Image)
+	assert.Equal(t, []string{"MODE=prod", "TZ=UTC"}, config.Env)
+	assert.Equal(t, nat.PortSet{"80/tcp": {}, "53/udp": {}}, config.ExposedPorts)
+	assert.Equal(t, nat.PortMap{
+		"80/tcp": {{HostPort: "8080"}},
+		"53/udp": {{HostIP: "127.0.0.1"}},
+	}, hostConfig.PortBindings)
+	assert.Equal(t, []mount.Mount{
+		{Type: mount.TypeVolume, Source: "webdata", Target: "/data"},
+		{Type: mount.TypeBind, Source: "/etc/nginx", Target: "/etc/nginx", ReadOnly: true},
+	}, hostConfig.Mounts)
+	assert.Equal(t, container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}, hostConfig.RestartPolicy)
+
+	for _, bad := range []ContainerSpec{
+		{},
+		{Image: "nginx", Name: "/web"},
+		{Image: "nginx", Pull: "sometimes"},
+		{Image: "nginx", Restart: "always:3"},
+		{Image: "nginx", Restart: "forever"},
+		{Image: "nginx", Env: map[string]string{"A=B": "c"}},
+		{Image: "nginx", Ports: []PortSpec{{Container: 0}}},
+		{Image: "nginx", Ports: []PortSpec{{Container: 80, Protocol: "sctp"}}},
+		{Image: "nginx", Volumes: []VolumeSpec{{Source: "data", Target: "data"}}},
+		{Image: "nginx", Volumes: []VolumeSpec{{Source: "../data", Target: "/data"}}},
+	} {
+		assert.ErrorIs(t, bad.Validate(), apierror.ErrInvalid, "%+v", bad)
+	}
+}
+
+func TestCheckHostAccess(t *testing.T) {
+	allowed := func(path string) bool { return path == "/srv/app" || strings.HasPrefix(path, "/srv/app/") }
+
+	ok := ContainerSpec{Image: "nginx", Network: "app", Volumes: []VolumeSpec{{Source: "data", Target: "/data"}, {Source: "/srv/app/html", Target: "/usr/share/nginx/html"}}}
+	assert.NoError(t, ok.CheckHostAccess(allowed))
+
+	for _, bad := range []ContainerSpec{
+		{Image: "nginx", Network: "host"},
+		{Image: "nginx", Network: "container:db"},
+		{Image: "nginx", Volumes: []VolumeSpec{{Source: "/", Target: "/host"}}},
+		{Image: "nginx", Volumes: []VolumeSpec{{Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"}}},
+		{Image: "nginx", Volumes: []VolumeSpec{{Source: "/srv/app/../../etc", Target: "/etc"}}},
+	} {
+		assert.ErrorIs(t, bad.CheckHostAccess(allowed), apierror.ErrNotAllowed, "%+v", bad)
+	}
+}
+
+func TestCreateContainer(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/images/nginx:latest/json":       map[string]interface{}{"Id": "sha256:" + dbID},
+		"/containers/create":              map[string]interface{}{"Id": webID},
+		"/containers/" + webID + "/start": map[string]interface{}{},
+		"/containers/" + webID + "/json":  map[string]interface{}{"Id": webID, "Name": "/quirky_hopper"},
+	})
+
+	created, err := m.CreateContainer(t.Context(), ContainerSpec{Image: "nginx"})
+	require.NoError(t, err)
+	assert.Equal(t, &CreatedContainer{ID: webID[:12], Name: "quirky_hopper", Image: "nginx:latest", Started: true}, created)
+
+	// The daemon has no such image, and it may not be pulled
+	m = fakeAPI(t, map[string]interface{}{})
+	_, err = m.CreateContainer(t.Context(), ContainerSpec{Image: "redis", Pull: "never"})
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+)
+
+// SystemDiskUsage returns how much space images, containers, volumes and
+// the build cache take, and how much removing the unused ones would free.
+// Sizes are worked out the way docker system df does.
+func (m *Manager) SystemDiskUsage(ctx context.Context) (*SystemDiskUsage, error) {
+	usage, err := m.client.DiskUsage(ctx, types.DiskUsageOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+
+	df := &SystemDiskUsage{}
+
+	// Layers shared between images count once, so the image total is the
+	// daemon's layer size, and only the layers unique to images in use
+	// can't be reclaimed
+	df.Images.Size = usage.LayersSize
+	var used int64
+	for _, img := range usage.Images {
+		df.Images.Total++
+		if img.Containers > 0 {
+			df.Images.Active++
+			if img.Size >= 0 && img.SharedSize >= 0 {
+				used += img.Size - img.SharedSize
+			}
+		}
+	}
+	df.Images.Reclaimable = max(df.Images.Size-used, 0)
+
+	for _, ctr := range usage.Containers {
+		df.Containers.Total++
+		df.Containers.Size += ctr.SizeRw
+		switch ctr.State {
+		case "running", "paused", "restarting":
+			df.Containers.Active++
+		default:
+			df.Containers.Reclaimable += ctr.SizeRw
+		}
+	}
+
+	for _, v := range usage.Volumes {
+		df.Volumes.Total++
+		if v.UsageData == nil {
+			continue
+		}
+		if v.UsageData.Size > 0 {
+			df.Volumes.Size += v.UsageData.Size
+		}
+		if v.UsageData.RefCount > 0 {
+			df.Volumes.Active++
+		} else if v.UsageData.Size > 0 {
+			df.Volumes.Reclaimable += v.UsageData.Size
+		}
+	}
+
+	for _, bc := range usage.BuildCache {
+		df.BuildCache.Total++
+		if bc.InUse {
+			df.BuildCache.Active++
+		}
+		if bc.Shared {
+			continue
+		}
+		df.BuildCache.Size += bc.Size
+		if !bc.InUse {
+			df.BuildCache.Reclaimable += bc.Size
+		}
+	}
+
+	for _, c := range []DiskUsageCategory{df.Images, df.Containers, df.Volumes, df.BuildCache} {
+		df.TotalSize += c.Size
+		df.Reclaimable += c.Reclaimable
+	}
+	return df, nil
+}
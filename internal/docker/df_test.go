@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemDiskUsage(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/system/df": map[string]interface{}{
+			"LayersSize": 1000,
+			"Images": []map[string]interface{}{
+				{"Id": "sha256:web", "Size": 600, "SharedSize": 200, "Containers": 1},
+				{"Id": "sha256:old", "Size": 500, "SharedSize": 200, "Containers": 0},
+			},
+			"Containers": []map[string]interface{}{
+				{"Id": webID, "State": "running", "SizeRw": 30},
+				{"Id": dbID, "State": "exited", "SizeRw": 20},
+			},
+			"Volumes": []map[string]interface{}{
+				{"Name": "data", "UsageData": map[string]interface{}{"Size": 300, "RefCount": 1}},
+				{"Name": "orphan", "UsageData": map[string]interface{}{"Size": 100, "RefCount": 0}},
+				{"Name": "remote", "UsageData": map[string]interface{}{"Size": -1, "RefCount": 0}},
+			},
+			"BuildCache": []map[string]interface{}{
+				{"ID": "a", "Size": 50, "InUse": true},
+				{"ID": "b", "Size": 70},
+				{"ID": "c", "Size": 40, "Shared": true},
+			},
+		},
+	})
+
+	df, err := m.SystemDiskUsage(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, DiskUsageCategory{Total: 2, Active: 1, Size: 1000, Reclaimable: 600}, df.Images)
+	assert.Equal(t, DiskUsageCategory{Total: 2, Active: 1, Size: 50, Reclaimable: 20}, df.Containers)
+	assert.Equal(t, DiskUsageCategory{Total: 3, Active: 1, Size: 400, Reclaimable: 100}, df.Volumes)
+	assert.Equal(t, DiskUsageCategory{Total: 3, Active: 1, Size: 120, Reclaimable: 70}, df.BuildCache)
+	assert.Equal(t, int64(1570), df.TotalSize)
+	assert.Equal(t, int64(790), df.Reclaimable)
+}
@@ -11,22 +11,47 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
 )
 
-// Manager handles Docker operations
+// Manager handles Docker operations, against Docker or Podman's
+// Docker-compatible API
 type Manager struct {
 	client *client.Client
+	cpu    cpuSamples // Previous CPU counters for ContainerUsage
+	engine Engine
+	auth   *RegistryAuth // Registry credentials; nil for none
+}
+
+// dockerError wraps a docker API error, marking missing objects as not found
+func dockerError(msg string, err error) error {
+	if client.IsErrNotFound(err) {
+		return apierror.NotFound("%s: %w", msg, err)
+	}
+	if errdefs.IsConflict(err) {
+		return apierror.Conflict("%s: %w", msg, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
 }
 
-// NewManager creates a new Docker manager
-func NewManager() (*Manager, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewManager creates a new Docker manager for the daemon at host, a URL
+// or socket path. Without one it uses DOCKER_HOST, or else the first
+// Docker or Podman socket found. Pulls use auth's credentials.
+func NewManager(host string, auth *RegistryAuth) (*Manager, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host = resolveHost(host, defaultSockets()); host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
 
 	return &Manager{
 		client: cli,
+		auth:   auth,
 	}, nil
 }
 
@@ -104,7 +129,7 @@ func (m *Manager) ListContainers(ctx context.Context, all bool) (*ContainerList,
 func (m *Manager) GetContainer(ctx context.Context, id string) (*ContainerInfo, error) {
 	inspect, err := m.client.ContainerInspect(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to inspect container: %w", err)
+		return nil, dockerError("failed to inspect container", err)
 	}
 
 	info := &ContainerInfo{
@@ -139,6 +164,9 @@ func (m *Manager) GetContainer(ctx context.Context, id string) (*ContainerInfo,
 // StartContainer starts a container
 func (m *Manager) StartContainer(ctx context.Context, id string) (*ContainerAction, error) {
 	if err := m.client.ContainerStart(ctx, id, types.ContainerStartOptions{}); err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, dockerError("failed to start container", err)
+		}
 		return &ContainerAction{
 			ID:      id,
 			Action:  "start",
@@ -159,6 +187,9 @@ func (m *Manager) StartContainer(ctx context.Context, id string) (*ContainerActi
 func (m *Manager) StopContainer(ctx context.Context, id string) (*ContainerAction, error) {
 	timeout := 30
 	if err := m.client.ContainerStop(ctx, id, container.StopOptions{Timeout: &timeout}); err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, dockerError("failed to stop container", err)
+		}
 		return &ContainerAction{
 			ID:      id,
 			Action:  "stop",
@@ -179,6 +210,9 @@ func (m *Manager) StopContainer(ctx context.Context, id string) (*ContainerActio
 func (m *Manager) RestartContainer(ctx context.Context, id string) (*ContainerAction, error) {
 	timeout := 30
 	if err := m.client.ContainerRestart(ctx, id, container.StopOptions{Timeout: &timeout}); err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, dockerError("failed to restart container", err)
+		}
 		return &ContainerAction{
 			ID:      id,
 			Action:  "restart",
@@ -195,7 +229,66 @@ func (m *Manager) RestartContainer(ctx context.Context, id string) (*ContainerAc
 	}, nil
 }
 
-// GetContainerLogs returns container logs
+// PauseContainer freezes a container's processes
+func (m *Manager) PauseContainer(ctx context.Context, id string) (*ContainerAction, error) {
+	return containerResult(id, "pause", "container paused", m.client.ContainerPause(ctx, id))
+}
+
+// UnpauseContainer resumes a paused container
+func (m *Manager) UnpauseContainer(ctx context.Context, id string) (*ContainerAction, error) {
+	return containerResult(id, "unpause", "container unpaused", m.client.ContainerUnpause(ctx, id))
+}
+
+// RenameContainer gives a container a new name
+func (m *Manager) RenameContainer(ctx context.Context, id, name string) (*ContainerAction, error) {
+	name = strings.TrimPrefix(name, "/")
+	if !validName.MatchString(name) {
+		return nil, apierror.Invalid("invalid container name %q", name)
+	}
+	action, err := containerResult(id, "rename", "container renamed to "+name, m.client.ContainerRename(ctx, id, name))
+	if action != nil {
+		action.Name = name
+	}
+	return action, err
+}
+
+// RemoveOptions controls RemoveContainer
+type RemoveOptions struct {
+	Force   bool // Kill a running container first
+	Volumes bool // Also remove its anonymous volumes
+}
+
+// RemoveContainer removes a container. A running container is only
+// removed with Force.
+func (m *Manager) RemoveContainer(ctx context.Context, id string, opts RemoveOptions) (*ContainerAction, error) {
+	err := m.client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: opts.Force, RemoveVolumes: opts.Volumes})
+	return containerResult(id, "remove", "container removed", err)
+}
+
+// containerResult reports the outcome of a container action. A missing
+// container is an error; other failures are an unsuccessful action.
+func containerResult(id, action, done string, err error) (*ContainerAction, error) {
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return nil, dockerError("failed to "+action+" container", err)
+		}
+		return &ContainerAction{
+			ID:      id,
+			Action:  action,
+			Success: false,
+			Message: fmt.Sprintf("failed to %s container: %v", action, err),
+		}, nil
+	}
+
+	return &ContainerAction{
+		ID:      id,
+		Action:  action,
+		Success: true,
+		Message: done,
+	}, nil
+}
+
+// GetContainerLogs returns container logs, stdout and stderr interleaved
 func (m *Manager) GetContainerLogs(ctx context.Context, id string, opts LogOptions) ([]string, error) {
 	options := types.ContainerLogsOptions{
 		ShowStdout: true,
@@ -210,64 +303,87 @@ func (m *Manager) GetContainerLogs(ctx context.Context, id string, opts LogOptio
 		options.Tail = "100"
 	}
 
+	tty, err := m.tty(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	reader, err := m.client.ContainerLogs(ctx, id, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get container logs: %w", err)
+		return nil, dockerError("failed to get container logs", err)
 	}
 	defer reader.Close()
 
-	var logs []string
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Docker logs have an 8-byte header for each line
-		if len(line) > 8 {
-			line = line[8:]
-		}
-		logs = append(logs, line)
+	logs := []string{}
+	err = readLogs(reader, tty, func(line LogLine) bool {
+		logs = append(logs, line.Line)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container logs: %w", err)
 	}
-
 	return logs, nil
 }
 
-// StreamContainerLogs streams container logs in real-time
-func (m *Manager) StreamContainerLogs(ctx context.Context, id string, logChan chan<- string) error {
+// StreamContainerLogs follows a container's logs, starting with the last
+// opts.Tail lines (default 50). Lines are sent to out until ctx is done or
+// the container stops, and then out is closed.
+func (m *Manager) StreamContainerLogs(ctx context.Context, id string, opts LogOptions, out chan<- LogLine) error {
 	options := types.ContainerLogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     true,
-		Tail:       "50",
+		Timestamps: opts.Timestamps,
+		Tail:       opts.Tail,
+		Since:      opts.Since,
+	}
+	if options.Tail == "" {
+		options.Tail = "50"
 	}
 
+	tty, err := m.tty(ctx, id)
+	if err != nil {
+		return err
+	}
 	reader, err := m.client.ContainerLogs(ctx, id, options)
 	if err != nil {
-		return fmt.Errorf("failed to stream container logs: %w", err)
+		return dockerError("failed to stream container logs", err)
 	}
 
 	go func() {
+		defer close(out)
 		defer reader.Close()
-		scanner := bufio.NewScanner(reader)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if len(line) > 8 {
-				line = line[8:]
+		readLogs(reader, tty, func(line LogLine) bool {
+			if opts.Timestamps {
+				line = splitTimestamp(line)
 			}
 			select {
-			case logChan <- line:
+			case out <- line:
+				return true
 			case <-ctx.Done():
-				return
+				return false
 			}
-		}
+		})
 	}()
 
 	return nil
 }
 
-// GetContainerStats returns container resource statistics
+// tty reports whether a container has a TTY, whose logs are not
+// multiplexed
+func (m *Manager) tty(ctx context.Context, id string) (bool, error) {
+	inspect, err := m.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return false, dockerError("failed to inspect container", err)
+	}
+	return inspect.Config != nil && inspect.Config.Tty, nil
+}
+
+// GetContainerStats returns container resource statistics. The daemon
+// waits a second for a second sample to measure CPU use.
 func (m *Manager) GetContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
 	stats, err := m.client.ContainerStats(ctx, id, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get container stats: %w", err)
+		return nil, dockerError("failed to get container stats", err)
 	}
 	defer stats.Body.Close()
 
@@ -276,50 +392,7 @@ func (m *Manager) GetContainerStats(ctx context.Context, id string) (*ContainerS
 		return nil, fmt.Errorf("failed to decode stats: %w", err)
 	}
 
-	// Calculate CPU percentage
-	cpuDelta := float64(v.CPUStats.CPUUsage.TotalUsage - v.PreCPUStats.CPUUsage.TotalUsage)
-	systemDelta := float64(v.CPUStats.SystemUsage - v.PreCPUStats.SystemUsage)
-	cpuPercent := 0.0
-	if systemDelta > 0.0 && cpuDelta > 0.0 {
-		cpuPercent = (cpuDelta / systemDelta) * float64(len(v.CPUStats.CPUUsage.PercpuUsage)) * 100.0
-	}
-
-	// Calculate memory percentage
-	memPercent := 0.0
-	if v.MemoryStats.Limit > 0 {
-		memPercent = float64(v.MemoryStats.Usage) / float64(v.MemoryStats.Limit) * 100.0
-	}
-
-	// Calculate network I/O
-	var netRx, netTx uint64
-	for _, net := range v.Networks {
-		netRx += net.RxBytes
-		netTx += net.TxBytes
-	}
-
-	// Calculate block I/O
-	var blockRead, blockWrite uint64
-	for _, bio := range v.BlkioStats.IoServiceBytesRecursive {
-		switch bio.Op {
-		case "Read":
-			blockRead += bio.Value
-		case "Write":
-			blockWrite += bio.Value
-		}
-	}
-
-	return &ContainerStats{
-		ID:            id,
-		CPUPercent:    cpuPercent,
-		MemoryUsage:   v.MemoryStats.Usage,
-		MemoryLimit:   v.MemoryStats.Limit,
-		MemoryPercent: memPercent,
-		NetworkRx:     netRx,
-		NetworkTx:     netTx,
-		BlockRead:     blockRead,
-		BlockWrite:    blockWrite,
-		PIDs:          v.PidsStats.Current,
-	}, nil
+	return newContainerStats(id, &v), nil
 }
 
 // ListImages returns all images
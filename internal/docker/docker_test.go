@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestContainerActions(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/containers/web/pause":   map[string]interface{}{},
+		"/containers/web/unpause": map[string]interface{}{},
+		"/containers/web/rename":  map[string]interface{}{},
+		"/containers/web":         map[string]interface{}{},
+	})
+
+	action, err := m.PauseContainer(t.Context(), "web")
+	require.NoError(t, err)
+	assert.Equal(t, &ContainerAction{ID: "web", Action: "pause", Success: true, Message: "container paused"}, action)
+
+	action, err = m.UnpauseContainer(t.Context(), "web")
+	require.NoError(t, err)
+	assert.True(t, action.Success)
+
+	action, err = m.RenameContainer(t.Context(), "web", "frontend")
+	require.NoError(t, err)
+	assert.Equal(t, "frontend", action.Name)
+	_, err = m.RenameContainer(t.Context(), "web", "front end")
+	assert.ErrorIs(t, err, apierror.ErrInvalid)
+
+	action, err = m.RemoveContainer(t.Context(), "web", RemoveOptions{Force: true, Volumes: true})
+	require.NoError(t, err)
+	assert.Equal(t, "remove", action.Action)
+
+	_, err = m.PauseContainer(t.Context(), "missing")
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+	_, err = m.RemoveContainer(t.Context(), "missing", RemoveOptions{})
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
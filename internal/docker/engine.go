@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Container engines
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+// errRegistryUnsupported is reported for registry lookups, which Podman's
+// Docker-compatible API does not offer
+var errRegistryUnsupported = errors.New("podman's API cannot look up image digests in a registry")
+
+// Engine describes the daemon the agent talks to
+type Engine struct {
+	Name       string `json:"name"` // docker or podman
+	Version    string `json:"version,omitempty"`
+	APIVersion string `json:"api_version,omitempty"`
+	Host       string `json:"host"`
+	Rootless   bool   `json:"rootless"`
+}
+
+// defaultSockets lists the API sockets tried when no host is configured:
+// Docker's, then rootful and rootless Podman's
+func defaultSockets() []string {
+	sockets := []string{"/var/run/docker.sock", "/run/podman/podman.sock"}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		sockets = append(sockets, filepath.Join(dir, "podman", "podman.sock"))
+	}
+	return append(sockets, fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid()))
+}
+
+// resolveHost returns the daemon address for host, which may be a URL or
+// a socket path. Without one it defers to DOCKER_HOST, then picks the
+// first of sockets that exists. An empty result leaves the client default.
+func resolveHost(host string, sockets []string) string {
+	if host != "" {
+		if strings.HasPrefix(host, "/") {
+			return "unix://" + host
+		}
+		return host
+	}
+	if os.Getenv("DOCKER_HOST") != "" {
+		return ""
+	}
+	for _, s := range sockets {
+		if info, err := os.Stat(s); err == nil && info.Mode().Type() == os.ModeSocket {
+			return "unix://" + s
+		}
+	}
+	return ""
+}
+
+// detectEngine asks the daemon what it is. Podman lists itself among the
+// version's components. When the daemon does not say, it is taken to be
+// Docker.
+func (m *Manager) detectEngine(ctx context.Context) Engine {
+	engine := Engine{Name: EngineDocker, Host: m.client.DaemonHost()}
+
+	version, err := m.client.ServerVersion(ctx)
+	if err != nil {
+		return engine
+	}
+	engine.Version, engine.APIVersion = version.Version, version.APIVersion
+	for _, c := range version.Components {
+		if strings.Contains(strings.ToLower(c.Name), EnginePodman) {
+			engine.Name, engine.Version = EnginePodman, c.Version
+		}
+	}
+
+	if info, err := m.client.Info(ctx); err == nil {
+		for _, opt := range info.SecurityOptions {
+			if opt == "name=rootless" {
+				engine.Rootless = true
+			}
+		}
+	}
+	return engine
+}
+
+// Engine returns the daemon the manager talks to
+func (m *Manager) Engine() Engine {
+	return m.engine
+}
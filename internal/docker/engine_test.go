@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveHost(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "podman.sock")
+	ln, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer ln.Close()
+	missing := filepath.Join(dir, "docker.sock")
+
+	t.Setenv("DOCKER_HOST", "")
+	assert.Equal(t, "unix:///run/podman/podman.sock", resolveHost("/run/podman/podman.sock", nil))
+	assert.Equal(t, "tcp://10.0.0.5:2375", resolveHost("tcp://10.0.0.5:2375", nil))
+	assert.Equal(t, "unix://"+sock, resolveHost("", []string{missing, sock}))
+	assert.Equal(t, "", resolveHost("", []string{missing}))
+
+	// DOCKER_HOST wins over detected sockets
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:2375")
+	assert.Equal(t, "", resolveHost("", []string{sock}))
+}
+
+// fakeEngine answers /version and /info as version and info
+func fakeEngine(t *testing.T, version, info map[string]interface{}) *Manager {
+	return fakeAPI(t, map[string]interface{}{"/version": version, "/info": info})
+}
+
+func TestDetectEngine(t *testing.T) {
+	m := fakeEngine(t,
+		map[string]interface{}{
+			"Version":    "4.9.3",
+			"ApiVersion": "1.41",
+			"Components": []map[string]interface{}{{"Name": "Podman Engine", "Version": "4.9.3"}},
+		},
+		map[string]interface{}{"SecurityOptions": []string{"name=seccomp", "name=rootless"}},
+	)
+	engine := m.detectEngine(context.Background())
+	assert.Equal(t, EnginePodman, engine.Name)
+	assert.Equal(t, "4.9.3", engine.Version)
+	assert.Equal(t, "1.41", engine.APIVersion)
+	assert.True(t, engine.Rootless)
+	assert.True(t, strings.HasPrefix(engine.Host, "tcp://"))
+
+	m = fakeEngine(t,
+		map[string]interface{}{
+			"Version":    "24.0.7",
+			"ApiVersion": "1.43",
+			"Components": []map[string]interface{}{{"Name": "Engine", "Version": "24.0.7"}},
+		},
+		map[string]interface{}{"SecurityOptions": []string{"name=seccomp"}},
+	)
+	engine = m.detectEngine(context.Background())
+	assert.Equal(t, EngineDocker, engine.Name)
+	assert.Equal(t, "24.0.7", engine.Version)
+	assert.False(t, engine.Rootless)
+}
+
+func TestPodmanSkipsRegistry(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/images/nginx:latest/json": map[string]interface{}{
+			"Id":          "sha256:aaa",
+			"RepoDigests": []string{"nginx@sha256:bbb"},
+		},
+	})
+	m.engine = Engine{Name: EnginePodman}
+
+	update := &ImageUpdate{Image: "nginx:latest"}
+	m.checkImage(context.Background(), update, "sha256:aaa", map[string]string{})
+	assert.Equal(t, UpdateError, update.Status)
+	assert.Equal(t, errRegistryUnsupported.Error(), update.Error)
+	assert.Equal(t, "sha256:bbb", update.LocalDigest)
+}
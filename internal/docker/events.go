@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// Events sends daemon events to out until ctx is done or the daemon ends
+// the stream
+func (m *Manager) Events(ctx context.Context, out chan<- Event) error {
+	messages, errs := m.client.Events(ctx, types.EventsOptions{})
+	for {
+		select {
+		case msg := <-messages:
+			event := Event{
+				Type:       string(msg.Type),
+				Action:     msg.Action,
+				ID:         msg.Actor.ID,
+				Name:       msg.Actor.Attributes["name"],
+				Attributes: msg.Actor.Attributes,
+				Time:       time.Unix(0, msg.TimeNano),
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return nil
+			}
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("docker event stream ended: %w", err)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// pullMessage is a message in the daemon's pull progress stream
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+// NormalizeImage validates an image reference and adds the latest tag when
+// it has neither a tag nor a digest
+func NormalizeImage(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", apierror.Invalid("invalid image reference %q: %v", ref, err)
+	}
+	return reference.FamiliarString(reference.TagNameOnly(named)), nil
+}
+
+// PullImage pulls ref, sending the daemon's progress messages to progress
+// when it is not nil. It fails with the first error the daemon reports.
+func (m *Manager) PullImage(ctx context.Context, ref string, progress chan<- PullProgress) (*PullResult, error) {
+	ref, err := NormalizeImage(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := m.auth.encoded(ref)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := m.client.ImagePull(ctx, ref, types.ImagePullOptions{RegistryAuth: auth})
+	if err != nil {
+		return nil, dockerError("failed to pull "+ref, err)
+	}
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to pull %s: %w", ref, err)
+		}
+		if msg.Error != "" {
+			return nil, fmt.Errorf("failed to pull %s: %s", ref, msg.Error)
+		}
+		if progress == nil {
+			continue
+		}
+		select {
+		case progress <- PullProgress{ID: msg.ID, Status: msg.Status, Current: msg.ProgressDetail.Current, Total: msg.ProgressDetail.Total}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	result := &PullResult{Image: ref}
+	if inspect, _, err := m.client.ImageInspectWithRaw(ctx, ref); err == nil {
+		result.ID = inspect.ID
+		if len(inspect.RepoDigests) > 0 {
+			result.Digest = inspect.RepoDigests[0]
+		}
+	}
+	return result, nil
+}
+
+// RemoveImage removes an image by ID or reference. An image that a
+// container uses, or that has several tags when removed by ID, is only
+// removed with force.
+func (m *Manager) RemoveImage(ctx context.Context, id string, force bool) ([]ImageRemoval, error) {
+	items, err := m.client.ImageRemove(ctx, id, types.ImageRemoveOptions{Force: force, PruneChildren: true})
+	if err != nil {
+		return nil, dockerError("failed to remove image "+id, err)
+	}
+
+	removed := make([]ImageRemoval, 0, len(items))
+	for _, item := range items {
+		removed = append(removed, ImageRemoval{Untagged: item.Untagged, Deleted: item.Deleted})
+	}
+	return removed, nil
+}
@@ -0,0 +1,32 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestNormalizeImage(t *testing.T) {
+	for ref, want := range map[string]string{
+		"nginx":                      "nginx:latest",
+		"nginx:1.27":                 "nginx:1.27",
+		"docker.io/library/redis":    "redis:latest",
+		"ghcr.io/org/app:v2":         "ghcr.io/org/app:v2",
+		"registry.lan:5000/tools/ci": "registry.lan:5000/tools/ci:latest",
+		"alpine@sha256:" + sha256Hex: "alpine@sha256:" + sha256Hex,
+	} {
+		got, err := NormalizeImage(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, got, ref)
+	}
+
+	for _, ref := range []string{"", "Nginx", "nginx:", "-rm"} {
+		_, err := NormalizeImage(ref)
+		assert.ErrorIs(t, err, apierror.ErrInvalid, ref)
+	}
+}
+
+const sha256Hex = "4bcff63911fcb4448bd4fdacec207030997caf25e9bea4045fa6c8c44de311d1"
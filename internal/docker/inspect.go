@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ContainerTop lists the processes running in a container. The container
+// must be running.
+func (m *Manager) ContainerTop(ctx context.Context, id string) (*ContainerTop, error) {
+	body, err := m.client.ContainerTop(ctx, id, nil)
+	if err != nil {
+		return nil, dockerError("failed to list container processes", err)
+	}
+
+	top := &ContainerTop{Titles: body.Titles, Processes: make([]map[string]string, 0, len(body.Processes))}
+	for _, row := range body.Processes {
+		process := make(map[string]string, len(row))
+		for i, value := range row {
+			if i < len(body.Titles) {
+				process[body.Titles[i]] = value
+			}
+		}
+		top.Processes = append(top.Processes, process)
+	}
+	return top, nil
+}
+
+// ContainerDiff lists the files added, modified or deleted in a
+// container's filesystem since it was created from its image, by path
+func (m *Manager) ContainerDiff(ctx context.Context, id string) (*ContainerDiff, error) {
+	changes, err := m.client.ContainerDiff(ctx, id)
+	if err != nil {
+		return nil, dockerError("failed to diff container", err)
+	}
+
+	diff := &ContainerDiff{Changes: make([]FileChange, 0, len(changes))}
+	for _, c := range changes {
+		change := FileChange{Path: c.Path}
+		switch c.Kind {
+		case container.ChangeAdd:
+			change.Kind = ChangeAdded
+			diff.Added++
+		case container.ChangeModify:
+			change.Kind = ChangeModified
+			diff.Modified++
+		case container.ChangeDelete:
+			change.Kind = ChangeDeleted
+			diff.Deleted++
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+	sort.Slice(diff.Changes, func(i, j int) bool { return diff.Changes[i].Path < diff.Changes[j].Path })
+	return diff, nil
+}
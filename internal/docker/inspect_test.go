@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestContainerTop(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/containers/web/top": map[string]interface{}{
+			"Titles": []string{"UID", "PID", "CMD"},
+			"Processes": [][]string{
+				{"root", "4120", "nginx: master process nginx -g daemon off;"},
+				{"101", "4171", "nginx: worker process"},
+			},
+		},
+	})
+
+	top, err := m.ContainerTop(context.Background(), "web")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"UID", "PID", "CMD"}, top.Titles)
+	assert.Equal(t, []map[string]string{
+		{"UID": "root", "PID": "4120", "CMD": "nginx: master process nginx -g daemon off;"},
+		{"UID": "101", "PID": "4171", "CMD": "nginx: worker process"},
+	}, top.Processes)
+
+	_, err = m.ContainerTop(context.Background(), "missing")
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
+
+func TestContainerDiff(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/containers/web/changes": []map[string]interface{}{
+			{"Path": "/var/cache/nginx", "Kind": 0},
+			{"Path": "/etc/nginx/conf.d/default.conf", "Kind": 2},
+			{"Path": "/var/cache/nginx/client_temp", "Kind": 1},
+		},
+	})
+
+	diff, err := m.ContainerDiff(context.Background(), "web")
+	require.NoError(t, err)
+	assert.Equal(t, &ContainerDiff{
+		Changes: []FileChange{
+			{Path: "/etc/nginx/conf.d/default.conf", Kind: ChangeDeleted},
+			{Path: "/var/cache/nginx", Kind: ChangeModified},
+			{Path: "/var/cache/nginx/client_temp", Kind: ChangeAdded},
+		},
+		Added:    1,
+		Modified: 1,
+		Deleted:  1,
+	}, diff)
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// maxLogLine bounds a line; longer output is split
+const maxLogLine = 64 << 10
+
+// errLogsStopped ends a copy when the reader no longer wants lines
+var errLogsStopped = errors.New("log reader stopped")
+
+// logWriter splits one stream's output into lines
+type logWriter struct {
+	stream string
+	buf    []byte
+	emit   func(LogLine) bool
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 && len(w.buf) < maxLogLine {
+			return len(p), nil
+		}
+		if i < 0 {
+			i = maxLogLine
+		}
+		line := strings.TrimSuffix(string(w.buf[:i]), "\r")
+		w.buf = w.buf[min(i+1, len(w.buf)):]
+		if !w.emit(LogLine{Stream: w.stream, Line: line}) {
+			return 0, errLogsStopped
+		}
+	}
+}
+
+// flush emits a final line without a newline
+func (w *logWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emit(LogLine{Stream: w.stream, Line: string(w.buf)})
+		w.buf = nil
+	}
+}
+
+// readLogs splits a log stream into lines until it ends or emit returns
+// false. Without a TTY, Docker multiplexes stdout and stderr in frames
+// with an 8-byte header; with one, all output is stdout.
+func readLogs(r io.Reader, tty bool, emit func(LogLine) bool) error {
+	stdout := &logWriter{stream: "stdout", emit: emit}
+	stderr := &logWriter{stream: "stderr", emit: emit}
+
+	var err error
+	if tty {
+		_, err = io.Copy(stdout, r)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, r)
+	}
+	if errors.Is(err, errLogsStopped) {
+		return nil
+	}
+	stdout.flush()
+	stderr.flush()
+	return err
+}
+
+// splitTimestamp separates the timestamp Docker adds to each line when
+// asked for
+func splitTimestamp(line LogLine) LogLine {
+	stamp, rest, ok := strings.Cut(line.Line, " ")
+	if !ok {
+		return line
+	}
+	if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+		line.Time, line.Line = &t, rest
+	}
+	return line
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLogsDemultiplexes(t *testing.T) {
+	var buf bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&buf, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(&buf, stdcopy.Stderr)
+	stdout.Write([]byte("starting\nlisten"))
+	stderr.Write([]byte("warning: no config\n"))
+	stdout.Write([]byte("ing on :80\r\nlast"))
+
+	var lines []LogLine
+	err := readLogs(&buf, false, func(line LogLine) bool {
+		lines = append(lines, line)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []LogLine{
+		{Stream: "stdout", Line: "starting"},
+		{Stream: "stderr", Line: "warning: no config"},
+		{Stream: "stdout", Line: "listening on :80"},
+		{Stream: "stdout", Line: "last"},
+	}, lines)
+}
+
+func TestReadLogsTTY(t *testing.T) {
+	var lines []LogLine
+	err := readLogs(strings.NewReader("one\ntwo\nthree\n"), true, func(line LogLine) bool {
+		lines = append(lines, line)
+		return len(lines) < 2
+	})
+	require.NoError(t, err, "stopping early is not an error")
+	assert.Equal(t, []LogLine{{Stream: "stdout", Line: "one"}, {Stream: "stdout", Line: "two"}}, lines)
+}
+
+func TestSplitTimestamp(t *testing.T) {
+	line := splitTimestamp(LogLine{Stream: "stdout", Line: "2024-05-01T10:00:00.123456789Z GET / 200"})
+	require.NotNil(t, line.Time)
+	assert.Equal(t, 2024, line.Time.Year())
+	assert.Equal(t, "GET / 200", line.Line)
+
+	line = splitTimestamp(LogLine{Line: "no timestamp here"})
+	assert.Nil(t, line.Time)
+	assert.Equal(t, "no timestamp here", line.Line)
+}
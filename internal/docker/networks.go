@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ListNetworks returns every network with the containers attached to it.
+// Stopped containers are included with the networks they will join.
+func (m *Manager) ListNetworks(ctx context.Context) (*NetworkList, error) {
+	networks, err := m.client.NetworkList(ctx, types.NetworkListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	containers, err := m.allContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members := networkMembers(containers)
+
+	list := &NetworkList{Networks: []NetworkInfo{}}
+	for _, n := range networks {
+		list.Networks = append(list.Networks, newNetworkInfo(n, members[n.ID]))
+	}
+	sort.Slice(list.Networks, func(i, j int) bool { return list.Networks[i].Name < list.Networks[j].Name })
+	list.Total = len(list.Networks)
+	return list, nil
+}
+
+// GetNetwork returns one network, by ID or name, with the containers
+// attached to it
+func (m *Manager) GetNetwork(ctx context.Context, id string) (*NetworkInfo, error) {
+	n, err := m.client.NetworkInspect(ctx, id, types.NetworkInspectOptions{})
+	if err != nil {
+		return nil, dockerError("failed to inspect network", err)
+	}
+	containers, err := m.allContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := newNetworkInfo(n, networkMembers(containers)[n.ID])
+	return &info, nil
+}
+
+func newNetworkInfo(n types.NetworkResource, members []AttachedContainer) NetworkInfo {
+	info := NetworkInfo{
+		ID:         n.ID[:12],
+		Name:       n.Name,
+		Driver:     n.Driver,
+		Scope:      n.Scope,
+		Created:    n.Created,
+		Internal:   n.Internal,
+		Attachable: n.Attachable,
+		IPv6:       n.EnableIPv6,
+		Labels:     n.Labels,
+		Containers: members,
+	}
+	for _, pool := range n.IPAM.Config {
+		info.Subnets = append(info.Subnets, NetworkSubnet{Subnet: pool.Subnet, Gateway: pool.Gateway})
+	}
+	if info.Containers == nil {
+		info.Containers = []AttachedContainer{}
+	}
+	return info
+}
+
+// networkMembers maps network IDs to the containers attached to them
+func networkMembers(containers []types.Container) map[string][]AttachedContainer {
+	members := make(map[string][]AttachedContainer)
+	for _, c := range containers {
+		if c.NetworkSettings == nil {
+			continue
+		}
+		for _, endpoint := range c.NetworkSettings.Networks {
+			if endpoint == nil || endpoint.NetworkID == "" {
+				continue
+			}
+			member := AttachedContainer{
+				ID:          c.ID[:12],
+				Name:        containerName(c),
+				State:       c.State,
+				IPv4Address: endpoint.IPAddress,
+				IPv6Address: endpoint.GlobalIPv6Address,
+				MacAddress:  endpoint.MacAddress,
+			}
+			members[endpoint.NetworkID] = append(members[endpoint.NetworkID], member)
+		}
+	}
+	return members
+}
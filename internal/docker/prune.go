@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+// DefaultPruneOptions removes stopped containers, dangling images and
+// unused networks, but never volumes
+var DefaultPruneOptions = PruneOptions{Containers: true, Images: true, Networks: true}
+
+// Prune removes what opts selects
+func (m *Manager) Prune(ctx context.Context, opts PruneOptions) (*PruneReport, error) {
+	report := &PruneReport{}
+
+	if opts.Containers {
+		containers, err := m.client.ContainersPrune(ctx, filters.Args{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune containers: %w", err)
+		}
+		report.ContainersDeleted = len(containers.ContainersDeleted)
+		report.SpaceReclaimed += containers.SpaceReclaimed
+	}
+
+	if opts.Images {
+		dangling := "true"
+		if opts.AllImages {
+			dangling = "false"
+		}
+		images, err := m.client.ImagesPrune(ctx, filters.NewArgs(filters.Arg("dangling", dangling)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune images: %w", err)
+		}
+		report.ImagesDeleted = len(images.ImagesDeleted)
+		report.SpaceReclaimed += images.SpaceReclaimed
+	}
+
+	if opts.Networks {
+		networks, err := m.client.NetworksPrune(ctx, filters.Args{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune networks: %w", err)
+		}
+		report.NetworksDeleted = len(networks.NetworksDeleted)
+	}
+
+	// Since API 1.42 the daemon only prunes anonymous volumes by default,
+	// so named volumes holding data survive
+	if opts.Volumes {
+		volumes, err := m.client.VolumesPrune(ctx, filters.Args{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune volumes: %w", err)
+		}
+		report.VolumesDeleted = len(volumes.VolumesDeleted)
+		report.SpaceReclaimed += volumes.SpaceReclaimed
+	}
+
+	return report, nil
+}
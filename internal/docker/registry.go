@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// dockerHub is the registry that images without one come from
+const dockerHub = "docker.io"
+
+// Credential is a login for a registry
+type Credential struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// RegistryAuth holds the credentials pulls and registry lookups use, by
+// registry host. It is safe for concurrent use.
+type RegistryAuth struct {
+	mu    sync.RWMutex
+	creds map[string]Credential
+}
+
+// NewRegistryAuth parses registry=username:password pairs, as stored in
+// REGISTRY_AUTH
+func NewRegistryAuth(pairs map[string]string) (*RegistryAuth, error) {
+	creds := make(map[string]Credential, len(pairs))
+	for host, login := range pairs {
+		username, password, _ := strings.Cut(login, ":")
+		creds[host] = Credential{Username: username, Password: password}
+	}
+	a := &RegistryAuth{}
+	if err := a.Set(creds); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Set replaces the credentials
+func (a *RegistryAuth) Set(creds map[string]Credential) error {
+	normalized := make(map[string]Credential, len(creds))
+	for host, cred := range creds {
+		if err := cred.validate(host); err != nil {
+			return err
+		}
+		normalized[RegistryHost(host)] = cred
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.creds = normalized
+	return nil
+}
+
+// Credentials returns a copy of the credentials by registry
+func (a *RegistryAuth) Credentials() map[string]Credential {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	creds := make(map[string]Credential, len(a.creds))
+	for host, cred := range a.creds {
+		creds[host] = cred
+	}
+	return creds
+}
+
+// Logins returns the registries with credentials and their usernames,
+// without passwords
+func (a *RegistryAuth) Logins() []RegistryLogin {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	logins := make([]RegistryLogin, 0, len(a.creds))
+	for host, cred := range a.creds {
+		logins = append(logins, RegistryLogin{Registry: host, Username: cred.Username})
+	}
+	sort.Slice(logins, func(i, j int) bool { return logins[i].Registry < logins[j].Registry })
+	return logins
+}
+
+// Pairs formats the credentials as registry=username:password pairs
+func (a *RegistryAuth) Pairs() map[string]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	pairs := make(map[string]string, len(a.creds))
+	for host, cred := range a.creds {
+		pairs[host] = cred.Username + ":" + cred.Password
+	}
+	return pairs
+}
+
+// encoded returns the X-Registry-Auth header for pulling image, or "" when
+// there are no credentials for its registry. A nil RegistryAuth has none.
+func (a *RegistryAuth) encoded(image string) (string, error) {
+	if a == nil {
+		return "", nil
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", nil // The daemon reports bad references
+	}
+	host := reference.Domain(named)
+
+	a.mu.RLock()
+	cred, ok := a.creds[host]
+	a.mu.RUnlock()
+	if !ok {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      cred.Username,
+		Password:      cred.Password,
+		ServerAddress: host,
+	})
+}
+
+// validate checks a credential can be stored in REGISTRY_AUTH, which is a
+// comma-separated list of registry=username:password pairs
+func (c Credential) validate(host string) error {
+	if !validRegistry(host) {
+		return apierror.Invalid("invalid registry %q: use a host such as ghcr.io or registry.lan:5000", host)
+	}
+	if c.Username == "" || strings.ContainsAny(c.Username, ",:\n") {
+		return apierror.Invalid("registry %s needs a username without commas or colons", host)
+	}
+	if c.Password == "" || strings.ContainsAny(c.Password, ",\n") {
+		return apierror.Invalid("registry %s needs a password without commas", host)
+	}
+	return nil
+}
+
+// validRegistry accepts a host with an optional port
+func validRegistry(host string) bool {
+	name, port, hasPort := strings.Cut(host, ":")
+	if name == "" || strings.ContainsAny(name, "=,/@ \n") {
+		return false
+	}
+	if !hasPort {
+		return true
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// RegistryHost normalizes Docker Hub's aliases to docker.io, as image
+// references name it
+func RegistryHost(host string) string {
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHub
+	}
+	return host
+}
+
+// String hides the password
+func (c Credential) String() string {
+	return fmt.Sprintf("%s:***", c.Username)
+}
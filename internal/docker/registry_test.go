@@ -0,0 +1,65 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestRegistryAuth(t *testing.T) {
+	auth, err := NewRegistryAuth(map[string]string{
+		"ghcr.io":           "octo:ghp_token",
+		"registry.lan:5000": "bob:p@ss:word",
+		"index.docker.io":   "hubuser:hubpass",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []RegistryLogin{
+		{Registry: "docker.io", Username: "hubuser"},
+		{Registry: "ghcr.io", Username: "octo"},
+		{Registry: "registry.lan:5000", Username: "bob"},
+	}, auth.Logins())
+	assert.Equal(t, "bob:p@ss:word", auth.Pairs()["registry.lan:5000"])
+
+	decode := func(image string) registry.AuthConfig {
+		encoded, err := auth.encoded(image)
+		require.NoError(t, err)
+		var config registry.AuthConfig
+		if encoded != "" {
+			data, err := base64.URLEncoding.DecodeString(encoded)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(data, &config))
+		}
+		return config
+	}
+	assert.Equal(t, registry.AuthConfig{Username: "octo", Password: "ghp_token", ServerAddress: "ghcr.io"}, decode("ghcr.io/octo/app:1.2"))
+	assert.Equal(t, "bob", decode("registry.lan:5000/team/api").Username)
+	assert.Equal(t, "hubuser", decode("nginx").Username)
+	assert.Equal(t, registry.AuthConfig{}, decode("quay.io/coreos/etcd"))
+
+	var none *RegistryAuth
+	encoded, err := none.encoded("ghcr.io/octo/app")
+	require.NoError(t, err)
+	assert.Empty(t, encoded)
+}
+
+func TestRegistryAuthValidation(t *testing.T) {
+	for host, cred := range map[string]Credential{
+		"":                   {Username: "u", Password: "p"},
+		"ghcr.io/octo":       {Username: "u", Password: "p"},
+		"registry.lan:99999": {Username: "u", Password: "p"},
+		"ghcr.io":            {Username: "", Password: "p"},
+		"quay.io":            {Username: "a:b", Password: "p"},
+		"gcr.io":             {Username: "u", Password: ""},
+		"ecr.aws":            {Username: "u", Password: "a,b"},
+	} {
+		err := (&RegistryAuth{}).Set(map[string]Credential{host: cred})
+		assert.ErrorIs(t, err, apierror.ErrInvalid, host)
+	}
+}
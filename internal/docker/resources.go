@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// build returns the daemon update for u, given the container's current
+// host config. A container with a swap limit keeps the same swap on top of
+// its new memory limit, as the daemon refuses a memory limit above the
+// old memory+swap limit.
+func (u ResourceUpdate) build(current *container.HostConfig) (container.UpdateConfig, []string, error) {
+	var update container.UpdateConfig
+	var changes []string
+
+	if u.CPUShares < 0 || u.CPUs < 0 {
+		return update, nil, apierror.Invalid("cpu_shares and cpus must not be negative")
+	}
+	if u.CPUShares > 0 {
+		update.CPUShares = u.CPUShares
+		changes = append(changes, fmt.Sprintf("cpu shares %d", u.CPUShares))
+	}
+	if u.CPUs > 0 {
+		update.NanoCPUs = int64(u.CPUs * 1e9)
+		changes = append(changes, fmt.Sprintf("cpus %g", u.CPUs))
+	}
+
+	if u.Memory != "" {
+		memory, err := units.RAMInBytes(u.Memory)
+		if err != nil || memory <= 0 {
+			return update, nil, apierror.Invalid("invalid memory %q: use bytes or a size such as 512m", u.Memory)
+		}
+		update.Memory = memory
+		if current != nil && current.Memory > 0 && current.MemorySwap > current.Memory {
+			update.MemorySwap = memory + current.MemorySwap - current.Memory
+		}
+		changes = append(changes, "memory "+units.BytesSize(float64(memory)))
+	}
+
+	if u.Restart != "" {
+		policy, err := parseRestartPolicy(u.Restart)
+		if err != nil {
+			return update, nil, err
+		}
+		update.RestartPolicy = policy
+		changes = append(changes, "restart "+u.Restart)
+	}
+
+	if len(changes) == 0 {
+		return update, nil, apierror.Invalid("set cpu_shares, cpus, memory or restart")
+	}
+	return update, changes, nil
+}
+
+// UpdateResources changes a container's CPU and memory limits and restart
+// policy without recreating it
+func (m *Manager) UpdateResources(ctx context.Context, id string, u ResourceUpdate) (*ContainerAction, error) {
+	inspect, err := m.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, dockerError("failed to inspect container", err)
+	}
+	update, changes, err := u.build(inspect.HostConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := m.client.ContainerUpdate(ctx, id, update)
+	action, err := containerResult(id, "update", "updated "+strings.Join(changes, ", "), err)
+	if action != nil {
+		action.Name = strings.TrimPrefix(inspect.Name, "/")
+		if len(result.Warnings) > 0 {
+			action.Message += " (" + strings.Join(result.Warnings, "; ") + ")"
+		}
+	}
+	return action, err
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestResourceUpdateBuild(t *testing.T) {
+	update, changes, err := ResourceUpdate{CPUShares: 512, CPUs: 1.5, Memory: "512m", Restart: "on-failure:3"}.build(&container.HostConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(512), update.CPUShares)
+	assert.Equal(t, int64(1500000000), update.NanoCPUs)
+	assert.Equal(t, int64(512<<20), update.Memory)
+	assert.Zero(t, update.MemorySwap)
+	assert.Equal(t, container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}, update.RestartPolicy)
+	assert.Equal(t, []string{"cpu shares 512", "cpus 1.5", "memory 512MiB", "restart on-failure:3"}, changes)
+
+	// The swap allowance on top of memory is kept
+	update, _, err = ResourceUpdate{Memory: "2g"}.build(&container.HostConfig{Resources: container.Resources{Memory: 1 << 30, MemorySwap: 2 << 30}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3<<30), update.MemorySwap)
+
+	// Restart alone leaves the limits alone
+	update, _, err = ResourceUpdate{Restart: "unless-stopped"}.build(&container.HostConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, container.UpdateConfig{RestartPolicy: container.RestartPolicy{Name: "unless-stopped"}}, update)
+
+	for _, bad := range []ResourceUpdate{
+		{},
+		{CPUs: -1},
+		{CPUShares: -2},
+		{Memory: "lots"},
+		{Memory: "0"},
+		{Restart: "forever"},
+	} {
+		_, _, err := bad.build(&container.HostConfig{})
+		assert.ErrorIs(t, err, apierror.ErrInvalid, "%+v", bad)
+	}
+}
+
+func TestUpdateResources(t *testing.T) {
+	m := fakeAPI(t, map[string]interface{}{
+		"/containers/web/json":   map[string]interface{}{"Id": webID, "Name": "/web", "HostConfig": map[string]interface{}{}},
+		"/containers/web/update": map[string]interface{}{"Warnings": []string{"swap limit not supported"}},
+	})
+
+	action, err := m.UpdateResources(context.Background(), "web", ResourceUpdate{Memory: "256m"})
+	require.NoError(t, err)
+	assert.True(t, action.Success)
+	assert.Equal(t, "web", action.Name)
+	assert.Equal(t, "updated memory 256MiB (swap limit not supported)", action.Message)
+
+	_, err = m.UpdateResources(context.Background(), "gone", ResourceUpdate{Memory: "256m"})
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// StreamContainerStats sends a container's stats to out about once a
+// second, until ctx is done or the container stops, and then closes out
+func (m *Manager) StreamContainerStats(ctx context.Context, id string, out chan<- ContainerStats) error {
+	stats, err := m.client.ContainerStats(ctx, id, true)
+	if err != nil {
+		return dockerError("failed to stream container stats", err)
+	}
+
+	go func() {
+		defer close(out)
+		defer stats.Body.Close()
+
+		dec := json.NewDecoder(stats.Body)
+		for {
+			var v types.StatsJSON
+			if err := dec.Decode(&v); err != nil {
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					log.Printf("Stats stream for container %s ended: %v", id, err)
+				}
+				return
+			}
+			select {
+			case out <- *newContainerStats(id, &v):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// AllContainerStats returns stats for every running container, sorted by
+// name. The daemon takes a second per container to measure CPU use, so
+// they are sampled in parallel. Containers that stop meanwhile are left out.
+func (m *Manager) AllContainerStats(ctx context.Context) ([]ContainerStats, error) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	all := make([]*ContainerStats, len(containers))
+	var g errgroup.Group
+	g.SetLimit(usageConcurrency)
+	for i, ctr := range containers {
+		g.Go(func() error {
+			stats, err := m.GetContainerStats(ctx, ctr.ID)
+			if err != nil {
+				return nil
+			}
+			if stats.Name == "" && len(ctr.Names) > 0 {
+				stats.Name = strings.TrimPrefix(ctr.Names[0], "/")
+			}
+			all[i] = stats
+			return nil
+		})
+	}
+	g.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]ContainerStats, 0, len(all))
+	for _, stats := range all {
+		if stats != nil {
+			result = append(result, *stats)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// newContainerStats derives usage from a stats frame the way `docker
+// stats` does. CPU use is measured against the frame's previous sample.
+func newContainerStats(id string, v *types.StatsJSON) *ContainerStats {
+	cpuPercent := 0.0
+	cpuDelta := float64(v.CPUStats.CPUUsage.TotalUsage) - float64(v.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(v.CPUStats.SystemUsage) - float64(v.PreCPUStats.SystemUsage)
+	if v.PreCPUStats.SystemUsage > 0 && systemDelta > 0 && cpuDelta > 0 {
+		// cgroup v2 has no per-CPU usage, only the number of CPUs online
+		cpus := float64(v.CPUStats.OnlineCPUs)
+		if cpus == 0 {
+			cpus = float64(len(v.CPUStats.CPUUsage.PercpuUsage))
+		}
+		cpuPercent = cpuDelta / systemDelta * cpus * 100.0
+	}
+
+	memUsage := memoryUsage(v)
+	memPercent := 0.0
+	if v.MemoryStats.Limit > 0 {
+		memPercent = float64(memUsage) / float64(v.MemoryStats.Limit) * 100.0
+	}
+
+	var netRx, netTx uint64
+	for _, net := range v.Networks {
+		netRx += net.RxBytes
+		netTx += net.TxBytes
+	}
+
+	// cgroup v1 reports "Read"; cgroup v2 reports "read"
+	var blockRead, blockWrite uint64
+	for _, bio := range v.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(bio.Op) {
+		case "read":
+			blockRead += bio.Value
+		case "write":
+			blockWrite += bio.Value
+		}
+	}
+
+	return &ContainerStats{
+		ID:            id,
+		Name:          strings.TrimPrefix(v.Name, "/"),
+		CPUPercent:    cpuPercent,
+		MemoryUsage:   memUsage,
+		MemoryLimit:   v.MemoryStats.Limit,
+		MemoryPercent: memPercent,
+		NetworkRx:     netRx,
+		NetworkTx:     netTx,
+		BlockRead:     blockRead,
+		BlockWrite:    blockWrite,
+		PIDs:          v.PidsStats.Current,
+	}
+}
+
+// memoryUsage leaves out the page cache the kernel can reclaim, as
+// `docker stats` does
+func memoryUsage(v *types.StatsJSON) uint64 {
+	usage := v.MemoryStats.Usage
+	for _, key := range []string{"total_inactive_file", "inactive_file"} { // cgroup v1, v2
+		if cache, ok := v.MemoryStats.Stats[key]; ok && cache < usage {
+			return usage - cache
+		}
+	}
+	return usage
+}
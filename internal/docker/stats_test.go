@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContainerStats(t *testing.T) {
+	// A cgroup v2 host: no per-CPU usage and lower-case block I/O ops
+	var v types.StatsJSON
+	v.Name = "/web"
+	v.CPUStats.CPUUsage.TotalUsage = 300
+	v.CPUStats.SystemUsage = 2000
+	v.CPUStats.OnlineCPUs = 4
+	v.PreCPUStats.CPUUsage.TotalUsage = 200
+	v.PreCPUStats.SystemUsage = 1000
+	v.MemoryStats.Usage = 300
+	v.MemoryStats.Limit = 1000
+	v.MemoryStats.Stats = map[string]uint64{"inactive_file": 100}
+	v.Networks = map[string]types.NetworkStats{"eth0": {RxBytes: 10, TxBytes: 20}, "eth1": {RxBytes: 1, TxBytes: 2}}
+	v.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{{Op: "read", Value: 5}, {Op: "write", Value: 7}, {Op: "Read", Value: 1}}
+	v.PidsStats.Current = 3
+
+	stats := newContainerStats("abc", &v)
+	assert.Equal(t, "web", stats.Name)
+	assert.InDelta(t, 40.0, stats.CPUPercent, 0.001, "100 of 1000 ticks across 4 CPUs")
+	assert.Equal(t, uint64(200), stats.MemoryUsage, "page cache is left out")
+	assert.InDelta(t, 20.0, stats.MemoryPercent, 0.001)
+	assert.Equal(t, uint64(11), stats.NetworkRx)
+	assert.Equal(t, uint64(22), stats.NetworkTx)
+	assert.Equal(t, uint64(6), stats.BlockRead)
+	assert.Equal(t, uint64(7), stats.BlockWrite)
+	assert.Equal(t, uint64(3), stats.PIDs)
+
+	// The first frame of a stream has no previous sample
+	v.PreCPUStats = types.CPUStats{}
+	assert.Zero(t, newContainerStats("abc", &v).CPUPercent)
+}
@@ -46,7 +46,7 @@ type ContainerList struct {
 type ContainerAction struct {
 	ID      string `json:"id"`
 	Name    string `json:"name"`
-	Action  string `json:"action"` // start, stop, restart, remove
+	Action  string `json:"action"` // start, stop, restart, pause, unpause, rename, update, remove
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 }
@@ -83,3 +83,275 @@ type ImageInfo struct {
 	Size        int64    `json:"size"`
 	Created     int64    `json:"created"`
 }
+
+// PruneReport summarizes what Prune removed
+type PruneReport struct {
+	ContainersDeleted int    `json:"containers_deleted"`
+	ImagesDeleted     int    `json:"images_deleted"`
+	NetworksDeleted   int    `json:"networks_deleted"`
+	VolumesDeleted    int    `json:"volumes_deleted"`
+	SpaceReclaimed    uint64 `json:"space_reclaimed"`
+}
+
+// Image update statuses
+const (
+	UpdateCurrent   = "up_to_date" // The tag still points at the running image
+	UpdateAvailable = "available"  // The registry has a newer image for the tag
+	UpdatePulled    = "pulled"     // A newer image is pulled; the container needs recreating
+	UpdateLocal     = "local"      // Built locally, with no registry digest to compare
+	UpdatePinned    = "pinned"     // Run from a digest, which never changes
+	UpdateError     = "error"
+)
+
+// ImageUpdate is whether a running container's image tag has moved on
+type ImageUpdate struct {
+	Container    string `json:"container"`
+	ContainerID  string `json:"container_id"`
+	Image        string `json:"image"`
+	Status       string `json:"status"`
+	LocalDigest  string `json:"local_digest,omitempty"`
+	RemoteDigest string `json:"remote_digest,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// UpdateReport lists the running containers and their image updates
+type UpdateReport struct {
+	Containers []ImageUpdate `json:"containers"`
+	Available  int           `json:"available"` // Containers with an update available or pulled
+	CheckedAt  time.Time     `json:"checked_at"`
+}
+
+// Automatic update outcomes
+const (
+	AutoUpdated    = "updated"
+	AutoRolledBack = "rolled_back" // The new container failed its healthcheck; the old one runs again
+	AutoFailed     = "failed"
+	AutoCurrent    = "up_to_date" // The pulled image is the one already running
+)
+
+// AutoUpdateResult is what happened to one container during an automatic update
+type AutoUpdateResult struct {
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	OldImage  string `json:"old_image_id"`
+	NewImage  string `json:"new_image_id,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AutoUpdateReport lists the containers an automatic update touched
+type AutoUpdateReport struct {
+	Results    []AutoUpdateResult `json:"results"`
+	Updated    int                `json:"updated"`
+	RolledBack int                `json:"rolled_back"`
+	Failed     int                `json:"failed"`
+}
+
+// Event is a Docker daemon event, such as a container starting or dying
+type Event struct {
+	Type       string            `json:"type"`   // container, image, network, volume, ...
+	Action     string            `json:"action"` // start, die, pull, ...
+	ID         string            `json:"id"`
+	Name       string            `json:"name,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Time       time.Time         `json:"time"`
+}
+
+// LogLine is one line of container output
+type LogLine struct {
+	Stream string     `json:"stream"` // stdout or stderr
+	Time   *time.Time `json:"time,omitempty"` // When timestamps were requested
+	Line   string     `json:"line"`
+}
+
+// PullProgress is one progress message from an image pull
+type PullProgress struct {
+	ID      string `json:"id,omitempty"` // The layer, when the message is about one
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// PullResult is an image that was pulled
+type PullResult struct {
+	Image  string `json:"image"`
+	ID     string `json:"id,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// ImageRemoval is a tag removed or an image deleted by RemoveImage
+type ImageRemoval struct {
+	Untagged string `json:"untagged,omitempty"`
+	Deleted  string `json:"deleted,omitempty"`
+}
+
+// PruneOptions selects what Prune removes
+type PruneOptions struct {
+	Containers bool `json:"containers"` // Stopped containers
+	Images     bool `json:"images"`     // Dangling images
+	AllImages  bool `json:"all_images"` // With Images, every image no container uses
+	Networks   bool `json:"networks"`   // Networks no container uses
+	Volumes    bool `json:"volumes"`    // Anonymous volumes no container uses
+}
+
+// VolumeInfo is a volume and the containers that mount it
+type VolumeInfo struct {
+	Name       string              `json:"name"`
+	Driver     string              `json:"driver"`
+	Mountpoint string              `json:"mountpoint"`
+	Scope      string              `json:"scope"`
+	CreatedAt  string              `json:"created_at,omitempty"`
+	Labels     map[string]string   `json:"labels,omitempty"`
+	Options    map[string]string   `json:"options,omitempty"`
+	Size       int64               `json:"size"` // -1 when the driver cannot report it
+	Containers []AttachedContainer `json:"containers"`
+}
+
+// VolumeList is every volume and their combined size
+type VolumeList struct {
+	Volumes   []VolumeInfo `json:"volumes"`
+	Total     int          `json:"total"`
+	TotalSize int64        `json:"total_size"`
+}
+
+// NetworkInfo is a network and the containers attached to it
+type NetworkInfo struct {
+	ID         string              `json:"id"`
+	Name       string              `json:"name"`
+	Driver     string              `json:"driver"`
+	Scope      string              `json:"scope"`
+	Created    time.Time           `json:"created"`
+	Internal   bool                `json:"internal"`
+	Attachable bool                `json:"attachable"`
+	IPv6       bool                `json:"ipv6"`
+	Subnets    []NetworkSubnet     `json:"subnets,omitempty"`
+	Labels     map[string]string   `json:"labels,omitempty"`
+	Containers []AttachedContainer `json:"containers"`
+}
+
+// NetworkSubnet is an IPAM pool of a network
+type NetworkSubnet struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// NetworkList is every network
+type NetworkList struct {
+	Networks []NetworkInfo `json:"networks"`
+	Total    int           `json:"total"`
+}
+
+// AttachedContainer is a container using a volume or network. Destination
+// and RW describe a volume mount; the addresses, a network endpoint.
+type AttachedContainer struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	Destination string `json:"destination,omitempty"`
+	RW          bool   `json:"rw,omitempty"`
+	IPv4Address string `json:"ipv4_address,omitempty"`
+	IPv6Address string `json:"ipv6_address,omitempty"`
+	MacAddress  string `json:"mac_address,omitempty"`
+}
+
+// ContainerSpec describes a container for CreateContainer. A volume
+// source that is an absolute path is a bind mount; anything else names a
+// volume, which is created if missing.
+type ContainerSpec struct {
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`
+	Command []string          `json:"command,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Ports   []PortSpec        `json:"ports,omitempty"`
+	Volumes []VolumeSpec      `json:"volumes,omitempty"`
+	Restart string            `json:"restart,omitempty"` // no, always, unless-stopped or on-failure[:N]
+	Labels  map[string]string `json:"labels,omitempty"`
+	Network string            `json:"network,omitempty"`
+	Pull    string            `json:"pull,omitempty"`  // missing (default), always or never
+	Start   *bool             `json:"start,omitempty"` // Default true
+}
+
+// PortSpec publishes a container port. Host 0 picks a free port.
+type PortSpec struct {
+	Container int    `json:"container"`
+	Host      int    `json:"host,omitempty"`
+	HostIP    string `json:"host_ip,omitempty"`
+	Protocol  string `json:"protocol,omitempty"` // tcp (default) or udp
+}
+
+// VolumeSpec mounts a volume or host path into a container
+type VolumeSpec struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+}
+
+// CreatedContainer is the result of CreateContainer
+type CreatedContainer struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Image    string   `json:"image"`
+	Pulled   bool     `json:"pulled"`
+	Started  bool     `json:"started"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ContainerTop lists the processes running in a container, as docker top
+type ContainerTop struct {
+	Titles    []string            `json:"titles"`    // ps columns, e.g. UID, PID, CMD
+	Processes []map[string]string `json:"processes"` // Each process by column title
+}
+
+// File change kinds
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+)
+
+// FileChange is a path that differs from the container's image
+type FileChange struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"` // added, modified or deleted
+}
+
+// ContainerDiff lists the files a container changed, as docker diff
+type ContainerDiff struct {
+	Changes  []FileChange `json:"changes"`
+	Added    int          `json:"added"`
+	Modified int          `json:"modified"`
+	Deleted  int          `json:"deleted"`
+}
+
+// RegistryLogin is a registry with credentials, without the password
+type RegistryLogin struct {
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+}
+
+// DiskUsageCategory is one kind of object in the disk usage report
+type DiskUsageCategory struct {
+	Total       int   `json:"total"`
+	Active      int   `json:"active"`      // In use by a container or build
+	Size        int64 `json:"size"`        // Bytes on disk
+	Reclaimable int64 `json:"reclaimable"` // Bytes a prune of unused objects would free
+}
+
+// SystemDiskUsage is the daemon's disk use, as docker system df
+type SystemDiskUsage struct {
+	Images      DiskUsageCategory `json:"images"`
+	Containers  DiskUsageCategory `json:"containers"`
+	Volumes     DiskUsageCategory `json:"volumes"`
+	BuildCache  DiskUsageCategory `json:"build_cache"`
+	TotalSize   int64             `json:"total_size"`
+	Reclaimable int64             `json:"reclaimable"`
+}
+
+// ResourceUpdate changes a container's limits while it runs, as docker
+// update does. Zero or empty fields keep their current value.
+type ResourceUpdate struct {
+	CPUShares int64   `json:"cpu_shares,omitempty"` // Relative weight under contention; the default is 1024
+	CPUs      float64 `json:"cpus,omitempty"`       // CPUs the container may use, e.g. 0.5
+	Memory    string  `json:"memory,omitempty"`     // Bytes, or e.g. "512m"
+	Restart   string  `json:"restart,omitempty"`    // no, always, unless-stopped or on-failure[:N]
+}
@@ -0,0 +1,232 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+var errUnavailable = apierror.Unavailable("docker not available")
+
+// CheckImageUpdates compares each running container's image with what its
+// tag points at, locally and in the registry. Registry lookups use no
+// credentials, so private images report an error.
+func (m *Manager) CheckImageUpdates(ctx context.Context) (*UpdateReport, error) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	report := &UpdateReport{Containers: []ImageUpdate{}, CheckedAt: time.Now()}
+	remote := map[string]string{} // Registry digest by reference, looked up once per check
+	for _, c := range containers {
+		update := ImageUpdate{Container: strings.TrimPrefix(c.Names[0], "/"), ContainerID: c.ID[:12]}
+
+		inspect, err := m.client.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			update.Status, update.Error = UpdateError, err.Error()
+			report.Containers = append(report.Containers, update)
+			continue
+		}
+		update.Image = inspect.Config.Image
+		m.checkImage(ctx, &update, inspect.Image, remote)
+
+		if update.Status == UpdateAvailable || update.Status == UpdatePulled {
+			report.Available++
+		}
+		report.Containers = append(report.Containers, update)
+	}
+
+	sort.Slice(report.Containers, func(i, j int) bool {
+		return report.Containers[i].Container < report.Containers[j].Container
+	})
+	return report, nil
+}
+
+// checkImage sets the status of a container running imageID
+func (m *Manager) checkImage(ctx context.Context, update *ImageUpdate, imageID string, remote map[string]string) {
+	if strings.Contains(update.Image, "@") {
+		update.Status = UpdatePinned
+		return
+	}
+
+	local, _, err := m.client.ImageInspectWithRaw(ctx, update.Image)
+	if err != nil {
+		update.Status, update.Error = UpdateError, fmt.Sprintf("failed to inspect image: %v", err)
+		return
+	}
+	if local.ID != imageID {
+		update.Status = UpdatePulled
+		return
+	}
+	if len(local.RepoDigests) == 0 {
+		update.Status = UpdateLocal
+		return
+	}
+	update.LocalDigest = digestOf(local.RepoDigests[0])
+
+	digest, ok := remote[update.Image]
+	if !ok && m.engine.Name == EnginePodman {
+		update.Status, update.Error = UpdateError, errRegistryUnsupported.Error()
+		return
+	}
+	if !ok {
+		auth, err := m.auth.encoded(update.Image)
+		if err != nil {
+			update.Status, update.Error = UpdateError, err.Error()
+			return
+		}
+		dist, err := m.client.DistributionInspect(ctx, update.Image, auth)
+		if err != nil {
+			update.Status, update.Error = UpdateError, fmt.Sprintf("failed to query the registry: %v", err)
+			return
+		}
+		digest = dist.Descriptor.Digest.String()
+		remote[update.Image] = digest
+	}
+	update.RemoteDigest = digest
+	update.Status = updateStatus(local.RepoDigests, digest)
+}
+
+// updateStatus compares the registry digest of a tag with the digests the
+// local image was pulled as
+func updateStatus(repoDigests []string, remote string) string {
+	for _, d := range repoDigests {
+		if digestOf(d) == remote {
+			return UpdateCurrent
+		}
+	}
+	return UpdateAvailable
+}
+
+// digestOf returns the digest of "repo@sha256:..."
+func digestOf(repoDigest string) string {
+	if i := strings.LastIndexByte(repoDigest, '@'); i >= 0 {
+		return repoDigest[i+1:]
+	}
+	return repoDigest
+}
+
+// UpdateChecker checks for image updates every interval and raises a
+// docker.image_update event when a container's image first has one
+type UpdateChecker struct {
+	interval time.Duration
+	bus      *events.Bus
+	check    func(ctx context.Context) (*UpdateReport, error)
+
+	mu       sync.Mutex
+	report   *UpdateReport
+	notified map[string]string // Container to the digest an event was raised for
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewUpdateChecker creates a checker for the daemon behind connector
+func NewUpdateChecker(connector *Connector, interval time.Duration, bus *events.Bus) *UpdateChecker {
+	u := &UpdateChecker{interval: interval, bus: bus, notified: map[string]string{}, stop: make(chan struct{})}
+	u.check = func(ctx context.Context) (*UpdateReport, error) {
+		manager := connector.Manager()
+		if manager == nil {
+			return nil, errUnavailable
+		}
+		return manager.CheckImageUpdates(ctx)
+	}
+	return u
+}
+
+// Start checks immediately and then every interval until Stop is called
+func (u *UpdateChecker) Start() {
+	if u.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(u.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := u.Check(context.Background()); err != nil {
+				log.Printf("Image update check failed: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-u.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the checks
+func (u *UpdateChecker) Stop() {
+	u.once.Do(func() { close(u.stop) })
+}
+
+// Report returns the latest report, checking now if there is none
+func (u *UpdateChecker) Report(ctx context.Context) (*UpdateReport, error) {
+	u.mu.Lock()
+	report := u.report
+	u.mu.Unlock()
+	if report != nil {
+		return report, nil
+	}
+	return u.Check(ctx)
+}
+
+// Check looks for updates now
+func (u *UpdateChecker) Check(ctx context.Context) (*UpdateReport, error) {
+	report, err := u.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	u.report = report
+	var found []ImageUpdate
+	running := map[string]bool{}
+	for _, c := range report.Containers {
+		running[c.Container] = true
+		if c.Status != UpdateAvailable && c.Status != UpdatePulled {
+			delete(u.notified, c.Container)
+			continue
+		}
+		if digest, ok := u.notified[c.Container]; ok && digest == c.RemoteDigest {
+			continue
+		}
+		u.notified[c.Container] = c.RemoteDigest
+		found = append(found, c)
+	}
+	for name := range u.notified {
+		if !running[name] {
+			delete(u.notified, name)
+		}
+	}
+	u.mu.Unlock()
+
+	if u.bus != nil {
+		for _, c := range found {
+			message := fmt.Sprintf("A newer %s image is available for container %s", c.Image, c.Container)
+			if c.Status == UpdatePulled {
+				message = fmt.Sprintf("Container %s runs an older %s image than the one pulled; recreate it to update", c.Container, c.Image)
+			}
+			u.bus.Publish(events.Event{
+				Type:     "docker.image_update",
+				Severity: events.SeverityInfo,
+				Source:   c.Container,
+				Message:  message,
+				Data:     c,
+			})
+		}
+	}
+	return report, nil
+}
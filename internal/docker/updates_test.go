@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	digests := []string{"nginx@sha256:aaa", "registry.local/nginx@sha256:bbb"}
+	assert.Equal(t, UpdateCurrent, updateStatus(digests, "sha256:bbb"))
+	assert.Equal(t, UpdateAvailable, updateStatus(digests, "sha256:ccc"))
+	assert.Equal(t, "sha256:aaa", digestOf("nginx@sha256:aaa"))
+}
+
+func TestUpdateCheckerEvents(t *testing.T) {
+	report := &UpdateReport{Containers: []ImageUpdate{
+		{Container: "web", Image: "nginx:1.25", Status: UpdateAvailable, RemoteDigest: "sha256:new"},
+		{Container: "db", Image: "postgres:16", Status: UpdateCurrent},
+	}}
+	bus := events.NewBus(events.DefaultCapacity)
+	u := NewUpdateChecker(nil, 0, bus)
+	u.check = func(context.Context) (*UpdateReport, error) { return report, nil }
+
+	_, err := u.Check(context.Background())
+	require.NoError(t, err)
+	list := bus.Recent(10, "docker.")
+	require.Len(t, list.Events, 1)
+	assert.Equal(t, "web", list.Events[0].Source)
+	assert.Equal(t, "A newer nginx:1.25 image is available for container web", list.Events[0].Message)
+
+	// The same update is reported once
+	_, err = u.Check(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, bus.Recent(10, "docker.").Events, 1)
+
+	// Pulling the new image without recreating the container is a new finding
+	report.Containers[0].Status, report.Containers[0].RemoteDigest = UpdatePulled, ""
+	got, err := u.Report(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, report, got)
+	_, err = u.Check(context.Background())
+	require.NoError(t, err)
+	list = bus.Recent(10, "docker.")
+	require.Len(t, list.Events, 2)
+	assert.Contains(t, list.Events[0].Message, "recreate it")
+}
@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// usageConcurrency bounds the stats requests made at once
+const usageConcurrency = 8
+
+// ContainerUsage is a running container's CPU and memory use, light enough
+// to collect for every container with each metrics snapshot
+type ContainerUsage struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryUsage   uint64  `json:"memory_usage"`
+	MemoryLimit   uint64  `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+}
+
+// cpuSample is a container's CPU counters at one snapshot
+type cpuSample struct {
+	total  uint64
+	system uint64
+}
+
+// cpuSamples keeps each container's previous counters. One-shot stats skip
+// the second sample the daemon otherwise waits a second for, so CPU use is
+// measured between consecutive snapshots instead.
+type cpuSamples struct {
+	mu   sync.Mutex
+	last map[string]cpuSample
+}
+
+// percent records a sample and returns CPU use since the previous one
+func (s *cpuSamples) percent(id string, v *types.StatsJSON) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil {
+		s.last = make(map[string]cpuSample)
+	}
+	cur := cpuSample{total: v.CPUStats.CPUUsage.TotalUsage, system: v.CPUStats.SystemUsage}
+	prev, ok := s.last[id]
+	s.last[id] = cur
+
+	// Daemons without one-shot support send their own previous sample
+	if v.PreCPUStats.SystemUsage > 0 {
+		prev, ok = cpuSample{total: v.PreCPUStats.CPUUsage.TotalUsage, system: v.PreCPUStats.SystemUsage}, true
+	}
+	if !ok || cur.total < prev.total || cur.system <= prev.system {
+		return 0
+	}
+
+	cpus := float64(v.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(v.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return float64(cur.total-prev.total) / float64(cur.system-prev.system) * cpus * 100.0
+}
+
+// keep forgets samples of containers that are no longer running
+func (s *cpuSamples) keep(ids map[string]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.last {
+		if !ids[id] {
+			delete(s.last, id)
+		}
+	}
+}
+
+// ContainerUsage returns CPU and memory use for every running container.
+// CPU use is zero for a container's first snapshot. Containers that stop
+// while being sampled are left out.
+func (m *Manager) ContainerUsage(ctx context.Context) ([]ContainerUsage, error) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	usage := make([]*ContainerUsage, len(containers))
+	running := make(map[string]bool, len(containers))
+
+	var g errgroup.Group
+	g.SetLimit(usageConcurrency)
+	for i, ctr := range containers {
+		running[ctr.ID] = true
+		name := ""
+		if len(ctr.Names) > 0 {
+			name = strings.TrimPrefix(ctr.Names[0], "/")
+		}
+
+		g.Go(func() error {
+			stats, err := m.client.ContainerStatsOneShot(ctx, ctr.ID)
+			if err != nil {
+				return nil
+			}
+			defer stats.Body.Close()
+
+			var v types.StatsJSON
+			if err := decodeStats(stats.Body, &v); err != nil {
+				return nil
+			}
+
+			u := &ContainerUsage{
+				ID:          ctr.ID[:12],
+				Name:        name,
+				CPUPercent:  m.cpu.percent(ctr.ID, &v),
+				MemoryUsage: memoryUsage(&v),
+				MemoryLimit: v.MemoryStats.Limit,
+			}
+			if v.MemoryStats.Limit > 0 {
+				u.MemoryPercent = float64(u.MemoryUsage) / float64(v.MemoryStats.Limit) * 100.0
+			}
+			usage[i] = u
+			return nil
+		})
+	}
+	g.Wait()
+	m.cpu.keep(running)
+
+	result := make([]ContainerUsage, 0, len(usage))
+	for _, u := range usage {
+		if u != nil {
+			result = append(result, *u)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
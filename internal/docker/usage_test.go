@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func stats(total, system uint64) *types.StatsJSON {
+	var v types.StatsJSON
+	v.CPUStats.CPUUsage.TotalUsage = total
+	v.CPUStats.SystemUsage = system
+	v.CPUStats.OnlineCPUs = 4
+	return &v
+}
+
+func TestCPUSamples(t *testing.T) {
+	var s cpuSamples
+
+	// The first snapshot has nothing to compare with
+	assert.Zero(t, s.percent("a", stats(100, 1000)))
+
+	// 50 of 1000 system ticks across 4 CPUs is 20%
+	assert.InDelta(t, 20.0, s.percent("a", stats(150, 2000)), 0.001)
+
+	// A restarted container's counters start over
+	assert.Zero(t, s.percent("a", stats(10, 3000)))
+
+	// A daemon's own previous sample is used when it sends one
+	v := stats(300, 5000)
+	v.PreCPUStats.CPUUsage.TotalUsage = 200
+	v.PreCPUStats.SystemUsage = 4000
+	assert.InDelta(t, 40.0, s.percent("b", v), 0.001)
+
+	s.keep(map[string]bool{"b": true})
+	assert.NotContains(t, s.last, "a")
+	assert.Contains(t, s.last, "b")
+}
@@ -0,0 +1,124 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// ListVolumes returns every volume with its size and the containers that
+// mount it. Sizes come from the daemon's disk usage report, which walks
+// local volumes and can take a while on large ones.
+func (m *Manager) ListVolumes(ctx context.Context) (*VolumeList, error) {
+	usage, err := m.client.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume usage: %w", err)
+	}
+	containers, err := m.allContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := volumeUsers(containers)
+
+	list := &VolumeList{Volumes: []VolumeInfo{}}
+	for _, v := range usage.Volumes {
+		info := newVolumeInfo(v, users[v.Name])
+		if info.Size > 0 {
+			list.TotalSize += info.Size
+		}
+		list.Volumes = append(list.Volumes, info)
+	}
+	sort.Slice(list.Volumes, func(i, j int) bool { return list.Volumes[i].Name < list.Volumes[j].Name })
+	list.Total = len(list.Volumes)
+	return list, nil
+}
+
+// GetVolume returns one volume with its size and the containers that
+// mount it
+func (m *Manager) GetVolume(ctx context.Context, name string) (*VolumeInfo, error) {
+	v, err := m.client.VolumeInspect(ctx, name)
+	if err != nil {
+		return nil, dockerError("failed to inspect volume", err)
+	}
+
+	// Inspect does not report the size; the disk usage report does
+	usage, err := m.client.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume usage: %w", err)
+	}
+	for _, u := range usage.Volumes {
+		if u.Name == v.Name {
+			v.UsageData = u.UsageData
+			break
+		}
+	}
+
+	containers, err := m.allContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := newVolumeInfo(&v, volumeUsers(containers)[v.Name])
+	return &info, nil
+}
+
+func newVolumeInfo(v *volume.Volume, users []AttachedContainer) VolumeInfo {
+	info := VolumeInfo{
+		Name:       v.Name,
+		Driver:     v.Driver,
+		Mountpoint: v.Mountpoint,
+		Scope:      v.Scope,
+		CreatedAt:  v.CreatedAt,
+		Labels:     v.Labels,
+		Options:    v.Options,
+		Size:       -1,
+		Containers: users,
+	}
+	if v.UsageData != nil {
+		info.Size = v.UsageData.Size
+	}
+	if info.Containers == nil {
+		info.Containers = []AttachedContainer{}
+	}
+	return info
+}
+
+// allContainers lists every container, running or not
+func (m *Manager) allContainers(ctx context.Context) ([]types.Container, error) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	sort.Slice(containers, func(i, j int) bool { return containerName(containers[i]) < containerName(containers[j]) })
+	return containers, nil
+}
+
+// volumeUsers maps volume names to the containers that mount them
+func volumeUsers(containers []types.Container) map[string][]AttachedContainer {
+	users := make(map[string][]AttachedContainer)
+	for _, c := range containers {
+		for _, mount := range c.Mounts {
+			if mount.Type != "volume" || mount.Name == "" {
+				continue
+			}
+			users[mount.Name] = append(users[mount.Name], AttachedContainer{
+				ID:          c.ID[:12],
+				Name:        containerName(c),
+				State:       c.State,
+				Destination: mount.Destination,
+				RW:          mount.RW,
+			})
+		}
+	}
+	return users
+}
+
+func containerName(c types.Container) string {
+	if len(c.Names) == 0 {
+		return c.ID[:12]
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+var (
+	webID   = strings.Repeat("a", 64)
+	dbID    = strings.Repeat("b", 64)
+	appNet  = strings.Repeat("1", 64)
+	hostNet = strings.Repeat("2", 64)
+)
+
+// fakeAPI serves canned Docker API responses by path
+func fakeAPI(t *testing.T, responses map[string]interface{}) *Manager {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[strings.TrimPrefix(r.URL.Path, "/v1.43")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "no such object"})
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+srv.Listener.Addr().String()), client.WithVersion("1.43"))
+	require.NoError(t, err)
+	return &Manager{client: cli}
+}
+
+func fakeObjects() map[string]interface{} {
+	return map[string]interface{}{
+		"/system/df": map[string]interface{}{
+			"Volumes": []map[string]interface{}{
+				{"Name": "pgdata", "Driver": "local", "Mountpoint": "/var/lib/docker/volumes/pgdata/_data", "Scope": "local", "UsageData": map[string]int64{"Size": 2048, "RefCount": 1}},
+				{"Name": "cache", "Driver": "local", "Scope": "local", "UsageData": map[string]int64{"Size": 512, "RefCount": 0}},
+				{"Name": "remote", "Driver": "nfs", "Scope": "global", "UsageData": map[string]int64{"Size": -1, "RefCount": 0}},
+			},
+		},
+		"/volumes/pgdata": map[string]interface{}{"Name": "pgdata", "Driver": "local", "Scope": "local"},
+		"/containers/json": []map[string]interface{}{
+			{
+				"Id": webID, "Names": []string{"/web"}, "State": "running",
+				"NetworkSettings": map[string]interface{}{"Networks": map[string]interface{}{
+					"app": map[string]string{"NetworkID": appNet, "IPAddress": "172.18.0.2", "MacAddress": "02:42:ac:12:00:02"},
+				}},
+			},
+			{
+				"Id": dbID, "Names": []string{"/db"}, "State": "exited",
+				"Mounts": []map[string]interface{}{{"Type": "volume", "Name": "pgdata", "Destination": "/var/lib/postgresql/data", "RW": true}},
+				"NetworkSettings": map[string]interface{}{"Networks": map[string]interface{}{
+					"app": map[string]string{"NetworkID": appNet},
+				}},
+			},
+		},
+		"/networks": []map[string]interface{}{
+			{"Id": hostNet, "Name": "host", "Driver": "host", "Scope": "local"},
+			{"Id": appNet, "Name": "app", "Driver": "bridge", "Scope": "local", "IPAM": map[string]interface{}{"Config": []map[string]string{{"Subnet": "172.18.0.0/16", "Gateway": "172.18.0.1"}}}},
+		},
+		"/networks/app": map[string]interface{}{"Id": appNet, "Name": "app", "Driver": "bridge", "Scope": "local"},
+	}
+}
+
+func TestListVolumes(t *testing.T) {
+	m := fakeAPI(t, fakeObjects())
+
+	list, err := m.ListVolumes(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 3, list.Total)
+	assert.Equal(t, int64(2560), list.TotalSize) // Unknown sizes are not counted
+
+	assert.Equal(t, []string{"cache", "pgdata", "remote"}, []string{list.Volumes[0].Name, list.Volumes[1].Name, list.Volumes[2].Name})
+	assert.Empty(t, list.Volumes[0].Containers)
+	assert.Equal(t, int64(-1), list.Volumes[2].Size)
+	assert.Equal(t, []AttachedContainer{{
+		ID: dbID[:12], Name: "db", State: "exited", Destination: "/var/lib/postgresql/data", RW: true,
+	}}, list.Volumes[1].Containers)
+
+	volume, err := m.GetVolume(t.Context(), "pgdata")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2048), volume.Size)
+	assert.Len(t, volume.Containers, 1)
+
+	_, err = m.GetVolume(t.Context(), "missing")
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
+
+func TestListNetworks(t *testing.T) {
+	m := fakeAPI(t, fakeObjects())
+
+	list, err := m.ListNetworks(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 2, list.Total)
+
+	app := list.Networks[0]
+	assert.Equal(t, "app", app.Name)
+	assert.Equal(t, appNet[:12], app.ID)
+	assert.Equal(t, []NetworkSubnet{{Subnet: "172.18.0.0/16", Gateway: "172.18.0.1"}}, app.Subnets)
+	assert.Equal(t, []AttachedContainer{
+		{ID: dbID[:12], Name: "db", State: "exited"},
+		{ID: webID[:12], Name: "web", State: "running", IPv4Address: "172.18.0.2", MacAddress: "02:42:ac:12:00:02"},
+	}, app.Containers)
+	assert.Empty(t, list.Networks[1].Containers)
+
+	network, err := m.GetNetwork(t.Context(), "app")
+	require.NoError(t, err)
+	assert.Len(t, network.Containers, 2)
+
+	_, err = m.GetNetwork(t.Context(), "missing")
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
@@ -0,0 +1,118 @@
+package energy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// fakeSysfs points the meter at temporary powercap and hwmon trees
+func fakeSysfs(t *testing.T) (rapl string) {
+	dir := t.TempDir()
+	origPowercap, origHwmon := powercapRoot, hwmonRoot
+	powercapRoot = filepath.Join(dir, "powercap")
+	hwmonRoot = filepath.Join(dir, "hwmon")
+	t.Cleanup(func() { powercapRoot, hwmonRoot = origPowercap, origHwmon })
+
+	rapl = filepath.Join(powercapRoot, "intel-rapl:0")
+	writeFile(t, filepath.Join(rapl, "name"), "package-0\n")
+	writeFile(t, filepath.Join(rapl, "energy_uj"), "1000000000\n")
+	writeFile(t, filepath.Join(rapl, "max_energy_range_uj"), "2000000000\n")
+	writeFile(t, filepath.Join(powercapRoot, "intel-rapl:0:0", "energy_uj"), "500\n")
+
+	writeFile(t, filepath.Join(hwmonRoot, "hwmon3", "name"), "amdgpu\n")
+	writeFile(t, filepath.Join(hwmonRoot, "hwmon3", "power1_average"), "12500000\n")
+	writeFile(t, filepath.Join(hwmonRoot, "hwmon3", "power1_label"), "PPT\n")
+	return rapl
+}
+
+func TestParseEstimates(t *testing.T) {
+	estimates, err := ParseEstimates(map[string]string{"disks": "12", "cpu": "5-35"})
+	require.NoError(t, err)
+	assert.Equal(t, []Estimate{
+		{Name: "cpu", Idle: 5, Max: 35},
+		{Name: "disks", Idle: 12, Max: 12},
+	}, estimates)
+
+	_, err = ParseEstimates(map[string]string{"cpu": "35-5"})
+	assert.Error(t, err)
+	_, err = ParseEstimates(map[string]string{"cpu": "lots"})
+	assert.Error(t, err)
+}
+
+func TestMeter_Sample(t *testing.T) {
+	rapl := fakeSysfs(t)
+	origNow, origUsage := now, cpuUsage
+	defer func() { now, cpuUsage = origNow, origUsage }()
+
+	clock := time.Date(2026, 1, 1, 10, 59, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	cpuUsage = func() float64 { return 50 }
+
+	dir := t.TempDir()
+	m := NewMeter([]Estimate{{Name: "cpu", Idle: 10, Max: 30}}, 30*time.Second, dir)
+	require.Len(t, m.zones, 1)
+	assert.True(t, m.Available())
+
+	// RAPL has no rate until the second read
+	p := m.Sample()
+	assert.Equal(t, 32.5, p.Watts)
+	assert.True(t, p.Estimated)
+	assert.Equal(t, Reading{Name: "amdgpu PPT", Source: SourceHwmon, Watts: 12.5}, p.Readings[0])
+
+	// 300 J over 30 s is 10 W
+	clock = clock.Add(30 * time.Second)
+	writeFile(t, filepath.Join(rapl, "energy_uj"), "1300000000\n")
+	p = m.Sample()
+	assert.Contains(t, p.Readings, Reading{Name: "package-0", Source: SourceRAPL, Watts: 10})
+	assert.Equal(t, 42.5, p.Watts)
+
+	clock = clock.Add(30 * time.Second)
+	writeFile(t, filepath.Join(rapl, "energy_uj"), "200000000\n") // Wrapped at 2000 J: 900 J
+	p = m.Sample()
+	assert.Contains(t, p.Readings, Reading{Name: "package-0", Source: SourceRAPL, Watts: 30})
+
+	// The first hour is finished and written to disk
+	history := m.History(0)
+	require.Len(t, history.Hours, 2)
+	assert.InDelta(t, 42.5*30/3600, history.Hours[0].EnergyWh, 0.01)
+	assert.Equal(t, 42.5, history.Hours[0].AvgWatts)
+	assert.True(t, history.Hours[1].Partial)
+	assert.Len(t, m.History(1).Hours, 1)
+
+	reloaded := NewMeter(nil, time.Minute, dir)
+	assert.Len(t, reloaded.History(0).Hours, 1)
+
+	// Long gaps are not counted
+	before := m.History(0).EnergyWh
+	clock = clock.Add(time.Hour)
+	m.Sample()
+	assert.Equal(t, before, m.History(0).EnergyWh)
+}
+
+func TestMeter_Unavailable(t *testing.T) {
+	fakeSysfs(t)
+	os.RemoveAll(powercapRoot)
+	os.RemoveAll(hwmonRoot)
+
+	m := NewMeter(nil, time.Minute, "")
+	assert.False(t, m.Available())
+	_, err := m.Current()
+	assert.Error(t, err)
+}
+
+func TestRound(t *testing.T) {
+	assert.Equal(t, 1.24, round(1.2351))
+	assert.Equal(t, -1.24, round(-1.2351), "a meter that ran backwards")
+	assert.Equal(t, 0.0, round(0.004))
+}
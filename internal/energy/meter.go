@@ -0,0 +1,328 @@
+package energy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// MaxHistory is the number of hours kept, about 90 days
+const MaxHistory = 90 * 24
+
+// raplWarmup is how long the first request waits for a second RAPL read
+const raplWarmup = 250 * time.Millisecond
+
+// Replaced in tests
+var (
+	now      = time.Now
+	cpuUsage = func() float64 {
+		percent, err := cpu.Percent(0, false)
+		if err != nil || len(percent) == 0 {
+			return 0
+		}
+		return percent[0]
+	}
+)
+
+// Estimate is a configured wattage for a device without a sensor. With a
+// range, the draw moves between Idle and Max with CPU usage.
+type Estimate struct {
+	Name string  `json:"name"`
+	Idle float64 `json:"idle"`
+	Max  float64 `json:"max"`
+}
+
+// ParseEstimates parses name=watts or name=idle-max pairs
+func ParseEstimates(values map[string]string) ([]Estimate, error) {
+	var estimates []Estimate
+	for name, value := range values {
+		idleStr, maxStr, isRange := strings.Cut(value, "-")
+		idle, err := strconv.ParseFloat(strings.TrimSpace(idleStr), 64)
+		if err != nil || idle < 0 {
+			return nil, fmt.Errorf("power estimate %s: invalid watts %q", name, value)
+		}
+		max := idle
+		if isRange {
+			if max, err = strconv.ParseFloat(strings.TrimSpace(maxStr), 64); err != nil || max < idle {
+				return nil, fmt.Errorf("power estimate %s: invalid range %q", name, value)
+			}
+		}
+		estimates = append(estimates, Estimate{Name: name, Idle: idle, Max: max})
+	}
+	sort.Slice(estimates, func(i, j int) bool { return estimates[i].Name < estimates[j].Name })
+	return estimates, nil
+}
+
+// Meter samples power draw and keeps hourly energy use. History is
+// appended to power.jsonl in the data directory.
+type Meter struct {
+	estimates   []Estimate
+	zones       []raplZone
+	interval    time.Duration
+	historyFile string
+
+	mu          sync.Mutex
+	lastEnergy  map[string]uint64
+	lastAt      time.Time
+	current     *Power
+	energyWh    float64
+	hour        Hour
+	hourSeconds float64
+	history     []Hour
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewMeter creates a meter using RAPL, hwmon power sensors and the
+// estimates
+func NewMeter(estimates []Estimate, interval time.Duration, dataDir string) *Meter {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	m := &Meter{
+		estimates:  estimates,
+		zones:      findRAPL(),
+		interval:   interval,
+		lastEnergy: make(map[string]uint64),
+		stop:       make(chan struct{}),
+	}
+	if dataDir != "" {
+		m.historyFile = filepath.Join(dataDir, "power.jsonl")
+		m.loadHistory()
+	}
+	return m
+}
+
+// Available reports whether there is anything to measure or estimate
+func (m *Meter) Available() bool {
+	return len(m.zones) > 0 || len(m.estimates) > 0 || len(readHwmon()) > 0
+}
+
+// Start samples every interval until Stop is called
+func (m *Meter) Start() {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.Sample()
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the sampling
+func (m *Meter) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// Current returns the latest sample, taking one if there is none yet
+func (m *Meter) Current() (*Power, error) {
+	if !m.Available() {
+		return nil, apierror.Unavailable("no power sensors found and no POWER_ESTIMATES configured")
+	}
+
+	m.mu.Lock()
+	current := m.current
+	m.mu.Unlock()
+	if current != nil {
+		return current, nil
+	}
+
+	// RAPL needs two reads to work out watts
+	if len(m.zones) > 0 {
+		m.Sample()
+		time.Sleep(raplWarmup)
+	}
+	return m.Sample(), nil
+}
+
+// Sample reads every source and adds the energy used since the previous
+// sample to the current hour
+func (m *Meter) Sample() *Power {
+	readings := readHwmon()
+	usage := -1.0
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at := now()
+	elapsed := at.Sub(m.lastAt).Seconds()
+	first := m.lastAt.IsZero()
+
+	for _, zone := range m.zones {
+		energy, ok := zone.energy()
+		if !ok {
+			continue
+		}
+		prev, seen := m.lastEnergy[zone.path]
+		m.lastEnergy[zone.path] = energy
+		if !seen || first || elapsed <= 0 {
+			continue
+		}
+		delta := energy - prev
+		if energy < prev {
+			delta = energy + zone.maxRange - prev // Counter wrapped
+		}
+		readings = append(readings, Reading{
+			Name:   zone.name,
+			Source: SourceRAPL,
+			Watts:  round(float64(delta) / 1e6 / elapsed),
+		})
+	}
+
+	power := &Power{Timestamp: at, Readings: readings}
+	for _, est := range m.estimates {
+		watts := est.Idle
+		if est.Max > est.Idle {
+			if usage < 0 {
+				usage = cpuUsage()
+			}
+			watts += (est.Max - est.Idle) * usage / 100
+		}
+		power.Readings = append(power.Readings, Reading{Name: est.Name, Source: SourceEstimate, Watts: round(watts)})
+		power.Estimated = true
+	}
+	for _, r := range power.Readings {
+		power.Watts += r.Watts
+	}
+	power.Watts = round(power.Watts)
+	if power.Readings == nil {
+		power.Readings = []Reading{}
+	}
+
+	// Gaps longer than two intervals, e.g. while suspended, are not counted
+	if !first && elapsed > 0 && elapsed <= 2*m.interval.Seconds() {
+		m.add(at, power.Watts, elapsed)
+	}
+	m.lastAt = at
+
+	power.EnergyWh = round(m.energyWh)
+	m.current = power
+	return power
+}
+
+// add records watts drawn for seconds in the hour of at
+func (m *Meter) add(at time.Time, watts, seconds float64) {
+	hour := at.Truncate(time.Hour)
+	if !m.hour.Hour.Equal(hour) {
+		if m.hourSeconds > 0 {
+			m.record(m.finishHour())
+		}
+		m.hour = Hour{Hour: hour}
+		m.hourSeconds = 0
+	}
+
+	wh := watts * seconds / 3600
+	m.energyWh += wh
+	m.hour.EnergyWh += wh
+	m.hourSeconds += seconds
+}
+
+// finishHour returns the current hour with its average
+func (m *Meter) finishHour() Hour {
+	h := m.hour
+	if m.hourSeconds > 0 {
+		h.AvgWatts = round(h.EnergyWh * 3600 / m.hourSeconds)
+	}
+	h.EnergyWh = round(h.EnergyWh)
+	return h
+}
+
+// History returns up to hours of hourly energy use, including the current
+// hour. Zero returns everything kept.
+func (m *Meter) History(hours int) *History {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	all := append([]Hour(nil), m.history...)
+	if m.hourSeconds > 0 {
+		current := m.finishHour()
+		current.Partial = true
+		all = append(all, current)
+	}
+	if hours > 0 && len(all) > hours {
+		all = all[len(all)-hours:]
+	}
+
+	history := &History{Hours: all}
+	for _, h := range all {
+		history.EnergyWh += h.EnergyWh
+	}
+	history.EnergyWh = round(history.EnergyWh)
+	return history
+}
+
+// record appends a finished hour to the history; called with mu held
+func (m *Meter) record(h Hour) {
+	m.history = append(m.history, h)
+	if len(m.history) > MaxHistory {
+		m.history = m.history[len(m.history)-MaxHistory:]
+	}
+
+	if m.historyFile == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.historyFile), 0750); err != nil {
+		log.Printf("Failed to create power data directory: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(m.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		log.Printf("Failed to open power history: %v", err)
+		return
+	}
+	defer f.Close()
+
+	data, _ := json.Marshal(h)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write power history: %v", err)
+	}
+}
+
+func (m *Meter) loadHistory() {
+	f, err := os.Open(m.historyFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var h Hour
+		if err := json.Unmarshal(scanner.Bytes(), &h); err != nil {
+			continue
+		}
+		m.history = append(m.history, h)
+	}
+
+	if len(m.history) > MaxHistory {
+		m.history = m.history[len(m.history)-MaxHistory:]
+	}
+}
+
+// round keeps two decimals, rounding halves away from zero
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
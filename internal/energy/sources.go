@@ -0,0 +1,103 @@
+package energy
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Sysfs roots; replaced in tests
+var (
+	powercapRoot = "/sys/class/powercap"
+	hwmonRoot    = "/sys/class/hwmon"
+)
+
+// raplZone is a top-level RAPL domain, e.g. package-0. Its counter is
+// cumulative, so watts come from the change between two reads.
+type raplZone struct {
+	name     string
+	path     string
+	maxRange uint64
+}
+
+// findRAPL returns the readable top-level RAPL zones. Subzones such as
+// core and dram are part of their package, so they are skipped. The
+// counters are often root-only.
+func findRAPL() []raplZone {
+	paths, _ := filepath.Glob(filepath.Join(powercapRoot, "intel-rapl:*"))
+
+	var zones []raplZone
+	for _, path := range paths {
+		if strings.Count(filepath.Base(path), ":") != 1 {
+			continue
+		}
+		if _, ok := readUint(filepath.Join(path, "energy_uj")); !ok {
+			continue
+		}
+		name := readString(filepath.Join(path, "name"))
+		if name == "" {
+			name = filepath.Base(path)
+		}
+		maxRange, _ := readUint(filepath.Join(path, "max_energy_range_uj"))
+		zones = append(zones, raplZone{name: name, path: path, maxRange: maxRange})
+	}
+	return zones
+}
+
+// energy returns the zone's counter in microjoules
+func (z raplZone) energy() (uint64, bool) {
+	return readUint(filepath.Join(z.path, "energy_uj"))
+}
+
+// readHwmon returns the hwmon power sensors, which report microwatts
+func readHwmon() []Reading {
+	var readings []Reading
+	for _, pattern := range []string{"power*_input", "power*_average"} {
+		paths, _ := filepath.Glob(filepath.Join(hwmonRoot, "hwmon*", pattern))
+		for _, path := range paths {
+			uw, ok := readUint(path)
+			if !ok {
+				continue
+			}
+			readings = append(readings, Reading{
+				Name:   hwmonName(path),
+				Source: SourceHwmon,
+				Watts:  float64(uw) / 1e6,
+			})
+		}
+	}
+	return readings
+}
+
+// hwmonName names a sensor from its chip and label, e.g. "amdgpu PPT"
+func hwmonName(path string) string {
+	dir := filepath.Dir(path)
+	name := readString(filepath.Join(dir, "name"))
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+
+	sensor, _, _ := strings.Cut(filepath.Base(path), "_")
+	if label := readString(filepath.Join(dir, sensor+"_label")); label != "" {
+		return name + " " + label
+	}
+	return name + " " + sensor
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readUint(path string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,40 @@
+package energy
+
+import "time"
+
+// Reading sources
+const (
+	SourceRAPL     = "rapl"
+	SourceHwmon    = "hwmon"
+	SourceEstimate = "estimate"
+)
+
+// Reading is the power draw of one source
+type Reading struct {
+	Name   string  `json:"name"`
+	Source string  `json:"source"`
+	Watts  float64 `json:"watts"`
+}
+
+// Power is the host's current power draw
+type Power struct {
+	Timestamp time.Time `json:"timestamp"`
+	Watts     float64   `json:"watts"`     // Sum of the readings
+	Estimated bool      `json:"estimated"` // Some of the total is a configured estimate
+	Readings  []Reading `json:"readings"`
+	EnergyWh  float64   `json:"energy_wh"` // Used since the agent started
+}
+
+// Hour is the energy used in one hour
+type Hour struct {
+	Hour     time.Time `json:"hour"`
+	EnergyWh float64   `json:"energy_wh"`
+	AvgWatts float64   `json:"avg_watts"`
+	Partial  bool      `json:"partial,omitempty"` // The current, unfinished hour
+}
+
+// History is hourly energy use, oldest first
+type History struct {
+	Hours    []Hour  `json:"hours"`
+	EnergyWh float64 `json:"energy_wh"` // Total over Hours
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCapacity is the number of recent events kept in memory
+	DefaultCapacity = 500
+	// subscriberBuffer is the channel size for each subscriber
+	subscriberBuffer = 64
+)
+
+// Bus fans out events to subscribers and keeps a bounded history
+type Bus struct {
+	recent      []Event
+	capacity    int
+	nextID      uint64
+	subscribers map[chan Event]struct{}
+	mu          sync.RWMutex
+}
+
+// NewBus creates a new event bus
+func NewBus(capacity int) *Bus {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Bus{
+		capacity:    capacity,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish records an event and delivers it to all subscribers. Slow
+// subscribers miss events rather than blocking the publisher.
+func (b *Bus) Publish(event Event) Event {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.Severity == "" {
+		event.Severity = SeverityInfo
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = b.nextID
+
+	b.recent = append(b.recent, event)
+	if len(b.recent) > b.capacity {
+		b.recent = b.recent[len(b.recent)-b.capacity:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+
+	if event.Severity != SeverityInfo {
+		log.Printf("[EVENT] %s (%s): %s", event.Type, event.Severity, event.Message)
+	}
+
+	return event
+}
+
+// Subscribe returns a channel receiving new events and a function to unsubscribe
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Recent returns the most recent events, newest first. A non-empty typePrefix
+// keeps only events whose type starts with it (e.g. "integrity").
+func (b *Bus) Recent(limit int, typePrefix string) *EventList {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if limit <= 0 || limit > len(b.recent) {
+		limit = len(b.recent)
+	}
+
+	events := make([]Event, 0, limit)
+	for i := len(b.recent) - 1; i >= 0 && len(events) < limit; i-- {
+		if typePrefix != "" && !strings.HasPrefix(b.recent[i].Type, typePrefix) {
+			continue
+		}
+		events = append(events, b.recent[i])
+	}
+
+	return &EventList{
+		Events: events,
+		Total:  len(events),
+	}
+}
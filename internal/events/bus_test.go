@@ -0,0 +1,189 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ids(list *EventList) []uint64 {
+	var out []uint64
+	for _, e := range list.Events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func TestBus_Publish(t *testing.T) {
+	b := NewBus(0)
+	assert.Equal(t, DefaultCapacity, b.capacity)
+
+	first := b.Publish(Event{Type: "agent.started", Message: "started"})
+	assert.Equal(t, uint64(1), first.ID)
+	assert.Equal(t, SeverityInfo, first.Severity)
+	assert.False(t, first.Timestamp.IsZero())
+
+	at := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	second := b.Publish(Event{Type: "disk.full", Severity: SeverityCritical, Timestamp: at})
+	assert.Equal(t, uint64(2), second.ID)
+	assert.Equal(t, SeverityCritical, second.Severity)
+	assert.Equal(t, at, second.Timestamp)
+}
+
+func TestBus_RingWrapsAround(t *testing.T) {
+	b := NewBus(3)
+	for i := 0; i < 5; i++ {
+		b.Publish(Event{Type: "test"})
+	}
+
+	recent := b.Recent(0, "")
+	assert.Equal(t, []uint64{5, 4, 3}, ids(recent), "newest first, oldest dropped")
+	assert.Equal(t, 3, recent.Total)
+
+	// IDs keep counting after the buffer wraps
+	assert.Equal(t, uint64(6), b.Publish(Event{Type: "test"}).ID)
+	assert.Equal(t, []uint64{6, 5, 4}, ids(b.Recent(0, "")))
+}
+
+func TestBus_Recent(t *testing.T) {
+	b := NewBus(10)
+	for _, typ := range []string{"integrity.changed", "docker.auto_update", "integrity.restored", "oom.kill", "integrity.changed"} {
+		b.Publish(Event{Type: typ})
+	}
+
+	tests := []struct {
+		name   string
+		limit  int
+		prefix string
+		want   []uint64
+	}{
+		{name: "all", want: []uint64{5, 4, 3, 2, 1}},
+		{name: "negative limit", limit: -1, want: []uint64{5, 4, 3, 2, 1}},
+		{name: "limit", limit: 2, want: []uint64{5, 4}},
+		{name: "limit above count", limit: 50, want: []uint64{5, 4, 3, 2, 1}},
+		{name: "prefix", prefix: "integrity", want: []uint64{5, 3, 1}},
+		{name: "prefix with limit", limit: 2, prefix: "integrity", want: []uint64{5, 3}},
+		{name: "exact type", prefix: "oom.kill", want: []uint64{4}},
+		{name: "no match", prefix: "backup", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := b.Recent(tt.limit, tt.prefix)
+			assert.Equal(t, tt.want, ids(list))
+			assert.Equal(t, len(tt.want), list.Total)
+			assert.NotNil(t, list.Events)
+		})
+	}
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	b := NewBus(10)
+	ch, unsubscribe := b.Subscribe()
+
+	b.Publish(Event{Type: "one"})
+	select {
+	case e := <-ch:
+		assert.Equal(t, "one", e.Type)
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not receive the event")
+	}
+
+	unsubscribe()
+	unsubscribe() // safe to call twice
+	b.Publish(Event{Type: "two"})
+	select {
+	case e := <-ch:
+		t.Fatalf("received %s after unsubscribing", e.Type)
+	default:
+	}
+	assert.Empty(t, b.subscribers)
+}
+
+func TestBus_SlowSubscriber(t *testing.T) {
+	b := NewBus(10)
+	slow, unsubscribeSlow := b.Subscribe()
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := b.Subscribe()
+	defer unsubscribeFast()
+
+	const total = subscriberBuffer * 3
+
+	var received []uint64
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for e := range fast {
+			received = append(received, e.ID)
+			if len(received) == total {
+				return
+			}
+		}
+	}()
+
+	// The publisher never blocks on the slow subscriber, which is not read
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < total; i++ {
+			b.Publish(Event{Type: "tick"})
+			// Let the fast subscriber catch up before its buffer fills
+			if i%subscriberBuffer == subscriberBuffer-1 {
+				for len(fast) > 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing blocked on a slow subscriber")
+	}
+	wg.Wait()
+
+	// The slow subscriber got the first events and missed the rest
+	require.Len(t, slow, subscriberBuffer)
+	assert.Equal(t, uint64(1), (<-slow).ID)
+	assert.Len(t, received, total)
+}
+
+func TestBus_Concurrent(t *testing.T) {
+	b := NewBus(50)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.Publish(Event{Type: fmt.Sprintf("worker%d.tick", i)})
+			}
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ch, unsubscribe := b.Subscribe()
+				select {
+				case <-ch:
+				default:
+				}
+				b.Recent(10, "worker")
+				unsubscribe()
+			}
+		}()
+	}
+	wg.Wait()
+
+	recent := b.Recent(0, "")
+	assert.Equal(t, 50, recent.Total)
+	assert.Equal(t, uint64(400), recent.Events[0].ID)
+}
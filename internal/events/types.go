@@ -0,0 +1,27 @@
+package events
+
+import "time"
+
+// Severity levels
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Event represents something noteworthy that happened on the host or agent
+type Event struct {
+	ID        uint64      `json:"id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Type      string      `json:"type"` // Dotted, e.g. "integrity.changed"
+	Severity  string      `json:"severity"`
+	Source    string      `json:"source,omitempty"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// EventList contains a list of events
+type EventList struct {
+	Events []Event `json:"events"`
+	Total  int     `json:"total"`
+}
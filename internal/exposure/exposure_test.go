@@ -0,0 +1,222 @@
+package exposure
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	psnet "github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+const ufwStatus = `Status: active
+Logging: on (low)
+Default: deny (incoming), allow (outgoing), disabled (routed)
+New profiles: skip
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW IN    Anywhere
+80,443/tcp                 ALLOW IN    Anywhere
+5432                       ALLOW IN    192.168.1.0/24
+8000:8100/tcp              DENY IN     Anywhere
+OpenSSH                    ALLOW IN    Anywhere
+53                         ALLOW OUT   Anywhere
+22/tcp (v6)                ALLOW IN    Anywhere (v6)
+`
+
+const nftRuleset = `table inet filter {
+	chain input {
+		type filter hook input priority filter; policy drop;
+		ct state established,related accept
+		iif "lo" accept
+		tcp dport 22 accept
+		tcp dport { 80, 443 } counter packets 10 bytes 600 accept comment "web"
+		ip saddr 10.0.0.0/8 udp dport 60000-61000 accept
+		jump services
+		icmp type echo-request accept
+	}
+	chain services {
+		tcp dport 9100 accept
+	}
+	chain forward {
+		type filter hook forward priority filter; policy drop;
+		tcp dport 3306 accept
+	}
+}
+`
+
+const iptablesRules = `-P INPUT DROP
+-A INPUT -i lo -j ACCEPT
+-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -j ACCEPT
+-A INPUT -s 192.168.0.0/16 -p tcp -m multiport --dports 3306,5432 -m comment --comment "db" -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 25 -j REJECT
+-A INPUT -j f2b-sshd
+`
+
+func TestParseUFW(t *testing.T) {
+	fw := parseUFW(ufwStatus)
+	assert.True(t, fw.Active)
+	assert.Equal(t, ActionDeny, fw.DefaultPolicy)
+	assert.Equal(t, []Rule{
+		{Protocol: "tcp", Port: 22, Action: ActionAllow},
+		{Protocol: "tcp", Port: 80, Action: ActionAllow},
+		{Protocol: "tcp", Port: 443, Action: ActionAllow},
+		{Port: 5432, Action: ActionAllow, Source: "192.168.1.0/24"},
+		{Protocol: "tcp", Port: 8000, PortEnd: 8100, Action: ActionDeny},
+		{Protocol: "tcp", Port: 22, Action: ActionAllow},
+	}, fw.Rules)
+
+	assert.False(t, parseUFW("Status: inactive\n").Active)
+}
+
+func TestParseNft(t *testing.T) {
+	fw, ok := parseNft(nftRuleset)
+	require.True(t, ok)
+	assert.True(t, fw.Active)
+	assert.Equal(t, ActionDeny, fw.DefaultPolicy)
+	assert.Equal(t, []Rule{
+		{Protocol: "tcp", Port: 22, Action: ActionAllow},
+		{Protocol: "tcp", Port: 80, Action: ActionAllow},
+		{Protocol: "tcp", Port: 443, Action: ActionAllow},
+		{Protocol: "udp", Port: 60000, PortEnd: 61000, Action: ActionAllow, Source: "10.0.0.0/8"},
+		{Protocol: "tcp", Port: 9100, Action: ActionAllow},
+	}, fw.Rules)
+
+	_, ok = parseNft("table inet nat {\n}\n")
+	assert.False(t, ok)
+}
+
+func TestParseIptables(t *testing.T) {
+	fw := parseIptables(iptablesRules)
+	assert.True(t, fw.Active)
+	assert.Equal(t, ActionDeny, fw.DefaultPolicy)
+	assert.Equal(t, []Rule{
+		{Protocol: "tcp", Port: 22, Action: ActionAllow},
+		{Protocol: "tcp", Port: 3306, Action: ActionAllow, Source: "192.168.0.0/16"},
+		{Protocol: "tcp", Port: 5432, Action: ActionAllow, Source: "192.168.0.0/16"},
+		{Protocol: "tcp", Port: 25, Action: ActionDeny},
+	}, fw.Rules)
+
+	assert.False(t, parseIptables("-P INPUT ACCEPT\n").Active)
+}
+
+func TestReach(t *testing.T) {
+	ufw := parseUFW(ufwStatus)
+	none := Firewall{Backend: "none"}
+
+	tests := []struct {
+		name     string
+		listener Listener
+		fw       Firewall
+		public   bool
+		want     string
+	}{
+		{"loopback", Listener{Protocol: "tcp", Address: "127.0.0.1", Port: 5432}, none, true, ReachLocal},
+		{"ipv6 loopback", Listener{Protocol: "tcp", Address: "::1", Port: 5432}, none, true, ReachLocal},
+		{"any without public address", Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 22}, none, false, ReachLAN},
+		{"any with public address", Listener{Protocol: "tcp", Address: "::", Port: 22}, none, true, ReachWAN},
+		{"private address", Listener{Protocol: "tcp", Address: "192.168.1.5", Port: 22}, none, true, ReachLAN},
+		{"tailscale address", Listener{Protocol: "tcp", Address: "100.101.102.103", Port: 22}, none, true, ReachLAN},
+		{"public address", Listener{Protocol: "tcp", Address: "203.0.113.7", Port: 22}, none, false, ReachWAN},
+		{"allowed", Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 443}, ufw, true, ReachWAN},
+		{"allowed from LAN", Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 5432}, ufw, true, ReachLAN},
+		{"denied by rule", Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 8080}, ufw, true, ReachFiltered},
+		{"denied by policy", Listener{Protocol: "udp", Address: "0.0.0.0", Port: 22}, ufw, true, ReachFiltered},
+		{"docker", Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 6379, Process: "docker-proxy"}, ufw, true, ReachWAN},
+		{"unreadable firewall", Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 6379}, Firewall{Backend: "ufw", Error: "denied"}, true, ReachWAN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := reach(tt.listener, &tt.fw, tt.public)
+			assert.Equal(t, tt.want, got, reason)
+			assert.NotEmpty(t, reason)
+		})
+	}
+}
+
+func stubScan(t *testing.T, conns []psnet.ConnectionStat) {
+	t.Helper()
+	origConns, origName, origAddrs, origLook := connections, processName, interfaceAddrs, lookPath
+	t.Cleanup(func() { connections, processName, interfaceAddrs, lookPath = origConns, origName, origAddrs, origLook })
+
+	connections = func(context.Context) ([]psnet.ConnectionStat, error) { return conns, nil }
+	processName = func(pid int32) string { return map[int32]string{10: "sshd", 20: "postgres"}[pid] }
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.ParseIP("192.168.1.5"), Mask: net.CIDRMask(24, 32)},
+			&net.IPNet{IP: net.ParseIP("203.0.113.7"), Mask: net.CIDRMask(24, 32)},
+		}, nil
+	}
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+}
+
+func TestScan(t *testing.T) {
+	stubScan(t, []psnet.ConnectionStat{
+		{Type: 1, Status: "LISTEN", Laddr: psnet.Addr{IP: "0.0.0.0", Port: 22}, Pid: 10},
+		{Type: 1, Status: "LISTEN", Laddr: psnet.Addr{IP: "0.0.0.0", Port: 22}, Pid: 10},
+		{Type: 1, Status: "LISTEN", Laddr: psnet.Addr{IP: "0.0.0.0", Port: 5432}, Pid: 20},
+		{Type: 1, Status: "LISTEN", Laddr: psnet.Addr{IP: "127.0.0.1", Port: 6379}},
+		{Type: 1, Status: "ESTABLISHED", Laddr: psnet.Addr{IP: "192.168.1.5", Port: 22}, Raddr: psnet.Addr{IP: "192.168.1.9", Port: 50000}},
+		{Type: 2, Laddr: psnet.Addr{IP: "0.0.0.0", Port: 53}},
+		{Type: 2, Laddr: psnet.Addr{IP: "192.168.1.5", Port: 40000}, Raddr: psnet.Addr{IP: "1.1.1.1", Port: 53}},
+	})
+
+	report, err := Scan(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "none", report.Firewall.Backend)
+	assert.Equal(t, []string{"203.0.113.7"}, report.PublicAddresses)
+	require.Len(t, report.Listeners, 4)
+	assert.Equal(t, 3, report.WAN)
+	assert.Equal(t, 1, report.Risky)
+
+	assert.Equal(t, Listener{
+		Protocol: "tcp", Address: "0.0.0.0", Port: 22, PID: 10, Process: "sshd", Service: "ssh",
+		Reachable: ReachWAN, Reason: "bound to all addresses of a host with a public address; no active firewall",
+	}, report.Listeners[0])
+	assert.Equal(t, "udp", report.Listeners[1].Protocol)
+	assert.Equal(t, "postgresql", report.Listeners[2].Service)
+	assert.True(t, report.Listeners[2].Risky)
+	assert.Equal(t, ReachLocal, report.Listeners[3].Reachable)
+}
+
+func TestMonitorChanges(t *testing.T) {
+	conns := []psnet.ConnectionStat{
+		{Type: 1, Status: "LISTEN", Laddr: psnet.Addr{IP: "0.0.0.0", Port: 22}},
+		{Type: 1, Status: "LISTEN", Laddr: psnet.Addr{IP: "0.0.0.0", Port: 8080}},
+	}
+	stubScan(t, conns)
+
+	bus := events.NewBus(events.DefaultCapacity)
+	m := NewMonitor(0, bus)
+	_, err := m.Check()
+	require.NoError(t, err)
+	assert.Empty(t, bus.Recent(0, "security.").Events, "the first scan is the baseline")
+
+	// Postgres opens to the internet and the web server stops
+	conns[1].Laddr.Port = 5432
+	_, err = m.Check()
+	require.NoError(t, err)
+
+	recent := bus.Recent(0, "security.").Events
+	require.Len(t, recent, 1)
+	assert.Equal(t, "security.exposure_changed", recent[0].Type)
+	assert.Equal(t, events.SeverityCritical, recent[0].Severity)
+	assert.Equal(t, "Newly reachable: 5432/tcp (postgresql) from WAN; Less exposed: 8080/tcp (http-alt) closed", recent[0].Message)
+
+	changes := recent[0].Data.([]Change)
+	require.Len(t, changes, 2)
+	assert.Empty(t, changes[0].Previous)
+	assert.True(t, changes[1].Closed)
+
+	// Nothing changed
+	_, err = m.Check()
+	require.NoError(t, err)
+	assert.Len(t, bus.Recent(0, "security.").Events, 1)
+}
@@ -0,0 +1,353 @@
+package exposure
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Replaced in tests
+var (
+	runCommand = defaultRunCommand
+	lookPath   = exec.LookPath
+)
+
+func defaultRunCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// Firewall actions
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// readFirewall finds the active inbound firewall, trying ufw, nftables and
+// then iptables
+func readFirewall(ctx context.Context) Firewall {
+	if _, err := lookPath("ufw"); err == nil {
+		out, err := runCommand(ctx, "ufw", "status", "verbose")
+		if err != nil {
+			return Firewall{Backend: "ufw", Error: err.Error()}
+		}
+		if fw := parseUFW(string(out)); fw.Active {
+			return fw
+		}
+	}
+
+	if _, err := lookPath("nft"); err == nil {
+		out, err := runCommand(ctx, "nft", "list", "ruleset")
+		if err != nil {
+			return Firewall{Backend: "nftables", Error: err.Error()}
+		}
+		if fw, ok := parseNft(string(out)); ok {
+			return fw
+		}
+	}
+
+	if _, err := lookPath("iptables"); err == nil {
+		out, err := runCommand(ctx, "iptables", "-S", "INPUT")
+		if err != nil {
+			return Firewall{Backend: "iptables", Error: err.Error()}
+		}
+		if fw := parseIptables(string(out)); fw.Active {
+			return fw
+		}
+	}
+
+	return Firewall{Backend: "none"}
+}
+
+// match returns the first rule covering a port, or nil
+func (f *Firewall) match(protocol string, port int) *Rule {
+	for i, r := range f.Rules {
+		if r.Protocol != "" && r.Protocol != protocol {
+			continue
+		}
+		end := max(r.Port, r.PortEnd)
+		if r.Port == 0 || (port >= r.Port && port <= end) {
+			return &f.Rules[i]
+		}
+	}
+	return nil
+}
+
+// policy normalises a firewall target or policy name
+func policy(s string) string {
+	switch strings.ToLower(s) {
+	case "accept", "allow":
+		return ActionAllow
+	case "drop", "deny", "reject", "limit":
+		return ActionDeny
+	}
+	return ""
+}
+
+// parsePorts parses "22", "80,443", "8000:8100" or "60000-61000" into
+// rules with the given protocol and action
+func parsePorts(spec, protocol, action, source string) ([]Rule, bool) {
+	var rules []Rule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, ":")
+		if !isRange {
+			from, to, isRange = strings.Cut(part, "-")
+		}
+		port, err := strconv.Atoi(from)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, false
+		}
+		rule := Rule{Protocol: protocol, Port: port, Action: action, Source: source}
+		if isRange {
+			if rule.PortEnd, err = strconv.Atoi(to); err != nil || rule.PortEnd < port {
+				return nil, false
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, len(rules) > 0
+}
+
+// ufwColumns splits a ufw status row; columns are padded with spaces
+var ufwColumns = regexp.MustCompile(`\s{2,}`)
+
+// parseUFW parses "ufw status verbose"
+func parseUFW(out string) Firewall {
+	fw := Firewall{Backend: "ufw"}
+	rules := false
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Status:"):
+			fw.Active = strings.TrimSpace(strings.TrimPrefix(line, "Status:")) == "active"
+		case strings.HasPrefix(line, "Default:"):
+			// deny (incoming), allow (outgoing), disabled (routed)
+			if fields := strings.Fields(strings.TrimPrefix(line, "Default:")); len(fields) > 0 {
+				fw.DefaultPolicy = policy(fields[0])
+			}
+		case strings.HasPrefix(line, "--"):
+			rules = true
+		case rules && line != "":
+			fw.Rules = append(fw.Rules, parseUFWRule(line)...)
+		}
+	}
+	return fw
+}
+
+// parseUFWRule parses a rule such as "80,443/tcp  ALLOW IN  192.168.1.0/24".
+// Application profiles and outgoing rules are skipped.
+func parseUFWRule(line string) []Rule {
+	cols := ufwColumns.Split(line, -1)
+	if len(cols) < 3 {
+		return nil
+	}
+	to, verb, from := cols[0], strings.Fields(cols[1]), cols[2]
+	if len(verb) == 0 || (len(verb) > 1 && verb[1] != "IN") {
+		return nil
+	}
+	action := policy(verb[0])
+	if action == "" {
+		return nil
+	}
+
+	source := strings.Fields(strings.TrimSuffix(from, " (v6)"))[0]
+	if source == "Anywhere" {
+		source = ""
+	}
+
+	// The destination may be preceded by an address: "10.0.0.5 22/tcp"
+	to = strings.TrimSuffix(to, " (v6)")
+	fields := strings.Fields(to)
+	spec := fields[len(fields)-1]
+	if spec == "Anywhere" {
+		return []Rule{{Action: action, Source: source}}
+	}
+	ports, protocol, _ := strings.Cut(spec, "/")
+	rules, _ := parsePorts(ports, protocol, action, source)
+	return rules
+}
+
+// iptablesModules are the match modules a counted rule may use
+var iptablesModules = map[string]bool{"tcp": true, "udp": true, "multiport": true, "comment": true}
+
+// parseIptables parses "iptables -S INPUT". Rules with other matches, such
+// as an interface or connection state, and jumps to other chains are
+// skipped.
+func parseIptables(out string) Firewall {
+	fw := Firewall{Backend: "iptables", DefaultPolicy: ActionAllow}
+	for _, line := range strings.Split(out, "\n") {
+		args := strings.Fields(line)
+		if len(args) >= 3 && args[0] == "-P" {
+			fw.DefaultPolicy = policy(args[2])
+			continue
+		}
+		if len(args) < 2 || args[0] != "-A" {
+			continue
+		}
+
+		var protocol, source, ports, action string
+		skip := false
+		for i := 2; i < len(args); i++ {
+			next := ""
+			if i+1 < len(args) {
+				next = args[i+1]
+			}
+			switch args[i] {
+			case "-p", "--protocol":
+				protocol, i = next, i+1
+			case "-s", "--source":
+				source, i = next, i+1
+			case "--dport", "--dports", "--destination-port":
+				ports, i = next, i+1
+			case "-j", "--jump":
+				action, i = policy(next), i+1
+			case "-m", "--match":
+				skip = skip || !iptablesModules[next]
+				i++
+			case "--comment":
+				i++
+			default:
+				// Interfaces, states, negations and other matches
+				skip = true
+			}
+		}
+		if skip || action == "" {
+			continue
+		}
+		if source == "0.0.0.0/0" {
+			source = ""
+		}
+		if ports == "" {
+			fw.Rules = append(fw.Rules, Rule{Protocol: protocol, Action: action, Source: source})
+			continue
+		}
+		if rules, ok := parsePorts(ports, protocol, action, source); ok {
+			fw.Rules = append(fw.Rules, rules...)
+		}
+	}
+	fw.Active = fw.DefaultPolicy == ActionDeny || len(fw.Rules) > 0
+	return fw
+}
+
+// parseNft parses "nft list ruleset", reading the chains hooked on input
+// and the chains they jump to unconditionally. It reports false when no
+// input chain exists.
+func parseNft(out string) (Firewall, bool) {
+	chains := map[string][]string{}
+	var inputs []string
+	fw := Firewall{Backend: "nftables", DefaultPolicy: ActionAllow}
+
+	table, chain := "", ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 3 && fields[0] == "table":
+			table = fields[1] + " " + fields[2]
+		case len(fields) >= 2 && fields[0] == "chain":
+			chain = table + " " + fields[1]
+		case line == "}":
+			if chain != "" {
+				chain = ""
+			} else {
+				table = ""
+			}
+		case chain == "":
+		case strings.HasPrefix(line, "type filter hook input"):
+			inputs = append(inputs, chain)
+			if strings.Contains(line, "policy drop") {
+				fw.DefaultPolicy = ActionDeny
+			}
+		default:
+			chains[chain] = append(chains[chain], line)
+		}
+	}
+	if len(inputs) == 0 {
+		return fw, false
+	}
+
+	for _, name := range inputs {
+		table := name[:strings.LastIndexByte(name, ' ')]
+		fw.Rules = append(fw.Rules, nftRules(chains, table, name, 0)...)
+	}
+	fw.Active = fw.DefaultPolicy == ActionDeny || len(fw.Rules) > 0
+	return fw, true
+}
+
+// nftRules returns the rules of a chain, following jumps up to a depth.
+// Rules with matches other than ports and source addresses, such as an
+// interface or connection state, are skipped.
+func nftRules(chains map[string][]string, table, name string, depth int) []Rule {
+	if depth > 5 {
+		return nil
+	}
+
+	var rules []Rule
+lines:
+	for _, line := range chains[name] {
+		// Drop comments and reject options such as "with icmpx admin-prohibited"
+		line, _, _ = strings.Cut(line, " comment ")
+		line, _, _ = strings.Cut(line, " with ")
+
+		// Sets such as { 80, 443 } become a comma-separated token
+		line = strings.NewReplacer("{ ", "", " }", "", ", ", ",").Replace(line)
+		fields := strings.Fields(line)
+
+		var protocol, source, ports, action string
+		for i := 0; i < len(fields); i++ {
+			next := ""
+			if i+1 < len(fields) {
+				next = fields[i+1]
+			}
+			switch fields[i] {
+			case "jump", "goto":
+				if i == 0 {
+					rules = append(rules, nftRules(chains, table, table+" "+next, depth+1)...)
+				}
+				continue lines
+			case "tcp", "udp", "th":
+				if next != "dport" || i+2 >= len(fields) {
+					continue lines
+				}
+				if fields[i] != "th" {
+					protocol = fields[i]
+				}
+				ports, i = fields[i+2], i+2
+			case "ip", "ip6":
+				if next != "saddr" || i+2 >= len(fields) {
+					continue lines
+				}
+				source, i = fields[i+2], i+2
+			case "counter", "log":
+			case "packets", "bytes":
+				i++
+			case "accept", "drop", "reject":
+				action = policy(fields[i])
+			default:
+				// A match this parser does not understand
+				continue lines
+			}
+		}
+		if action == "" {
+			continue
+		}
+		if ports == "" {
+			rules = append(rules, Rule{Protocol: protocol, Action: action, Source: source})
+			continue
+		}
+		if parsed, ok := parsePorts(ports, protocol, action, source); ok {
+			rules = append(rules, parsed...)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,170 @@
+package exposure
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+// scanTimeout bounds a scan, including the firewall commands
+const scanTimeout = 30 * time.Second
+
+// Monitor rescans periodically and raises an event when listeners become
+// more or less exposed
+type Monitor struct {
+	interval time.Duration
+	bus      *events.Bus
+
+	mu     sync.Mutex
+	report *Report
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewMonitor creates an exposure monitor publishing to bus
+func NewMonitor(interval time.Duration, bus *events.Bus) *Monitor {
+	return &Monitor{interval: interval, bus: bus, stop: make(chan struct{})}
+}
+
+// Start scans immediately and then every interval until Stop is called
+func (m *Monitor) Start() {
+	if m.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := m.Check(); err != nil {
+				log.Printf("Exposure scan failed: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the scans
+func (m *Monitor) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// Report returns the latest report, scanning now if there is none
+func (m *Monitor) Report() (*Report, error) {
+	m.mu.Lock()
+	report := m.report
+	m.mu.Unlock()
+	if report != nil {
+		return report, nil
+	}
+	return m.Check()
+}
+
+// Check scans now and publishes the changes since the previous scan. The
+// first scan is not compared with anything.
+func (m *Monitor) Check() (*Report, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	report, err := Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	previous := m.report
+	m.report = report
+	m.mu.Unlock()
+
+	if previous == nil || m.bus == nil {
+		return report, nil
+	}
+	if changes := Compare(previous, report); len(changes) > 0 {
+		m.bus.Publish(changeEvent(changes))
+	}
+	return report, nil
+}
+
+// Compare returns the listeners that became more or less exposed. Only
+// moves between local or filtered, LAN and WAN count.
+func Compare(previous, current *Report) []Change {
+	before := make(map[string]Listener, len(previous.Listeners))
+	for _, l := range previous.Listeners {
+		before[l.key()] = l
+	}
+
+	var changes []Change
+	for _, l := range current.Listeners {
+		old, ok := before[l.key()]
+		delete(before, l.key())
+		if rank(l.Reachable) == rank(old.Reachable) {
+			continue
+		}
+		change := Change{Listener: l}
+		if ok {
+			change.Previous = old.Reachable
+		}
+		changes = append(changes, change)
+	}
+	for _, l := range previous.Listeners {
+		if _, gone := before[l.key()]; gone && rank(l.Reachable) > 0 {
+			changes = append(changes, Change{Listener: l, Previous: l.Reachable, Closed: true})
+		}
+	}
+	return changes
+}
+
+// changeEvent describes changes as a security.exposure_changed event,
+// critical when a risky service became reachable from the internet
+func changeEvent(changes []Change) events.Event {
+	severity := events.SeverityInfo
+	var opened, reduced []string
+	for _, c := range changes {
+		name := fmt.Sprintf("%d/%s", c.Port, c.Protocol)
+		if c.Service != "" {
+			name += " (" + c.Service + ")"
+		}
+		switch {
+		case c.Closed:
+			reduced = append(reduced, name+" closed")
+			continue
+		case rank(c.Reachable) < rank(c.Previous):
+			reduced = append(reduced, name+" now "+c.Reachable)
+			continue
+		}
+
+		opened = append(opened, name+" from "+strings.ToUpper(c.Reachable))
+		switch {
+		case c.Reachable == ReachWAN && c.Risky:
+			severity = events.SeverityCritical
+		case severity == events.SeverityInfo:
+			severity = events.SeverityWarning
+		}
+	}
+
+	var parts []string
+	if len(opened) > 0 {
+		parts = append(parts, "Newly reachable: "+strings.Join(opened, ", "))
+	}
+	if len(reduced) > 0 {
+		parts = append(parts, "Less exposed: "+strings.Join(reduced, ", "))
+	}
+	return events.Event{
+		Type:     "security.exposure_changed",
+		Severity: severity,
+		Source:   "exposure",
+		Message:  strings.Join(parts, "; "),
+		Data:     changes,
+	}
+}
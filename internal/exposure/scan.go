@@ -0,0 +1,237 @@
+package exposure
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v4/net"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// Replaced in tests
+var (
+	connections = func(ctx context.Context) ([]psnet.ConnectionStat, error) {
+		return psnet.ConnectionsWithContext(ctx, "inet")
+	}
+	processName = func(pid int32) string {
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			return ""
+		}
+		name, _ := p.Name()
+		return name
+	}
+	interfaceAddrs = net.InterfaceAddrs
+)
+
+type service struct {
+	name  string
+	risky bool
+}
+
+// services are well-known ports. Risky ones carry databases, remote
+// desktops, unauthenticated APIs or plain-text logins.
+var services = map[int]service{
+	21:    {"ftp", true},
+	22:    {"ssh", false},
+	23:    {"telnet", true},
+	25:    {"smtp", false},
+	53:    {"dns", false},
+	80:    {"http", false},
+	110:   {"pop3", false},
+	111:   {"rpcbind", true},
+	139:   {"netbios", true},
+	143:   {"imap", false},
+	443:   {"https", false},
+	445:   {"smb", true},
+	465:   {"smtps", false},
+	587:   {"submission", false},
+	631:   {"ipp", true},
+	993:   {"imaps", false},
+	995:   {"pop3s", false},
+	1883:  {"mqtt", true},
+	2049:  {"nfs", true},
+	2375:  {"docker", true},
+	2376:  {"docker-tls", false},
+	3306:  {"mysql", true},
+	3389:  {"rdp", true},
+	5432:  {"postgresql", true},
+	5672:  {"amqp", true},
+	5900:  {"vnc", true},
+	6379:  {"redis", true},
+	8080:  {"http-alt", false},
+	8443:  {"https-alt", false},
+	9090:  {"prometheus", true},
+	9100:  {"node-exporter", true},
+	9200:  {"elasticsearch", true},
+	11211: {"memcached", true},
+	27017: {"mongodb", true},
+	51820: {"wireguard", false},
+}
+
+// cgnat is the shared address space used by carrier NAT and overlays
+// such as Tailscale
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// private reports whether ip is only routable on local networks
+func private(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLinkLocalUnicast() || cgnat.Contains(ip)
+}
+
+// rank orders reachability; local and filtered are not exposed
+func rank(reach string) int {
+	switch reach {
+	case ReachLAN:
+		return 1
+	case ReachWAN:
+		return 2
+	}
+	return 0
+}
+
+// Scan lists the listening sockets and works out how far each can be
+// reached from their bound address and the firewall
+func Scan(ctx context.Context) (*Report, error) {
+	conns, err := connections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sockets: %w", err)
+	}
+
+	report := &Report{Listeners: []Listener{}, Firewall: readFirewall(ctx), ScannedAt: time.Now()}
+	report.PublicAddresses = publicAddresses()
+
+	seen := map[string]bool{}
+	names := map[int32]string{}
+	for _, conn := range conns {
+		protocol := "tcp"
+		switch {
+		case conn.Type == 2: // SOCK_DGRAM
+			if conn.Raddr.Port != 0 {
+				continue
+			}
+			protocol = "udp"
+		case conn.Status != "LISTEN":
+			continue
+		}
+
+		l := Listener{Protocol: protocol, Address: conn.Laddr.IP, Port: int(conn.Laddr.Port), PID: conn.Pid}
+		key := l.key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if l.PID > 0 {
+			if _, ok := names[l.PID]; !ok {
+				names[l.PID] = processName(l.PID)
+			}
+			l.Process = names[l.PID]
+		}
+		if svc, ok := services[l.Port]; ok {
+			l.Service, l.Risky = svc.name, svc.risky
+		}
+		l.Reachable, l.Reason = reach(l, &report.Firewall, len(report.PublicAddresses) > 0)
+
+		switch l.Reachable {
+		case ReachLAN:
+			report.LAN++
+		case ReachWAN:
+			report.WAN++
+			if l.Risky {
+				report.Risky++
+			}
+		}
+		report.Listeners = append(report.Listeners, l)
+	}
+
+	sort.Slice(report.Listeners, func(i, j int) bool {
+		a, b := report.Listeners[i], report.Listeners[j]
+		if rank(a.Reachable) != rank(b.Reachable) {
+			return rank(a.Reachable) > rank(b.Reachable)
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.key() < b.key()
+	})
+	return report, nil
+}
+
+// key identifies a listener between scans
+func (l Listener) key() string {
+	return l.Protocol + " " + net.JoinHostPort(l.Address, strconv.Itoa(l.Port))
+}
+
+// publicAddresses returns the host's internet-routable addresses
+func publicAddresses() []string {
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var public []string
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip := ipNet.IP; ip.IsGlobalUnicast() && !private(ip) {
+			public = append(public, ip.String())
+		}
+	}
+	return public
+}
+
+// reach decides how far a listener can be reached and why
+func reach(l Listener, fw *Firewall, public bool) (string, string) {
+	ip := net.ParseIP(l.Address)
+	var level, reason string
+	switch {
+	case ip == nil:
+		return ReachLocal, "not bound to an IP address"
+	case ip.IsLoopback():
+		return ReachLocal, "bound to loopback"
+	case ip.IsUnspecified() && public:
+		level, reason = ReachWAN, "bound to all addresses of a host with a public address"
+	case ip.IsUnspecified():
+		level, reason = ReachLAN, "bound to all addresses of a host without a public address"
+	case private(ip):
+		level, reason = ReachLAN, "bound to a private address"
+	default:
+		level, reason = ReachWAN, "bound to a public address"
+	}
+
+	switch {
+	case l.Process == "docker-proxy":
+		// Published ports are forwarded before the INPUT chain
+		return level, reason + "; published by Docker, which bypasses the host firewall"
+	case fw.Error != "":
+		return level, reason + "; firewall rules could not be read"
+	case !fw.Active:
+		return level, reason + "; no active firewall"
+	}
+
+	rule := fw.match(l.Protocol, l.Port)
+	switch {
+	case rule == nil && fw.DefaultPolicy == ActionDeny:
+		return ReachFiltered, reason + "; denied by the default " + fw.Backend + " policy"
+	case rule == nil:
+		return level, reason + "; allowed by the default " + fw.Backend + " policy"
+	case rule.Action == ActionDeny:
+		return ReachFiltered, reason + "; denied by a " + fw.Backend + " rule"
+	case rule.Source == "":
+		return level, reason + "; allowed by a " + fw.Backend + " rule"
+	}
+
+	src, _, err := net.ParseCIDR(rule.Source)
+	if err != nil {
+		src = net.ParseIP(rule.Source)
+	}
+	if src != nil && private(src) {
+		level = ReachLAN
+	}
+	return level, reason + "; allowed from " + rule.Source + " by a " + fw.Backend + " rule"
+}
@@ -0,0 +1,63 @@
+package exposure
+
+import "time"
+
+// Reachability, from least to most exposed
+const (
+	ReachLocal    = "local"    // Bound to loopback
+	ReachFiltered = "filtered" // Dropped by the firewall
+	ReachLAN      = "lan"      // Reachable from private networks only
+	ReachWAN      = "wan"      // Reachable from the internet
+)
+
+// Listener is a socket accepting connections and how far it can be reached
+type Listener struct {
+	Protocol  string `json:"protocol"` // tcp or udp
+	Address   string `json:"address"`
+	Port      int    `json:"port"`
+	PID       int32  `json:"pid,omitempty"`
+	Process   string `json:"process,omitempty"`
+	Service   string `json:"service,omitempty"` // Well-known service on the port
+	Reachable string `json:"reachable"`
+	Reason    string `json:"reason"`
+	// Risky services, such as databases, should not be reachable from
+	// the internet
+	Risky bool `json:"risky,omitempty"`
+}
+
+// Rule is an inbound firewall rule for a port range. A zero Port matches
+// all ports and an empty Protocol both protocols.
+type Rule struct {
+	Protocol string `json:"protocol,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	PortEnd  int    `json:"port_end,omitempty"`
+	Action   string `json:"action"`           // allow or deny
+	Source   string `json:"source,omitempty"` // Address or CIDR; empty for anywhere
+}
+
+// Firewall is the inbound filtering found on the host
+type Firewall struct {
+	Backend       string `json:"backend"` // ufw, nftables, iptables or none
+	Active        bool   `json:"active"`
+	DefaultPolicy string `json:"default_policy,omitempty"` // allow or deny
+	Rules         []Rule `json:"rules,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Report lists the listening sockets and their reachability
+type Report struct {
+	Listeners       []Listener `json:"listeners"`
+	Firewall        Firewall   `json:"firewall"`
+	PublicAddresses []string   `json:"public_addresses,omitempty"`
+	LAN             int        `json:"lan"`
+	WAN             int        `json:"wan"`
+	Risky           int        `json:"risky"` // Risky listeners reachable from the internet
+	ScannedAt       time.Time  `json:"scanned_at"`
+}
+
+// Change is a listener whose reachability changed between scans
+type Change struct {
+	Listener
+	Previous string `json:"previous,omitempty"` // Empty when the listener is new
+	Closed   bool   `json:"closed,omitempty"`   // The listener is gone
+}
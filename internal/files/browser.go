@@ -1,6 +1,8 @@
 package files
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -12,6 +14,8 @@ import (
 	"strings"
 	"syscall"
 	"unicode/utf8"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
 )
 
 const (
@@ -21,10 +25,21 @@ const (
 	MaxDirEntries = 1000
 )
 
-// Browser handles file system operations (read-only)
+// ErrInvalidSort is returned for an unknown ListOptions.Sort key
+var ErrInvalidSort = apierror.Invalid("invalid sort: use name, size or mtime")
+
+// ErrDeleteDisabled is returned when file deletion has not been enabled
+var ErrDeleteDisabled = apierror.NotAllowed("file deletion is disabled")
+
+// ErrAccessDenied is returned for paths outside the allowed list
+var ErrAccessDenied = apierror.NotAllowed("access denied: path not in allowed list")
+
+// Browser handles file system operations. It is read-only unless a trash
+// is configured, in which case deleted files are moved there.
 type Browser struct {
 	allowedPaths []string
 	allowAll     bool
+	trash        *Trash
 }
 
 // NewBrowser creates a new file browser
@@ -54,6 +69,16 @@ func NewBrowser(allowedPaths []string) *Browser {
 	}
 }
 
+// SetTrash enables deletion, moving deleted files into t
+func (b *Browser) SetTrash(t *Trash) {
+	b.trash = t
+}
+
+// Trash returns the configured trash, or nil if deletion is disabled
+func (b *Browser) Trash() *Trash {
+	return b.trash
+}
+
 // GetAllowedPaths returns the list of allowed paths for the UI
 func (b *Browser) GetAllowedPaths() []string {
 	if b.allowAll {
@@ -62,28 +87,25 @@ func (b *Browser) GetAllowedPaths() []string {
 	return b.allowedPaths
 }
 
-// IsPathAllowed checks if a path is within allowed directories
+// IsPathAllowed checks if a path is within allowed directories. Symlinks are
+// resolved first, so a link inside an allowed directory cannot reach outside
+// it, and /var/log2 is not within /var/log.
 func (b *Browser) IsPathAllowed(path string) bool {
 	if b.allowAll {
 		return true
 	}
 
-	absPath, err := filepath.Abs(path)
+	absPath, err := resolvePath(path)
 	if err != nil {
 		return false
 	}
 
-	// Clean the path to prevent directory traversal
-	absPath = filepath.Clean(absPath)
-
 	for _, allowed := range b.allowedPaths {
-		allowedAbs, err := filepath.Abs(allowed)
+		allowedAbs, err := resolvePath(allowed)
 		if err != nil {
 			continue
 		}
-		allowedAbs = filepath.Clean(allowedAbs)
-
-		if strings.HasPrefix(absPath, allowedAbs) {
+		if isWithin(absPath, allowedAbs) {
 			return true
 		}
 	}
@@ -91,83 +113,214 @@ func (b *Browser) IsPathAllowed(path string) bool {
 	return false
 }
 
-// ListDirectory returns the contents of a directory
-func (b *Browser) ListDirectory(path string) (*DirectoryListing, error) {
+// resolvePath returns the absolute path with symlinks resolved. Parts that
+// do not exist yet, such as a file about to be restored, are kept as given.
+func resolvePath(path string) (string, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("invalid path: %w", err)
+		return "", err
+	}
+
+	var missing []string
+	for dir := absPath; ; dir = filepath.Dir(dir) {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) || dir == filepath.Dir(dir) {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(dir)}, missing...)
+	}
+}
+
+// ListDirectory returns one page of the contents of a directory.
+// Directories are always listed before files.
+func (b *Browser) ListDirectory(path string, opts ListOptions) (*DirectoryListing, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, apierror.Invalid("invalid path: %w", err)
 	}
 
 	if !b.IsPathAllowed(absPath) {
-		return nil, fmt.Errorf("access denied: path not in allowed list")
+		return nil, ErrAccessDenied
 	}
 
 	info, err := os.Stat(absPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stat path: %w", err)
+		return nil, statError("path", err)
 	}
 
 	if !info.IsDir() {
-		return nil, fmt.Errorf("path is not a directory")
+		return nil, apierror.Invalid("path is not a directory")
+	}
+
+	switch opts.Sort {
+	case "":
+		opts.Sort = SortByName
+	case SortByName, SortBySize, SortByMtime:
+	default:
+		return nil, ErrInvalidSort
+	}
+	if opts.Limit <= 0 || opts.Limit > MaxDirEntries {
+		opts.Limit = MaxDirEntries
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+
+	listing := &DirectoryListing{
+		Path:   absPath,
+		Files:  []FileInfo{},
+		Offset: opts.Offset,
+		Limit:  opts.Limit,
 	}
 
 	entries, err := os.ReadDir(absPath)
 	if err != nil {
-		return &DirectoryListing{
-			Path:    absPath,
-			Files:   []FileInfo{},
-			Total:   0,
-			CanRead: false,
-		}, nil
+		return listing, nil
 	}
+	listing.CanRead = true
 
-	var files []FileInfo
-	for i, entry := range entries {
-		if i >= MaxDirEntries {
-			break
+	// Lstat every entry so the whole directory can be sorted, but only resolve
+	// owners and link targets for the requested page
+	stats := make([]fs.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if opts.HideHidden && strings.HasPrefix(entry.Name(), ".") {
+			continue
 		}
-
-		fileInfo, err := b.getFileInfo(filepath.Join(absPath, entry.Name()))
+		info, err := entry.Info()
 		if err != nil {
 			continue
 		}
-		files = append(files, *fileInfo)
+		stats = append(stats, info)
 	}
 
-	// Sort: directories first, then by name
-	sort.Slice(files, func(i, j int) bool {
-		if files[i].IsDir != files[j].IsDir {
-			return files[i].IsDir
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		if opts.Desc {
+			a, b = b, a
 		}
-		return files[i].Name < files[j].Name
+		return compareEntries(a, b, opts.Sort) < 0
 	})
 
-	return &DirectoryListing{
-		Path:    absPath,
-		Files:   files,
-		Total:   len(files),
-		CanRead: true,
-	}, nil
+	listing.Total = len(stats)
+	if opts.Offset >= len(stats) {
+		return listing, nil
+	}
+
+	end := opts.Offset + opts.Limit
+	if end < len(stats) {
+		listing.Truncated = true
+		listing.NextOffset = end
+	} else {
+		end = len(stats)
+	}
+
+	for _, info := range stats[opts.Offset:end] {
+		fileInfo, err := b.getFileInfo(filepath.Join(absPath, info.Name()))
+		if err != nil {
+			continue
+		}
+		listing.Files = append(listing.Files, *fileInfo)
+	}
+
+	return listing, nil
+}
+
+// Delete moves a file or directory into the trash
+func (b *Browser) Delete(path string) (*TrashItem, error) {
+	if b.trash == nil {
+		return nil, ErrDeleteDisabled
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, apierror.Invalid("invalid path: %w", err)
+	}
+
+	if !b.IsPathAllowed(absPath) {
+		return nil, ErrAccessDenied
+	}
+
+	// Never delete an allowed root, the filesystem root, or the trash itself
+	if resolved, err := resolvePath(absPath); err != nil || absPath == "/" || isWithin(resolved, b.trash.dir) {
+		return nil, apierror.NotAllowed("access denied: path cannot be deleted")
+	}
+	for _, allowed := range b.allowedPaths {
+		if allowedAbs, err := filepath.Abs(allowed); err == nil && filepath.Clean(allowedAbs) == absPath {
+			return nil, apierror.NotAllowed("access denied: path cannot be deleted")
+		}
+	}
+
+	return b.trash.Put(absPath)
+}
+
+// Restore moves a trashed item back to its original location
+func (b *Browser) Restore(id string) (*TrashItem, error) {
+	if b.trash == nil {
+		return nil, ErrDeleteDisabled
+	}
+
+	item, err := b.trash.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.IsPathAllowed(item.OriginalPath) {
+		return nil, ErrAccessDenied
+	}
+
+	return b.trash.Restore(id)
+}
+
+// statError wraps an os.Stat failure, marking missing and unreadable paths
+func statError(what string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return apierror.NotFound("failed to stat %s: %w", what, err)
+	case errors.Is(err, fs.ErrPermission):
+		return apierror.NotAllowed("failed to stat %s: %w", what, err)
+	default:
+		return fmt.Errorf("failed to stat %s: %w", what, err)
+	}
+}
+
+// isWithin reports whether path is dir or below it, with dir's symlinks
+// resolved. path must already be resolved.
+func isWithin(path, dir string) bool {
+	dir, err := resolvePath(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
 }
 
 // ReadFile returns the content of a file
 func (b *Browser) ReadFile(path string) (*FileContent, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("invalid path: %w", err)
+		return nil, apierror.Invalid("invalid path: %w", err)
 	}
 
 	if !b.IsPathAllowed(absPath) {
-		return nil, fmt.Errorf("access denied: path not in allowed list")
+		return nil, ErrAccessDenied
 	}
 
 	info, err := os.Stat(absPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stat file: %w", err)
+		return nil, statError("file", err)
 	}
 
 	if info.IsDir() {
-		return nil, fmt.Errorf("path is a directory")
+		return nil, apierror.Invalid("path is a directory")
 	}
 
 	// Check file size
@@ -212,11 +365,11 @@ func (b *Browser) ReadFile(path string) (*FileContent, error) {
 func (b *Browser) GetDiskUsage(path string) (*DiskUsageInfo, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("invalid path: %w", err)
+		return nil, apierror.Invalid("invalid path: %w", err)
 	}
 
 	if !b.IsPathAllowed(absPath) {
-		return nil, fmt.Errorf("access denied: path not in allowed list")
+		return nil, ErrAccessDenied
 	}
 
 	var totalSize int64
@@ -270,6 +423,21 @@ func (b *Browser) GetDiskUsage(path string) (*DiskUsageInfo, error) {
 	}, nil
 }
 
+// compareEntries orders two entries by the sort key, falling back to name
+func compareEntries(a, b fs.FileInfo, key string) int {
+	switch key {
+	case SortBySize:
+		if c := cmp.Compare(a.Size(), b.Size()); c != 0 {
+			return c
+		}
+	case SortByMtime:
+		if c := a.ModTime().Compare(b.ModTime()); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.Name(), b.Name())
+}
+
 func (b *Browser) getFileInfo(path string) (*FileInfo, error) {
 	info, err := os.Lstat(path)
 	if err != nil {
@@ -287,6 +455,10 @@ func (b *Browser) getFileInfo(path string) (*FileInfo, error) {
 		Permissions: info.Mode().Perm().String(),
 	}
 
+	if info.Mode().IsRegular() {
+		fileInfo.ContentType, fileInfo.Category = DetectContentType(path)
+	}
+
 	// Get symlink target
 	if fileInfo.IsSymlink {
 		target, err := os.Readlink(path)
@@ -0,0 +1,106 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"b.log", "a.log", "c.log", ".hidden"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", (i+1)*10)), 0644))
+		require.NoError(t, os.Chtimes(path, base, base.Add(time.Duration(i)*time.Minute)))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "zdir"), 0755))
+
+	return dir
+}
+
+func names(listing *DirectoryListing) []string {
+	var out []string
+	for _, f := range listing.Files {
+		out = append(out, f.Name)
+	}
+	return out
+}
+
+func TestListDirectory_Pagination(t *testing.T) {
+	dir := newTestDir(t)
+	b := NewBrowser([]string{dir})
+
+	page, err := b.ListDirectory(dir, ListOptions{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zdir", ".hidden"}, names(page))
+	assert.Equal(t, 5, page.Total)
+	assert.True(t, page.Truncated)
+	assert.Equal(t, 2, page.NextOffset)
+
+	page, err = b.ListDirectory(dir, ListOptions{Offset: page.NextOffset, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.log", "b.log"}, names(page))
+
+	page, err = b.ListDirectory(dir, ListOptions{Offset: 4, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c.log"}, names(page))
+	assert.False(t, page.Truncated)
+	assert.Zero(t, page.NextOffset)
+}
+
+func TestListDirectory_SortAndFilter(t *testing.T) {
+	dir := newTestDir(t)
+	b := NewBrowser([]string{dir})
+
+	bySize, err := b.ListDirectory(dir, ListOptions{Sort: SortBySize, Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zdir", ".hidden", "c.log", "a.log", "b.log"}, names(bySize))
+
+	byMtime, err := b.ListDirectory(dir, ListOptions{Sort: SortByMtime})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zdir", "b.log", "a.log", "c.log", ".hidden"}, names(byMtime))
+
+	visible, err := b.ListDirectory(dir, ListOptions{HideHidden: true})
+	require.NoError(t, err)
+	assert.Equal(t, 4, visible.Total)
+	assert.NotContains(t, names(visible), ".hidden")
+
+	_, err = b.ListDirectory(dir, ListOptions{Sort: "owner"})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestIsPathAllowed(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "log")
+	sibling := filepath.Join(parent, "log2")
+	require.NoError(t, os.Mkdir(root, 0755))
+	require.NoError(t, os.Mkdir(sibling, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sibling, "secret"), []byte("x"), 0644))
+	require.NoError(t, os.Symlink(sibling, filepath.Join(root, "out")))
+	require.NoError(t, os.Symlink(root, filepath.Join(parent, "alias")))
+
+	b := NewBrowser([]string{root})
+	assert.True(t, b.IsPathAllowed(root))
+	assert.True(t, b.IsPathAllowed(filepath.Join(root, "syslog")), "paths that do not exist yet")
+	assert.True(t, b.IsPathAllowed(filepath.Join(parent, "alias", "syslog")), "links into the root")
+	assert.False(t, b.IsPathAllowed(sibling), "a shared prefix is not within")
+	assert.False(t, b.IsPathAllowed(filepath.Join(sibling, "secret")))
+	assert.False(t, b.IsPathAllowed(filepath.Join(root, "out", "secret")), "links out of the root")
+	assert.False(t, b.IsPathAllowed(filepath.Join(root, "..", "log2")))
+}
+
+func TestIsWithin(t *testing.T) {
+	assert.True(t, isWithin("/var/log", "/var/log"))
+	assert.True(t, isWithin("/var/log/syslog", "/var/log"))
+	assert.True(t, isWithin("/var/log/..foo", "/var/log"))
+	assert.False(t, isWithin("/var/log2", "/var/log"))
+	assert.False(t, isWithin("/var", "/var/log"))
+}
@@ -0,0 +1,107 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// MaxDiffFileSize is the maximum size of each side of a diff (512KB)
+	MaxDiffFileSize = 512 * 1024
+	// DiffContextLines is the number of unchanged lines around each hunk
+	DiffContextLines = 3
+)
+
+// Diff returns a unified diff between two text files
+func (b *Browser) Diff(pathA, pathB string) (*FileDiff, error) {
+	a, absA, err := b.readDiffSide(pathA)
+	if err != nil {
+		return nil, err
+	}
+	bText, absB, err := b.readDiffSide(pathB)
+	if err != nil {
+		return nil, err
+	}
+
+	return unifiedDiff(absA, absB, a, bText)
+}
+
+// DiffContent returns a unified diff between a text file and supplied content
+func (b *Browser) DiffContent(path, content string) (*FileDiff, error) {
+	if len(content) > MaxDiffFileSize {
+		return nil, apierror.Invalid("content too large to diff (max %d bytes)", MaxDiffFileSize)
+	}
+	if !utf8.ValidString(content) {
+		return nil, apierror.Invalid("content is not valid UTF-8 text")
+	}
+
+	a, absA, err := b.readDiffSide(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return unifiedDiff(absA, "(supplied)", a, content)
+}
+
+func (b *Browser) readDiffSide(path string) (string, string, error) {
+	absPath, info, err := b.statFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	if info.Size() > MaxDiffFileSize {
+		return "", "", apierror.Invalid("%s too large to diff (max %d bytes)", absPath, MaxDiffFileSize)
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if !utf8.Valid(data) {
+		return "", "", apierror.Invalid("%s is not a text file", absPath)
+	}
+
+	return string(data), absPath, nil
+}
+
+func unifiedDiff(nameA, nameB, a, b string) (*FileDiff, error) {
+	result := &FileDiff{
+		A:         nameA,
+		B:         nameB,
+		Identical: a == b,
+	}
+	if result.Identical {
+		return result, nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: nameA,
+		ToFile:   nameB,
+		Context:  DiffContextLines,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute diff: %w", err)
+	}
+	result.Diff = diff
+
+	// Skip the ---/+++ file header when counting changed lines
+	lines := strings.Split(diff, "\n")
+	for _, line := range lines[2:] {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			result.Additions++
+		case strings.HasPrefix(line, "-"):
+			result.Deletions++
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package files
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "nginx.conf")
+	b := filepath.Join(dir, "nginx.conf.good")
+	require.NoError(t, os.WriteFile(a, []byte("worker_processes 4;\nlisten 80;\n"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("worker_processes 2;\nlisten 80;\n"), 0644))
+
+	browser := NewBrowser([]string{dir})
+
+	diff, err := browser.Diff(a, b)
+	require.NoError(t, err)
+	assert.False(t, diff.Identical)
+	assert.Equal(t, 1, diff.Additions)
+	assert.Equal(t, 1, diff.Deletions)
+	assert.Contains(t, diff.Diff, "-worker_processes 4;")
+	assert.Contains(t, diff.Diff, "+worker_processes 2;")
+
+	same, err := browser.DiffContent(a, "worker_processes 4;\nlisten 80;\n")
+	require.NoError(t, err)
+	assert.True(t, same.Identical)
+	assert.Empty(t, same.Diff)
+}
+
+func TestDiff_RejectsBinaryAndDisallowed(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "blob")
+	require.NoError(t, os.WriteFile(bin, []byte{0xff, 0xfe, 0x00}, 0644))
+	text := filepath.Join(dir, "a.conf")
+	require.NoError(t, os.WriteFile(text, []byte("a\n"), 0644))
+
+	browser := NewBrowser([]string{dir})
+
+	_, err := browser.DiffContent(bin, "text")
+	assert.ErrorIs(t, err, apierror.ErrInvalid)
+
+	_, err = browser.Diff(text, "/etc/passwd")
+	assert.ErrorIs(t, err, apierror.ErrNotAllowed)
+	assert.Equal(t, http.StatusForbidden, apierror.Status(err, http.StatusInternalServerError))
+}
+
+func TestDiff_RejectsSpecialFiles(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	text := filepath.Join(dir, "a.conf")
+	require.NoError(t, os.WriteFile(text, []byte("a\n"), 0644))
+	secret := filepath.Join(outside, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("b\n"), 0644))
+
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Symlink(text, link))
+	escape := filepath.Join(dir, "escape")
+	require.NoError(t, os.Symlink(secret, escape))
+	fifo := filepath.Join(dir, "fifo")
+	require.NoError(t, syscall.Mkfifo(fifo, 0644))
+
+	browser := NewBrowser([]string{dir})
+	for _, path := range []string{link, fifo} {
+		_, err := browser.Diff(text, path)
+		assert.ErrorIs(t, err, apierror.ErrInvalid, path)
+	}
+	_, err := browser.Diff(text, escape)
+	assert.ErrorIs(t, err, apierror.ErrNotAllowed)
+}
@@ -0,0 +1,135 @@
+package files
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File categories used by the dashboard to pick a viewer
+const (
+	CategoryText    = "text"
+	CategoryLog     = "log"
+	CategoryImage   = "image"
+	CategoryArchive = "archive"
+	CategoryBinary  = "binary"
+)
+
+// sniffLen is the number of bytes read for content sniffing
+const sniffLen = 512
+
+// textExtensions covers config and script formats missing from most mime.types
+var textExtensions = map[string]string{
+	".conf":    "text/plain",
+	".cfg":     "text/plain",
+	".ini":     "text/plain",
+	".env":     "text/plain",
+	".toml":    "application/toml",
+	".yaml":    "application/yaml",
+	".yml":     "application/yaml",
+	".json":    "application/json",
+	".md":      "text/markdown",
+	".sh":      "application/x-sh",
+	".service": "text/plain",
+	".timer":   "text/plain",
+	".socket":  "text/plain",
+	".log":     "text/plain",
+	".go":      "text/plain",
+	".py":      "text/x-python",
+}
+
+var archiveExtensions = map[string]string{
+	".tar": "application/x-tar",
+	".tgz": "application/gzip",
+	".gz":  "application/gzip",
+	".bz2": "application/x-bzip2",
+	".xz":  "application/x-xz",
+	".zst": "application/zstd",
+	".zip": "application/zip",
+	".7z":  "application/x-7z-compressed",
+	".rar": "application/vnd.rar",
+}
+
+// rotatedLog matches rotated log names such as syslog.1 or app.log.2
+var rotatedLog = regexp.MustCompile(`\.log(\.\d+)?$|\.\d+$`)
+
+// DetectContentType returns the MIME type and category of a regular file.
+// The extension is tried first; unknown extensions are sniffed from content.
+func DetectContentType(path string) (contentType, category string) {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	switch {
+	case archiveExtensions[ext] != "":
+		contentType = archiveExtensions[ext]
+	case textExtensions[ext] != "":
+		contentType = textExtensions[ext]
+	default:
+		contentType = mime.TypeByExtension(ext)
+	}
+
+	if contentType == "" {
+		contentType = sniffContentType(path)
+	}
+	if contentType == "" {
+		// Unreadable and no known extension
+		return "", ""
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+
+	return contentType, classify(path, contentType)
+}
+
+func sniffContentType(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, _ := f.Read(buf)
+	return http.DetectContentType(buf[:n])
+}
+
+func classify(path, contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return CategoryImage
+	case isArchiveType(contentType):
+		return CategoryArchive
+	case isTextType(contentType):
+		if rotatedLog.MatchString(filepath.Base(path)) || strings.HasPrefix(path, "/var/log/") {
+			return CategoryLog
+		}
+		return CategoryText
+	default:
+		return CategoryBinary
+	}
+}
+
+func isArchiveType(contentType string) bool {
+	for _, t := range archiveExtensions {
+		if t == contentType {
+			return true
+		}
+	}
+	return contentType == "application/x-gzip"
+}
+
+func isTextType(contentType string) bool {
+	if strings.HasPrefix(contentType, "text/") {
+		return true
+	}
+	switch contentType {
+	case "application/json", "application/xml", "application/yaml", "application/toml",
+		"application/javascript", "application/x-sh", "image/svg+xml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContentType(t *testing.T) {
+	dir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name        string
+		content     []byte
+		contentType string
+		category    string
+	}{
+		{"nginx.conf", []byte("server {}"), "text/plain", CategoryText},
+		{"app.log", []byte("started"), "text/plain", CategoryLog},
+		{"syslog.1", []byte("Oct 1 boot"), "text/plain", CategoryLog},
+		{"backup.tar.gz", []byte{0x1f, 0x8b}, "application/gzip", CategoryArchive},
+		{"photo", png, "image/png", CategoryImage},
+		{"blob", []byte{0x00, 0x01, 0x02, 0xff}, "application/octet-stream", CategoryBinary},
+		{"README", []byte("plain words"), "text/plain", CategoryText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			require.NoError(t, os.WriteFile(path, tt.content, 0644))
+
+			contentType, category := DetectContentType(path)
+			assert.Equal(t, tt.contentType, contentType)
+			assert.Equal(t, tt.category, category)
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders for thumbnails
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// MaxPreviewImageSize is the largest image file that will be thumbnailed (20MB)
+	MaxPreviewImageSize = 20 * 1024 * 1024
+	// MaxPreviewPixels guards against decompression bombs (50 megapixels)
+	MaxPreviewPixels = 50_000_000
+	// MaxArchivePreviewSize is the largest tar archive that will be listed (512MB)
+	MaxArchivePreviewSize = 512 * 1024 * 1024
+	// MaxArchiveReadSize is the most uncompressed tar data read while listing,
+	// so a small compressed archive cannot keep the agent decompressing (1GB)
+	MaxArchiveReadSize = 1024 * 1024 * 1024
+	// MaxArchiveEntries is the maximum number of archive entries returned
+	MaxArchiveEntries = 1000
+	// DefaultThumbnailSize is the default longest edge of a thumbnail in pixels
+	DefaultThumbnailSize = 256
+	// MaxThumbnailSize is the largest thumbnail edge that can be requested
+	MaxThumbnailSize = 1024
+)
+
+// archiveReadLimit is MaxArchiveReadSize, lowered in tests
+var archiveReadLimit int64 = MaxArchiveReadSize
+
+// ErrPreviewUnsupported is returned for files that cannot be previewed
+var ErrPreviewUnsupported = apierror.Unsupported("preview not supported for this file type")
+
+// Thumbnail returns a JPEG thumbnail of an image whose longest edge is at most size pixels
+func (b *Browser) Thumbnail(path string, size int) ([]byte, error) {
+	absPath, info, err := b.statFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() > MaxPreviewImageSize {
+		return nil, apierror.Unsupported("image too large to preview (max %d bytes)", MaxPreviewImageSize)
+	}
+	if size <= 0 {
+		size = DefaultThumbnailSize
+	}
+	if size > MaxThumbnailSize {
+		size = MaxThumbnailSize
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, ErrPreviewUnsupported
+	}
+	if cfg.Width*cfg.Height > MaxPreviewPixels {
+		return nil, apierror.Unsupported("image dimensions too large to preview (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			height = max(1, height*size/width)
+			width = size
+		} else {
+			width = max(1, width*size/height)
+			height = size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ListArchive lists the contents of a zip or tar (optionally gzip/bzip2
+// compressed) archive without extracting it
+func (b *Browser) ListArchive(path string) (*ArchiveListing, error) {
+	absPath, info, err := b.statFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(filepath.Base(absPath))
+	if strings.HasSuffix(name, ".zip") {
+		return listZip(absPath, info.Size())
+	}
+
+	if info.Size() > MaxArchivePreviewSize {
+		return nil, apierror.Unsupported("archive too large to preview (max %d bytes)", MaxArchivePreviewSize)
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	format := "tar"
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip archive: %w", err)
+		}
+		defer gz.Close()
+		r, format = gz, "tar.gz"
+	case strings.HasSuffix(name, ".tar.bz2"), strings.HasSuffix(name, ".tbz2"):
+		r, format = bzip2.NewReader(f), "tar.bz2"
+	case strings.HasSuffix(name, ".tar"):
+	default:
+		return nil, ErrPreviewUnsupported
+	}
+
+	listing := &ArchiveListing{
+		Path:    absPath,
+		Format:  format,
+		Entries: []ArchiveEntry{},
+	}
+
+	// Skipping an entry decompresses its data, so the listing stops once
+	// archiveReadLimit bytes have been read
+	limited := &io.LimitedReader{R: r, N: archiveReadLimit}
+	tr := tar.NewReader(limited)
+	for {
+		hdr, err := tr.Next()
+		if limited.N <= 0 {
+			listing.Truncated = true
+			break
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		listing.Total++
+		if len(listing.Entries) >= MaxArchiveEntries {
+			// Stop early rather than decompressing the rest of the archive
+			listing.Truncated = true
+			break
+		}
+		listing.Entries = append(listing.Entries, ArchiveEntry{
+			Name:    hdr.Name,
+			Size:    hdr.Size,
+			Mode:    hdr.FileInfo().Mode().String(),
+			ModTime: hdr.ModTime,
+			IsDir:   hdr.Typeflag == tar.TypeDir,
+		})
+	}
+
+	return listing, nil
+}
+
+func listZip(path string, size int64) (*ArchiveListing, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	// Zip archives keep a central directory, so listing does not read file data
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	listing := &ArchiveListing{
+		Path:    path,
+		Format:  "zip",
+		Entries: []ArchiveEntry{},
+		Total:   len(zr.File),
+	}
+
+	for _, file := range zr.File {
+		if len(listing.Entries) >= MaxArchiveEntries {
+			listing.Truncated = true
+			break
+		}
+		listing.Entries = append(listing.Entries, ArchiveEntry{
+			Name:           file.Name,
+			Size:           int64(file.UncompressedSize64),
+			CompressedSize: int64(file.CompressedSize64),
+			Mode:           file.Mode().String(),
+			ModTime:        file.Modified,
+			IsDir:          file.FileInfo().IsDir(),
+		})
+	}
+
+	return listing, nil
+}
+
+// statFile resolves an allowed regular file path
+func (b *Browser) statFile(path string) (string, os.FileInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", nil, apierror.Invalid("invalid path: %w", err)
+	}
+
+	if !b.IsPathAllowed(absPath) {
+		return "", nil, ErrAccessDenied
+	}
+
+	// Lstat, so a symlink cannot point the read outside the allowed paths,
+	// and a FIFO or device cannot block or stream forever
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return "", nil, statError("file", err)
+	}
+
+	if info.IsDir() {
+		return "", nil, apierror.Invalid("path is a directory")
+	}
+	if !info.Mode().IsRegular() {
+		return "", nil, apierror.Invalid("path is not a regular file")
+	}
+
+	return absPath, info, nil
+}
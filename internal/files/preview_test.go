@@ -0,0 +1,124 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.png")
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	b := NewBrowser([]string{dir})
+	thumb, err := b.Thumbnail(path, 100)
+	require.NoError(t, err)
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 100, cfg.Width)
+	assert.Equal(t, 50, cfg.Height)
+}
+
+func TestThumbnail_NotAnImage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	_, err := NewBrowser([]string{dir}).Thumbnail(path, 0)
+	assert.ErrorIs(t, err, ErrPreviewUnsupported)
+}
+
+func TestListArchive_TarGz(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "backup.tar.gz")
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "etc/hosts", Mode: 0644, Size: 5}))
+	_, err := tw.Write([]byte("hosts"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	listing, err := NewBrowser([]string{dir}).ListArchive(path)
+	require.NoError(t, err)
+	assert.Equal(t, "tar.gz", listing.Format)
+	assert.Equal(t, 2, listing.Total)
+	require.Len(t, listing.Entries, 2)
+	assert.True(t, listing.Entries[0].IsDir)
+	assert.Equal(t, "etc/hosts", listing.Entries[1].Name)
+	assert.Equal(t, int64(5), listing.Entries[1].Size)
+}
+
+func TestListArchive_ReadLimit(t *testing.T) {
+	archiveReadLimit = 64 * 1024
+	defer func() { archiveReadLimit = MaxArchiveReadSize }()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bomb.tar.gz")
+
+	// Zeros compress to almost nothing, but each entry must be decompressed
+	// to reach the next header
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	zeros := make([]byte, 32*1024)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(zeros))}))
+		_, err := tw.Write(zeros)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	listing, err := NewBrowser([]string{dir}).ListArchive(path)
+	require.NoError(t, err)
+	assert.True(t, listing.Truncated)
+	assert.Less(t, len(listing.Entries), 4)
+	assert.Equal(t, "a", listing.Entries[0].Name)
+}
+
+func TestListArchive_Zip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "site.zip")
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("index.html")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("<html></html>"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	listing, err := NewBrowser([]string{dir}).ListArchive(path)
+	require.NoError(t, err)
+	assert.Equal(t, "zip", listing.Format)
+	require.Len(t, listing.Entries, 1)
+	assert.Equal(t, "index.html", listing.Entries[0].Name)
+	assert.Equal(t, int64(13), listing.Entries[0].Size)
+}
@@ -0,0 +1,372 @@
+package files
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// DefaultTrashRetention is how long deleted files are kept before purging
+const DefaultTrashRetention = 7 * 24 * time.Hour
+
+// Trash errors
+var (
+	ErrTrashItemNotFound = apierror.NotFound("trash item not found")
+	ErrRestoreConflict   = apierror.Conflict("a file already exists at the original path")
+)
+
+// Trash holds deleted files in an agent-managed directory until they are
+// restored, purged, or expire
+type Trash struct {
+	dir       string
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+// NewTrash creates a trash rooted at dir
+func NewTrash(dir string, retention time.Duration) *Trash {
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	return &Trash{
+		dir:       dir,
+		retention: retention,
+	}
+}
+
+// Put moves path into the trash
+func (t *Trash) Put(path string) (*TrashItem, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.purgeExpired()
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, statError("path", err)
+	}
+
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	now := time.Now()
+	item := &TrashItem{
+		ID:           newTrashID(),
+		Name:         info.Name(),
+		OriginalPath: path,
+		IsDir:        info.IsDir(),
+		Size:         pathSize(path),
+		DeletedAt:    now,
+		ExpiresAt:    now.Add(t.retention),
+	}
+
+	if err := movePath(path, t.dataPath(item.ID)); err != nil {
+		return nil, fmt.Errorf("failed to move to trash: %w", err)
+	}
+
+	if err := t.writeMeta(item); err != nil {
+		// Put the file back rather than leaving it untracked in the trash
+		_ = movePath(t.dataPath(item.ID), path)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// List returns trashed items, most recently deleted first
+func (t *Trash) List() (*TrashList, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.purgeExpired()
+
+	items, err := t.items()
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	for _, item := range items {
+		size += item.Size
+	}
+
+	return &TrashList{
+		Items:     items,
+		Total:     len(items),
+		TotalSize: size,
+		Retention: t.retention.String(),
+	}, nil
+}
+
+// Get returns a single trashed item
+func (t *Trash) Get(id string) (*TrashItem, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.readMeta(id)
+}
+
+// Restore moves an item back to its original path
+func (t *Trash) Restore(id string) (*TrashItem, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	item, err := t.readMeta(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Lstat(item.OriginalPath); err == nil {
+		return nil, ErrRestoreConflict
+	}
+
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to recreate parent directory: %w", err)
+	}
+
+	if err := movePath(t.dataPath(id), item.OriginalPath); err != nil {
+		return nil, fmt.Errorf("failed to restore: %w", err)
+	}
+
+	_ = os.Remove(t.metaPath(id))
+	return item, nil
+}
+
+// Purge permanently deletes an item
+func (t *Trash) Purge(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := t.readMeta(id); err != nil {
+		return err
+	}
+	return t.remove(id)
+}
+
+// PurgeAll permanently deletes every item and returns how many were removed
+func (t *Trash) PurgeAll() (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	items, err := t.items()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range items {
+		if err := t.remove(item.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(items), nil
+}
+
+// PurgeExpired deletes items past the retention period and returns how
+// many were removed
+func (t *Trash) PurgeExpired() (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.purgeExpired()
+}
+
+func (t *Trash) purgeExpired() (int, error) {
+	items, err := t.items()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	removed := 0
+	for _, item := range items {
+		if !now.After(item.ExpiresAt) {
+			continue
+		}
+		if rmErr := t.remove(item.ID); rmErr != nil {
+			err = rmErr
+			continue
+		}
+		removed++
+	}
+	return removed, err
+}
+
+func (t *Trash) items() ([]TrashItem, error) {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []TrashItem{}, nil
+		}
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	items := []TrashItem{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		item, err := t.readMeta(id)
+		if err != nil {
+			continue
+		}
+		items = append(items, *item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+func (t *Trash) remove(id string) error {
+	if err := os.RemoveAll(t.dataPath(id)); err != nil {
+		return fmt.Errorf("failed to purge: %w", err)
+	}
+	return os.Remove(t.metaPath(id))
+}
+
+func (t *Trash) readMeta(id string) (*TrashItem, error) {
+	// IDs are hex; reject anything that could escape the trash directory
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, ErrTrashItemNotFound
+	}
+
+	data, err := os.ReadFile(t.metaPath(id))
+	if err != nil {
+		return nil, ErrTrashItemNotFound
+	}
+
+	var item TrashItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("corrupt trash metadata: %w", err)
+	}
+	return &item, nil
+}
+
+func (t *Trash) writeMeta(item *TrashItem) error {
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(t.metaPath(item.ID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write trash metadata: %w", err)
+	}
+	return nil
+}
+
+func (t *Trash) dataPath(id string) string {
+	return filepath.Join(t.dir, id)
+}
+
+func (t *Trash) metaPath(id string) string {
+	return filepath.Join(t.dir, id+".json")
+}
+
+func newTrashID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// movePath renames src to dst, copying across filesystems when needed
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) || !errors.Is(linkErr.Err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyPath(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+func copyPath(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, devices and pipes cannot be copied meaningfully
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func pathSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
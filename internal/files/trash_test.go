@@ -0,0 +1,92 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTrashBrowser(t *testing.T, retention time.Duration) (*Browser, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	b := NewBrowser([]string{root})
+	b.SetTrash(NewTrash(filepath.Join(t.TempDir(), "trash"), retention))
+	return b, root
+}
+
+func TestTrash_DeleteAndRestore(t *testing.T) {
+	b, root := newTrashBrowser(t, time.Hour)
+	path := filepath.Join(root, "app.conf")
+	require.NoError(t, os.WriteFile(path, []byte("listen 80"), 0644))
+
+	item, err := b.Delete(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, item.OriginalPath)
+	assert.Equal(t, int64(9), item.Size)
+	assert.NoFileExists(t, path)
+
+	list, err := b.Trash().List()
+	require.NoError(t, err)
+	require.Equal(t, 1, list.Total)
+	assert.Equal(t, item.ID, list.Items[0].ID)
+
+	// Restoring over a file that has since been recreated is refused
+	require.NoError(t, os.WriteFile(path, []byte("new"), 0644))
+	_, err = b.Restore(item.ID)
+	assert.ErrorIs(t, err, ErrRestoreConflict)
+	require.NoError(t, os.Remove(path))
+
+	_, err = b.Restore(item.ID)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "listen 80", string(data))
+
+	list, err = b.Trash().List()
+	require.NoError(t, err)
+	assert.Zero(t, list.Total)
+}
+
+func TestTrash_PurgeAndExpiry(t *testing.T) {
+	b, root := newTrashBrowser(t, time.Millisecond)
+	dir := filepath.Join(root, "old-logs")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.log"), []byte("a"), 0644))
+
+	item, err := b.Delete(dir)
+	require.NoError(t, err)
+	assert.True(t, item.IsDir)
+
+	time.Sleep(5 * time.Millisecond)
+	list, err := b.Trash().List()
+	require.NoError(t, err)
+	assert.Zero(t, list.Total, "expired items are purged")
+
+	assert.ErrorIs(t, b.Trash().Purge(item.ID), ErrTrashItemNotFound)
+	assert.ErrorIs(t, b.Trash().Purge("../etc"), ErrTrashItemNotFound)
+}
+
+func TestTrash_DeleteGuards(t *testing.T) {
+	b, root := newTrashBrowser(t, time.Hour)
+
+	_, err := b.Delete(root)
+	assert.Error(t, err, "allowed roots cannot be deleted")
+
+	_, err = b.Delete("/etc/hostname")
+	assert.Error(t, err)
+
+	// The trash cannot be deleted through a link to it either
+	require.NoError(t, os.MkdirAll(b.trash.dir, 0700))
+	require.NoError(t, os.Symlink(b.trash.dir, filepath.Join(root, "trash")))
+	_, err = b.Delete(filepath.Join(root, "trash", "x"))
+	assert.Error(t, err)
+
+	readOnly := NewBrowser([]string{root})
+	_, err = readOnly.Delete(filepath.Join(root, "x"))
+	assert.ErrorIs(t, err, ErrDeleteDisabled)
+}
@@ -15,14 +15,37 @@ type FileInfo struct {
 	Owner       string    `json:"owner"`
 	Group       string    `json:"group"`
 	Permissions string    `json:"permissions"`
+	ContentType string    `json:"content_type,omitempty"`
+	Category    string    `json:"category,omitempty"` // text, log, image, archive or binary
 }
 
 // DirectoryListing represents a directory and its contents
 type DirectoryListing struct {
 	Path    string     `json:"path"`
 	Files   []FileInfo `json:"files"`
-	Total   int        `json:"total"`
+	Total   int        `json:"total"` // Entries matching the filters, across all pages
 	CanRead bool       `json:"can_read"`
+	Offset  int        `json:"offset"`
+	Limit   int        `json:"limit"`
+	// Truncated is set when more entries follow this page; fetch them with NextOffset
+	Truncated  bool `json:"truncated"`
+	NextOffset int  `json:"next_offset,omitempty"`
+}
+
+// Sort keys for directory listings
+const (
+	SortByName  = "name"
+	SortBySize  = "size"
+	SortByMtime = "mtime"
+)
+
+// ListOptions controls paging, ordering and filtering of directory listings
+type ListOptions struct {
+	Offset     int
+	Limit      int    // Defaults to MaxDirEntries
+	Sort       string // name (default), size or mtime
+	Desc       bool
+	HideHidden bool
 }
 
 // FileContent represents the content of a file
@@ -35,6 +58,54 @@ type FileContent struct {
 	Truncated bool  `json:"truncated"`
 }
 
+// ArchiveEntry represents a single file inside an archive
+type ArchiveEntry struct {
+	Name           string    `json:"name"`
+	Size           int64     `json:"size"`
+	CompressedSize int64     `json:"compressed_size,omitempty"`
+	Mode           string    `json:"mode"`
+	ModTime        time.Time `json:"mod_time"`
+	IsDir          bool      `json:"is_dir"`
+}
+
+// ArchiveListing represents the contents of an archive
+type ArchiveListing struct {
+	Path      string         `json:"path"`
+	Format    string         `json:"format"` // zip, tar, tar.gz or tar.bz2
+	Entries   []ArchiveEntry `json:"entries"`
+	Total     int            `json:"total"`
+	Truncated bool           `json:"truncated"`
+}
+
+// FileDiff represents a unified diff between two files
+type FileDiff struct {
+	A         string `json:"a"`
+	B         string `json:"b"`
+	Diff      string `json:"diff"`
+	Identical bool   `json:"identical"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// TrashItem represents a deleted file or directory held in the trash
+type TrashItem struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	OriginalPath string    `json:"original_path"`
+	IsDir        bool      `json:"is_dir"`
+	Size         int64     `json:"size"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// TrashList contains the items in the trash
+type TrashList struct {
+	Items     []TrashItem `json:"items"`
+	Total     int         `json:"total"`
+	TotalSize int64       `json:"total_size"`
+	Retention string      `json:"retention"`
+}
+
 // DiskUsageInfo represents disk usage for a path
 type DiskUsageInfo struct {
 	Path       string `json:"path"`
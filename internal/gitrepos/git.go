@@ -0,0 +1,272 @@
+package gitrepos
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// statusTimeout bounds reading one checkout, fetchTimeout a fetch
+	statusTimeout = 10 * time.Second
+	fetchTimeout  = time.Minute
+
+	// PullTimeout bounds a pull job
+	PullTimeout = 5 * time.Minute
+)
+
+// runGit runs git in dir and returns its stdout; replaced in tests
+var runGit = defaultRunGit
+
+func defaultRunGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	if err := checkOwner(dir); err != nil {
+		return nil, err
+	}
+
+	// A prompt for credentials would hang until the timeout
+	sub := args[0]
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "LC_ALL=C")
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", sub, lastLine(msg))
+		}
+		return nil, fmt.Errorf("git %s: %w", sub, err)
+	}
+	return out, nil
+}
+
+// checkOwner refuses a checkout the agent does not own. Its owner controls
+// .git/config and hooks, which git runs as the agent.
+func checkOwner(dir string) error {
+	for _, path := range []string{dir, filepath.Join(dir, ".git")} {
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) && path != dir {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read checkout: %w", err)
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Geteuid() {
+			return apierror.NotAllowed("%s is owned by uid %d, not the agent", path, st.Uid)
+		}
+	}
+	return nil
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// ParseRepos builds repositories from name=path pairs. Names in pull may
+// be pulled.
+func ParseRepos(repos map[string]string, pull []string) ([]Repo, error) {
+	result := make([]Repo, 0, len(repos))
+	for name, path := range repos {
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("repository %s: path must be absolute", name)
+		}
+		result = append(result, Repo{Name: name, Path: filepath.Clean(path)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	for _, name := range pull {
+		found := false
+		for i := range result {
+			if result[i].Name == name || name == "*" {
+				result[i].Pull, found = true, true
+			}
+		}
+		if !found && name != "*" {
+			return nil, fmt.Errorf("GIT_PULL_ALLOWED names unknown repository %s", name)
+		}
+	}
+	return result, nil
+}
+
+// Manager reads and pulls configured checkouts
+type Manager struct {
+	repos []Repo
+}
+
+// NewManager creates a manager for repos
+func NewManager(repos []Repo) *Manager {
+	return &Manager{repos: repos}
+}
+
+// Status returns every repository's status, fetching first when fetch is
+// set. Failures are reported per repository.
+func (m *Manager) Status(ctx context.Context, fetch bool) []Status {
+	statuses := make([]Status, 0, len(m.repos))
+	for _, repo := range m.repos {
+		statuses = append(statuses, m.status(ctx, repo, fetch))
+	}
+	return statuses
+}
+
+// Get returns one repository's status
+func (m *Manager) Get(ctx context.Context, name string, fetch bool) (*Status, error) {
+	repo, ok := m.repo(name)
+	if !ok {
+		return nil, apierror.NotFound("git repository %q not configured", name)
+	}
+	status := m.status(ctx, repo, fetch)
+	return &status, nil
+}
+
+// CheckPull returns an error unless name may be pulled
+func (m *Manager) CheckPull(name string) error {
+	repo, ok := m.repo(name)
+	if !ok {
+		return apierror.NotFound("git repository %q not configured", name)
+	}
+	if !repo.Pull {
+		return apierror.NotAllowed("pulling %s is not allowed", name).WithDetail("hint", "add it to GIT_PULL_ALLOWED")
+	}
+	return nil
+}
+
+// Pull fast-forwards a repository from its upstream. A pull that would
+// need a merge fails and leaves the checkout as it was.
+func (m *Manager) Pull(ctx context.Context, name string) (*PullResult, error) {
+	if err := m.CheckPull(name); err != nil {
+		return nil, err
+	}
+	repo, _ := m.repo(name)
+
+	before, err := head(ctx, repo.Path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runGit(ctx, repo.Path, "pull", "--ff-only", "--no-rebase")
+	if err != nil {
+		return nil, err
+	}
+	after, err := head(ctx, repo.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PullResult{
+		Name:    name,
+		Before:  before,
+		After:   after,
+		Updated: before != after,
+		Output:  strings.TrimSpace(string(out)),
+	}, nil
+}
+
+func (m *Manager) repo(name string) (Repo, bool) {
+	for _, repo := range m.repos {
+		if repo.Name == name {
+			return repo, true
+		}
+	}
+	return Repo{}, false
+}
+
+func (m *Manager) status(ctx context.Context, repo Repo, fetch bool) Status {
+	status := Status{Name: repo.Name, Path: repo.Path, Pullable: repo.Pull, CheckedAt: time.Now().UTC()}
+
+	var fetchErr error
+	if fetch {
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+		_, fetchErr = runGit(fetchCtx, repo.Path, "fetch", "--quiet")
+		cancel()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	out, err := runGit(ctx, repo.Path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	parseStatus(out, &status)
+
+	if status.Commit != "" {
+		out, err := runGit(ctx, repo.Path, "log", "-1", "--format=%s%x00%an%x00%cI")
+		if err != nil {
+			status.Error = err.Error()
+			return status
+		}
+		parseCommit(out, &status)
+	}
+
+	if fetchErr != nil {
+		status.Error = fetchErr.Error()
+	}
+	return status
+}
+
+// parseStatus reads the output of git status --porcelain=v2 --branch
+func parseStatus(data []byte, status *Status) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				status.Commit = oid
+			}
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head == "(detached)" {
+				status.Detached = true
+			} else {
+				status.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(line, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "), strings.HasPrefix(line, "u "):
+			status.Changed++
+		case strings.HasPrefix(line, "? "):
+			status.Untracked++
+		}
+	}
+	status.Dirty = status.Changed > 0 || status.Untracked > 0
+}
+
+// parseCommit reads subject, author and commit time separated by NULs
+func parseCommit(data []byte, status *Status) {
+	fields := strings.SplitN(strings.TrimSuffix(string(data), "\n"), "\x00", 3)
+	if len(fields) != 3 {
+		return
+	}
+	status.Subject, status.Author = fields[0], fields[1]
+	if t, err := time.Parse(time.RFC3339, fields[2]); err == nil {
+		status.CommitTime = &t
+	}
+}
+
+func head(ctx context.Context, dir string) (string, error) {
+	out, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,138 @@
+package gitrepos
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const porcelain = `# branch.oid 4b825dc642cb6eb9a060e54bf8d69288fbee4904
+# branch.head main
+# branch.upstream origin/main
+# branch.ab +1 -3
+1 .M N... 100644 100644 100644 aaa bbb README.md
+2 R. N... 100644 100644 100644 ccc ccc R100 new.go	old.go
+? notes.txt
+`
+
+func TestParseRepos(t *testing.T) {
+	repos, err := ParseRepos(map[string]string{"web": "/srv/web/", "blog": "/srv/blog"}, []string{"web"})
+	require.NoError(t, err)
+	assert.Equal(t, []Repo{{Name: "blog", Path: "/srv/blog"}, {Name: "web", Path: "/srv/web", Pull: true}}, repos)
+
+	repos, err = ParseRepos(map[string]string{"web": "/srv/web", "blog": "/srv/blog"}, []string{"*"})
+	require.NoError(t, err)
+	assert.True(t, repos[0].Pull && repos[1].Pull)
+
+	_, err = ParseRepos(map[string]string{"web": "srv/web"}, nil)
+	assert.Error(t, err)
+	_, err = ParseRepos(map[string]string{"web": "/srv/web"}, []string{"api"})
+	assert.Error(t, err)
+}
+
+func TestParseStatus(t *testing.T) {
+	var status Status
+	parseStatus([]byte(porcelain), &status)
+
+	assert.Equal(t, "main", status.Branch)
+	assert.False(t, status.Detached)
+	assert.Equal(t, "4b825dc642cb6eb9a060e54bf8d69288fbee4904", status.Commit)
+	assert.Equal(t, "origin/main", status.Upstream)
+	assert.Equal(t, 1, status.Ahead)
+	assert.Equal(t, 3, status.Behind)
+	assert.Equal(t, 2, status.Changed)
+	assert.Equal(t, 1, status.Untracked)
+	assert.True(t, status.Dirty)
+
+	status = Status{}
+	parseStatus([]byte("# branch.oid (initial)\n# branch.head (detached)\n"), &status)
+	assert.True(t, status.Detached)
+	assert.Empty(t, status.Commit)
+	assert.False(t, status.Dirty)
+}
+
+func TestStatus(t *testing.T) {
+	orig := runGit
+	defer func() { runGit = orig }()
+
+	var calls []string
+	runGit = func(ctx context.Context, dir string, args ...string) ([]byte, error) {
+		calls = append(calls, args[0])
+		switch {
+		case dir == "/srv/broken":
+			return nil, errors.New("git status: fatal: not a git repository")
+		case args[0] == "status":
+			return []byte(porcelain), nil
+		case args[0] == "log":
+			return []byte("Fix the build\x00Jane\x002024-03-01T10:00:00+01:00\n"), nil
+		}
+		return nil, nil
+	}
+
+	repos, _ := ParseRepos(map[string]string{"web": "/srv/web", "broken": "/srv/broken"}, nil)
+	statuses := NewManager(repos).Status(context.Background(), true)
+	require.Len(t, statuses, 2)
+
+	assert.Equal(t, "broken", statuses[0].Name)
+	assert.Contains(t, statuses[0].Error, "not a git repository")
+
+	web := statuses[1]
+	assert.Empty(t, web.Error)
+	assert.Equal(t, "Fix the build", web.Subject)
+	assert.Equal(t, "Jane", web.Author)
+	require.NotNil(t, web.CommitTime)
+	assert.True(t, web.CommitTime.Equal(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []string{"fetch", "status", "fetch", "status", "log"}, calls)
+}
+
+func TestPull(t *testing.T) {
+	orig := runGit
+	defer func() { runGit = orig }()
+
+	heads := []string{"aaa", "bbb"}
+	runGit = func(ctx context.Context, dir string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "rev-parse":
+			h := heads[0]
+			heads = heads[1:]
+			return []byte(h + "\n"), nil
+		case "pull":
+			assert.Equal(t, []string{"pull", "--ff-only", "--no-rebase"}, args)
+			return []byte("Fast-forward\n README.md | 2 +-\n"), nil
+		}
+		return nil, nil
+	}
+
+	repos, _ := ParseRepos(map[string]string{"web": "/srv/web", "blog": "/srv/blog"}, []string{"web"})
+	m := NewManager(repos)
+
+	result, err := m.Pull(context.Background(), "web")
+	require.NoError(t, err)
+	assert.Equal(t, &PullResult{Name: "web", Before: "aaa", After: "bbb", Updated: true, Output: "Fast-forward\n README.md | 2 +-"}, result)
+
+	_, err = m.Pull(context.Background(), "blog")
+	assert.ErrorIs(t, err, apierror.ErrNotAllowed)
+
+	_, err = m.Get(context.Background(), "missing", false)
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+}
+
+func TestCheckOwner(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0o755))
+	assert.NoError(t, checkOwner(dir))
+
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership needs root")
+	}
+	require.NoError(t, os.Chown(filepath.Join(dir, ".git"), 65534, 65534))
+	assert.ErrorIs(t, checkOwner(dir), apierror.ErrNotAllowed)
+}
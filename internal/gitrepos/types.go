@@ -0,0 +1,40 @@
+package gitrepos
+
+import "time"
+
+// Repo is a configured git checkout
+type Repo struct {
+	Name string
+	Path string
+	Pull bool // POST /api/git/:name/pull may update it
+}
+
+// Status describes a checkout's branch and working tree
+type Status struct {
+	Name       string     `json:"name"`
+	Path       string     `json:"path"`
+	Branch     string     `json:"branch,omitempty"` // Empty when detached
+	Detached   bool       `json:"detached"`
+	Commit     string     `json:"commit,omitempty"`
+	Subject    string     `json:"subject,omitempty"`
+	Author     string     `json:"author,omitempty"`
+	CommitTime *time.Time `json:"commit_time,omitempty"`
+	Upstream   string     `json:"upstream,omitempty"`
+	Ahead      int        `json:"ahead"`  // Commits not on the upstream
+	Behind     int        `json:"behind"` // Upstream commits not checked out, as of the last fetch
+	Dirty      bool       `json:"dirty"`
+	Changed    int        `json:"changed"` // Modified, staged or conflicted files
+	Untracked  int        `json:"untracked"`
+	Pullable   bool       `json:"pullable"`
+	Error      string     `json:"error,omitempty"`
+	CheckedAt  time.Time  `json:"checked_at"`
+}
+
+// PullResult is the outcome of a pull
+type PullResult struct {
+	Name    string `json:"name"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Updated bool   `json:"updated"`
+	Output  string `json:"output,omitempty"`
+}
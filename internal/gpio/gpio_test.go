@@ -0,0 +1,78 @@
+package gpio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const pinctrlOutput = ` 0: ip    pu | hi // ID_SDA/GPIO0 = input
+14: a0    pn | hi // TXD0/GPIO14 = TXD0
+17: ip    pu | lo // GPIO17 = input
+27: op dh pd | hi // GPIO27 = output
+`
+
+func TestParsePinctrl(t *testing.T) {
+	pins := parsePinctrl(pinctrlOutput)
+	require.Len(t, pins, 4)
+	assert.Equal(t, Pin{Pin: 14, Name: "TXD0/GPIO14", Function: "TXD0", Pull: "none", Level: LevelHigh}, pins[1])
+	assert.Equal(t, Pin{Pin: 27, Name: "GPIO27", Function: "output", Pull: "down", Level: LevelHigh}, pins[3])
+}
+
+func TestParseRaspiGPIO(t *testing.T) {
+	pins := parseRaspiGPIO(`BANK0 (GPIO 0 to 27):
+GPIO 0: level=1 fsel=0 func=INPUT pull=UP
+GPIO 14: level=1 fsel=4 alt=0 func=TXD0 pull=NONE
+GPIO 27: level=0 fsel=1 func=OUTPUT
+`)
+	require.Len(t, pins, 3)
+	assert.Equal(t, Pin{Pin: 0, Name: "GPIO0", Function: "input", Pull: "up", Level: LevelHigh}, pins[0])
+	assert.Equal(t, "TXD0", pins[1].Function)
+	assert.Equal(t, Pin{Pin: 27, Name: "GPIO27", Function: "output", Level: LevelLow}, pins[2])
+}
+
+func TestNewReader_InvalidInputs(t *testing.T) {
+	_, err := NewReader(map[string]string{"door": "abc"})
+	assert.Error(t, err)
+	_, err = NewReader(map[string]string{"door": "17:sideways"})
+	assert.Error(t, err)
+}
+
+func TestReader_Read(t *testing.T) {
+	origRun, origLook := runCommand, lookPath
+	defer func() { runCommand, lookPath = origRun, origLook }()
+
+	lookPath = func(file string) (string, error) {
+		if file == ToolPinctrl {
+			return "/usr/bin/pinctrl", nil
+		}
+		return "", errors.New("not found")
+	}
+	runCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		assert.Equal(t, []string{"get"}, args)
+		return []byte(pinctrlOutput), nil
+	}
+
+	r, err := NewReader(map[string]string{"door": "17:low", "relay": "27", "missing": "5"})
+	require.NoError(t, err)
+
+	state, err := r.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ToolPinctrl, state.Tool)
+	assert.Len(t, state.Pins, 4)
+	assert.Equal(t, []Input{
+		{Name: "door", Pin: 17, Level: LevelLow, Active: true, ActiveLow: true},
+		{Name: "missing", Pin: 5},
+		{Name: "relay", Pin: 27, Level: LevelHigh, Active: true},
+	}, state.Inputs)
+
+	// No tool installed
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	_, err = r.Read(context.Background())
+	assert.True(t, errors.Is(err, apierror.ErrUnavailable))
+}
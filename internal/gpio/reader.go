@@ -0,0 +1,178 @@
+package gpio
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// runCommand runs a command and returns its output; replaced in tests
+var runCommand = defaultRunCommand
+
+func defaultRunCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// lookPath finds a tool; replaced in tests
+var lookPath = exec.LookPath
+
+// Reader reads pin states. It never changes them.
+type Reader struct {
+	inputs []Input
+}
+
+// NewReader creates a reader with named inputs given as name=pin, or
+// name=pin:low for inputs that are active when low
+func NewReader(inputs map[string]string) (*Reader, error) {
+	r := &Reader{}
+	for name, spec := range inputs {
+		pinStr, mode, _ := strings.Cut(spec, ":")
+		pin, err := strconv.Atoi(strings.TrimSpace(pinStr))
+		if err != nil || pin < 0 {
+			return nil, fmt.Errorf("GPIO input %s: invalid pin %q", name, pinStr)
+		}
+		if mode != "" && mode != "low" && mode != "high" {
+			return nil, fmt.Errorf("GPIO input %s: active level must be high or low, got %q", name, mode)
+		}
+		r.inputs = append(r.inputs, Input{Name: name, Pin: pin, ActiveLow: mode == "low"})
+	}
+	sort.Slice(r.inputs, func(i, j int) bool { return r.inputs[i].Name < r.inputs[j].Name })
+	return r, nil
+}
+
+// Tool returns the installed tool used to read the pins, or ""
+func (r *Reader) Tool() string {
+	for _, tool := range []string{ToolPinctrl, ToolRaspiGPIO} {
+		if _, err := lookPath(tool); err == nil {
+			return tool
+		}
+	}
+	return ""
+}
+
+// Read returns the state of every pin and of the named inputs
+func (r *Reader) Read(ctx context.Context) (*State, error) {
+	tool := r.Tool()
+	if tool == "" {
+		return nil, apierror.Unavailable("no GPIO tool found (install pinctrl or raspi-gpio)")
+	}
+
+	out, err := runCommand(ctx, tool, "get")
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{Tool: tool}
+	if tool == ToolPinctrl {
+		state.Pins = parsePinctrl(string(out))
+	} else {
+		state.Pins = parseRaspiGPIO(string(out))
+	}
+
+	levels := make(map[int]string, len(state.Pins))
+	for _, p := range state.Pins {
+		levels[p.Pin] = p.Level
+	}
+	state.Inputs = make([]Input, 0, len(r.inputs))
+	for _, in := range r.inputs {
+		in.Level = levels[in.Pin]
+		if in.ActiveLow {
+			in.Active = in.Level == LevelLow
+		} else {
+			in.Active = in.Level == LevelHigh
+		}
+		state.Inputs = append(state.Inputs, in)
+	}
+	return state, nil
+}
+
+// parsePinctrl parses `pinctrl get` lines such as
+//
+//	14: a0    pn | hi // TXD0/GPIO14 = TXD0
+//	17: op dh pd | lo // GPIO17 = output
+func parsePinctrl(out string) []Pin {
+	pulls := map[string]string{"pu": "up", "pd": "down", "pn": "none"}
+	levels := map[string]string{"hi": LevelHigh, "lo": LevelLow}
+
+	var pins []Pin
+	for _, line := range strings.Split(out, "\n") {
+		left, right, ok := strings.Cut(line, "//")
+		if !ok {
+			continue
+		}
+		num, rest, ok := strings.Cut(left, ":")
+		if !ok {
+			continue
+		}
+		pin, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil {
+			continue
+		}
+		settings, level, _ := strings.Cut(rest, "|")
+		name, function, _ := strings.Cut(right, "=")
+
+		p := Pin{
+			Pin:      pin,
+			Name:     strings.TrimSpace(name),
+			Function: strings.TrimSpace(function),
+			Level:    levels[strings.TrimSpace(level)],
+		}
+		if fields := strings.Fields(settings); len(fields) > 0 {
+			p.Pull = pulls[fields[len(fields)-1]]
+		}
+		pins = append(pins, p)
+	}
+	return pins
+}
+
+// parseRaspiGPIO parses `raspi-gpio get` lines such as
+//
+//	GPIO 17: level=0 fsel=1 func=OUTPUT pull=DOWN
+func parseRaspiGPIO(out string) []Pin {
+	var pins []Pin
+	for _, line := range strings.Split(out, "\n") {
+		head, rest, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		num, ok := strings.CutPrefix(head, "GPIO ")
+		if !ok {
+			continue
+		}
+		pin, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil {
+			continue
+		}
+
+		p := Pin{Pin: pin, Name: "GPIO" + strconv.Itoa(pin)}
+		for _, field := range strings.Fields(rest) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "level":
+				if value == "1" {
+					p.Level = LevelHigh
+				} else {
+					p.Level = LevelLow
+				}
+			case "func":
+				if value == "INPUT" || value == "OUTPUT" {
+					value = strings.ToLower(value)
+				}
+				p.Function = value
+			case "pull":
+				p.Pull = strings.ToLower(value)
+			}
+		}
+		pins = append(pins, p)
+	}
+	return pins
+}
@@ -0,0 +1,38 @@
+package gpio
+
+// Tools the pin states are read with
+const (
+	ToolPinctrl   = "pinctrl"
+	ToolRaspiGPIO = "raspi-gpio"
+)
+
+// Pin levels
+const (
+	LevelHigh = "high"
+	LevelLow  = "low"
+)
+
+// Pin is the state of one GPIO
+type Pin struct {
+	Pin      int    `json:"pin"`
+	Name     string `json:"name,omitempty"`  // e.g. "GPIO14" or "TXD0/GPIO14"
+	Function string `json:"function"`        // input, output or an alternate function such as TXD0
+	Pull     string `json:"pull,omitempty"`  // up, down or none
+	Level    string `json:"level,omitempty"` // high or low
+}
+
+// Input is a named digital input such as a door sensor or relay
+type Input struct {
+	Name      string `json:"name"`
+	Pin       int    `json:"pin"`
+	Level     string `json:"level,omitempty"` // Empty when the pin was not reported
+	Active    bool   `json:"active"`
+	ActiveLow bool   `json:"active_low,omitempty"`
+}
+
+// State is the GPIO state of the board
+type State struct {
+	Tool   string  `json:"tool"`
+	Pins   []Pin   `json:"pins"`
+	Inputs []Input `json:"inputs"`
+}
@@ -0,0 +1,171 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	pingTimeout = 10 * time.Second
+	// pingAttempts is how many times a ping is tried before the interval
+	// is counted as missed
+	pingAttempts = 3
+	retryDelay   = 5 * time.Second
+
+	// LabelsHeader carries the host's labels, URL-encoded, on each ping
+	LabelsHeader = "X-Hivedeck-Labels"
+)
+
+// Pinger sends a GET to a dead-man-switch URL (healthchecks.io, Uptime
+// Kuma push monitors and similar) every interval. The monitoring service
+// alerts when pings stop, which covers the host or agent going down.
+type Pinger struct {
+	url      string
+	interval time.Duration
+	http     *http.Client
+
+	status Status
+	labels string
+	mu     sync.Mutex
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewPinger creates a pinger for url
+func NewPinger(url string, interval time.Duration) *Pinger {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	return &Pinger{
+		url:      url,
+		interval: interval,
+		http:     &http.Client{Timeout: pingTimeout},
+		status: Status{
+			Enabled:  true,
+			URL:      redact(url),
+			Interval: interval.String(),
+		},
+		stop: make(chan struct{}),
+	}
+}
+
+// Start pings immediately and then every interval until Stop is called
+func (p *Pinger) Start() {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.beat()
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends pinging
+func (p *Pinger) Stop() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+// SetLabels sets the labels sent with each ping
+func (p *Pinger) SetLabels(labels map[string]string) {
+	values := make(url.Values, len(labels))
+	for k, v := range labels {
+		values.Set(k, v)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.labels = values.Encode()
+}
+
+// Status returns a snapshot of the pinger's state
+func (p *Pinger) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// beat pings with a few retries, so one dropped request does not trip
+// the switch
+func (p *Pinger) beat() {
+	var err error
+	for attempt := 0; attempt < pingAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(retryDelay):
+			case <-p.stop:
+				return
+			}
+		}
+		if err = p.Ping(context.Background()); err == nil {
+			return
+		}
+	}
+	log.Printf("Heartbeat: %v", err)
+}
+
+// Ping sends a single heartbeat
+func (p *Pinger) Ping(ctx context.Context) error {
+	err := p.ping(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.status.Failed++
+		p.status.LastError = err.Error()
+		return err
+	}
+	now := time.Now()
+	p.status.Sent++
+	p.status.LastPing = &now
+	p.status.LastError = ""
+	return nil
+}
+
+func (p *Pinger) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "hivedeck-agent")
+	p.mu.Lock()
+	if p.labels != "" {
+		req.Header.Set(LabelsHeader, p.labels)
+	}
+	p.mu.Unlock()
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("ping returned %s", resp.Status)
+	}
+	return nil
+}
+
+// redact hides the path of a ping URL, which is usually the secret that
+// identifies the check
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	return u.Scheme + "://" + u.Host + "/…"
+}
@@ -0,0 +1,60 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	status := http.StatusOK
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		assert.Equal(t, "/ping/abc-123", r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	p := NewPinger(srv.URL+"/ping/abc-123", time.Minute)
+	require.NoError(t, p.Ping(context.Background()))
+
+	s := p.Status()
+	assert.Equal(t, 1, s.Sent)
+	assert.NotNil(t, s.LastPing)
+	assert.NotContains(t, s.URL, "abc-123", "the check ID is a secret")
+
+	status = http.StatusNotFound
+	require.Error(t, p.Ping(context.Background()))
+
+	s = p.Status()
+	assert.Equal(t, 1, s.Failed)
+	assert.Contains(t, s.LastError, "404")
+	assert.Equal(t, 2, hits)
+}
+
+func TestStartPingsImmediately(t *testing.T) {
+	pinged := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPinger(srv.URL, time.Hour)
+	p.Start()
+	defer p.Stop()
+
+	select {
+	case <-pinged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ping sent on start")
+	}
+}
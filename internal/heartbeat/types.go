@@ -0,0 +1,14 @@
+package heartbeat
+
+import "time"
+
+// Status describes the state of the heartbeat
+type Status struct {
+	Enabled   bool       `json:"enabled"`
+	URL       string     `json:"url,omitempty"`
+	Interval  string     `json:"interval,omitempty"`
+	LastPing  *time.Time `json:"last_ping,omitempty"` // Last successful ping
+	LastError string     `json:"last_error,omitempty"`
+	Sent      int        `json:"sent"`
+	Failed    int        `json:"failed"`
+}
@@ -0,0 +1,223 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// Command is the subcommand that runs the helper: hivedeck-agent helper ...
+const Command = "helper"
+
+// ServiceActions are the systemctl verbs the helper runs
+var ServiceActions = []string{"start", "stop", "restart", "enable", "disable", "mask", "unmask"}
+
+// aptCommands are the apt-get commands the helper runs, always
+// non-interactively and keeping locally modified configuration files
+var aptCommands = map[string][]string{
+	"update":  {"apt-get", "update"},
+	"upgrade": {"apt-get", "-y", "-o", "Dpkg::Options::=--force-confold", "upgrade"},
+}
+
+var (
+	unitPattern = regexp.MustCompile(`^[A-Za-z0-9@_.:-]+\.service$`)
+	whenPattern = regexp.MustCompile(`^(now|\+[0-9]+|[0-9]{1,2}:[0-9]{2})$`)
+
+	governorPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// Client runs privileged operations through the helper under sudo, so the
+// agent itself needs no privileges
+type Client struct {
+	sudo string
+	exe  string
+}
+
+// NewClient creates a client that runs this executable's helper with sudo
+func NewClient(sudo string) (*Client, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the agent executable: %w", err)
+	}
+	return &Client{sudo: sudo, exe: exe}, nil
+}
+
+// CommandLine returns a shell command that runs a helper command, for tasks
+// that run through bash
+func (c *Client) CommandLine(args ...string) string {
+	argv := append([]string{c.sudo, "-n", c.exe, Command}, args...)
+	for i, arg := range argv {
+		argv[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(argv, " ")
+}
+
+// Run runs a helper command and returns its combined output. sudo is run
+// non-interactively, so a missing sudoers entry fails instead of prompting.
+func (c *Client) Run(ctx context.Context, args ...string) ([]byte, error) {
+	argv := append([]string{"-n", c.exe, Command}, args...)
+	return exec.CommandContext(ctx, c.sudo, argv...).CombinedOutput()
+}
+
+// Main runs the helper with the arguments after "helper" and returns the
+// exit status. It trusts nothing from its caller: each command is checked
+// before anything runs.
+func Main(args []string) int {
+	argv, err := Validate(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "helper: %v\n", err)
+		return 2
+	}
+
+	// The governor is written to sysfs, not set by running a program
+	if argv[0] == "governor" {
+		if err := system.SetGovernor(argv[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "helper: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = []string{"PATH=/usr/sbin:/usr/bin:/sbin:/bin"}
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "helper: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Validate checks a helper command and returns the program and arguments it
+// runs, or for governor, the governor the helper sets itself after checking
+// that every CPU offers it. The commands are:
+//
+//	service start|stop|restart|enable|disable|mask|unmask <unit>.service
+//	daemon-reload
+//	apt-get update|upgrade
+//	governor <name>
+//	shutdown -r|-h now|+MINUTES|HH:MM [message]
+//	shutdown -c [message]
+func Validate(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command")
+	}
+
+	switch args[0] {
+	case "service":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("usage: service %s <unit>.service", strings.Join(ServiceActions, "|"))
+		}
+		if !contains(ServiceActions, args[1]) {
+			return nil, fmt.Errorf("unknown service action %q", args[1])
+		}
+		if !unitPattern.MatchString(args[2]) {
+			return nil, fmt.Errorf("invalid unit name %q", args[2])
+		}
+		return []string{"systemctl", args[1], "--", args[2]}, nil
+
+	case "daemon-reload":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("usage: daemon-reload")
+		}
+		return []string{"systemctl", "daemon-reload"}, nil
+
+	case "apt-get":
+		if len(args) != 2 || aptCommands[args[1]] == nil {
+			return nil, fmt.Errorf("usage: apt-get update|upgrade")
+		}
+		return append([]string{"env", "DEBIAN_FRONTEND=noninteractive"}, aptCommands[args[1]]...), nil
+
+	case "governor":
+		if len(args) != 2 || !governorPattern.MatchString(args[1]) {
+			return nil, fmt.Errorf("usage: governor <name>")
+		}
+		return []string{"governor", args[1]}, nil
+
+	case "shutdown":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("usage: shutdown -r|-h|-c ...")
+		}
+		switch args[1] {
+		case "-c":
+			if len(args) > 3 {
+				return nil, fmt.Errorf("usage: shutdown -c [message]")
+			}
+		case "-r", "-h":
+			if len(args) < 3 || len(args) > 4 || !whenPattern.MatchString(args[2]) {
+				return nil, fmt.Errorf("usage: shutdown -r|-h now|+MINUTES|HH:MM [message]")
+			}
+		default:
+			return nil, fmt.Errorf("unknown shutdown flag %q", args[1])
+		}
+		for _, arg := range args[2:] {
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("options are not allowed: %q", arg)
+			}
+		}
+		return append([]string{"shutdown"}, args[1:]...), nil
+	}
+
+	return nil, fmt.Errorf("unknown command %q", args[0])
+}
+
+// Sudoers returns sudoers rules that let user run exactly the helper
+// commands for the given services, plus power actions when power is true,
+// package updates when packages is true and CPU governor changes when
+// governor is true.
+// Any service access also allows daemon-reload, which enable and disable need
+// after a unit file is edited.
+// Scheduled times and messages vary, so power rules allow any arguments; the
+// helper validates them.
+func Sudoers(user, exe string, services []string, power, packages, governor bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Hivedeck agent helper for %s. Install with: visudo -f /etc/sudoers.d/hivedeck-agent\n", user)
+	for _, svc := range services {
+		unit := strings.TrimSuffix(svc, ".service") + ".service"
+		if svc == "*" {
+			// ALLOWED_SERVICES=* allows every unit
+			unit = "*"
+		} else if !unitPattern.MatchString(unit) {
+			continue
+		}
+		for _, action := range ServiceActions {
+			fmt.Fprintf(&b, "%s ALL=(root) NOPASSWD: %s %s service %s %s\n", user, exe, Command, action, unit)
+		}
+	}
+	if len(services) > 0 {
+		fmt.Fprintf(&b, "%s ALL=(root) NOPASSWD: %s %s daemon-reload\n", user, exe, Command)
+	}
+	if power {
+		for _, flag := range []string{"-r", "-h", "-c"} {
+			fmt.Fprintf(&b, "%s ALL=(root) NOPASSWD: %s %s shutdown %s *\n", user, exe, Command, flag)
+		}
+		fmt.Fprintf(&b, "%s ALL=(root) NOPASSWD: %s %s shutdown -c\n", user, exe, Command)
+	}
+	if packages {
+		for _, cmd := range []string{"update", "upgrade"} {
+			fmt.Fprintf(&b, "%s ALL=(root) NOPASSWD: %s %s apt-get %s\n", user, exe, Command, cmd)
+		}
+	}
+	if governor {
+		fmt.Fprintf(&b, "%s ALL=(root) NOPASSWD: %s %s governor *\n", user, exe, Command)
+	}
+	return b.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
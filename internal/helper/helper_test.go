@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := map[string][]string{
+		"service restart nginx.service":   {"systemctl", "restart", "--", "nginx.service"},
+		"service stop getty@tty1.service": {"systemctl", "stop", "--", "getty@tty1.service"},
+		"service enable nginx.service":    {"systemctl", "enable", "--", "nginx.service"},
+		"service mask cups.service":       {"systemctl", "mask", "--", "cups.service"},
+		"daemon-reload":                   {"systemctl", "daemon-reload"},
+		"apt-get update":                  {"env", "DEBIAN_FRONTEND=noninteractive", "apt-get", "update"},
+		"governor performance":            {"governor", "performance"},
+		"shutdown -r +5":                  {"shutdown", "-r", "+5"},
+		"shutdown -h 23:30":               {"shutdown", "-h", "23:30"},
+		"shutdown -c":                     {"shutdown", "-c"},
+	}
+	for cmd, want := range valid {
+		argv, err := Validate(strings.Fields(cmd))
+		require.NoError(t, err, cmd)
+		assert.Equal(t, want, argv, cmd)
+	}
+
+	argv, err := Validate([]string{"shutdown", "-r", "now", "kernel update"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shutdown", "-r", "now", "kernel update"}, argv)
+
+	for _, cmd := range [][]string{
+		nil,
+		{"service", "kill", "nginx.service"},
+		{"daemon-reload", "--user"},
+		{"apt-get", "install", "nmap"},
+		{"governor", "../../../etc/shadow"},
+		{"governor", "performance", "powersave"},
+		{"apt-get", "upgrade", "-o", "APT::Get::Allow-Downgrades=true"},
+		{"service", "restart", "nginx"},
+		{"service", "restart", "../nginx.service"},
+		{"service", "restart", "--user", "nginx.service"},
+		{"service", "restart", "nginx.service", "sshd.service"},
+		{"shutdown", "-k", "now"},
+		{"shutdown", "-r", "tomorrow"},
+		{"shutdown", "-r", "now", "--no-wall"},
+		{"bash", "-c", "id"},
+	} {
+		_, err := Validate(cmd)
+		assert.Error(t, err, "%q", cmd)
+	}
+}
+
+func TestSudoers(t *testing.T) {
+	rules := Sudoers("hivedeck", "/usr/local/bin/hivedeck-agent", []string{"nginx", "docker.service", "bad name"}, false, false, false)
+	assert.Contains(t, rules, "hivedeck ALL=(root) NOPASSWD: /usr/local/bin/hivedeck-agent helper service restart nginx.service\n")
+	assert.Contains(t, rules, "helper service start docker.service\n")
+	assert.Contains(t, rules, "helper service enable docker.service\n")
+	assert.Contains(t, rules, "helper daemon-reload\n")
+	assert.NotContains(t, rules, "bad name")
+	assert.NotContains(t, rules, "shutdown")
+	assert.NotContains(t, rules, "apt-get")
+	assert.NotContains(t, rules, "governor")
+
+	rules = Sudoers("hivedeck", "/usr/local/bin/hivedeck-agent", []string{"*"}, true, true, true)
+	assert.Contains(t, rules, "helper service stop *\n")
+	assert.Contains(t, rules, "helper shutdown -r *\n")
+	assert.Contains(t, rules, "helper apt-get upgrade\n")
+	assert.Contains(t, rules, "helper governor *\n")
+}
+
+func TestCommandLine(t *testing.T) {
+	c := &Client{sudo: "sudo", exe: "/opt/hive deck/agent"}
+	assert.Equal(t, `'sudo' '-n' '/opt/hive deck/agent' 'helper' 'apt-get' 'update'`, c.CommandLine("apt-get", "update"))
+}
@@ -0,0 +1,83 @@
+package hostaudit
+
+import (
+	"strings"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/audit"
+)
+
+// Audit actions
+const (
+	ActionShell = "host.shell"
+	ActionSudo  = "host.sudo"
+	ActionSu    = "host.su"
+)
+
+// parseSudo parses sudo's log line, e.g.
+//
+//	alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/apt update
+//	alice : 3 incorrect password attempts ; TTY=pts/0 ; ... ; COMMAND=/usr/bin/ls
+func parseSudo(msg string) (*audit.Entry, bool) {
+	actor, rest, ok := strings.Cut(strings.TrimSpace(msg), " : ")
+	if !ok || !strings.Contains(rest, "COMMAND=") {
+		return nil, false
+	}
+
+	entry := &audit.Entry{Action: ActionSudo, Actor: actor, Success: true, Source: audit.SourceHost}
+	fields := map[string]string{}
+	for _, part := range strings.Split(rest, " ; ") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || strings.Contains(key, " ") {
+			// A reason such as "command not allowed" means it was refused
+			entry.Success = false
+			entry.Message = strings.TrimSpace(part)
+			continue
+		}
+		fields[key] = value
+	}
+
+	entry.Target = fields["COMMAND"]
+	details := "as " + fields["USER"]
+	if tty := fields["TTY"]; tty != "" && tty != "unknown" {
+		details += " on " + tty
+	}
+	if pwd := fields["PWD"]; pwd != "" {
+		details += " in " + pwd
+	}
+	if entry.Message != "" {
+		entry.Message += "; " + details
+	} else {
+		entry.Message = details
+	}
+	return entry, true
+}
+
+// parseSu parses su's log line, e.g. "(to root) alice on pts/0" or
+// "FAILED SU (to root) alice on pts/0"
+func parseSu(msg string) (*audit.Entry, bool) {
+	msg = strings.TrimSpace(msg)
+	failed := strings.HasPrefix(msg, "FAILED SU ")
+	msg = strings.TrimPrefix(msg, "FAILED SU ")
+
+	rest, ok := strings.CutPrefix(msg, "(to ")
+	if !ok {
+		return nil, false
+	}
+	target, rest, ok := strings.Cut(rest, ") ")
+	if !ok {
+		return nil, false
+	}
+	actor, tty, _ := strings.Cut(rest, " on ")
+
+	entry := &audit.Entry{
+		Action:  ActionSu,
+		Target:  target,
+		Actor:   actor,
+		Success: !failed,
+		Source:  audit.SourceHost,
+	}
+	if tty != "" {
+		entry.Message = "on " + tty
+	}
+	return entry, true
+}
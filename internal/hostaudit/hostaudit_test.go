@@ -0,0 +1,155 @@
+package hostaudit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/audit"
+	"github.com/ngenohkevin/hivedeck-agent/internal/redact"
+)
+
+// writeProc adds a fake /proc/<pid> under root
+func writeProc(t *testing.T, root string, pid, ppid int, name string, ttyNr, started int, args ...string) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	// Fields after the name: state ppid pgrp session tty_nr ... starttime is the 20th
+	rest := []string{"S", strconv.Itoa(ppid), "1", "1", strconv.Itoa(ttyNr)}
+	for len(rest) < 19 {
+		rest = append(rest, "0")
+	}
+	rest = append(rest, strconv.Itoa(started), "0")
+	stat := fmt.Sprintf("%d (%s) %s", pid, name, strings.Join(rest, " "))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(args, "\x00")+"\x00"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "status"), []byte("Name:\t"+name+"\nUid:\t4242\t0\t0\t0\n"), 0644))
+}
+
+func TestTTYName(t *testing.T) {
+	assert.Equal(t, "", ttyName(0))
+	assert.Equal(t, "pts/3", ttyName(136<<8|3))
+	assert.Equal(t, "tty1", ttyName(4<<8|1))
+	assert.Equal(t, "ttyS0", ttyName(4<<8|64))
+}
+
+func TestInteractive(t *testing.T) {
+	assert.True(t, interactive([]string{"-bash"}))
+	assert.True(t, interactive([]string{"bash", "-l", "-i"}))
+	assert.False(t, interactive([]string{"bash", "-c", "ls"}))
+	assert.False(t, interactive([]string{"sh", "/usr/local/bin/backup.sh"}))
+	assert.False(t, interactive(nil))
+}
+
+func TestScan(t *testing.T) {
+	orig := procRoot
+	defer func() { procRoot = orig }()
+	procRoot = t.TempDir()
+
+	writeProc(t, procRoot, 100, 1, "sshd", 0, 10, "sshd: alice")
+	writeProc(t, procRoot, 101, 100, "bash", 136<<8, 20, "-bash")
+
+	logger := audit.NewLogger(10)
+	w := NewWatcher(logger, 0, nil)
+	w.scan()
+	assert.Empty(t, logger.List(0, "").Entries, "the first scan only takes a baseline")
+
+	writeProc(t, procRoot, 102, 100, "bash", 136<<8|1, 30, "-bash")
+	writeProc(t, procRoot, 103, 1, "bash", 0, 31, "bash", "/etc/cron.daily/job")
+	writeProc(t, procRoot, 104, 102, "sh", 136<<8|1, 32, "sh", "-c", "true")
+	writeProc(t, procRoot, 105, 102, "sudo", 136<<8|1, 33, "sudo", "apt", "update")
+	w.scan()
+
+	entries := logger.List(0, audit.SourceHost).Entries
+	require.Len(t, entries, 2)
+
+	sudo, shell := entries[0], entries[1]
+	assert.Equal(t, ActionShell, shell.Action)
+	assert.Equal(t, "bash", shell.Target)
+	assert.Equal(t, "bash on pts/1 (PID 102) from sshd", shell.Message)
+	assert.Equal(t, audit.SourceHost, shell.Source)
+	assert.True(t, shell.Success)
+	assert.NotEmpty(t, shell.Actor)
+
+	assert.Equal(t, ActionSudo, sudo.Action)
+	assert.Equal(t, "apt update", sudo.Target)
+
+	// Known processes are not recorded again
+	w.scan()
+	assert.Len(t, logger.List(0, "").Entries, 2)
+
+	// With the journal followed, sudo is left to it
+	w.journal = true
+	writeProc(t, procRoot, 106, 102, "sudo", 136<<8|1, 40, "sudo", "reboot")
+	w.scan()
+	assert.Len(t, logger.List(0, "").Entries, 2)
+}
+
+func TestParseSudo(t *testing.T) {
+	e, ok := parseSudo("   alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/apt update")
+	require.True(t, ok)
+	assert.Equal(t, ActionSudo, e.Action)
+	assert.Equal(t, "alice", e.Actor)
+	assert.Equal(t, "/usr/bin/apt update", e.Target)
+	assert.Equal(t, "as root on pts/0 in /home/alice", e.Message)
+	assert.True(t, e.Success)
+
+	e, ok = parseSudo("bob : user NOT in sudoers ; TTY=pts/1 ; PWD=/tmp ; USER=root ; COMMAND=/bin/ls")
+	require.True(t, ok)
+	assert.False(t, e.Success)
+	assert.Equal(t, "user NOT in sudoers; as root on pts/1 in /tmp", e.Message)
+
+	_, ok = parseSudo("pam_unix(sudo:session): session opened for user root(uid=0) by alice(uid=1000)")
+	assert.False(t, ok)
+}
+
+func TestParseSu(t *testing.T) {
+	e, ok := parseSu("(to root) alice on pts/0")
+	require.True(t, ok)
+	assert.Equal(t, ActionSu, e.Action)
+	assert.Equal(t, "root", e.Target)
+	assert.Equal(t, "alice", e.Actor)
+	assert.Equal(t, "on pts/0", e.Message)
+	assert.True(t, e.Success)
+
+	e, ok = parseSu("FAILED SU (to postgres) bob on pts/2")
+	require.True(t, ok)
+	assert.False(t, e.Success)
+	assert.Equal(t, "postgres", e.Target)
+
+	_, ok = parseSu("pam_unix(su:session): session opened for user root")
+	assert.False(t, ok)
+}
+
+func TestRead(t *testing.T) {
+	logger := audit.NewLogger(10)
+	w := NewWatcher(logger, 0, nil)
+	w.read(strings.NewReader(`{"SYSLOG_IDENTIFIER":"sudo","MESSAGE":"alice : TTY=pts/0 ; PWD=/ ; USER=root ; COMMAND=/bin/true","__REALTIME_TIMESTAMP":"1700000000000000"}
+{"SYSLOG_IDENTIFIER":"sudo","MESSAGE":"pam_unix(sudo:session): session closed for user root"}
+{"SYSLOG_IDENTIFIER":"su","MESSAGE":"(to root) bob on pts/1"}
+not json
+`))
+
+	entries := logger.List(0, "host").Entries
+	require.Len(t, entries, 2)
+	assert.Equal(t, ActionSu, entries[0].Action)
+	assert.Equal(t, ActionSudo, entries[1].Action)
+	assert.Equal(t, int64(1700000000), entries[1].Timestamp.Unix())
+}
+
+func TestReadRedacts(t *testing.T) {
+	logger := audit.NewLogger(10)
+	w := NewWatcher(logger, 0, redact.New(redact.DefaultKeywords))
+	w.read(strings.NewReader(`{"SYSLOG_IDENTIFIER":"sudo","MESSAGE":"alice : TTY=pts/0 ; PWD=/ ; USER=root ; COMMAND=/usr/bin/mysql --password=hunter2 app"}`))
+
+	entries := logger.List(0, "host").Entries
+	require.Len(t, entries, 1)
+	assert.Equal(t, "/usr/bin/mysql --password=******** app", entries[0].Target)
+}
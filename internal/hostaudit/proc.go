@@ -0,0 +1,131 @@
+package hostaudit
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procRoot is the proc filesystem; replaced in tests
+var procRoot = "/proc"
+
+// shells are the programs recorded as shell launches
+var shells = map[string]bool{
+	"bash": true, "sh": true, "dash": true, "zsh": true, "fish": true,
+	"ksh": true, "mksh": true, "tcsh": true, "csh": true, "ash": true,
+}
+
+// proc is what the scanner needs from /proc/<pid>
+type proc struct {
+	pid     int
+	ppid    int
+	name    string
+	tty     string // Empty without a controlling terminal
+	started uint64 // Clock ticks after boot, to tell reused PIDs apart
+}
+
+// readProc parses /proc/<pid>/stat
+func readProc(pid int) (*proc, bool) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, false
+	}
+
+	// The name is in parentheses and may contain spaces
+	stat := string(data)
+	lp, rp := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if lp < 0 || rp < lp {
+		return nil, false
+	}
+	fields := strings.Fields(stat[rp+1:])
+	if len(fields) < 20 {
+		return nil, false
+	}
+
+	p := &proc{pid: pid, name: stat[lp+1 : rp]}
+	p.ppid, _ = strconv.Atoi(fields[1])
+	ttyNr, _ := strconv.ParseUint(fields[4], 10, 32)
+	p.tty = ttyName(ttyNr)
+	p.started, _ = strconv.ParseUint(fields[19], 10, 64)
+	return p, true
+}
+
+// ttyName turns a tty_nr into a terminal name such as pts/0
+func ttyName(nr uint64) string {
+	if nr == 0 {
+		return ""
+	}
+	major := (nr >> 8) & 0xfff
+	minor := (nr & 0xff) | ((nr >> 12) & 0xfff00)
+	switch {
+	case major >= 136 && major <= 143:
+		return fmt.Sprintf("pts/%d", (major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return fmt.Sprintf("tty%d", minor)
+	case major == 4:
+		return fmt.Sprintf("ttyS%d", minor-64)
+	}
+	return fmt.Sprintf("tty(%d:%d)", major, minor)
+}
+
+// cmdline returns a process's arguments
+func cmdline(pid int) []string {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+}
+
+// owner returns the name of a process's real user
+func owner(pid int) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "Uid:"); ok {
+			fields := strings.Fields(rest)
+			if len(fields) == 0 {
+				return ""
+			}
+			if u, err := user.LookupId(fields[0]); err == nil {
+				return u.Username
+			}
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// interactive reports whether a shell's arguments start an interactive
+// session rather than run a script or a -c command
+func interactive(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if arg == "-c" || !strings.HasPrefix(arg, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// listPIDs returns the PIDs in /proc
+func listPIDs() []int {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,241 @@
+package hostaudit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/audit"
+	"github.com/ngenohkevin/hivedeck-agent/internal/redact"
+)
+
+const (
+	// DefaultInterval is how often /proc is scanned
+	DefaultInterval = 2 * time.Second
+	// retryDelay is how long to wait before following the journal again
+	retryDelay = 30 * time.Second
+)
+
+// Replaced in tests
+var (
+	lookPath = exec.LookPath
+	// startCommand starts a command and returns its stdout. Closing it
+	// waits for the command.
+	startCommand = func(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return cmdOutput{stdout, cmd}, nil
+	}
+)
+
+type cmdOutput struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (o cmdOutput) Close() error {
+	o.ReadCloser.Close()
+	return o.cmd.Wait()
+}
+
+// Watcher records interactive shells, su and sudo on the host into the
+// audit log. Shells are found by scanning /proc; su and sudo come from the
+// journal when there is one, since they often exit between scans.
+type Watcher struct {
+	log      *audit.Logger
+	interval time.Duration
+	journal  bool
+	redactor *redact.Redactor
+
+	seen    map[int]uint64 // PID to start time
+	scanned bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher creates a watcher recording into logger. Secrets in su and
+// sudo commands are masked by redactor.
+func NewWatcher(logger *audit.Logger, interval time.Duration, redactor *redact.Redactor) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Watcher{log: logger, interval: interval, redactor: redactor, seen: map[int]uint64{}}
+}
+
+// Start scans /proc and follows the journal until Stop is called
+func (w *Watcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	_, err := lookPath("journalctl")
+	w.journal = err == nil
+
+	var followed chan struct{}
+	if w.journal {
+		followed = make(chan struct{})
+		go func() {
+			defer close(followed)
+			for {
+				if err := w.follow(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("Host audit: %v", err)
+				}
+				select {
+				case <-time.After(retryDelay):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(w.done)
+		if followed != nil {
+			defer func() { <-followed }()
+		}
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		w.scan()
+		for {
+			select {
+			case <-ticker.C:
+				w.scan()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the watcher
+func (w *Watcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+		<-w.done
+	}
+}
+
+// scan records processes started since the last scan. The first scan only
+// notes what is already running.
+func (w *Watcher) scan() {
+	current := make(map[int]uint64)
+	for _, pid := range listPIDs() {
+		p, ok := readProc(pid)
+		if !ok {
+			continue
+		}
+		current[pid] = p.started
+		if started, ok := w.seen[pid]; ok && started == p.started {
+			continue
+		}
+		if w.scanned {
+			w.check(p)
+		}
+	}
+	w.seen = current
+	w.scanned = true
+}
+
+// check records p if it is an interactive shell, or su or sudo when the
+// journal is not followed
+func (w *Watcher) check(p *proc) {
+	switch {
+	case shells[p.name] && p.tty != "":
+		args := cmdline(p.pid)
+		if !interactive(args) {
+			return
+		}
+		message := fmt.Sprintf("%s on %s (PID %d)", p.name, p.tty, p.pid)
+		if parent, ok := readProc(p.ppid); ok {
+			message += " from " + parent.name
+		}
+		w.record(audit.Entry{Action: ActionShell, Target: p.name, Actor: owner(p.pid), Message: message, Success: true})
+
+	case !w.journal && (p.name == "sudo" || p.name == "su" || p.name == "doas"):
+		action := ActionSudo
+		if p.name == "su" {
+			action = ActionSu
+		}
+		args := cmdline(p.pid)
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		message := fmt.Sprintf("PID %d", p.pid)
+		if p.tty != "" {
+			message = "on " + p.tty + ", " + message
+		}
+		w.record(audit.Entry{Action: action, Target: strings.Join(args, " "), Actor: owner(p.pid), Message: message, Success: true})
+	}
+}
+
+// follow streams su and sudo messages from the journal until journalctl
+// exits
+func (w *Watcher) follow(ctx context.Context) error {
+	out, err := startCommand(ctx, "journalctl", "-f", "-n", "0", "--output=json", "--no-pager",
+		"SYSLOG_IDENTIFIER=sudo", "SYSLOG_IDENTIFIER=su")
+	if err != nil {
+		return fmt.Errorf("failed to start journalctl: %w", err)
+	}
+	defer out.Close()
+	w.read(out)
+	return fmt.Errorf("journalctl exited")
+}
+
+// read records su and sudo entries from journal JSON
+func (w *Watcher) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			Identifier string      `json:"SYSLOG_IDENTIFIER"`
+			Message    interface{} `json:"MESSAGE"` // A byte array when not UTF-8
+			Timestamp  string      `json:"__REALTIME_TIMESTAMP"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		msg, _ := line.Message.(string)
+
+		var entry *audit.Entry
+		var ok bool
+		switch line.Identifier {
+		case "sudo":
+			entry, ok = parseSudo(msg)
+		case "su":
+			entry, ok = parseSu(msg)
+		}
+		if !ok {
+			continue
+		}
+		if usec, err := strconv.ParseInt(line.Timestamp, 10, 64); err == nil {
+			entry.Timestamp = time.UnixMicro(usec)
+		}
+		w.record(*entry)
+	}
+}
+
+// record adds a host entry to the audit log
+func (w *Watcher) record(entry audit.Entry) {
+	entry.Source = audit.SourceHost
+	if entry.Action == ActionSudo || entry.Action == ActionSu {
+		entry.Target = w.redactor.Cmdline(entry.Target)
+	}
+	w.log.Record(entry)
+}
@@ -0,0 +1,337 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+const (
+	// MaxHashSize is the largest file that will be hashed (64MB)
+	MaxHashSize = 64 * 1024 * 1024
+	// MaxFilesPerPath limits how many files are tracked under one directory
+	MaxFilesPerPath = 1000
+)
+
+// Monitor records checksums of critical files and reports changes
+type Monitor struct {
+	paths        []string
+	interval     time.Duration
+	baselineFile string
+	bus          *events.Bus
+
+	baseline map[string]FileState
+	last     *Report
+	// reported tracks the last status an event was raised for, so a
+	// persistent change is only reported once
+	reported map[string]string
+	mu       sync.Mutex
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewMonitor creates a file integrity monitor. Directories are watched
+// recursively. The baseline is stored as integrity.json in dataDir.
+func NewMonitor(paths []string, interval time.Duration, dataDir string, bus *events.Bus) *Monitor {
+	m := &Monitor{
+		paths:    paths,
+		interval: interval,
+		bus:      bus,
+		baseline: make(map[string]FileState),
+		reported: make(map[string]string),
+		stop:     make(chan struct{}),
+	}
+
+	if dataDir != "" {
+		m.baselineFile = filepath.Join(dataDir, "integrity.json")
+		m.loadBaseline()
+	}
+
+	return m
+}
+
+// Start records an initial baseline if none exists and re-verifies files
+// every interval until Stop is called
+func (m *Monitor) Start() {
+	if len(m.paths) == 0 || m.interval <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	empty := len(m.baseline) == 0
+	m.mu.Unlock()
+	if empty {
+		if err := m.Accept(nil); err != nil {
+			log.Printf("Failed to record integrity baseline: %v", err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		m.Check()
+		for {
+			select {
+			case <-ticker.C:
+				m.Check()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends periodic verification
+func (m *Monitor) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// Report returns the most recent check, running one if none has happened yet
+func (m *Monitor) Report() *Report {
+	m.mu.Lock()
+	last := m.last
+	m.mu.Unlock()
+
+	if last != nil {
+		return last
+	}
+	return m.Check()
+}
+
+// Check compares the current state of all watched files with the baseline
+// and publishes an event for each newly detected change
+func (m *Monitor) Check() *Report {
+	current, errs := m.scan()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &Report{
+		Files:     []FileStatus{},
+		CheckedAt: time.Now(),
+		Paths:     m.paths,
+		Interval:  m.interval.String(),
+	}
+
+	seen := make(map[string]bool)
+	for path, state := range current {
+		state := state
+		seen[path] = true
+		status := FileStatus{Path: path, Current: &state, Status: StatusOK}
+		if base, ok := m.baseline[path]; !ok {
+			status.Status = StatusNew
+		} else {
+			status.Baseline = &base
+			if base.SHA256 != state.SHA256 || base.Mode != state.Mode {
+				status.Status = StatusModified
+			}
+		}
+		report.Files = append(report.Files, status)
+	}
+	for path, base := range m.baseline {
+		if seen[path] {
+			continue
+		}
+		base := base
+		status := FileStatus{Path: path, Baseline: &base, Status: StatusMissing}
+		if err, ok := errs[path]; ok {
+			status.Status = StatusError
+			status.Error = err.Error()
+		}
+		report.Files = append(report.Files, status)
+	}
+
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+
+	for _, f := range report.Files {
+		if f.Status != StatusOK {
+			report.Changed++
+		}
+		m.notify(f)
+	}
+	report.Total = len(report.Files)
+
+	m.last = report
+	return report
+}
+
+// notify publishes an event when a file's status differs from the last one reported
+func (m *Monitor) notify(f FileStatus) {
+	previous, known := m.reported[f.Path]
+	if !known {
+		previous = StatusOK
+	}
+	if f.Status == previous {
+		return
+	}
+	m.reported[f.Path] = f.Status
+
+	if m.bus == nil {
+		return
+	}
+
+	event := events.Event{
+		Type:     "integrity." + f.Status,
+		Severity: events.SeverityWarning,
+		Source:   "integrity",
+		Data:     f,
+	}
+	switch f.Status {
+	case StatusOK:
+		event.Type = "integrity.restored"
+		event.Severity = events.SeverityInfo
+		event.Message = fmt.Sprintf("%s matches its baseline again", f.Path)
+	case StatusModified:
+		event.Severity = events.SeverityCritical
+		event.Message = fmt.Sprintf("%s has been modified", f.Path)
+	case StatusMissing:
+		event.Severity = events.SeverityCritical
+		event.Message = fmt.Sprintf("%s has been removed", f.Path)
+	case StatusNew:
+		event.Message = fmt.Sprintf("%s is new and not in the baseline", f.Path)
+	case StatusError:
+		event.Message = fmt.Sprintf("%s could not be verified: %s", f.Path, f.Error)
+	}
+	m.bus.Publish(event)
+}
+
+// Accept records the current state of the given files (or all watched
+// files when paths is empty) as the new baseline
+func (m *Monitor) Accept(paths []string) error {
+	current, _ := m.scan()
+
+	m.mu.Lock()
+	if len(paths) == 0 {
+		m.baseline = current
+		m.reported = make(map[string]string)
+	} else {
+		for _, path := range paths {
+			path = filepath.Clean(path)
+			if state, ok := current[path]; ok {
+				m.baseline[path] = state
+			} else {
+				delete(m.baseline, path)
+			}
+			delete(m.reported, path)
+		}
+	}
+	m.last = nil
+	data, err := json.MarshalIndent(m.baseline, "", "  ")
+	m.mu.Unlock()
+
+	if err != nil || m.baselineFile == "" {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.baselineFile), 0750); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := os.WriteFile(m.baselineFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// scan hashes every watched file. Files that exist but cannot be read are
+// returned in errs.
+func (m *Monitor) scan() (map[string]FileState, map[string]error) {
+	states := make(map[string]FileState)
+	errs := make(map[string]error)
+
+	for _, root := range m.paths {
+		root = filepath.Clean(root)
+		count := 0
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if !os.IsNotExist(err) {
+					errs[path] = err
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if count >= MaxFilesPerPath {
+				return filepath.SkipAll
+			}
+			count++
+
+			state, err := hashFile(path)
+			if err != nil {
+				errs[path] = err
+				return nil
+			}
+			states[path] = *state
+			return nil
+		})
+	}
+
+	return states, errs
+}
+
+func hashFile(path string) (*FileState, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &FileState{
+		Path:    path,
+		Size:    info.Size(),
+		Mode:    info.Mode().String(),
+		ModTime: info.ModTime(),
+	}
+
+	h := sha256.New()
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		// Track where symlinks (e.g. enabled units) point, not the target content
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		h.Write([]byte("symlink:" + target))
+	case !info.Mode().IsRegular():
+		return nil, fmt.Errorf("not a regular file")
+	case info.Size() > MaxHashSize:
+		return nil, fmt.Errorf("file too large to hash (max %d bytes)", MaxHashSize)
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return nil, err
+		}
+	}
+
+	state.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return state, nil
+}
+
+func (m *Monitor) loadBaseline() {
+	data, err := os.ReadFile(m.baselineFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &m.baseline); err != nil {
+		log.Printf("Failed to parse integrity baseline: %v", err)
+		m.baseline = make(map[string]FileState)
+	}
+}
@@ -0,0 +1,79 @@
+package integrity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+func statusOf(report *Report, path string) string {
+	for _, f := range report.Files {
+		if f.Path == path {
+			return f.Status
+		}
+	}
+	return ""
+}
+
+func TestMonitor_DetectsChanges(t *testing.T) {
+	dir := t.TempDir()
+	sshd := filepath.Join(dir, "sshd_config")
+	units := filepath.Join(dir, "system")
+	unit := filepath.Join(units, "app.service")
+	require.NoError(t, os.WriteFile(sshd, []byte("PermitRootLogin no\n"), 0600))
+	require.NoError(t, os.Mkdir(units, 0755))
+	require.NoError(t, os.WriteFile(unit, []byte("[Service]\n"), 0644))
+
+	bus := events.NewBus(0)
+	dataDir := t.TempDir()
+	m := NewMonitor([]string{sshd, units}, time.Minute, dataDir, bus)
+	require.NoError(t, m.Accept(nil))
+
+	report := m.Check()
+	assert.Equal(t, 2, report.Total)
+	assert.Zero(t, report.Changed)
+
+	require.NoError(t, os.WriteFile(sshd, []byte("PermitRootLogin yes\n"), 0600))
+	require.NoError(t, os.Remove(unit))
+	require.NoError(t, os.WriteFile(filepath.Join(units, "evil.service"), []byte("[Service]\n"), 0644))
+
+	report = m.Check()
+	assert.Equal(t, 3, report.Changed)
+	assert.Equal(t, StatusModified, statusOf(report, sshd))
+	assert.Equal(t, StatusMissing, statusOf(report, unit))
+	assert.Equal(t, StatusNew, statusOf(report, filepath.Join(units, "evil.service")))
+	assert.Equal(t, 3, bus.Recent(0, "integrity").Total)
+
+	// A persistent change is only reported once
+	m.Check()
+	assert.Equal(t, 3, bus.Recent(0, "integrity").Total)
+
+	// The baseline survives a restart
+	restarted := NewMonitor([]string{sshd, units}, time.Minute, dataDir, nil)
+	assert.Equal(t, StatusModified, statusOf(restarted.Check(), sshd))
+}
+
+func TestMonitor_AcceptSelectedPaths(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.conf")
+	b := filepath.Join(dir, "b.conf")
+	require.NoError(t, os.WriteFile(a, []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("b"), 0644))
+
+	m := NewMonitor([]string{dir}, time.Minute, "", nil)
+	require.NoError(t, m.Accept(nil))
+
+	require.NoError(t, os.WriteFile(a, []byte("a2"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("b2"), 0644))
+	require.NoError(t, m.Accept([]string{a}))
+
+	report := m.Check()
+	assert.Equal(t, StatusOK, statusOf(report, a))
+	assert.Equal(t, StatusModified, statusOf(report, b))
+}
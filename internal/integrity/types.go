@@ -0,0 +1,45 @@
+package integrity
+
+import "time"
+
+// File statuses relative to the baseline
+const (
+	StatusOK       = "ok"
+	StatusModified = "modified"
+	StatusMissing  = "missing"
+	StatusNew      = "new"
+	StatusError    = "error"
+)
+
+// FileState is the recorded state of a single file
+type FileState struct {
+	Path    string    `json:"path"`
+	SHA256  string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// FileStatus compares a file's current state with its baseline
+type FileStatus struct {
+	Path     string     `json:"path"`
+	Status   string     `json:"status"`
+	Baseline *FileState `json:"baseline,omitempty"`
+	Current  *FileState `json:"current,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Report is the result of an integrity check
+type Report struct {
+	Files     []FileStatus `json:"files"`
+	Total     int          `json:"total"`
+	Changed   int          `json:"changed"`
+	CheckedAt time.Time    `json:"checked_at"`
+	Paths     []string     `json:"paths"`
+	Interval  string       `json:"interval"`
+}
+
+// BaselineRequest selects which files to re-baseline; empty means all
+type BaselineRequest struct {
+	Paths []string `json:"paths,omitempty"`
+}
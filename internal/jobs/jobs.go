@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// MaxFinishedJobs is the number of completed jobs retained for polling
+const MaxFinishedJobs = 200
+
+// Manager runs jobs in the background and tracks their status
+type Manager struct {
+	jobs map[string]*Job
+	mu   sync.RWMutex
+}
+
+// NewManager creates a new job manager
+func NewManager() *Manager {
+	return &Manager{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Submit starts fn on target in the background and returns the created job.
+// A zero timeout means the job runs until fn returns.
+func (m *Manager) Submit(jobType, target string, timeout time.Duration, fn Func) *Job {
+	m.mu.Lock()
+	job := m.submit(jobType, target, timeout, fn)
+	m.mu.Unlock()
+	return job
+}
+
+// SubmitUnique starts fn like Submit unless a job of the same type is
+// pending or running. In that case it returns the existing job and false.
+// The check and the submit are one step, so concurrent callers cannot both
+// start a job.
+func (m *Manager) SubmitUnique(jobType, target string, timeout time.Duration, fn Func) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job := m.active(jobType); job != nil {
+		snapshot := *job
+		return &snapshot, false
+	}
+	return m.submit(jobType, target, timeout, fn), true
+}
+
+// submit records a new job and starts it. Caller must hold the lock.
+func (m *Manager) submit(jobType, target string, timeout time.Duration, fn Func) *Job {
+	job := &Job{
+		ID:        newID(),
+		Type:      jobType,
+		Target:    target,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if timeout > 0 {
+		job.Timeout = timeout.String()
+	}
+
+	m.jobs[job.ID] = job
+	m.prune()
+	snapshot := *job
+
+	go m.run(job, timeout, fn)
+
+	return &snapshot
+}
+
+func (m *Manager) run(job *Job, timeout time.Duration, fn Func) {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	started := time.Now()
+	m.mu.Lock()
+	job.Status = StatusRunning
+	job.StartedAt = &started
+	m.mu.Unlock()
+
+	result, err := fn(ctx)
+
+	finished := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job.FinishedAt = &finished
+	job.Result = result
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = StatusSucceeded
+}
+
+// Get returns a snapshot of a job by ID
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, apierror.NotFound("job '%s' not found", id)
+	}
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// List returns all tracked jobs, newest first, optionally filtered by type
+func (m *Manager) List(jobType string) *JobList {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var list []Job
+	for _, job := range m.jobs {
+		if jobType != "" && job.Type != jobType {
+			continue
+		}
+		list = append(list, *job)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+
+	return &JobList{
+		Jobs:  list,
+		Total: len(list),
+	}
+}
+
+// Active returns the first pending or running job of the given type, if any
+func (m *Manager) Active(jobType string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if job := m.active(jobType); job != nil {
+		snapshot := *job
+		return &snapshot, true
+	}
+	return nil, false
+}
+
+// active returns the first pending or running job of the given type, or
+// nil. Caller must hold the lock.
+func (m *Manager) active(jobType string) *Job {
+	for _, job := range m.jobs {
+		if job.Type == jobType && (job.Status == StatusPending || job.Status == StatusRunning) {
+			return job
+		}
+	}
+	return nil
+}
+
+// prune drops the oldest finished jobs beyond MaxFinishedJobs. Caller must hold the lock.
+func (m *Manager) prune() {
+	var finished []*Job
+	for _, job := range m.jobs {
+		if job.FinishedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+
+	if len(finished) <= MaxFinishedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, job := range finished[:len(finished)-MaxFinishedJobs] {
+		delete(m.jobs, job.ID)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitForJob(t *testing.T, m *Manager, id string) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		require.NoError(t, err)
+		if job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestManager_SubmitSuccess(t *testing.T) {
+	m := NewManager()
+
+	job := m.Submit("test", "", 0, func(ctx context.Context) (interface{}, error) {
+		return "done", nil
+	})
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, "test", job.Type)
+
+	finished := waitForJob(t, m, job.ID)
+	assert.Equal(t, StatusSucceeded, finished.Status)
+	assert.Equal(t, "done", finished.Result)
+	assert.Empty(t, finished.Error)
+}
+
+func TestManager_SubmitFailure(t *testing.T) {
+	m := NewManager()
+
+	job := m.Submit("test", "", 0, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	finished := waitForJob(t, m, job.ID)
+	assert.Equal(t, StatusFailed, finished.Status)
+	assert.Equal(t, "boom", finished.Error)
+}
+
+func TestManager_Timeout(t *testing.T) {
+	m := NewManager()
+
+	job := m.Submit("test", "", 20*time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	finished := waitForJob(t, m, job.ID)
+	assert.Equal(t, StatusFailed, finished.Status)
+	assert.Contains(t, finished.Error, "deadline exceeded")
+}
+
+func TestManager_ActiveAndList(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+
+	job := m.Submit("slow", "", 0, func(ctx context.Context) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	active, ok := m.Active("slow")
+	assert.True(t, ok)
+	assert.Equal(t, job.ID, active.ID)
+
+	_, ok = m.Active("other")
+	assert.False(t, ok)
+
+	assert.Equal(t, 1, m.List("slow").Total)
+	assert.Equal(t, 0, m.List("other").Total)
+
+	close(release)
+	waitForJob(t, m, job.ID)
+
+	_, ok = m.Active("slow")
+	assert.False(t, ok)
+}
+
+func TestManager_SubmitUnique(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	var runs atomic.Int32
+	slow := func(ctx context.Context) (interface{}, error) {
+		runs.Add(1)
+		<-release
+		return nil, nil
+	}
+
+	// Concurrent callers start one job and all get it back
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := map[string]bool{}
+	var started atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job, ok := m.SubmitUnique("slow", "", 0, slow)
+			if ok {
+				started.Add(1)
+			}
+			mu.Lock()
+			ids[job.ID] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), started.Load())
+	require.Len(t, ids, 1)
+	assert.Equal(t, 1, m.List("slow").Total)
+
+	// Another type is not blocked
+	other, ok := m.SubmitUnique("other", "", 0, func(ctx context.Context) (interface{}, error) { return nil, nil })
+	assert.True(t, ok)
+	waitForJob(t, m, other.ID)
+
+	// Once the job finishes a new one can start
+	close(release)
+	for id := range ids {
+		waitForJob(t, m, id)
+	}
+	job, ok := m.SubmitUnique("slow", "", 0, slow)
+	assert.True(t, ok)
+	waitForJob(t, m, job.ID)
+	assert.Equal(t, int32(2), runs.Load())
+}
+
+func TestManager_GetMissing(t *testing.T) {
+	m := NewManager()
+
+	_, err := m.Get("nonexistent")
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// Status represents the lifecycle state of a job
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Func is the work performed by a job. The returned value is stored as the job result.
+type Func func(ctx context.Context) (interface{}, error)
+
+// Job represents an asynchronous unit of work, exposed by the API as an operation
+type Job struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Target     string      `json:"target,omitempty"`
+	Status     Status      `json:"status"`
+	Timeout    string      `json:"timeout,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// JobList contains a list of jobs
+type JobList struct {
+	Jobs  []Job `json:"jobs"`
+	Total int   `json:"total"`
+}
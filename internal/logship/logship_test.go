@@ -0,0 +1,197 @@
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSink(t *testing.T) {
+	sink, err := NewSink(Options{URL: "http://loki:3100"})
+	require.NoError(t, err)
+	assert.Equal(t, "loki", sink.Name())
+	assert.Equal(t, "http://loki:3100/loki/api/v1/push", sink.(*lokiSink).url)
+
+	sink, err = NewSink(Options{URL: "udp://logs.lan:514"})
+	require.NoError(t, err)
+	assert.Equal(t, "syslog", sink.Name())
+
+	_, err = NewSink(Options{URL: "ftp://logs.lan"})
+	assert.Error(t, err)
+}
+
+func TestLokiSinkSend(t *testing.T) {
+	var push lokiPush
+	var user string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ = r.BasicAuth()
+		json.NewDecoder(r.Body).Decode(&push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(Options{
+		URL:      srv.URL,
+		Username: "tenant",
+		Password: "secret",
+		Hostname: "web-01",
+		Labels:   map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+
+	ts := time.Unix(1700000000, 0)
+	err = sink.Send(context.Background(), []Entry{
+		{Timestamp: ts, Unit: "nginx.service", Priority: 6, Message: "a"},
+		{Timestamp: ts, Unit: "nginx.service", Priority: 6, Message: "b"},
+		{Timestamp: ts, File: "/var/log/app.log", Priority: 3, Message: "c"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "tenant", user)
+	require.Len(t, push.Streams, 2)
+	assert.Equal(t, "nginx.service", push.Streams[0].Stream["unit"])
+	assert.Equal(t, "prod", push.Streams[0].Stream["env"])
+	assert.Equal(t, "web-01", push.Streams[0].Stream["host"])
+	assert.Len(t, push.Streams[0].Values, 2)
+	assert.Equal(t, "1700000000000000000", push.Streams[0].Values[0][0])
+	assert.Equal(t, "/var/log/app.log", push.Streams[1].Stream["filename"])
+	assert.Equal(t, "error", push.Streams[1].Stream["level"])
+}
+
+func TestLokiSinkRejectedBatchIsPermanent(t *testing.T) {
+	status := http.StatusBadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(Options{URL: srv.URL})
+	require.NoError(t, err)
+
+	err = sink.Send(context.Background(), []Entry{{Message: "x"}})
+	assert.True(t, isPermanent(err))
+
+	status = http.StatusServiceUnavailable
+	err = sink.Send(context.Background(), []Entry{{Message: "x"}})
+	require.Error(t, err)
+	assert.False(t, isPermanent(err))
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s := &syslogSink{hostname: "web-01", labels: map[string]string{"env": `p"rod`}}
+	msg := s.format(Entry{
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Unit:      "nginx.service",
+		Priority:  3,
+		Message:   "upstream timed out",
+	})
+	assert.Equal(t, `<131>1 2024-01-02T03:04:05Z web-01 nginx - - [labels@32473 env="p\"rod"] upstream timed out`, msg)
+}
+
+// fakeSink records batches and fails while err is set
+type fakeSink struct {
+	mu      sync.Mutex
+	batches [][]Entry
+	err     error
+}
+
+func (f *fakeSink) Name() string { return "fake" }
+func (f *fakeSink) Close() error { return nil }
+
+func (f *fakeSink) Send(ctx context.Context, entries []Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.batches = append(f.batches, entries)
+	return nil
+}
+
+func TestShipperBatchesAndRetries(t *testing.T) {
+	sink := &fakeSink{err: errors.New("connection refused")}
+	s := newShipper(Options{BatchSize: 2}, sink)
+
+	for _, msg := range []string{"a", "b", "c"} {
+		s.add(Entry{Message: msg})
+	}
+
+	require.Error(t, s.flush(context.Background()))
+	status := s.Status()
+	assert.Equal(t, 3, status.Pending, "failed batch is requeued")
+	assert.Equal(t, 1, status.Failures)
+	assert.Equal(t, minBackoff, s.backoff())
+
+	sink.err = nil
+	require.NoError(t, s.flush(context.Background()))
+	require.Len(t, sink.batches, 2)
+	assert.Equal(t, "a", sink.batches[0][0].Message)
+	assert.Equal(t, "c", sink.batches[1][0].Message)
+
+	status = s.Status()
+	assert.Equal(t, uint64(3), status.Shipped)
+	assert.Equal(t, 0, status.Pending)
+	assert.Equal(t, 0, status.Failures)
+}
+
+func TestShipperDropsRejectedBatch(t *testing.T) {
+	sink := &fakeSink{err: &permanentError{errors.New("bad request")}}
+	s := newShipper(Options{}, sink)
+	s.add(Entry{Message: "a"})
+
+	require.NoError(t, s.flush(context.Background()))
+	status := s.Status()
+	assert.Equal(t, uint64(1), status.Dropped)
+	assert.Equal(t, 0, status.Pending)
+}
+
+func TestShipperBackoff(t *testing.T) {
+	s := newShipper(Options{}, &fakeSink{})
+	s.status.Failures = 3
+	assert.Equal(t, 4*time.Second, s.backoff())
+	s.status.Failures = 20
+	assert.Equal(t, maxBackoff, s.backoff())
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("old line\n"), 0644))
+
+	lines := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tailFile(ctx, path, func(line string) { lines <- line })
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for line")
+			return ""
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	f.WriteString("first\nsec")
+	f.Close()
+	assert.Equal(t, "first", next())
+
+	// Rotation flushes the unterminated line and reads the new file in full
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("fresh\n"), 0644))
+	assert.Equal(t, "sec", next())
+	assert.Equal(t, "fresh", next())
+}
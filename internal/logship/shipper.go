@@ -0,0 +1,274 @@
+package logship
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
+)
+
+const (
+	// MaxPending caps buffered entries; the oldest are dropped beyond it
+	MaxPending = 10000
+
+	sendTimeout = 30 * time.Second
+	minBackoff  = time.Second
+	maxBackoff  = time.Minute
+)
+
+// Shipper follows journal units and log files and forwards their lines to
+// a syslog server or Loki in batches, backing off while the sink is down
+type Shipper struct {
+	opts    Options
+	sink    Sink
+	journal *systemd.JournalReader
+
+	pending []Entry
+	status  Status
+	mu      sync.Mutex
+
+	notify chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewShipper creates a shipper for the sink selected by opts.URL
+func NewShipper(opts Options) (*Shipper, error) {
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+
+	sink, err := NewSink(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newShipper(opts, sink), nil
+}
+
+func newShipper(opts Options, sink Sink) *Shipper {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+
+	return &Shipper{
+		opts:    opts,
+		sink:    sink,
+		journal: systemd.NewJournalReader(),
+		status: Status{
+			Enabled: true,
+			Target:  sink.Name(),
+			URL:     opts.URL,
+			Units:   opts.Units,
+			Files:   opts.Files,
+		},
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Start begins following the configured sources and shipping their lines
+func (s *Shipper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, unit := range s.opts.Units {
+		s.wg.Add(1)
+		go func(unit string) {
+			defer s.wg.Done()
+			s.followUnit(ctx, unit)
+		}(unit)
+	}
+
+	for _, path := range s.opts.Files {
+		s.wg.Add(1)
+		go func(path string) {
+			defer s.wg.Done()
+			tailFile(ctx, path, func(line string) {
+				s.add(Entry{Timestamp: time.Now(), File: path, Priority: 6, Message: line})
+			})
+		}(path)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx)
+	}()
+}
+
+// Stop stops following sources, flushes what is buffered and closes the sink
+func (s *Shipper) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.sink.Close()
+}
+
+// Status returns a snapshot of the shipper's state
+func (s *Shipper) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Pending = len(s.pending)
+	return status
+}
+
+func (s *Shipper) followUnit(ctx context.Context, unit string) {
+	entries := make(chan systemd.JournalEntry, 100)
+	if err := s.journal.Follow(ctx, unit, entries); err != nil {
+		s.setError(err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-entries:
+			if e.Unit == "" {
+				e.Unit = unit
+			}
+			s.add(Entry{Timestamp: e.Timestamp, Unit: e.Unit, Priority: e.Priority, Message: e.Message})
+		}
+	}
+}
+
+// add buffers an entry, dropping the oldest when the buffer is full
+func (s *Shipper) add(e Entry) {
+	s.mu.Lock()
+	if len(s.pending) >= MaxPending {
+		s.pending = s.pending[1:]
+		s.status.Dropped++
+	}
+	s.pending = append(s.pending, e)
+	full := len(s.pending) >= s.opts.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Shipper) run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Last attempt for whatever is buffered
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+		case <-s.notify:
+		}
+
+		if err := s.flush(ctx); err != nil {
+			s.wait(ctx, s.backoff())
+		}
+	}
+}
+
+// flush sends buffered entries in batches until the buffer is empty or a
+// send fails. A failed batch is put back unless the sink rejected it outright.
+func (s *Shipper) flush(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		n := len(s.pending)
+		if n == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		if n > s.opts.BatchSize {
+			n = s.opts.BatchSize
+		}
+		batch := make([]Entry, n)
+		copy(batch, s.pending)
+		s.pending = s.pending[n:]
+		s.mu.Unlock()
+
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := s.sink.Send(sendCtx, batch)
+		cancel()
+
+		now := time.Now()
+		s.mu.Lock()
+		switch {
+		case err == nil:
+			s.status.Shipped += uint64(len(batch))
+			s.status.LastShip = &now
+			s.status.Failures = 0
+			s.status.LastError = ""
+		case isPermanent(err):
+			s.status.Dropped += uint64(len(batch))
+			s.status.LastError = err.Error()
+		default:
+			s.requeue(batch)
+			s.status.Failures++
+			s.status.LastError = err.Error()
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			log.Printf("Log forwarding: %v", err)
+			if !isPermanent(err) {
+				return err
+			}
+		}
+	}
+}
+
+// requeue puts a failed batch back at the front of the buffer. Caller holds mu.
+func (s *Shipper) requeue(batch []Entry) {
+	merged := append(batch, s.pending...)
+	if over := len(merged) - MaxPending; over > 0 {
+		merged = merged[over:]
+		s.status.Dropped += uint64(over)
+	}
+	s.pending = merged
+}
+
+// backoff doubles with each consecutive failure, up to maxBackoff
+func (s *Shipper) backoff() time.Duration {
+	s.mu.Lock()
+	failures := s.status.Failures
+	s.mu.Unlock()
+
+	d := minBackoff
+	for i := 1; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// wait sleeps for d or until ctx ends
+func (s *Shipper) wait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (s *Shipper) setError(err error) {
+	s.mu.Lock()
+	s.status.LastError = err.Error()
+	s.mu.Unlock()
+	log.Printf("Log forwarding: %v", err)
+}
@@ -0,0 +1,321 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// permanentError marks a batch the sink will never accept, so retrying it
+// would block the queue forever
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// NewSink creates the sink selected by the URL scheme
+func NewSink(opts Options) (Sink, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log forwarding URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/loki/api/v1/push"
+		}
+		return &lokiSink{
+			url:      u.String(),
+			username: opts.Username,
+			password: opts.Password,
+			labels:   opts.Labels,
+			hostname: opts.Hostname,
+			http:     &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	case "udp", "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("syslog URL needs a host: %s", opts.URL)
+		}
+		return &syslogSink{
+			network:  u.Scheme,
+			addr:     u.Host,
+			labels:   opts.Labels,
+			hostname: opts.Hostname,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported log forwarding scheme %q (use http, https, udp or tcp)", u.Scheme)
+	}
+}
+
+// lokiSink pushes entries to the Loki push API
+type lokiSink struct {
+	url      string
+	username string
+	password string
+	labels   map[string]string
+	hostname string
+	http     *http.Client
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+func (s *lokiSink) Name() string { return "loki" }
+
+func (s *lokiSink) Close() error { return nil }
+
+func (s *lokiSink) Send(ctx context.Context, entries []Entry) error {
+	body, err := json.Marshal(s.payload(entries))
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("loki push failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("loki returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// payload groups entries into one stream per label set
+func (s *lokiSink) payload(entries []Entry) lokiPush {
+	streams := make(map[string]*lokiStream)
+	var order []string
+
+	for _, e := range entries {
+		labels := s.streamLabels(e)
+		key := labelKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			order = append(order, key)
+		}
+		stream.Values = append(stream.Values, [2]string{
+			strconv.FormatInt(e.Timestamp.UnixNano(), 10),
+			e.Message,
+		})
+	}
+
+	push := lokiPush{Streams: make([]lokiStream, 0, len(order))}
+	for _, key := range order {
+		push.Streams = append(push.Streams, *streams[key])
+	}
+	return push
+}
+
+func (s *lokiSink) streamLabels(e Entry) map[string]string {
+	labels := map[string]string{
+		"job":   "hivedeck-agent",
+		"host":  s.hostname,
+		"level": levelName(e.Priority),
+	}
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	if e.Unit != "" {
+		labels["unit"] = e.Unit
+	}
+	if e.File != "" {
+		labels["filename"] = e.File
+	}
+	return labels
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// syslogFacility is local0
+const syslogFacility = 16
+
+// syslogSink writes RFC 5424 messages over UDP or TCP. TCP uses octet
+// counting framing (RFC 6587).
+type syslogSink struct {
+	network  string
+	addr     string
+	labels   map[string]string
+	hostname string
+
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+func (s *syslogSink) Name() string { return "syslog" }
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *syslogSink) Send(ctx context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, s.network, s.addr)
+		if err != nil {
+			return fmt.Errorf("syslog connect failed: %w", err)
+		}
+		s.conn = conn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, e := range entries {
+		msg := s.format(e)
+		if s.network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		if _, err := io.WriteString(s.conn, msg); err != nil {
+			// Reconnect on the next send
+			s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("syslog write failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// format renders an entry as an RFC 5424 message
+func (s *syslogSink) format(e Entry) string {
+	priority := e.Priority
+	if priority < 0 || priority > 7 {
+		priority = 6
+	}
+
+	app := "-"
+	if e.Unit != "" {
+		app = strings.TrimSuffix(e.Unit, ".service")
+	} else if e.File != "" {
+		app = filepath.Base(e.File)
+	}
+
+	hostname := s.hostname
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s - - %s %s",
+		syslogFacility*8+priority,
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		hostname,
+		truncate(app, 48),
+		structuredData(s.labels),
+		e.Message,
+	)
+}
+
+// structuredData renders labels as an RFC 5424 SD element. 32473 is the
+// private enterprise number reserved for documentation.
+func structuredData(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	var b strings.Builder
+	b.WriteString("[labels@32473")
+	for _, k := range keys {
+		fmt.Fprintf(&b, ` %s="%s"`, k, escaper.Replace(labels[k]))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// levelName maps a syslog severity to a Loki level label
+func levelName(priority int) string {
+	switch {
+	case priority < 0:
+		return "unknown"
+	case priority <= 2:
+		return "critical"
+	case priority == 3:
+		return "error"
+	case priority == 4:
+		return "warning"
+	case priority <= 6:
+		return "info"
+	default:
+		return "debug"
+	}
+}
@@ -0,0 +1,100 @@
+package logship
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// tailPollInterval is how often a tailed file is checked for new lines
+const tailPollInterval = time.Second
+
+// tailFile emits every line appended to path until ctx is done. It starts
+// at the end of the file and follows rotation and truncation.
+func tailFile(ctx context.Context, path string, emit func(string)) {
+	var (
+		file    *os.File
+		reader  *bufio.Reader
+		offset  int64
+		partial string
+	)
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	open := func(fromEnd bool) bool {
+		f, err := os.Open(path)
+		if err != nil {
+			return false
+		}
+		offset = 0
+		if fromEnd {
+			if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+				f.Close()
+				return false
+			}
+		}
+		file, reader, partial = f, bufio.NewReader(f), ""
+		return true
+	}
+
+	drain := func() {
+		for {
+			line, err := reader.ReadString('\n')
+			offset += int64(len(line))
+			if err != nil {
+				partial += line
+				return
+			}
+			if line = strings.TrimRight(partial+line, "\r\n"); line != "" {
+				emit(line)
+			}
+			partial = ""
+		}
+	}
+
+	// Existing content is not shipped; files created later are read in full
+	open(true)
+
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if file != nil {
+			drain()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if file == nil {
+			open(false)
+			continue
+		}
+
+		// Reopen from the start if the file was rotated or truncated
+		info, err := os.Stat(path)
+		current, statErr := file.Stat()
+		if err != nil || statErr != nil {
+			continue
+		}
+		if !os.SameFile(info, current) || info.Size() < offset {
+			// Lines written to a rotated file before it was moved
+			drain()
+			if partial != "" {
+				emit(partial)
+			}
+			file.Close()
+			file = nil
+			open(false)
+		}
+	}
+}
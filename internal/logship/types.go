@@ -0,0 +1,54 @@
+package logship
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is a single log line to forward
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Unit      string    `json:"unit,omitempty"` // Set for journal entries
+	File      string    `json:"file,omitempty"` // Set for file entries
+	Priority  int       `json:"priority"`       // Syslog severity, 0-7
+	Message   string    `json:"message"`
+}
+
+// Options configures a Shipper
+type Options struct {
+	// URL selects the sink: http(s)://host:3100 for Loki, udp://host:514
+	// or tcp://host:514 for syslog
+	URL      string
+	Username string // Loki basic auth
+	Password string
+
+	Units  []string // Journal units to follow
+	Files  []string // Log files to tail
+	Labels map[string]string
+
+	Hostname      string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Sink delivers batches of entries to a remote log store
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, entries []Entry) error
+	Close() error
+}
+
+// Status describes the state of log forwarding
+type Status struct {
+	Enabled   bool       `json:"enabled"`
+	Target    string     `json:"target,omitempty"` // loki or syslog
+	URL       string     `json:"url,omitempty"`
+	Units     []string   `json:"units,omitempty"`
+	Files     []string   `json:"files,omitempty"`
+	Shipped   uint64     `json:"shipped"`
+	Dropped   uint64     `json:"dropped"`
+	Pending   int        `json:"pending"`
+	Failures  int        `json:"failures"` // Consecutive failed sends
+	LastShip  *time.Time `json:"last_ship,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
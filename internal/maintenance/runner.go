@@ -0,0 +1,267 @@
+package maintenance
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// MaxHistory is the number of reports kept in memory
+	MaxHistory = 50
+	// maxOutput bounds each step's output in a report; the end is kept
+	maxOutput = 16 << 10
+)
+
+// Runner runs maintenance steps in a weekly window. Steps run in order,
+// each after the previous one finishes, whether or not it succeeded. Steps
+// that have not started when the window ends are skipped.
+type Runner struct {
+	schedule    Schedule
+	window      time.Duration
+	steps       []Step
+	historyFile string
+	onStart     func()
+	onReport    func(*Report)
+
+	mu      sync.Mutex
+	started bool
+	running bool
+	next    time.Time
+	history []Report
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewRunner creates a runner. Reports are appended to maintenance.jsonl in
+// dataDir.
+func NewRunner(schedule Schedule, window time.Duration, steps []Step, dataDir string) *Runner {
+	r := &Runner{
+		schedule: schedule,
+		window:   window,
+		steps:    steps,
+		stop:     make(chan struct{}),
+	}
+	if dataDir != "" {
+		r.historyFile = filepath.Join(dataDir, "maintenance.jsonl")
+		r.loadHistory()
+	}
+	return r
+}
+
+// OnStart sets a function called before the first step of each run
+func (r *Runner) OnStart(fn func()) {
+	r.onStart = fn
+}
+
+// OnReport sets a function called with each finished report
+func (r *Runner) OnReport(fn func(*Report)) {
+	r.onReport = fn
+}
+
+// Start runs the steps at each window until Stop is called
+func (r *Runner) Start() {
+	r.mu.Lock()
+	r.started = true
+	r.mu.Unlock()
+
+	go func() {
+		for {
+			next := r.schedule.Next(time.Now())
+			r.mu.Lock()
+			r.next = next
+			r.mu.Unlock()
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				if _, err := r.Run(context.Background(), "schedule"); err != nil {
+					log.Printf("Auto-maintenance: %v", err)
+				}
+			case <-r.stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends scheduled runs. A run in progress finishes its current step.
+func (r *Runner) Stop() {
+	r.once.Do(func() { close(r.stop) })
+}
+
+// Run runs every step now, within one window's time
+func (r *Runner) Run(ctx context.Context, trigger string) (*Report, error) {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil, apierror.Conflict("maintenance is already running")
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, r.window)
+	defer cancel()
+
+	report := &Report{ID: newID(), Trigger: trigger, StartedAt: time.Now(), Success: true}
+	log.Printf("Auto-maintenance started (%s)", trigger)
+	if r.onStart != nil {
+		r.onStart()
+	}
+
+	for _, step := range r.steps {
+		result := StepResult{Name: step.Name}
+		if ctx.Err() != nil {
+			result.Skipped = true
+			result.Error = "maintenance window ended"
+			report.Success = false
+			report.Steps = append(report.Steps, result)
+			continue
+		}
+
+		start := time.Now()
+		output, err := step.Run(ctx)
+		result.Duration = time.Since(start).Seconds()
+		result.Output = truncate(output)
+		result.Success = err == nil
+		if err != nil {
+			result.Error = err.Error()
+			report.Success = false
+		}
+		report.Steps = append(report.Steps, result)
+	}
+
+	report.FinishedAt = time.Now()
+	r.record(*report)
+	log.Printf("Auto-maintenance finished (success=%t)", report.Success)
+	if r.onReport != nil {
+		r.onReport(report)
+	}
+	return report, nil
+}
+
+// Status returns the schedule, the next run and the last report
+func (r *Runner) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := Status{
+		Enabled:  r.started,
+		Schedule: r.schedule.String(),
+		Window:   r.window.String(),
+		Running:  r.running,
+	}
+	for _, step := range r.steps {
+		status.Steps = append(status.Steps, step.Name)
+	}
+	if r.started && !r.next.IsZero() {
+		next := r.next
+		status.NextRun = &next
+	}
+	if len(r.history) > 0 {
+		last := r.history[len(r.history)-1]
+		status.LastRun = &last
+	}
+	return status
+}
+
+// History returns up to limit reports, most recent first
+func (r *Runner) History(limit int) []Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limit <= 0 || limit > len(r.history) {
+		limit = len(r.history)
+	}
+	out := make([]Report, 0, limit)
+	for i := len(r.history) - 1; i >= len(r.history)-limit; i-- {
+		out = append(out, r.history[i])
+	}
+	return out
+}
+
+func (r *Runner) record(report Report) {
+	r.mu.Lock()
+	r.history = append(r.history, report)
+	if len(r.history) > MaxHistory {
+		r.history = r.history[len(r.history)-MaxHistory:]
+	}
+	r.mu.Unlock()
+
+	if r.historyFile == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.historyFile), 0750); err != nil {
+		log.Printf("Failed to create maintenance data directory: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(r.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		log.Printf("Failed to open maintenance history: %v", err)
+		return
+	}
+	defer f.Close()
+
+	data, _ := json.Marshal(report)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("Failed to write maintenance history: %v", err)
+	}
+}
+
+func (r *Runner) loadHistory() {
+	f, err := os.Open(r.historyFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		var report Report
+		if err := json.Unmarshal(scanner.Bytes(), &report); err != nil {
+			continue
+		}
+		r.history = append(r.history, report)
+	}
+
+	if len(r.history) > MaxHistory {
+		r.history = r.history[len(r.history)-MaxHistory:]
+	}
+}
+
+// truncate keeps the end of long output, where errors usually are
+func truncate(output string) string {
+	if len(output) <= maxOutput {
+		return output
+	}
+	return fmt.Sprintf("[%d bytes truncated]\n%s", len(output)-maxOutput, output[len(output)-maxOutput:])
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,165 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestRunnerRun(t *testing.T) {
+	dir := t.TempDir()
+	var order []string
+	step := func(name string, err error) Step {
+		return Func(name, func(ctx context.Context) (string, error) {
+			order = append(order, name)
+			return name + " done", err
+		})
+	}
+
+	schedule, _ := ParseSchedule("sun 03:30")
+	r := NewRunner(schedule, time.Minute, []Step{
+		step("first", nil),
+		step("second", errors.New("boom")),
+		step("third", nil),
+	}, dir)
+
+	var started bool
+	var reported *Report
+	r.OnStart(func() { started = true })
+	r.OnReport(func(report *Report) { reported = report })
+
+	report, err := r.Run(context.Background(), "manual")
+	require.NoError(t, err)
+
+	// A failed step does not stop the ones after it
+	assert.Equal(t, []string{"first", "second", "third"}, order)
+	assert.True(t, started)
+	assert.Same(t, report, reported)
+	assert.False(t, report.Success)
+	assert.Equal(t, "manual", report.Trigger)
+	require.Len(t, report.Steps, 3)
+	assert.True(t, report.Steps[0].Success)
+	assert.Equal(t, "first done", report.Steps[0].Output)
+	assert.False(t, report.Steps[1].Success)
+	assert.Equal(t, "boom", report.Steps[1].Error)
+	assert.True(t, report.Steps[2].Success)
+
+	status := r.Status()
+	assert.False(t, status.Enabled)
+	assert.Equal(t, "sun 03:30", status.Schedule)
+	assert.Equal(t, []string{"first", "second", "third"}, status.Steps)
+	require.NotNil(t, status.LastRun)
+	assert.Equal(t, report.ID, status.LastRun.ID)
+
+	// History survives a restart
+	_, err = os.Stat(filepath.Join(dir, "maintenance.jsonl"))
+	require.NoError(t, err)
+	reloaded := NewRunner(schedule, time.Minute, nil, dir)
+	history := reloaded.History(10)
+	require.Len(t, history, 1)
+	assert.Equal(t, report.ID, history[0].ID)
+}
+
+func TestRunnerWindow(t *testing.T) {
+	slow := Func("slow", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	never := Func("never", func(ctx context.Context) (string, error) {
+		t.Fatal("step after the window ran")
+		return "", nil
+	})
+
+	r := NewRunner(Schedule{}, 20*time.Millisecond, []Step{slow, never}, "")
+	report, err := r.Run(context.Background(), "manual")
+	require.NoError(t, err)
+
+	require.Len(t, report.Steps, 2)
+	assert.False(t, report.Steps[0].Success)
+	assert.True(t, report.Steps[1].Skipped)
+	assert.False(t, report.Success)
+}
+
+func TestRunnerConflict(t *testing.T) {
+	release := make(chan struct{})
+	running := make(chan struct{})
+	block := Func("block", func(ctx context.Context) (string, error) {
+		close(running)
+		<-release
+		return "", nil
+	})
+
+	r := NewRunner(Schedule{}, time.Minute, []Step{block}, "")
+	done := make(chan struct{})
+	go func() {
+		_, _ = r.Run(context.Background(), "manual")
+		close(done)
+	}()
+	<-running
+
+	assert.True(t, r.Status().Running)
+	_, err := r.Run(context.Background(), "manual")
+	assert.ErrorIs(t, err, apierror.ErrConflict)
+
+	close(release)
+	<-done
+	assert.False(t, r.Status().Running)
+}
+
+func TestRebootIfRequired(t *testing.T) {
+	rebootRequiredFile = filepath.Join(t.TempDir(), "reboot-required")
+	defer func() { rebootRequiredFile = "/var/run/reboot-required" }()
+
+	var rebooted bool
+	step := RebootIfRequired(func(ctx context.Context) error {
+		rebooted = true
+		return nil
+	})
+
+	output, err := step.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "no reboot required", output)
+	assert.False(t, rebooted)
+
+	require.NoError(t, os.WriteFile(rebootRequiredFile, []byte("*** System restart required ***\n"), 0644))
+	require.NoError(t, os.WriteFile(rebootRequiredFile+".pkgs", []byte("linux-base\nlibc6\n"), 0644))
+	output, err = step.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "reboot required by linux-base, libc6; reboot scheduled", output)
+	assert.True(t, rebooted)
+}
+
+func TestAptUpgrade(t *testing.T) {
+	var calls [][]string
+	runCommand = func(ctx context.Context, env []string, name string, args ...string) (string, error) {
+		assert.Equal(t, []string{"DEBIAN_FRONTEND=noninteractive"}, env)
+		calls = append(calls, append([]string{name}, args...))
+		return name + "\n", nil
+	}
+	defer func() { runCommand = defaultRunCommand }()
+
+	_, err := AptUpgrade(nil).Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	assert.Equal(t, []string{"apt-get", "update"}, calls[0])
+	assert.Equal(t, "upgrade", calls[1][len(calls[1])-1])
+
+	// With the helper, apt-get runs through it instead
+	calls = nil
+	var helperCalls [][]string
+	_, err = AptUpgrade(func(ctx context.Context, args ...string) ([]byte, error) {
+		helperCalls = append(helperCalls, args)
+		return nil, nil
+	}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+	assert.Equal(t, [][]string{{"apt-get", "update"}, {"apt-get", "upgrade"}}, helperCalls)
+}
@@ -0,0 +1,81 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Schedule is a weekly maintenance window start in local time
+type Schedule struct {
+	Days   []time.Weekday // Empty means every day
+	Hour   int
+	Minute int
+}
+
+// ParseSchedule parses "daily 03:30", "sun 03:30" or "mon,thu 02:00"
+func ParseSchedule(s string) (Schedule, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) != 2 {
+		return Schedule{}, fmt.Errorf("schedule must be \"<days> HH:MM\", e.g. \"sun 03:30\"")
+	}
+
+	var sched Schedule
+	if _, err := fmt.Sscanf(fields[1], "%d:%d", &sched.Hour, &sched.Minute); err != nil ||
+		sched.Hour < 0 || sched.Hour > 23 || sched.Minute < 0 || sched.Minute > 59 {
+		return Schedule{}, fmt.Errorf("invalid time %q", fields[1])
+	}
+
+	if fields[0] != "daily" {
+		for _, day := range strings.Split(fields[0], ",") {
+			wd, ok := weekdays[day]
+			if !ok {
+				return Schedule{}, fmt.Errorf("invalid day %q", day)
+			}
+			sched.Days = append(sched.Days, wd)
+		}
+	}
+	return sched, nil
+}
+
+// Next returns the first window start after now
+func (s Schedule) Next(now time.Time) time.Time {
+	start := time.Date(now.Year(), now.Month(), now.Day(), s.Hour, s.Minute, 0, 0, now.Location())
+	for i := 0; i <= 7; i++ {
+		t := start.AddDate(0, 0, i)
+		if t.After(now) && s.onDay(t.Weekday()) {
+			return t
+		}
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+func (s Schedule) onDay(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the schedule as ParseSchedule accepts it
+func (s Schedule) String() string {
+	days := "daily"
+	if len(s.Days) > 0 {
+		names := make([]string, len(s.Days))
+		for i, d := range s.Days {
+			names[i] = strings.ToLower(d.String()[:3])
+		}
+		days = strings.Join(names, ",")
+	}
+	return fmt.Sprintf("%s %02d:%02d", days, s.Hour, s.Minute)
+}
@@ -0,0 +1,46 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	s, err := ParseSchedule("Mon,thu 02:05")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Thursday}, s.Days)
+	assert.Equal(t, 2, s.Hour)
+	assert.Equal(t, 5, s.Minute)
+	assert.Equal(t, "mon,thu 02:05", s.String())
+
+	s, err = ParseSchedule("daily 23:59")
+	require.NoError(t, err)
+	assert.Empty(t, s.Days)
+	assert.Equal(t, "daily 23:59", s.String())
+
+	for _, bad := range []string{"", "sun", "sun 24:00", "sun 03:60", "someday 03:00", "sun 03:00 extra"} {
+		_, err := ParseSchedule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	daily, _ := ParseSchedule("daily 03:30")
+	assert.Equal(t, time.Date(2026, 10, 15, 3, 30, 0, 0, time.UTC), daily.Next(now))
+
+	later, _ := ParseSchedule("daily 13:00")
+	assert.Equal(t, time.Date(2026, 10, 14, 13, 0, 0, 0, time.UTC), later.Next(now))
+
+	sunday, _ := ParseSchedule("sun 03:30")
+	assert.Equal(t, time.Date(2026, 10, 18, 3, 30, 0, 0, time.UTC), sunday.Next(now))
+
+	// The same day's window has passed, so the next one is a week later
+	wednesday, _ := ParseSchedule("wed 03:30")
+	assert.Equal(t, time.Date(2026, 10, 21, 3, 30, 0, 0, time.UTC), wednesday.Next(now))
+}
@@ -0,0 +1,112 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Step names accepted in MAINTENANCE_STEPS
+const (
+	StepAptUpgrade    = "apt-upgrade"
+	StepDockerPrune   = "docker-prune"
+	StepDockerUpdate  = "docker-update"
+	StepJournalVacuum = "journal-vacuum"
+	StepReboot        = "reboot"
+)
+
+// rebootRequiredFile is created by Debian and Ubuntu when an upgrade needs a reboot
+var rebootRequiredFile = "/var/run/reboot-required"
+
+// runCommand runs a command and returns its combined output; replaced in tests
+var runCommand = defaultRunCommand
+
+func defaultRunCommand(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// AptUpgrade refreshes the package lists and installs available upgrades,
+// keeping locally modified configuration files. With a helper, apt-get runs
+// through it as "apt-get update" and "apt-get upgrade".
+func AptUpgrade(helper func(ctx context.Context, args ...string) ([]byte, error)) Step {
+	args := map[string][]string{
+		"update":  {"update"},
+		"upgrade": {"-y", "-o", "Dpkg::Options::=--force-confold", "upgrade"},
+	}
+	apt := func(ctx context.Context, cmd string) (string, error) {
+		if helper != nil {
+			out, err := helper(ctx, "apt-get", cmd)
+			return string(out), err
+		}
+		return runCommand(ctx, []string{"DEBIAN_FRONTEND=noninteractive"}, "apt-get", args[cmd]...)
+	}
+
+	return Step{
+		Name: StepAptUpgrade,
+		Run: func(ctx context.Context) (string, error) {
+			update, err := apt(ctx, "update")
+			if err != nil {
+				return update, fmt.Errorf("apt-get update failed: %w", err)
+			}
+
+			upgrade, err := apt(ctx, "upgrade")
+			output := update + upgrade
+			if err != nil {
+				return output, fmt.Errorf("apt-get upgrade failed: %w", err)
+			}
+			return output, nil
+		},
+	}
+}
+
+// JournalVacuum shrinks the systemd journal to size (e.g. "500M")
+func JournalVacuum(size string) Step {
+	return Step{
+		Name: StepJournalVacuum,
+		Run: func(ctx context.Context) (string, error) {
+			output, err := runCommand(ctx, nil, "journalctl", "--vacuum-size="+size)
+			if err != nil {
+				return output, fmt.Errorf("journalctl --vacuum-size failed: %w", err)
+			}
+			return output, nil
+		},
+	}
+}
+
+// Func wraps a function as a step
+func Func(name string, run func(ctx context.Context) (string, error)) Step {
+	return Step{Name: name, Run: run}
+}
+
+// RebootIfRequired calls reboot when an earlier step left the system
+// needing a restart.
+func RebootIfRequired(reboot func(ctx context.Context) error) Step {
+	return Step{
+		Name: StepReboot,
+		Run: func(ctx context.Context) (string, error) {
+			data, err := os.ReadFile(rebootRequiredFile)
+			if os.IsNotExist(err) {
+				return "no reboot required", nil
+			}
+
+			output := "reboot required"
+			if pkgs, err := os.ReadFile(rebootRequiredFile + ".pkgs"); err == nil && len(pkgs) > 0 {
+				output += " by " + strings.Join(strings.Fields(string(pkgs)), ", ")
+			} else if len(data) > 0 {
+				output = strings.TrimSpace(string(data))
+			}
+
+			if err := reboot(ctx); err != nil {
+				return output, fmt.Errorf("failed to schedule reboot: %w", err)
+			}
+			return output + "; reboot scheduled", nil
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+)
+
+// Step is one maintenance job. Run returns the output to include in the
+// report.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Name     string  `json:"name"`
+	Success  bool    `json:"success"`
+	Skipped  bool    `json:"skipped,omitempty"` // The window ended before the step started
+	Output   string  `json:"output,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// Report is the outcome of one maintenance run
+type Report struct {
+	ID         string       `json:"id"`
+	Trigger    string       `json:"trigger"` // "schedule" or "manual"
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Success    bool         `json:"success"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// Status describes the auto-maintenance configuration and recent runs
+type Status struct {
+	Enabled  bool       `json:"enabled"`
+	Schedule string     `json:"schedule,omitempty"`
+	Window   string     `json:"window,omitempty"`
+	Steps    []string   `json:"steps"`
+	NextRun  *time.Time `json:"next_run,omitempty"`
+	Running  bool       `json:"running"`
+	LastRun  *Report    `json:"last_run,omitempty"`
+}
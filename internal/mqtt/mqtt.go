@@ -0,0 +1,321 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// publishTimeout bounds how long a single publish waits for the broker
+const publishTimeout = 10 * time.Second
+
+// Publisher sends metrics snapshots and agent events to an MQTT broker.
+//
+// Topics, relative to the prefix:
+//
+//	status          online/offline (retained, also the last will)
+//	state           flattened State snapshot (retained)
+//	metrics         full metrics snapshot
+//	events/<type>   agent events such as alerts and service/container actions
+type Publisher struct {
+	opts     Options
+	hostname string
+	collect  func() (*system.AllMetrics, error)
+	bus      *events.Bus
+	client   paho.Client
+
+	status Status
+	mu     sync.Mutex
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewPublisher creates a publisher. collect provides the metrics snapshot
+// published every interval.
+func NewPublisher(opts Options, collect func() (*system.AllMetrics, error), bus *events.Bus) *Publisher {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "agent"
+	}
+	if opts.TopicPrefix == "" {
+		opts.TopicPrefix = "hivedeck/" + hostname
+	}
+	opts.TopicPrefix = strings.TrimSuffix(opts.TopicPrefix, "/")
+	if opts.ClientID == "" {
+		opts.ClientID = "hivedeck-" + hostname
+	}
+	if opts.DiscoveryPrefix == "" {
+		opts.DiscoveryPrefix = "homeassistant"
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+
+	return &Publisher{
+		opts:     opts,
+		hostname: hostname,
+		collect:  collect,
+		bus:      bus,
+		status: Status{
+			Enabled:     true,
+			Broker:      opts.Broker,
+			TopicPrefix: opts.TopicPrefix,
+			Discovery:   opts.Discovery,
+		},
+		stop: make(chan struct{}),
+	}
+}
+
+// Start connects to the broker in the background and publishes until Stop
+func (p *Publisher) Start() {
+	clientOpts := paho.NewClientOptions().
+		AddBroker(p.opts.Broker).
+		SetClientID(p.opts.ClientID).
+		SetUsername(p.opts.Username).
+		SetPassword(p.opts.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(p.topic("status"), "offline", 1, true).
+		SetOnConnectHandler(func(paho.Client) { p.onConnect() }).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			p.setError(fmt.Errorf("connection lost: %w", err))
+		})
+
+	p.client = paho.NewClient(clientOpts)
+	p.client.Connect()
+
+	go p.run()
+}
+
+// Stop publishes offline and disconnects
+func (p *Publisher) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+		if p.client != nil && p.client.IsConnected() {
+			p.publish(p.topic("status"), true, []byte("offline"))
+			p.client.Disconnect(250)
+		}
+	})
+}
+
+// Status returns the connection state
+func (p *Publisher) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := p.status
+	status.Connected = p.client != nil && p.client.IsConnected()
+	return status
+}
+
+func (p *Publisher) run() {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+
+	agentEvents, unsubscribe := p.bus.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.publishMetrics()
+		case event := <-agentEvents:
+			payload, _ := json.Marshal(event)
+			p.publish(p.topic("events/"+strings.ReplaceAll(event.Type, ".", "/")), false, payload)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *Publisher) onConnect() {
+	p.setError(nil)
+	p.publish(p.topic("status"), true, []byte("online"))
+
+	if p.opts.Discovery {
+		for topic, config := range DiscoveryConfigs(p.opts.DiscoveryPrefix, p.opts.TopicPrefix, p.hostname) {
+			p.publish(topic, true, config)
+		}
+	}
+
+	p.publishMetrics()
+}
+
+func (p *Publisher) publishMetrics() {
+	if p.client == nil || !p.client.IsConnected() {
+		return
+	}
+
+	metrics, err := p.collect()
+	if err != nil || metrics == nil {
+		p.setError(fmt.Errorf("failed to collect metrics: %v", err))
+		return
+	}
+
+	state, _ := json.Marshal(NewState(metrics))
+	full, _ := json.Marshal(metrics)
+	p.publish(p.topic("state"), true, state)
+	p.publish(p.topic("metrics"), false, full)
+}
+
+func (p *Publisher) publish(topic string, retain bool, payload []byte) {
+	if p.client == nil || !p.client.IsConnected() {
+		return
+	}
+
+	token := p.client.Publish(topic, 1, retain, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		p.setError(fmt.Errorf("publish to %s timed out", topic))
+		return
+	}
+	if err := token.Error(); err != nil {
+		p.setError(fmt.Errorf("publish to %s: %w", topic, err))
+		return
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	p.status.LastPublish = &now
+	p.mu.Unlock()
+}
+
+func (p *Publisher) setError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		p.status.LastError = ""
+		return
+	}
+	p.status.LastError = err.Error()
+	log.Printf("MQTT: %v", err)
+}
+
+func (p *Publisher) topic(suffix string) string {
+	return p.opts.TopicPrefix + "/" + suffix
+}
+
+// NewState flattens a metrics snapshot. Disk usage is that of the root
+// filesystem (or the fullest partition if / is not mounted), and the
+// temperature is the hottest sensor.
+func NewState(m *system.AllMetrics) State {
+	state := State{
+		Timestamp:     m.Timestamp,
+		CPUPercent:    round(m.CPU.UsageTotal),
+		Load1:         m.CPU.LoadAvg1,
+		MemoryPercent: round(m.Memory.UsedPercent),
+		SwapPercent:   round(m.Memory.SwapPercent),
+		Uptime:        m.Host.Uptime,
+		Processes:     m.Host.Procs,
+	}
+
+	for _, part := range m.Disk.Partitions {
+		if part.Mountpoint == "/" {
+			state.DiskPercent = round(part.UsedPercent)
+			break
+		}
+		if part.UsedPercent > state.DiskPercent {
+			state.DiskPercent = round(part.UsedPercent)
+		}
+	}
+
+	for _, t := range m.Host.Temperatures {
+		if state.Temperature == nil || t.Temperature > *state.Temperature {
+			temp := round(t.Temperature)
+			state.Temperature = &temp
+		}
+	}
+
+	return state
+}
+
+// round keeps one decimal, rounding halves away from zero
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// sensor describes a Home Assistant sensor backed by a State field
+type sensor struct {
+	key         string
+	name        string
+	unit        string
+	deviceClass string
+	icon        string
+}
+
+var sensors = []sensor{
+	{key: "cpu_percent", name: "CPU", unit: "%", icon: "mdi:cpu-64-bit"},
+	{key: "load_1", name: "Load (1m)", icon: "mdi:gauge"},
+	{key: "memory_percent", name: "Memory", unit: "%", icon: "mdi:memory"},
+	{key: "swap_percent", name: "Swap", unit: "%", icon: "mdi:swap-horizontal"},
+	{key: "disk_percent", name: "Disk", unit: "%", icon: "mdi:harddisk"},
+	{key: "uptime", name: "Uptime", unit: "s", deviceClass: "duration"},
+	{key: "processes", name: "Processes", icon: "mdi:format-list-numbered"},
+	{key: "temperature", name: "Temperature", unit: "°C", deviceClass: "temperature"},
+}
+
+// DiscoveryConfigs returns the retained Home Assistant discovery messages,
+// keyed by topic, that register a device with one sensor per State field
+func DiscoveryConfigs(discoveryPrefix, topicPrefix, hostname string) map[string][]byte {
+	nodeID := sanitizeID(hostname)
+	device := map[string]interface{}{
+		"identifiers":  []string{"hivedeck_" + nodeID},
+		"name":         hostname,
+		"manufacturer": "Hivedeck",
+		"model":        "hivedeck-agent",
+	}
+
+	configs := make(map[string][]byte, len(sensors))
+	for _, s := range sensors {
+		config := map[string]interface{}{
+			"name":                  s.name,
+			"unique_id":             "hivedeck_" + nodeID + "_" + s.key,
+			"object_id":             nodeID + "_" + s.key,
+			"state_topic":           topicPrefix + "/state",
+			"value_template":        "{{ value_json." + s.key + " }}",
+			"availability_topic":    topicPrefix + "/status",
+			"payload_available":     "online",
+			"payload_not_available": "offline",
+			"state_class":           "measurement",
+			"device":                device,
+		}
+		if s.unit != "" {
+			config["unit_of_measurement"] = s.unit
+		}
+		if s.deviceClass != "" {
+			config["device_class"] = s.deviceClass
+		}
+		if s.icon != "" {
+			config["icon"] = s.icon
+		}
+
+		payload, _ := json.Marshal(config)
+		configs[fmt.Sprintf("%s/sensor/%s/%s/config", discoveryPrefix, nodeID, s.key)] = payload
+	}
+
+	return configs
+}
+
+// sanitizeID makes a hostname safe for discovery topics and entity IDs
+func sanitizeID(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+func TestNewState(t *testing.T) {
+	m := &system.AllMetrics{
+		Host: system.HostInfo{
+			Uptime: 3600,
+			Procs:  120,
+			Temperatures: []system.Temperature{
+				{SensorKey: "acpi", Temperature: 41.26},
+				{SensorKey: "cpu", Temperature: 58.04},
+			},
+		},
+		CPU:    system.CPUInfo{UsageTotal: 12.345, LoadAvg1: 0.5},
+		Memory: system.MemoryInfo{UsedPercent: 63.37},
+		Disk: system.DiskInfo{Partitions: []system.DiskPartition{
+			{Mountpoint: "/data", UsedPercent: 91},
+			{Mountpoint: "/", UsedPercent: 40.04},
+		}},
+	}
+
+	state := NewState(m)
+	assert.Equal(t, 12.3, state.CPUPercent)
+	assert.Equal(t, 63.4, state.MemoryPercent)
+	assert.Equal(t, 40.0, state.DiskPercent, "root filesystem wins over fuller partitions")
+	assert.Equal(t, uint64(3600), state.Uptime)
+	assert.Equal(t, uint64(120), state.Processes)
+	require.NotNil(t, state.Temperature)
+	assert.Equal(t, 58.0, *state.Temperature)
+}
+
+func TestNewStateWithoutRoot(t *testing.T) {
+	m := &system.AllMetrics{
+		Disk: system.DiskInfo{Partitions: []system.DiskPartition{
+			{Mountpoint: "/boot", UsedPercent: 20},
+			{Mountpoint: "/data", UsedPercent: 75},
+		}},
+	}
+
+	state := NewState(m)
+	assert.Equal(t, 75.0, state.DiskPercent)
+	assert.Nil(t, state.Temperature)
+}
+
+func TestDiscoveryConfigs(t *testing.T) {
+	configs := DiscoveryConfigs("homeassistant", "hivedeck/web-01", "Web-01.local")
+	require.Len(t, configs, len(sensors))
+
+	payload, ok := configs["homeassistant/sensor/web_01_local/cpu_percent/config"]
+	require.True(t, ok)
+
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &config))
+	assert.Equal(t, "hivedeck_web_01_local_cpu_percent", config["unique_id"])
+	assert.Equal(t, "hivedeck/web-01/state", config["state_topic"])
+	assert.Equal(t, "hivedeck/web-01/status", config["availability_topic"])
+	assert.Equal(t, "{{ value_json.cpu_percent }}", config["value_template"])
+	assert.Equal(t, "%", config["unit_of_measurement"])
+
+	device, ok := config["device"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "Web-01.local", device["name"])
+}
+
+func TestRound(t *testing.T) {
+	assert.Equal(t, 42.5, round(42.46))
+	assert.Equal(t, 0.0, round(0.04))
+	// Below zero, as outdoor sensors can read, halves round away from zero too
+	assert.Equal(t, -3.5, round(-3.46))
+	assert.Equal(t, -0.2, round(-0.15))
+}
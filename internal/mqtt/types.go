@@ -0,0 +1,40 @@
+package mqtt
+
+import "time"
+
+// Options configures the MQTT publisher
+type Options struct {
+	Broker          string // e.g. tcp://broker.lan:1883
+	ClientID        string
+	Username        string
+	Password        string
+	TopicPrefix     string // defaults to hivedeck/<hostname>
+	Interval        time.Duration
+	Discovery       bool   // publish Home Assistant discovery configs
+	DiscoveryPrefix string // defaults to homeassistant
+}
+
+// State is the flattened metrics snapshot published to <prefix>/state and
+// referenced by the Home Assistant sensors
+type State struct {
+	Timestamp     time.Time `json:"timestamp"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	Load1         float64   `json:"load_1"`
+	MemoryPercent float64   `json:"memory_percent"`
+	SwapPercent   float64   `json:"swap_percent"`
+	DiskPercent   float64   `json:"disk_percent"`
+	Uptime        uint64    `json:"uptime"`
+	Processes     uint64    `json:"processes"`
+	Temperature   *float64  `json:"temperature,omitempty"`
+}
+
+// Status describes the MQTT connection
+type Status struct {
+	Enabled     bool       `json:"enabled"`
+	Broker      string     `json:"broker,omitempty"`
+	TopicPrefix string     `json:"topic_prefix,omitempty"`
+	Connected   bool       `json:"connected"`
+	Discovery   bool       `json:"discovery"`
+	LastPublish *time.Time `json:"last_publish,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
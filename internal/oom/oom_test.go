@@ -0,0 +1,82 @@
+package oom
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+const kernelLog = `postgres invoked oom-killer: gfp_mask=0x140cca(GFP_HIGHUSER_MOVABLE|__GFP_COMP), order=0, oom_score_adj=0
+Mem-Info:
+oom-kill:constraint=CONSTRAINT_MEMCG,nodemask=(null),cpuset=docker-3f2a9c1b7d4e5f60718293a4b5c6d7e8f90112233445566778899aabbccddeeff.scope,mems_allowed=0,oom_memcg=/system.slice/docker-3f2a9c1b7d4e5f60718293a4b5c6d7e8f90112233445566778899aabbccddeeff.scope,task_memcg=/system.slice/docker-3f2a9c1b7d4e5f60718293a4b5c6d7e8f90112233445566778899aabbccddeeff.scope,task=postgres,pid=4321,uid=999
+Memory cgroup out of memory: Killed process 4321 (postgres) total-vm:2097152kB, anon-rss:1048576kB, file-rss:2048kB, shmem-rss:0kB, UID:999 pgtables:2200kB oom_score_adj:0
+oom_reaper: reaped process 4321 (postgres), now anon-rss:0kB, file-rss:0kB, shmem-rss:0kB
+`
+
+func TestParser(t *testing.T) {
+	var p parser
+	var kills []*Kill
+	at := time.Now()
+	for _, line := range strings.Split(kernelLog, "\n") {
+		if k := p.line(at, line); k != nil {
+			kills = append(kills, k)
+		}
+	}
+
+	require.Len(t, kills, 1)
+	k := kills[0]
+	assert.Equal(t, 4321, k.PID)
+	assert.Equal(t, "postgres", k.Process)
+	assert.Equal(t, 999, k.UID)
+	assert.Equal(t, uint64(2<<30), k.TotalVM)
+	assert.Equal(t, uint64(1<<30)+2048*1024, k.RSS())
+	assert.Equal(t, "CONSTRAINT_MEMCG", k.Constraint)
+	assert.Equal(t, "3f2a9c1b7d4e", k.Container)
+	assert.Equal(t, "postgres", k.InvokedBy)
+
+	// A kill without the preceding lines
+	k = p.line(at, "Out of memory: Killed process 77 (java) total-vm:100kB, anon-rss:50kB, file-rss:0kB, shmem-rss:0kB, UID:0 pgtables:1kB oom_score_adj:-100")
+	require.NotNil(t, k)
+	assert.Equal(t, "java", k.Process)
+	assert.Equal(t, -100, k.OOMScoreAdj)
+	assert.Empty(t, k.InvokedBy)
+}
+
+func TestWatcher(t *testing.T) {
+	origLook, origStart := lookPath, startCommand
+	defer func() { lookPath, startCommand = origLook, origStart }()
+
+	lookPath = func(string) (string, error) { return "/usr/bin/journalctl", nil }
+	startCommand = func(_ context.Context, name string, args ...string) (io.ReadCloser, error) {
+		if args[1] == "-b" {
+			return io.NopCloser(strings.NewReader(
+				`{"__REALTIME_TIMESTAMP":"1700000000000000","MESSAGE":"Out of memory: Killed process 10 (old) total-vm:1kB, anon-rss:1kB, file-rss:0kB, shmem-rss:0kB, UID:0 pgtables:1kB oom_score_adj:0"}` + "\n")), nil
+		}
+		return io.NopCloser(strings.NewReader(
+			`{"__REALTIME_TIMESTAMP":"1700000100000000","MESSAGE":"Out of memory: Killed process 20 (new) total-vm:1kB, anon-rss:2048kB, file-rss:0kB, shmem-rss:0kB, UID:0 pgtables:1kB oom_score_adj:0"}` + "\n")), nil
+	}
+
+	bus := events.NewBus(events.DefaultCapacity)
+	w := NewWatcher(bus)
+	w.Start()
+	require.Eventually(t, func() bool { return w.Recent(0).Total == 2 }, time.Second, 10*time.Millisecond)
+	w.Stop()
+
+	list := w.Recent(1)
+	require.Len(t, list.Kills, 1)
+	assert.Equal(t, "new", list.Kills[0].Process)
+	assert.Equal(t, time.UnixMicro(1700000100000000), list.Kills[0].Time)
+
+	// Only the live kill raises an event
+	evts := bus.Recent(10, "system.oom_kill")
+	require.Len(t, evts.Events, 1)
+	assert.Equal(t, events.SeverityCritical, evts.Events[0].Severity)
+	assert.Contains(t, evts.Events[0].Message, "killed new (PID 20) using 2 MiB")
+}
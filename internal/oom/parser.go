@@ -0,0 +1,80 @@
+package oom
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/process"
+)
+
+var (
+	// "foo invoked oom-killer: gfp_mask=..."
+	invokedPattern = regexp.MustCompile(`^(.+?) invoked oom-killer:`)
+	// "Out of memory: Killed process 1234 (foo) total-vm:..." or
+	// "Memory cgroup out of memory: Killed process ..."
+	killedPattern = regexp.MustCompile(`[Oo]ut of memory: Killed process (\d+) \((.*?)\)(.*)`)
+	// "total-vm:123456kB", "UID:1000", "oom_score_adj:0"
+	fieldPattern = regexp.MustCompile(`([A-Za-z_-]+):(-?\d+)(kB)?`)
+)
+
+// parser turns kernel log lines into kills. The kernel logs the trigger,
+// then an "oom-kill:" summary, then the kill itself.
+type parser struct {
+	pending Kill
+}
+
+// line parses one kernel message and returns a kill when it completes one
+func (p *parser) line(at time.Time, msg string) *Kill {
+	msg = strings.TrimSpace(msg)
+
+	if m := invokedPattern.FindStringSubmatch(msg); m != nil {
+		p.pending = Kill{InvokedBy: m[1]}
+		return nil
+	}
+
+	if rest, ok := strings.CutPrefix(msg, "oom-kill:"); ok {
+		for _, field := range strings.Split(rest, ",") {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "constraint":
+				p.pending.Constraint = value
+			case "task_memcg":
+				p.pending.Cgroup = value
+				p.pending.Container = process.ContainerFromCgroup(value)
+			}
+		}
+		return nil
+	}
+
+	m := killedPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return nil
+	}
+
+	kill := p.pending
+	p.pending = Kill{}
+	kill.Time = at
+	kill.PID, _ = strconv.Atoi(m[1])
+	kill.Process = m[2]
+	for _, f := range fieldPattern.FindAllStringSubmatch(m[3], -1) {
+		n, _ := strconv.ParseInt(f[2], 10, 64)
+		kb := uint64(n) * 1024
+		switch f[1] {
+		case "total-vm":
+			kill.TotalVM = kb
+		case "anon-rss":
+			kill.AnonRSS = kb
+		case "file-rss":
+			kill.FileRSS = kb
+		case "shmem-rss":
+			kill.ShmemRSS = kb
+		case "UID":
+			kill.UID = int(n)
+		case "oom_score_adj":
+			kill.OOMScoreAdj = int(n)
+		}
+	}
+	return &kill
+}
@@ -0,0 +1,31 @@
+package oom
+
+import "time"
+
+// Kill is a process killed by the kernel's OOM killer
+type Kill struct {
+	Time        time.Time `json:"time"`
+	PID         int       `json:"pid"`
+	Process     string    `json:"process"`
+	UID         int       `json:"uid"`
+	TotalVM     uint64    `json:"total_vm"`  // Bytes
+	AnonRSS     uint64    `json:"anon_rss"`  // Bytes
+	FileRSS     uint64    `json:"file_rss"`  // Bytes
+	ShmemRSS    uint64    `json:"shmem_rss"` // Bytes
+	OOMScoreAdj int       `json:"oom_score_adj"`
+	Constraint  string    `json:"constraint,omitempty"` // e.g. CONSTRAINT_MEMCG for a cgroup limit
+	Cgroup      string    `json:"cgroup,omitempty"`     // The victim's memory cgroup
+	Container   string    `json:"container,omitempty"`  // Docker container ID, when in one
+	InvokedBy   string    `json:"invoked_by,omitempty"` // The process whose allocation triggered it
+}
+
+// RSS is the resident memory at the time of the kill
+func (k Kill) RSS() uint64 {
+	return k.AnonRSS + k.FileRSS + k.ShmemRSS
+}
+
+// KillList is recent OOM kills, newest first
+type KillList struct {
+	Kills []Kill `json:"kills"`
+	Total int    `json:"total"`
+}
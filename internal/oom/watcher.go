@@ -0,0 +1,204 @@
+package oom
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+const (
+	// MaxKills is the number of kills kept in memory
+	MaxKills = 100
+	// retryDelay is how long to wait before following the log again
+	retryDelay = 30 * time.Second
+)
+
+// Replaced in tests
+var (
+	lookPath = exec.LookPath
+	// startCommand starts a command and returns its stdout. Closing it
+	// waits for the command.
+	startCommand = func(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return cmdOutput{stdout, cmd}, nil
+	}
+)
+
+type cmdOutput struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (o cmdOutput) Close() error {
+	o.ReadCloser.Close()
+	return o.cmd.Wait()
+}
+
+// Watcher follows the kernel log for OOM kills and publishes each one as a
+// system.oom_kill event. Kills since boot are loaded at start without
+// raising events.
+type Watcher struct {
+	bus *events.Bus
+
+	mu    sync.Mutex
+	kills []Kill
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher creates a watcher publishing to bus
+func NewWatcher(bus *events.Bus) *Watcher {
+	return &Watcher{bus: bus}
+}
+
+// Start loads the kills since boot and follows the kernel log, from the
+// journal or else dmesg, until Stop is called
+func (w *Watcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+
+		journal := true
+		if _, err := lookPath("journalctl"); err != nil {
+			journal = false
+		}
+		if journal {
+			w.load(ctx)
+		}
+
+		for {
+			if err := w.follow(ctx, journal); err != nil && ctx.Err() == nil {
+				log.Printf("OOM watcher: %v", err)
+			}
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the watcher
+func (w *Watcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+		<-w.done
+	}
+}
+
+// Recent returns up to limit kills, newest first; limit <= 0 returns all
+func (w *Watcher) Recent(limit int) *KillList {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if limit <= 0 || limit > len(w.kills) {
+		limit = len(w.kills)
+	}
+	kills := make([]Kill, 0, limit)
+	for i := len(w.kills) - 1; i >= 0 && len(kills) < limit; i-- {
+		kills = append(kills, w.kills[i])
+	}
+	return &KillList{Kills: kills, Total: len(w.kills)}
+}
+
+// load reads the kills logged since boot
+func (w *Watcher) load(ctx context.Context) {
+	out, err := startCommand(ctx, "journalctl", "-k", "-b", "--output=json", "--no-pager")
+	if err != nil {
+		log.Printf("OOM watcher: failed to read the kernel log: %v", err)
+		return
+	}
+	defer out.Close()
+	w.read(out, true, false)
+}
+
+// follow streams new kernel messages until the command exits
+func (w *Watcher) follow(ctx context.Context, journal bool) error {
+	name, args := "dmesg", []string{"--follow-new", "--notime"}
+	if journal {
+		name, args = "journalctl", []string{"-k", "-f", "-n", "0", "--output=json", "--no-pager"}
+	}
+
+	out, err := startCommand(ctx, name, args...)
+	if err != nil {
+		return fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	defer out.Close()
+	w.read(out, journal, true)
+	return fmt.Errorf("%s exited", name)
+}
+
+// read parses kernel messages from r, which are journal JSON when journal
+// is set and plain text otherwise
+func (w *Watcher) read(r io.Reader, journal, publish bool) {
+	var p parser
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		at, msg := time.Now(), scanner.Text()
+		if journal {
+			var entry struct {
+				Message   interface{} `json:"MESSAGE"` // A byte array when not UTF-8
+				Timestamp string      `json:"__REALTIME_TIMESTAMP"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			msg, _ = entry.Message.(string)
+			if usec, err := strconv.ParseInt(entry.Timestamp, 10, 64); err == nil {
+				at = time.UnixMicro(usec)
+			}
+		}
+
+		if kill := p.line(at, msg); kill != nil {
+			w.record(*kill, publish)
+		}
+	}
+}
+
+// record keeps a kill and publishes it
+func (w *Watcher) record(kill Kill, publish bool) {
+	w.mu.Lock()
+	w.kills = append(w.kills, kill)
+	if len(w.kills) > MaxKills {
+		w.kills = w.kills[len(w.kills)-MaxKills:]
+	}
+	w.mu.Unlock()
+
+	if !publish || w.bus == nil {
+		return
+	}
+
+	message := fmt.Sprintf("Out of memory: killed %s (PID %d) using %d MiB", kill.Process, kill.PID, kill.RSS()>>20)
+	if kill.Container != "" {
+		message += " in container " + kill.Container
+	}
+	w.bus.Publish(events.Event{
+		Type:     "system.oom_kill",
+		Severity: events.SeverityCritical,
+		Source:   "kernel",
+		Message:  message,
+		Data:     kill,
+	})
+}
@@ -0,0 +1,264 @@
+package power
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// Manager schedules reboots and shutdowns and tracks maintenance mode
+type Manager struct {
+	// shutdown runs shutdown(8) with the given arguments
+	shutdown    func(ctx context.Context, args ...string) ([]byte, error)
+	scheduled   *ScheduledAction
+	maintenance Maintenance
+	// autoMaintenance is true when maintenance mode was enabled by a scheduled action
+	autoMaintenance bool
+	mu              sync.RWMutex
+}
+
+// NewManager creates a new power manager
+func NewManager() *Manager {
+	return &Manager{shutdown: execShutdown}
+}
+
+// SetShutdownCommand replaces how shutdown(8) is run, e.g. through the
+// privilege helper
+func (m *Manager) SetShutdownCommand(run func(ctx context.Context, args ...string) ([]byte, error)) {
+	m.shutdown = run
+}
+
+func execShutdown(ctx context.Context, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, "shutdown", args...).CombinedOutput()
+}
+
+// Reboot schedules a system reboot
+func (m *Manager) Reboot(ctx context.Context, req Request) (*ActionResult, error) {
+	return m.schedule(ctx, ActionReboot, req)
+}
+
+// Shutdown schedules a system shutdown
+func (m *Manager) Shutdown(ctx context.Context, req Request) (*ActionResult, error) {
+	return m.schedule(ctx, ActionShutdown, req)
+}
+
+// checkMessage rejects a wall message shutdown(8) would read as an option,
+// such as "-c" turning a reboot into a cancel
+func checkMessage(message string) error {
+	if strings.HasPrefix(message, "-") {
+		return apierror.Invalid("message must not start with '-'")
+	}
+	return nil
+}
+
+func (m *Manager) schedule(ctx context.Context, action Action, req Request) (*ActionResult, error) {
+	if err := checkMessage(req.Message); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	executeAt, when, err := ParseWhen(req.When, now)
+	if err != nil {
+		return nil, err
+	}
+
+	flag := "-r"
+	if action == ActionShutdown {
+		flag = "-h"
+	}
+
+	args := []string{flag, when}
+	if req.Message != "" {
+		args = append(args, req.Message)
+	}
+
+	output, err := m.shutdown(ctx, args...)
+	if err != nil {
+		return &ActionResult{
+			Action:  action,
+			Success: false,
+			Message: fmt.Sprintf("failed to schedule %s: %v %s", action, err, strings.TrimSpace(string(output))),
+		}, nil
+	}
+
+	scheduled := &ScheduledAction{
+		Action:      action,
+		ScheduledAt: now,
+		ExecuteAt:   executeAt,
+		Message:     req.Message,
+	}
+
+	m.mu.Lock()
+	m.scheduled = scheduled
+	if !m.maintenance.Enabled || m.autoMaintenance {
+		m.maintenance = Maintenance{
+			Enabled: true,
+			Reason:  fmt.Sprintf("scheduled %s", action),
+			Since:   now,
+			Until:   executeAt,
+		}
+		m.autoMaintenance = true
+	}
+	m.mu.Unlock()
+
+	return &ActionResult{
+		Action:    action,
+		Success:   true,
+		Message:   fmt.Sprintf("%s scheduled for %s", action, executeAt.Format(time.RFC3339)),
+		Scheduled: scheduled,
+	}, nil
+}
+
+// Cancel cancels a pending reboot or shutdown
+func (m *Manager) Cancel(ctx context.Context, message string) (*ActionResult, error) {
+	if err := checkMessage(message); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	scheduled := m.scheduled
+	m.mu.RUnlock()
+
+	if scheduled == nil {
+		return &ActionResult{
+			Success: false,
+			Message: "no power action is scheduled",
+		}, nil
+	}
+
+	args := []string{"-c"}
+	if message != "" {
+		args = append(args, message)
+	}
+
+	output, err := m.shutdown(ctx, args...)
+	if err != nil {
+		return &ActionResult{
+			Action:  scheduled.Action,
+			Success: false,
+			Message: fmt.Sprintf("failed to cancel %s: %v %s", scheduled.Action, err, strings.TrimSpace(string(output))),
+		}, nil
+	}
+
+	m.mu.Lock()
+	m.scheduled = nil
+	if m.autoMaintenance {
+		m.maintenance = Maintenance{}
+		m.autoMaintenance = false
+	}
+	m.mu.Unlock()
+
+	return &ActionResult{
+		Action:  scheduled.Action,
+		Success: true,
+		Message: fmt.Sprintf("scheduled %s cancelled", scheduled.Action),
+	}, nil
+}
+
+// Status returns the pending power action and maintenance state
+func (m *Manager) Status() *Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return &Status{
+		Scheduled:   m.currentScheduled(),
+		Maintenance: m.currentMaintenance(),
+	}
+}
+
+// Maintenance returns the current maintenance mode state
+func (m *Manager) Maintenance() Maintenance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.currentMaintenance()
+}
+
+// SetMaintenance enables maintenance mode until the given time (zero for indefinitely)
+func (m *Manager) SetMaintenance(reason string, until time.Time) Maintenance {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maintenance = Maintenance{
+		Enabled: true,
+		Reason:  reason,
+		Since:   time.Now(),
+		Until:   until,
+	}
+	m.autoMaintenance = false
+
+	return m.maintenance
+}
+
+// ClearMaintenance disables maintenance mode
+func (m *Manager) ClearMaintenance() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maintenance = Maintenance{}
+	m.autoMaintenance = false
+}
+
+func (m *Manager) currentScheduled() *ScheduledAction {
+	if m.scheduled == nil || time.Now().After(m.scheduled.ExecuteAt.Add(time.Minute)) {
+		return nil
+	}
+	return m.scheduled
+}
+
+func (m *Manager) currentMaintenance() Maintenance {
+	if m.maintenance.Enabled && !m.maintenance.Until.IsZero() && time.Now().After(m.maintenance.Until.Add(time.Minute)) {
+		return Maintenance{}
+	}
+	return m.maintenance
+}
+
+// ParseWhen converts a schedule expression into an execution time and the
+// equivalent shutdown(8) time argument. Supported forms are "now", "+N"
+// (minutes), "+<duration>" (e.g. "+5m", "+1h30m"), "HH:MM" and RFC3339.
+func ParseWhen(when string, now time.Time) (time.Time, string, error) {
+	when = strings.TrimSpace(when)
+
+	if when == "" || when == "now" {
+		return now, "now", nil
+	}
+
+	if strings.HasPrefix(when, "+") {
+		spec := strings.TrimPrefix(when, "+")
+		if minutes, err := strconv.Atoi(spec); err == nil && minutes >= 0 {
+			return now.Add(time.Duration(minutes) * time.Minute), "+" + strconv.Itoa(minutes), nil
+		}
+
+		d, err := time.ParseDuration(spec)
+		if err != nil || d < 0 {
+			return time.Time{}, "", apierror.Invalid("invalid delay: %s", when)
+		}
+		minutes := int(math.Ceil(d.Minutes()))
+		return now.Add(time.Duration(minutes) * time.Minute), "+" + strconv.Itoa(minutes), nil
+	}
+
+	if t, err := time.ParseInLocation("15:04", when, now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.Add(24 * time.Hour)
+		}
+		return at, at.Format("15:04"), nil
+	}
+
+	if at, err := time.Parse(time.RFC3339, when); err == nil {
+		if !at.After(now) {
+			return time.Time{}, "", apierror.Invalid("scheduled time is in the past: %s", when)
+		}
+		minutes := int(math.Ceil(at.Sub(now).Minutes()))
+		return now.Add(time.Duration(minutes) * time.Minute), "+" + strconv.Itoa(minutes), nil
+	}
+
+	return time.Time{}, "", apierror.Invalid("invalid schedule: %s", when)
+}
@@ -0,0 +1,87 @@
+package power
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestParseWhen(t *testing.T) {
+	loc := time.FixedZone("EAT", 3*60*60)
+	now := time.Date(2024, 3, 10, 22, 0, 0, 0, loc)
+
+	tests := []struct {
+		name    string
+		when    string
+		want    time.Time
+		arg     string
+		wantErr bool
+	}{
+		{name: "empty", when: "", want: now, arg: "now"},
+		{name: "now", when: " now ", want: now, arg: "now"},
+		{name: "minutes", when: "+5", want: now.Add(5 * time.Minute), arg: "+5"},
+		{name: "zero minutes", when: "+0", want: now, arg: "+0"},
+		{name: "duration", when: "+1h30m", want: now.Add(90 * time.Minute), arg: "+90"},
+		{name: "duration rounds up", when: "+90s", want: now.Add(2 * time.Minute), arg: "+2"},
+		{name: "later today", when: "23:30", want: time.Date(2024, 3, 10, 23, 30, 0, 0, loc), arg: "23:30"},
+		{name: "past midnight", when: "06:15", want: time.Date(2024, 3, 11, 6, 15, 0, 0, loc), arg: "06:15"},
+		{name: "current minute rolls over", when: "22:00", want: time.Date(2024, 3, 11, 22, 0, 0, 0, loc), arg: "22:00"},
+		{name: "rfc3339", when: "2024-03-10T19:10:30Z", want: now.Add(11 * time.Minute), arg: "+11"},
+		{name: "rfc3339 in the past", when: "2024-03-10T18:00:00Z", wantErr: true},
+		{name: "negative delay", when: "+-5", wantErr: true},
+		{name: "bad delay", when: "+soon", wantErr: true},
+		{name: "bad clock time", when: "25:00", wantErr: true},
+		{name: "unknown", when: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, arg, err := ParseWhen(tt.when, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(at), "want %s, got %s", tt.want, at)
+			assert.Equal(t, tt.arg, arg)
+		})
+	}
+}
+
+func TestManager_RejectsOptionMessages(t *testing.T) {
+	var calls [][]string
+	m := NewManager()
+	m.SetShutdownCommand(func(ctx context.Context, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, nil
+	})
+	ctx := context.Background()
+
+	for _, message := range []string{"-c", "-P", "-k", "--no-wall"} {
+		_, err := m.Reboot(ctx, Request{When: "+5", Message: message})
+		assert.True(t, errors.Is(err, apierror.ErrInvalid), message)
+		_, err = m.Shutdown(ctx, Request{When: "+5", Message: message})
+		assert.True(t, errors.Is(err, apierror.ErrInvalid), message)
+	}
+	assert.Empty(t, calls, "shutdown must not run with an option as the message")
+
+	result, err := m.Reboot(ctx, Request{When: "+5", Message: "kernel update - back soon"})
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, [][]string{{"-r", "+5", "kernel update - back soon"}}, calls)
+
+	_, err = m.Cancel(ctx, "-r")
+	assert.True(t, errors.Is(err, apierror.ErrInvalid))
+	assert.Len(t, calls, 1)
+
+	result, err = m.Cancel(ctx, "never mind")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, []string{"-c", "never mind"}, calls[1])
+}
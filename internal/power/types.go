@@ -0,0 +1,47 @@
+package power
+
+import "time"
+
+// Action is a power action that can be scheduled
+type Action string
+
+const (
+	ActionReboot   Action = "reboot"
+	ActionShutdown Action = "shutdown"
+)
+
+// Request represents a request to schedule a power action
+type Request struct {
+	When    string `json:"when,omitempty"`    // "now", "+5m", "+10", "23:30" or RFC3339
+	Message string `json:"message,omitempty"` // Wall message broadcast to logged-in users
+}
+
+// ScheduledAction represents a pending reboot or shutdown
+type ScheduledAction struct {
+	Action      Action    `json:"action"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	ExecuteAt   time.Time `json:"execute_at"`
+	Message     string    `json:"message,omitempty"`
+}
+
+// Maintenance represents the agent maintenance mode state
+type Maintenance struct {
+	Enabled bool      `json:"enabled"`
+	Reason  string    `json:"reason,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+	Until   time.Time `json:"until,omitempty"`
+}
+
+// Status contains the current power and maintenance state
+type Status struct {
+	Scheduled   *ScheduledAction `json:"scheduled"`
+	Maintenance Maintenance      `json:"maintenance"`
+}
+
+// ActionResult represents the result of a power operation
+type ActionResult struct {
+	Action    Action           `json:"action"`
+	Success   bool             `json:"success"`
+	Message   string           `json:"message"`
+	Scheduled *ScheduledAction `json:"scheduled,omitempty"`
+}
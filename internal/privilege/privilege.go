@@ -0,0 +1,165 @@
+package privilege
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Features that need privileges the agent may not have
+const (
+	FeatureServices  = "services"  // Starting, stopping and restarting units
+	FeaturePower     = "power"     // Reboot and shutdown
+	FeatureLogs      = "logs"      // Reading the system journal
+	FeatureDocker    = "docker"    // Talking to the Docker socket
+	FeatureProcesses = "processes" // Signalling other users' processes
+	FeaturePackages  = "packages"  // Installing package updates
+	FeatureGovernor  = "governor"  // Switching the CPU frequency governor
+)
+
+// Polkit actions that let a non-root agent manage units and power
+const (
+	PolkitManageUnits = "org.freedesktop.systemd1.manage-units"
+	PolkitReboot      = "org.freedesktop.login1.reboot"
+	PolkitPowerOff    = "org.freedesktop.login1.power-off"
+)
+
+// journalGroups may read the whole system journal
+var journalGroups = []string{"systemd-journal", "adm", "wheel"}
+
+// DockerSocket is checked for access when the agent is not root
+const DockerSocket = "/var/run/docker.sock"
+
+// Report describes what the agent can do as the user it runs as
+type Report struct {
+	User    string            `json:"user"`
+	UID     int               `json:"uid"`
+	Root    bool              `json:"root"`
+	Helper  bool              `json:"helper"` // Privileged actions run through the sudo helper
+	Groups  []string          `json:"groups,omitempty"`
+	Polkit  map[string]bool   `json:"polkit,omitempty"`
+	Limited map[string]string `json:"limited"` // Feature to the reason it is limited
+}
+
+// Allowed reports whether a feature works with the agent's privileges
+func (r *Report) Allowed(feature string) bool {
+	_, limited := r.Limited[feature]
+	return !limited
+}
+
+// probes are the system lookups Detect makes, replaced in tests
+type probes struct {
+	uid    int
+	user   func() (name string, groups []string)
+	polkit func(action string) (bool, error)
+	access func(path string) error
+}
+
+// Detect checks the agent's user, groups and polkit authorizations
+func Detect() *Report {
+	return detect(probes{
+		uid:    os.Geteuid(),
+		user:   currentUser,
+		polkit: PolkitAuthorized,
+		access: func(path string) error {
+			if _, err := os.Stat(path); err != nil {
+				return err
+			}
+			return syscall.Access(path, 0x2) // W_OK; connecting needs write
+		},
+	})
+}
+
+func detect(p probes) *Report {
+	name, groups := p.user()
+	r := &Report{
+		User:    name,
+		UID:     p.uid,
+		Root:    p.uid == 0,
+		Groups:  groups,
+		Limited: make(map[string]string),
+	}
+	if r.Root {
+		return r
+	}
+
+	r.Polkit = make(map[string]bool)
+	for _, action := range []string{PolkitManageUnits, PolkitReboot, PolkitPowerOff} {
+		r.Polkit[action], _ = p.polkit(action)
+	}
+
+	if !r.Polkit[PolkitManageUnits] {
+		r.Limited[FeatureServices] = "not root and polkit does not allow " + PolkitManageUnits
+	}
+	if !r.Polkit[PolkitReboot] || !r.Polkit[PolkitPowerOff] {
+		r.Limited[FeaturePower] = fmt.Sprintf("not root and polkit does not allow %s and %s", PolkitReboot, PolkitPowerOff)
+	}
+	if !inAny(groups, journalGroups) {
+		r.Limited[FeatureLogs] = "only the agent's own journal entries are readable; add the user to systemd-journal"
+	}
+	if err := p.access(DockerSocket); err != nil && !os.IsNotExist(err) {
+		r.Limited[FeatureDocker] = "no access to " + DockerSocket + "; add the user to the docker group"
+	}
+	r.Limited[FeatureProcesses] = "only processes owned by " + name + " can be signalled"
+	r.Limited[FeaturePackages] = "apt needs root; set PRIVILEGE_HELPER=true to update packages"
+	r.Limited[FeatureGovernor] = "sysfs needs root; set PRIVILEGE_HELPER=true to switch the CPU governor"
+
+	return r
+}
+
+func inAny(groups, want []string) bool {
+	for _, g := range groups {
+		for _, w := range want {
+			if g == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// currentUser returns the agent's user name and group names
+func currentUser() (string, []string) {
+	u, err := user.Current()
+	if err != nil {
+		return fmt.Sprint(os.Geteuid()), nil
+	}
+	ids, _ := u.GroupIds()
+	var groups []string
+	for _, id := range ids {
+		if g, err := user.LookupGroupId(id); err == nil {
+			groups = append(groups, g.Name)
+		}
+	}
+	return u.Username, groups
+}
+
+// PolkitAuthorized asks polkit whether the agent may perform action without
+// an interactive prompt
+func PolkitAuthorized(action string) (bool, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return false, err
+	}
+
+	subject := struct {
+		Kind    string
+		Details map[string]dbus.Variant
+	}{"system-bus-name", map[string]dbus.Variant{"name": dbus.MakeVariant(conn.Names()[0])}}
+
+	var result struct {
+		Authorized bool
+		Challenge  bool
+		Details    map[string]string
+	}
+	authority := conn.Object("org.freedesktop.PolicyKit1", "/org/freedesktop/PolicyKit1/Authority")
+	err = authority.Call("org.freedesktop.PolicyKit1.Authority.CheckAuthorization", 0,
+		subject, action, map[string]string{}, uint32(0), "").Store(&result)
+	if err != nil {
+		return false, err
+	}
+	return result.Authorized, nil
+}
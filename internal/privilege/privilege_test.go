@@ -0,0 +1,49 @@
+package privilege
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRoot(t *testing.T) {
+	r := detect(probes{
+		uid:  0,
+		user: func() (string, []string) { return "root", []string{"root"} },
+	})
+	assert.True(t, r.Root)
+	assert.Empty(t, r.Limited)
+	assert.True(t, r.Allowed(FeatureServices))
+}
+
+func TestDetectUnprivileged(t *testing.T) {
+	r := detect(probes{
+		uid:    999,
+		user:   func() (string, []string) { return "hivedeck", []string{"hivedeck", "systemd-journal"} },
+		polkit: func(action string) (bool, error) { return action == PolkitManageUnits, nil },
+		access: func(string) error { return os.ErrPermission },
+	})
+
+	assert.False(t, r.Root)
+	assert.True(t, r.Allowed(FeatureServices), "polkit allows managing units")
+	assert.True(t, r.Allowed(FeatureLogs), "systemd-journal member")
+	assert.False(t, r.Allowed(FeaturePower))
+	assert.False(t, r.Allowed(FeatureDocker))
+	assert.False(t, r.Allowed(FeatureProcesses))
+}
+
+func TestDetectNoPolkit(t *testing.T) {
+	r := detect(probes{
+		uid:    999,
+		user:   func() (string, []string) { return "hivedeck", nil },
+		polkit: func(string) (bool, error) { return false, errors.New("no system bus") },
+		access: func(string) error { return os.ErrNotExist },
+	})
+
+	assert.False(t, r.Allowed(FeatureServices))
+	assert.False(t, r.Allowed(FeatureLogs))
+	// No Docker socket is not a privilege problem
+	assert.True(t, r.Allowed(FeatureDocker))
+}
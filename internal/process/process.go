@@ -3,26 +3,33 @@ package process
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/process"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/redact"
 )
 
 // Manager handles process operations
 type Manager struct {
-	// AllowedProcessNames contains process names that can be killed
+	// AllowedProcessNames contains process names that can be killed or
+	// limited
 	AllowedProcessNames map[string]bool
+
+	redactor *redact.Redactor
 }
 
-// NewManager creates a new process manager
-func NewManager() *Manager {
-	return &Manager{
-		AllowedProcessNames: map[string]bool{
-			// Add allowed process names here
-			// By default, we don't allow killing any processes for safety
-		},
+// NewManager creates a new process manager. By default no process may be
+// killed or limited, for safety. Command lines are masked by redactor.
+func NewManager(allowedNames []string, redactor *redact.Redactor) *Manager {
+	m := &Manager{AllowedProcessNames: map[string]bool{}, redactor: redactor}
+	for _, name := range allowedNames {
+		m.AllowProcess(name)
 	}
+	return m
 }
 
 // List returns all running processes
@@ -73,7 +80,7 @@ func (m *Manager) ListTop(n int) (*ProcessList, error) {
 func (m *Manager) Get(pid int32) (*ProcessInfo, error) {
 	p, err := process.NewProcess(pid)
 	if err != nil {
-		return nil, fmt.Errorf("process not found: %w", err)
+		return nil, apierror.NotFound("process not found: %w", err)
 	}
 
 	return m.getProcessInfo(p)
@@ -83,21 +90,13 @@ func (m *Manager) Get(pid int32) (*ProcessInfo, error) {
 func (m *Manager) Kill(pid int32, signal int) (*KillResponse, error) {
 	p, err := process.NewProcess(pid)
 	if err != nil {
-		return &KillResponse{
-			PID:     pid,
-			Success: false,
-			Message: fmt.Sprintf("process not found: %v", err),
-		}, nil
+		return nil, apierror.NotFound("process not found: %w", err)
 	}
 
 	// Check if process is in allowed list
 	name, _ := p.Name()
 	if !m.IsAllowed(name) {
-		return &KillResponse{
-			PID:     pid,
-			Success: false,
-			Message: fmt.Sprintf("killing process '%s' is not allowed", name),
-		}, nil
+		return nil, apierror.NotAllowed("killing process '%s' is not allowed", name)
 	}
 
 	// Default to SIGTERM
@@ -141,7 +140,7 @@ func (m *Manager) getProcessInfo(p *process.Process) (*ProcessInfo, error) {
 	cpuPercent, _ := p.CPUPercent()
 	memPercent, _ := p.MemoryPercent()
 	memInfo, _ := p.MemoryInfo()
-	cmdline, _ := p.Cmdline()
+	args, _ := p.CmdlineSlice()
 	createTime, _ := p.CreateTime()
 	numThreads, _ := p.NumThreads()
 
@@ -163,7 +162,7 @@ func (m *Manager) getProcessInfo(p *process.Process) (*ProcessInfo, error) {
 		CPUPercent: cpuPercent,
 		MemPercent: memPercent,
 		MemRSS:     memRSS,
-		Cmdline:    cmdline,
+		Cmdline:    strings.Join(m.redactor.Args(args), " "),
 		CreateTime: time.UnixMilli(createTime),
 		NumThreads: numThreads,
 	}, nil
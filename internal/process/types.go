@@ -33,3 +33,26 @@ type KillResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 }
+
+// LimitRequest represents a request to limit a process's resources
+type LimitRequest struct {
+	CPUQuota  int    `json:"cpu_quota,omitempty"`  // Percent of one CPU
+	MemoryMax string `json:"memory_max,omitempty"` // Bytes, or e.g. "512M"
+}
+
+// ZombieParent is a process with unreaped zombie children
+type ZombieParent struct {
+	PID           int32  `json:"pid"`
+	Name          string `json:"name"`
+	Zombies       int    `json:"zombies"`
+	OldestSeconds int64  `json:"oldest_seconds"`      // Age of its oldest zombie
+	Container     string `json:"container,omitempty"` // Docker container ID, when in one
+}
+
+// ZombieReport summarises zombie processes by parent
+type ZombieReport struct {
+	Total     int            `json:"total"`
+	Parents   []ZombieParent `json:"parents"`
+	Alerting  bool           `json:"alerting"` // Over the count or age threshold
+	CheckedAt time.Time      `json:"checked_at"`
+}
@@ -0,0 +1,227 @@
+package process
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+// zombie is a defunct process waiting to be reaped by its parent
+type zombie struct {
+	pid, ppid int32
+	started   time.Time
+}
+
+// listZombies returns the zombie processes; replaced in tests
+var listZombies = func() ([]zombie, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get processes: %w", err)
+	}
+
+	var zombies []zombie
+	for _, p := range procs {
+		status, err := p.Status()
+		if err != nil || len(status) == 0 || status[0] != process.Zombie {
+			continue
+		}
+		ppid, err := p.Ppid()
+		if err != nil {
+			continue
+		}
+		created, _ := p.CreateTime()
+		zombies = append(zombies, zombie{pid: p.Pid, ppid: ppid, started: time.UnixMilli(created)})
+	}
+	return zombies, nil
+}
+
+// processName returns a process's name; replaced in tests
+var processName = func(pid int32) string {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return ""
+	}
+	name, _ := p.Name()
+	return name
+}
+
+// containerIDPattern matches a Docker container ID in a cgroup path, for
+// both cgroup v1 (/docker/<id>) and v2 (docker-<id>.scope)
+var containerIDPattern = regexp.MustCompile(`docker[-/]([0-9a-f]{64})`)
+
+// processContainer returns the short ID of the container a process runs
+// in, or ""; replaced in tests
+var processContainer = func(pid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+	return ContainerFromCgroup(string(data))
+}
+
+// ContainerFromCgroup returns the short Docker container ID in a cgroup
+// path, or ""
+func ContainerFromCgroup(cgroup string) string {
+	if m := containerIDPattern.FindStringSubmatch(cgroup); m != nil {
+		return m[1][:12]
+	}
+	return ""
+}
+
+// Zombies groups zombie processes by parent, most zombies first. A parent
+// that keeps zombies around is not reaping its children, which usually
+// means a broken container entrypoint or supervisor.
+func (m *Manager) Zombies() (*ZombieReport, error) {
+	zombies, err := listZombies()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	parents := make(map[int32]*ZombieParent)
+	for _, z := range zombies {
+		parent, ok := parents[z.ppid]
+		if !ok {
+			parent = &ZombieParent{PID: z.ppid}
+			parents[z.ppid] = parent
+		}
+		parent.Zombies++
+		if age := int64(now.Sub(z.started).Seconds()); age > parent.OldestSeconds {
+			parent.OldestSeconds = age
+		}
+	}
+
+	report := &ZombieReport{Total: len(zombies), Parents: []ZombieParent{}, CheckedAt: now}
+	for _, parent := range parents {
+		parent.Name = processName(parent.PID)
+		parent.Container = processContainer(parent.PID)
+		report.Parents = append(report.Parents, *parent)
+	}
+	sort.Slice(report.Parents, func(i, j int) bool {
+		a, b := report.Parents[i], report.Parents[j]
+		if a.Zombies != b.Zombies {
+			return a.Zombies > b.Zombies
+		}
+		return a.PID < b.PID
+	})
+	return report, nil
+}
+
+// ZombieOptions configures a ZombieMonitor
+type ZombieOptions struct {
+	Interval  time.Duration
+	Threshold int           // Alert at this many zombies in total; 0 disables
+	MaxAge    time.Duration // Alert when a zombie is older; 0 disables
+}
+
+// ZombieMonitor checks for zombies every interval and raises an event when
+// they pile up or a parent leaves one unreaped for too long
+type ZombieMonitor struct {
+	manager *Manager
+	opts    ZombieOptions
+	bus     *events.Bus
+
+	mu     sync.Mutex
+	report *ZombieReport
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewZombieMonitor creates a zombie monitor publishing to bus
+func NewZombieMonitor(manager *Manager, opts ZombieOptions, bus *events.Bus) *ZombieMonitor {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	return &ZombieMonitor{
+		manager: manager,
+		opts:    opts,
+		bus:     bus,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start checks immediately and then every interval until Stop is called
+func (z *ZombieMonitor) Start() {
+	go func() {
+		ticker := time.NewTicker(z.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := z.Check(); err != nil {
+				log.Printf("Zombie check failed: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-z.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the checks
+func (z *ZombieMonitor) Stop() {
+	z.once.Do(func() { close(z.stop) })
+}
+
+// Report returns the latest report, checking now if there is none
+func (z *ZombieMonitor) Report() (*ZombieReport, error) {
+	z.mu.Lock()
+	report := z.report
+	z.mu.Unlock()
+	if report != nil {
+		return report, nil
+	}
+	return z.Check()
+}
+
+// Check looks for zombies now and publishes an event when the alert
+// condition starts or clears
+func (z *ZombieMonitor) Check() (*ZombieReport, error) {
+	report, err := z.manager.Zombies()
+	if err != nil {
+		return nil, err
+	}
+
+	var oldest *ZombieParent
+	for i := range report.Parents {
+		if oldest == nil || report.Parents[i].OldestSeconds > oldest.OldestSeconds {
+			oldest = &report.Parents[i]
+		}
+	}
+	tooMany := z.opts.Threshold > 0 && report.Total >= z.opts.Threshold
+	tooOld := z.opts.MaxAge > 0 && oldest != nil && time.Duration(oldest.OldestSeconds)*time.Second >= z.opts.MaxAge
+	report.Alerting = tooMany || tooOld
+
+	z.mu.Lock()
+	wasAlerting := z.report != nil && z.report.Alerting
+	z.report = report
+	z.mu.Unlock()
+
+	if z.bus == nil || report.Alerting == wasAlerting {
+		return report, nil
+	}
+
+	event := events.Event{Type: "process.zombies", Severity: events.SeverityWarning, Source: "processes", Data: report}
+	switch {
+	case !report.Alerting:
+		event.Type, event.Severity = "process.zombies_cleared", events.SeverityInfo
+		event.Message = fmt.Sprintf("Zombie processes are back to %d", report.Total)
+	case tooMany:
+		event.Message = fmt.Sprintf("%d zombie processes, most from %s (PID %d)", report.Total, report.Parents[0].Name, report.Parents[0].PID)
+	default:
+		event.Message = fmt.Sprintf("%s (PID %d) has not reaped a zombie for %s", oldest.Name, oldest.PID,
+			(time.Duration(oldest.OldestSeconds) * time.Second).String())
+	}
+	z.bus.Publish(event)
+	return report, nil
+}
@@ -0,0 +1,85 @@
+package process
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+func fakeZombies(t *testing.T, zombies *[]zombie) {
+	origList, origName, origContainer := listZombies, processName, processContainer
+	t.Cleanup(func() { listZombies, processName, processContainer = origList, origName, origContainer })
+
+	listZombies = func() ([]zombie, error) { return *zombies, nil }
+	processName = func(pid int32) string { return map[int32]string{100: "entrypoint.sh", 200: "cron"}[pid] }
+	processContainer = func(pid int32) string {
+		if pid == 100 {
+			return "3f2a9c1b7d4e"
+		}
+		return ""
+	}
+}
+
+func TestZombies(t *testing.T) {
+	now := time.Now()
+	zombies := []zombie{
+		{pid: 101, ppid: 100, started: now.Add(-time.Hour)},
+		{pid: 102, ppid: 100, started: now.Add(-time.Minute)},
+		{pid: 201, ppid: 200, started: now.Add(-10 * time.Second)},
+	}
+	fakeZombies(t, &zombies)
+
+	report, err := NewManager(nil, nil).Zombies()
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	require.Len(t, report.Parents, 2)
+	assert.Equal(t, "entrypoint.sh", report.Parents[0].Name)
+	assert.Equal(t, 2, report.Parents[0].Zombies)
+	assert.Equal(t, "3f2a9c1b7d4e", report.Parents[0].Container)
+	assert.InDelta(t, 3600, report.Parents[0].OldestSeconds, 2)
+	assert.Equal(t, "cron", report.Parents[1].Name)
+}
+
+func TestZombieMonitor(t *testing.T) {
+	now := time.Now()
+	zombies := []zombie{{pid: 201, ppid: 200, started: now.Add(-time.Minute)}}
+	fakeZombies(t, &zombies)
+
+	bus := events.NewBus(events.DefaultCapacity)
+	m := NewZombieMonitor(NewManager(nil, nil), ZombieOptions{Threshold: 3, MaxAge: 30 * time.Minute}, bus)
+
+	report, err := m.Check()
+	require.NoError(t, err)
+	assert.False(t, report.Alerting)
+
+	// A parent not reaping for too long
+	zombies = append(zombies, zombie{pid: 101, ppid: 100, started: now.Add(-time.Hour)})
+	report, err = m.Check()
+	require.NoError(t, err)
+	assert.True(t, report.Alerting)
+
+	// Still alerting, so no second event
+	zombies = append(zombies, zombie{pid: 102, ppid: 100, started: now})
+	_, err = m.Check()
+	require.NoError(t, err)
+
+	zombies = nil
+	report, err = m.Report()
+	require.NoError(t, err)
+	assert.True(t, report.Alerting, "Report returns the latest check")
+	report, err = m.Check()
+	require.NoError(t, err)
+	assert.False(t, report.Alerting)
+
+	list := bus.Recent(10, "process.")
+	require.Len(t, list.Events, 2)
+	var types []string
+	for _, e := range list.Events {
+		types = append(types, e.Type)
+	}
+	assert.ElementsMatch(t, []string{"process.zombies", "process.zombies_cleared"}, types)
+}
@@ -0,0 +1,64 @@
+package profile
+
+// Compare reports how remote differs from local. Sections either profile
+// could not read are skipped rather than reported as removed.
+func Compare(local, remote *Profile) *Drift {
+	drift := &Drift{Remote: remote.Host.Hostname, Sections: map[string][]Change{}}
+
+	sections := []struct {
+		name          string
+		local, remote map[string]string
+	}{
+		{SectionHost, hostFields(local.Host), hostFields(remote.Host)},
+		{SectionPackages, local.Packages, remote.Packages},
+		{SectionServices, local.Services, remote.Services},
+		{SectionImages, local.Images, remote.Images},
+		{SectionFiles, local.Files, remote.Files},
+	}
+	for _, s := range sections {
+		if _, failed := local.Errors[s.name]; failed {
+			drift.Skipped = append(drift.Skipped, s.name)
+			continue
+		}
+		if _, failed := remote.Errors[s.name]; failed {
+			drift.Skipped = append(drift.Skipped, s.name)
+			continue
+		}
+		if changes := diff(s.local, s.remote); len(changes) > 0 {
+			drift.Sections[s.name] = changes
+			drift.Changes += len(changes)
+		}
+	}
+	drift.Identical = drift.Changes == 0
+	return drift
+}
+
+// hostFields returns the host settings that should match
+func hostFields(h Host) map[string]string {
+	return map[string]string{
+		"os":               h.OS,
+		"platform":         h.Platform,
+		"platform_version": h.PlatformVersion,
+		"kernel":           h.Kernel,
+		"arch":             h.Arch,
+	}
+}
+
+// diff returns the keys whose values differ, sorted by key
+func diff(local, remote map[string]string) []Change {
+	keys := map[string]struct{}{}
+	for k := range local {
+		keys[k] = struct{}{}
+	}
+	for k := range remote {
+		keys[k] = struct{}{}
+	}
+
+	var changes []Change
+	for _, k := range sortedKeys(keys) {
+		if local[k] != remote[k] {
+			changes = append(changes, Change{Name: k, Local: local[k], Remote: remote[k]})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,293 @@
+package profile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// Sections of a profile
+const (
+	SectionHost     = "host"
+	SectionPackages = "packages"
+	SectionServices = "services"
+	SectionImages   = "images"
+	SectionFiles    = "files"
+)
+
+const (
+	// MaxFiles bounds the files hashed, across all configured paths
+	MaxFiles = 1000
+	// maxFileSize is the largest file hashed
+	maxFileSize = 16 << 20
+)
+
+// Values recorded instead of a hash
+const (
+	Missing    = "missing"    // A configured path that does not exist
+	Unreadable = "unreadable" // Permission denied
+)
+
+// Replaced in tests
+var (
+	runCommand  = defaultRunCommand
+	lookPath    = exec.LookPath
+	getHostInfo = system.GetHostInfo
+)
+
+func defaultRunCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
+
+// ImagesFunc returns image IDs by repo:tag
+type ImagesFunc func(ctx context.Context) (map[string]string, error)
+
+// Collector builds profiles
+type Collector struct {
+	files  []string
+	labels map[string]string
+	images ImagesFunc // nil without Docker
+}
+
+// NewCollector creates a collector that hashes files, which may include
+// directories, and reports labels with the host
+func NewCollector(files []string, labels map[string]string, images ImagesFunc) *Collector {
+	return &Collector{files: files, labels: labels, images: images}
+}
+
+// Collect builds the profile. Sections that cannot be read are empty and
+// listed in Errors.
+func (c *Collector) Collect(ctx context.Context) *Profile {
+	p := &Profile{
+		Packages:    map[string]string{},
+		Services:    map[string]string{},
+		Images:      map[string]string{},
+		Files:       map[string]string{},
+		Errors:      map[string]string{},
+		GeneratedAt: time.Now().UTC(),
+	}
+	fail := func(section string, err error) {
+		p.Errors[section] = err.Error()
+	}
+
+	if info, err := getHostInfo(); err != nil {
+		fail(SectionHost, err)
+	} else {
+		p.Host = Host{
+			Hostname:        info.Hostname,
+			OS:              info.OS,
+			Platform:        info.Platform,
+			PlatformVersion: info.PlatformVersion,
+			Kernel:          info.KernelVersion,
+			Arch:            info.KernelArch,
+			Labels:          c.labels,
+		}
+	}
+
+	var err error
+	if p.PackageManager, p.Packages, err = packages(ctx); err != nil {
+		fail(SectionPackages, err)
+	}
+	if p.Services, err = services(ctx); err != nil {
+		fail(SectionServices, err)
+	}
+	if c.images == nil {
+		fail(SectionImages, errors.New("docker is not enabled"))
+	} else if p.Images, err = c.images(ctx); err != nil {
+		p.Images = map[string]string{}
+		fail(SectionImages, err)
+	}
+	if p.Files, err = hashFiles(c.files); err != nil {
+		fail(SectionFiles, err)
+	}
+
+	p.Digests = digests(p)
+	if len(p.Errors) == 0 {
+		p.Errors = nil
+	}
+	return p
+}
+
+// packages lists installed packages with dpkg, rpm or pacman
+func packages(ctx context.Context) (string, map[string]string, error) {
+	managers := []struct {
+		name string
+		args []string
+	}{
+		{"dpkg-query", []string{"-W", "-f", "${db:Status-Abbrev}\t${Package}\t${Version}\n"}},
+		{"rpm", []string{"-qa", "--qf", "ii \t%{NAME}\t%{EPOCHNUM}:%{VERSION}-%{RELEASE}\n"}},
+		{"pacman", []string{"-Q"}},
+	}
+	for _, m := range managers {
+		if _, err := lookPath(m.name); err != nil {
+			continue
+		}
+		out, err := runCommand(ctx, m.name, m.args...)
+		if err != nil {
+			return m.name, map[string]string{}, err
+		}
+		if m.name == "pacman" {
+			return m.name, parsePacman(out), nil
+		}
+		return m.name, parsePackages(out), nil
+	}
+	return "", map[string]string{}, errors.New("no supported package manager found (dpkg, rpm or pacman)")
+}
+
+// parsePackages reads status, name and version separated by tabs, keeping
+// installed packages. Architectures are left out so hosts of different
+// architectures compare; the host section reports them.
+func parsePackages(data []byte) map[string]string {
+	result := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], "ii") {
+			continue
+		}
+		result[fields[1]] = strings.TrimPrefix(fields[2], "0:")
+	}
+	return result
+}
+
+func parsePacman(data []byte) map[string]string {
+	result := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if name, version, ok := strings.Cut(scanner.Text(), " "); ok {
+			result[name] = version
+		}
+	}
+	return result
+}
+
+// services lists service unit files that are enabled, disabled or masked.
+// Static and generated units follow from the packages.
+func services(ctx context.Context) (map[string]string, error) {
+	out, err := runCommand(ctx, "systemctl", "list-unit-files", "--type=service", "--no-legend", "--no-pager")
+	if err != nil {
+		return map[string]string{}, err
+	}
+	return parseUnitFiles(out), nil
+}
+
+func parseUnitFiles(data []byte) map[string]string {
+	result := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[1] {
+		case "enabled", "disabled", "masked":
+			result[fields[0]] = fields[1]
+		}
+	}
+	return result
+}
+
+// hashFiles hashes each path, walking directories, up to MaxFiles
+func hashFiles(paths []string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.Type().IsRegular() {
+				return nil
+			}
+			if len(result) >= MaxFiles {
+				return fmt.Errorf("more than %d files", MaxFiles)
+			}
+
+			sum := ""
+			if err == nil {
+				sum, err = hashFile(path)
+			}
+			switch {
+			case err == nil:
+				result[path] = sum
+			case errors.Is(err, fs.ErrNotExist) && path == root:
+				result[path] = Missing
+			case errors.Is(err, fs.ErrPermission):
+				result[path] = Unreadable // A directory is skipped
+			default:
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, maxFileSize)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digests hashes each section's canonical JSON; map keys are sorted
+func digests(p *Profile) map[string]string {
+	host := p.Host
+	host.Hostname, host.Labels = "", nil // Identity, not configuration
+
+	sections := map[string]interface{}{
+		SectionHost:     host,
+		SectionPackages: p.Packages,
+		SectionServices: p.Services,
+		SectionImages:   p.Images,
+		SectionFiles:    p.Files,
+	}
+	result := make(map[string]string, len(sections)+1)
+	all := sha256.New()
+	for _, name := range sortedKeys(sections) {
+		data, _ := json.Marshal(sections[name])
+		sum := sha256.Sum256(data)
+		result[name] = hex.EncodeToString(sum[:])
+		fmt.Fprintf(all, "%s=%s\n", name, result[name])
+	}
+	result["all"] = hex.EncodeToString(all.Sum(nil))
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+func TestParsers(t *testing.T) {
+	assert.Equal(t, map[string]string{"apt": "2.6.1", "epoch": "1:2.0-1"}, parsePackages([]byte(
+		"ii \tapt\t2.6.1\nrc \tremoved\t1.0\nii \tepoch\t1:2.0-1\n")))
+	assert.Equal(t, map[string]string{"bash": "5.2.026-2"}, parsePacman([]byte("bash 5.2.026-2\n")))
+	assert.Equal(t, map[string]string{"ssh.service": "enabled", "cups.service": "masked", "nfs.service": "disabled"}, parseUnitFiles([]byte(
+		"ssh.service      enabled  enabled\napt-daily.service static   -\ncups.service masked enabled\nnfs.service disabled enabled\n")))
+}
+
+func TestHashFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.conf"), []byte("a"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "conf.d"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "b.conf"), []byte("b"), 0644))
+
+	files, err := hashFiles([]string{filepath.Join(dir, "a.conf"), filepath.Join(dir, "conf.d"), filepath.Join(dir, "none")})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		filepath.Join(dir, "a.conf"):        "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb",
+		filepath.Join(dir, "conf.d/b.conf"): "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d",
+		filepath.Join(dir, "none"):          Missing,
+	}, files)
+}
+
+func TestCollectAndCompare(t *testing.T) {
+	origRun, origLook, origHost := runCommand, lookPath, getHostInfo
+	defer func() { runCommand, lookPath, getHostInfo = origRun, origLook, origHost }()
+
+	lookPath = func(name string) (string, error) {
+		if name == "dpkg-query" {
+			return "/usr/bin/dpkg-query", nil
+		}
+		return "", errors.New("not found")
+	}
+	runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "dpkg-query" {
+			return []byte("ii \tapt\t2.6.1\nii \tcurl\t7.88\n"), nil
+		}
+		return nil, errors.New("systemctl: not running")
+	}
+	getHostInfo = func() (*system.HostInfo, error) {
+		return &system.HostInfo{Hostname: "web1", OS: "linux", Platform: "debian", PlatformVersion: "12.5", KernelVersion: "6.1.0", KernelArch: "x86_64"}, nil
+	}
+
+	images := func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"nginx:1.25": "sha256:aaa"}, nil
+	}
+	local := NewCollector(nil, map[string]string{"role": "web"}, images).Collect(context.Background())
+	assert.Equal(t, "dpkg-query", local.PackageManager)
+	assert.Equal(t, "2.6.1", local.Packages["apt"])
+	assert.Equal(t, map[string]string{"services": "systemctl: not running"}, local.Errors)
+	assert.Len(t, local.Digests, 6)
+
+	// The same host under another name has the same digests
+	getHostInfo = func() (*system.HostInfo, error) {
+		return &system.HostInfo{Hostname: "web2", OS: "linux", Platform: "debian", PlatformVersion: "12.5", KernelVersion: "6.1.0", KernelArch: "x86_64"}, nil
+	}
+	remote := NewCollector(nil, nil, images).Collect(context.Background())
+	assert.Equal(t, local.Digests, remote.Digests)
+
+	drift := Compare(local, remote)
+	assert.True(t, drift.Identical)
+	assert.Equal(t, "web2", drift.Remote)
+	assert.Equal(t, []string{"services"}, drift.Skipped)
+
+	remote.Packages["curl"] = "8.0"
+	delete(remote.Packages, "apt")
+	remote.Host.Kernel = "6.6.0"
+	remote.Images = map[string]string{}
+	drift = Compare(local, remote)
+	assert.False(t, drift.Identical)
+	assert.Equal(t, 4, drift.Changes)
+	assert.Equal(t, []Change{{Name: "apt", Local: "2.6.1"}, {Name: "curl", Local: "7.88", Remote: "8.0"}}, drift.Sections["packages"])
+	assert.Equal(t, []Change{{Name: "kernel", Local: "6.1.0", Remote: "6.6.0"}}, drift.Sections["host"])
+	assert.Equal(t, []Change{{Name: "nginx:1.25", Local: "sha256:aaa"}}, drift.Sections["images"])
+}
@@ -0,0 +1,48 @@
+package profile
+
+import "time"
+
+// Host identifies the operating system and kernel
+type Host struct {
+	Hostname        string            `json:"hostname"`
+	OS              string            `json:"os"`
+	Platform        string            `json:"platform"`
+	PlatformVersion string            `json:"platform_version"`
+	Kernel          string            `json:"kernel"`
+	Arch            string            `json:"arch"`
+	Labels          map[string]string `json:"labels,omitempty"`
+}
+
+// Profile is a normalized description of a host's configuration, for
+// comparing hosts that should be identical
+type Profile struct {
+	Host           Host              `json:"host"`
+	PackageManager string            `json:"package_manager,omitempty"`
+	Packages       map[string]string `json:"packages"` // Name to version
+	Services       map[string]string `json:"services"` // Unit to enabled, disabled or masked
+	Images         map[string]string `json:"images"`   // repo:tag to image ID
+	Files          map[string]string `json:"files"`    // Path to SHA-256, missing or unreadable
+	// Digests hash each section, and "all" every section, so equal digests
+	// mean equal sections
+	Digests     map[string]string `json:"digests"`
+	Errors      map[string]string `json:"errors,omitempty"` // Sections that could not be read
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// Change is one item that differs between two profiles. An empty side
+// means the item is absent there.
+type Change struct {
+	Name   string `json:"name"`
+	Local  string `json:"local,omitempty"`
+	Remote string `json:"remote,omitempty"`
+}
+
+// Drift is the difference between this host's profile and another's
+type Drift struct {
+	Remote    string              `json:"remote"` // The other host's name
+	Identical bool                `json:"identical"`
+	Sections  map[string][]Change `json:"sections"` // Only sections with changes
+	Changes   int                 `json:"changes"`
+	// Skipped lists sections either side could not read
+	Skipped []string `json:"skipped,omitempty"`
+}
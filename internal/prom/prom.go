@@ -0,0 +1,139 @@
+package prom
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+type family struct {
+	help    string
+	typ     string
+	samples []string
+}
+
+// Writer collects samples and renders them in the text format, with the
+// samples of each metric together under its HELP and TYPE lines
+type Writer struct {
+	families map[string]*family
+	order    []string
+}
+
+// NewWriter creates an empty writer
+func NewWriter() *Writer {
+	return &Writer{families: map[string]*family{}}
+}
+
+// Gauge adds a gauge sample. labels are name, value pairs.
+func (w *Writer) Gauge(name, help string, value float64, labels ...string) {
+	w.add(name, help, Gauge, value, labels)
+}
+
+// Counter adds a counter sample. labels are name, value pairs.
+func (w *Writer) Counter(name, help string, value float64, labels ...string) {
+	w.add(name, help, Counter, value, labels)
+}
+
+func (w *Writer) add(name, help, typ string, value float64, labels []string) {
+	f, ok := w.families[name]
+	if !ok {
+		f = &family{help: help, typ: typ}
+		w.families[name] = f
+		w.order = append(w.order, name)
+	}
+
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 1 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(LabelName(labels[i]))
+			b.WriteString(`="`)
+			b.WriteString(escape(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatValue(value))
+	f.samples = append(f.samples, b.String())
+}
+
+// Bytes renders the metrics in the order they were first added
+func (w *Writer) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, name := range w.order {
+		f := w.families[name]
+		buf.WriteString("# HELP " + name + " " + strings.ReplaceAll(f.help, "\n", " ") + "\n")
+		buf.WriteString("# TYPE " + name + " " + f.typ + "\n")
+		for _, s := range f.samples {
+			buf.WriteString(s + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// Labels flattens a map into sorted name, value pairs
+func Labels(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, 2*len(m))
+	for _, name := range names {
+		pairs = append(pairs, name, m[name])
+	}
+	return pairs
+}
+
+// LabelName replaces characters not allowed in a label name with _
+func LabelName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
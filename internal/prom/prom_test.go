@@ -0,0 +1,35 @@
+package prom
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	w := NewWriter()
+	w.Gauge("hivedeck_temperature_celsius", "Sensor temperature", 48.5, "sensor", "cpu_thermal")
+	w.Counter("hivedeck_network_receive_bytes_total", "Bytes received", 1e12, "interface", "eth0")
+	w.Gauge("hivedeck_temperature_celsius", "Sensor temperature", math.NaN(), "sensor", `odd "name"`+"\n")
+	w.Gauge("hivedeck_up", "Always 1", 1)
+
+	assert.Equal(t, `# HELP hivedeck_temperature_celsius Sensor temperature
+# TYPE hivedeck_temperature_celsius gauge
+hivedeck_temperature_celsius{sensor="cpu_thermal"} 48.5
+hivedeck_temperature_celsius{sensor="odd \"name\"\n"} NaN
+# HELP hivedeck_network_receive_bytes_total Bytes received
+# TYPE hivedeck_network_receive_bytes_total counter
+hivedeck_network_receive_bytes_total{interface="eth0"} 1e+12
+# HELP hivedeck_up Always 1
+# TYPE hivedeck_up gauge
+hivedeck_up 1
+`, string(w.Bytes()))
+}
+
+func TestLabels(t *testing.T) {
+	assert.Equal(t, []string{"role", "nas", "site", "home"}, Labels(map[string]string{"site": "home", "role": "nas"}))
+	assert.Equal(t, "rack_id", LabelName("rack-id"))
+	assert.Equal(t, "_zone", LabelName("1zone"))
+	assert.Equal(t, "_", LabelName(""))
+}
@@ -0,0 +1,337 @@
+package pull
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of a request or response body
+	SignatureHeader = "X-Hivedeck-Signature"
+	// AgentHeader identifies the polling agent
+	AgentHeader = "X-Hivedeck-Agent"
+	// TimestampHeader is signed on polls so captured requests cannot be replayed later
+	TimestampHeader = "X-Hivedeck-Timestamp"
+	// LabelsHeader carries the agent's labels, URL-encoded, on polls
+	LabelsHeader = "X-Hivedeck-Labels"
+
+	// MaxQueueSize is the largest queue document accepted (1MB)
+	MaxQueueSize = 1 << 20
+	// MaxCommandsPerPoll limits how many commands run per poll
+	MaxCommandsPerPoll = 25
+	// MaxCommandTTL is the furthest in the future a command may expire,
+	// which bounds how long its nonce must be remembered
+	MaxCommandTTL = time.Hour
+)
+
+// ErrBadSignature is returned when a queue is not signed with the shared secret
+var ErrBadSignature = errors.New("queue signature is missing or invalid")
+
+// Sign returns the signature header value for body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Client polls the dashboard for commands, runs the allowed ones and posts
+// the results back. It lets the dashboard control hosts that cannot accept
+// inbound connections.
+type Client struct {
+	url      string
+	secret   []byte
+	agent    string
+	interval time.Duration
+	allowed  []string
+	exec     Executor
+	http     *http.Client
+
+	// seen holds executed command IDs until they expire, so a replayed
+	// queue does not run a command twice
+	seen map[string]time.Time
+	// nonces holds used command nonces until they expire, so a signed
+	// command cannot be replayed under a different ID
+	nonces map[string]time.Time
+	status Status
+	labels string
+	mu     sync.Mutex
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewClient creates a pull client. allowed lists "METHOD /path/prefix"
+// patterns; commands matching none of them are rejected.
+func NewClient(url, secret, agent string, interval time.Duration, allowed []string, exec Executor) *Client {
+	return &Client{
+		url:      url,
+		secret:   []byte(secret),
+		agent:    agent,
+		interval: interval,
+		allowed:  allowed,
+		exec:     exec,
+		http:     &http.Client{Timeout: 30 * time.Second},
+		seen:     make(map[string]time.Time),
+		nonces:   make(map[string]time.Time),
+		status: Status{
+			Enabled:  true,
+			URL:      url,
+			Interval: interval.String(),
+			Allowed:  allowed,
+		},
+		stop: make(chan struct{}),
+	}
+}
+
+// Start polls every interval until Stop is called
+func (c *Client) Start() {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.Poll(context.Background()); err != nil {
+				log.Printf("Pull mode: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends polling
+func (c *Client) Stop() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+// SetLabels sets the labels sent with each poll, so the dashboard can
+// group agents
+func (c *Client) SetLabels(labels map[string]string) {
+	values := make(url.Values, len(labels))
+	for k, v := range labels {
+		values.Set(k, v)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.labels = values.Encode()
+}
+
+// Status returns a snapshot of the client's state
+func (c *Client) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Poll fetches the queue once, runs new commands and reports their results
+func (c *Client) Poll(ctx context.Context) error {
+	err := c.poll(ctx)
+
+	now := time.Now()
+	c.mu.Lock()
+	c.status.LastPoll = &now
+	c.status.LastError = ""
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+	c.mu.Unlock()
+
+	return err
+}
+
+func (c *Client) poll(ctx context.Context) error {
+	queue, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var results []Result
+	for _, cmd := range queue.Commands {
+		if len(results) >= MaxCommandsPerPoll {
+			break
+		}
+		result, run := c.admit(cmd)
+		if run {
+			result = c.exec(ctx, cmd)
+			result.ID = cmd.ID
+			log.Printf("[PULL] %s %s %s | Status: %d", cmd.ID, strings.ToUpper(cmd.Method), cmd.Path, result.Status)
+		}
+		if result.ID != "" {
+			results = append(results, result)
+		}
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+	return c.report(ctx, results)
+}
+
+// admit decides whether cmd may run. Rejected commands get a result
+// explaining why; commands already run are skipped silently.
+func (c *Client) admit(cmd Command) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, expires := range c.seen {
+		if now.After(expires) {
+			delete(c.seen, id)
+		}
+	}
+	for nonce, expires := range c.nonces {
+		if now.After(expires) {
+			delete(c.nonces, nonce)
+		}
+	}
+
+	reject := func(status int, message string) (Result, bool) {
+		c.status.Rejected++
+		body, _ := json.Marshal(apierror.New(status, message, nil))
+		return Result{ID: cmd.ID, Status: status, Body: body, ExecutedAt: now}, false
+	}
+
+	if _, done := c.seen[cmd.ID]; done || cmd.ID == "" {
+		return Result{}, false
+	}
+	if cmd.ExpiresAt.IsZero() || now.After(cmd.ExpiresAt) {
+		c.seen[cmd.ID] = now.Add(MaxCommandTTL)
+		return reject(http.StatusGone, "command expired")
+	}
+	if cmd.ExpiresAt.After(now.Add(MaxCommandTTL)) {
+		c.seen[cmd.ID] = now.Add(MaxCommandTTL)
+		return reject(http.StatusBadRequest, "command expiry is too far in the future")
+	}
+
+	c.seen[cmd.ID] = cmd.ExpiresAt
+	if cmd.Agent != c.agent {
+		return reject(http.StatusForbidden, "command is for another agent")
+	}
+	if cmd.Nonce == "" {
+		return reject(http.StatusBadRequest, "command has no nonce")
+	}
+	if _, used := c.nonces[cmd.Nonce]; used {
+		return reject(http.StatusConflict, "command nonce already used")
+	}
+	c.nonces[cmd.Nonce] = cmd.ExpiresAt
+	if !c.isAllowed(cmd) {
+		return reject(http.StatusForbidden, "command not allowed in pull mode")
+	}
+
+	c.status.Executed++
+	return Result{}, true
+}
+
+func (c *Client) isAllowed(cmd Command) bool {
+	method := strings.ToUpper(cmd.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	for _, pattern := range c.allowed {
+		m, prefix, ok := strings.Cut(strings.TrimSpace(pattern), " ")
+		if !ok {
+			continue
+		}
+		if (m == "*" || strings.EqualFold(m, method)) && strings.HasPrefix(cmd.Path, strings.TrimSpace(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) fetch(ctx context.Context) (*Queue, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pull URL: %w", err)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(AgentHeader, c.agent)
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(c.secret, []byte(c.agent+"\n"+ts)))
+	c.mu.Lock()
+	if c.labels != "" {
+		req.Header.Set(LabelsHeader, c.labels)
+	}
+	c.mu.Unlock()
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch command queue: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return &Queue{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("command queue returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxQueueSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command queue: %w", err)
+	}
+	if len(body) > MaxQueueSize {
+		return nil, fmt.Errorf("command queue exceeds %d bytes", MaxQueueSize)
+	}
+	if !Verify(c.secret, body, resp.Header.Get(SignatureHeader)) {
+		return nil, ErrBadSignature
+	}
+
+	var queue Queue
+	if err := json.Unmarshal(body, &queue); err != nil {
+		return nil, fmt.Errorf("failed to parse command queue: %w", err)
+	}
+	return &queue, nil
+}
+
+func (c *Client) report(ctx context.Context, results []Result) error {
+	body, err := json.Marshal(ResultBatch{Agent: c.agent, Results: results})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid pull URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(AgentHeader, c.agent)
+	req.Header.Set(SignatureHeader, Sign(c.secret, body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post results: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("posting results returned %s", resp.Status)
+	}
+	return nil
+}
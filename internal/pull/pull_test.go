@@ -0,0 +1,155 @@
+package pull
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "shared-secret"
+
+// dashboard serves a fixed queue and records posted results
+type dashboard struct {
+	queue     []byte
+	signature string
+	mu        sync.Mutex
+	results   []ResultBatch
+	labels    string
+}
+
+func (d *dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ts := r.Header.Get(TimestampHeader)
+		if !Verify([]byte(secret), []byte(r.Header.Get(AgentHeader)+"\n"+ts), r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		d.labels = r.Header.Get(LabelsHeader)
+		w.Header().Set(SignatureHeader, d.signature)
+		_, _ = w.Write(d.queue)
+	case http.MethodPost:
+		body, _ := io.ReadAll(r.Body)
+		if !Verify([]byte(secret), body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var batch ResultBatch
+		_ = json.Unmarshal(body, &batch)
+		d.mu.Lock()
+		d.results = append(d.results, batch)
+		d.mu.Unlock()
+	}
+}
+
+func newDashboard(t *testing.T, commands []Command) *dashboard {
+	body, err := json.Marshal(Queue{Commands: commands})
+	require.NoError(t, err)
+	return &dashboard{queue: body, signature: Sign([]byte(secret), body)}
+}
+
+func TestClient_Poll(t *testing.T) {
+	future := time.Now().Add(time.Minute)
+	d := newDashboard(t, []Command{
+		{ID: "1", Agent: "pi", Nonce: "n1", Method: "GET", Path: "/api/metrics", ExpiresAt: future},
+		{ID: "2", Agent: "pi", Nonce: "n2", Method: "POST", Path: "/api/system/reboot", ExpiresAt: future},
+		{ID: "3", Agent: "pi", Nonce: "n3", Method: "GET", Path: "/api/info", ExpiresAt: time.Now().Add(-time.Minute)},
+	})
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	var executed []string
+	exec := func(ctx context.Context, cmd Command) Result {
+		executed = append(executed, cmd.ID)
+		return Result{Status: http.StatusOK, Body: json.RawMessage(`{"ok":true}`)}
+	}
+
+	c := NewClient(srv.URL, secret, "pi", time.Minute, []string{"GET /api/"}, exec)
+	c.SetLabels(map[string]string{"role": "nas", "location": "garage"})
+	require.NoError(t, c.Poll(context.Background()))
+
+	assert.Equal(t, []string{"1"}, executed)
+	assert.Equal(t, "location=garage&role=nas", d.labels)
+	require.Len(t, d.results, 1)
+	assert.Equal(t, "pi", d.results[0].Agent)
+
+	statuses := map[string]int{}
+	for _, r := range d.results[0].Results {
+		statuses[r.ID] = r.Status
+	}
+	assert.Equal(t, map[string]int{"1": http.StatusOK, "2": http.StatusForbidden, "3": http.StatusGone}, statuses)
+
+	// Polling the same queue again does not re-run anything
+	require.NoError(t, c.Poll(context.Background()))
+	assert.Equal(t, []string{"1"}, executed)
+	assert.Len(t, d.results, 1)
+
+	status := c.Status()
+	assert.Equal(t, 1, status.Executed)
+	assert.Equal(t, 2, status.Rejected)
+	assert.NotNil(t, status.LastPoll)
+}
+
+func TestClient_RejectsUnsignedQueue(t *testing.T) {
+	d := newDashboard(t, []Command{
+		{ID: "1", Agent: "pi", Nonce: "n1", Method: "GET", Path: "/api/metrics", ExpiresAt: time.Now().Add(time.Minute)},
+	})
+	d.signature = Sign([]byte("wrong-secret"), d.queue)
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	exec := func(ctx context.Context, cmd Command) Result {
+		t.Fatal("unsigned commands must not run")
+		return Result{}
+	}
+
+	c := NewClient(srv.URL, secret, "pi", time.Minute, []string{"* /api/"}, exec)
+	assert.ErrorIs(t, c.Poll(context.Background()), ErrBadSignature)
+	assert.Equal(t, ErrBadSignature.Error(), c.Status().LastError)
+}
+
+func TestClient_RejectsMisboundCommands(t *testing.T) {
+	future := time.Now().Add(time.Minute)
+	d := newDashboard(t, []Command{
+		{ID: "1", Agent: "pi", Nonce: "n1", Method: "GET", Path: "/api/metrics", ExpiresAt: future},
+		{ID: "2", Agent: "nas", Nonce: "n2", Method: "GET", Path: "/api/metrics", ExpiresAt: future},
+		{ID: "3", Agent: "pi", Method: "GET", Path: "/api/metrics", ExpiresAt: future},
+		{ID: "4", Agent: "pi", Nonce: "n1", Method: "GET", Path: "/api/metrics", ExpiresAt: future},
+		{ID: "5", Agent: "pi", Nonce: "n5", Method: "GET", Path: "/api/metrics", ExpiresAt: time.Now().Add(2 * MaxCommandTTL)},
+	})
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	var executed []string
+	exec := func(ctx context.Context, cmd Command) Result {
+		executed = append(executed, cmd.ID)
+		return Result{Status: http.StatusOK}
+	}
+
+	c := NewClient(srv.URL, secret, "pi", time.Minute, []string{"GET /api/"}, exec)
+	require.NoError(t, c.Poll(context.Background()))
+
+	assert.Equal(t, []string{"1"}, executed)
+	require.Len(t, d.results, 1)
+
+	statuses := map[string]int{}
+	for _, r := range d.results[0].Results {
+		statuses[r.ID] = r.Status
+	}
+	assert.Equal(t, map[string]int{
+		"1": http.StatusOK,
+		"2": http.StatusForbidden,
+		"3": http.StatusBadRequest,
+		"4": http.StatusConflict,
+		"5": http.StatusBadRequest,
+	}, statuses)
+	assert.Equal(t, 4, c.Status().Rejected)
+}
@@ -0,0 +1,54 @@
+package pull
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Command is a single API call queued by the dashboard. Agent, Nonce and
+// ExpiresAt are covered by the queue signature and bind the command to one
+// agent and one run.
+type Command struct {
+	ID        string          `json:"id"`
+	Agent     string          `json:"agent"`
+	Nonce     string          `json:"nonce"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Body      json.RawMessage `json:"body,omitempty"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// Queue is the signed document returned by the dashboard
+type Queue struct {
+	Commands []Command `json:"commands"`
+}
+
+// Result reports the outcome of a command back to the dashboard
+type Result struct {
+	ID         string          `json:"id"`
+	Status     int             `json:"status"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	ExecutedAt time.Time       `json:"executed_at"`
+}
+
+// ResultBatch is the signed document posted back to the dashboard
+type ResultBatch struct {
+	Agent   string   `json:"agent"`
+	Results []Result `json:"results"`
+}
+
+// Executor runs an allowed command against the local API
+type Executor func(ctx context.Context, cmd Command) Result
+
+// Status describes the state of pull mode
+type Status struct {
+	Enabled   bool       `json:"enabled"`
+	URL       string     `json:"url,omitempty"`
+	Interval  string     `json:"interval,omitempty"`
+	Allowed   []string   `json:"allowed,omitempty"`
+	LastPoll  *time.Time `json:"last_poll,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Executed  int        `json:"executed"`
+	Rejected  int        `json:"rejected"`
+}
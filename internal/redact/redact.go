@@ -0,0 +1,114 @@
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces a redacted value
+const Mask = "********"
+
+// DefaultKeywords mark a flag or variable as holding a secret when its
+// name contains one of them
+var DefaultKeywords = []string{
+	"password", "passwd", "secret", "token", "apikey", "api-key", "api_key",
+	"access-key", "access_key", "private-key", "private_key", "credential",
+}
+
+// variable matches the name in a KEY=value argument
+var variable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Redactor masks secrets in command lines: the values of flags and
+// KEY=value arguments whose names contain a keyword, and passwords in
+// URLs. A nil Redactor leaves everything as it is.
+type Redactor struct {
+	keywords []string
+}
+
+// New creates a redactor for keywords, matched case-insensitively
+func New(keywords []string) *Redactor {
+	r := &Redactor{}
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			r.keywords = append(r.keywords, k)
+		}
+	}
+	return r
+}
+
+// Args returns args with secrets masked. A sensitive flag's value is
+// masked whether it follows an = or is the next argument.
+func (r *Redactor) Args(args []string) []string {
+	if r == nil || len(args) == 0 {
+		return args
+	}
+
+	out := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		out[i] = r.url(arg)
+
+		if strings.HasPrefix(arg, "-") {
+			name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if !r.sensitive(name) {
+				continue
+			}
+			if hasValue {
+				out[i] = arg[:strings.IndexByte(arg, '=')+1] + Mask
+			} else if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				out[i] = Mask
+			}
+			continue
+		}
+
+		if name, _, ok := strings.Cut(arg, "="); ok && variable.MatchString(name) && r.sensitive(name) {
+			out[i] = name + "=" + Mask
+		}
+	}
+	return out
+}
+
+// Cmdline masks secrets in a command line of space-separated arguments
+func (r *Redactor) Cmdline(cmdline string) string {
+	if r == nil || cmdline == "" {
+		return cmdline
+	}
+	return strings.Join(r.Args(strings.Split(cmdline, " ")), " ")
+}
+
+// sensitive reports whether a flag or variable name holds a secret
+func (r *Redactor) sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, k := range r.keywords {
+		if strings.Contains(name, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// url masks the password of a URL within arg, such as a database DSN
+func (r *Redactor) url(arg string) string {
+	start := strings.Index(arg, "://")
+	if start < 0 {
+		return arg
+	}
+	for start > 0 && isSchemeChar(arg[start-1]) {
+		start--
+	}
+	u, err := url.Parse(arg[start:])
+	if err != nil || u.User == nil {
+		return arg
+	}
+	if _, ok := u.User.Password(); !ok {
+		return arg
+	}
+	u.User = url.UserPassword(u.User.Username(), Mask)
+	return arg[:start] + strings.Replace(u.String(), url.QueryEscape(Mask), Mask, 1)
+}
+
+func isSchemeChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'
+}
@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// SystemReadPaths are readable (and executable) in every sandbox: programs
+// and libraries the agent runs, configuration, kernel interfaces and logs
+var SystemReadPaths = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib64",
+	"/etc", "/proc", "/sys", "/run",
+	"/var/log", "/var/lib/systemd",
+}
+
+// SystemWritePaths are writable in every sandbox. Commands the agent runs
+// write to /dev/null and /tmp.
+var SystemWritePaths = []string{"/dev", "/tmp"}
+
+// Policy is what the sandbox allows. Paths that do not exist are skipped.
+type Policy struct {
+	ReadPaths  []string // Readable and executable
+	WritePaths []string // Also writable, including creating and removing files
+	Seccomp    bool     // Deny syscalls the agent never needs
+}
+
+// Status reports which restrictions are in place
+type Status struct {
+	Landlock    bool     `json:"landlock"`
+	LandlockABI int      `json:"landlock_abi,omitempty"`
+	Seccomp     bool     `json:"seccomp"`
+	Denied      []string `json:"denied_syscalls,omitempty"`
+	ReadPaths   []string `json:"read_paths,omitempty"`
+	WritePaths  []string `json:"write_paths,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// normalize cleans paths, drops empty and duplicate ones, and drops read
+// paths that are also writable
+func (p Policy) normalize() Policy {
+	write := clean(p.WritePaths, nil)
+	skip := make(map[string]bool, len(write))
+	for _, path := range write {
+		skip[path] = true
+	}
+	return Policy{
+		ReadPaths:  clean(p.ReadPaths, skip),
+		WritePaths: write,
+		Seccomp:    p.Seccomp,
+	}
+}
+
+func clean(paths []string, skip map[string]bool) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		path = filepath.Clean(path)
+		if seen[path] || skip[path] {
+			continue
+		}
+		seen[path] = true
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,168 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Filesystem rights by landlock ABI version. Rights a kernel does not know
+// cannot be handled, so they stay unrestricted there.
+const (
+	readAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+	abi1Access = readAccess |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+
+	// Rights that apply to a file rather than a directory's contents
+	fileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// handledAccess returns the rights a ruleset restricts on a kernel with the
+// given landlock ABI
+func handledAccess(abi int) uint64 {
+	access := uint64(abi1Access)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	return access
+}
+
+// Apply restricts the whole process, and every program it runs, to the
+// policy. It is best effort: restrictions the kernel does not support are
+// reported in the status rather than failing. Sandboxing cannot be undone.
+func Apply(p Policy) *Status {
+	p = p.normalize()
+	status := &Status{ReadPaths: p.ReadPaths, WritePaths: p.WritePaths}
+
+	// Both landlock and an unprivileged seccomp filter need no_new_privs,
+	// which also stops setuid programs such as sudo from gaining privileges
+	allThreads := true
+	if _, _, errno := syscall.AllThreadsSyscall6(syscall.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0, 0); errno != 0 {
+		// Binaries linked with cgo cannot change every thread at once.
+		// Seccomp can still sync its filter to all threads.
+		allThreads = false
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("no_new_privs: %v", err))
+			return status
+		}
+	}
+
+	switch abi, err := landlockABI(); {
+	case err != nil:
+		status.Errors = append(status.Errors, fmt.Sprintf("landlock: %v", err))
+	case !allThreads:
+		status.Errors = append(status.Errors, "landlock: needs a binary built with CGO_ENABLED=0")
+	default:
+		if err := applyLandlock(abi, p); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("landlock: %v", err))
+		} else {
+			status.Landlock = true
+			status.LandlockABI = abi
+		}
+	}
+
+	if p.Seccomp {
+		if err := applySeccomp(); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("seccomp: %v", err))
+		} else {
+			status.Seccomp = true
+			status.Denied = deniedNames()
+		}
+	}
+
+	return status
+}
+
+// landlockABI returns the kernel's landlock ABI version
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	switch errno {
+	case 0:
+		return int(abi), nil
+	case unix.ENOSYS:
+		return 0, errors.New("not supported by this kernel")
+	case unix.EOPNOTSUPP:
+		return 0, errors.New("disabled in this kernel")
+	}
+	return 0, errno
+}
+
+// applyLandlock restricts filesystem access on every thread to the
+// policy's paths
+func applyLandlock(abi int, p Policy) error {
+	handled := handledAccess(abi)
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+
+	// Only handled_access_fs is passed, which every ABI understands
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET,
+		uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr.Access_fs), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+
+	for _, path := range p.ReadPaths {
+		if err := addPathRule(int(fd), path, readAccess); err != nil {
+			return err
+		}
+	}
+	for _, path := range p.WritePaths {
+		if err := addPathRule(int(fd), path, handled); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to restrict the process: %w", errno)
+	}
+	return nil
+}
+
+// addPathRule allows access beneath path. Missing paths are skipped.
+func addPathRule(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= fileAccess
+	}
+
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset),
+		unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to allow %s: %w", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestHandledAccess(t *testing.T) {
+	assert.Zero(t, handledAccess(1)&unix.LANDLOCK_ACCESS_FS_REFER)
+	assert.NotZero(t, handledAccess(2)&unix.LANDLOCK_ACCESS_FS_REFER)
+	assert.Zero(t, handledAccess(2)&unix.LANDLOCK_ACCESS_FS_TRUNCATE)
+	assert.NotZero(t, handledAccess(3)&unix.LANDLOCK_ACCESS_FS_TRUNCATE)
+	assert.Equal(t, uint64(readAccess), handledAccess(3)&readAccess)
+}
+
+func TestSeccompFilter(t *testing.T) {
+	filter := seccompFilter(unix.AUDIT_ARCH_X86_64, []uintptr{200, 100})
+	require.Len(t, filter, 8)
+
+	// Checks are sorted and each jumps to the final deny instruction
+	deny := len(filter) - 1
+	assert.Equal(t, uint32(100), filter[4].K)
+	assert.Equal(t, uint32(200), filter[5].K)
+	for _, i := range []int{4, 5} {
+		assert.Equal(t, deny, i+1+int(filter[i].Jt), "instruction %d", i)
+	}
+	assert.Equal(t, deny, 1+1+int(filter[1].Jf), "foreign architectures are denied")
+	assert.Equal(t, deny, 3+1+int(filter[3].Jt), "x32 syscalls are denied")
+
+	assert.Equal(t, uint32(unix.SECCOMP_RET_ALLOW), filter[deny-1].K)
+	assert.Equal(t, uint32(unix.SECCOMP_RET_ERRNO|uint32(unix.EPERM)), filter[deny].K)
+}
+
+// TestApply sandboxes a child test process, since the sandbox cannot be
+// lifted from the process that applies it
+func TestApply(t *testing.T) {
+	if _, err := landlockABI(); err != nil {
+		t.Skipf("landlock unavailable: %v", err)
+	}
+
+	dir := t.TempDir()
+	readDir := filepath.Join(dir, "read")
+	writeDir := filepath.Join(dir, "write")
+	require.NoError(t, os.Mkdir(readDir, 0o755))
+	require.NoError(t, os.Mkdir(writeDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(readDir, "file"), []byte("data"), 0o644))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSandboxedChild$", "-test.v")
+	cmd.Env = append(os.Environ(), "SANDBOX_TEST_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestSandboxedChild(t *testing.T) {
+	dir := os.Getenv("SANDBOX_TEST_DIR")
+	if dir == "" {
+		t.Skip("run by TestApply")
+	}
+	readDir := filepath.Join(dir, "read")
+	writeDir := filepath.Join(dir, "write")
+
+	status := Apply(Policy{ReadPaths: []string{readDir}, WritePaths: []string{writeDir}, Seccomp: true})
+	if !status.Landlock {
+		t.Skipf("landlock not applied: %v", status.Errors)
+	}
+
+	_, err := os.ReadFile(filepath.Join(readDir, "file"))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, os.WriteFile(filepath.Join(readDir, "new"), nil, 0o644), os.ErrPermission)
+	assert.NoError(t, os.WriteFile(filepath.Join(writeDir, "new"), nil, 0o644))
+	_, err = os.ReadFile("/etc/hostname")
+	assert.ErrorIs(t, err, os.ErrPermission)
+
+	if status.Seccomp {
+		assert.ErrorIs(t, unix.Unshare(unix.CLONE_NEWUTS), unix.EPERM)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package sandbox
+
+// Apply is a no-op outside Linux, which has no landlock or seccomp
+func Apply(p Policy) *Status {
+	return &Status{Errors: []string{"sandboxing is only supported on Linux"}}
+}
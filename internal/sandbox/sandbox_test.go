@@ -0,0 +1,19 @@
+package sandbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyNormalize(t *testing.T) {
+	p := Policy{
+		ReadPaths:  []string{"/usr", "/etc/", "", "/usr", "/var/lib/hivedeck-agent"},
+		WritePaths: []string{"/var/lib/hivedeck-agent/", "/tmp", "/tmp"},
+		Seccomp:    true,
+	}.normalize()
+
+	assert.Equal(t, []string{"/etc", "/usr"}, p.ReadPaths)
+	assert.Equal(t, []string{"/tmp", "/var/lib/hivedeck-agent"}, p.WritePaths)
+	assert.True(t, p.Seccomp)
+}
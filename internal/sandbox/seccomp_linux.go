@@ -0,0 +1,116 @@
+package sandbox
+
+import (
+	"fmt"
+	"sort"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// deniedSyscalls fail with EPERM once seccomp is applied. The agent never
+// needs them, and they are how a compromised process would reach other
+// processes, the kernel or the host's mounts.
+var deniedSyscalls = map[string]uintptr{
+	"ptrace":            unix.SYS_PTRACE,
+	"process_vm_readv":  unix.SYS_PROCESS_VM_READV,
+	"process_vm_writev": unix.SYS_PROCESS_VM_WRITEV,
+	"mount":             unix.SYS_MOUNT,
+	"umount2":           unix.SYS_UMOUNT2,
+	"pivot_root":        unix.SYS_PIVOT_ROOT,
+	"chroot":            unix.SYS_CHROOT,
+	"setns":             unix.SYS_SETNS,
+	"unshare":           unix.SYS_UNSHARE,
+	"kexec_load":        unix.SYS_KEXEC_LOAD,
+	"kexec_file_load":   unix.SYS_KEXEC_FILE_LOAD,
+	"init_module":       unix.SYS_INIT_MODULE,
+	"finit_module":      unix.SYS_FINIT_MODULE,
+	"delete_module":     unix.SYS_DELETE_MODULE,
+	"bpf":               unix.SYS_BPF,
+	"perf_event_open":   unix.SYS_PERF_EVENT_OPEN,
+	"userfaultfd":       unix.SYS_USERFAULTFD,
+	"open_by_handle_at": unix.SYS_OPEN_BY_HANDLE_AT,
+	"add_key":           unix.SYS_ADD_KEY,
+	"request_key":       unix.SYS_REQUEST_KEY,
+	"keyctl":            unix.SYS_KEYCTL,
+	"swapon":            unix.SYS_SWAPON,
+	"swapoff":           unix.SYS_SWAPOFF,
+	"acct":              unix.SYS_ACCT,
+}
+
+// x32 syscalls on amd64 share the architecture but set this bit; no
+// architecture the agent builds for has native syscalls this high
+const x32SyscallBit = 0x40000000
+
+// Offsets into struct seccomp_data
+const (
+	seccompDataNR   = 0
+	seccompDataArch = 4
+)
+
+// deniedNames returns the names of the denied syscalls, sorted
+func deniedNames() []string {
+	names := make([]string, 0, len(deniedSyscalls))
+	for name := range deniedSyscalls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// seccompFilter builds a BPF program that returns EPERM for the given
+// syscalls, and for any syscall made with a foreign architecture or the x32
+// ABI, and allows everything else
+func seccompFilter(arch uint32, syscalls []uintptr) []unix.SockFilter {
+	sorted := append([]uintptr(nil), syscalls...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+
+	// Every check jumps forward to the deny instruction at the end, which
+	// follows the allow instruction
+	n := len(sorted)
+	prog := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArch),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 0, uint8(n+3)),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNR),
+		jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, uint8(n+1), 0),
+	}
+	for i, nr := range sorted {
+		prog = append(prog, jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), uint8(n-i), 0))
+	}
+	return append(prog,
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|(uint32(unix.EPERM)&unix.SECCOMP_RET_DATA)),
+	)
+}
+
+// applySeccomp installs the filter on every thread of the process. The
+// caller has set no_new_privs.
+func applySeccomp() error {
+	if auditArch == 0 {
+		return fmt.Errorf("not supported on this architecture")
+	}
+
+	syscalls := make([]uintptr, 0, len(deniedSyscalls))
+	for _, nr := range deniedSyscalls {
+		syscalls = append(syscalls, nr)
+	}
+	filter := seccompFilter(auditArch, syscalls)
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	tid, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return errno
+	}
+	if tid != 0 {
+		return fmt.Errorf("thread %d could not be synchronized", tid)
+	}
+	return nil
+}
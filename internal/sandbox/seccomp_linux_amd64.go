@@ -0,0 +1,10 @@
+package sandbox
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+func init() {
+	deniedSyscalls["iopl"] = unix.SYS_IOPL
+	deniedSyscalls["ioperm"] = unix.SYS_IOPERM
+}
@@ -0,0 +1,328 @@
+package schedules
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/maintenance"
+	"github.com/ngenohkevin/hivedeck-agent/internal/power"
+)
+
+const (
+	// MaxSchedules bounds the schedules kept
+	MaxSchedules = 100
+	// MaxNoteLength bounds a schedule's note
+	MaxNoteLength = 200
+
+	// missedGrace is how late a one-off action still runs, e.g. after the
+	// agent restarted
+	missedGrace = 10 * time.Minute
+	// maxSleep bounds how long the scheduler sleeps, so it notices clock
+	// changes
+	maxSleep = time.Minute
+)
+
+// RunFunc runs a service action, returning a message for the schedule
+type RunFunc func(ctx context.Context, service, action string) (string, error)
+
+// Scheduler runs service actions at set times. Schedules are kept in
+// schedules.json under the data directory.
+type Scheduler struct {
+	file    string
+	run     RunFunc
+	allowed func(service string) bool
+	items   []Schedule // Sorted by NextRun
+	mu      sync.Mutex
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	now     func() time.Time
+}
+
+// NewScheduler creates a scheduler that runs actions with run, for the
+// services allowed accepts. Saved schedules are loaded from dataDir; with
+// an empty dataDir they last until restart.
+func NewScheduler(dataDir string, allowed func(string) bool, run RunFunc) *Scheduler {
+	s := &Scheduler{
+		run:     run,
+		allowed: allowed,
+		wake:    make(chan struct{}, 1),
+		now:     time.Now,
+	}
+	if dataDir != "" {
+		s.file = filepath.Join(dataDir, "schedules.json")
+		s.load()
+	}
+	return s
+}
+
+// Start runs due schedules in the background until Stop
+func (s *Scheduler) Start() {
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go s.loop()
+}
+
+// Stop stops running schedules and waits for a running action to finish
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+// List returns the schedules for service, or all with an empty service,
+// soonest first
+func (s *Scheduler) List(service string) *ScheduleList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []Schedule{}
+	for _, item := range s.items {
+		if service == "" || item.Service == service {
+			list = append(list, item)
+		}
+	}
+	return &ScheduleList{Schedules: list, Total: len(list)}
+}
+
+// Add creates a schedule from req
+func (s *Scheduler) Add(req Request) (*Schedule, error) {
+	now := s.now()
+	item, err := s.newSchedule(req, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) >= MaxSchedules {
+		return nil, apierror.Conflict("at most %d schedules can be kept; delete some first", MaxSchedules)
+	}
+	s.items = append(s.items, *item)
+	s.sort()
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	s.notify()
+	return item, nil
+}
+
+// Delete removes a schedule
+func (s *Scheduler) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, item := range s.items {
+		if item.ID == id {
+			s.items = append(s.items[:i], s.items[i+1:]...)
+			s.notify()
+			return s.save()
+		}
+	}
+	return apierror.NotFound("schedule %s not found", id)
+}
+
+// newSchedule validates req
+func (s *Scheduler) newSchedule(req Request, now time.Time) (*Schedule, error) {
+	item := &Schedule{
+		ID:        newID(),
+		Service:   strings.TrimSpace(req.Service),
+		Action:    req.Action,
+		Note:      strings.TrimSpace(req.Note),
+		CreatedAt: now,
+	}
+	if item.Service == "" {
+		return nil, apierror.Invalid("service is required")
+	}
+	if !s.allowed(item.Service) {
+		return nil, apierror.NotAllowed("service '%s' is not in allowed list", item.Service)
+	}
+	switch item.Action {
+	case "start", "stop", "restart":
+	default:
+		return nil, apierror.Invalid("invalid action %q: use start, stop or restart", req.Action)
+	}
+	if len(item.Note) > MaxNoteLength {
+		return nil, apierror.Invalid("note is longer than %d characters", MaxNoteLength)
+	}
+
+	switch {
+	case req.At != "" && req.Schedule != "":
+		return nil, apierror.Invalid("set either at or schedule, not both")
+	case req.At != "":
+		at, _, err := power.ParseWhen(req.At, now)
+		if err != nil {
+			return nil, err
+		}
+		if !at.After(now) {
+			return nil, apierror.Invalid("at must be in the future")
+		}
+		item.At, item.NextRun = &at, at
+	case req.Schedule != "":
+		sched, err := maintenance.ParseSchedule(req.Schedule)
+		if err != nil {
+			return nil, apierror.Invalid("invalid schedule: %v", err)
+		}
+		item.Recurring, item.NextRun = sched.String(), sched.Next(now)
+	default:
+		return nil, apierror.Invalid("set at for a one-off action or schedule for a recurring one")
+	}
+	return item, nil
+}
+
+func (s *Scheduler) loop() {
+	defer close(s.done)
+	for {
+		s.runDue()
+
+		sleep := maxSleep
+		s.mu.Lock()
+		if len(s.items) > 0 {
+			sleep = min(max(s.items[0].NextRun.Sub(s.now()), 0), maxSleep)
+		}
+		s.mu.Unlock()
+
+		timer := time.NewTimer(sleep)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// runDue runs the schedules whose time has come, in order
+func (s *Scheduler) runDue() {
+	for {
+		s.mu.Lock()
+		if len(s.items) == 0 || s.items[0].NextRun.After(s.now()) {
+			s.mu.Unlock()
+			return
+		}
+		item := s.items[0]
+		s.mu.Unlock()
+
+		message, err := s.run(context.Background(), item.Service, item.Action)
+		run := &Run{Time: s.now(), Success: err == nil, Message: message}
+		if err != nil {
+			run.Message = err.Error()
+		}
+		s.finish(item, run)
+	}
+}
+
+// finish removes a one-off schedule once it ran, and moves a recurring one
+// to its next time
+func (s *Scheduler) finish(item Schedule, run *Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.items {
+		if s.items[i].ID != item.ID {
+			continue
+		}
+		if item.Recurring == "" {
+			s.items = append(s.items[:i], s.items[i+1:]...)
+		} else {
+			sched, _ := maintenance.ParseSchedule(item.Recurring)
+			s.items[i].NextRun = sched.Next(run.Time)
+			s.items[i].LastRun = run
+			s.sort()
+		}
+		break
+	}
+	if err := s.save(); err != nil {
+		log.Printf("Failed to save schedules: %v", err)
+	}
+}
+
+// notify wakes the loop to recompute its sleep. The caller holds mu.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sort orders schedules by next run. The caller holds mu.
+func (s *Scheduler) sort() {
+	sort.SliceStable(s.items, func(i, j int) bool { return s.items[i].NextRun.Before(s.items[j].NextRun) })
+}
+
+// save writes all schedules to a temporary file and renames it into
+// place. The caller holds mu.
+func (s *Scheduler) save() error {
+	if s.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), 0750); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	tmp := s.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	if err := os.Rename(tmp, s.file); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	return nil
+}
+
+// load reads saved schedules. Recurring schedules resume at their next
+// time from now, and one-off actions missed by more than missedGrace are
+// dropped.
+func (s *Scheduler) load() {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return
+	}
+	var items []Schedule
+	if err := json.Unmarshal(data, &items); err != nil {
+		log.Printf("Failed to read schedules: %v", err)
+		return
+	}
+
+	now := s.now()
+	for _, item := range items {
+		if item.Recurring != "" {
+			sched, err := maintenance.ParseSchedule(item.Recurring)
+			if err != nil {
+				log.Printf("Dropping schedule %s: %v", item.ID, err)
+				continue
+			}
+			item.NextRun = sched.Next(now)
+		} else if now.Sub(item.NextRun) > missedGrace {
+			log.Printf("Dropping missed schedule %s: %s %s at %s", item.ID, item.Action, item.Service, item.NextRun.Format(time.RFC3339))
+			continue
+		}
+		s.items = append(s.items, item)
+	}
+	s.sort()
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,127 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+type call struct{ service, action string }
+
+// testScheduler returns a scheduler for "game" and "web" whose clock reads
+// *now, recording the actions it runs
+func testScheduler(t *testing.T, dataDir string, now *time.Time) (*Scheduler, *[]call) {
+	calls := &[]call{}
+	s := NewScheduler("", func(service string) bool { return service == "game" || service == "web" },
+		func(_ context.Context, service, action string) (string, error) {
+			*calls = append(*calls, call{service, action})
+			if service == "web" {
+				return "", errors.New("unit failed")
+			}
+			return "done", nil
+		})
+	s.now = func() time.Time { return *now }
+	if dataDir != "" {
+		s.file = filepath.Join(dataDir, "schedules.json")
+		s.load()
+	}
+	return s, calls
+}
+
+func TestSchedulerAdd(t *testing.T) {
+	now := time.Date(2026, 3, 6, 12, 0, 0, 0, time.Local) // A Friday
+	s, _ := testScheduler(t, "", &now)
+
+	item, err := s.Add(Request{Service: "game", Action: "stop", Schedule: "daily 02:00"})
+	require.NoError(t, err)
+	assert.Equal(t, "daily 02:00", item.Recurring)
+	assert.Equal(t, time.Date(2026, 3, 7, 2, 0, 0, 0, time.Local), item.NextRun)
+
+	item, err = s.Add(Request{Service: "game", Action: "restart", At: "+30m", Note: "patch"})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(30*time.Minute), *item.At)
+
+	list := s.List("game")
+	require.Equal(t, 2, list.Total)
+	assert.Equal(t, "restart", list.Schedules[0].Action, "soonest first")
+	assert.Equal(t, 0, s.List("web").Total)
+
+	for _, req := range []Request{
+		{Action: "stop", At: "+1m"},
+		{Service: "sshd", Action: "stop", At: "+1m"},
+		{Service: "game", Action: "reload", At: "+1m"},
+		{Service: "game", Action: "stop"},
+		{Service: "game", Action: "stop", At: "+1m", Schedule: "daily 02:00"},
+		{Service: "game", Action: "stop", Schedule: "someday 02:00"},
+		{Service: "game", Action: "stop", At: "2020-01-01T00:00:00Z"},
+	} {
+		_, err := s.Add(req)
+		assert.Error(t, err, req)
+	}
+	_, err = s.Add(Request{Service: "sshd", Action: "stop", At: "+1m"})
+	assert.ErrorIs(t, err, apierror.ErrNotAllowed)
+
+	require.NoError(t, s.Delete(item.ID))
+	assert.ErrorIs(t, s.Delete(item.ID), apierror.ErrNotFound)
+}
+
+func TestSchedulerRunsDue(t *testing.T) {
+	now := time.Date(2026, 3, 6, 12, 0, 0, 0, time.Local)
+	s, calls := testScheduler(t, "", &now)
+
+	_, err := s.Add(Request{Service: "game", Action: "stop", At: "+5m"})
+	require.NoError(t, err)
+	recurring, err := s.Add(Request{Service: "web", Action: "start", Schedule: "daily 12:10"})
+	require.NoError(t, err)
+
+	s.runDue()
+	assert.Empty(t, *calls, "nothing is due yet")
+
+	now = now.Add(15 * time.Minute)
+	s.runDue()
+	assert.Equal(t, []call{{"game", "stop"}, {"web", "start"}}, *calls)
+
+	// The one-off is gone and the recurring one moved to tomorrow
+	list := s.List("")
+	require.Equal(t, 1, list.Total)
+	assert.Equal(t, recurring.ID, list.Schedules[0].ID)
+	assert.Equal(t, time.Date(2026, 3, 7, 12, 10, 0, 0, time.Local), list.Schedules[0].NextRun)
+	require.NotNil(t, list.Schedules[0].LastRun)
+	assert.False(t, list.Schedules[0].LastRun.Success)
+	assert.Equal(t, "unit failed", list.Schedules[0].LastRun.Message)
+}
+
+func TestSchedulerPersists(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 6, 12, 0, 0, 0, time.Local)
+	s, _ := testScheduler(t, dir, &now)
+	_, err := s.Add(Request{Service: "game", Action: "stop", At: "+5m"})
+	require.NoError(t, err)
+	_, err = s.Add(Request{Service: "game", Action: "start", At: "+2h"})
+	require.NoError(t, err)
+	_, err = s.Add(Request{Service: "game", Action: "restart", Schedule: "sun 04:00"})
+	require.NoError(t, err)
+
+	// An hour later, the first one-off was missed while the agent was down
+	now = now.Add(time.Hour)
+	s, _ = testScheduler(t, dir, &now)
+	list := s.List("game")
+	require.Equal(t, 2, list.Total)
+	assert.Equal(t, "start", list.Schedules[0].Action)
+	assert.Equal(t, "restart", list.Schedules[1].Action)
+	assert.Equal(t, time.Date(2026, 3, 8, 4, 0, 0, 0, time.Local), list.Schedules[1].NextRun)
+}
+
+func TestSchedulerStartStop(t *testing.T) {
+	now := time.Now()
+	s, _ := testScheduler(t, "", &now)
+	s.Start()
+	s.Stop()
+}
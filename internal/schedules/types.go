@@ -0,0 +1,38 @@
+package schedules
+
+import "time"
+
+// Schedule is a service action to run once or on a weekly schedule
+type Schedule struct {
+	ID        string     `json:"id"`
+	Service   string     `json:"service"`
+	Action    string     `json:"action"`             // start, stop or restart
+	At        *time.Time `json:"at,omitempty"`       // When a one-off action runs
+	Recurring string     `json:"schedule,omitempty"` // e.g. "daily 02:00" or "mon,fri 08:00"
+	Note      string     `json:"note,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	NextRun   time.Time  `json:"next_run"`
+	LastRun   *Run       `json:"last_run,omitempty"` // Recurring schedules only
+}
+
+// Run is the outcome of running a schedule
+type Run struct {
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Request creates a schedule. Exactly one of At and Schedule is set.
+type Request struct {
+	Service  string `json:"service"`
+	Action   string `json:"action"`
+	At       string `json:"at"`       // "+30m", "22:00" or RFC 3339
+	Schedule string `json:"schedule"` // "daily 02:00", "sat,sun 08:00"
+	Note     string `json:"note"`
+}
+
+// ScheduleList contains a list of schedules, soonest first
+type ScheduleList struct {
+	Schedules []Schedule `json:"schedules"`
+	Total     int        `json:"total"`
+}
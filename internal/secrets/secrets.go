@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix marks an encrypted value
+const Prefix = "enc:v1:"
+
+const (
+	// CredentialName is the systemd credential holding the key
+	// (LoadCredential=secrets-key:/path in the unit)
+	CredentialName = "secrets-key"
+
+	machineIDFile = "/etc/machine-id"
+	keyInfo       = "hivedeck-agent secrets v1"
+)
+
+// Box encrypts and decrypts config values with AES-256-GCM
+type Box struct {
+	aead   cipher.AEAD
+	source string
+}
+
+// NewBox derives an encryption key from secret. source describes where the
+// secret came from.
+func NewBox(secret []byte, source string) (*Box, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("empty secret")
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, nil, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead, source: source}, nil
+}
+
+// Open loads the machine secret and returns a Box for it. The secret is read
+// from keyFile if set, then from the systemd credential, then from
+// /etc/machine-id.
+func Open(keyFile string) (*Box, error) {
+	candidates := []string{keyFile}
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, CredentialName))
+	}
+	candidates = append(candidates, machineIDFile)
+
+	for _, path := range candidates {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if path == keyFile {
+				return nil, fmt.Errorf("failed to read secrets key: %w", err)
+			}
+			continue
+		}
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) == 0 {
+			continue
+		}
+		return NewBox(secret, path)
+	}
+
+	return nil, errors.New("no secrets key: set SECRETS_KEY_FILE")
+}
+
+// Source returns the file the key was derived from
+func (b *Box) Source() string {
+	return b.source
+}
+
+// Encrypt returns the encrypted form of value. Encrypted values are
+// returned unchanged.
+func (b *Box) Encrypt(value string) (string, error) {
+	if IsEncrypted(value) {
+		return value, nil
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(value), nil)
+	return Prefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of an encrypted value. Other values are
+// returned unchanged.
+func (b *Box) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	size := b.aead.NonceSize()
+	if len(sealed) < size {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	plain, err := b.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value with the key from %s (was it encrypted on another machine?)", b.source)
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
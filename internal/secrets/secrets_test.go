@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	box, err := NewBox([]byte("machine-secret"), "test")
+	require.NoError(t, err)
+
+	enc, err := box.Encrypt("hunter2")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enc, Prefix))
+	assert.NotContains(t, enc, "hunter2")
+
+	// A fresh nonce each time
+	again, err := box.Encrypt("hunter2")
+	require.NoError(t, err)
+	assert.NotEqual(t, enc, again)
+
+	plain, err := box.Decrypt(enc)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", plain)
+
+	// Encrypting twice is a no-op, and plain values pass through
+	same, err := box.Encrypt(enc)
+	require.NoError(t, err)
+	assert.Equal(t, enc, same)
+	plain, err = box.Decrypt("not-encrypted")
+	require.NoError(t, err)
+	assert.Equal(t, "not-encrypted", plain)
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	box, err := NewBox([]byte("machine-a"), "a")
+	require.NoError(t, err)
+	other, err := NewBox([]byte("machine-b"), "b")
+	require.NoError(t, err)
+
+	enc, err := box.Encrypt("hunter2")
+	require.NoError(t, err)
+	_, err = other.Decrypt(enc)
+	assert.Error(t, err)
+
+	_, err = box.Decrypt(Prefix + "!!!")
+	assert.Error(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("secret\n"), 0600))
+
+	box, err := Open(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, keyFile, box.Source())
+
+	// The key file's trailing newline does not change the key
+	other, err := NewBox([]byte("secret"), "inline")
+	require.NoError(t, err)
+	enc, err := box.Encrypt("x")
+	require.NoError(t, err)
+	plain, err := other.Decrypt(enc)
+	require.NoError(t, err)
+	assert.Equal(t, "x", plain)
+
+	// A systemd credential is used when no key file is set
+	cred := filepath.Join(dir, CredentialName)
+	require.NoError(t, os.WriteFile(cred, []byte("from-systemd"), 0600))
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	box, err = Open("")
+	require.NoError(t, err)
+	assert.Equal(t, cred, box.Source())
+
+	_, err = Open(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
+)
+
+// GetAgentStats handles GET /api/agent/stats: the agent's own resource use
+// and what it keeps on disk
+func (h *Handlers) GetAgentStats(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	resp := gin.H{
+		"version":        h.cfg.Version,
+		"pid":            os.Getpid(),
+		"started_at":     h.startedAt.UTC(),
+		"uptime_seconds": int64(time.Since(h.startedAt).Seconds()),
+		"goroutines":     runtime.NumGoroutine(),
+		"memory": gin.H{
+			"heap_alloc_bytes": mem.HeapAlloc,
+			"heap_sys_bytes":   mem.HeapSys,
+			"sys_bytes":        mem.Sys,
+			"gc_cycles":        mem.NumGC,
+		},
+		"connections":       h.conns.Usage(),
+		"journal_followers": systemd.ActiveFollowers(),
+	}
+
+	storage := gin.H{"enabled": h.store != nil}
+	if h.store != nil {
+		stats, err := h.store.Stats(c.Request.Context())
+		if err != nil {
+			storage["error"] = err.Error()
+		} else {
+			storage["database"] = stats
+		}
+	}
+	if h.cfg.DataDir != "" {
+		storage["data_dir"] = h.cfg.DataDir
+		storage["data_dir_bytes"] = dirSize(h.cfg.DataDir)
+	}
+	resp["storage"] = storage
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// dirSize adds up the files under dir, skipping what cannot be read
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/datadir"
+)
+
+func TestAgentStats(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.DataDir = t.TempDir()
+	srv := New(cfg)
+	require.NotNil(t, srv.handlers.store)
+	t.Cleanup(func() { srv.handlers.store.Close() })
+
+	req := httptest.NewRequest("GET", "/api/agent/stats", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats struct {
+		Goroutines int `json:"goroutines"`
+		Storage    struct {
+			Enabled  bool `json:"enabled"`
+			Database struct {
+				SchemaVersion int              `json:"schema_version"`
+				SizeBytes     int64            `json:"size_bytes"`
+				Tables        map[string]int64 `json:"tables"`
+			} `json:"database"`
+			DataDirBytes int64 `json:"data_dir_bytes"`
+		} `json:"storage"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Positive(t, stats.Goroutines)
+	assert.True(t, stats.Storage.Enabled)
+	assert.Positive(t, stats.Storage.Database.SchemaVersion)
+	assert.Contains(t, stats.Storage.Database.Tables, "kv")
+	assert.GreaterOrEqual(t, stats.Storage.DataDirBytes, stats.Storage.Database.SizeBytes)
+}
+
+func TestStorage(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.DataDir = t.TempDir()
+	srv := New(cfg)
+	db := srv.handlers.store
+	t.Cleanup(func() { db.Close() })
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "/api/agent/storage", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var report datadir.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "database", report.Categories[0].Name)
+	assert.Positive(t, report.Categories[0].Bytes)
+	assert.Equal(t, "24h0m0s", report.PruneInterval)
+
+	w = do("POST", "/api/agent/storage/prune", `{"categories": ["state"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do("POST", "/api/agent/storage/prune", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var result datadir.PruneResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, datadir.TriggerManual, result.Trigger)
+	assert.Contains(t, result.Freed, "database")
+	assert.Contains(t, result.Freed, "history")
+	assert.Empty(t, result.Errors)
+
+	// Without DATA_DIR there is nothing to manage
+	srv = New(config.LoadWithDefaults())
+	w = do("GET", "/api/agent/storage", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/alerts"
+)
+
+// newAlertManager builds the alert manager from ALERT_RULES and ALERT_SINKS.
+// Invalid settings are logged and left out.
+func (h *Handlers) newAlertManager(cfg *config.Config) *alerts.Manager {
+	rules, err := alerts.ParseRules(cfg.AlertRules)
+	if err != nil {
+		log.Printf("Configured alert rules disabled: %v", err)
+	}
+	sinks, err := alerts.ParseSinks(cfg.AlertSinkSpecs())
+	if err != nil {
+		log.Printf("Alert sinks disabled: %v", err)
+	}
+
+	return alerts.NewManager(alerts.Options{
+		Rules:    rules,
+		Sinks:    sinks,
+		Interval: cfg.AlertInterval,
+		DataDir:  cfg.DataDir,
+	}, alerts.Sources{
+		Metrics: h.metricsCollector.GetAllMetrics,
+		ServiceState: func(ctx context.Context, name string) (string, error) {
+			info, err := h.serviceManager.Get(ctx, name)
+			if err != nil {
+				return "", err
+			}
+			return info.ActiveState, nil
+		},
+		ContainerState: func(ctx context.Context, name string) (string, error) {
+			manager := h.dockerManager()
+			if manager == nil {
+				return "", errDockerUnavailable
+			}
+			info, err := manager.GetContainer(ctx, name)
+			if err != nil {
+				return "", err
+			}
+			return info.State, nil
+		},
+	}, h.eventBus)
+}
+
+// ListAlerts handles GET /api/alerts: every rule with its state, and the
+// configured sinks
+func (h *Handlers) ListAlerts(c *gin.Context) {
+	c.JSON(http.StatusOK, h.alerts.List())
+}
+
+// GetAlert handles GET /api/alerts/rules/:id
+func (h *Handlers) GetAlert(c *gin.Context) {
+	status, err := h.alerts.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// CreateAlert handles POST /api/alerts/rules
+func (h *Handlers) CreateAlert(c *gin.Context) {
+	var rule alerts.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	created, err := h.alerts.Add(rule)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateAlert handles PUT /api/alerts/rules/:id, replacing the rule
+func (h *Handlers) UpdateAlert(c *gin.Context) {
+	var rule alerts.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	updated, err := h.alerts.Update(c.Param("id"), rule)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteAlert handles DELETE /api/alerts/rules/:id
+func (h *Handlers) DeleteAlert(c *gin.Context) {
+	if err := h.alerts.Delete(c.Param("id")); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Alert rule deleted"})
+}
+
+// TestAlertSink handles POST /api/alerts/sinks/:name/test
+func (h *Handlers) TestAlertSink(c *gin.Context) {
+	if err := h.alerts.Test(c.Request.Context(), c.Param("name")); err != nil {
+		respondError(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Test notification sent"})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestAlerts(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AlertRules = map[string]string{"high-cpu": "cpu>90@5m"}
+	srv := New(cfg)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/alerts/rules", `{"name": "root-full", "metric": "disk", "target": "/", "operator": ">", "threshold": 95}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		ID       string `json:"id"`
+		Severity string `json:"severity"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "warning", created.Severity)
+
+	w = do("GET", "/api/alerts", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Alerts []struct {
+			Name   string `json:"name"`
+			Source string `json:"source"`
+			State  string `json:"state"`
+		} `json:"alerts"`
+		Total int `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, 2, list.Total)
+	assert.Equal(t, "high-cpu", list.Alerts[0].Name)
+	assert.Equal(t, "config", list.Alerts[0].Source)
+	assert.Equal(t, "ok", list.Alerts[1].State)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/alerts/rules", `{"name": "x", "metric": "cpu"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/alerts/rules", `{"name": "x", "metric": "cpu", "operator": ">", "sinks": ["pager"]}`).Code)
+	assert.Equal(t, http.StatusConflict, do("POST", "/api/alerts/rules", `{"name": "high-cpu", "metric": "load", "operator": ">", "threshold": 4}`).Code)
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/api/alerts/rules/high-cpu", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/api/alerts/sinks/pager/test", "").Code)
+
+	assert.Equal(t, http.StatusOK, do("DELETE", "/api/alerts/rules/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/alerts/rules/"+created.ID, "").Code)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/annotations"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+// ListAnnotations handles GET /api/annotations. from and to are RFC 3339
+// times; tag and limit narrow the list further.
+func (h *Handlers) ListAnnotations(c *gin.Context) {
+	var f annotations.Filter
+	for param, t := range map[string]*time.Time{"from": &f.From, "to": &f.To} {
+		if raw := c.Query(param); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				respondMessage(c, http.StatusBadRequest, param+" must be an RFC 3339 time")
+				return
+			}
+			*t = parsed
+		}
+	}
+	f.Tag = c.Query("tag")
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil {
+			f.Limit = n
+		}
+	}
+
+	c.JSON(http.StatusOK, h.annotations.List(f))
+}
+
+// GetAnnotation handles GET /api/annotations/:id
+func (h *Handlers) GetAnnotation(c *gin.Context) {
+	a, err := h.annotations.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// CreateAnnotation handles POST /api/annotations
+func (h *Handlers) CreateAnnotation(c *gin.Context) {
+	var req struct {
+		Time time.Time `json:"time"`
+		Text string    `json:"text"`
+		Tags []string  `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	a, err := h.annotations.Add(req.Text, req.Time, req.Tags)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.eventBus.Publish(events.Event{
+		Type:    "annotation.created",
+		Source:  a.ID,
+		Message: a.Text,
+		Data:    a,
+	})
+	c.JSON(http.StatusCreated, a)
+}
+
+// UpdateAnnotation handles PUT /api/annotations/:id. Fields left out are
+// unchanged.
+func (h *Handlers) UpdateAnnotation(c *gin.Context) {
+	var req annotations.Update
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	a, err := h.annotations.Update(c.Param("id"), req)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAnnotation handles DELETE /api/annotations/:id
+func (h *Handlers) DeleteAnnotation(c *gin.Context) {
+	if err := h.annotations.Delete(c.Param("id")); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Annotation deleted"})
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"errors"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,10 +17,13 @@ type JWTClaims struct {
 	Role string `json:"role,omitempty"`
 }
 
-// AuthService handles authentication
+// AuthService handles authentication. Its credentials can be replaced at
+// runtime when the API key is saved.
 type AuthService struct {
 	apiKey    string
 	jwtSecret []byte
+	leeway    time.Duration
+	mu        sync.RWMutex
 }
 
 // NewAuthService creates a new auth service
@@ -29,9 +34,54 @@ func NewAuthService(apiKey, jwtSecret string) *AuthService {
 	}
 }
 
+// SetCredentials replaces the API key and JWT secret. Tokens and sessions
+// signed with the old secret stop validating.
+func (a *AuthService) SetCredentials(apiKey, jwtSecret string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.apiKey = apiKey
+	a.jwtSecret = []byte(jwtSecret)
+}
+
+// SetLeeway sets the clock skew tolerated when checking token times
+func (a *AuthService) SetLeeway(leeway time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.leeway = leeway
+}
+
+// parserOptions checks iat as well as exp and nbf, each with the leeway
+func (a *AuthService) parserOptions(opts ...jwt.ParserOption) []jwt.ParserOption {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append(opts, jwt.WithIssuedAt(), jwt.WithLeeway(a.leeway))
+}
+
+// Configured reports whether an API key is set, i.e. setup is complete
+func (a *AuthService) Configured() bool {
+	return a.key() != ""
+}
+
+func (a *AuthService) key() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.apiKey
+}
+
+// secret returns the JWT signing key. Without one nothing is signed or
+// accepted, so tokens cannot be forged with an empty key during setup.
+func (a *AuthService) secret() ([]byte, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if len(a.jwtSecret) == 0 {
+		return nil, errors.New("authentication is not configured")
+	}
+	return a.jwtSecret, nil
+}
+
 // ValidateAPIKey validates an API key
 func (a *AuthService) ValidateAPIKey(key string) bool {
-	return key != "" && key == a.apiKey
+	return key != "" && key == a.key()
 }
 
 // GenerateToken generates a new JWT token
@@ -45,21 +95,27 @@ func (a *AuthService) GenerateToken(role string, duration time.Duration) (string
 		Role: role,
 	}
 
+	secret, err := a.secret()
+	if err != nil {
+		return "", err
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(a.jwtSecret)
+	return token.SignedString(secret)
 }
 
-// ValidateToken validates a JWT token
+// ValidateToken validates a JWT token. A correctly signed token rejected
+// for its times returns a *ClockSkewError.
 func (a *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return a.jwtSecret, nil
-	})
+		return a.secret()
+	}, a.parserOptions()...)
 
 	if err != nil {
-		return nil, err
+		return nil, a.timeError(token, err)
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
@@ -69,6 +125,82 @@ func (a *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// ClockSkewError is a correctly signed token rejected because it has
+// expired or is not valid yet. Right after boot, this usually means the
+// agent's clock or the issuer's clock is wrong, e.g. on a Raspberry Pi
+// without a real-time clock.
+type ClockSkewError struct {
+	Err        error
+	ServerTime time.Time
+	IssuedAt   *time.Time
+	ExpiresAt  *time.Time
+	NotBefore  *time.Time
+	Leeway     time.Duration
+}
+
+func (e *ClockSkewError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClockSkewError) Unwrap() error {
+	return e.Err
+}
+
+// Skew estimates how far the client's clock is ahead of the agent's, from
+// the request's Date header or else from an issue time in the future
+func (e *ClockSkewError) Skew(clientDate string) (time.Duration, bool) {
+	if t, err := http.ParseTime(clientDate); err == nil {
+		return t.Sub(e.ServerTime), true
+	}
+	if e.IssuedAt != nil && e.IssuedAt.After(e.ServerTime) {
+		return e.IssuedAt.Sub(e.ServerTime), true
+	}
+	return 0, false
+}
+
+// Details describes the times involved for the error response
+func (e *ClockSkewError) Details(clientDate string) map[string]interface{} {
+	details := map[string]interface{}{
+		"reason":         e.Err.Error(),
+		"server_time":    e.ServerTime.UTC(),
+		"leeway_seconds": int64(e.Leeway.Seconds()),
+	}
+	for key, t := range map[string]*time.Time{"issued_at": e.IssuedAt, "expires_at": e.ExpiresAt, "not_before": e.NotBefore} {
+		if t != nil {
+			details[key] = t.UTC()
+		}
+	}
+	if skew, ok := e.Skew(clientDate); ok {
+		details["clock_skew_seconds"] = int64(skew.Seconds())
+	}
+	return details
+}
+
+// timeError wraps a time validation failure of a verified token in a
+// ClockSkewError. jwt only validates claims once the signature checks out,
+// so their times can be trusted.
+func (a *AuthService) timeError(token *jwt.Token, err error) error {
+	if token == nil || !(errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenUsedBeforeIssued) || errors.Is(err, jwt.ErrTokenNotValidYet)) {
+		return err
+	}
+
+	a.mu.RLock()
+	leeway := a.leeway
+	a.mu.RUnlock()
+
+	skewErr := &ClockSkewError{Err: err, ServerTime: time.Now(), Leeway: leeway}
+	if iat, _ := token.Claims.GetIssuedAt(); iat != nil {
+		skewErr.IssuedAt = &iat.Time
+	}
+	if exp, _ := token.Claims.GetExpirationTime(); exp != nil {
+		skewErr.ExpiresAt = &exp.Time
+	}
+	if nbf, _ := token.Claims.GetNotBefore(); nbf != nil {
+		skewErr.NotBefore = &nbf.Time
+	}
+	return skewErr
+}
+
 // ExtractToken extracts the token from the Authorization header
 func ExtractToken(c *gin.Context) string {
 	// Check Authorization header
@@ -1,11 +1,14 @@
 package server
 
 import (
+	"errors"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,6 +45,52 @@ func TestAuthService_ExpiredToken(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// signToken signs claims issued at iat, as a client with its own clock would
+func signToken(t *testing.T, secret string, iat time.Time, ttl time.Duration) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(iat),
+		ExpiresAt: jwt.NewNumericDate(iat.Add(ttl)),
+	}).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthService_Leeway(t *testing.T) {
+	auth := NewAuthService("api-key", "jwt-secret")
+	auth.SetLeeway(time.Minute)
+	now := time.Now()
+
+	// A client 30 seconds ahead is within the leeway
+	_, err := auth.ValidateToken(signToken(t, "jwt-secret", now.Add(30*time.Second), time.Hour))
+	assert.NoError(t, err)
+
+	// One 10 minutes ahead is not, and the error says by how much
+	_, err = auth.ValidateToken(signToken(t, "jwt-secret", now.Add(10*time.Minute), time.Hour))
+	var skew *ClockSkewError
+	require.ErrorAs(t, err, &skew)
+	assert.ErrorIs(t, err, jwt.ErrTokenUsedBeforeIssued)
+	d, ok := skew.Skew("")
+	require.True(t, ok)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), d.Seconds(), 2)
+
+	// A token expired less than the leeway ago still works
+	_, err = auth.ValidateToken(signToken(t, "jwt-secret", now.Add(-time.Hour-30*time.Second), time.Hour))
+	assert.NoError(t, err)
+	_, err = auth.ValidateToken(signToken(t, "jwt-secret", now.Add(-2*time.Hour), time.Hour))
+	require.ErrorAs(t, err, &skew)
+	_, ok = skew.Skew("")
+	assert.False(t, ok, "an expired token alone does not show the skew")
+	d, ok = skew.Skew(now.Add(-2 * time.Hour).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+	assert.InDelta(t, (-2 * time.Hour).Seconds(), d.Seconds(), 2)
+
+	// Times of a token with a bad signature are not reported
+	_, err = auth.ValidateToken(signToken(t, "other-secret", now.Add(-2*time.Hour), time.Hour))
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &skew))
+}
+
 func TestAuthService_InvalidToken(t *testing.T) {
 	auth := NewAuthService("api-key", "jwt-secret")
 
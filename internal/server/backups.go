@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListBackups handles GET /api/backups. Results are cached for
+// BACKUP_CACHE_MINUTES; refresh=true checks the repositories again.
+func (h *Handlers) ListBackups(c *gin.Context) {
+	repos := h.backups.Status(c.Request.Context(), c.Query("refresh") == "true")
+	c.JSON(http.StatusOK, gin.H{
+		"repositories": repos,
+		"total":        len(repos),
+	})
+}
+
+// GetBackupSnapshots handles GET /api/backups/:name/snapshots
+func (h *Handlers) GetBackupSnapshots(c *gin.Context) {
+	limit := 0
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil {
+			limit = n
+		}
+	}
+
+	list, err := h.backups.Snapshots(c.Request.Context(), c.Param("name"), limit, c.Query("refresh") == "true")
+	if err != nil {
+		respondError(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestBackups_Unconfigured(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/api/backups")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"repositories":[],"total":0}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, do("/api/backups/home/snapshots").Code)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// MaxBatchRequests is the maximum number of calls in a single batch
+const MaxBatchRequests = 25
+
+// BatchRequest is a single API call within a batch
+type BatchRequest struct {
+	ID     string          `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse is the result of a single API call within a batch
+type BatchResponse struct {
+	ID     string          `json:"id,omitempty"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// batchRecorder captures a sub-request response in memory
+type batchRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (r *batchRecorder) Header() http.Header         { return r.header }
+func (r *batchRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *batchRecorder) WriteHeader(status int)      { r.status = status }
+
+// isStreamingPath reports whether a path serves a long-lived stream that
+// cannot be answered inside a batch
+func isStreamingPath(path string) bool {
+	return path == "/api/events" || path == "/api/logs" || path == "/api/ws" || strings.HasSuffix(path, "/stream")
+}
+
+// HandleBatch handles POST /api/batch
+func (s *Server) HandleBatch(c *gin.Context) {
+	var reqs []BatchRequest
+	if err := c.ShouldBindJSON(&reqs); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: expected a JSON array of calls")
+		return
+	}
+
+	if len(reqs) == 0 {
+		respondMessage(c, http.StatusBadRequest, "batch is empty")
+		return
+	}
+	if len(reqs) > MaxBatchRequests {
+		respondMessage(c, http.StatusBadRequest, fmt.Sprintf("batch exceeds %d calls", MaxBatchRequests))
+		return
+	}
+
+	responses := make([]BatchResponse, len(reqs))
+	var wg sync.WaitGroup
+
+	for i, req := range reqs {
+		wg.Add(1)
+		go func(i int, req BatchRequest) {
+			defer wg.Done()
+			responses[i] = s.dispatchBatchCall(c.Request, req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"responses": responses,
+		"total":     len(responses),
+	})
+}
+
+func (s *Server) dispatchBatchCall(parent *http.Request, req BatchRequest) BatchResponse {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	path := req.Path
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+
+	if !strings.HasPrefix(path, "/api/") {
+		return batchError(req.ID, http.StatusBadRequest, "path must start with /api/")
+	}
+	if path == "/api/batch" {
+		return batchError(req.ID, http.StatusBadRequest, "nested batches are not allowed")
+	}
+	if isStreamingPath(path) {
+		return batchError(req.ID, http.StatusBadRequest, "streaming endpoints cannot be batched")
+	}
+
+	sub, err := http.NewRequestWithContext(parent.Context(), method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return batchError(req.ID, http.StatusBadRequest, "invalid request: "+err.Error())
+	}
+
+	// Sub-requests authenticate with the same credentials as the batch
+	sub.Header.Set("Authorization", parent.Header.Get("Authorization"))
+	if len(req.Body) > 0 {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	sub.RemoteAddr = parent.RemoteAddr
+	if sub.URL.Query().Get("token") == "" {
+		if token := parent.URL.Query().Get("token"); token != "" {
+			q := sub.URL.Query()
+			q.Set("token", token)
+			sub.URL.RawQuery = q.Encode()
+		}
+	}
+
+	rec := newBatchRecorder()
+	s.router.ServeHTTP(rec, sub)
+
+	body := bytes.TrimSpace(rec.body.Bytes())
+	if len(body) > 0 && !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+
+	return BatchResponse{
+		ID:     req.ID,
+		Status: rec.status,
+		Body:   body,
+	}
+}
+
+func batchError(id string, status int, message string) BatchResponse {
+	body, _ := json.Marshal(apierror.New(status, message, nil))
+	return BatchResponse{
+		ID:     id,
+		Status: status,
+		Body:   body,
+	}
+}
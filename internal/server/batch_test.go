@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestHandleBatch(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	body := `[
+		{"id": "cache", "method": "GET", "path": "/api/agent/cache"},
+		{"id": "missing", "method": "GET", "path": "/api/does-not-exist"},
+		{"id": "stream", "method": "GET", "path": "/api/events"},
+		{"id": "nested", "method": "POST", "path": "/api/batch"}
+	]`
+
+	req := httptest.NewRequest("POST", "/api/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Responses []BatchResponse `json:"responses"`
+		Total     int             `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 4, resp.Total)
+
+	assert.Equal(t, "cache", resp.Responses[0].ID)
+	assert.Equal(t, http.StatusOK, resp.Responses[0].Status)
+	assert.Equal(t, http.StatusNotFound, resp.Responses[1].Status)
+	assert.Equal(t, http.StatusBadRequest, resp.Responses[2].Status)
+	assert.Equal(t, http.StatusBadRequest, resp.Responses[3].Status)
+}
+
+func TestHandleBatch_RequiresAuth(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	req := httptest.NewRequest("POST", "/api/batch", strings.NewReader(`[{"path": "/api/agent/cache"}]`))
+	w := httptest.NewRecorder()
+
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
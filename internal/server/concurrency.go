@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// connRetryAfter is the Retry-After sent with a 503 for a full limiter
+const connRetryAfter = "5"
+
+// connSlotKey marks a request context that holds a slot, so batch calls
+// dispatched within it do not take more
+type connSlotKey struct{}
+
+// connLimiter caps concurrent requests and streams. Streams are SSE and
+// WebSocket connections and WebSocket subscriptions, which are long-lived
+// and may each run a follower such as journalctl, so they are counted
+// apart from ordinary requests. A limit of 0 disables it.
+type connLimiter struct {
+	maxRequests int
+	maxStreams  int
+
+	mu       sync.Mutex
+	requests int
+	streams  int
+}
+
+// connUsage is what the limiter has handed out
+type connUsage struct {
+	Requests    int `json:"requests"`
+	MaxRequests int `json:"max_requests"`
+	Streams     int `json:"streams"`
+	MaxStreams  int `json:"max_streams"`
+}
+
+func newConnLimiter(maxRequests, maxStreams int) *connLimiter {
+	return &connLimiter{maxRequests: maxRequests, maxStreams: maxStreams}
+}
+
+// acquire takes a request or stream slot, returning a function that gives
+// it back. It fails at once rather than queueing when none is free.
+func (l *connLimiter) acquire(stream bool) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count, limit, kind := &l.requests, l.maxRequests, "requests"
+	if stream {
+		count, limit, kind = &l.streams, l.maxStreams, "streams"
+	}
+	if limit > 0 && *count >= limit {
+		return nil, apierror.Unavailable("too many concurrent %s: all %d are in use; close some or retry later", kind, limit).
+			WithDetail("kind", kind).
+			WithDetail("limit", limit)
+	}
+	*count++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			*count--
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// Usage returns the slots in use and the limits
+func (l *connLimiter) Usage() connUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return connUsage{Requests: l.requests, MaxRequests: l.maxRequests, Streams: l.streams, MaxStreams: l.maxStreams}
+}
+
+// ConcurrencyMiddleware holds a request or stream slot for each request,
+// answering 503 with Retry-After when none is free
+func ConcurrencyMiddleware(limiter *connLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Context().Value(connSlotKey{}) != nil {
+			c.Next()
+			return
+		}
+
+		stream := isStreamingPath(c.Request.URL.Path) || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+		release, err := limiter.acquire(stream)
+		if err != nil {
+			c.Header("Retry-After", connRetryAfter)
+			c.AbortWithStatusJSON(apierror.From(err, http.StatusServiceUnavailable))
+			return
+		}
+		defer release()
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), connSlotKey{}, true))
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestRunTask_RequiresConfirmationToken(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AllowedTasks["danger"] = config.Task{Name: "danger", Command: "true", Description: "Test", Dangerous: true}
+	srv := New(cfg)
+
+	run := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/tasks/danger/run", nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		if token != "" {
+			req.Header.Set("X-Confirmation-Token", token)
+		}
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	// The old guessable flag no longer executes anything
+	req := httptest.NewRequest("POST", "/api/tasks/danger/run?confirm=true", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = run("")
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	var challenge struct {
+		Code    string `json:"code"`
+		Details struct {
+			Confirmation struct {
+				Token  string `json:"token"`
+				Impact string `json:"impact"`
+			} `json:"confirmation"`
+		} `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	assert.Equal(t, "confirmation_required", challenge.Code)
+	assert.Contains(t, challenge.Details.Confirmation.Impact, "Runs `true`")
+
+	token := challenge.Details.Confirmation.Token
+	assert.Equal(t, http.StatusOK, run(token).Code)
+	assert.Equal(t, http.StatusForbidden, run(token).Code)
+}
+
+func TestRunTask_QueuesForApproval(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.ApprovalsEnabled = true
+	cfg.AllowedTasks["danger"] = config.Task{Name: "danger", Command: "true", Description: "Test", Dangerous: true}
+	srv := New(cfg)
+
+	jwt, err := srv.auth.GenerateToken("admin", time.Hour)
+	require.NoError(t, err)
+
+	do := func(method, path, token, userAgent string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/tasks/danger/run", "test-api-key", "laptop")
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var queued struct {
+		Approval struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"approval"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.Equal(t, "pending", queued.Approval.Status)
+
+	// The requesting credential cannot approve its own request, from any device
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/approvals/"+queued.Approval.ID+"/approve", "test-api-key", "laptop").Code)
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/approvals/"+queued.Approval.ID+"/approve", "test-api-key", "phone").Code)
+
+	w = do("POST", "/api/approvals/"+queued.Approval.ID+"/approve", jwt, "laptop")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"approved"`)
+
+	assert.Equal(t, http.StatusConflict, do("POST", "/api/approvals/"+queued.Approval.ID+"/approve", jwt, "phone").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/approvals/missing", jwt, "phone").Code)
+}
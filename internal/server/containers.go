@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+)
+
+// CreateContainer handles POST /api/docker/containers. It needs
+// DOCKER_CREATE_ENABLED. The spec is validated before the response; the
+// image is pulled when missing, so it runs as an operation.
+func (h *Handlers) CreateContainer(c *gin.Context) {
+	if !h.cfg.DockerCreateEnabled {
+		respondError(c, http.StatusForbidden, docker.ErrCreateDisabled)
+		return
+	}
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	var spec docker.ContainerSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if err := spec.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := spec.CheckHostAccess(h.fileBrowser.IsPathAllowed); err != nil {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+
+	target := spec.Name
+	if target == "" {
+		target = spec.Image
+	}
+	h.recordAudit(c, "container.create", target, true, spec.Image)
+	h.runOperation(c, "container.create", target, imagePullTimeout, func(ctx context.Context) (interface{}, error) {
+		result, err := manager.CreateContainer(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		h.publishAction("container.create", "docker", true, "Created container "+result.Name+" from "+result.Image, result)
+		return result, nil
+	})
+}
+
+// PauseContainer handles POST /api/docker/containers/:id/pause
+func (h *Handlers) PauseContainer(c *gin.Context) {
+	h.containerAction(c, "pause", (*docker.Manager).PauseContainer)
+}
+
+// UnpauseContainer handles POST /api/docker/containers/:id/unpause
+func (h *Handlers) UnpauseContainer(c *gin.Context) {
+	h.containerAction(c, "unpause", (*docker.Manager).UnpauseContainer)
+}
+
+// RenameContainer handles POST /api/docker/containers/:id/rename
+func (h *Handlers) RenameContainer(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	h.containerAction(c, "rename", func(m *docker.Manager, ctx context.Context, id string) (*docker.ContainerAction, error) {
+		return m.RenameContainer(ctx, id, req.Name)
+	})
+}
+
+// UpdateContainerResources handles POST /api/docker/containers/:id/update
+// and /resources. It changes CPU and memory limits and the restart policy in
+// place, as docker update does.
+func (h *Handlers) UpdateContainerResources(c *gin.Context) {
+	var req docker.ResourceUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	h.containerAction(c, "resources", func(m *docker.Manager, ctx context.Context, id string) (*docker.ContainerAction, error) {
+		return m.UpdateResources(ctx, id, req)
+	})
+}
+
+// RemoveContainer handles DELETE /api/docker/containers/:id. ?force=true
+// removes a running container. ?volumes=true also removes its anonymous
+// volumes, which deletes data, so it needs confirmation or approval.
+func (h *Handlers) RemoveContainer(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	id := c.Param("id")
+	opts := docker.RemoveOptions{Force: c.Query("force") == "true", Volumes: c.Query("volumes") == "true"}
+	run := h.containerJob(manager, "remove", id, func(m *docker.Manager, ctx context.Context, id string) (*docker.ContainerAction, error) {
+		return m.RemoveContainer(ctx, id, opts)
+	})
+	if opts.Volumes {
+		impact := fmt.Sprintf("Removes container %s on %s with its anonymous volumes and their data.", id, hostname())
+		if !h.guardDestructive(c, "container.remove", id, impact, containerActionTimeout, run) {
+			return
+		}
+	}
+	h.recordAudit(c, "container.remove", id, true, "")
+	h.runOperation(c, "container.remove", id, containerActionTimeout, run)
+}
+
+// UpdateContainerImage handles POST /api/docker/containers/:id/update-image.
+// It pulls a newer image for the container's tag and recreates the
+// container from it, rolling back if the new one does not become healthy.
+func (h *Handlers) UpdateContainerImage(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	id := c.Param("id")
+	h.recordAudit(c, "container.update", id, true, "")
+	h.runOperation(c, "container.update", id, imagePullTimeout+h.cfg.DockerHealthTimeout, func(ctx context.Context) (interface{}, error) {
+		result, err := manager.UpdateContainer(ctx, id, h.cfg.DockerHealthTimeout)
+		if err != nil {
+			return nil, err
+		}
+		h.publishAutoUpdate(*result)
+		if result.Status == docker.AutoFailed || result.Status == docker.AutoRolledBack {
+			return result, actionError(ctx, result.Error)
+		}
+		return result, nil
+	})
+}
+
+// GetContainerTop handles GET /api/docker/containers/:id/top
+func (h *Handlers) GetContainerTop(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	top, err := manager.ContainerTop(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	for _, p := range top.Processes {
+		for _, column := range []string{"CMD", "COMMAND"} {
+			if cmd, ok := p[column]; ok {
+				p[column] = h.redactor.Cmdline(cmd)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, top)
+}
+
+// GetContainerDiff handles GET /api/docker/containers/:id/diff
+func (h *Handlers) GetContainerDiff(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	diff, err := manager.ContainerDiff(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
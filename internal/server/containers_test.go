@@ -0,0 +1,37 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestUpdateContainer_OnlyChangesResources(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	do := func(path, body string) int {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// /update is a resource change and never a pull and recreate, so a
+	// missing or malformed body is rejected
+	for _, path := range []string{"/api/docker/containers/web/update", "/api/docker/containers/web/resources"} {
+		assert.Equal(t, http.StatusBadRequest, do(path, `{"cpus": "half"}`), path)
+		assert.Equal(t, http.StatusBadRequest, do(path, `{"memory": `), path)
+		assert.Equal(t, http.StatusBadRequest, do(path, ""), path)
+	}
+
+	// The image update has its own route and ignores the body
+	assert.NotEqual(t, http.StatusBadRequest, do("/api/docker/containers/web/update-image", ""))
+	assert.NotEqual(t, http.StatusNotFound, do("/api/docker/containers/web/update-image", `{}`))
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/coredumps"
+)
+
+// ListCoredumps handles GET /api/system/coredumps. since is passed to
+// coredumpctl, e.g. "yesterday"; limit defaults to 50.
+func (h *Handlers) ListCoredumps(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		respondMessage(c, http.StatusBadRequest, "limit must be a positive number")
+		return
+	}
+
+	list, err := coredumps.List(c.Request.Context(), c.Query("since"), limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetCoredump handles GET /api/system/coredumps/:pid with the metadata and
+// stack trace of the latest crash of that PID
+func (h *Handlers) GetCoredump(c *gin.Context) {
+	pid, err := strconv.Atoi(c.Param("pid"))
+	if err != nil || pid < 1 {
+		respondMessage(c, http.StatusBadRequest, "invalid pid")
+		return
+	}
+
+	info, err := coredumps.Get(c.Request.Context(), pid)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
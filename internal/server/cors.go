@@ -0,0 +1,172 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+// CORSMiddleware handles CORS headers. allowedOrigins applies to every path
+// except those under a route, which use the route's origins instead.
+//
+// An origin listed explicitly, or matching a *.example.com pattern, is
+// echoed back with Access-Control-Allow-Credentials so the browser may send
+// cookies. A * entry lets any other origin in without credentials, as
+// browsers require. Browsers cache a preflight for maxAge, or their own
+// default when it is 0.
+func CORSMiddleware(allowedOrigins []string, maxAge time.Duration, routes ...config.CORSRoute) gin.HandlerFunc {
+	rules := newCORSRules(allowedOrigins, routes)
+	allowHeaders := "Origin, Content-Type, Authorization, X-Confirmation-Token, Prefer, " + CSRFHeader
+
+	return func(c *gin.Context) {
+		policy := rules.policy(c.Request.URL.Path)
+		header := c.Writer.Header()
+
+		allowOrigin, credentials := policy.allow(c.GetHeader("Origin"))
+		if !policy.anyOnly() {
+			header.Add("Vary", "Origin")
+		}
+		if allowOrigin != "" {
+			header.Set("Access-Control-Allow-Origin", allowOrigin)
+			if credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			header.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+			header.Set("Access-Control-Expose-Headers", "Location")
+			if maxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			}
+		}
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// corsRules picks the origin policy for a path
+type corsRules struct {
+	fallback *originPolicy
+	routes   []corsRoute // Longest prefix first
+}
+
+type corsRoute struct {
+	prefix string
+	policy *originPolicy
+}
+
+func newCORSRules(allowedOrigins []string, routes []config.CORSRoute) *corsRules {
+	rules := &corsRules{fallback: newOriginPolicy(allowedOrigins)}
+	for _, r := range routes {
+		rules.routes = append(rules.routes, corsRoute{prefix: r.Prefix, policy: newOriginPolicy(r.Origins)})
+	}
+	sort.SliceStable(rules.routes, func(i, j int) bool {
+		return len(rules.routes[i].prefix) > len(rules.routes[j].prefix)
+	})
+	return rules
+}
+
+func (r *corsRules) policy(p string) *originPolicy {
+	clean := path.Clean("/" + p)
+	for _, route := range r.routes {
+		if pathUnder(clean, route.prefix) {
+			return route.policy
+		}
+	}
+	return r.fallback
+}
+
+// originPolicy is the set of origins allowed on some paths
+type originPolicy struct {
+	any      bool // * was listed
+	origins  []string
+	patterns []originPattern
+}
+
+// originPattern matches subdomains: [scheme://]*.example.com[:port]. Without
+// a scheme any scheme matches; without a port the origin must have none.
+type originPattern struct {
+	scheme string
+	suffix string // .example.com
+	port   string
+}
+
+func newOriginPolicy(origins []string) *originPolicy {
+	p := &originPolicy{}
+	for _, o := range origins {
+		switch {
+		case o == "*":
+			p.any = true
+		case strings.Contains(o, "*."):
+			scheme, host, found := strings.Cut(o, "://")
+			if !found {
+				scheme, host = "", o
+			}
+			host, port, _ := strings.Cut(host, ":")
+			p.patterns = append(p.patterns, originPattern{
+				scheme: strings.ToLower(scheme),
+				suffix: strings.ToLower(strings.TrimPrefix(host, "*")),
+				port:   port,
+			})
+		default:
+			p.origins = append(p.origins, strings.TrimSuffix(o, "/"))
+		}
+	}
+	return p
+}
+
+// anyOnly reports whether the policy answers the same for every origin
+func (p *originPolicy) anyOnly() bool {
+	return p.any && len(p.origins) == 0 && len(p.patterns) == 0
+}
+
+// allow returns the Access-Control-Allow-Origin value for origin, if any,
+// and whether credentials are allowed
+func (p *originPolicy) allow(origin string) (string, bool) {
+	if origin != "" && p.matches(origin) {
+		return origin, true
+	}
+	if p.any {
+		return "*", false
+	}
+	return "", false
+}
+
+// matches reports whether origin is listed or matches a pattern; * does
+// not count
+func (p *originPolicy) matches(origin string) bool {
+	for _, o := range p.origins {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	if len(p.patterns) == 0 {
+		return false
+	}
+
+	scheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok {
+		return false
+	}
+	host, port, _ := strings.Cut(host, ":")
+	for _, pattern := range p.patterns {
+		if pattern.scheme != "" && pattern.scheme != scheme {
+			continue
+		}
+		if pattern.port == port && strings.HasSuffix(host, pattern.suffix) && len(host) > len(pattern.suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,520 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// DashboardPage serves the web dashboard to a signed-in session, and the
+// sign-in page otherwise. The dashboard uses the regular API with the
+// session cookie.
+func (h *SetupHandlers) DashboardPage(c *gin.Context) {
+	claims, ok := sessionFromCookie(c, h.auth)
+	if !ok {
+		h.servePage(c, loginPageHTML, "")
+		return
+	}
+	h.servePage(c, dashboardPageHTML, claims.CSRF)
+}
+
+const dashboardPageHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Hivedeck Agent</title>
+    <style>
+        * { box-sizing: border-box; margin: 0; padding: 0; }
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            background: #f3f4f6;
+            color: #1f2937;
+            min-height: 100vh;
+            padding: 20px;
+        }
+        .container { max-width: 1100px; margin: 0 auto; }
+        .header { display: flex; align-items: center; gap: 12px; margin-bottom: 20px; }
+        .header svg { width: 36px; height: 36px; color: #3b82f6; }
+        h1 { font-size: 24px; }
+        .host { color: #6b7280; font-size: 14px; }
+        .header .actions { margin-left: auto; display: flex; gap: 8px; }
+        .tabs { display: flex; gap: 4px; margin-bottom: 20px; border-bottom: 1px solid #e5e7eb; }
+        .tab {
+            padding: 10px 16px;
+            border: none;
+            background: none;
+            font-size: 14px;
+            font-weight: 500;
+            color: #6b7280;
+            cursor: pointer;
+            border-bottom: 2px solid transparent;
+        }
+        .tab.active { color: #3b82f6; border-bottom-color: #3b82f6; }
+        .card {
+            background: white;
+            border-radius: 12px;
+            box-shadow: 0 1px 3px rgba(0, 0, 0, 0.1);
+            padding: 20px;
+            margin-bottom: 20px;
+        }
+        .card h2 { font-size: 16px; margin-bottom: 12px; }
+        .grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(240px, 1fr)); gap: 20px; }
+        .stat { font-size: 28px; font-weight: 600; }
+        .stat-label { color: #6b7280; font-size: 13px; margin-bottom: 4px; }
+        canvas { width: 100%; height: 60px; margin-top: 8px; }
+        table { width: 100%; border-collapse: collapse; font-size: 14px; }
+        th { text-align: left; color: #6b7280; font-weight: 500; padding: 8px; border-bottom: 1px solid #e5e7eb; }
+        td { padding: 8px; border-bottom: 1px solid #f3f4f6; vertical-align: middle; }
+        td.actions { white-space: nowrap; text-align: right; }
+        .badge { display: inline-block; padding: 2px 8px; border-radius: 9999px; font-size: 12px; background: #e5e7eb; }
+        .badge.ok { background: #d1fae5; color: #065f46; }
+        .badge.bad { background: #fee2e2; color: #991b1b; }
+        button {
+            padding: 6px 12px;
+            border: none;
+            border-radius: 6px;
+            font-size: 13px;
+            font-weight: 500;
+            cursor: pointer;
+            background: #e5e7eb;
+            color: #374151;
+        }
+        button:hover { background: #d1d5db; }
+        button.primary { background: #3b82f6; color: white; }
+        button.primary:hover { background: #2563eb; }
+        a.button { text-decoration: none; padding: 6px 12px; border-radius: 6px; font-size: 13px; font-weight: 500; background: #e5e7eb; color: #374151; }
+        input, select { padding: 6px 10px; border: 1px solid #d1d5db; border-radius: 6px; font-size: 14px; }
+        .toolbar { display: flex; gap: 8px; margin-bottom: 12px; align-items: center; }
+        .toolbar input[type="text"] { flex: 1; }
+        pre.logs {
+            background: #111827;
+            color: #e5e7eb;
+            font-family: 'Monaco', 'Menlo', monospace;
+            font-size: 12px;
+            padding: 12px;
+            border-radius: 6px;
+            height: 480px;
+            overflow: auto;
+            white-space: pre-wrap;
+        }
+        .muted { color: #6b7280; font-size: 14px; }
+        .alert { padding: 12px 16px; border-radius: 6px; margin-bottom: 16px; font-size: 14px; }
+        .alert-error { background: #fee2e2; color: #991b1b; }
+        .alert-success { background: #d1fae5; color: #065f46; }
+        .hidden { display: none; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <svg xmlns="http://www.w3.org/2000/svg" fill="none" viewBox="0 0 24 24" stroke="currentColor">
+                <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M5 12h14M5 12a2 2 0 01-2-2V6a2 2 0 012-2h14a2 2 0 012 2v4a2 2 0 01-2 2M5 12a2 2 0 00-2 2v4a2 2 0 002 2h14a2 2 0 002-2v-4a2 2 0 00-2-2m-2-4h.01M17 16h.01" />
+            </svg>
+            <div>
+                <h1 id="hostname">Hivedeck Agent</h1>
+                <div class="host" id="hostInfo"></div>
+            </div>
+            <div class="actions">
+                <a class="button" href="/settings">Settings</a>
+                <button onclick="logout()">Sign Out</button>
+            </div>
+        </div>
+
+        <div id="alert" class="alert hidden"></div>
+
+        <div class="tabs">
+            <button class="tab active" data-tab="overview">Overview</button>
+            <button class="tab" data-tab="services">Services</button>
+            <button class="tab" data-tab="containers">Containers</button>
+            <button class="tab" data-tab="logs">Logs</button>
+        </div>
+
+        <div id="tab-overview">
+            <div class="grid">
+                <div class="card">
+                    <div class="stat-label">CPU</div>
+                    <div class="stat" id="cpuValue">-</div>
+                    <canvas id="cpuChart"></canvas>
+                </div>
+                <div class="card">
+                    <div class="stat-label">Memory</div>
+                    <div class="stat" id="memValue">-</div>
+                    <canvas id="memChart"></canvas>
+                </div>
+                <div class="card">
+                    <div class="stat-label">Network (rx / tx)</div>
+                    <div class="stat" id="netValue">-</div>
+                    <canvas id="netChart"></canvas>
+                </div>
+            </div>
+            <div class="card">
+                <h2>Disks</h2>
+                <table>
+                    <thead><tr><th>Mount</th><th>Used</th><th>Size</th><th>Usage</th></tr></thead>
+                    <tbody id="disks"><tr><td colspan="4" class="muted">Waiting for metrics...</td></tr></tbody>
+                </table>
+            </div>
+        </div>
+
+        <div id="tab-services" class="hidden">
+            <div class="card">
+                <div class="toolbar">
+                    <input type="text" id="serviceFilter" placeholder="Filter services">
+                    <button onclick="loadServices()">Refresh</button>
+                </div>
+                <table>
+                    <thead><tr><th>Name</th><th>State</th><th>Description</th><th></th></tr></thead>
+                    <tbody id="services"></tbody>
+                </table>
+            </div>
+        </div>
+
+        <div id="tab-containers" class="hidden">
+            <div class="card">
+                <div class="toolbar">
+                    <span class="muted" style="flex: 1">Docker containers</span>
+                    <button onclick="loadContainers()">Refresh</button>
+                </div>
+                <table>
+                    <thead><tr><th>Name</th><th>Image</th><th>State</th><th>Status</th><th></th></tr></thead>
+                    <tbody id="containers"></tbody>
+                </table>
+            </div>
+        </div>
+
+        <div id="tab-logs" class="hidden">
+            <div class="card">
+                <div class="toolbar">
+                    <input type="text" id="logUnit" list="unitList" placeholder="Unit, e.g. nginx.service">
+                    <datalist id="unitList"></datalist>
+                    <select id="logLines">
+                        <option value="100">100 lines</option>
+                        <option value="500">500 lines</option>
+                        <option value="1000">1000 lines</option>
+                    </select>
+                    <button class="primary" onclick="loadLogs()">Show</button>
+                    <button id="followButton" onclick="toggleFollow()">Follow</button>
+                </div>
+                <pre class="logs" id="logs"></pre>
+            </div>
+        </div>
+    </div>
+
+    <script>
+        const CSRF_TOKEN = '{{csrf_token}}';
+        const HISTORY = 60;
+        const samples = { cpu: [], mem: [], rx: [], tx: [] };
+        let lastNet = null;
+        let metricsStream = null;
+        let logStream = null;
+        let serviceList = [];
+
+        function showAlert(message, type) {
+            const alertDiv = document.getElementById('alert');
+            alertDiv.textContent = message;
+            alertDiv.className = 'alert alert-' + type;
+            setTimeout(() => alertDiv.className = 'alert hidden', 5000);
+        }
+
+        function escapeHTML(s) {
+            return String(s == null ? '' : s).replace(/[&<>"']/g, c => ({
+                '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+            })[c]);
+        }
+
+        function formatBytes(n) {
+            const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+            let i = 0;
+            while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+            return n.toFixed(i ? 1 : 0) + ' ' + units[i];
+        }
+
+        // api calls the agent API with the session cookie. State changes carry
+        // the CSRF token; a 428 confirmation challenge is confirmed and retried.
+        async function api(path, options = {}) {
+            options.credentials = 'same-origin';
+            options.headers = Object.assign({}, options.headers || {});
+            if (options.method && options.method !== 'GET') {
+                options.headers['X-CSRF-Token'] = CSRF_TOKEN;
+            }
+            let res = await fetch(path, options);
+            if (res.status === 401) {
+                window.location.reload();
+                throw new Error('session expired');
+            }
+            if (res.status === 428) {
+                const data = await res.json();
+                const challenge = data.details && data.details.confirmation;
+                if (!challenge || !confirm(challenge.impact + '\n\nContinue?')) {
+                    throw new Error('cancelled');
+                }
+                options.headers['X-Confirmation-Token'] = challenge.token;
+                res = await fetch(path, options);
+            }
+            return res;
+        }
+
+        async function apiJSON(path, options) {
+            const res = await api(path, options);
+            const data = await res.json();
+            if (!res.ok) {
+                throw new Error(data.message || res.statusText);
+            }
+            return data;
+        }
+
+        async function logout() {
+            await api('/settings/logout', { method: 'POST' });
+            window.location.reload();
+        }
+
+        // Tabs
+
+        document.querySelectorAll('.tab').forEach(tab => {
+            tab.addEventListener('click', () => showTab(tab.dataset.tab));
+        });
+
+        function showTab(name) {
+            document.querySelectorAll('.tab').forEach(t => t.classList.toggle('active', t.dataset.tab === name));
+            ['overview', 'services', 'containers', 'logs'].forEach(t => {
+                document.getElementById('tab-' + t).classList.toggle('hidden', t !== name);
+            });
+            if (name === 'services') loadServices();
+            if (name === 'containers') loadContainers();
+            if (name === 'logs' && !serviceList.length) loadServices();
+        }
+
+        // Overview
+
+        async function loadInfo() {
+            try {
+                const info = await apiJSON('/api/info');
+                document.getElementById('hostname').textContent = info.hostname;
+                document.getElementById('hostInfo').textContent =
+                    info.platform + ' ' + info.kernel + ' (' + info.arch + ') - up ' + info.uptime + ' - agent ' + info.version;
+            } catch (err) {
+                showAlert('Failed to load host info: ' + err.message, 'error');
+            }
+        }
+
+        function push(series, value) {
+            series.push(value);
+            if (series.length > HISTORY) series.shift();
+        }
+
+        // drawChart draws one or two series as sparklines. max is the top of
+        // the scale; without it the scale follows the data.
+        function drawChart(id, seriesList, max) {
+            const canvas = document.getElementById(id);
+            const w = canvas.width = canvas.clientWidth * window.devicePixelRatio;
+            const h = canvas.height = canvas.clientHeight * window.devicePixelRatio;
+            const ctx = canvas.getContext('2d');
+            ctx.clearRect(0, 0, w, h);
+
+            const top = max || Math.max(1, ...seriesList.flat());
+            const colors = ['#3b82f6', '#10b981'];
+            seriesList.forEach((series, i) => {
+                ctx.beginPath();
+                ctx.strokeStyle = colors[i];
+                ctx.lineWidth = 2 * window.devicePixelRatio;
+                series.forEach((v, j) => {
+                    const x = (HISTORY - series.length + j) * w / (HISTORY - 1);
+                    const y = h - (v / top) * (h - 4) - 2;
+                    j ? ctx.lineTo(x, y) : ctx.moveTo(x, y);
+                });
+                ctx.stroke();
+            });
+        }
+
+        function onMetrics(m) {
+            push(samples.cpu, m.cpu.usage_total);
+            push(samples.mem, m.memory.used_percent);
+            document.getElementById('cpuValue').textContent = m.cpu.usage_total.toFixed(1) + '%';
+            document.getElementById('memValue').textContent = m.memory.used_percent.toFixed(1) + '%';
+            drawChart('cpuChart', [samples.cpu], 100);
+            drawChart('memChart', [samples.mem], 100);
+
+            // Network counters are cumulative; chart the rate between samples
+            let rx = 0, tx = 0;
+            (m.network.interfaces || []).forEach(i => { rx += i.bytes_recv; tx += i.bytes_sent; });
+            const now = Date.now();
+            if (lastNet) {
+                const secs = (now - lastNet.time) / 1000;
+                const rxRate = Math.max(0, (rx - lastNet.rx) / secs);
+                const txRate = Math.max(0, (tx - lastNet.tx) / secs);
+                push(samples.rx, rxRate);
+                push(samples.tx, txRate);
+                document.getElementById('netValue').textContent =
+                    formatBytes(rxRate) + '/s / ' + formatBytes(txRate) + '/s';
+                drawChart('netChart', [samples.rx, samples.tx]);
+            }
+            lastNet = { rx: rx, tx: tx, time: now };
+
+            const disks = (m.disk.partitions || []).map(p =>
+                '<tr><td>' + escapeHTML(p.mountpoint) + '</td>' +
+                '<td>' + formatBytes(p.used) + '</td>' +
+                '<td>' + formatBytes(p.total) + '</td>' +
+                '<td><span class="badge ' + (p.used_percent > 90 ? 'bad' : 'ok') + '">' +
+                p.used_percent.toFixed(1) + '%</span></td></tr>');
+            document.getElementById('disks').innerHTML = disks.join('') ||
+                '<tr><td colspan="4" class="muted">No partitions</td></tr>';
+        }
+
+        function startMetrics() {
+            metricsStream = new EventSource('/api/events');
+            metricsStream.addEventListener('metrics', e => onMetrics(JSON.parse(e.data)));
+            metricsStream.onerror = () => {
+                // EventSource reconnects on its own; a closed stream means the
+                // session has gone
+                if (metricsStream.readyState === EventSource.CLOSED) window.location.reload();
+            };
+        }
+
+        // Services
+
+        async function loadServices() {
+            const body = document.getElementById('services');
+            try {
+                const data = await apiJSON('/api/services');
+                serviceList = data.services || [];
+                document.getElementById('unitList').innerHTML =
+                    serviceList.map(s => '<option value="' + escapeHTML(s.name) + '">').join('');
+                renderServices();
+            } catch (err) {
+                body.innerHTML = '<tr><td colspan="4" class="muted">' + escapeHTML(err.message) + '</td></tr>';
+            }
+        }
+
+        function renderServices() {
+            const filter = document.getElementById('serviceFilter').value.toLowerCase();
+            const rows = serviceList
+                .filter(s => !filter || s.name.toLowerCase().includes(filter) ||
+                    (s.description || '').toLowerCase().includes(filter))
+                .map(s => {
+                    const name = escapeHTML(s.name);
+                    const ok = s.active_state === 'active';
+                    return '<tr><td>' + name + '</td>' +
+                        '<td><span class="badge ' + (ok ? 'ok' : (s.active_state === 'failed' ? 'bad' : '')) + '">' +
+                        escapeHTML(s.active_state + ' (' + s.sub_state + ')') + '</span></td>' +
+                        '<td class="muted">' + escapeHTML(s.description) + '</td>' +
+                        '<td class="actions">' +
+                        '<button data-service="' + name + '" data-action="start">Start</button> ' +
+                        '<button data-service="' + name + '" data-action="restart">Restart</button> ' +
+                        '<button data-service="' + name + '" data-action="stop">Stop</button> ' +
+                        '<button data-service="' + name + '" data-action="logs">Logs</button></td></tr>';
+                });
+            document.getElementById('services').innerHTML = rows.join('') ||
+                '<tr><td colspan="4" class="muted">No services</td></tr>';
+        }
+
+        document.getElementById('serviceFilter').addEventListener('input', renderServices);
+        document.getElementById('services').addEventListener('click', async e => {
+            const name = e.target.dataset.service;
+            const action = e.target.dataset.action;
+            if (!name) return;
+            if (action === 'logs') {
+                document.getElementById('logUnit').value = name;
+                showTab('logs');
+                loadLogs();
+                return;
+            }
+            await runAction('/api/services/' + encodeURIComponent(name) + '/' + action, action + ' ' + name);
+            loadServices();
+        });
+
+        // Containers
+
+        async function loadContainers() {
+            const body = document.getElementById('containers');
+            try {
+                const data = await apiJSON('/api/docker/containers');
+                const rows = (data.containers || []).map(ct => {
+                    const id = escapeHTML(ct.id);
+                    const running = ct.state === 'running';
+                    return '<tr><td>' + escapeHTML(ct.name) + '</td>' +
+                        '<td class="muted">' + escapeHTML(ct.image) + '</td>' +
+                        '<td><span class="badge ' + (running ? 'ok' : (ct.state === 'exited' ? 'bad' : '')) + '">' +
+                        escapeHTML(ct.state) + '</span></td>' +
+                        '<td class="muted">' + escapeHTML(ct.status) + '</td>' +
+                        '<td class="actions">' +
+                        '<button data-container="' + id + '" data-name="' + escapeHTML(ct.name) + '" data-action="start">Start</button> ' +
+                        '<button data-container="' + id + '" data-name="' + escapeHTML(ct.name) + '" data-action="restart">Restart</button> ' +
+                        '<button data-container="' + id + '" data-name="' + escapeHTML(ct.name) + '" data-action="stop">Stop</button></td></tr>';
+                });
+                body.innerHTML = rows.join('') || '<tr><td colspan="5" class="muted">No containers</td></tr>';
+            } catch (err) {
+                body.innerHTML = '<tr><td colspan="5" class="muted">' + escapeHTML(err.message) + '</td></tr>';
+            }
+        }
+
+        document.getElementById('containers').addEventListener('click', async e => {
+            const id = e.target.dataset.container;
+            if (!id) return;
+            const action = e.target.dataset.action;
+            await runAction('/api/docker/containers/' + encodeURIComponent(id) + '/' + action, action + ' ' + e.target.dataset.name);
+            loadContainers();
+        });
+
+        async function runAction(path, label) {
+            try {
+                const res = await api(path, { method: 'POST' });
+                const data = await res.json();
+                if (res.status === 202) {
+                    showAlert(data.message, 'success');
+                } else if (res.ok) {
+                    showAlert(label + ': done', 'success');
+                } else {
+                    showAlert(label + ': ' + (data.message || res.statusText), 'error');
+                }
+            } catch (err) {
+                if (err.message !== 'cancelled') showAlert(label + ': ' + err.message, 'error');
+            }
+        }
+
+        // Logs
+
+        function logLine(entry) {
+            return new Date(entry.timestamp).toLocaleString() + ' ' + entry.unit + ': ' + entry.message + '\n';
+        }
+
+        async function loadLogs() {
+            const unit = document.getElementById('logUnit').value.trim();
+            const pre = document.getElementById('logs');
+            if (!unit) return;
+            stopFollow();
+            try {
+                const lines = document.getElementById('logLines').value;
+                const data = await apiJSON('/api/logs/' + encodeURIComponent(unit) + '?lines=' + lines);
+                pre.textContent = (data.entries || []).map(logLine).join('');
+                pre.scrollTop = pre.scrollHeight;
+            } catch (err) {
+                pre.textContent = err.message;
+            }
+        }
+
+        function toggleFollow() {
+            if (logStream) {
+                stopFollow();
+                return;
+            }
+            const unit = document.getElementById('logUnit').value.trim();
+            if (!unit) return;
+            const pre = document.getElementById('logs');
+            logStream = new EventSource('/api/logs?unit=' + encodeURIComponent(unit));
+            logStream.addEventListener('log', e => {
+                pre.textContent += logLine(JSON.parse(e.data));
+                pre.scrollTop = pre.scrollHeight;
+            });
+            document.getElementById('followButton').textContent = 'Stop';
+        }
+
+        function stopFollow() {
+            if (logStream) logStream.close();
+            logStream = null;
+            document.getElementById('followButton').textContent = 'Follow';
+        }
+
+        loadInfo();
+        startMetrics();
+    </script>
+</body>
+</html>`
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/cache"
+)
+
+// GetDatabaseHealth handles GET /api/services/databases
+func (h *Handlers) GetDatabaseHealth(c *gin.Context) {
+	list, _ := h.cache.GetOrSet(cache.KeyDatabases, func() (interface{}, error) {
+		return h.databases.Check(c.Request.Context()), nil
+	})
+	c.JSON(http.StatusOK, list)
+}
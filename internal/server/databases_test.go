@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/databases"
+)
+
+func TestGetDatabaseHealth(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := config.LoadWithDefaults()
+	cfg.DatabaseURLs = "cache=redis://" + addr
+	srv := New(cfg)
+
+	req := httptest.NewRequest("GET", "/api/services/databases", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var list databases.StatusList
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, 1, list.Down)
+	require.Len(t, list.Databases, 1)
+	assert.Equal(t, "cache", list.Databases[0].Name)
+	assert.Equal(t, addr, list.Databases[0].Address)
+	assert.NotEmpty(t, list.Databases[0].Error)
+}
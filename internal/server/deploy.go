@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/deploy"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/jobs"
+)
+
+const deployJob = "deploy"
+
+// Deploy handles POST /api/deploy. The compose file is validated before
+// the response; pull and up run as a job to poll at /api/operations/:id
+// or follow at /api/deploy/:id/stream.
+func (h *Handlers) Deploy(c *gin.Context) {
+	if !h.cfg.DeployEnabled {
+		respondError(c, http.StatusForbidden, deploy.ErrDisabled)
+		return
+	}
+
+	var req deploy.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	job, err := h.startDeploy(c.Request.Context(), req)
+	if err != nil {
+		h.recordAudit(c, "deploy", req.Project+req.Path, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.recordAudit(c, "deploy", job.Target, true, "started as "+job.ID)
+
+	c.Header("Location", "/api/operations/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// startDeploy validates req and starts the deployment job, unless one is
+// already running
+func (h *Handlers) startDeploy(ctx context.Context, req deploy.Request) (*jobs.Job, error) {
+	if job, running := h.jobManager.Active(deployJob); running {
+		return nil, apierror.Conflict("a deployment is already running").WithDetail("job", job)
+	}
+
+	plan, err := h.deployer.Prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := deploy.NewOutput()
+	job := h.jobManager.Submit(deployJob, plan.Project, h.cfg.DeployTimeout, func(ctx context.Context) (interface{}, error) {
+		result, err := h.deployer.Run(ctx, plan, out)
+		h.publishDeploy(plan, err)
+		return result, err
+	})
+	h.deployer.Track(job.ID, out)
+	return job, nil
+}
+
+func (h *Handlers) publishDeploy(plan *deploy.Plan, err error) {
+	event := events.Event{
+		Type:     "deploy.completed",
+		Severity: events.SeverityInfo,
+		Source:   plan.Project,
+		Message:  fmt.Sprintf("Deployed %s", plan.Project),
+		Data:     plan,
+	}
+	if err != nil {
+		event.Type = "deploy.failed"
+		event.Severity = events.SeverityWarning
+		event.Message = fmt.Sprintf("Deployment of %s failed: %v", plan.Project, err)
+	}
+	h.eventBus.Publish(event)
+}
+
+// StreamDeploy handles GET /api/deploy/:id/stream (SSE). Output so far is
+// replayed, then followed until a done event with the outcome.
+func (h *Handlers) StreamDeploy(c *gin.Context) {
+	if h.deployer == nil {
+		respondError(c, http.StatusForbidden, deploy.ErrDisabled)
+		return
+	}
+
+	id := c.Param("id")
+	out, ok := h.deployer.Output(id)
+	if !ok {
+		respondMessage(c, http.StatusNotFound, "deployment not found")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ctx := c.Request.Context()
+	sent := 0
+	c.Stream(func(w io.Writer) bool {
+		lines, done, more := out.Since(sent)
+		for _, line := range lines {
+			c.SSEvent("output", line)
+		}
+		sent += len(lines)
+
+		if done {
+			outcome := gin.H{"id": id, "success": out.Err() == nil}
+			if err := out.Err(); err != nil {
+				outcome["error"] = err.Error()
+			}
+			data, _ := json.Marshal(outcome)
+			c.SSEvent("done", string(data))
+			return false
+		}
+
+		select {
+		case <-more:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
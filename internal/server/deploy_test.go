@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestDeploy(t *testing.T) {
+	request := func(srv *Server, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	cfg := config.LoadWithDefaults()
+	w := request(New(cfg), "POST", "/api/deploy", `{"path": "/tmp/compose.yaml"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, "disabled by default")
+
+	cfg = config.LoadWithDefaults()
+	cfg.DeployEnabled = true
+	srv := New(cfg)
+
+	w = request(srv, "POST", "/api/deploy", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "set exactly one of compose and path")
+
+	w = request(srv, "POST", "/api/deploy", `{"path": "/etc/compose.yaml"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, "outside ALLOWED_PATHS")
+
+	w = request(srv, "GET", "/api/deploy/missing/stream", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/diagnostics"
+)
+
+func TestProbePort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	srv := New(config.LoadWithDefaults())
+	probe := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/diagnostics/port"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := probe("?host=127.0.0.1&port=" + port)
+	require.Equal(t, http.StatusOK, w.Code)
+	var result diagnostics.ProbeResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.Reachable)
+	assert.Equal(t, ln.Addr().String(), result.Address)
+
+	assert.Equal(t, http.StatusBadRequest, probe("?port="+port).Code)
+	assert.Equal(t, http.StatusBadRequest, probe("?host=127.0.0.1&port=http").Code)
+	assert.Equal(t, http.StatusBadRequest, probe("?host=127.0.0.1&port=0").Code)
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/cache"
+)
+
+// GetDNSServers handles GET /api/dns
+func (h *Handlers) GetDNSServers(c *gin.Context) {
+	list, _ := h.cache.GetOrSet(cache.KeyDNS, func() (interface{}, error) {
+		return h.dnsMonitor.Status(c.Request.Context()), nil
+	})
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/energy"
+)
+
+// GetPowerMetrics handles GET /api/metrics/power with the current draw
+func (h *Handlers) GetPowerMetrics(c *gin.Context) {
+	power, err := h.energy.Current()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, power)
+}
+
+// GetPowerHistory handles GET /api/metrics/power/history with hourly
+// energy use. hours defaults to 24.
+func (h *Handlers) GetPowerHistory(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > energy.MaxHistory {
+		respondMessage(c, http.StatusBadRequest, "hours must be between 1 and "+strconv.Itoa(energy.MaxHistory))
+		return
+	}
+	c.JSON(http.StatusOK, h.energy.History(hours))
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// respondError writes err in the standard error envelope. Typed errors pick
+// their own status; anything else is reported with fallback.
+func respondError(c *gin.Context, fallback int, err error) {
+	c.JSON(apierror.From(err, fallback))
+}
+
+// respondMessage writes message in the standard error envelope
+func respondMessage(c *gin.Context, status int, message string) {
+	c.JSON(status, apierror.New(status, message, nil))
+}
+
+// respondFailed reports an action that ran but did not succeed. The action's
+// result is kept in the envelope's details.
+func respondFailed(c *gin.Context, status int, message string, result interface{}) {
+	c.JSON(status, apierror.New(status, message, map[string]interface{}{"result": result}))
+}
+
+// abortMessage is respondMessage for middleware
+func abortMessage(c *gin.Context, status int, message string, details map[string]interface{}) {
+	c.AbortWithStatusJSON(status, apierror.New(status, message, details))
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetExposure handles GET /api/security/exposure with the listening
+// sockets and how far each can be reached. refresh=true scans now instead
+// of returning the latest scan.
+func (h *Handlers) GetExposure(c *gin.Context) {
+	scan := h.exposure.Report
+	if c.Query("refresh") == "true" {
+		scan = h.exposure.Check
+	}
+	report, err := scan()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
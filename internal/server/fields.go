@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseFields splits a fields query parameter into dot-separated paths
+func ParseFields(raw string) [][]string {
+	var paths [][]string
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		paths = append(paths, strings.Split(field, "."))
+	}
+	return paths
+}
+
+// SelectFields returns a sparse copy of data containing only the requested
+// paths. Paths traverse objects by key and apply to every element of arrays,
+// so "disk.partitions.mountpoint" keeps only the mountpoint of each partition.
+func SelectFields(data interface{}, paths [][]string) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+
+	return project(generic, buildFieldTree(paths)), nil
+}
+
+// fieldTree is a nested set of requested keys; a nil subtree selects the whole value
+type fieldTree map[string]fieldTree
+
+func buildFieldTree(paths [][]string) fieldTree {
+	root := fieldTree{}
+	for _, path := range paths {
+		node := root
+		for i, key := range path {
+			child, exists := node[key]
+			if i == len(path)-1 {
+				// A shorter path selects the whole subtree
+				node[key] = nil
+				break
+			}
+			if exists && child == nil {
+				break
+			}
+			if !exists {
+				child = fieldTree{}
+				node[key] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+func project(value interface{}, tree fieldTree) interface{} {
+	if tree == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(tree))
+		for key, subtree := range tree {
+			if child, ok := v[key]; ok {
+				out[key] = project(child, subtree)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = project(item, tree)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// fieldsWriter buffers a JSON response so it can be projected before sending.
+// Streaming responses (which flush) are passed through untouched.
+type fieldsWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (w *fieldsWriter) Write(data []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *fieldsWriter) WriteString(s string) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.buf.WriteString(s)
+}
+
+func (w *fieldsWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		if w.buf.Len() > 0 {
+			_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+			w.buf.Reset()
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// FieldsMiddleware applies the `fields` query parameter to JSON responses
+func FieldsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		paths := ParseFields(c.Query("fields"))
+		if len(paths) == 0 {
+			c.Next()
+			return
+		}
+
+		writer := &fieldsWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.streaming {
+			return
+		}
+
+		body := writer.buf.Bytes()
+		status := writer.Status()
+		isJSON := strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json")
+
+		// Only successful JSON bodies are projected; errors are returned as-is
+		if isJSON && status >= 200 && status < 300 {
+			var data interface{}
+			if err := json.Unmarshal(body, &data); err == nil {
+				if projected, err := json.Marshal(project(data, buildFieldTree(paths))); err == nil {
+					body = projected
+				}
+			}
+		}
+
+		if len(body) == 0 {
+			writer.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		_, _ = writer.ResponseWriter.Write(body)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectFields(t *testing.T) {
+	data := gin.H{
+		"cpu": gin.H{
+			"usage_total":   12.5,
+			"usage_per_cpu": []float64{10, 15},
+		},
+		"memory": gin.H{
+			"used_percent": 40.0,
+			"total":        1024,
+		},
+		"disk": gin.H{
+			"partitions": []gin.H{
+				{"mountpoint": "/", "used": 10},
+				{"mountpoint": "/boot", "used": 1},
+			},
+		},
+	}
+
+	result, err := SelectFields(data, ParseFields("cpu.usage_total, memory.used_percent,disk.partitions.mountpoint"))
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"cpu":    map[string]interface{}{"usage_total": 12.5},
+		"memory": map[string]interface{}{"used_percent": 40.0},
+		"disk": map[string]interface{}{
+			"partitions": []interface{}{
+				map[string]interface{}{"mountpoint": "/"},
+				map[string]interface{}{"mountpoint": "/boot"},
+			},
+		},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestSelectFields_WholeSubtree(t *testing.T) {
+	data := gin.H{"cpu": gin.H{"a": 1, "b": 2}, "memory": gin.H{"c": 3}}
+
+	result, err := SelectFields(data, ParseFields("cpu.a,cpu"))
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"cpu": map[string]interface{}{"a": 1.0, "b": 2.0},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestFieldsMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(FieldsMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"keep": 1, "drop": 2})
+	})
+	router.GET("/error", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+
+	req := httptest.NewRequest("GET", "/test?fields=keep", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{"keep": 1.0}, body)
+
+	// Error responses are not projected
+	req = httptest.NewRequest("GET", "/error?fields=keep", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "not found")
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/gitrepos"
+	"github.com/ngenohkevin/hivedeck-agent/internal/jobs"
+)
+
+const gitPullJob = "git.pull"
+
+// ListGitRepos handles GET /api/git. Ahead and behind counts are as of the
+// last fetch; fetch=true fetches first.
+func (h *Handlers) ListGitRepos(c *gin.Context) {
+	repos := h.gitRepos.Status(c.Request.Context(), c.Query("fetch") == "true")
+	c.JSON(http.StatusOK, gin.H{
+		"repositories": repos,
+		"total":        len(repos),
+	})
+}
+
+// GetGitRepo handles GET /api/git/:name
+func (h *Handlers) GetGitRepo(c *gin.Context) {
+	status, err := h.gitRepos.Get(c.Request.Context(), c.Param("name"), c.Query("fetch") == "true")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// PullGitRepo handles POST /api/git/:name/pull, fast-forwarding the
+// checkout as a job to poll at /api/operations/:id
+func (h *Handlers) PullGitRepo(c *gin.Context) {
+	name := c.Param("name")
+	if err := h.gitRepos.CheckPull(name); err != nil {
+		h.recordAudit(c, gitPullJob, name, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, job := range h.jobManager.List(gitPullJob).Jobs {
+		if job.Target == name && (job.Status == jobs.StatusPending || job.Status == jobs.StatusRunning) {
+			respondError(c, http.StatusConflict, apierror.Conflict("a pull of %s is already running", name).WithDetail("job", job))
+			return
+		}
+	}
+
+	job := h.jobManager.Submit(gitPullJob, name, gitrepos.PullTimeout, func(ctx context.Context) (interface{}, error) {
+		return h.gitRepos.Pull(ctx, name)
+	})
+	h.recordAudit(c, gitPullJob, name, true, "started as "+job.ID)
+
+	c.Header("Location", "/api/operations/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
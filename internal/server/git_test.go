@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestGitRepos(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.GitRepos = map[string]string{"web": t.TempDir()}
+	srv := New(cfg)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "/api/git")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Repositories []struct {
+			Name     string `json:"name"`
+			Pullable bool   `json:"pullable"`
+			Error    string `json:"error"`
+		} `json:"repositories"`
+		Total int `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Total)
+	assert.Equal(t, "web", resp.Repositories[0].Name)
+	assert.False(t, resp.Repositories[0].Pullable)
+	assert.NotEmpty(t, resp.Repositories[0].Error) // Not a checkout
+
+	assert.Equal(t, http.StatusNotFound, do("GET", "/api/git/blog").Code)
+	assert.Equal(t, http.StatusForbidden, do("POST", "/api/git/web/pull").Code)
+	assert.Equal(t, http.StatusNotFound, do("POST", "/api/git/blog/pull").Code)
+}
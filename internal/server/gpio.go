@@ -0,0 +1,17 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGPIO handles GET /api/gpio with the pin states and named inputs
+func (h *Handlers) GetGPIO(c *gin.Context) {
+	state, err := h.gpio.Read(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/cache"
+	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+	"github.com/ngenohkevin/hivedeck-agent/internal/process"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
+)
+
+// GraphQLRequest is the body of a POST /api/graphql request
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+var errDockerUnavailable = apierror.Unavailable("docker not available")
+
+// requireModule wraps a resolver so it fails when its module is disabled
+func (h *Handlers) requireModule(module string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if !h.cfg.ModuleEnabled(module) {
+			return nil, fmt.Errorf("module %s is disabled", module)
+		}
+		return resolve(p)
+	}
+}
+
+// jsonScalar passes arbitrary values (such as label maps) through unchanged
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Arbitrary JSON value",
+	Serialize:   func(value interface{}) interface{} { return value },
+	ParseValue:  func(value interface{}) interface{} { return value },
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return valueAST.GetValue()
+	},
+})
+
+// graphqlTypes builds GraphQL object types from Go structs. Field names follow
+// the struct's JSON tags so queries use the same names as the REST responses.
+type graphqlTypes struct {
+	objects map[reflect.Type]*graphql.Object
+}
+
+func (g *graphqlTypes) outputType(t reflect.Type) graphql.Output {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return graphql.DateTime
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return graphql.String
+	case reflect.Bool:
+		return graphql.Boolean
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return graphql.Int
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		// Byte counters and similar overflow GraphQL's 32-bit Int
+		return graphql.Float
+	case reflect.Float32, reflect.Float64:
+		return graphql.Float
+	case reflect.Slice, reflect.Array:
+		return graphql.NewList(g.outputType(t.Elem()))
+	case reflect.Struct:
+		return g.object(t)
+	default:
+		return jsonScalar
+	}
+}
+
+func (g *graphqlTypes) object(t reflect.Type) *graphql.Object {
+	if obj, ok := g.objects[t]; ok {
+		return obj
+	}
+
+	fields := graphql.Fields{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || name == "" {
+			continue
+		}
+		fields[name] = &graphql.Field{Type: g.outputType(field.Type)}
+	}
+
+	obj := graphql.NewObject(graphql.ObjectConfig{
+		Name:   t.Name(),
+		Fields: fields,
+	})
+	g.objects[t] = obj
+	return obj
+}
+
+// buildGraphQLSchema exposes metrics, processes, services and containers as a
+// read-only GraphQL schema backed by the same managers as the REST handlers
+func (h *Handlers) buildGraphQLSchema() (graphql.Schema, error) {
+	types := &graphqlTypes{objects: make(map[reflect.Type]*graphql.Object)}
+
+	cached := func(key string, fetch func() (interface{}, error)) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			return h.cache.GetOrSet(key, fetch)
+		}
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"metrics": &graphql.Field{
+				Type: types.object(reflect.TypeOf(system.AllMetrics{})),
+				Resolve: cached(cache.KeyAll, func() (interface{}, error) {
+					return h.metricsCollector.GetAllMetrics()
+				}),
+			},
+			"host": &graphql.Field{
+				Type: types.object(reflect.TypeOf(system.HostInfo{})),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return system.GetHostInfo()
+				},
+			},
+			"cpu": &graphql.Field{
+				Type: types.object(reflect.TypeOf(system.CPUInfo{})),
+				Resolve: cached(cache.KeyCPU, func() (interface{}, error) {
+					return h.metricsCollector.GetCPUInfo()
+				}),
+			},
+			"memory": &graphql.Field{
+				Type: types.object(reflect.TypeOf(system.MemoryInfo{})),
+				Resolve: cached(cache.KeyMemory, func() (interface{}, error) {
+					return h.metricsCollector.GetMemoryInfo()
+				}),
+			},
+			"disk": &graphql.Field{
+				Type: types.object(reflect.TypeOf(system.DiskInfo{})),
+				Resolve: cached(cache.KeyDisk, func() (interface{}, error) {
+					return h.metricsCollector.GetDiskInfo()
+				}),
+			},
+			"network": &graphql.Field{
+				Type: types.object(reflect.TypeOf(system.NetworkInfo{})),
+				Resolve: cached(cache.KeyNetwork, func() (interface{}, error) {
+					return h.metricsCollector.GetNetworkInfo()
+				}),
+			},
+			"processes": &graphql.Field{
+				Type: types.object(reflect.TypeOf(process.ProcessList{})),
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 50},
+				},
+				Resolve: h.requireModule(config.ModuleProcesses, func(p graphql.ResolveParams) (interface{}, error) {
+					limit, _ := p.Args["limit"].(int)
+					if limit <= 0 {
+						limit = 50
+					}
+					return h.processManager.ListTop(limit)
+				}),
+			},
+			"services": &graphql.Field{
+				Type: types.object(reflect.TypeOf(systemd.ServiceList{})),
+				Resolve: h.requireModule(config.ModuleServices, func(p graphql.ResolveParams) (interface{}, error) {
+					return h.serviceManager.List(p.Context)
+				}),
+			},
+			"service": &graphql.Field{
+				Type: types.object(reflect.TypeOf(systemd.ServiceInfo{})),
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: h.requireModule(config.ModuleServices, func(p graphql.ResolveParams) (interface{}, error) {
+					return h.serviceManager.Get(p.Context, p.Args["name"].(string))
+				}),
+			},
+			"containers": &graphql.Field{
+				Type: types.object(reflect.TypeOf(docker.ContainerList{})),
+				Args: graphql.FieldConfigArgument{
+					"all": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: h.requireModule(config.ModuleDocker, func(p graphql.ResolveParams) (interface{}, error) {
+					manager := h.dockerManager()
+					if manager == nil {
+						return nil, errDockerUnavailable
+					}
+					all, _ := p.Args["all"].(bool)
+					return manager.ListContainers(p.Context, all)
+				}),
+			},
+			"container": &graphql.Field{
+				Type: types.object(reflect.TypeOf(docker.ContainerInfo{})),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: h.requireModule(config.ModuleDocker, func(p graphql.ResolveParams) (interface{}, error) {
+					manager := h.dockerManager()
+					if manager == nil {
+						return nil, errDockerUnavailable
+					}
+					return manager.GetContainer(p.Context, p.Args["id"].(string))
+				}),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// GraphQL handles GET and POST /api/graphql
+func (h *Handlers) GraphQL(c *gin.Context) {
+	var req GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	if req.Query == "" {
+		respondMessage(c, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	h.graphqlOnce.Do(func() {
+		h.graphqlSchema, h.graphqlErr = h.buildGraphQLSchema()
+	})
+	if h.graphqlErr != nil {
+		respondMessage(c, http.StatusInternalServerError, fmt.Sprintf("graphql schema: %v", h.graphqlErr))
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.graphqlSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        c.Request.Context(),
+	})
+
+	// Per the GraphQL convention, field errors are reported alongside partial
+	// data with a 200; only requests that produced no data at all are rejected
+	status := http.StatusOK
+	if result.Data == nil && result.HasErrors() {
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, result)
+}
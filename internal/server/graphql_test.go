@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func doGraphQL(t *testing.T, srv *Server, body string) (int, map[string]interface{}) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.Router().ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestGraphQL_SelectsRequestedFields(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	status, resp := doGraphQL(t, srv, `{"query": "{ memory { total used_percent } processes(limit: 2) { processes { pid name } } }"}`)
+	require.Equal(t, http.StatusOK, status)
+
+	data := resp["data"].(map[string]interface{})
+
+	memory := data["memory"].(map[string]interface{})
+	assert.Len(t, memory, 2)
+	assert.Greater(t, memory["total"], 0.0)
+
+	processes := data["processes"].(map[string]interface{})["processes"].([]interface{})
+	assert.LessOrEqual(t, len(processes), 2)
+	for _, p := range processes {
+		assert.Len(t, p, 2)
+	}
+}
+
+func TestGraphQL_DockerUnavailable(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.DockerEnabled = false
+	srv := New(cfg)
+
+	status, resp := doGraphQL(t, srv, `{"query": "{ containers { total } }"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp["errors"])
+}
+
+func TestGraphQL_InvalidQuery(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	status, resp := doGraphQL(t, srv, `{"query": "{ nonexistent }"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, resp["errors"])
+}
@@ -3,75 +3,374 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
 
 	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/alerts"
+	"github.com/ngenohkevin/hivedeck-agent/internal/annotations"
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/approvals"
+	"github.com/ngenohkevin/hivedeck-agent/internal/audit"
+	"github.com/ngenohkevin/hivedeck-agent/internal/backups"
 	"github.com/ngenohkevin/hivedeck-agent/internal/cache"
+	"github.com/ngenohkevin/hivedeck-agent/internal/cloudmeta"
+	"github.com/ngenohkevin/hivedeck-agent/internal/confirm"
+	"github.com/ngenohkevin/hivedeck-agent/internal/databases"
+	"github.com/ngenohkevin/hivedeck-agent/internal/datadir"
+	"github.com/ngenohkevin/hivedeck-agent/internal/deploy"
+	"github.com/ngenohkevin/hivedeck-agent/internal/diagnostics"
+	"github.com/ngenohkevin/hivedeck-agent/internal/dns"
 	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+	"github.com/ngenohkevin/hivedeck-agent/internal/energy"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/exposure"
 	"github.com/ngenohkevin/hivedeck-agent/internal/files"
+	"github.com/ngenohkevin/hivedeck-agent/internal/gitrepos"
+	"github.com/ngenohkevin/hivedeck-agent/internal/gpio"
+	"github.com/ngenohkevin/hivedeck-agent/internal/heartbeat"
+	"github.com/ngenohkevin/hivedeck-agent/internal/helper"
+	"github.com/ngenohkevin/hivedeck-agent/internal/hostaudit"
+	"github.com/ngenohkevin/hivedeck-agent/internal/integrity"
+	"github.com/ngenohkevin/hivedeck-agent/internal/jobs"
+	"github.com/ngenohkevin/hivedeck-agent/internal/logship"
+	"github.com/ngenohkevin/hivedeck-agent/internal/maintenance"
+	"github.com/ngenohkevin/hivedeck-agent/internal/mqtt"
+	"github.com/ngenohkevin/hivedeck-agent/internal/oom"
+	"github.com/ngenohkevin/hivedeck-agent/internal/power"
+	"github.com/ngenohkevin/hivedeck-agent/internal/privilege"
 	"github.com/ngenohkevin/hivedeck-agent/internal/process"
+	"github.com/ngenohkevin/hivedeck-agent/internal/profile"
+	"github.com/ngenohkevin/hivedeck-agent/internal/redact"
+	"github.com/ngenohkevin/hivedeck-agent/internal/sandbox"
+	"github.com/ngenohkevin/hivedeck-agent/internal/schedules"
+	"github.com/ngenohkevin/hivedeck-agent/internal/speedtest"
+	"github.com/ngenohkevin/hivedeck-agent/internal/store"
 	"github.com/ngenohkevin/hivedeck-agent/internal/system"
 	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
 	"github.com/ngenohkevin/hivedeck-agent/internal/tasks"
+	"github.com/ngenohkevin/hivedeck-agent/internal/thermal"
+	"github.com/ngenohkevin/hivedeck-agent/internal/upstreams"
+	"github.com/ngenohkevin/hivedeck-agent/internal/vulns"
+	"github.com/ngenohkevin/hivedeck-agent/internal/webhooks"
 )
 
 // Handlers holds all HTTP handlers
 type Handlers struct {
-	cfg            *config.Config
-	cache          *cache.MetricsCache
+	cfg              *config.Config
+	cache            *cache.MetricsCache
 	metricsCollector *system.Collector
-	processManager *process.Manager
-	serviceManager *systemd.Manager
-	journalReader  *systemd.JournalReader
-	dockerManager  *docker.Manager
-	fileBrowser    *files.Browser
-	taskManager    *tasks.Manager
+	metricsHub       *metricsHub
+	processManager   *process.Manager
+	serviceManager   *systemd.Manager
+	redactor         *redact.Redactor
+	schedules        *schedules.Scheduler
+	journalReader    *systemd.JournalReader
+	docker           *docker.Connector // nil unless DOCKER_ENABLED
+	registryAuth     *docker.RegistryAuth
+	fileBrowser      *files.Browser
+	taskManager      *tasks.Manager
+	powerManager     *power.Manager
+	auditLog         *audit.Logger
+	hostAuditLog     *audit.Logger // separate, so host activity cannot push API actions out
+	prober           *diagnostics.Prober
+	jobManager       *jobs.Manager
+	speedtestRunner  *speedtest.Runner
+	eventBus         *events.Bus
+	confirmations    *confirm.Store
+	approvalQueue    *approvals.Queue  // nil unless approvals are enabled
+	mqttPublisher    *mqtt.Publisher   // nil unless MQTT_BROKER is set
+	logShipper       *logship.Shipper  // nil unless LOG_FORWARD_URL is set
+	heartbeat        *heartbeat.Pinger // nil unless HEARTBEAT_URL is set
+	integrityMonitor *integrity.Monitor
+	exposure         *exposure.Monitor
+	vulns            *vulns.Scanner
+	imageUpdates     *docker.UpdateChecker
+	privileges       *privilege.Report
+	helper           *helper.Client // nil unless PRIVILEGE_HELPER
+	annotations      *annotations.Store
+	sandbox          *sandbox.Status // nil unless SANDBOX_ENABLED
+	maintenance      *maintenance.Runner
+	backups          *backups.Monitor
+	gitRepos         *gitrepos.Manager
+	databases        *databases.Checker
+	upstreams        *upstreams.Monitor
+	dnsMonitor       *dns.Monitor
+	thermal          *thermal.Monitor
+	gpio             *gpio.Reader
+	energy           *energy.Meter
+	zombies          *process.ZombieMonitor
+	oomWatcher       *oom.Watcher
+	hostAudit        *hostaudit.Watcher
+	deployer         *deploy.Deployer // nil unless DEPLOY_ENABLED or a webhook deploys
+	webhooks         map[string]*webhooks.Hook
+	webhookReplays   *webhooks.ReplayGuard
+	alerts           *alerts.Manager
+	profiles         *profile.Collector
+	store            *store.DB        // nil without DATA_DIR or if it failed to open
+	storage          *datadir.Manager // nil without DATA_DIR
+	machine          *cloudmeta.Detector
+	conns            *connLimiter
+	startedAt        time.Time
+
+	graphqlOnce   sync.Once
+	graphqlSchema graphql.Schema
+	graphqlErr    error
 }
 
 // NewHandlers creates a new handlers instance
 func NewHandlers(cfg *config.Config) *Handlers {
+	redactor := redact.New(slices.Concat(redact.DefaultKeywords, cfg.RedactKeywords))
 	h := &Handlers{
 		cfg:              cfg,
-		cache:            cache.NewMetricsCache(),
+		cache:            cache.NewMetricsCache(cfg.CacheTTL, cfg.CacheTTLs),
 		metricsCollector: system.NewCollector(),
-		processManager:   process.NewManager(),
+		processManager:   process.NewManager(cfg.AllowedProcesses, redactor),
+		redactor:         redactor,
 		serviceManager:   systemd.NewManager(cfg.AllowedServices),
 		journalReader:    systemd.NewJournalReader(),
 		fileBrowser:      files.NewBrowser(cfg.AllowedPaths),
 		taskManager:      tasks.NewManager(cfg.AllowedTasks),
+		powerManager:     power.NewManager(),
+		auditLog:         audit.NewLogger(audit.DefaultCapacity),
+		hostAuditLog:     audit.NewLogger(audit.DefaultCapacity),
+		prober:           diagnostics.NewProber(),
+		jobManager:       jobs.NewManager(),
+		speedtestRunner:  speedtest.NewRunner(cfg.SpeedtestBackend, cfg.SpeedtestServer, cfg.DataDir),
+		eventBus:         events.NewBus(events.DefaultCapacity),
+		confirmations:    confirm.NewStore(confirm.DefaultTTL),
+		privileges:       privilege.Detect(),
+		annotations:      annotations.NewStore(cfg.DataDir),
+		machine:          cloudmeta.NewDetector(cfg.CloudMetadata),
+		conns:            newConnLimiter(cfg.MaxRequests, cfg.MaxStreams),
+		startedAt:        time.Now(),
 	}
+	h.metricsHub = newMetricsHub(h.metricsCollector.GetAllMetrics, streamInterval)
 
-	// Initialize Docker if enabled
-	if cfg.DockerEnabled {
-		dockerMgr, err := docker.NewManager()
-		if err == nil {
-			h.dockerManager = dockerMgr
+	if cfg.PrivilegeHelper {
+		h.useHelper()
+	}
+
+	if !h.privileges.Root {
+		log.Printf("Running as %s without root; limited features:", h.privileges.User)
+		for feature, reason := range h.privileges.Limited {
+			log.Printf("  %s: %s", feature, reason)
+		}
+	}
+
+	if cfg.DataDir != "" {
+		db, err := store.Open(context.Background(), cfg.DataDir)
+		if err != nil {
+			log.Printf("Storage disabled: %v", err)
+		} else {
+			h.store = db
+		}
+	}
+
+	if cfg.ApprovalsEnabled {
+		h.approvalQueue = approvals.NewQueue(cfg.ApprovalTTL)
+	}
+
+	// Deleted files go to a trash under DATA_DIR rather than being unlinked
+	if cfg.FilesDeleteEnabled {
+		if cfg.DataDir != "" {
+			h.fileBrowser.SetTrash(files.NewTrash(filepath.Join(cfg.DataDir, "trash"), cfg.TrashRetention))
+		} else {
+			log.Printf("File deletion disabled: DATA_DIR is required for the trash")
+		}
+	}
+
+	h.loadWebhooks(cfg)
+	if cfg.DeployEnabled || h.needsDeployer() {
+		h.deployer = deploy.NewDeployer(cfg.DataDir, h.fileBrowser.IsPathAllowed)
+	}
+
+	h.maintenance = h.newMaintenanceRunner(cfg)
+	h.schedules = schedules.NewScheduler(cfg.DataDir, h.serviceManager.IsAllowed, h.runScheduledAction)
+	if cfg.DataDir != "" {
+		h.storage = h.newStorageManager(cfg)
+	}
+
+	repos, err := backups.ParseRepos(cfg.BackupRepos, cfg.BackupPasswordFiles)
+	if err != nil {
+		log.Printf("Backup repositories disabled: %v", err)
+	}
+	h.backups = backups.NewMonitor(repos, cfg.BackupCacheTTL)
+
+	checkouts, err := gitrepos.ParseRepos(cfg.GitRepos, cfg.GitPullAllowed)
+	if err != nil {
+		log.Printf("Git repositories disabled: %v", err)
+	}
+	h.gitRepos = gitrepos.NewManager(checkouts)
+
+	if h.databases, err = databases.NewChecker(cfg.Databases()); err != nil {
+		log.Printf("Database health checks disabled: %v", err)
+		h.databases, _ = databases.NewChecker(nil)
+	}
+
+	if h.dnsMonitor, err = dns.NewMonitor(cfg.DNS()); err != nil {
+		log.Printf("DNS server monitoring disabled: %v", err)
+		h.dnsMonitor, _ = dns.NewMonitor(nil)
+	}
+
+	upstreamOpts := upstreams.Options{Interval: cfg.UpstreamInterval, Timeout: cfg.UpstreamTimeout, Insecure: cfg.UpstreamInsecure}
+	if h.upstreams, err = upstreams.NewMonitor(cfg.Upstreams, upstreamOpts, h.eventBus); err != nil {
+		log.Printf("Upstream checks disabled: %v", err)
+		h.upstreams, _ = upstreams.NewMonitor(nil, upstreamOpts, h.eventBus)
+	}
+
+	h.thermal = h.newThermalMonitor(cfg)
+	if cfg.ThermalEnabled {
+		h.metricsCollector.SetThrottleSource(func() *bool { return h.thermal.Status().Throttled })
+	}
+	h.alerts = h.newAlertManager(cfg)
+	h.profiles = h.newProfileCollector()
+
+	h.oomWatcher = oom.NewWatcher(h.eventBus)
+	h.hostAudit = hostaudit.NewWatcher(h.hostAuditLog, cfg.HostAuditInterval, redactor)
+
+	h.zombies = process.NewZombieMonitor(h.processManager, process.ZombieOptions{
+		Interval:  cfg.ZombieInterval,
+		Threshold: cfg.ZombieThreshold,
+		MaxAge:    cfg.ZombieMaxAge,
+	}, h.eventBus)
+
+	if h.gpio, err = gpio.NewReader(cfg.GPIOInputs); err != nil {
+		log.Printf("GPIO inputs disabled: %v", err)
+		h.gpio, _ = gpio.NewReader(nil)
+	}
+
+	estimates, err := energy.ParseEstimates(cfg.PowerEstimates)
+	if err != nil {
+		log.Printf("Power estimates disabled: %v", err)
+	}
+	h.energy = energy.NewMeter(estimates, cfg.PowerInterval, cfg.DataDir)
+
+	h.integrityMonitor = integrity.NewMonitor(cfg.IntegrityPaths, cfg.IntegrityInterval, cfg.DataDir, h.eventBus)
+	h.exposure = exposure.NewMonitor(cfg.ExposureInterval, h.eventBus)
+	h.vulns = h.newVulnScanner()
+
+	if cfg.MQTTBroker != "" {
+		h.mqttPublisher = mqtt.NewPublisher(mqtt.Options{
+			Broker:          cfg.MQTTBroker,
+			ClientID:        cfg.MQTTClientID,
+			Username:        cfg.MQTTUsername,
+			Password:        cfg.MQTTPassword,
+			TopicPrefix:     cfg.MQTTTopicPrefix,
+			Interval:        cfg.MQTTInterval,
+			Discovery:       cfg.MQTTDiscovery,
+			DiscoveryPrefix: cfg.MQTTDiscoveryPrefix,
+		}, h.metricsCollector.GetAllMetrics, h.eventBus)
+	}
+
+	if cfg.HeartbeatURL != "" {
+		h.heartbeat = heartbeat.NewPinger(cfg.HeartbeatURL, cfg.HeartbeatInterval)
+		h.heartbeat.SetLabels(cfg.Labels)
+	}
+
+	if cfg.LogForwardURL != "" {
+		shipper, err := logship.NewShipper(logship.Options{
+			URL:           cfg.LogForwardURL,
+			Username:      cfg.LogForwardUsername,
+			Password:      cfg.LogForwardPassword,
+			Units:         cfg.LogForwardUnits,
+			Files:         cfg.LogForwardFiles,
+			Labels:        cfg.LogForwardLabels,
+			BatchSize:     cfg.LogForwardBatchSize,
+			FlushInterval: cfg.LogForwardFlush,
+		})
+		if err != nil {
+			log.Printf("Log forwarding disabled: %v", err)
+		} else {
+			h.logShipper = shipper
 		}
 	}
 
+	// Docker connects on first use and is rechecked in the background, so
+	// the daemon may start after the agent
+	auth, err := docker.NewRegistryAuth(cfg.RegistryLogins())
+	if err != nil {
+		log.Printf("Ignoring REGISTRY_AUTH: %v", err)
+		auth, _ = docker.NewRegistryAuth(nil)
+	}
+	h.registryAuth = auth
+	if cfg.DockerEnabled {
+		h.docker = docker.NewConnector(cfg.DockerCheckInterval, cfg.DockerHost, h.registryAuth)
+		h.imageUpdates = docker.NewUpdateChecker(h.docker, cfg.DockerUpdateInterval, h.eventBus)
+	}
+
 	return h
 }
 
+// useHelper runs service, power and package actions through the privilege
+// helper. The helper's sudoers rules decide what is allowed, so those
+// features are no longer reported as limited.
+func (h *Handlers) useHelper() {
+	client, err := helper.NewClient(h.cfg.HelperSudo)
+	if err != nil {
+		log.Printf("Privilege helper disabled: %v", err)
+		return
+	}
+
+	h.serviceManager.SetHelper(client.Run)
+	h.powerManager.SetShutdownCommand(func(ctx context.Context, args ...string) ([]byte, error) {
+		return client.Run(ctx, append([]string{"shutdown"}, args...)...)
+	})
+
+	// The default package tasks run apt through the helper too. The task
+	// manager shares cfg.AllowedTasks, and customised tasks are left alone.
+	defaults := config.DefaultTasks()
+	for name, cmd := range map[string]string{"apt-update": "update", "apt-upgrade": "upgrade"} {
+		if t, ok := h.cfg.AllowedTasks[name]; ok && t.Command == defaults[name].Command {
+			t.Command = client.CommandLine("apt-get", cmd)
+			h.cfg.AllowedTasks[name] = t
+		}
+	}
+
+	h.helper = client
+	h.privileges.Helper = true
+	delete(h.privileges.Limited, privilege.FeatureServices)
+	delete(h.privileges.Limited, privilege.FeaturePower)
+	delete(h.privileges.Limited, privilege.FeaturePackages)
+	delete(h.privileges.Limited, privilege.FeatureGovernor)
+}
+
 // HealthCheck handles GET /health
 func (h *Handlers) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"status":    "ok",
 		"timestamp": time.Now().UTC(),
 		"version":   "1.0.0",
-	})
+	}
+
+	if maintenance := h.powerManager.Maintenance(); maintenance.Enabled {
+		resp["maintenance"] = maintenance
+	}
+	if h.docker != nil {
+		resp["docker_available"] = h.docker.Status().Available
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 // GetInfo handles GET /api/info
 func (h *Handlers) GetInfo(c *gin.Context) {
 	hostInfo, err := system.GetHostInfo()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -83,98 +382,120 @@ func (h *Handlers) GetInfo(c *gin.Context) {
 		"arch":      hostInfo.KernelArch,
 		"uptime":    hostInfo.UptimeHuman,
 		"agent":     "hivedeck-agent",
-		"version":   "1.0.0",
+		"version":   h.cfg.Version,
+		"built":     h.cfg.BuildTime,
+		"labels":    h.cfg.Labels,
+		"machine":   h.machine.Get(),
+		"engine":    h.dockerEngine(),
+	})
+}
+
+// dockerEngine returns the container engine while it is reachable
+func (h *Handlers) dockerEngine() *docker.Engine {
+	if h.docker == nil {
+		return nil
+	}
+	return h.docker.Status().Engine
+}
+
+// GetCapabilities handles GET /api/capabilities
+func (h *Handlers) GetCapabilities(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"modules":          h.cfg.Modules(),
+		"docker_available": h.dockerManager() != nil,
+		"file_delete":      h.fileBrowser.Trash() != nil,
+		"privileges":       h.privileges,
+		"sandbox":          h.sandbox,
+		"auto_maintenance": h.cfg.MaintenanceEnabled,
+		"deploy":           h.cfg.DeployEnabled,
+		"docker_create":    h.cfg.DockerCreateEnabled,
+		"journal_backend":  h.journalReader.Backend(),
 	})
 }
 
 // GetAllMetrics handles GET /api/metrics
 func (h *Handlers) GetAllMetrics(c *gin.Context) {
-	cached, found := h.cache.Get(cache.KeyAll)
-	if found {
-		c.JSON(http.StatusOK, cached)
+	opts, ok := parseMetricsOptions(c)
+	if !ok {
 		return
 	}
 
-	metrics, err := h.metricsCollector.GetAllMetrics()
+	metrics, err := h.cache.GetOrSet(cache.KeyAll, func() (interface{}, error) {
+		return h.metricsCollector.GetAllMetrics()
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.cache.Set(cache.KeyAll, metrics)
-	c.JSON(http.StatusOK, metrics)
+	c.JSON(http.StatusOK, h.metricsResponse(metrics.(*system.AllMetrics), opts))
 }
 
 // GetCPUMetrics handles GET /api/metrics/cpu
 func (h *Handlers) GetCPUMetrics(c *gin.Context) {
-	cached, found := h.cache.Get(cache.KeyCPU)
-	if found {
-		c.JSON(http.StatusOK, cached)
+	opts, ok := parseMetricsOptions(c)
+	if !ok {
 		return
 	}
 
-	cpu, err := h.metricsCollector.GetCPUInfo()
+	cpu, err := h.cache.GetOrSet(cache.KeyCPU, func() (interface{}, error) {
+		return h.metricsCollector.GetCPUInfo()
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.cache.Set(cache.KeyCPU, cpu)
-	c.JSON(http.StatusOK, cpu)
+	c.JSON(http.StatusOK, opts.cpu(cpu.(*system.CPUInfo)))
 }
 
 // GetMemoryMetrics handles GET /api/metrics/memory
 func (h *Handlers) GetMemoryMetrics(c *gin.Context) {
-	cached, found := h.cache.Get(cache.KeyMemory)
-	if found {
-		c.JSON(http.StatusOK, cached)
-		return
-	}
-
-	memory, err := h.metricsCollector.GetMemoryInfo()
+	memory, err := h.cache.GetOrSet(cache.KeyMemory, func() (interface{}, error) {
+		return h.metricsCollector.GetMemoryInfo()
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.cache.Set(cache.KeyMemory, memory)
 	c.JSON(http.StatusOK, memory)
 }
 
 // GetDiskMetrics handles GET /api/metrics/disk
 func (h *Handlers) GetDiskMetrics(c *gin.Context) {
-	cached, found := h.cache.Get(cache.KeyDisk)
-	if found {
-		c.JSON(http.StatusOK, cached)
+	opts, ok := parseMetricsOptions(c)
+	if !ok {
 		return
 	}
 
-	disk, err := h.metricsCollector.GetDiskInfo()
+	disk, err := h.cache.GetOrSet(cache.KeyDisk, func() (interface{}, error) {
+		return h.metricsCollector.GetDiskInfo()
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.cache.Set(cache.KeyDisk, disk)
-	c.JSON(http.StatusOK, disk)
+	c.JSON(http.StatusOK, opts.disk(disk.(*system.DiskInfo)))
 }
 
 // GetNetworkMetrics handles GET /api/metrics/network
 func (h *Handlers) GetNetworkMetrics(c *gin.Context) {
-	cached, found := h.cache.Get(cache.KeyNetwork)
-	if found {
-		c.JSON(http.StatusOK, cached)
+	opts, ok := parseMetricsOptions(c)
+	if !ok {
 		return
 	}
 
-	network, err := h.metricsCollector.GetNetworkInfo()
+	network, err := h.cache.GetOrSet(cache.KeyNetwork, func() (interface{}, error) {
+		return h.metricsCollector.GetNetworkInfo()
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	h.cache.Set(cache.KeyNetwork, network)
-	c.JSON(http.StatusOK, network)
+	c.JSON(http.StatusOK, opts.network(network.(*system.NetworkInfo)))
 }
 
 // ListProcesses handles GET /api/processes
@@ -187,7 +508,7 @@ func (h *Handlers) ListProcesses(c *gin.Context) {
 
 	processes, err := h.processManager.ListTop(limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -199,7 +520,7 @@ func (h *Handlers) KillProcess(c *gin.Context) {
 	pidStr := c.Param("pid")
 	pid, err := strconv.ParseInt(pidStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pid"})
+		respondMessage(c, http.StatusBadRequest, "invalid pid")
 		return
 	}
 
@@ -210,14 +531,71 @@ func (h *Handlers) KillProcess(c *gin.Context) {
 
 	result, err := h.processManager.Kill(int32(pid), req.Signal)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	if !result.Success {
-		c.JSON(http.StatusForbidden, result)
+		respondFailed(c, http.StatusInternalServerError, result.Message, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetZombies handles GET /api/processes/zombies with zombie processes by
+// parent. refresh=true checks now instead of returning the latest check.
+func (h *Handlers) GetZombies(c *gin.Context) {
+	check := h.zombies.Report
+	if c.Query("refresh") == "true" {
+		check = h.zombies.Check
+	}
+	report, err := check()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// LimitProcess handles POST /api/processes/:pid/limit, moving an allowed
+// process into a transient scope with a CPU quota and/or memory limit
+func (h *Handlers) LimitProcess(c *gin.Context) {
+	pid, err := strconv.ParseInt(c.Param("pid"), 10, 32)
+	if err != nil || pid <= 1 {
+		respondMessage(c, http.StatusBadRequest, "invalid pid")
+		return
+	}
+
+	var req process.LimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	limits := systemd.Limits{CPUQuota: req.CPUQuota}
+	if req.MemoryMax != "" {
+		if limits.MemoryMax, err = systemd.ParseSize(req.MemoryMax); err != nil {
+			respondError(c, http.StatusBadRequest, apierror.Invalid("memory_max: %v", err))
+			return
+		}
+	}
+
+	info, err := h.processManager.Get(int32(pid))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
+	if !h.processManager.IsAllowed(info.Name) {
+		respondError(c, http.StatusForbidden, apierror.NotAllowed("limiting process '%s' is not allowed", info.Name))
+		return
+	}
+
+	result, err := h.serviceManager.LimitProcess(c.Request.Context(), uint32(pid), limits)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.recordAudit(c, "process.limit", info.Name, true, result.Message)
 
 	c.JSON(http.StatusOK, result)
 }
@@ -226,78 +604,133 @@ func (h *Handlers) KillProcess(c *gin.Context) {
 func (h *Handlers) ListServices(c *gin.Context) {
 	services, err := h.serviceManager.List(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, services)
 }
 
+// serviceLogStatsTimeout bounds counting a unit's errors and warnings for
+// GET /api/services/:name, which are left out when it runs over
+const serviceLogStatsTimeout = 5 * time.Second
+
 // GetService handles GET /api/services/:name
 func (h *Handlers) GetService(c *gin.Context) {
 	name := c.Param("name")
 
 	service, err := h.serviceManager.Get(c.Request.Context(), name)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, service)
+	if c.Query("logs") != "false" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), serviceLogStatsTimeout)
+		defer cancel()
+		if service.Logs, err = h.journalReader.LevelStats(ctx, name); err != nil {
+			log.Printf("Failed to count log levels for %s: %v", name, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, serviceDetail{ServiceInfo: service, Schedules: h.schedules.List(name).Schedules})
 }
 
 // StartService handles POST /api/services/:name/start
 func (h *Handlers) StartService(c *gin.Context) {
-	name := c.Param("name")
+	h.serviceAction(c, "start", h.serviceManager.Start)
+}
 
-	result, err := h.serviceManager.Start(c.Request.Context(), name)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+// StopService handles POST /api/services/:name/stop
+func (h *Handlers) StopService(c *gin.Context) {
+	h.serviceAction(c, "stop", h.serviceManager.Stop)
+}
 
-	if !result.Success {
-		c.JSON(http.StatusForbidden, result)
-		return
-	}
+// RestartService handles POST /api/services/:name/restart
+func (h *Handlers) RestartService(c *gin.Context) {
+	h.serviceAction(c, "restart", h.serviceManager.Restart)
+}
 
-	c.JSON(http.StatusOK, result)
+// EnableService handles POST /api/services/:name/enable
+func (h *Handlers) EnableService(c *gin.Context) {
+	h.serviceAction(c, "enable", h.serviceManager.Enable)
 }
 
-// StopService handles POST /api/services/:name/stop
-func (h *Handlers) StopService(c *gin.Context) {
-	name := c.Param("name")
+// DisableService handles POST /api/services/:name/disable
+func (h *Handlers) DisableService(c *gin.Context) {
+	h.serviceAction(c, "disable", h.serviceManager.Disable)
+}
+
+// MaskService handles POST /api/services/:name/mask
+func (h *Handlers) MaskService(c *gin.Context) {
+	h.serviceAction(c, "mask", h.serviceManager.Mask)
+}
+
+// UnmaskService handles POST /api/services/:name/unmask
+func (h *Handlers) UnmaskService(c *gin.Context) {
+	h.serviceAction(c, "unmask", h.serviceManager.Unmask)
+}
+
+// ReloadSystemd handles POST /api/services/daemon-reload
+func (h *Handlers) ReloadSystemd(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.ServiceActionTimeout)
+	defer cancel()
 
-	result, err := h.serviceManager.Stop(c.Request.Context(), name)
+	err := h.serviceManager.DaemonReload(ctx)
+	msg := "systemd reloaded"
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		msg = err.Error()
 	}
-
-	if !result.Success {
-		c.JSON(http.StatusForbidden, result)
+	h.recordAudit(c, "service.daemon-reload", "systemd", err == nil, msg)
+	h.publishAction("service.daemon-reload", "systemd", err == nil, msg, nil)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, gin.H{"message": msg})
 }
 
-// RestartService handles POST /api/services/:name/restart
-func (h *Handlers) RestartService(c *gin.Context) {
+func (h *Handlers) serviceAction(c *gin.Context, action string, do func(context.Context, string) (*systemd.ServiceAction, error)) {
 	name := c.Param("name")
 
-	result, err := h.serviceManager.Restart(c.Request.Context(), name)
+	// Reject early so async requests fail with 403 instead of a failed operation
+	if !h.serviceManager.IsAllowed(name) {
+		respondError(c, http.StatusForbidden, apierror.NotAllowed("service '%s' is not in allowed list", name))
+		return
+	}
+
+	h.runOperation(c, "service."+action, name, h.cfg.ServiceActionTimeout, func(ctx context.Context) (interface{}, error) {
+		result, err := do(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if result.Pending {
+			return result, nil
+		}
+		h.publishAction("service."+action, "systemd", result.Success, result.Message, result)
+		if !result.Success {
+			return result, actionError(ctx, result.Message)
+		}
+		return result, nil
+	})
+}
+
+// GetServiceJob handles GET /api/services/:name/jobs/:id
+func (h *Handlers) GetServiceJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondMessage(c, http.StatusBadRequest, "invalid job id")
 		return
 	}
 
-	if !result.Success {
-		c.JSON(http.StatusForbidden, result)
+	job, err := h.serviceManager.GetJob(c.Request.Context(), c.Param("name"), id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, job)
 }
 
 // GetLogs handles GET /api/logs/query
@@ -322,10 +755,14 @@ func (h *Handlers) GetLogs(c *gin.Context) {
 
 	query.Since = c.Query("since")
 	query.Until = c.Query("until")
+	query.After = c.Query("after")
+	query.Before = c.Query("before")
+	query.AllFields = c.Query("all_fields") == "true"
+	query.Structured = c.Query("structured") == "true"
 
 	logs, err := h.journalReader.Query(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -344,7 +781,7 @@ func (h *Handlers) GetUnitLogs(c *gin.Context) {
 
 	logs, err := h.journalReader.GetRecentLogs(c.Request.Context(), unit, lines)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -354,9 +791,11 @@ func (h *Handlers) GetUnitLogs(c *gin.Context) {
 	})
 }
 
-// StreamLogs handles GET /api/logs (SSE)
+// StreamLogs handles GET /api/logs (SSE). structured=true parses JSON
+// messages; stack traces are not merged, as that would hold entries back.
 func (h *Handlers) StreamLogs(c *gin.Context) {
 	unit := c.Query("unit")
+	structured := c.Query("structured") == "true"
 
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
@@ -369,13 +808,16 @@ func (h *Handlers) StreamLogs(c *gin.Context) {
 	entryChan := make(chan systemd.JournalEntry, 100)
 
 	if err := h.journalReader.Follow(ctx, unit, entryChan); err != nil {
-		c.SSEvent("error", gin.H{"error": err.Error()})
+		c.SSEvent("error", apierror.New(http.StatusInternalServerError, err.Error(), nil))
 		return
 	}
 
 	c.Stream(func(w io.Writer) bool {
 		select {
 		case entry := <-entryChan:
+			if structured {
+				entry = systemd.Structure(entry)
+			}
 			data, _ := json.Marshal(entry)
 			c.SSEvent("log", string(data))
 			return true
@@ -385,27 +827,58 @@ func (h *Handlers) StreamLogs(c *gin.Context) {
 	})
 }
 
-// StreamEvents handles GET /api/events (SSE metrics)
+// GetLogForwarding handles GET /api/logs/forwarding
+func (h *Handlers) GetLogForwarding(c *gin.Context) {
+	if h.logShipper == nil {
+		c.JSON(http.StatusOK, logship.Status{Enabled: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.logShipper.Status())
+}
+
+// StreamEvents handles GET /api/events (SSE metrics). Agent events are
+// sent as they happen; metrics follow ?interval=.
 func (h *Handlers) StreamEvents(c *gin.Context) {
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
 
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
 	ctx := c.Request.Context()
+	fields := ParseFields(c.Query("fields"))
+	opts, ok := parseMetricsOptions(c)
+	if !ok {
+		return
+	}
+
+	agentEvents, unsubscribe := h.eventBus.Subscribe()
+	defer unsubscribe()
+	samples, unsubscribeMetrics := h.metricsHub.Subscribe()
+	defer unsubscribeMetrics()
+	throttle := sampleThrottle{interval: opts.interval}
 
 	c.Stream(func(w io.Writer) bool {
 		select {
-		case <-ticker.C:
-			metrics, err := h.metricsCollector.GetAllMetrics()
-			if err != nil {
-				c.SSEvent("error", gin.H{"error": err.Error()})
+		case event := <-agentEvents:
+			data, _ := json.Marshal(event)
+			c.SSEvent("event", string(data))
+			return true
+		case sample := <-samples:
+			if !throttle.allow(time.Now()) {
+				return true
+			}
+			if sample.err != nil {
+				c.SSEvent("error", apierror.New(http.StatusInternalServerError, sample.err.Error(), nil))
 				return true
 			}
-			data, _ := json.Marshal(metrics)
+			payload := h.metricsResponse(sample.metrics, opts)
+			if len(fields) > 0 {
+				if sparse, err := SelectFields(payload, fields); err == nil {
+					payload = sparse
+				}
+			}
+			data, _ := json.Marshal(payload)
 			c.SSEvent("metrics", string(data))
 			return true
 		case <-ctx.Done():
@@ -416,18 +889,47 @@ func (h *Handlers) StreamEvents(c *gin.Context) {
 
 // Docker handlers
 
+// dockerManager returns the Docker client, or nil when Docker is disabled or
+// the daemon is not reachable
+func (h *Handlers) dockerManager() *docker.Manager {
+	if h.docker == nil {
+		return nil
+	}
+	return h.docker.Manager()
+}
+
+// GetDockerStatus handles GET /api/docker/status
+func (h *Handlers) GetDockerStatus(c *gin.Context) {
+	if h.docker == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false, "available": false})
+		return
+	}
+
+	// Manager retries a connection when the daemon was down
+	h.docker.Manager()
+	status := h.docker.Status()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":    true,
+		"available":  status.Available,
+		"last_check": status.LastCheck,
+		"error":      status.Error,
+		"engine":     status.Engine,
+	})
+}
+
 // ListContainers handles GET /api/docker/containers
 func (h *Handlers) ListContainers(c *gin.Context) {
-	if h.dockerManager == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "docker not available"})
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
 		return
 	}
 
 	all := c.Query("all") == "true"
 
-	containers, err := h.dockerManager.ListContainers(c.Request.Context(), all)
+	containers, err := manager.ListContainers(c.Request.Context(), all)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -436,80 +938,90 @@ func (h *Handlers) ListContainers(c *gin.Context) {
 
 // GetContainer handles GET /api/docker/containers/:id
 func (h *Handlers) GetContainer(c *gin.Context) {
-	if h.dockerManager == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "docker not available"})
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
 		return
 	}
 
 	id := c.Param("id")
 
-	container, err := h.dockerManager.GetContainer(c.Request.Context(), id)
+	container, err := manager.GetContainer(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, container)
 }
 
-// StartContainer handles POST /api/docker/containers/:id/start
-func (h *Handlers) StartContainer(c *gin.Context) {
-	if h.dockerManager == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "docker not available"})
+// GetImageUpdates handles GET /api/docker/updates with whether each running
+// container's image tag has a newer image. refresh=true checks now instead
+// of returning the latest check.
+func (h *Handlers) GetImageUpdates(c *gin.Context) {
+	if h.imageUpdates == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
 		return
 	}
 
-	id := c.Param("id")
-
-	result, err := h.dockerManager.StartContainer(c.Request.Context(), id)
+	check := h.imageUpdates.Report
+	if c.Query("refresh") == "true" {
+		check = h.imageUpdates.Check
+	}
+	report, err := check(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
+	c.JSON(http.StatusOK, report)
+}
 
-	c.JSON(http.StatusOK, result)
+// StartContainer handles POST /api/docker/containers/:id/start
+func (h *Handlers) StartContainer(c *gin.Context) {
+	h.containerAction(c, "start", (*docker.Manager).StartContainer)
 }
 
 // StopContainer handles POST /api/docker/containers/:id/stop
 func (h *Handlers) StopContainer(c *gin.Context) {
-	if h.dockerManager == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "docker not available"})
-		return
-	}
-
-	id := c.Param("id")
-
-	result, err := h.dockerManager.StopContainer(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, result)
+	h.containerAction(c, "stop", (*docker.Manager).StopContainer)
 }
 
 // RestartContainer handles POST /api/docker/containers/:id/restart
 func (h *Handlers) RestartContainer(c *gin.Context) {
-	if h.dockerManager == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "docker not available"})
+	h.containerAction(c, "restart", (*docker.Manager).RestartContainer)
+}
+
+func (h *Handlers) containerAction(c *gin.Context, action string, do func(*docker.Manager, context.Context, string) (*docker.ContainerAction, error)) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
 		return
 	}
 
 	id := c.Param("id")
+	h.runOperation(c, "container."+action, id, containerActionTimeout, h.containerJob(manager, action, id, do))
+}
 
-	result, err := h.dockerManager.RestartContainer(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// containerJob runs a container action, publishing its outcome
+func (h *Handlers) containerJob(manager *docker.Manager, action, id string, do func(*docker.Manager, context.Context, string) (*docker.ContainerAction, error)) func(context.Context) (interface{}, error) {
+	return func(ctx context.Context) (interface{}, error) {
+		result, err := do(manager, ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		h.publishAction("container."+action, "docker", result.Success, fmt.Sprintf("%s: %s", id, result.Message), result)
+		if !result.Success {
+			return result, actionError(ctx, result.Message)
+		}
+		return result, nil
 	}
-
-	c.JSON(http.StatusOK, result)
 }
 
 // GetContainerLogs handles GET /api/docker/containers/:id/logs
 func (h *Handlers) GetContainerLogs(c *gin.Context) {
-	if h.dockerManager == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "docker not available"})
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
 		return
 	}
 
@@ -522,9 +1034,9 @@ func (h *Handlers) GetContainerLogs(c *gin.Context) {
 		Timestamps: c.Query("timestamps") == "true",
 	}
 
-	logs, err := h.dockerManager.GetContainerLogs(c.Request.Context(), id, opts)
+	logs, err := manager.GetContainerLogs(c.Request.Context(), id, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -534,69 +1046,354 @@ func (h *Handlers) GetContainerLogs(c *gin.Context) {
 	})
 }
 
-// File browser handlers
-
-// GetAllowedPaths handles GET /api/files/paths
-func (h *Handlers) GetAllowedPaths(c *gin.Context) {
-	paths := h.fileBrowser.GetAllowedPaths()
-	c.JSON(http.StatusOK, gin.H{
-		"paths": paths,
-	})
-}
+// StreamContainerLogs handles GET /api/docker/containers/:id/logs/stream
+// (SSE). Each line is a log event with its stream, stdout or stderr; an
+// end event follows when the container stops.
+func (h *Handlers) StreamContainerLogs(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
 
-// ListDirectory handles GET /api/files
-func (h *Handlers) ListDirectory(c *gin.Context) {
-	path := c.DefaultQuery("path", "/")
+	id := c.Param("id")
+	opts := docker.LogOptions{
+		Tail:       c.DefaultQuery("tail", "50"),
+		Since:      c.Query("since"),
+		Timestamps: c.Query("timestamps") == "true",
+	}
 
-	listing, err := h.fileBrowser.ListDirectory(path)
-	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "access denied: path not in allowed list" {
-			status = http.StatusForbidden
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	lines := make(chan docker.LogLine, 100)
+	if err := manager.StreamContainerLogs(ctx, id, opts, lines); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, listing)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				data, _ := json.Marshal(gin.H{"id": id})
+				c.SSEvent("end", string(data))
+				return false
+			}
+			data, _ := json.Marshal(line)
+			c.SSEvent("log", string(data))
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
 }
 
-// GetFileContent handles GET /api/files/content
-func (h *Handlers) GetFileContent(c *gin.Context) {
-	path := c.Query("path")
-	if path == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+// GetContainerStats handles GET /api/docker/containers/:id/stats
+func (h *Handlers) GetContainerStats(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
 		return
 	}
 
-	content, err := h.fileBrowser.ReadFile(path)
+	stats, err := manager.GetContainerStats(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "access denied: path not in allowed list" {
-			status = http.StatusForbidden
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
-
-	c.JSON(http.StatusOK, content)
+	c.JSON(http.StatusOK, stats)
 }
 
-// GetDiskUsage handles GET /api/files/diskusage
+// StreamContainerStats handles GET /api/docker/containers/:id/stats/stream
+// (SSE), sending a stats event about once a second and an end event when
+// the container stops
+func (h *Handlers) StreamContainerStats(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	id := c.Param("id")
+	ctx := c.Request.Context()
+	samples := make(chan docker.ContainerStats, 1)
+	if err := manager.StreamContainerStats(ctx, id, samples); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case stats, ok := <-samples:
+			if !ok {
+				data, _ := json.Marshal(gin.H{"id": id})
+				c.SSEvent("end", string(data))
+				return false
+			}
+			data, _ := json.Marshal(stats)
+			c.SSEvent("stats", string(data))
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// GetAllContainerStats handles GET /api/docker/stats
+func (h *Handlers) GetAllContainerStats(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	stats, err := manager.AllContainerStats(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"containers": stats,
+		"count":      len(stats),
+	})
+}
+
+// File browser handlers
+
+// GetAllowedPaths handles GET /api/files/paths
+func (h *Handlers) GetAllowedPaths(c *gin.Context) {
+	paths := h.fileBrowser.GetAllowedPaths()
+	c.JSON(http.StatusOK, gin.H{
+		"paths": paths,
+	})
+}
+
+// ListDirectory handles GET /api/files
+func (h *Handlers) ListDirectory(c *gin.Context) {
+	path := c.DefaultQuery("path", "/")
+
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	opts := files.ListOptions{
+		Offset:     offset,
+		Limit:      limit,
+		Sort:       c.Query("sort"),
+		Desc:       c.Query("order") == "desc",
+		HideHidden: c.Query("hidden") == "false",
+	}
+
+	listing, err := h.fileBrowser.ListDirectory(path, opts)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listing)
+}
+
+// GetFileContent handles GET /api/files/content
+func (h *Handlers) GetFileContent(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		respondMessage(c, http.StatusBadRequest, "path is required")
+		return
+	}
+
+	content, err := h.fileBrowser.ReadFile(path)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, content)
+}
+
+// PreviewFile handles GET /api/files/preview. Images return a JPEG thumbnail
+// and archives return a JSON listing of their contents.
+func (h *Handlers) PreviewFile(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		respondMessage(c, http.StatusBadRequest, "path is required")
+		return
+	}
+
+	fail := func(err error) {
+		respondError(c, http.StatusInternalServerError, err)
+	}
+
+	if !h.fileBrowser.IsPathAllowed(path) {
+		fail(fmt.Errorf("access denied: path not in allowed list"))
+		return
+	}
+
+	switch _, category := files.DetectContentType(path); category {
+	case files.CategoryImage:
+		size, _ := strconv.Atoi(c.Query("size"))
+		thumb, err := h.fileBrowser.Thumbnail(path, size)
+		if err != nil {
+			fail(err)
+			return
+		}
+		c.Header("Cache-Control", "private, max-age=60")
+		c.Data(http.StatusOK, "image/jpeg", thumb)
+	case files.CategoryArchive:
+		listing, err := h.fileBrowser.ListArchive(path)
+		if err != nil {
+			fail(err)
+			return
+		}
+		c.JSON(http.StatusOK, listing)
+	default:
+		fail(files.ErrPreviewUnsupported)
+	}
+}
+
+// DiffFiles handles GET /api/files/diff?a=&b= and POST /api/files/diff,
+// which compares file a against either file b or the supplied content
+func (h *Handlers) DiffFiles(c *gin.Context) {
+	var req struct {
+		A       string  `json:"a"`
+		B       string  `json:"b"`
+		Content *string `json:"content"`
+	}
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	} else {
+		req.A, req.B = c.Query("a"), c.Query("b")
+	}
+
+	if req.A == "" || (req.B == "" && req.Content == nil) {
+		respondMessage(c, http.StatusBadRequest, "a and either b or content are required")
+		return
+	}
+
+	var diff *files.FileDiff
+	var err error
+	if req.Content != nil {
+		diff, err = h.fileBrowser.DiffContent(req.A, *req.Content)
+	} else {
+		diff, err = h.fileBrowser.Diff(req.A, req.B)
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// DeleteFile handles DELETE /api/files
+func (h *Handlers) DeleteFile(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		respondMessage(c, http.StatusBadRequest, "path is required")
+		return
+	}
+
+	item, err := h.fileBrowser.Delete(path)
+	if err != nil {
+		h.recordAudit(c, "file.delete", path, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, "file.delete", item.OriginalPath, true, "moved to trash as "+item.ID)
+	c.JSON(http.StatusOK, item)
+}
+
+// ListTrash handles GET /api/files/trash
+func (h *Handlers) ListTrash(c *gin.Context) {
+	trash := h.fileBrowser.Trash()
+	if trash == nil {
+		respondError(c, http.StatusForbidden, files.ErrDeleteDisabled)
+		return
+	}
+
+	list, err := trash.List()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// RestoreTrashItem handles POST /api/files/trash/:id/restore
+func (h *Handlers) RestoreTrashItem(c *gin.Context) {
+	id := c.Param("id")
+
+	item, err := h.fileBrowser.Restore(id)
+	if err != nil {
+		h.recordAudit(c, "file.restore", id, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, "file.restore", item.OriginalPath, true, "restored from trash")
+	c.JSON(http.StatusOK, item)
+}
+
+// PurgeTrashItem handles DELETE /api/files/trash/:id
+func (h *Handlers) PurgeTrashItem(c *gin.Context) {
+	trash := h.fileBrowser.Trash()
+	if trash == nil {
+		respondError(c, http.StatusForbidden, files.ErrDeleteDisabled)
+		return
+	}
+
+	id := c.Param("id")
+	if err := trash.Purge(id); err != nil {
+		h.recordAudit(c, "file.purge", id, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, "file.purge", id, true, "permanently deleted")
+	c.JSON(http.StatusOK, gin.H{"id": id, "purged": true})
+}
+
+// EmptyTrash handles DELETE /api/files/trash
+func (h *Handlers) EmptyTrash(c *gin.Context) {
+	trash := h.fileBrowser.Trash()
+	if trash == nil {
+		respondError(c, http.StatusForbidden, files.ErrDeleteDisabled)
+		return
+	}
+
+	purged, err := trash.PurgeAll()
+	if err != nil {
+		h.recordAudit(c, "file.purge", "all", false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, "file.purge", "all", true, fmt.Sprintf("%d items permanently deleted", purged))
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+// GetDiskUsage handles GET /api/files/diskusage
 func (h *Handlers) GetDiskUsage(c *gin.Context) {
 	path := c.Query("path")
 	if path == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		respondMessage(c, http.StatusBadRequest, "path is required")
 		return
 	}
 
 	usage, err := h.fileBrowser.GetDiskUsage(path)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "access denied: path not in allowed list" {
-			status = http.StatusForbidden
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -618,36 +1415,874 @@ func (h *Handlers) RunTask(c *gin.Context) {
 	// Check if task exists
 	task, err := h.taskManager.Get(name)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
-	// Warn about dangerous tasks
+	run := h.taskRunner(name)
+
+	// Dangerous tasks need confirmation or a second party's approval
 	if task.Dangerous {
-		confirm := c.Query("confirm")
-		if confirm != "true" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   fmt.Sprintf("task '%s' is dangerous, add ?confirm=true to execute", name),
-				"task":    task,
-			})
+		impact := fmt.Sprintf("Runs `%s` on %s: %s", task.Command, hostname(), task.Description)
+		if !h.guardDestructive(c, "task.run", name, impact, taskTimeout, run) {
+			return
+		}
+	}
+
+	h.runOperation(c, "task.run", name, taskTimeout, run)
+}
+
+// taskRunner returns a job that runs a task, failing when it exits non-zero
+func (h *Handlers) taskRunner(name string) func(ctx context.Context) (interface{}, error) {
+	return func(ctx context.Context) (interface{}, error) {
+		result, err := h.taskManager.Run(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Success {
+			return result, actionError(ctx, fmt.Sprintf("task exited with code %d", result.ExitCode))
+		}
+		return result, nil
+	}
+}
+
+// System power handlers
+
+// RebootSystem handles POST /api/system/reboot
+func (h *Handlers) RebootSystem(c *gin.Context) {
+	h.schedulePower(c, power.ActionReboot)
+}
+
+// ShutdownSystem handles POST /api/system/shutdown
+func (h *Handlers) ShutdownSystem(c *gin.Context) {
+	h.schedulePower(c, power.ActionShutdown)
+}
+
+func (h *Handlers) schedulePower(c *gin.Context, action power.Action) {
+	var req power.Request
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	when := req.When
+	if when == "" {
+		when = "now"
+	}
+	impact := fmt.Sprintf("Reboots %s (%s). Services and sessions will be interrupted.", hostname(), when)
+	if action == power.ActionShutdown {
+		impact = fmt.Sprintf("Shuts down %s (%s). The host stays offline until it is powered on manually.", hostname(), when)
+	}
+	run := func(ctx context.Context) (interface{}, error) {
+		var result *power.ActionResult
+		var err error
+		if action == power.ActionShutdown {
+			result, err = h.powerManager.Shutdown(ctx, req)
+		} else {
+			result, err = h.powerManager.Reboot(ctx, req)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !result.Success {
+			return result, errors.New(result.Message)
+		}
+		return result, nil
+	}
+	if !h.guardDestructive(c, "system."+string(action), when, impact, time.Minute, run) {
+		return
+	}
+
+	var result *power.ActionResult
+	var err error
+	if action == power.ActionShutdown {
+		result, err = h.powerManager.Shutdown(c.Request.Context(), req)
+	} else {
+		result, err = h.powerManager.Reboot(c.Request.Context(), req)
+	}
+	if err != nil {
+		h.recordAudit(c, "system."+string(action), req.When, false, err.Error())
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	h.recordAudit(c, "system."+string(action), req.When, result.Success, result.Message)
+
+	if !result.Success {
+		respondFailed(c, http.StatusInternalServerError, result.Message, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetPowerStatus handles GET /api/system/power
+func (h *Handlers) GetPowerStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.powerManager.Status())
+}
+
+// CancelPowerAction handles POST /api/system/power/cancel
+func (h *Handlers) CancelPowerAction(c *gin.Context) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	result, err := h.powerManager.Cancel(c.Request.Context(), req.Message)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, "system.power.cancel", string(result.Action), result.Success, result.Message)
+
+	if !result.Success {
+		respondFailed(c, http.StatusConflict, result.Message, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetMaintenance handles GET /api/system/maintenance
+func (h *Handlers) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, h.powerManager.Maintenance())
+}
+
+// EnableMaintenance handles POST /api/system/maintenance
+func (h *Handlers) EnableMaintenance(c *gin.Context) {
+	var req struct {
+		Reason string    `json:"reason"`
+		Until  time.Time `json:"until"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	maintenance := h.powerManager.SetMaintenance(req.Reason, req.Until)
+	h.recordAudit(c, "system.maintenance.enable", req.Reason, true, "maintenance mode enabled")
+
+	c.JSON(http.StatusOK, maintenance)
+}
+
+// DisableMaintenance handles DELETE /api/system/maintenance
+func (h *Handlers) DisableMaintenance(c *gin.Context) {
+	h.powerManager.ClearMaintenance()
+	h.recordAudit(c, "system.maintenance.disable", "", true, "maintenance mode disabled")
+
+	c.JSON(http.StatusOK, h.powerManager.Maintenance())
+}
+
+// Diagnostics handlers
+
+// ProbePort handles GET /api/diagnostics/port
+func (h *Handlers) ProbePort(c *gin.Context) {
+	host := c.Query("host")
+	if host == "" {
+		respondMessage(c, http.StatusBadRequest, "host is required")
+		return
+	}
+
+	port, err := strconv.Atoi(c.Query("port"))
+	if err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid port")
+		return
+	}
+
+	req := diagnostics.ProbeRequest{
+		Host:       host,
+		Port:       port,
+		TLS:        diagnostics.IsTLSPort(port),
+		ServerName: c.Query("servername"),
+	}
+
+	if t := c.Query("tls"); t != "" {
+		req.TLS = t == "true"
+	}
+
+	if t := c.Query("timeout"); t != "" {
+		if d, err := time.ParseDuration(t); err == nil {
+			req.Timeout = d
+		}
+	}
+
+	result, err := h.prober.Probe(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RunSpeedTest handles POST /api/speedtest
+func (h *Handlers) RunSpeedTest(c *gin.Context) {
+	if h.speedtestRunner.Backend() == "" {
+		respondMessage(c, http.StatusServiceUnavailable, "no speed test client found (install speedtest-cli or iperf3)")
+		return
+	}
+
+	job, started := h.jobManager.SubmitUnique("speedtest", h.speedtestRunner.Backend(), 5*time.Minute, func(ctx context.Context) (interface{}, error) {
+		result, err := h.speedtestRunner.Run(ctx)
+		if err != nil {
+			// A typed nil would be reported as a non-nil result
+			return nil, err
+		}
+		return result, nil
+	})
+	if !started {
+		c.JSON(http.StatusConflict, apierror.New(http.StatusConflict, "a speed test is already running",
+			map[string]interface{}{"job": job}))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetSpeedTestHistory handles GET /api/speedtest/history
+func (h *Handlers) GetSpeedTestHistory(c *gin.Context) {
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil {
+			limit = n
+		}
+	}
+
+	c.JSON(http.StatusOK, h.speedtestRunner.History(limit))
+}
+
+// Operation handlers
+
+const (
+	// MaxOperationTimeout caps the ?timeout= a client may request
+	MaxOperationTimeout = 30 * time.Minute
+
+	containerActionTimeout = time.Minute
+	taskTimeout            = 5 * time.Minute
+)
+
+// runOperation runs a slow action with a per-request timeout (?timeout=,
+// which replaces the route group timeout, or defaultTimeout). By default the handler waits for the result.
+// With ?async=true or "Prefer: respond-async" it responds 202 with an
+// operation to poll at /api/operations/:id instead.
+func (h *Handlers) runOperation(c *gin.Context, opType, target string, defaultTimeout time.Duration, fn jobs.Func) {
+	timeout := defaultTimeout
+	parent := c.Request.Context()
+	if t := c.Query("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d <= 0 || d > MaxOperationTimeout {
+			respondMessage(c, http.StatusBadRequest, fmt.Sprintf("invalid timeout: use a duration up to %s", MaxOperationTimeout))
 			return
 		}
+		// An explicit timeout replaces the route group's
+		timeout = d
+		parent = baseContext(c)
+	} else if deadline, ok := parent.Deadline(); ok && time.Until(deadline) < timeout {
+		// The operation's own timeout outranks a shorter route group's, e.g.
+		// an image pull under the docker group's
+		parent = baseContext(c)
+	}
+
+	if c.Query("async") == "true" || strings.Contains(c.GetHeader("Prefer"), "respond-async") {
+		op := h.jobManager.Submit(opType, target, timeout, fn)
+		c.Header("Location", "/api/operations/"+op.ID)
+		c.JSON(http.StatusAccepted, op)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	result, err := fn(ctx)
+
+	// A task that ran and exited non-zero is reported by its result, as it
+	// was before tasks became operations. Failing to run it is still an error.
+	if task, ok := result.(*tasks.TaskResult); ok && err != nil && ctx.Err() == nil && task.ExitCode > 0 {
+		c.JSON(http.StatusOK, result)
+		return
 	}
 
-	// Run with 5 minute timeout
-	result, err := h.taskManager.RunWithTimeout(name, 5*time.Minute)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if result != nil {
+			respondFailed(c, apierror.Status(err, http.StatusInternalServerError), err.Error(), result)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// A systemd job that outlived the wait is accepted, not failed
+	if action, ok := result.(*systemd.ServiceAction); ok && action.Pending {
+		c.Header("Location", fmt.Sprintf("/api/services/%s/jobs/%d", action.Name, action.JobID))
+		c.JSON(http.StatusAccepted, result)
 		return
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
+// publishAction records a service or container action on the event bus
+func (h *Handlers) publishAction(eventType, source string, success bool, message string, data interface{}) {
+	severity := events.SeverityInfo
+	if !success {
+		severity = events.SeverityWarning
+	}
+	h.eventBus.Publish(events.Event{
+		Type:     eventType,
+		Severity: severity,
+		Source:   source,
+		Message:  message,
+		Data:     data,
+	})
+}
+
+// actionError reports a failed action, noting when it failed because ctx
+// timed out or was cancelled
+func actionError(ctx context.Context, message string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return errors.New(message)
+}
+
+// ListJobs handles GET /api/jobs and GET /api/operations
+func (h *Handlers) ListJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, h.jobManager.List(c.Query("type")))
+}
+
+// GetJob handles GET /api/jobs/:id and GET /api/operations/:id
+func (h *Handlers) GetJob(c *gin.Context) {
+	job, err := h.jobManager.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// Agent cache handlers
+
+// GetCacheStats handles GET /api/agent/cache
+func (h *Handlers) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cache.Stats())
+}
+
+// InvalidateCache handles DELETE /api/agent/cache
+func (h *Handlers) InvalidateCache(c *gin.Context) {
+	key := c.Query("key")
+	prefix := c.Query("prefix")
+
+	removed := 0
+	switch {
+	case key != "":
+		if h.cache.Delete(key) {
+			removed = 1
+		}
+	case prefix != "":
+		removed = h.cache.DeletePrefix(prefix)
+	default:
+		removed = h.cache.Stats().Size
+		h.cache.Clear()
+	}
+
+	if c.Query("reset_stats") == "true" {
+		h.cache.ResetStats()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "cache invalidated",
+		"removed": removed,
+	})
+}
+
+// SetCacheTTL handles PUT /api/agent/cache/ttl. It changes one key's TTL
+// as PUT /api/settings/cache does, and is saved the same way.
+func (h *Handlers) SetCacheTTL(c *gin.Context) {
+	var req struct {
+		Key string `json:"key" binding:"required"`
+		TTL string `json:"ttl"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: key is required")
+		return
+	}
+
+	ttls, err := parseCacheTTLs(map[string]string{req.Key: req.TTL})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.saveCacheTTLs(nil, ttls); err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"key": req.Key,
+		"ttl": h.cache.TTL(req.Key).String(),
+	})
+}
+
+// GetCacheSettings handles GET /api/settings/cache
+func (h *Handlers) GetCacheSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cacheSettings())
+}
+
+func (h *Handlers) cacheSettings() gin.H {
+	ttls := make(map[string]string)
+	for key, ttl := range h.cache.KeyTTLs() {
+		ttls[key] = ttl.String()
+	}
+	return gin.H{
+		"default_ttl": h.cache.TTL("").String(),
+		"ttls":        ttls,
+	}
+}
+
+// UpdateCacheSettings handles PUT /api/settings/cache. TTLs apply right away
+// and are saved to .env as CACHE_TTL and CACHE_TTLS. Keys missing from ttls
+// are left unchanged; an empty TTL removes a key's override.
+func (h *Handlers) UpdateCacheSettings(c *gin.Context) {
+	var req struct {
+		DefaultTTL *string           `json:"default_ttl"`
+		TTLs       map[string]string `json:"ttls"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	// Parse everything first so a bad value changes nothing
+	var defaultTTL *time.Duration
+	if req.DefaultTTL != nil {
+		d, err := time.ParseDuration(*req.DefaultTTL)
+		if err != nil || d <= 0 {
+			respondMessage(c, http.StatusBadRequest, "default_ttl must be a positive duration such as 2s")
+			return
+		}
+		defaultTTL = &d
+	}
+	ttls, err := parseCacheTTLs(req.TTLs)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.saveCacheTTLs(defaultTTL, ttls); err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
+		return
+	}
+
+	resp := h.cacheSettings()
+	resp["message"] = "Cache settings updated"
+	c.JSON(http.StatusOK, resp)
+}
+
+// parseCacheTTLs parses per-key TTLs. An empty TTL, which removes a key's
+// override, parses as 0.
+func parseCacheTTLs(values map[string]string) (map[string]time.Duration, error) {
+	if err := checkMap("ttls", values); err != nil {
+		return nil, apierror.Invalid("%s", err)
+	}
+
+	ttls := make(map[string]time.Duration, len(values))
+	for key, value := range values {
+		if value == "" {
+			ttls[key] = 0
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, apierror.Invalid("ttl for %s must be a positive duration", key)
+		}
+		ttls[key] = d
+	}
+	return ttls, nil
+}
+
+// saveCacheTTLs applies a new default TTL, when set, and per-key TTLs, and
+// saves them to .env as CACHE_TTL and CACHE_TTLS
+func (h *Handlers) saveCacheTTLs(defaultTTL *time.Duration, ttls map[string]time.Duration) error {
+	updates := make(map[string]string)
+	if defaultTTL != nil {
+		h.cfg.CacheTTL = *defaultTTL
+		h.cache.SetDefaultTTL(*defaultTTL)
+		updates["CACHE_TTL"] = defaultTTL.String()
+	}
+	if len(ttls) > 0 {
+		for key, ttl := range ttls {
+			h.cache.SetKeyTTL(key, ttl)
+			h.cache.Delete(key)
+		}
+		h.cfg.CacheTTLs = h.cache.KeyTTLs()
+		pairs := make(map[string]string, len(h.cfg.CacheTTLs))
+		for key, ttl := range h.cfg.CacheTTLs {
+			pairs[key] = ttl.String()
+		}
+		updates["CACHE_TTLS"] = joinMap(pairs)
+	}
+	return h.cfg.SaveEnv(updates)
+}
+
+// GetHeartbeatStatus handles GET /api/heartbeat
+func (h *Handlers) GetHeartbeatStatus(c *gin.Context) {
+	if h.heartbeat == nil {
+		c.JSON(http.StatusOK, heartbeat.Status{Enabled: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.heartbeat.Status())
+}
+
+// GetMQTTStatus handles GET /api/mqtt
+func (h *Handlers) GetMQTTStatus(c *gin.Context) {
+	if h.mqttPublisher == nil {
+		c.JSON(http.StatusOK, mqtt.Status{Enabled: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.mqttPublisher.Status())
+}
+
+// Event handlers
+
+// GetRecentEvents handles GET /api/events/recent
+func (h *Handlers) GetRecentEvents(c *gin.Context) {
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil {
+			limit = n
+		}
+	}
+
+	c.JSON(http.StatusOK, h.eventBus.Recent(limit, c.Query("type")))
+}
+
+// File integrity handlers
+
+// GetIntegrityReport handles GET /api/integrity
+func (h *Handlers) GetIntegrityReport(c *gin.Context) {
+	c.JSON(http.StatusOK, h.integrityMonitor.Report())
+}
+
+// CheckIntegrity handles POST /api/integrity/check
+func (h *Handlers) CheckIntegrity(c *gin.Context) {
+	c.JSON(http.StatusOK, h.integrityMonitor.Check())
+}
+
+// AcceptIntegrityBaseline handles POST /api/integrity/baseline
+func (h *Handlers) AcceptIntegrityBaseline(c *gin.Context) {
+	var req integrity.BaselineRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	}
+
+	target := "all"
+	if len(req.Paths) > 0 {
+		target = fmt.Sprintf("%v", req.Paths)
+	}
+
+	if err := h.integrityMonitor.Accept(req.Paths); err != nil {
+		h.recordAudit(c, "integrity.baseline", target, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, "integrity.baseline", target, true, "baseline updated")
+	c.JSON(http.StatusOK, h.integrityMonitor.Check())
+}
+
+// Confirmation helpers
+
+// guardDestructive reports whether a destructive action may run now. With
+// approvals enabled, the action is queued for a second party and 202 is
+// returned; otherwise a confirmation token is required.
+func (h *Handlers) guardDestructive(c *gin.Context, action, target, impact string, timeout time.Duration, run approvals.Func) bool {
+	if h.approvalQueue == nil {
+		return h.requireConfirmation(c, action, target, impact)
+	}
+
+	approval := h.approvalQueue.Submit(action, target, impact, requestParty(c), timeout, run)
+	h.recordAudit(c, "approval.request", action+" "+target, true, "queued as "+approval.ID)
+	h.eventBus.Publish(events.Event{
+		Type:     "approval.requested",
+		Severity: events.SeverityWarning,
+		Source:   "approvals",
+		Message:  fmt.Sprintf("%s %s is waiting for approval", action, target),
+		Data:     approval,
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "action queued: approve it with another credential via /api/approvals/" + approval.ID + "/approve",
+		"approval": approval,
+	})
+	return false
+}
+
+// requireConfirmation reports whether the request carries a valid confirmation
+// token for action on target. Without a token it responds 428 with a new
+// challenge describing the impact; the client repeats the request with the
+// token in the X-Confirmation-Token header (or ?confirm_token=).
+func (h *Handlers) requireConfirmation(c *gin.Context, action, target, impact string) bool {
+	token := c.GetHeader("X-Confirmation-Token")
+	if token == "" {
+		token = c.Query("confirm_token")
+	}
+
+	if token == "" {
+		challenge := h.confirmations.Issue(action, target, impact)
+		c.JSON(http.StatusPreconditionRequired, apierror.New(http.StatusPreconditionRequired,
+			"confirmation required: repeat the request with the X-Confirmation-Token header",
+			map[string]interface{}{"confirmation": challenge}))
+		return false
+	}
+
+	if err := h.confirmations.Consume(token, action, target); err != nil {
+		h.recordAudit(c, action, target, false, err.Error())
+		respondError(c, http.StatusForbidden, err)
+		return false
+	}
+
+	return true
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "this host"
+	}
+	return name
+}
+
+// Approval handlers
+
+// ListApprovals handles GET /api/approvals
+func (h *Handlers) ListApprovals(c *gin.Context) {
+	if h.approvalQueue == nil {
+		respondMessage(c, http.StatusNotFound, "approvals are disabled")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.approvalQueue.List(approvals.Status(c.Query("status"))))
+}
+
+// GetApproval handles GET /api/approvals/:id
+func (h *Handlers) GetApproval(c *gin.Context) {
+	if h.approvalQueue == nil {
+		respondMessage(c, http.StatusNotFound, "approvals are disabled")
+		return
+	}
+
+	approval, err := h.approvalQueue.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// ApproveAction handles POST /api/approvals/:id/approve
+func (h *Handlers) ApproveAction(c *gin.Context) {
+	h.decideApproval(c, true)
+}
+
+// RejectAction handles POST /api/approvals/:id/reject
+func (h *Handlers) RejectAction(c *gin.Context) {
+	h.decideApproval(c, false)
+}
+
+func (h *Handlers) decideApproval(c *gin.Context, approve bool) {
+	if h.approvalQueue == nil {
+		respondMessage(c, http.StatusNotFound, "approvals are disabled")
+		return
+	}
+
+	var req approvals.DecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	id := c.Param("id")
+	decision := "approval.reject"
+	var approval *approvals.Approval
+	var err error
+	if approve {
+		decision = "approval.approve"
+		approval, err = h.approvalQueue.Approve(id, requestParty(c), req.Reason)
+	} else {
+		approval, err = h.approvalQueue.Reject(id, requestParty(c), req.Reason)
+	}
+
+	if err != nil {
+		h.recordAudit(c, decision, id, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, decision, id, true, approval.Action+" "+approval.Target)
+	if approve {
+		h.recordAudit(c, approval.Action, approval.Target, approval.Status == approvals.StatusApproved,
+			"executed after approval "+approval.ID+approvalError(approval))
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+func approvalError(a *approvals.Approval) string {
+	if a.Error == "" {
+		return ""
+	}
+	return ": " + a.Error
+}
+
+// requestParty identifies the credential and device making a request
+func requestParty(c *gin.Context) approvals.Party {
+	return approvals.Party{
+		Actor:     c.GetString("auth_method"),
+		Principal: c.GetString(principalKey),
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// Audit handlers
+
+// GetAuditLog handles GET /api/audit
+func (h *Handlers) GetAuditLog(c *gin.Context) {
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil {
+			limit = n
+		}
+	}
+
+	source := c.Query("source")
+	if source != "" && source != audit.SourceAPI && source != audit.SourceHost {
+		respondMessage(c, http.StatusBadRequest, "source must be api or host")
+		return
+	}
+
+	switch source {
+	case audit.SourceAPI:
+		c.JSON(http.StatusOK, h.auditLog.List(limit, source))
+	case audit.SourceHost:
+		c.JSON(http.StatusOK, h.hostAuditLog.List(limit, source))
+	default:
+		c.JSON(http.StatusOK, audit.Merge(limit, h.auditLog.List(limit, ""), h.hostAuditLog.List(limit, "")))
+	}
+}
+
+// recordAudit records an action performed through the API
+func (h *Handlers) recordAudit(c *gin.Context, action, target string, success bool, message string) {
+	h.auditLog.Record(audit.Entry{
+		Action:   action,
+		Target:   target,
+		Actor:    c.GetString("auth_method"),
+		ClientIP: c.ClientIP(),
+		Success:  success,
+		Message:  message,
+	})
+}
+
+// Start launches background monitors
+func (h *Handlers) Start() {
+	go h.machine.Get() // Detect in the background so /api/info does not wait
+	h.integrityMonitor.Start()
+	h.exposure.Start()
+	h.vulns.Start()
+	if h.mqttPublisher != nil {
+		h.mqttPublisher.Start()
+	}
+	if h.logShipper != nil {
+		h.logShipper.Start()
+	}
+	if h.heartbeat != nil {
+		h.heartbeat.Start()
+	}
+	if h.docker != nil {
+		h.docker.Start()
+		h.imageUpdates.Start()
+	}
+	if h.cfg.MaintenanceEnabled {
+		h.maintenance.Start()
+	}
+	if h.cfg.ServicesEnabled {
+		h.schedules.Start()
+	}
+	h.upstreams.Start()
+	if h.storage != nil {
+		h.storage.Start()
+	}
+	if h.cfg.ThermalEnabled {
+		h.thermal.Start()
+	}
+	if h.energy.Available() {
+		h.energy.Start()
+	}
+	if h.cfg.ProcessesEnabled {
+		h.zombies.Start()
+	}
+	if h.cfg.OOMWatch {
+		h.oomWatcher.Start()
+	}
+	if h.cfg.HostAudit {
+		h.hostAudit.Start()
+	}
+	h.alerts.Start()
+}
+
 // Close cleans up handlers resources
 func (h *Handlers) Close() error {
-	if h.dockerManager != nil {
-		return h.dockerManager.Close()
+	h.integrityMonitor.Stop()
+	h.exposure.Stop()
+	h.vulns.Stop()
+	h.maintenance.Stop()
+	h.schedules.Stop()
+	h.databases.Close()
+	h.upstreams.Stop()
+	h.thermal.Stop()
+	h.energy.Stop()
+	h.zombies.Stop()
+	h.oomWatcher.Stop()
+	h.hostAudit.Stop()
+	h.alerts.Stop()
+	h.serviceManager.Close()
+	if h.mqttPublisher != nil {
+		h.mqttPublisher.Stop()
+	}
+	if h.logShipper != nil {
+		h.logShipper.Stop()
+	}
+	if h.heartbeat != nil {
+		h.heartbeat.Stop()
+	}
+	if h.storage != nil {
+		h.storage.Stop()
+	}
+	if h.store != nil {
+		h.store.Close()
+	}
+	if h.docker != nil {
+		h.imageUpdates.Stop()
+		return h.docker.Stop()
 	}
 	return nil
 }
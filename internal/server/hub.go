@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// streamInterval is how often streams receive metrics
+const streamInterval = 2 * time.Second
+
+// metricsSample is one collection, shared by every stream
+type metricsSample struct {
+	metrics *system.AllMetrics
+	err     error
+}
+
+// metricsHub collects metrics once per interval and fans each sample out
+// to the SSE and WebSocket streams, so open dashboards do not each collect.
+// The sampler only runs while a stream is subscribed.
+type metricsHub struct {
+	collect  func() (*system.AllMetrics, error)
+	interval time.Duration
+
+	mu   sync.Mutex
+	subs map[chan metricsSample]struct{}
+	last *metricsSample     // Latest sample of the running sampler
+	stop context.CancelFunc // nil while the sampler is stopped
+}
+
+func newMetricsHub(collect func() (*system.AllMetrics, error), interval time.Duration) *metricsHub {
+	return &metricsHub{
+		collect:  collect,
+		interval: interval,
+		subs:     make(map[chan metricsSample]struct{}),
+	}
+}
+
+// Subscribe returns a channel of samples, starting with the latest one,
+// and a function to unsubscribe. A subscriber that falls behind misses
+// samples rather than delaying the others.
+func (h *metricsHub) Subscribe() (<-chan metricsSample, func()) {
+	ch := make(chan metricsSample, 1)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	if h.last != nil {
+		ch <- *h.last
+	}
+	if h.stop == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.stop = cancel
+		go h.run(ctx)
+	}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.subs, ch)
+		if len(h.subs) == 0 && h.stop != nil {
+			h.stop()
+			h.stop, h.last = nil, nil
+		}
+	}
+}
+
+func (h *metricsHub) run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		metrics, err := h.collect()
+		sample := metricsSample{metrics: metrics, err: err}
+
+		h.mu.Lock()
+		if ctx.Err() == nil {
+			h.last = &sample
+			for ch := range h.subs {
+				select {
+				case ch <- sample:
+				default:
+				}
+			}
+		}
+		h.mu.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sampleThrottle lets a stream through at most one sample per interval.
+// Samples arrive on the collection ticker, so some slack keeps jitter from
+// costing a whole extra tick.
+type sampleThrottle struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func (t *sampleThrottle) allow(now time.Time) bool {
+	if t.interval > 0 && !t.last.IsZero() && now.Sub(t.last) < t.interval-streamInterval/4 {
+		return false
+	}
+	t.last = now
+	return true
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+func TestMetricsHub(t *testing.T) {
+	var collections atomic.Int32
+	hub := newMetricsHub(func() (*system.AllMetrics, error) {
+		collections.Add(1)
+		return &system.AllMetrics{}, nil
+	}, 20*time.Millisecond)
+
+	receive := func(ch <-chan metricsSample) metricsSample {
+		select {
+		case sample := <-ch:
+			return sample
+		case <-time.After(time.Second):
+			t.Fatal("no sample")
+			return metricsSample{}
+		}
+	}
+
+	first, unsubscribeFirst := hub.Subscribe()
+	second, unsubscribeSecond := hub.Subscribe()
+	for i := 0; i < 5; i++ {
+		require.NotNil(t, receive(first).metrics)
+		require.NotNil(t, receive(second).metrics)
+	}
+
+	// Both streams share the collections
+	assert.LessOrEqual(t, collections.Load(), int32(7))
+
+	unsubscribeFirst()
+	receive(second)
+	unsubscribeSecond()
+
+	// The sampler stops with the last subscriber
+	time.Sleep(50 * time.Millisecond)
+	stopped := collections.Load()
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, stopped, collections.Load())
+}
+
+func TestSampleThrottle(t *testing.T) {
+	start := time.Now()
+	at := func(seconds float64) time.Time { return start.Add(time.Duration(seconds * float64(time.Second))) }
+
+	// Samples every 2s, a little late or early, at most one per 5s
+	throttle := sampleThrottle{interval: 5 * time.Second}
+	var sent []float64
+	for _, s := range []float64{0, 2.1, 3.9, 6.05, 8, 10.1, 11.9} {
+		if throttle.allow(at(s)) {
+			sent = append(sent, s)
+		}
+	}
+	assert.Equal(t, []float64{0, 6.05, 11.9}, sent)
+
+	unthrottled := sampleThrottle{}
+	assert.True(t, unthrottled.allow(at(0)))
+	assert.True(t, unthrottled.allow(at(0)))
+}
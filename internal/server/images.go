@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+)
+
+// imagePullTimeout bounds a pull; large images on slow links take a while
+const imagePullTimeout = 15 * time.Minute
+
+// ListImages handles GET /api/docker/images
+func (h *Handlers) ListImages(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	images, err := manager.ListImages(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if images == nil {
+		images = []docker.ImageInfo{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"images": images,
+		"count":  len(images),
+	})
+}
+
+// PullImage handles POST /api/docker/images/pull. With Accept:
+// text/event-stream the daemon's progress is streamed as progress events,
+// ending with a done event; otherwise it runs as an operation.
+func (h *Handlers) PullImage(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	var req struct {
+		Image string `json:"image" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	ref, err := docker.NormalizeImage(req.Image)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	h.recordAudit(c, "image.pull", ref, true, "")
+	pull := func(ctx context.Context, progress chan<- docker.PullProgress) (*docker.PullResult, error) {
+		result, err := manager.PullImage(ctx, ref, progress)
+		if err == nil {
+			h.publishAction("image.pull", "docker", true, "Pulled "+ref, result)
+		}
+		return result, err
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		h.streamPull(c, pull)
+		return
+	}
+	h.runOperation(c, "image.pull", ref, imagePullTimeout, func(ctx context.Context) (interface{}, error) {
+		return pull(ctx, nil)
+	})
+}
+
+// streamPull runs a pull while relaying its progress over SSE. The route
+// group's deadline does not apply; the pull has its own.
+func (h *Handlers) streamPull(c *gin.Context, pull func(context.Context, chan<- docker.PullProgress) (*docker.PullResult, error)) {
+	ctx, cancel := context.WithTimeout(baseContext(c), imagePullTimeout)
+	defer cancel()
+
+	progress := make(chan docker.PullProgress, 16)
+	done := make(chan gin.H, 1)
+	go func() {
+		result, err := pull(ctx, progress)
+		outcome := gin.H{"success": err == nil, "result": result}
+		if err != nil {
+			outcome["error"] = err.Error()
+		}
+		done <- outcome
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case p := <-progress:
+			data, _ := json.Marshal(p)
+			c.SSEvent("progress", string(data))
+			return true
+		case outcome := <-done:
+			data, _ := json.Marshal(outcome)
+			c.SSEvent("done", string(data))
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// RemoveImage handles DELETE /api/docker/images/:id. ?force=true removes
+// an image that containers use or that has several tags.
+func (h *Handlers) RemoveImage(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	id := c.Param("id")
+	removed, err := manager.RemoveImage(c.Request.Context(), id, c.Query("force") == "true")
+	if err != nil {
+		h.recordAudit(c, "image.remove", id, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.recordAudit(c, "image.remove", id, true, fmt.Sprintf("%d removed", len(removed)))
+	h.publishAction("image.remove", "docker", true, "Removed image "+id, removed)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"removed": removed,
+	})
+}
+
+// GetDockerDiskUsage handles GET /api/docker/system/df
+func (h *Handlers) GetDockerDiskUsage(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	df, err := manager.SystemDiskUsage(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, df)
+}
+
+// PruneDocker handles POST /api/docker/prune. Without a body it removes
+// stopped containers, dangling images and unused networks. Pruning
+// volumes deletes data, so it needs confirmation or approval.
+func (h *Handlers) PruneDocker(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	opts := docker.DefaultPruneOptions
+	if c.Request.ContentLength != 0 {
+		opts = docker.PruneOptions{}
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	}
+
+	target := pruneTarget(opts)
+	if target == "" {
+		respondMessage(c, http.StatusBadRequest, "nothing to prune: set containers, images, networks or volumes")
+		return
+	}
+
+	run := func(ctx context.Context) (interface{}, error) {
+		return manager.Prune(ctx, opts)
+	}
+	if opts.Volumes {
+		impact := fmt.Sprintf("Deletes Docker volumes on %s that no container uses, with their data.", hostname())
+		if !h.guardDestructive(c, "docker.prune", target, impact, containerActionTimeout, run) {
+			return
+		}
+	}
+	h.recordAudit(c, "docker.prune", target, true, "")
+	h.runOperation(c, "docker.prune", target, containerActionTimeout, run)
+}
+
+// pruneTarget names what opts prunes, e.g. "containers,images"
+func pruneTarget(opts docker.PruneOptions) string {
+	var parts []string
+	for name, on := range map[string]bool{"containers": opts.Containers, "images": opts.Images, "networks": opts.Networks, "volumes": opts.Volumes} {
+		if on {
+			parts = append(parts, name)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
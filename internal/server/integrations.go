@@ -0,0 +1,514 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/alerts"
+	"github.com/ngenohkevin/hivedeck-agent/internal/crash"
+	"github.com/ngenohkevin/hivedeck-agent/internal/logship"
+	"github.com/ngenohkevin/hivedeck-agent/internal/maintenance"
+	"github.com/ngenohkevin/hivedeck-agent/internal/webhooks"
+)
+
+// integrationSettings is the body of PUT /api/settings/integrations. Omitted
+// fields are left unchanged; an empty string clears a value.
+type integrationSettings struct {
+	MQTT *struct {
+		Broker          *string `json:"broker"`
+		Username        *string `json:"username"`
+		Password        *string `json:"password"`
+		ClientID        *string `json:"client_id"`
+		TopicPrefix     *string `json:"topic_prefix"`
+		IntervalSeconds *int    `json:"interval_seconds"`
+		Discovery       *bool   `json:"discovery"`
+		DiscoveryPrefix *string `json:"discovery_prefix"`
+	} `json:"mqtt"`
+	LogForwarding *struct {
+		URL          *string           `json:"url"`
+		Username     *string           `json:"username"`
+		Password     *string           `json:"password"`
+		Units        []string          `json:"units"`
+		Files        []string          `json:"files"`
+		Labels       map[string]string `json:"labels"`
+		BatchSize    *int              `json:"batch_size"`
+		FlushSeconds *int              `json:"flush_seconds"`
+	} `json:"log_forwarding"`
+	Heartbeat *struct {
+		URL             *string `json:"url"`
+		IntervalSeconds *int    `json:"interval_seconds"`
+	} `json:"heartbeat"`
+	CrashReporting *struct {
+		SentryDSN  *string `json:"sentry_dsn"`
+		WebhookURL *string `json:"webhook_url"`
+	} `json:"crash_reporting"`
+	Alerts *struct {
+		Rules           map[string]string `json:"rules"`    // name=metric[:target][op threshold][@duration]
+		Channels        map[string]string `json:"channels"` // name=type:address
+		IntervalSeconds *int              `json:"interval_seconds"`
+	} `json:"alerts"`
+	Monitors *struct {
+		Upstreams       map[string]string `json:"upstreams"`
+		IntervalSeconds *int              `json:"interval_seconds"`
+		TimeoutSeconds  *int              `json:"timeout_seconds"`
+		Insecure        *bool             `json:"insecure"`
+	} `json:"monitors"`
+	Maintenance *struct {
+		Enabled       *bool    `json:"enabled"`
+		Schedule      *string  `json:"schedule"`
+		WindowMinutes *int     `json:"window_minutes"`
+		Reboot        *bool    `json:"reboot"`
+		Steps         []string `json:"steps"`
+	} `json:"maintenance"`
+	Webhooks *struct {
+		Hooks   map[string]string `json:"hooks"`   // name=action:target
+		Secrets map[string]string `json:"secrets"` // Merged into the saved secrets
+	} `json:"webhooks"`
+	Pull *struct {
+		URL             *string  `json:"url"`
+		Secret          *string  `json:"secret"`
+		IntervalSeconds *int     `json:"interval_seconds"`
+		AllowedCommands []string `json:"allowed_commands"`
+	} `json:"pull"`
+}
+
+// maintenanceSteps are the steps MAINTENANCE_STEPS may name
+var maintenanceSteps = []string{
+	maintenance.StepAptUpgrade,
+	maintenance.StepDockerPrune,
+	maintenance.StepDockerUpdate,
+	maintenance.StepJournalVacuum,
+}
+
+// GetIntegrationSettings handles GET /api/settings/integrations. Passwords,
+// DSNs, ping URLs, channel addresses and webhook secrets are secrets, so
+// only whether they are set is returned. The agent's hostname and labels say
+// which agent the settings belong to when a client manages several.
+func (h *SetupHandlers) GetIntegrationSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.integrationSettings())
+}
+
+func (h *SetupHandlers) integrationSettings() gin.H {
+	return gin.H{
+		"mqtt": gin.H{
+			"broker":           h.cfg.MQTTBroker,
+			"username":         h.cfg.MQTTUsername,
+			"password_set":     h.cfg.MQTTPassword != "",
+			"client_id":        h.cfg.MQTTClientID,
+			"topic_prefix":     h.cfg.MQTTTopicPrefix,
+			"interval_seconds": int(h.cfg.MQTTInterval.Seconds()),
+			"discovery":        h.cfg.MQTTDiscovery,
+			"discovery_prefix": h.cfg.MQTTDiscoveryPrefix,
+		},
+		"log_forwarding": gin.H{
+			"url":           h.cfg.LogForwardURL,
+			"username":      h.cfg.LogForwardUsername,
+			"password_set":  h.cfg.LogForwardPassword != "",
+			"units":         h.cfg.LogForwardUnits,
+			"files":         h.cfg.LogForwardFiles,
+			"labels":        h.cfg.LogForwardLabels,
+			"batch_size":    h.cfg.LogForwardBatchSize,
+			"flush_seconds": int(h.cfg.LogForwardFlush.Seconds()),
+		},
+		"heartbeat": gin.H{
+			"url_set":          h.cfg.HeartbeatURL != "",
+			"interval_seconds": int(h.cfg.HeartbeatInterval.Seconds()),
+		},
+		"crash_reporting": gin.H{
+			"sentry_dsn_set":  h.cfg.SentryDSN != "",
+			"webhook_url_set": h.cfg.CrashWebhookURL != "",
+		},
+		"alerts": gin.H{
+			"rules":            h.cfg.AlertRules,
+			"channels":         channelTypes(h.cfg.AlertSinkSpecs()),
+			"interval_seconds": int(h.cfg.AlertInterval.Seconds()),
+		},
+		"monitors": gin.H{
+			"upstreams":        h.cfg.Upstreams,
+			"interval_seconds": int(h.cfg.UpstreamInterval.Seconds()),
+			"timeout_seconds":  int(h.cfg.UpstreamTimeout.Seconds()),
+			"insecure":         h.cfg.UpstreamInsecure,
+		},
+		"maintenance": gin.H{
+			"enabled":        h.cfg.MaintenanceEnabled,
+			"schedule":       h.cfg.MaintenanceSchedule,
+			"window_minutes": int(h.cfg.MaintenanceWindow.Minutes()),
+			"reboot":         h.cfg.MaintenanceReboot,
+			"steps":          h.cfg.MaintenanceSteps,
+			"available":      maintenanceSteps,
+		},
+		"webhooks": gin.H{
+			"hooks":       h.cfg.Webhooks,
+			"secrets_set": sortedKeys(h.cfg.HookSecrets()),
+		},
+		"pull": gin.H{
+			"url":              h.cfg.PullURL,
+			"secret_set":       h.cfg.PullSecret != "",
+			"interval_seconds": int(h.cfg.PullInterval.Seconds()),
+			"allowed_commands": h.cfg.PullAllowed,
+		},
+		"agent": gin.H{
+			"hostname": hostname(),
+			"labels":   h.cfg.Labels,
+		},
+	}
+}
+
+// channelTypes returns each alert channel's type without its address,
+// which may hold a token
+func channelTypes(specs map[string]string) map[string]string {
+	types := make(map[string]string, len(specs))
+	for name, spec := range specs {
+		types[name], _, _ = strings.Cut(spec, ":")
+	}
+	return types
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// UpdateIntegrationSettings handles PUT /api/settings/integrations. The
+// integrations start with the agent, so changes apply after a restart.
+func (h *SetupHandlers) UpdateIntegrationSettings(c *gin.Context) {
+	var req integrationSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if err := validateIntegrations(h.cfg, &req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	updates := make(map[string]string)
+
+	if m := req.MQTT; m != nil {
+		setString(updates, "MQTT_BROKER", &h.cfg.MQTTBroker, m.Broker)
+		setString(updates, "MQTT_USERNAME", &h.cfg.MQTTUsername, m.Username)
+		setString(updates, "MQTT_PASSWORD", &h.cfg.MQTTPassword, m.Password)
+		setString(updates, "MQTT_CLIENT_ID", &h.cfg.MQTTClientID, m.ClientID)
+		setString(updates, "MQTT_TOPIC_PREFIX", &h.cfg.MQTTTopicPrefix, m.TopicPrefix)
+		setString(updates, "MQTT_DISCOVERY_PREFIX", &h.cfg.MQTTDiscoveryPrefix, m.DiscoveryPrefix)
+		setSeconds(updates, "MQTT_INTERVAL_SECONDS", &h.cfg.MQTTInterval, m.IntervalSeconds)
+		if m.Discovery != nil {
+			h.cfg.MQTTDiscovery = *m.Discovery
+			updates["MQTT_DISCOVERY"] = strconv.FormatBool(*m.Discovery)
+		}
+	}
+
+	if l := req.LogForwarding; l != nil {
+		setString(updates, "LOG_FORWARD_URL", &h.cfg.LogForwardURL, l.URL)
+		setString(updates, "LOG_FORWARD_USERNAME", &h.cfg.LogForwardUsername, l.Username)
+		setString(updates, "LOG_FORWARD_PASSWORD", &h.cfg.LogForwardPassword, l.Password)
+		setSeconds(updates, "LOG_FORWARD_FLUSH_SECONDS", &h.cfg.LogForwardFlush, l.FlushSeconds)
+		if l.Units != nil {
+			h.cfg.LogForwardUnits = l.Units
+			updates["LOG_FORWARD_UNITS"] = joinSlice(l.Units)
+		}
+		if l.Files != nil {
+			h.cfg.LogForwardFiles = l.Files
+			updates["LOG_FORWARD_FILES"] = joinSlice(l.Files)
+		}
+		if l.Labels != nil {
+			h.cfg.LogForwardLabels = l.Labels
+			updates["LOG_FORWARD_LABELS"] = joinMap(l.Labels)
+		}
+		if l.BatchSize != nil {
+			h.cfg.LogForwardBatchSize = *l.BatchSize
+			updates["LOG_FORWARD_BATCH_SIZE"] = strconv.Itoa(*l.BatchSize)
+		}
+	}
+
+	if hb := req.Heartbeat; hb != nil {
+		setString(updates, "HEARTBEAT_URL", &h.cfg.HeartbeatURL, hb.URL)
+		setSeconds(updates, "HEARTBEAT_INTERVAL_SECONDS", &h.cfg.HeartbeatInterval, hb.IntervalSeconds)
+	}
+
+	if cr := req.CrashReporting; cr != nil {
+		setString(updates, "SENTRY_DSN", &h.cfg.SentryDSN, cr.SentryDSN)
+		setString(updates, "CRASH_WEBHOOK_URL", &h.cfg.CrashWebhookURL, cr.WebhookURL)
+	}
+
+	if a := req.Alerts; a != nil {
+		setMap(updates, "ALERT_RULES", &h.cfg.AlertRules, a.Rules)
+		if a.Channels != nil {
+			h.cfg.AlertSinks = joinMap(a.Channels)
+			updates["ALERT_SINKS"] = h.cfg.AlertSinks
+		}
+		setSeconds(updates, "ALERT_INTERVAL_SECONDS", &h.cfg.AlertInterval, a.IntervalSeconds)
+	}
+
+	if m := req.Monitors; m != nil {
+		setMap(updates, "UPSTREAMS", &h.cfg.Upstreams, m.Upstreams)
+		setSeconds(updates, "UPSTREAM_INTERVAL_SECONDS", &h.cfg.UpstreamInterval, m.IntervalSeconds)
+		setSeconds(updates, "UPSTREAM_TIMEOUT_SECONDS", &h.cfg.UpstreamTimeout, m.TimeoutSeconds)
+		setBool(updates, "UPSTREAM_INSECURE", &h.cfg.UpstreamInsecure, m.Insecure)
+	}
+
+	if m := req.Maintenance; m != nil {
+		setBool(updates, "MAINTENANCE_ENABLED", &h.cfg.MaintenanceEnabled, m.Enabled)
+		setString(updates, "MAINTENANCE_SCHEDULE", &h.cfg.MaintenanceSchedule, m.Schedule)
+		setBool(updates, "MAINTENANCE_REBOOT", &h.cfg.MaintenanceReboot, m.Reboot)
+		if m.WindowMinutes != nil {
+			h.cfg.MaintenanceWindow = time.Duration(*m.WindowMinutes) * time.Minute
+			updates["MAINTENANCE_WINDOW_MINUTES"] = strconv.Itoa(*m.WindowMinutes)
+		}
+		if m.Steps != nil {
+			h.cfg.MaintenanceSteps = m.Steps
+			updates["MAINTENANCE_STEPS"] = joinSlice(m.Steps)
+		}
+	}
+
+	if w := req.Webhooks; w != nil {
+		setMap(updates, "WEBHOOKS", &h.cfg.Webhooks, w.Hooks)
+		if w.Hooks != nil || w.Secrets != nil {
+			h.cfg.WebhookSecrets = joinMap(hookSecrets(h.cfg.Webhooks, h.cfg.HookSecrets(), w.Secrets))
+			updates["WEBHOOK_SECRETS"] = h.cfg.WebhookSecrets
+		}
+	}
+
+	if p := req.Pull; p != nil {
+		setString(updates, "PULL_URL", &h.cfg.PullURL, p.URL)
+		setString(updates, "PULL_SECRET", &h.cfg.PullSecret, p.Secret)
+		setSeconds(updates, "PULL_INTERVAL_SECONDS", &h.cfg.PullInterval, p.IntervalSeconds)
+		if p.AllowedCommands != nil {
+			h.cfg.PullAllowed = p.AllowedCommands
+			updates["PULL_ALLOWED_COMMANDS"] = joinSlice(p.AllowedCommands)
+		}
+	}
+
+	if err := h.cfg.SaveEnv(updates); err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
+		return
+	}
+
+	resp := h.integrationSettings()
+	resp["message"] = "Integration settings updated"
+	resp["note"] = "Restart the agent to apply integration changes"
+	c.JSON(http.StatusOK, resp)
+}
+
+// validateIntegrations checks a request before anything is changed, so a bad
+// field does not leave the config half updated
+func validateIntegrations(cfg *config.Config, req *integrationSettings) error {
+	if m := req.MQTT; m != nil {
+		if m.Broker != nil && *m.Broker != "" {
+			u, err := url.Parse(*m.Broker)
+			if err != nil || u.Host == "" {
+				return fmt.Errorf("mqtt.broker must be a URL such as tcp://broker.lan:1883")
+			}
+		}
+		if m.IntervalSeconds != nil && *m.IntervalSeconds <= 0 {
+			return fmt.Errorf("mqtt.interval_seconds must be positive")
+		}
+	}
+
+	if l := req.LogForwarding; l != nil {
+		if err := checkMap("log_forwarding.labels", l.Labels); err != nil {
+			return err
+		}
+		if l.URL != nil && *l.URL != "" {
+			if _, err := logship.NewSink(logship.Options{URL: *l.URL}); err != nil {
+				return err
+			}
+		}
+		if l.BatchSize != nil && *l.BatchSize <= 0 {
+			return fmt.Errorf("log_forwarding.batch_size must be positive")
+		}
+		if l.FlushSeconds != nil && *l.FlushSeconds <= 0 {
+			return fmt.Errorf("log_forwarding.flush_seconds must be positive")
+		}
+	}
+
+	if hb := req.Heartbeat; hb != nil {
+		if hb.URL != nil && *hb.URL != "" && !httpURL(*hb.URL) {
+			return fmt.Errorf("heartbeat.url must be an http or https URL")
+		}
+		if hb.IntervalSeconds != nil && *hb.IntervalSeconds <= 0 {
+			return fmt.Errorf("heartbeat.interval_seconds must be positive")
+		}
+	}
+
+	if cr := req.CrashReporting; cr != nil {
+		if cr.SentryDSN != nil && *cr.SentryDSN != "" {
+			if _, err := crash.NewReporter(crash.Options{SentryDSN: *cr.SentryDSN}); err != nil {
+				return err
+			}
+		}
+		if cr.WebhookURL != nil && *cr.WebhookURL != "" && !httpURL(*cr.WebhookURL) {
+			return fmt.Errorf("crash_reporting.webhook_url must be an http or https URL")
+		}
+	}
+
+	if a := req.Alerts; a != nil {
+		if err := checkMap("alerts.rules", a.Rules); err != nil {
+			return err
+		}
+		if _, err := alerts.ParseRules(a.Rules); err != nil {
+			return err
+		}
+		if err := checkMap("alerts.channels", a.Channels); err != nil {
+			return err
+		}
+		if _, err := alerts.ParseSinks(a.Channels); err != nil {
+			return err
+		}
+		if a.IntervalSeconds != nil && *a.IntervalSeconds <= 0 {
+			return fmt.Errorf("alerts.interval_seconds must be positive")
+		}
+	}
+
+	if m := req.Monitors; m != nil {
+		if err := checkMap("monitors.upstreams", m.Upstreams); err != nil {
+			return err
+		}
+		for name, raw := range m.Upstreams {
+			if !httpURL(raw) {
+				return fmt.Errorf("monitors.upstreams: %s must be an http or https URL", name)
+			}
+		}
+		if m.IntervalSeconds != nil && *m.IntervalSeconds <= 0 {
+			return fmt.Errorf("monitors.interval_seconds must be positive")
+		}
+		if m.TimeoutSeconds != nil && *m.TimeoutSeconds <= 0 {
+			return fmt.Errorf("monitors.timeout_seconds must be positive")
+		}
+	}
+
+	if m := req.Maintenance; m != nil {
+		if m.Schedule != nil {
+			if _, err := maintenance.ParseSchedule(*m.Schedule); err != nil {
+				return fmt.Errorf("maintenance.schedule: %w", err)
+			}
+		}
+		if m.WindowMinutes != nil && *m.WindowMinutes <= 0 {
+			return fmt.Errorf("maintenance.window_minutes must be positive")
+		}
+		for _, step := range m.Steps {
+			if !slices.Contains(maintenanceSteps, step) {
+				return fmt.Errorf("maintenance.steps: unknown step %q, use %s", step, strings.Join(maintenanceSteps, ", "))
+			}
+		}
+	}
+
+	if w := req.Webhooks; w != nil {
+		if err := checkMap("webhooks.hooks", w.Hooks); err != nil {
+			return err
+		}
+		if err := checkMap("webhooks.secrets", w.Secrets); err != nil {
+			return err
+		}
+		// Every hook needs a secret, either saved already or in the request
+		if w.Hooks != nil {
+			if _, err := webhooks.Parse(w.Hooks, hookSecrets(w.Hooks, cfg.HookSecrets(), w.Secrets)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p := req.Pull; p != nil {
+		if p.URL != nil && *p.URL != "" && !httpURL(*p.URL) {
+			return fmt.Errorf("pull.url must be an http or https URL")
+		}
+		if p.IntervalSeconds != nil && *p.IntervalSeconds <= 0 {
+			return fmt.Errorf("pull.interval_seconds must be positive")
+		}
+	}
+
+	return nil
+}
+
+// checkMap rejects entries that would not read back from a name=value,...
+// env var
+func checkMap(field string, m map[string]string) error {
+	for k, v := range m {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, ",=") {
+			return fmt.Errorf("%s: invalid name %q", field, k)
+		}
+		if strings.Contains(v, ",") {
+			return fmt.Errorf("%s: %s must not contain a comma", field, k)
+		}
+	}
+	return nil
+}
+
+// hookSecrets merges new webhook secrets into the saved ones, keeping only
+// those of configured hooks
+func hookSecrets(hooks, saved, updates map[string]string) map[string]string {
+	merged := make(map[string]string, len(hooks))
+	for name := range hooks {
+		if secret := updates[name]; secret != "" {
+			merged[name] = secret
+		} else if secret := saved[name]; secret != "" {
+			merged[name] = secret
+		}
+	}
+	return merged
+}
+
+func httpURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// setString applies an optional string setting to the config and the env updates
+func setString(updates map[string]string, key string, dst *string, v *string) {
+	if v == nil {
+		return
+	}
+	*dst = strings.TrimSpace(*v)
+	updates[key] = *dst
+}
+
+// setBool applies an optional boolean setting
+func setBool(updates map[string]string, key string, dst *bool, v *bool) {
+	if v == nil {
+		return
+	}
+	*dst = *v
+	updates[key] = strconv.FormatBool(*v)
+}
+
+// setMap replaces a name=value setting when one is given
+func setMap(updates map[string]string, key string, dst *map[string]string, v map[string]string) {
+	if v == nil {
+		return
+	}
+	*dst = v
+	updates[key] = joinMap(v)
+}
+
+// setSeconds applies an optional interval given in seconds
+func setSeconds(updates map[string]string, key string, dst *time.Duration, v *int) {
+	if v == nil {
+		return
+	}
+	*dst = time.Duration(*v) * time.Second
+	updates[key] = strconv.Itoa(*v)
+}
+
+// joinMap formats labels as sorted key=value pairs, the format getEnvMap reads
+func joinMap(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return joinSlice(pairs)
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func putIntegrations(srv *Server, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("PUT", "/api/settings/integrations", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateIntegrationSettings(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	srv := New(cfg)
+
+	w := putIntegrations(srv, `{
+		"mqtt": {"broker": "tcp://broker.lan:1883", "password": "s3cret", "interval_seconds": 15},
+		"log_forwarding": {"url": "udp://syslog.lan:514", "labels": {"site": "rack1", "env": "home"}},
+		"heartbeat": {"url": "https://hc-ping.com/abc"}
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "s3cret")
+	assert.NotContains(t, w.Body.String(), "hc-ping.com")
+
+	assert.Equal(t, "tcp://broker.lan:1883", cfg.MQTTBroker)
+	assert.Equal(t, 15*time.Second, cfg.MQTTInterval)
+	assert.Equal(t, "https://hc-ping.com/abc", cfg.HeartbeatURL)
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "MQTT_PASSWORD=s3cret")
+	assert.Contains(t, string(data), "LOG_FORWARD_LABELS=env=home,site=rack1")
+	assert.NotContains(t, string(data), "SENTRY_DSN")
+}
+
+func TestUpdateIntegrationSettings_Invalid(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	srv := New(cfg)
+
+	for _, body := range []string{
+		`{"log_forwarding": {"url": "ftp://logs.lan"}}`,
+		`{"heartbeat": {"interval_seconds": 0}}`,
+		`{"crash_reporting": {"sentry_dsn": "https://sentry.lan/42"}}`,
+		`{"mqtt": {"broker": "tcp://broker.lan:1883"}, "heartbeat": {"url": "not a url"}}`,
+	} {
+		w := putIntegrations(srv, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	// A rejected request changes nothing
+	assert.Empty(t, cfg.MQTTBroker)
+	assert.NoFileExists(t, cfg.EnvFile)
+}
+
+func TestUpdateCacheSettings(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	srv := New(cfg)
+
+	req := httptest.NewRequest("PUT", "/api/settings/cache",
+		strings.NewReader(`{"default_ttl": "5s", "ttls": {"metrics:disk": "1m", "metrics:host": "10m"}}`))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, 5*time.Second, srv.handlers.cache.TTL("metrics:cpu"))
+	assert.Equal(t, time.Minute, srv.handlers.cache.TTL("metrics:disk"))
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "CACHE_TTL=5s")
+	assert.Contains(t, string(data), "CACHE_TTLS=metrics:disk=1m0s,metrics:host=10m0s")
+
+	// An empty TTL drops the override; a bad one changes nothing
+	for body, want := range map[string]int{
+		`{"ttls": {"metrics:host": ""}}`:                         http.StatusOK,
+		`{"default_ttl": "1s", "ttls": {"metrics:cpu": "soon"}}`: http.StatusBadRequest,
+		`{"default_ttl": "0s"}`:                                  http.StatusBadRequest,
+		`{"ttls": {"metrics:cpu,metrics:disk": "1s"}}`:           http.StatusBadRequest,
+		`{"ttls": {"metrics=cpu": "1s"}}`:                        http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("PUT", "/api/settings/cache", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, body)
+	}
+	assert.Equal(t, 5*time.Second, srv.handlers.cache.TTL("metrics:host"))
+	assert.Equal(t, map[string]time.Duration{"metrics:disk": time.Minute}, cfg.CacheTTLs)
+
+	// The per-key endpoint validates and saves the same way
+	for body, want := range map[string]int{
+		`{"key": "metrics:cpu", "ttl": "30s"}`:  http.StatusOK,
+		`{"key": "metrics:cpu,x", "ttl": "1s"}`: http.StatusBadRequest,
+		`{"key": "metrics:cpu", "ttl": "-1s"}`:  http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("PUT", "/api/agent/cache/ttl", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, body)
+	}
+	assert.Equal(t, 30*time.Second, srv.handlers.cache.TTL("metrics:cpu"))
+	data, err = os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "CACHE_TTLS=metrics:cpu=30s,metrics:disk=1m0s")
+}
+
+func TestUpdateLabelSettings(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	srv := New(cfg)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/settings/labels", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"labels": {"role": "nas", "location": "garage"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]string{"role": "nas", "location": "garage"}, cfg.Labels)
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "LABELS=location=garage,role=nas")
+
+	for _, body := range []string{
+		`{}`,
+		`{"labels": {"my-role": "nas"}}`,
+		`{"labels": {"role": ""}}`,
+		`{"labels": {"role": "nas,web"}}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, put(body).Code, body)
+	}
+	assert.Equal(t, "nas", cfg.Labels["role"])
+}
+
+func TestRegistrySettings(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	cfg.SecretsKeyFile = filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(cfg.SecretsKeyFile, []byte("registry-test-key"), 0o600))
+	cfg.EncryptSecrets = true
+	srv := New(cfg)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("PUT", "/api/settings/registries", `{"registries": {"ghcr.io": {"username": "octo", "password": "ghp_secret"}, "registry.lan:5000": {"username": "bob", "password": "hunter2"}}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "ghp_secret")
+
+	// Stored encrypted
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "REGISTRY_AUTH=enc:v1:")
+	assert.NotContains(t, string(data), "ghp_secret")
+
+	// A username change keeps the password; null removes a registry
+	w = request("PUT", "/api/settings/registries", `{"registries": {"ghcr.io": {"username": "octocat"}, "registry.lan:5000": null}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "octocat:ghp_secret", cfg.RegistryLogins()["ghcr.io"])
+	assert.NotContains(t, cfg.RegistryLogins(), "registry.lan:5000")
+
+	for _, path := range []string{"/api/settings/registries", "/api/settings"} {
+		w = request("GET", path, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "ghp_secret", path)
+	}
+	assert.JSONEq(t, `{"registries": [{"registry": "ghcr.io", "username": "octocat"}]}`, request("GET", "/api/settings/registries", "").Body.String())
+
+	for _, body := range []string{
+		`{}`,
+		`{"registries": {"quay.io": {"username": "u"}}}`,
+		`{"registries": {"ghcr.io/octo": {"username": "u", "password": "p"}}}`,
+		`{"registries": {"quay.io": {"username": "u", "password": "a,b"}}}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, request("PUT", "/api/settings/registries", body).Code, body)
+	}
+	assert.Len(t, srv.handlers.registryAuth.Logins(), 1)
+}
+
+func TestUpdateIntegrationSettings_Subsystems(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	cfg.WebhookSecrets = "redeploy=old-secret"
+	srv := New(cfg)
+
+	w := putIntegrations(srv, `{
+		"alerts": {"rules": {"high-cpu": "cpu>90@5m"}, "channels": {"ops": "telegram:123:abc/42"}, "interval_seconds": 60},
+		"monitors": {"upstreams": {"app": "http://127.0.0.1:3000/health"}, "timeout_seconds": 2},
+		"maintenance": {"enabled": true, "schedule": "mon,thu 02:00", "steps": ["docker-prune", "journal-vacuum"]},
+		"webhooks": {"hooks": {"redeploy": "task:df", "cleanup": "task:uptime"}, "secrets": {"cleanup": "new-secret"}},
+		"pull": {"url": "https://hub.lan/agents", "secret": "pull-secret", "allowed_commands": ["GET /api/", "POST /api/tasks/"]}
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	for _, secret := range []string{"123:abc", "old-secret", "new-secret", "pull-secret"} {
+		assert.NotContains(t, w.Body.String(), secret)
+	}
+	assert.Contains(t, w.Body.String(), `"channels":{"ops":"telegram"}`)
+	assert.Contains(t, w.Body.String(), `"secrets_set":["cleanup","redeploy"]`)
+
+	assert.Equal(t, time.Minute, cfg.AlertInterval)
+	assert.Equal(t, 2*time.Second, cfg.UpstreamTimeout)
+	assert.True(t, cfg.MaintenanceEnabled)
+	assert.Equal(t, map[string]string{"cleanup": "new-secret", "redeploy": "old-secret"}, cfg.HookSecrets())
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	for _, line := range []string{
+		"ALERT_RULES='high-cpu=cpu>90@5m'",
+		"UPSTREAMS=app=http://127.0.0.1:3000/health",
+		"MAINTENANCE_STEPS=docker-prune,journal-vacuum",
+		"WEBHOOKS=cleanup=task:uptime,redeploy=task:df",
+		"PULL_URL=https://hub.lan/agents",
+	} {
+		assert.Contains(t, string(data), line)
+	}
+
+	for _, body := range []string{
+		`{"alerts": {"rules": {"hot": "temperature>90"}}}`,
+		`{"alerts": {"channels": {"ops": "email:me@example.com"}}}`,
+		`{"monitors": {"upstreams": {"app": "tcp://127.0.0.1:3000"}}}`,
+		`{"maintenance": {"schedule": "someday 03:00"}}`,
+		`{"maintenance": {"steps": ["rm-rf"]}}`,
+		`{"webhooks": {"hooks": {"deploy": "task:df"}}}`,
+		`{"webhooks": {"hooks": {"a=b": "task:df"}, "secrets": {"a=b": "s"}}}`,
+		`{"pull": {"url": "hub.lan"}}`,
+		`{"log_forwarding": {"labels": {"site": "a,b"}}}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, putIntegrations(srv, body).Code, body)
+	}
+	assert.Equal(t, map[string]string{"high-cpu": "cpu>90@5m"}, cfg.AlertRules)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// labelKeyPattern matches Prometheus label names, so labels can be
+// attached to metrics unchanged
+var labelKeyPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// GetLabelSettings handles GET /api/settings/labels
+func (s *Server) GetLabelSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"labels": s.cfg.Labels})
+}
+
+// UpdateLabelSettings handles PUT /api/settings/labels. The labels replace
+// the current ones, apply right away and are saved to .env as LABELS.
+func (s *Server) UpdateLabelSettings(c *gin.Context) {
+	var req struct {
+		Labels map[string]string `json:"labels" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	for key, value := range req.Labels {
+		if !labelKeyPattern.MatchString(key) {
+			respondMessage(c, http.StatusBadRequest, "invalid label name: "+key)
+			return
+		}
+		// LABELS is a comma-separated list, so values cannot contain commas
+		if value == "" || len(value) > 128 || strings.ContainsAny(value, ",\n") {
+			respondMessage(c, http.StatusBadRequest, "label "+key+" needs a value of at most 128 characters without commas")
+			return
+		}
+	}
+
+	if err := s.cfg.SaveEnv(map[string]string{"LABELS": joinMap(req.Labels)}); err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
+		return
+	}
+
+	s.cfg.Labels = req.Labels
+	if s.handlers.heartbeat != nil {
+		s.handlers.heartbeat.SetLabels(req.Labels)
+	}
+	if s.pullClient != nil {
+		s.pullClient.SetLabels(req.Labels)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"labels": req.Labels, "message": "Labels updated"})
+}
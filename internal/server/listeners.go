@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// newHTTPServer creates the server for one listener. Its address is
+// resolved and its handler limited to the listener's paths.
+func (s *Server) newHTTPServer(l config.Listener) (*http.Server, error) {
+	addr, err := resolveListenAddr(l.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           restrictPaths(l.Paths, s.router),
+		ReadTimeout:       s.cfg.ReadTimeout,
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+		IdleTimeout:       s.cfg.IdleTimeout,
+		Protocols:         protocols(s.cfg),
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: s.cfg.HTTP2MaxStreams,
+			SendPingTimeout:      s.cfg.HTTP2PingInterval,
+		},
+	}, nil
+}
+
+// protocols returns the HTTP versions to serve. HTTP/2 over TLS is only
+// possible when the agent terminates TLS itself; h2c is opt-in because only
+// a trusted proxy should speak it.
+func protocols(cfg *config.Config) *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(cfg.HTTP2 && cfg.TLSEnabled())
+	p.SetUnencryptedHTTP2(cfg.H2C)
+	return p
+}
+
+// listen binds a listener's address with the configured TCP keep-alive
+func (s *Server) listen(addr string) (net.Listener, error) {
+	lc := net.ListenConfig{KeepAlive: s.cfg.TCPKeepAlive}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// serve serves on ln, over TLS when a certificate is configured
+func (s *Server) serve(srv *http.Server, ln net.Listener) error {
+	if s.cfg.TLSEnabled() {
+		return srv.ServeTLS(ln, s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+	}
+	return srv.Serve(ln)
+}
+
+// resolveListenAddr replaces an interface name in host:port with the
+// interface's address, preferring IPv4. Other hosts are returned as they are.
+func resolveListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	iface, err := net.InterfaceByName(host)
+	if err != nil {
+		// Not an interface; a hostname such as localhost
+		return addr, nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("interface %s: %w", host, err)
+	}
+
+	var v6 net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			return net.JoinHostPort(ipnet.IP.String(), port), nil
+		}
+		if v6 == nil {
+			v6 = ipnet.IP
+		}
+	}
+	if v6 != nil {
+		return net.JoinHostPort(v6.String(), port), nil
+	}
+	return "", fmt.Errorf("interface %s has no usable address (is it up?)", host)
+}
+
+// restrictPaths serves only requests under one of the path prefixes and
+// answers 404 to the rest, as if the routes did not exist there. No prefixes
+// means no restriction.
+func restrictPaths(prefixes []string, next http.Handler) http.Handler {
+	if len(prefixes) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Match the cleaned path so dot segments cannot escape a prefix
+		clean := path.Clean("/" + r.URL.Path)
+		for _, p := range prefixes {
+			if pathUnder(clean, p) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(apierror.New(http.StatusNotFound, "not available on this listener", nil))
+	})
+}
+
+// pathUnder reports whether p is prefix or below it
+func pathUnder(p, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || p == prefix || strings.HasPrefix(p, prefix+"/")
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestResolveListenAddr(t *testing.T) {
+	addr, err := resolveListenAddr("lo:8091")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8091", addr)
+
+	for _, a := range []string{"0.0.0.0:8091", "[::1]:8091", "localhost:8091", ":8091"} {
+		addr, err := resolveListenAddr(a)
+		require.NoError(t, err)
+		assert.Equal(t, a, addr)
+	}
+
+	_, err = resolveListenAddr("tailscale0")
+	assert.Error(t, err)
+}
+
+func TestRestrictPaths(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	handler := restrictPaths([]string{"/health", "/api/metrics"}, srv.Router())
+
+	for path, want := range map[string]int{
+		"/health":                         http.StatusOK,
+		"/api/metrics/memory":             http.StatusUnauthorized, // allowed here, still needs auth
+		"/api/metricsx":                   http.StatusNotFound,
+		"/api/services":                   http.StatusNotFound,
+		"/api/metrics/../services":        http.StatusNotFound,
+		"/settings":                       http.StatusNotFound,
+		"/health/../api/settings/api-key": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+
+	// No prefixes serves everything
+	w := httptest.NewRecorder()
+	restrictPaths(nil, srv.Router()).ServeHTTP(w, httptest.NewRequest("GET", "/settings", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestNewHTTPServerTuning(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.HTTP2MaxStreams = 500
+	srv, err := New(cfg).newHTTPServer(config.Listener{Addr: "127.0.0.1:0"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 120*time.Second, srv.IdleTimeout)
+	assert.Equal(t, 10*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 500, srv.HTTP2.MaxConcurrentStreams)
+	assert.True(t, srv.Protocols.HTTP1())
+	// HTTP/2 needs TLS unless h2c is turned on
+	assert.False(t, srv.Protocols.HTTP2())
+	assert.False(t, srv.Protocols.UnencryptedHTTP2())
+
+	cfg.TLSCertFile, cfg.TLSKeyFile = "cert.pem", "key.pem"
+	cfg.H2C = true
+	p := protocols(cfg)
+	assert.True(t, p.HTTP2())
+	assert.True(t, p.UnencryptedHTTP2())
+
+	cfg.HTTP2 = false
+	assert.False(t, protocols(cfg).HTTP2())
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/maintenance"
+	"github.com/ngenohkevin/hivedeck-agent/internal/power"
+)
+
+// newMaintenanceRunner builds the auto-maintenance runner from the
+// configured steps. Unknown steps are logged and left out.
+func (h *Handlers) newMaintenanceRunner(cfg *config.Config) *maintenance.Runner {
+	schedule, err := maintenance.ParseSchedule(cfg.MaintenanceSchedule)
+	if err != nil {
+		log.Printf("Invalid MAINTENANCE_SCHEDULE, using sun 03:30: %v", err)
+		schedule, _ = maintenance.ParseSchedule("sun 03:30")
+	}
+
+	var apt func(ctx context.Context, args ...string) ([]byte, error)
+	if h.helper != nil {
+		apt = h.helper.Run
+	}
+
+	var steps []maintenance.Step
+	for _, name := range cfg.MaintenanceSteps {
+		switch name {
+		case maintenance.StepAptUpgrade:
+			steps = append(steps, maintenance.AptUpgrade(apt))
+		case maintenance.StepDockerPrune:
+			steps = append(steps, maintenance.Func(name, h.pruneDocker))
+		case maintenance.StepDockerUpdate:
+			steps = append(steps, maintenance.Func(name, h.updateDocker))
+		case maintenance.StepJournalVacuum:
+			steps = append(steps, maintenance.JournalVacuum(cfg.JournalVacuumSize))
+		default:
+			log.Printf("Unknown maintenance step %q ignored", name)
+		}
+	}
+	if cfg.MaintenanceReboot {
+		steps = append(steps, maintenance.RebootIfRequired(func(ctx context.Context) error {
+			result, err := h.powerManager.Reboot(ctx, power.Request{
+				When:    "+1",
+				Message: "Rebooting after auto-maintenance",
+			})
+			if err != nil {
+				return err
+			}
+			if !result.Success {
+				return fmt.Errorf("%s", result.Message)
+			}
+			return nil
+		}))
+	}
+
+	// The host is in maintenance mode while the steps run, unless an
+	// operator already put it there
+	var setMode bool
+	runner := maintenance.NewRunner(schedule, cfg.MaintenanceWindow, steps, cfg.DataDir)
+	runner.OnStart(func() {
+		setMode = !h.powerManager.Maintenance().Enabled
+		if setMode {
+			h.powerManager.SetMaintenance("auto-maintenance", time.Now().Add(cfg.MaintenanceWindow))
+		}
+	})
+	runner.OnReport(func(report *maintenance.Report) {
+		if setMode {
+			h.powerManager.ClearMaintenance()
+		}
+		h.publishMaintenanceReport(report)
+	})
+	return runner
+}
+
+func (h *Handlers) pruneDocker(ctx context.Context) (string, error) {
+	manager := h.dockerManager()
+	if manager == nil {
+		return "", errDockerUnavailable
+	}
+
+	report, err := manager.Prune(ctx, docker.DefaultPruneOptions)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("removed %d containers, %d images, %d networks; reclaimed %d bytes",
+		report.ContainersDeleted, report.ImagesDeleted, report.NetworksDeleted, report.SpaceReclaimed), nil
+}
+
+// updateDocker recreates the containers selected for automatic updates
+// whose images have moved on, raising an event for each one touched
+func (h *Handlers) updateDocker(ctx context.Context) (string, error) {
+	manager := h.dockerManager()
+	if manager == nil {
+		return "", errDockerUnavailable
+	}
+
+	report, err := manager.AutoUpdate(ctx, docker.AutoUpdatePolicy{
+		Containers:    h.cfg.DockerAutoUpdate,
+		HealthTimeout: h.cfg.DockerHealthTimeout,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, r := range report.Results {
+		line := fmt.Sprintf("%s (%s): %s", r.Container, r.Image, r.Status)
+		if r.Error != "" {
+			line += ": " + r.Error
+		}
+		lines = append(lines, line)
+		h.publishAutoUpdate(r)
+	}
+	if len(lines) == 0 {
+		return "no updates", nil
+	}
+
+	output := strings.Join(lines, "\n")
+	if report.RolledBack > 0 || report.Failed > 0 {
+		return output, fmt.Errorf("%d containers rolled back, %d failed", report.RolledBack, report.Failed)
+	}
+	return output, nil
+}
+
+func (h *Handlers) publishAutoUpdate(result docker.AutoUpdateResult) {
+	severity := events.SeverityInfo
+	message := fmt.Sprintf("Container %s updated to the latest %s image", result.Container, result.Image)
+	switch result.Status {
+	case docker.AutoCurrent:
+		return
+	case docker.AutoRolledBack:
+		severity = events.SeverityWarning
+		message = fmt.Sprintf("Container %s rolled back to its previous image: %s", result.Container, result.Error)
+	case docker.AutoFailed:
+		severity = events.SeverityWarning
+		message = fmt.Sprintf("Failed to update container %s: %s", result.Container, result.Error)
+	}
+	h.eventBus.Publish(events.Event{
+		Type:     "docker.auto_update",
+		Severity: severity,
+		Source:   result.Container,
+		Message:  message,
+		Data:     result,
+	})
+}
+
+func (h *Handlers) publishMaintenanceReport(report *maintenance.Report) {
+	severity := events.SeverityInfo
+	message := "auto-maintenance completed"
+	if !report.Success {
+		severity = events.SeverityWarning
+		message = "auto-maintenance completed with failures"
+	}
+	h.eventBus.Publish(events.Event{
+		Type:     "maintenance.completed",
+		Severity: severity,
+		Source:   "maintenance",
+		Message:  message,
+		Data:     report,
+	})
+}
+
+// GetAutoMaintenance handles GET /api/maintenance. limit bounds the
+// number of past reports returned.
+func (h *Handlers) GetAutoMaintenance(c *gin.Context) {
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil {
+			limit = n
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  h.maintenance.Status(),
+		"history": h.maintenance.History(limit),
+	})
+}
+
+// RunAutoMaintenance handles POST /api/maintenance/run
+func (h *Handlers) RunAutoMaintenance(c *gin.Context) {
+	status := h.maintenance.Status()
+	impact := fmt.Sprintf("Runs %v on %s now.", status.Steps, hostname())
+	run := func(ctx context.Context) (interface{}, error) {
+		return h.maintenance.Run(ctx, "manual")
+	}
+	if !h.guardDestructive(c, "maintenance.run", "auto", impact, h.cfg.MaintenanceWindow, run) {
+		return
+	}
+
+	h.runOperation(c, "maintenance.run", "auto", h.cfg.MaintenanceWindow, run)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestRunAutoMaintenance(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.MaintenanceSchedule = "daily 04:00"
+	cfg.MaintenanceSteps = []string{} // Nothing that touches the host
+	srv := New(cfg)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		if token != "" {
+			req.Header.Set("X-Confirmation-Token", token)
+		}
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/maintenance/run", "")
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+	var challenge struct {
+		Details struct {
+			Confirmation struct {
+				Token string `json:"token"`
+			} `json:"confirmation"`
+		} `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+
+	w = do("POST", "/api/maintenance/run", challenge.Details.Confirmation.Token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report struct {
+		Trigger string `json:"trigger"`
+		Success bool   `json:"success"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "manual", report.Trigger)
+	assert.True(t, report.Success)
+
+	// Maintenance mode was only held for the run
+	assert.False(t, srv.handlers.powerManager.Maintenance().Enabled)
+	assert.Equal(t, 1, srv.handlers.eventBus.Recent(0, "maintenance.completed").Total)
+
+	w = do("GET", "/api/maintenance", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Status struct {
+			Enabled  bool   `json:"enabled"`
+			Schedule string `json:"schedule"`
+		} `json:"status"`
+		History []json.RawMessage `json:"history"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Status.Enabled)
+	assert.Equal(t, "daily 04:00", resp.Status.Schedule)
+	assert.Len(t, resp.History, 1)
+}
+
+func TestNewMaintenanceRunner_Steps(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.MaintenanceSteps = []string{"apt-upgrade", "bogus", "docker-prune", "docker-update"}
+	cfg.MaintenanceReboot = true
+	srv := New(cfg)
+
+	assert.Equal(t, []string{"apt-upgrade", "docker-prune", "docker-update", "reboot"}, srv.handlers.maintenance.Status().Steps)
+}
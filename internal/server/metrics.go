@@ -0,0 +1,280 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/cache"
+	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+	"github.com/ngenohkevin/hivedeck-agent/internal/energy"
+	"github.com/ngenohkevin/hivedeck-agent/internal/process"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// maxTopProcesses bounds top_processes
+const maxTopProcesses = 50
+
+// maxStreamInterval bounds the interval of a metrics stream
+const maxStreamInterval = 10 * time.Minute
+
+// metricsSections are the sections of a snapshot that sections= selects
+var metricsSections = []string{"host", "cpu", "memory", "disk", "network"}
+
+// metricsOptions trim and extend metrics responses, so constrained clients
+// get only the numbers they display. The zero value changes nothing.
+type metricsOptions struct {
+	docker       bool     // include=docker: add per-container usage
+	power        bool     // include=power: add the power draw
+	topProcesses int      // top_processes=N: add the N busiest processes
+	interfaces   []string // interfaces=eth0,wlan0: keep only these interfaces
+	mountpoints  []string // mountpoints=/,/data: keep only these partitions
+	noPerCPU     bool     // per_cpu=false: drop per-core usage
+	sections     []string // sections=cpu,memory: keep only these sections
+
+	// interval=10s: send streams a sample at most this often. Snapshots
+	// ignore it.
+	interval time.Duration
+}
+
+// parseMetricsOptions reads metrics options from the query. It responds
+// with 400 and returns false when one is invalid.
+func parseMetricsOptions(c *gin.Context) (metricsOptions, bool) {
+	opts := metricsOptions{
+		interfaces:  splitQuery(c.Query("interfaces")),
+		mountpoints: splitQuery(c.Query("mountpoints")),
+	}
+	for _, include := range splitQuery(c.Query("include")) {
+		switch include {
+		case "docker":
+			opts.docker = true
+		case "power":
+			opts.power = true
+		}
+	}
+
+	if raw := c.Query("top_processes"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > maxTopProcesses {
+			respondMessage(c, http.StatusBadRequest, "top_processes must be between 0 and "+strconv.Itoa(maxTopProcesses))
+			return opts, false
+		}
+		opts.topProcesses = n
+	}
+
+	if raw := c.Query("per_cpu"); raw != "" {
+		perCPU, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondMessage(c, http.StatusBadRequest, "per_cpu must be true or false")
+			return opts, false
+		}
+		opts.noPerCPU = !perCPU
+	}
+
+	for _, section := range splitQuery(c.Query("sections")) {
+		if !inList(metricsSections, section) {
+			respondMessage(c, http.StatusBadRequest, "sections must be among "+strings.Join(metricsSections, ", "))
+			return opts, false
+		}
+		opts.sections = append(opts.sections, section)
+	}
+
+	if raw := c.Query("interval"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < streamInterval || interval > maxStreamInterval {
+			respondMessage(c, http.StatusBadRequest, fmt.Sprintf("interval must be a duration between %s and %s", streamInterval, maxStreamInterval))
+			return opts, false
+		}
+		opts.interval = interval
+	}
+
+	return opts, true
+}
+
+// splitQuery splits a comma-separated query value, dropping empty entries
+func splitQuery(raw string) []string {
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func inList(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// cpu applies per_cpu. Cached values are shared, so it copies rather than
+// modifying them.
+func (o metricsOptions) cpu(info *system.CPUInfo) *system.CPUInfo {
+	if !o.noPerCPU {
+		return info
+	}
+	trimmed := *info
+	trimmed.UsagePerCPU = nil
+	return &trimmed
+}
+
+// network applies interfaces
+func (o metricsOptions) network(info *system.NetworkInfo) *system.NetworkInfo {
+	if len(o.interfaces) == 0 {
+		return info
+	}
+	trimmed := &system.NetworkInfo{Interfaces: []system.NetworkInterface{}}
+	for _, iface := range info.Interfaces {
+		if inList(o.interfaces, iface.Name) {
+			trimmed.Interfaces = append(trimmed.Interfaces, iface)
+		}
+	}
+	return trimmed
+}
+
+// disk applies mountpoints
+func (o metricsOptions) disk(info *system.DiskInfo) *system.DiskInfo {
+	if len(o.mountpoints) == 0 {
+		return info
+	}
+	trimmed := &system.DiskInfo{Partitions: []system.DiskPartition{}}
+	for _, part := range info.Partitions {
+		if inList(o.mountpoints, part.Mountpoint) {
+			trimmed.Partitions = append(trimmed.Partitions, part)
+		}
+	}
+	return trimmed
+}
+
+// metricsResponse is a metrics snapshot with the sections metricsOptions
+// add. Sections that were not requested are left out.
+type metricsResponse struct {
+	*system.AllMetrics
+	*containerUsage
+	*powerUsage
+	TopProcesses []process.ProcessInfo `json:"top_processes,omitempty"`
+	ProcessError string                `json:"process_error,omitempty"`
+}
+
+// containerUsage is the include=docker section
+type containerUsage struct {
+	Containers  []docker.ContainerUsage `json:"containers"`
+	DockerError string                  `json:"docker_error,omitempty"`
+}
+
+// powerUsage is the include=power section
+type powerUsage struct {
+	Power      *energy.Power `json:"power,omitempty"`
+	PowerError string        `json:"power_error,omitempty"`
+}
+
+// metricsResponse applies opts to a snapshot. Sections that fail to
+// collect report why instead of failing the snapshot.
+func (h *Handlers) metricsResponse(metrics *system.AllMetrics, opts metricsOptions) interface{} {
+	if !opts.docker && !opts.power && opts.topProcesses == 0 && !opts.noPerCPU &&
+		len(opts.interfaces) == 0 && len(opts.mountpoints) == 0 && len(opts.sections) == 0 {
+		return metrics
+	}
+
+	trimmed := *metrics
+	trimmed.CPU = *opts.cpu(&metrics.CPU)
+	trimmed.Network = *opts.network(&metrics.Network)
+	trimmed.Disk = *opts.disk(&metrics.Disk)
+	resp := metricsResponse{AllMetrics: &trimmed}
+
+	if opts.docker {
+		resp.containerUsage = h.containerUsage()
+	}
+	if opts.power {
+		resp.powerUsage = &powerUsage{}
+		if power, err := h.energy.Current(); err != nil {
+			resp.PowerError = err.Error()
+		} else {
+			resp.Power = power
+		}
+	}
+	if opts.topProcesses > 0 {
+		if !h.cfg.ProcessesEnabled {
+			resp.ProcessError = "processes module is disabled"
+		} else if list, err := h.processManager.ListTop(opts.topProcesses); err != nil {
+			resp.ProcessError = err.Error()
+		} else {
+			resp.TopProcesses = list.Processes
+		}
+	}
+	return opts.selectSections(resp)
+}
+
+// selectSections applies sections, leaving out the other snapshot sections
+// and their errors. What include= and top_processes add is kept.
+func (o metricsOptions) selectSections(resp metricsResponse) interface{} {
+	if len(o.sections) == 0 {
+		return resp
+	}
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return resp
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return resp
+	}
+
+	dropped := func(section string) bool {
+		return inList(metricsSections, section) && !inList(o.sections, section)
+	}
+	errs := map[string]string{}
+	for section, msg := range resp.Errors {
+		if !dropped(section) {
+			errs[section] = msg
+		}
+	}
+	for key := range fields {
+		if dropped(key) {
+			delete(fields, key)
+		}
+	}
+	delete(fields, "errors")
+	if len(errs) > 0 {
+		fields["errors"], _ = json.Marshal(errs)
+	}
+	return fields
+}
+
+// containerUsageTimeout bounds one container usage snapshot
+const containerUsageTimeout = 30 * time.Second
+
+// containerUsage returns each running container's CPU and memory use. If
+// Docker is unavailable the reason is in DockerError.
+func (h *Handlers) containerUsage() *containerUsage {
+	section := &containerUsage{Containers: []docker.ContainerUsage{}}
+	manager := h.dockerManager()
+	if manager == nil {
+		section.DockerError = errDockerUnavailable.Error()
+		return section
+	}
+
+	// The snapshot is shared with every caller waiting on the key, so it
+	// must not end when the request that started it does
+	usage, err := h.cache.GetOrSet(cache.KeyDocker, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), containerUsageTimeout)
+		defer cancel()
+		return manager.ContainerUsage(ctx)
+	})
+	if err != nil {
+		section.DockerError = err.Error()
+		return section
+	}
+	section.Containers = usage.([]docker.ContainerUsage)
+	return section
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/energy"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+func parseOptions(t *testing.T, query string) (metricsOptions, int) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/metrics?"+query, nil)
+	opts, ok := parseMetricsOptions(c)
+	if ok {
+		return opts, http.StatusOK
+	}
+	return opts, w.Code
+}
+
+func TestParseMetricsOptions(t *testing.T) {
+	opts, status := parseOptions(t, "include=docker,power&top_processes=5&interfaces=eth0,+wlan0&per_cpu=false")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, opts.docker)
+	assert.True(t, opts.power)
+	assert.Equal(t, 5, opts.topProcesses)
+	assert.Equal(t, []string{"eth0", "wlan0"}, opts.interfaces)
+	assert.True(t, opts.noPerCPU)
+
+	opts, status = parseOptions(t, "sections=cpu,memory&interval=5s")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"cpu", "memory"}, opts.sections)
+	assert.Equal(t, 5*time.Second, opts.interval)
+
+	for _, query := range []string{"top_processes=x", "top_processes=-1", "top_processes=500", "per_cpu=maybe",
+		"sections=cpu,gpu", "interval=1s", "interval=1h", "interval=soon"} {
+		_, status := parseOptions(t, query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
+
+func TestMetricsResponse(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	srv.handlers.docker = nil
+	metrics := &system.AllMetrics{
+		CPU:     system.CPUInfo{UsageTotal: 12.5, UsagePerCPU: []float64{10, 15}},
+		Network: system.NetworkInfo{Interfaces: []system.NetworkInterface{{Name: "eth0"}, {Name: "lo"}}},
+		Disk:    system.DiskInfo{Partitions: []system.DiskPartition{{Mountpoint: "/"}, {Mountpoint: "/boot"}}},
+	}
+
+	assert.Same(t, metrics, srv.handlers.metricsResponse(metrics, metricsOptions{}))
+
+	opts := metricsOptions{docker: true, interfaces: []string{"eth0"}, mountpoints: []string{"/"}, noPerCPU: true}
+	sparse, err := SelectFields(srv.handlers.metricsResponse(metrics, opts),
+		ParseFields("cpu,network.interfaces.name,disk.partitions.mountpoint,containers,docker_error,top_processes"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"cpu": map[string]interface{}{
+			"cores": 0.0, "model_name": "", "mhz": 0.0, "usage_total": 12.5, "usage_per_cpu": nil,
+			"load_avg_1": 0.0, "load_avg_5": 0.0, "load_avg_15": 0.0,
+		},
+		"network":      map[string]interface{}{"interfaces": []interface{}{map[string]interface{}{"name": "eth0"}}},
+		"disk":         map[string]interface{}{"partitions": []interface{}{map[string]interface{}{"mountpoint": "/"}}},
+		"containers":   []interface{}{},
+		"docker_error": "docker not available",
+	}, sparse)
+
+	// The cached snapshot is left alone
+	assert.Len(t, metrics.CPU.UsagePerCPU, 2)
+	assert.Len(t, metrics.Network.Interfaces, 2)
+	assert.Len(t, metrics.Disk.Partitions, 2)
+}
+
+func TestMetricsSections(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	srv.handlers.docker = nil
+	metrics := &system.AllMetrics{
+		CPU:    system.CPUInfo{UsageTotal: 12.5},
+		Errors: map[string]string{"disk": "failed", "memory": "failed"},
+	}
+
+	resp := srv.handlers.metricsResponse(metrics, metricsOptions{sections: []string{"cpu", "memory"}, docker: true})
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.ElementsMatch(t, []string{"timestamp", "cpu", "memory", "errors", "containers", "docker_error"}, keys(got))
+	assert.Equal(t, map[string]interface{}{"memory": "failed"}, got["errors"])
+}
+
+func keys(m map[string]interface{}) []string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	return list
+}
+
+func TestMetricsTopProcesses(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	req := httptest.NewRequest("GET", "/api/metrics?top_processes=3&fields=top_processes.pid", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"top_processes":[{"pid":`)
+}
+
+func TestMetricsPower(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	srv.handlers.energy = energy.NewMeter([]energy.Estimate{{Name: "nas", Idle: 20, Max: 20}}, time.Minute, "")
+
+	resp := srv.handlers.metricsResponse(&system.AllMetrics{}, metricsOptions{power: true})
+	usage := resp.(metricsResponse).powerUsage
+	require.NotNil(t, usage)
+	require.NotNil(t, usage.Power)
+	assert.GreaterOrEqual(t, usage.Power.Watts, 20.0)
+	assert.True(t, usage.Power.Estimated)
+
+	for query, want := range map[string]int{"": http.StatusOK, "?hours=48": http.StatusOK, "?hours=0": http.StatusBadRequest} {
+		req := httptest.NewRequest("GET", "/api/metrics/power/history"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, query)
+	}
+}
@@ -1,12 +1,24 @@
 package server
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/crash"
+	"github.com/ngenohkevin/hivedeck-agent/internal/privilege"
 )
 
 // AuthMiddleware creates authentication middleware
@@ -14,34 +26,140 @@ func AuthMiddleware(auth *AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := ExtractToken(c)
 		if token == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "missing authentication token",
-			})
+			// The settings page authenticates with a session cookie, which
+			// needs a CSRF token on anything that changes state
+			if claims, ok := sessionFromCookie(c, auth); ok {
+				if !safeMethod(c.Request.Method) && !validCSRF(c.GetHeader(CSRFHeader), claims.CSRF) {
+					abortMessage(c, http.StatusForbidden, "missing or invalid CSRF token", nil)
+					return
+				}
+				c.Set("auth_method", "session")
+				cookie, _ := c.Cookie(sessionCookie)
+				c.Set(principalKey, principal("session", claims.ID, cookie))
+				c.Next()
+				return
+			}
+
+			abortMessage(c, http.StatusUnauthorized, "missing authentication token", nil)
 			return
 		}
 
 		// Try API key first
 		if auth.ValidateAPIKey(token) {
 			c.Set("auth_method", "api_key")
+			c.Set(principalKey, principal("api_key", "", token))
 			c.Next()
 			return
 		}
 
 		// Try JWT
 		claims, err := auth.ValidateToken(token)
+		var skew *ClockSkewError
+		if errors.As(err, &skew) {
+			abortMessage(c, http.StatusUnauthorized, "authentication token expired or not yet valid: check the clocks of the agent and the client", skew.Details(c.GetHeader("Date")))
+			return
+		}
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "invalid authentication token",
-			})
+			abortMessage(c, http.StatusUnauthorized, "invalid authentication token", nil)
 			return
 		}
 
 		c.Set("auth_method", "jwt")
 		c.Set("claims", claims)
+		c.Set(principalKey, principal("jwt", claims.ID, token))
+		c.Next()
+	}
+}
+
+// principalKey holds the credential that authenticated a request, for
+// approvals to tell requester and approver apart
+const principalKey = "auth_principal"
+
+// principal names a credential by its ID, or by a hash of the token when it
+// has none. Everyone holding the API key is one principal.
+func principal(method, id, token string) string {
+	if id != "" {
+		return method + ":" + id
+	}
+	sum := sha256.Sum256([]byte(token))
+	return method + ":" + hex.EncodeToString(sum[:8])
+}
+
+// ModuleMiddleware rejects requests to a module disabled in the config
+func ModuleMiddleware(cfg *config.Config, module string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.ModuleEnabled(module) {
+			abortMessage(c, http.StatusForbidden, "module "+module+" is disabled", map[string]interface{}{"module": module})
+			return
+		}
 		c.Next()
 	}
 }
 
+// PrivilegeMiddleware rejects requests for a feature the agent lacks the
+// privileges for, rather than letting the operation fail part way
+func PrivilegeMiddleware(report *privilege.Report, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !report.Allowed(feature) {
+			abortMessage(c, http.StatusForbidden, "insufficient privileges: "+report.Limited[feature],
+				map[string]interface{}{"feature": feature})
+			return
+		}
+		c.Next()
+	}
+}
+
+// baseContextKey holds the request context from before LimitsMiddleware set
+// a deadline, for handlers that validate their own ?timeout=
+const baseContextKey = "base_context"
+
+// routeGroup returns the route group of an API path: its first segment
+// after /api/
+func routeGroup(path string) string {
+	group := strings.TrimPrefix(path, "/api/")
+	if i := strings.Index(group, "/"); i >= 0 {
+		group = group[:i]
+	}
+	return group
+}
+
+// LimitsMiddleware applies the request timeout and body size limit of the
+// route group. Streaming endpoints have no timeout.
+func LimitsMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := routeGroup(c.Request.URL.Path)
+
+		if limit := cfg.RouteBodyLimit(group); limit > 0 && c.Request.Body != nil {
+			if c.Request.ContentLength > limit {
+				abortMessage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", limit),
+					map[string]interface{}{"limit": limit})
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+
+		timeout := cfg.RouteTimeout(group)
+		if timeout <= 0 || isStreamingPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		c.Set(baseContextKey, c.Request.Context())
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// baseContext returns the request context without the route group deadline
+func baseContext(c *gin.Context) context.Context {
+	if ctx, ok := c.Value(baseContextKey).(context.Context); ok {
+		return ctx
+	}
+	return c.Request.Context()
+}
+
 // RateLimiter implements a simple rate limiter
 type RateLimiter struct {
 	requests map[string][]time.Time
@@ -90,9 +208,7 @@ func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
 		key := c.ClientIP()
 
 		if !limiter.Allow(key) {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "rate limit exceeded",
-			})
+			abortMessage(c, http.StatusTooManyRequests, "rate limit exceeded", nil)
 			return
 		}
 
@@ -118,53 +234,70 @@ func LoggerMiddleware() gin.HandlerFunc {
 		authenticated := authMethod != nil
 
 		log.Printf("[%s] %s %s | Status: %d | Latency: %v | Client: %s | Auth: %v",
-			method, path, c.Request.URL.RawQuery, status, latency, clientIP, authenticated)
+			method, path, redactQuery(path, c.Request.URL.Query()), status, latency, clientIP, authenticated)
 	}
 }
 
-// RecoveryMiddleware handles panics
-func RecoveryMiddleware() gin.HandlerFunc {
+// redactQuery hides credentials passed as query parameters so they do not
+// end up in logs: ?token= anywhere, and ?key= from old settings page links
+func redactQuery(path string, q url.Values) string {
+	if q.Has("token") {
+		q.Set("token", "REDACTED")
+	}
+	if path == "/settings" && q.Has("key") {
+		q.Set("key", "REDACTED")
+	}
+	return q.Encode()
+}
+
+// RecoveryMiddleware handles panics, sending them to reporter if set
+func RecoveryMiddleware(reporter *crash.Reporter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("[PANIC] %v", err)
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-					"error": "internal server error",
-				})
+				stack := debug.Stack()
+				log.Printf("[PANIC] %v\n%s", err, stack)
+				if reporter != nil {
+					reporter.Panic(err, stack, c.Request)
+				}
+				abortMessage(c, http.StatusInternalServerError, "internal server error", nil)
 			}
 		}()
 		c.Next()
 	}
 }
 
-// CORSMiddleware handles CORS headers
-func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
-	allowAll := len(allowedOrigins) == 1 && allowedOrigins[0] == "*"
+// apiContentSecurityPolicy denies everything; JSON responses load nothing
+const apiContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
 
+// SecurityHeadersMiddleware sets protective response headers. HTML pages
+// replace the API content security policy with setPageHeaders.
+func SecurityHeadersMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		origin := c.GetHeader("Origin")
-
-		if allowAll {
-			c.Header("Access-Control-Allow-Origin", "*")
-		} else {
-			for _, allowed := range allowedOrigins {
-				if origin == allowed {
-					c.Header("Access-Control-Allow-Origin", origin)
-					break
-				}
-			}
+		if !cfg.SecurityHeaders {
+			c.Next()
+			return
 		}
 
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Max-Age", "86400")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Content-Security-Policy", apiContentSecurityPolicy)
+		if cfg.FrameOptions != "" {
+			header.Set("X-Frame-Options", cfg.FrameOptions)
+		}
+		if cfg.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		if cfg.HSTSMaxAge > 0 && isHTTPS(c.Request) {
+			header.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", cfg.HSTSMaxAge))
 		}
 
 		c.Next()
 	}
 }
+
+// isHTTPS reports whether the client reached the agent over TLS, directly
+// or through a proxy such as Tailscale Serve
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -1,13 +1,20 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/privilege"
 )
 
 func init() {
@@ -86,6 +93,32 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
 
+func TestAuthMiddleware_ClockSkew(t *testing.T) {
+	auth := NewAuthService("test-api-key", "test-secret")
+
+	router := gin.New()
+	router.Use(AuthMiddleware(auth))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	// The client's clock is a day ahead of the agent's
+	clientTime := time.Now().Add(24 * time.Hour)
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, "test-secret", clientTime, time.Hour))
+	req.Header.Set("Date", clientTime.UTC().Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	var resp apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Contains(t, resp.Message, "check the clocks")
+	assert.InDelta(t, 86400, resp.Details["clock_skew_seconds"], 2)
+	assert.Contains(t, resp.Details, "server_time")
+	assert.Contains(t, resp.Details, "issued_at")
+}
+
 func TestRateLimiter(t *testing.T) {
 	limiter := NewRateLimiter(5) // 5 requests per second
 
@@ -129,7 +162,7 @@ func TestRateLimitMiddleware(t *testing.T) {
 
 func TestCORSMiddleware(t *testing.T) {
 	router := gin.New()
-	router.Use(CORSMiddleware([]string{"*"}))
+	router.Use(CORSMiddleware([]string{"*"}, time.Hour))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
@@ -142,11 +175,13 @@ func TestCORSMiddleware(t *testing.T) {
 
 	assert.Equal(t, http.StatusNoContent, w.Code)
 	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-CSRF-Token")
 }
 
 func TestCORSMiddleware_SpecificOrigins(t *testing.T) {
 	router := gin.New()
-	router.Use(CORSMiddleware([]string{"http://allowed.com", "http://also-allowed.com"}))
+	router.Use(CORSMiddleware([]string{"http://allowed.com", "http://also-allowed.com"}, 0))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
@@ -157,6 +192,7 @@ func TestCORSMiddleware_SpecificOrigins(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, "http://allowed.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Max-Age"), "a max age of 0 leaves it to the browser")
 
 	// Not allowed origin
 	req = httptest.NewRequest("GET", "/test", nil)
@@ -166,9 +202,69 @@ func TestCORSMiddleware_SpecificOrigins(t *testing.T) {
 	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
 }
 
+func TestCORSMiddleware_Credentials(t *testing.T) {
+	router := gin.New()
+	router.Use(CORSMiddleware([]string{"*", "https://dash.example.com"}, 0))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	// A wildcard never allows credentials
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	// A listed origin does
+	req.Header.Set("Origin", "https://dash.example.com")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "https://dash.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSMiddleware_Routes(t *testing.T) {
+	router := gin.New()
+	router.Use(CORSMiddleware([]string{"https://dash.lan"}, 0,
+		config.CORSRoute{Prefix: "/health", Origins: []string{"*"}},
+		config.CORSRoute{Prefix: "/api/metrics", Origins: []string{"https://*.example.com"}},
+		config.CORSRoute{Prefix: "/api/metrics/private"},
+	))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/health", ok)
+	router.GET("/api/metrics/cpu", ok)
+	router.GET("/api/metrics/private", ok)
+	router.GET("/api/services", ok)
+
+	allowed := func(path, origin string) string {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Equal(t, "*", allowed("/health", "https://anywhere.test"))
+	assert.Equal(t, "https://grafana.example.com", allowed("/api/metrics/cpu", "https://grafana.example.com"))
+	assert.Equal(t, "https://a.b.example.com", allowed("/api/metrics/cpu", "https://a.b.example.com"))
+	assert.Empty(t, allowed("/api/metrics/cpu", "https://example.com"))
+	assert.Empty(t, allowed("/api/metrics/cpu", "http://grafana.example.com"))
+	assert.Empty(t, allowed("/api/metrics/cpu", "https://grafana.example.com:8443"))
+	assert.Empty(t, allowed("/api/metrics/cpu", "https://evilexample.com"))
+	assert.Empty(t, allowed("/api/metrics/private", "https://grafana.example.com"))
+
+	// Other paths keep ALLOWED_ORIGINS
+	assert.Equal(t, "https://dash.lan", allowed("/api/services", "https://dash.lan"))
+	assert.Empty(t, allowed("/api/services", "https://grafana.example.com"))
+	assert.Empty(t, allowed("/health-check", "https://anywhere.test"))
+}
+
 func TestRecoveryMiddleware(t *testing.T) {
 	router := gin.New()
-	router.Use(RecoveryMiddleware())
+	router.Use(RecoveryMiddleware(nil))
 	router.GET("/panic", func(c *gin.Context) {
 		panic("test panic")
 	})
@@ -183,3 +279,251 @@ func TestRecoveryMiddleware(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func TestModuleMiddleware_DisabledModule(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.FilesEnabled = false
+	srv := New(cfg)
+
+	for _, path := range []string{"/api/files?path=/tmp", "/api/files/content?path=/tmp/x"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+		assert.Contains(t, w.Body.String(), "module files is disabled")
+	}
+
+	// Other modules are unaffected
+	req := httptest.NewRequest("GET", "/api/capabilities", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"files":false`)
+}
+
+func TestPrivilegeMiddleware(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	// Routes hold the detected report; make it look like a non-root agent
+	*srv.handlers.privileges = privilege.Report{
+		User:    "hivedeck",
+		UID:     999,
+		Limited: map[string]string{privilege.FeaturePower: "polkit does not allow reboot"},
+	}
+
+	req := httptest.NewRequest("POST", "/api/system/reboot", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient privileges")
+
+	// Capabilities say which features are limited and why
+	req = httptest.NewRequest("GET", "/api/capabilities", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"limited":{"power":"polkit does not allow reboot"}`)
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AllowedPaths = []string{"/tmp"}
+	srv := New(cfg)
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/api/files?path=/etc", http.StatusForbidden, "not_allowed"},
+		{"/api/files/content", http.StatusBadRequest, "bad_request"},
+		{"/api/tasks/missing/run", http.StatusNotFound, "not_found"},
+		{"/api/jobs/missing", http.StatusNotFound, "not_found"},
+	}
+
+	for _, tt := range tests {
+		method := "GET"
+		if strings.HasSuffix(tt.path, "/run") {
+			method = "POST"
+		}
+		req := httptest.NewRequest(method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+
+		srv.Router().ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.path)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), tt.path)
+		assert.Equal(t, tt.code, body["code"], tt.path)
+		assert.NotEmpty(t, body["message"], tt.path)
+		assert.Equal(t, body["message"], body["error"], tt.path)
+	}
+
+	// Requests rejected by middleware use the same envelope
+	req := httptest.NewRequest("GET", "/api/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"unauthorized"`)
+}
+
+func TestRouteGroup(t *testing.T) {
+	assert.Equal(t, "metrics", routeGroup("/api/metrics"))
+	assert.Equal(t, "metrics", routeGroup("/api/metrics/cpu"))
+	assert.Equal(t, "tasks", routeGroup("/api/tasks/df/run"))
+}
+
+func TestLimitsMiddleware_Timeout(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+
+	router := gin.New()
+	router.Use(LimitsMiddleware(cfg))
+	var remaining time.Duration
+	var hasDeadline bool
+	handler := func(c *gin.Context) {
+		var deadline time.Time
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+		c.Status(http.StatusOK)
+	}
+	router.GET("/api/metrics", handler)
+	router.GET("/api/tasks", handler)
+	router.GET("/api/events", handler)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/metrics", nil))
+	require.True(t, hasDeadline)
+	assert.InDelta(t, (10 * time.Second).Seconds(), remaining.Seconds(), 1)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/tasks", nil))
+	require.True(t, hasDeadline)
+	assert.InDelta(t, (30 * time.Minute).Seconds(), remaining.Seconds(), 1)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/events", nil))
+	assert.False(t, hasDeadline, "streams have no timeout")
+}
+
+func TestLimitsMiddleware_BodyLimit(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.MaxBodyBytes = 16
+
+	router := gin.New()
+	router.Use(LimitsMiddleware(cfg))
+	router.POST("/api/batch", func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("POST", "/api/batch", strings.NewReader(`{"a": "0123456789"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(16), resp["details"].(map[string]interface{})["limit"])
+
+	// Without a Content-Length the body is cut off while reading
+	req = httptest.NewRequest("POST", "/api/batch", strings.NewReader(`{"a": "0123456789"}`))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest("POST", "/api/batch", strings.NewReader(`{"a": 1}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+
+	router := gin.New()
+	router.Use(SecurityHeadersMiddleware(cfg))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, apiContentSecurityPolicy, w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"), "HSTS is only sent over HTTPS")
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "max-age=31536000; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+
+	cfg.SecurityHeaders = false
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	assert.Empty(t, w.Header().Get("X-Content-Type-Options"))
+}
+
+func TestSettingsPageHeaders(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/settings", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, config.DefaultContentSecurityPolicy, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+}
+
+func TestConcurrencyMiddleware(t *testing.T) {
+	limiter := newConnLimiter(1, 1)
+
+	router := gin.New()
+	router.Use(ConcurrencyMiddleware(limiter))
+	entered, unblock := make(chan struct{}), make(chan struct{})
+	router.GET("/api/events", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-unblock
+		c.Status(http.StatusOK)
+	})
+	router.GET("/api/metrics", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/events", nil))
+		done <- w.Code
+	}()
+	<-entered
+	assert.Equal(t, connUsage{Streams: 1, MaxStreams: 1, MaxRequests: 1}, limiter.Usage())
+
+	// The stream slot is taken, but ordinary requests have their own
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/events", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, connRetryAfter, w.Header().Get("Retry-After"))
+	var body apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Contains(t, body.Error, "too many concurrent streams")
+	assert.Equal(t, "streams", body.Details["kind"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	close(unblock)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, connUsage{MaxStreams: 1, MaxRequests: 1}, limiter.Usage())
+}
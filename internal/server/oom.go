@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOOMKills handles GET /api/system/oom-kills with the processes the
+// kernel killed for lack of memory since boot, newest first
+func (h *Handlers) ListOOMKills(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		respondMessage(c, http.StatusBadRequest, "limit must be a positive number")
+		return
+	}
+	c.JSON(http.StatusOK, h.oomWatcher.Recent(limit))
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestRunTask_AsyncOperation(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AllowedTasks["hello"] = config.Task{Name: "hello", Command: "echo hello", Description: "Test"}
+	srv := New(cfg)
+
+	do := func(method, path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/tasks/hello/run", map[string]string{"Prefer": "respond-async"})
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var op struct {
+		ID     string `json:"id"`
+		Type   string `json:"type"`
+		Target string `json:"target"`
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &op))
+	assert.Equal(t, "task.run", op.Type)
+	assert.Equal(t, "hello", op.Target)
+	assert.Equal(t, "/api/operations/"+op.ID, w.Header().Get("Location"))
+
+	require.Eventually(t, func() bool {
+		w := do("GET", "/api/operations/"+op.ID, nil)
+		return w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &op) == nil && op.Status == "succeeded"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Without async the handler still waits for the result
+	w = do("POST", "/api/tasks/hello/run", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "hello")
+}
+
+func TestRunTask_FailedTask(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AllowedTasks["fail"] = config.Task{Name: "fail", Command: "echo broken; exit 3", Description: "Test"}
+	srv := New(cfg)
+
+	req := httptest.NewRequest("POST", "/api/tasks/fail/run", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+
+	// The task ran, so the request succeeded and the result has the failure
+	require.Equal(t, http.StatusOK, w.Code)
+	var result struct {
+		Success  bool   `json:"success"`
+		ExitCode int    `json:"exit_code"`
+		Output   string `json:"output"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.False(t, result.Success)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Contains(t, result.Output, "broken")
+}
+
+func TestRunTask_OperationTimeout(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AllowedTasks["slow"] = config.Task{Name: "slow", Command: "sleep 5", Description: "Test"}
+	srv := New(cfg)
+
+	run := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/tasks/slow/run"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, run("?timeout=forever").Code)
+	assert.Equal(t, http.StatusBadRequest, run("?timeout=2h").Code)
+
+	start := time.Now()
+	w := run("?timeout=50ms")
+	assert.Less(t, time.Since(start), 4*time.Second)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"timeout"`)
+}
+
+func TestRunOperation_OutlivesRouteTimeout(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	srv := New(cfg)
+	require.Equal(t, 2*time.Minute, cfg.RouteTimeout("docker"))
+
+	router := gin.New()
+	router.Use(LimitsMiddleware(cfg))
+	var remaining time.Duration
+	router.POST("/api/docker/pull", func(c *gin.Context) {
+		srv.handlers.runOperation(c, "image.pull", "nginx", imagePullTimeout, func(ctx context.Context) (interface{}, error) {
+			deadline, _ := ctx.Deadline()
+			remaining = time.Until(deadline)
+			return "done", nil
+		})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/docker/pull", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	// A sync pull gets its 15 minutes, not the docker group's 2
+	assert.InDelta(t, imagePullTimeout.Seconds(), remaining.Seconds(), 1)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/privilege"
+)
+
+func TestLimitProcess(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	*srv.handlers.privileges = privilege.Report{User: "root", Limited: map[string]string{}}
+
+	limit := func(pid int, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/processes/%d/limit", pid), strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, limit(1, `{"cpu_quota": 50}`).Code)
+	assert.Equal(t, http.StatusBadRequest, limit(os.Getpid(), `{"memory_max": "lots"}`).Code)
+
+	// The test binary is not in ALLOWED_PROCESSES
+	w := limit(os.Getpid(), `{"cpu_quota": 50, "memory_max": "512M"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "not allowed")
+}
+
+func TestGetZombies(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	req := httptest.NewRequest("GET", "/api/processes/zombies?refresh=true", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"parents":[`)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/profile"
+)
+
+// newProfileCollector includes Docker images when Docker is enabled
+func (h *Handlers) newProfileCollector() *profile.Collector {
+	var images profile.ImagesFunc
+	if h.cfg.DockerEnabled {
+		images = func(ctx context.Context) (map[string]string, error) {
+			manager := h.dockerManager()
+			if manager == nil {
+				return nil, errDockerUnavailable
+			}
+			list, err := manager.ListImages(ctx)
+			if err != nil {
+				return nil, err
+			}
+			result := map[string]string{}
+			for _, img := range list {
+				for _, tag := range img.RepoTags {
+					if tag != "<none>:<none>" {
+						result[tag] = img.ID
+					}
+				}
+			}
+			return result, nil
+		}
+	}
+	return profile.NewCollector(h.cfg.ProfileFiles, h.cfg.Labels, images)
+}
+
+// GetProfile handles GET /api/profile: packages, services, Docker images
+// and configuration hashes, for comparing hosts
+func (h *Handlers) GetProfile(c *gin.Context) {
+	c.JSON(http.StatusOK, h.profiles.Collect(c.Request.Context()))
+}
+
+// CompareProfile handles POST /api/profile/compare. The body is another
+// agent's profile; the response lists how it differs from this host.
+func (h *Handlers) CompareProfile(c *gin.Context) {
+	var remote profile.Profile
+	if err := c.ShouldBindJSON(&remote); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid profile: "+err.Error())
+		return
+	}
+
+	local := h.profiles.Collect(c.Request.Context())
+	c.JSON(http.StatusOK, profile.Compare(local, &remote))
+}
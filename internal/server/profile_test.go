@@ -0,0 +1,52 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestProfile(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.DockerEnabled = false
+	srv := New(cfg)
+
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("GET", "/api/profile", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var profile struct {
+		Digests map[string]string `json:"digests"`
+		Errors  map[string]string `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+	assert.NotEmpty(t, profile.Digests["all"])
+	assert.Equal(t, "docker is not enabled", profile.Errors["images"])
+
+	// A host compared with itself has no drift
+	w = do("POST", "/api/profile/compare", w.Body.Bytes())
+	require.Equal(t, http.StatusOK, w.Code)
+	var drift struct {
+		Identical bool     `json:"identical"`
+		Skipped   []string `json:"skipped"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &drift))
+	assert.True(t, drift.Identical)
+	assert.Contains(t, drift.Skipped, "images")
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/profile/compare", []byte("{")).Code)
+}
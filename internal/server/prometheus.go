@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/cache"
+	"github.com/ngenohkevin/hivedeck-agent/internal/prom"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
+)
+
+// GetPrometheusMetrics handles GET on PROMETHEUS_PATH with host and
+// container metrics in the Prometheus text format. Sections that fail to
+// collect are left out, and hivedeck_scrape_error reports them.
+func (h *Handlers) GetPrometheusMetrics(c *gin.Context) {
+	w := prom.NewWriter()
+
+	info := map[string]string{}
+	for name, value := range h.cfg.Labels {
+		info[prom.LabelName(name)] = value
+	}
+	info["version"] = h.cfg.Version
+	w.Gauge("hivedeck_agent_info", "Agent version and host labels from LABELS, always 1", 1, prom.Labels(info)...)
+	w.Gauge("hivedeck_agent_journal_followers", "journalctl processes and native readers following the journal", float64(systemd.ActiveFollowers()))
+
+	metrics, err := h.cache.GetOrSet(cache.KeyAll, func() (interface{}, error) {
+		return h.metricsCollector.GetAllMetrics()
+	})
+	if err != nil {
+		w.Gauge("hivedeck_scrape_error", "1 if a section failed to collect", 1, "section", "system")
+	} else {
+		writeSystemMetrics(w, metrics.(*system.AllMetrics))
+	}
+
+	if h.docker != nil {
+		usage := h.containerUsage()
+		up := 1.0
+		if usage.DockerError != "" {
+			up = 0
+		}
+		w.Gauge("hivedeck_docker_up", "1 if the Docker daemon answered", up)
+		for _, ct := range usage.Containers {
+			labels := []string{"id", ct.ID, "name", ct.Name}
+			w.Gauge("hivedeck_container_cpu_usage_percent", "Container CPU use since the previous sample, 100 per core", ct.CPUPercent, labels...)
+			w.Gauge("hivedeck_container_memory_usage_bytes", "Container memory use", float64(ct.MemoryUsage), labels...)
+			w.Gauge("hivedeck_container_memory_limit_bytes", "Container memory limit", float64(ct.MemoryLimit), labels...)
+		}
+	}
+
+	if h.energy.Available() {
+		if power, err := h.energy.Current(); err == nil {
+			w.Gauge("hivedeck_power_watts", "Power draw, including configured estimates", power.Watts)
+		}
+	}
+	if h.cfg.ProcessesEnabled {
+		if report, err := h.zombies.Report(); err == nil {
+			w.Gauge("hivedeck_zombie_processes", "Zombie processes at the last check", float64(report.Total))
+		}
+	}
+
+	c.Data(http.StatusOK, prom.ContentType, w.Bytes())
+}
+
+// writeSystemMetrics adds a system snapshot. Sections that failed, listed
+// in Errors, are reported as scrape errors.
+func writeSystemMetrics(w *prom.Writer, m *system.AllMetrics) {
+	for section := range m.Errors {
+		w.Gauge("hivedeck_scrape_error", "1 if a section failed to collect", 1, "section", section)
+	}
+
+	if _, failed := m.Errors["host"]; !failed {
+		w.Gauge("hivedeck_boot_time_seconds", "Boot time as a Unix timestamp", float64(m.Host.BootTime))
+		w.Gauge("hivedeck_uptime_seconds", "Seconds since boot", float64(m.Host.Uptime))
+		w.Gauge("hivedeck_processes", "Running processes", float64(m.Host.Procs))
+		for _, t := range m.Host.Temperatures {
+			w.Gauge("hivedeck_temperature_celsius", "Sensor temperature", t.Temperature, "sensor", t.SensorKey)
+		}
+	}
+
+	if _, failed := m.Errors["cpu"]; !failed {
+		w.Gauge("hivedeck_cpu_cores", "Logical CPUs", float64(m.CPU.Cores))
+		w.Gauge("hivedeck_cpu_usage_percent", "CPU use across all cores", m.CPU.UsageTotal)
+		for i, usage := range m.CPU.UsagePerCPU {
+			w.Gauge("hivedeck_cpu_core_usage_percent", "CPU use of one core", usage, "cpu", strconv.Itoa(i))
+		}
+		w.Gauge("hivedeck_load1", "1-minute load average", m.CPU.LoadAvg1)
+		w.Gauge("hivedeck_load5", "5-minute load average", m.CPU.LoadAvg5)
+		w.Gauge("hivedeck_load15", "15-minute load average", m.CPU.LoadAvg15)
+	}
+
+	if _, failed := m.Errors["memory"]; !failed {
+		w.Gauge("hivedeck_memory_total_bytes", "Physical memory", float64(m.Memory.Total))
+		w.Gauge("hivedeck_memory_available_bytes", "Memory available to new programs", float64(m.Memory.Available))
+		w.Gauge("hivedeck_memory_used_bytes", "Memory in use", float64(m.Memory.Used))
+		w.Gauge("hivedeck_memory_buffers_bytes", "Memory in kernel buffers", float64(m.Memory.Buffers))
+		w.Gauge("hivedeck_memory_cached_bytes", "Memory in the page cache", float64(m.Memory.Cached))
+		w.Gauge("hivedeck_swap_total_bytes", "Swap space", float64(m.Memory.SwapTotal))
+		w.Gauge("hivedeck_swap_used_bytes", "Swap in use", float64(m.Memory.SwapUsed))
+	}
+
+	if _, failed := m.Errors["disk"]; !failed {
+		for _, p := range m.Disk.Partitions {
+			labels := []string{"device", p.Device, "mountpoint", p.Mountpoint, "fstype", p.Fstype}
+			w.Gauge("hivedeck_filesystem_size_bytes", "Filesystem size", float64(p.Total), labels...)
+			w.Gauge("hivedeck_filesystem_used_bytes", "Filesystem space in use", float64(p.Used), labels...)
+			w.Gauge("hivedeck_filesystem_free_bytes", "Filesystem space free", float64(p.Free), labels...)
+		}
+	}
+
+	if _, failed := m.Errors["network"]; !failed {
+		for _, iface := range m.Network.Interfaces {
+			name := []string{"interface", iface.Name}
+			w.Counter("hivedeck_network_receive_bytes_total", "Bytes received", float64(iface.BytesRecv), name...)
+			w.Counter("hivedeck_network_transmit_bytes_total", "Bytes sent", float64(iface.BytesSent), name...)
+			w.Counter("hivedeck_network_receive_packets_total", "Packets received", float64(iface.PacketsRecv), name...)
+			w.Counter("hivedeck_network_transmit_packets_total", "Packets sent", float64(iface.PacketsSent), name...)
+			w.Counter("hivedeck_network_receive_errors_total", "Receive errors", float64(iface.Errin), name...)
+			w.Counter("hivedeck_network_transmit_errors_total", "Transmit errors", float64(iface.Errout), name...)
+			w.Counter("hivedeck_network_receive_drop_total", "Received packets dropped", float64(iface.Dropin), name...)
+			w.Counter("hivedeck_network_transmit_drop_total", "Sent packets dropped", float64(iface.Dropout), name...)
+		}
+	}
+}
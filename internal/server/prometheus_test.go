@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/prom"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	scrape := func(srv *Server, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/metrics/prometheus", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	cfg := config.LoadWithDefaults()
+	assert.Equal(t, http.StatusNotFound, scrape(New(cfg), "test-api-key").Code, "disabled by default")
+
+	cfg.PrometheusEnabled = true
+	cfg.DockerEnabled = false
+	cfg.Labels = map[string]string{"role": "nas", "rack-id": "r1"}
+	srv := New(cfg)
+	assert.Equal(t, http.StatusUnauthorized, scrape(srv, "").Code)
+
+	w := scrape(srv, "test-api-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, prom.ContentType, w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, `hivedeck_agent_info{rack_id="r1",role="nas",version="dev"} 1`)
+	assert.Contains(t, body, "# TYPE hivedeck_memory_total_bytes gauge")
+	assert.Contains(t, body, "# TYPE hivedeck_network_receive_bytes_total counter")
+	assert.NotContains(t, body, "hivedeck_docker_up")
+
+	cfg.PrometheusAuth = false
+	cfg.PrometheusPath = "/metrics"
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w = httptest.NewRecorder()
+	New(cfg).Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
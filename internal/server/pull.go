@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/pull"
+)
+
+// runPullCommand executes a command from the dashboard's queue against the
+// local API, exactly as a batch call authenticated with the API key would be
+func (s *Server) runPullCommand(ctx context.Context, cmd pull.Command) pull.Result {
+	parent, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/api/batch", nil)
+	parent.Header.Set("Authorization", "Bearer "+s.auth.key())
+	parent.RemoteAddr = "127.0.0.1:0"
+
+	resp := s.dispatchBatchCall(parent, BatchRequest{
+		ID:     cmd.ID,
+		Method: cmd.Method,
+		Path:   cmd.Path,
+		Body:   cmd.Body,
+	})
+
+	return pull.Result{
+		ID:         cmd.ID,
+		Status:     resp.Status,
+		Body:       resp.Body,
+		ExecutedAt: time.Now(),
+	}
+}
+
+// GetPullStatus handles GET /api/pull
+func (s *Server) GetPullStatus(c *gin.Context) {
+	if s.pullClient == nil {
+		c.JSON(http.StatusOK, pull.Status{Enabled: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.pullClient.Status())
+}
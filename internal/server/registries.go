@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+)
+
+// GetRegistrySettings handles GET /api/settings/registries. Passwords are
+// never returned.
+func (s *Server) GetRegistrySettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"registries": s.handlers.registryAuth.Logins()})
+}
+
+// UpdateRegistrySettings handles PUT /api/settings/registries. Each
+// registry in the body is added or replaced, and null removes one. A
+// password left out keeps the saved one. Changes apply to the next pull
+// and are saved to .env as REGISTRY_AUTH, encrypted with ENCRYPT_SECRETS.
+func (s *Server) UpdateRegistrySettings(c *gin.Context) {
+	var req struct {
+		Registries map[string]*docker.Credential `json:"registries" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	creds := s.handlers.registryAuth.Credentials()
+	for host, cred := range req.Registries {
+		host = docker.RegistryHost(strings.TrimSpace(host))
+		if cred == nil {
+			delete(creds, host)
+			continue
+		}
+		if cred.Password == "" && creds[host].Password != "" {
+			cred.Password = creds[host].Password
+		}
+		creds[host] = *cred
+	}
+
+	next := &docker.RegistryAuth{}
+	if err := next.Set(creds); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	value := joinMap(next.Pairs())
+	if err := s.cfg.SaveEnv(map[string]string{"REGISTRY_AUTH": value}); err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
+		return
+	}
+
+	s.cfg.RegistryAuth = value
+	s.handlers.registryAuth.Set(creds)
+	c.JSON(http.StatusOK, gin.H{
+		"registries": s.handlers.registryAuth.Logins(),
+		"encrypted":  s.cfg.EncryptSecrets,
+		"message":    "Registry credentials updated",
+	})
+}
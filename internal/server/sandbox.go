@@ -0,0 +1,79 @@
+package server
+
+import (
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/sandbox"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// sandboxPolicy returns the paths the agent needs for its configured
+// features
+func sandboxPolicy(cfg *config.Config) sandbox.Policy {
+	read := append([]string{}, sandbox.SystemReadPaths...)
+	read = append(read, cfg.TLSCertFile, cfg.TLSKeyFile, cfg.SecretsKeyFile)
+	read = append(read, cfg.IntegrityPaths...)
+	read = append(read, cfg.LogForwardFiles...)
+	read = append(read, cfg.ProfileFiles...)
+	for _, file := range cfg.BackupPasswordFiles {
+		read = append(read, file)
+	}
+	for _, spec := range cfg.BackupRepos {
+		if _, location, _ := strings.Cut(spec, ":"); filepath.IsAbs(location) {
+			read = append(read, location)
+		}
+	}
+	read = append(read, cfg.SandboxReadPaths...)
+
+	// The env file is saved by writing a new file next to it
+	write := append([]string{}, sandbox.SystemWritePaths...)
+	write = append(write, cfg.DataDir, filepath.Dir(cfg.EnvFile))
+	write = append(write, cfg.SandboxWritePaths...)
+
+	// Fetching and pulling write to a checkout
+	for name, path := range cfg.GitRepos {
+		if slices.Contains(cfg.GitPullAllowed, name) || slices.Contains(cfg.GitPullAllowed, "*") {
+			write = append(write, path)
+		} else {
+			read = append(read, path)
+		}
+	}
+
+	// Switching the governor writes to each cpufreq policy
+	if cfg.CPUGovernorControl {
+		write = append(write, system.CPUFreqPath)
+	}
+
+	// Deleting moves files from the allowed paths to the trash
+	if cfg.FilesDeleteEnabled {
+		write = append(write, cfg.AllowedPaths...)
+	} else {
+		read = append(read, cfg.AllowedPaths...)
+	}
+
+	return sandbox.Policy{ReadPaths: read, WritePaths: write, Seccomp: true}
+}
+
+// applySandbox restricts the agent once its listeners are bound and its
+// components started
+func (s *Server) applySandbox() {
+	if !s.cfg.SandboxEnabled {
+		return
+	}
+	if s.cfg.PrivilegeHelper {
+		log.Printf("Sandbox not applied: the privilege helper needs sudo, which the sandbox blocks")
+		s.handlers.sandbox = &sandbox.Status{Errors: []string{"not applied with PRIVILEGE_HELPER"}}
+		return
+	}
+
+	status := sandbox.Apply(sandboxPolicy(s.cfg))
+	for _, err := range status.Errors {
+		log.Printf("Sandbox: %s", err)
+	}
+	log.Printf("Sandbox applied: landlock=%t seccomp=%t", status.Landlock, status.Seccomp)
+	s.handlers.sandbox = status
+}
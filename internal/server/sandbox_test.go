@@ -0,0 +1,33 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestSandboxPolicy(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.DataDir = "/var/lib/hivedeck-agent"
+	cfg.EnvFile = "/etc/hivedeck/.env"
+	cfg.SandboxReadPaths = []string{"/srv/www"}
+	cfg.BackupRepos = map[string]string{"home": "restic:/srv/restic", "offsite": "restic:sftp:host:/restic"}
+	cfg.BackupPasswordFiles = map[string]string{"home": "/etc/restic.pass"}
+
+	p := sandboxPolicy(cfg)
+	assert.Subset(t, p.ReadPaths, []string{"/usr", "/var/log", "/tmp", "/srv/www", "/srv/restic", "/etc/restic.pass"})
+	assert.NotContains(t, p.ReadPaths, "sftp:host:/restic")
+	assert.Subset(t, p.WritePaths, []string{"/dev", "/var/lib/hivedeck-agent", "/etc/hivedeck"})
+	assert.True(t, p.Seccomp)
+
+	// Deleting files needs write access to the allowed paths
+	cfg.FilesDeleteEnabled = true
+	assert.Contains(t, sandboxPolicy(cfg).WritePaths, "/var/log")
+
+	// So does switching the CPU governor
+	assert.NotContains(t, sandboxPolicy(cfg).WritePaths, "/sys/devices/system/cpu/cpufreq")
+	cfg.CPUGovernorControl = true
+	assert.Contains(t, sandboxPolicy(cfg).WritePaths, "/sys/devices/system/cpu/cpufreq")
+}
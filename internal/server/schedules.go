@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/audit"
+	"github.com/ngenohkevin/hivedeck-agent/internal/schedules"
+	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
+)
+
+// serviceDetail is a service's status with its upcoming scheduled actions
+type serviceDetail struct {
+	*systemd.ServiceInfo
+	Schedules []schedules.Schedule `json:"schedules"`
+}
+
+// ListServiceSchedules handles GET /api/services/schedules, soonest
+// first. service= limits the list to one service.
+func (h *Handlers) ListServiceSchedules(c *gin.Context) {
+	c.JSON(http.StatusOK, h.schedules.List(c.Query("service")))
+}
+
+// CreateServiceSchedule handles POST /api/services/schedules
+func (h *Handlers) CreateServiceSchedule(c *gin.Context) {
+	var req schedules.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	item, err := h.schedules.Add(req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	h.recordAudit(c, "service.schedule", item.Service, true, fmt.Sprintf("%s at %s", item.Action, item.NextRun.Format("2006-01-02 15:04")))
+	c.JSON(http.StatusCreated, item)
+}
+
+// DeleteServiceSchedule handles DELETE /api/services/schedules/:id
+func (h *Handlers) DeleteServiceSchedule(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.schedules.Delete(id); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.recordAudit(c, "service.unschedule", id, true, "")
+	c.JSON(http.StatusOK, gin.H{"id": id, "message": "Schedule deleted"})
+}
+
+// runScheduledAction runs a schedule's service action, recording it as the
+// API would
+func (h *Handlers) runScheduledAction(ctx context.Context, service, action string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.ServiceActionTimeout)
+	defer cancel()
+
+	do := map[string]func(context.Context, string) (*systemd.ServiceAction, error){
+		"start":   h.serviceManager.Start,
+		"stop":    h.serviceManager.Stop,
+		"restart": h.serviceManager.Restart,
+	}[action]
+	result, err := do(ctx, service)
+	if err == nil && !result.Success && !result.Pending {
+		err = errors.New(result.Message)
+	}
+
+	message := fmt.Sprintf("Scheduled %s of %s", action, service)
+	if err != nil {
+		message = fmt.Sprintf("Scheduled %s of %s failed: %v", action, service, err)
+	}
+	h.auditLog.Record(audit.Entry{
+		Action:  "service." + action,
+		Target:  service,
+		Actor:   "schedule",
+		Success: err == nil,
+		Message: message,
+	})
+	h.publishAction("service."+action, "schedule", err == nil, message, gin.H{"service": service, "action": action})
+	if err != nil {
+		return "", err
+	}
+	return result.Message, nil
+}
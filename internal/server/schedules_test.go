@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestServiceSchedules(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.DataDir = t.TempDir()
+	srv := New(cfg)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/services/schedules", `{"service":"nginx","action":"restart","at":"+1h"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, "services outside the allow list are refused")
+
+	w = do("POST", "/api/services/schedules", `{"service":"test-service","action":"reload","at":"+1h"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do("POST", "/api/services/schedules", `{"service":"test-service","action":"restart","schedule":"daily 03:00","note":"nightly"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		ID       string `json:"id"`
+		Schedule string `json:"schedule"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "daily 03:00", created.Schedule)
+
+	w = do("GET", "/api/services/schedules?service=test-service", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Total int `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Total)
+
+	w = do("DELETE", "/api/services/schedules/"+created.ID, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = do("DELETE", "/api/services/schedules/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
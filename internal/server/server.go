@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/crash"
+	"github.com/ngenohkevin/hivedeck-agent/internal/privilege"
+	"github.com/ngenohkevin/hivedeck-agent/internal/pull"
 )
 
 // Server represents the HTTP server
@@ -23,7 +29,9 @@ type Server struct {
 	setupHandlers *SetupHandlers
 	auth          *AuthService
 	limiter       *RateLimiter
-	httpServer    *http.Server
+	pullClient    *pull.Client    // nil unless pull mode is configured
+	reporter      *crash.Reporter // nil unless crash reporting is configured
+	httpServers   []*http.Server
 }
 
 // New creates a new server instance
@@ -38,9 +46,10 @@ func New(cfg *config.Config) *Server {
 	router := gin.New()
 
 	auth := NewAuthService(cfg.APIKey, cfg.JWTSecret)
+	auth.SetLeeway(cfg.JWTLeeway)
 	limiter := NewRateLimiter(cfg.RateLimitRPS)
 	handlers := NewHandlers(cfg)
-	setupHandlers := NewSetupHandlers(cfg)
+	setupHandlers := NewSetupHandlers(cfg, auth)
 
 	s := &Server{
 		cfg:           cfg,
@@ -51,6 +60,22 @@ func New(cfg *config.Config) *Server {
 		limiter:       limiter,
 	}
 
+	reporter, err := crash.NewReporter(crash.Options{
+		SentryDSN:  cfg.SentryDSN,
+		WebhookURL: cfg.CrashWebhookURL,
+		Version:    cfg.Version,
+		Hostname:   hostname(),
+	})
+	if err != nil {
+		log.Printf("Crash reporting disabled: %v", err)
+	}
+	s.reporter = reporter
+
+	if cfg.PullEnabled() {
+		s.pullClient = pull.NewClient(cfg.PullURL, cfg.PullSecret, hostname(), cfg.PullInterval, cfg.PullAllowed, s.runPullCommand)
+		s.pullClient.SetLabels(cfg.Labels)
+	}
+
 	s.setupMiddleware()
 	s.setupRoutes()
 
@@ -59,13 +84,16 @@ func New(cfg *config.Config) *Server {
 
 func (s *Server) setupMiddleware() {
 	// Recovery middleware
-	s.router.Use(RecoveryMiddleware())
+	s.router.Use(RecoveryMiddleware(s.reporter))
 
 	// Logger middleware
 	s.router.Use(LoggerMiddleware())
 
+	// Security headers
+	s.router.Use(SecurityHeadersMiddleware(s.cfg))
+
 	// CORS middleware
-	s.router.Use(CORSMiddleware(s.cfg.AllowedOrigins))
+	s.router.Use(CORSMiddleware(s.cfg.AllowedOrigins, s.cfg.CORSMaxAge, s.cfg.CORSRoutes...))
 
 	// Rate limiting
 	s.router.Use(RateLimitMiddleware(s.limiter))
@@ -75,9 +103,10 @@ func (s *Server) setupRoutes() {
 	// Health check (no auth)
 	s.router.GET("/health", s.handlers.HealthCheck)
 
-	// Setup routes (no auth required in setup mode)
+	// Setup routes (no auth required in setup mode). They close as soon as
+	// a key is saved; the API routes accept it without a restart.
 	if s.cfg.SetupMode {
-		setup := s.router.Group("/setup")
+		setup := s.router.Group("/setup", SetupModeMiddleware(s.auth), CSRFMiddleware())
 		{
 			setup.GET("", s.setupHandlers.SetupPage)
 			setup.POST("/generate", s.setupHandlers.GenerateKey)
@@ -85,12 +114,38 @@ func (s *Server) setupRoutes() {
 		}
 	}
 
+	// Prometheus scrapes, optionally without auth
+	if s.cfg.PrometheusEnabled {
+		handlers := []gin.HandlerFunc{s.handlers.GetPrometheusMetrics}
+		if s.cfg.PrometheusAuth {
+			handlers = append([]gin.HandlerFunc{AuthMiddleware(s.auth)}, handlers...)
+		}
+		s.router.GET(s.cfg.PrometheusPath, handlers...)
+	}
+
+	// Inbound webhooks, authenticated by their signature
+	s.router.POST("/hooks/:name", s.handlers.TriggerWebhook)
+
 	// API routes (require auth)
 	api := s.router.Group("/api")
+	api.Use(LimitsMiddleware(s.cfg))
 	api.Use(AuthMiddleware(s.auth))
+	api.Use(ConcurrencyMiddleware(s.handlers.conns))
+	api.Use(FieldsMiddleware())
 	{
 		// Server info
 		api.GET("/info", s.handlers.GetInfo)
+		api.GET("/capabilities", s.handlers.GetCapabilities)
+
+		// Batch requests
+		api.POST("/batch", s.HandleBatch)
+
+		// Pull mode status
+		api.GET("/pull", s.GetPullStatus)
+
+		// GraphQL
+		api.GET("/graphql", s.handlers.GraphQL)
+		api.POST("/graphql", s.handlers.GraphQL)
 
 		// Metrics
 		api.GET("/metrics", s.handlers.GetAllMetrics)
@@ -98,95 +153,352 @@ func (s *Server) setupRoutes() {
 		api.GET("/metrics/memory", s.handlers.GetMemoryMetrics)
 		api.GET("/metrics/disk", s.handlers.GetDiskMetrics)
 		api.GET("/metrics/network", s.handlers.GetNetworkMetrics)
+		api.GET("/metrics/power", s.handlers.GetPowerMetrics)
+		api.GET("/metrics/power/history", s.handlers.GetPowerHistory)
 
 		// Processes
-		api.GET("/processes", s.handlers.ListProcesses)
-		api.POST("/processes/:pid/kill", s.handlers.KillProcess)
+		processes := api.Group("/processes", ModuleMiddleware(s.cfg, config.ModuleProcesses))
+		processes.GET("", s.handlers.ListProcesses)
+		processes.GET("/zombies", s.handlers.GetZombies)
+		processes.POST("/:pid/kill", s.handlers.KillProcess)
+		processes.POST("/:pid/limit", PrivilegeMiddleware(s.handlers.privileges, privilege.FeatureServices), s.handlers.LimitProcess)
 
 		// Services (systemd)
-		api.GET("/services", s.handlers.ListServices)
-		api.GET("/services/:name", s.handlers.GetService)
-		api.POST("/services/:name/start", s.handlers.StartService)
-		api.POST("/services/:name/stop", s.handlers.StopService)
-		api.POST("/services/:name/restart", s.handlers.RestartService)
+		services := api.Group("/services", ModuleMiddleware(s.cfg, config.ModuleServices))
+		services.GET("", s.handlers.ListServices)
+		services.GET("/databases", s.handlers.GetDatabaseHealth)
+		services.GET("/schedules", s.handlers.ListServiceSchedules)
+		services.GET("/:name", s.handlers.GetService)
+		serviceControl := services.Group("", PrivilegeMiddleware(s.handlers.privileges, privilege.FeatureServices))
+		serviceControl.POST("/:name/start", s.handlers.StartService)
+		serviceControl.POST("/:name/stop", s.handlers.StopService)
+		serviceControl.POST("/:name/restart", s.handlers.RestartService)
+		serviceControl.POST("/:name/enable", s.handlers.EnableService)
+		serviceControl.POST("/:name/disable", s.handlers.DisableService)
+		serviceControl.POST("/:name/mask", s.handlers.MaskService)
+		serviceControl.POST("/:name/unmask", s.handlers.UnmaskService)
+		serviceControl.POST("/daemon-reload", s.handlers.ReloadSystemd)
+		serviceControl.POST("/schedules", s.handlers.CreateServiceSchedule)
+		serviceControl.DELETE("/schedules/:id", s.handlers.DeleteServiceSchedule)
+		services.GET("/:name/jobs/:id", s.handlers.GetServiceJob)
 
 		// Logs
-		api.GET("/logs", s.handlers.StreamLogs)
-		api.GET("/logs/query", s.handlers.GetLogs)
-		api.GET("/logs/:unit", s.handlers.GetUnitLogs)
+		logs := api.Group("/logs", ModuleMiddleware(s.cfg, config.ModuleLogs))
+		logs.GET("", s.handlers.StreamLogs)
+		logs.GET("/query", s.handlers.GetLogs)
+		logs.GET("/forwarding", s.handlers.GetLogForwarding)
+		logs.GET("/:unit", s.handlers.GetUnitLogs)
 
 		// Docker
-		api.GET("/docker/containers", s.handlers.ListContainers)
-		api.GET("/docker/containers/:id", s.handlers.GetContainer)
-		api.POST("/docker/containers/:id/start", s.handlers.StartContainer)
-		api.POST("/docker/containers/:id/stop", s.handlers.StopContainer)
-		api.POST("/docker/containers/:id/restart", s.handlers.RestartContainer)
-		api.GET("/docker/containers/:id/logs", s.handlers.GetContainerLogs)
+		dockerAPI := api.Group("/docker", ModuleMiddleware(s.cfg, config.ModuleDocker))
+		dockerAPI.GET("/status", s.handlers.GetDockerStatus)
+		dockerAPI.GET("/containers", s.handlers.ListContainers)
+		dockerAPI.POST("/containers", s.handlers.CreateContainer)
+		dockerAPI.GET("/containers/:id", s.handlers.GetContainer)
+		dockerAPI.POST("/containers/:id/start", s.handlers.StartContainer)
+		dockerAPI.POST("/containers/:id/stop", s.handlers.StopContainer)
+		dockerAPI.POST("/containers/:id/restart", s.handlers.RestartContainer)
+		dockerAPI.POST("/containers/:id/pause", s.handlers.PauseContainer)
+		dockerAPI.POST("/containers/:id/unpause", s.handlers.UnpauseContainer)
+		dockerAPI.POST("/containers/:id/rename", s.handlers.RenameContainer)
+		dockerAPI.DELETE("/containers/:id", s.handlers.RemoveContainer)
+		dockerAPI.POST("/containers/:id/update", s.handlers.UpdateContainerResources)
+		dockerAPI.POST("/containers/:id/update-image", s.handlers.UpdateContainerImage)
+		dockerAPI.POST("/containers/:id/resources", s.handlers.UpdateContainerResources)
+		dockerAPI.GET("/containers/:id/logs", s.handlers.GetContainerLogs)
+		dockerAPI.GET("/containers/:id/logs/stream", s.handlers.StreamContainerLogs)
+		dockerAPI.GET("/containers/:id/stats", s.handlers.GetContainerStats)
+		dockerAPI.GET("/containers/:id/stats/stream", s.handlers.StreamContainerStats)
+		dockerAPI.GET("/containers/:id/top", s.handlers.GetContainerTop)
+		dockerAPI.GET("/containers/:id/diff", s.handlers.GetContainerDiff)
+		dockerAPI.GET("/stats", s.handlers.GetAllContainerStats)
+		dockerAPI.GET("/images", s.handlers.ListImages)
+		dockerAPI.POST("/images/pull", s.handlers.PullImage)
+		dockerAPI.DELETE("/images/:id", s.handlers.RemoveImage)
+		dockerAPI.GET("/volumes", s.handlers.ListVolumes)
+		dockerAPI.GET("/volumes/:name", s.handlers.GetVolume)
+		dockerAPI.GET("/networks", s.handlers.ListNetworks)
+		dockerAPI.GET("/networks/:id", s.handlers.GetNetwork)
+		dockerAPI.GET("/system/df", s.handlers.GetDockerDiskUsage)
+		dockerAPI.POST("/prune", s.handlers.PruneDocker)
+		dockerAPI.GET("/updates", s.handlers.GetImageUpdates)
+
+		// Compose deployments
+		deployAPI := api.Group("/deploy", ModuleMiddleware(s.cfg, config.ModuleDocker))
+		deployAPI.POST("", s.handlers.Deploy)
+		deployAPI.GET("/:id/stream", s.handlers.StreamDeploy)
+
+		// Inbound webhooks (triggered at /hooks/:name)
+		api.GET("/webhooks", s.handlers.ListWebhooks)
 
 		// Files
-		api.GET("/files", s.handlers.ListDirectory)
-		api.GET("/files/paths", s.handlers.GetAllowedPaths)
-		api.GET("/files/content", s.handlers.GetFileContent)
-		api.GET("/files/diskusage", s.handlers.GetDiskUsage)
+		filesAPI := api.Group("/files", ModuleMiddleware(s.cfg, config.ModuleFiles))
+		filesAPI.GET("", s.handlers.ListDirectory)
+		filesAPI.GET("/paths", s.handlers.GetAllowedPaths)
+		filesAPI.GET("/content", s.handlers.GetFileContent)
+		filesAPI.GET("/preview", s.handlers.PreviewFile)
+		filesAPI.GET("/diff", s.handlers.DiffFiles)
+		filesAPI.POST("/diff", s.handlers.DiffFiles)
+		filesAPI.GET("/diskusage", s.handlers.GetDiskUsage)
+		filesAPI.DELETE("", s.handlers.DeleteFile)
+		filesAPI.GET("/trash", s.handlers.ListTrash)
+		filesAPI.DELETE("/trash", s.handlers.EmptyTrash)
+		filesAPI.POST("/trash/:id/restore", s.handlers.RestoreTrashItem)
+		filesAPI.DELETE("/trash/:id", s.handlers.PurgeTrashItem)
 
 		// Tasks
-		api.GET("/tasks", s.handlers.ListTasks)
-		api.POST("/tasks/:name/run", s.handlers.RunTask)
+		tasksAPI := api.Group("/tasks", ModuleMiddleware(s.cfg, config.ModuleTasks))
+		tasksAPI.GET("", s.handlers.ListTasks)
+		tasksAPI.POST("/:name/run", s.handlers.RunTask)
+		tasksAPI.GET("/units", s.handlers.ListTaskUnits)
+		taskUnits := tasksAPI.Group("", PrivilegeMiddleware(s.handlers.privileges, privilege.FeatureServices))
+		taskUnits.POST("/:name/launch", s.handlers.LaunchTask)
+		taskUnits.DELETE("/units/:unit", s.handlers.StopTaskUnit)
+
+		// System power and maintenance
+		api.GET("/system/power", s.handlers.GetPowerStatus)
+		powerControl := api.Group("/system", PrivilegeMiddleware(s.handlers.privileges, privilege.FeaturePower))
+		powerControl.POST("/reboot", s.handlers.RebootSystem)
+		powerControl.POST("/shutdown", s.handlers.ShutdownSystem)
+		powerControl.POST("/power/cancel", s.handlers.CancelPowerAction)
+		api.GET("/system/coredumps", s.handlers.ListCoredumps)
+		api.GET("/system/coredumps/:pid", s.handlers.GetCoredump)
+		api.GET("/system/oom-kills", s.handlers.ListOOMKills)
+		api.POST("/system/cpu/governor", s.handlers.SetCPUGovernor)
+		api.GET("/system/maintenance", s.handlers.GetMaintenance)
+		api.POST("/system/maintenance", s.handlers.EnableMaintenance)
+		api.DELETE("/system/maintenance", s.handlers.DisableMaintenance)
+
+		// Scheduled auto-maintenance (upgrades, pruning, journal vacuum)
+		api.GET("/maintenance", s.handlers.GetAutoMaintenance)
+		api.POST("/maintenance/run", s.handlers.RunAutoMaintenance)
+
+		// Diagnostics
+		api.GET("/diagnostics/port", s.handlers.ProbePort)
+		api.POST("/speedtest", s.handlers.RunSpeedTest)
+		api.GET("/speedtest/history", s.handlers.GetSpeedTestHistory)
+
+		// Background operations (jobs is the original name)
+		api.GET("/operations", s.handlers.ListJobs)
+		api.GET("/operations/:id", s.handlers.GetJob)
+		api.GET("/jobs", s.handlers.ListJobs)
+		api.GET("/jobs/:id", s.handlers.GetJob)
+
+		// Agent internals
+		api.GET("/agent/stats", s.handlers.GetAgentStats)
+		api.GET("/agent/storage", s.handlers.GetStorage)
+		api.POST("/agent/storage/prune", s.handlers.PruneStorage)
+		api.GET("/agent/cache", s.handlers.GetCacheStats)
+		api.DELETE("/agent/cache", s.handlers.InvalidateCache)
+		api.PUT("/agent/cache/ttl", s.handlers.SetCacheTTL)
+
+		// File integrity monitoring
+		api.GET("/integrity", s.handlers.GetIntegrityReport)
+		api.POST("/integrity/check", s.handlers.CheckIntegrity)
+		api.POST("/integrity/baseline", s.handlers.AcceptIntegrityBaseline)
+
+		// Listening port exposure
+		api.GET("/security/exposure", s.handlers.GetExposure)
+
+		// Vulnerability scans
+		api.GET("/security/vulnerabilities", s.handlers.GetVulnerabilities)
+		api.POST("/security/vulnerabilities/scan", s.handlers.ScanVulnerabilities)
+
+		// Temperature alerts and fan hooks
+		api.GET("/thermal", s.handlers.GetThermal)
+
+		// GPIO pin states (read-only)
+		api.GET("/gpio", s.handlers.GetGPIO)
+
+		// Pi-hole, AdGuard Home and unbound statistics
+		api.GET("/dns", s.handlers.GetDNSServers)
+
+		// Reverse-proxy upstream health
+		api.GET("/upstreams", s.handlers.GetUpstreams)
+		api.POST("/upstreams/check", s.handlers.CheckUpstreams)
+
+		// Restic/borg repository status (read-only)
+		api.GET("/backups", s.handlers.ListBackups)
+		api.GET("/backups/:name/snapshots", s.handlers.GetBackupSnapshots)
+
+		// Host profile for drift comparison between agents
+		api.GET("/profile", s.handlers.GetProfile)
+		api.POST("/profile/compare", s.handlers.CompareProfile)
+
+		// Git checkouts
+		api.GET("/git", s.handlers.ListGitRepos)
+		api.GET("/git/:name", s.handlers.GetGitRepo)
+		api.POST("/git/:name/pull", s.handlers.PullGitRepo)
+
+		// Operator notes for overlaying on metric history
+		api.GET("/annotations", s.handlers.ListAnnotations)
+		api.POST("/annotations", s.handlers.CreateAnnotation)
+		api.GET("/annotations/:id", s.handlers.GetAnnotation)
+		api.PUT("/annotations/:id", s.handlers.UpdateAnnotation)
+		api.DELETE("/annotations/:id", s.handlers.DeleteAnnotation)
+
+		// Approvals for destructive actions
+		api.GET("/approvals", s.handlers.ListApprovals)
+		api.GET("/approvals/:id", s.handlers.GetApproval)
+		api.POST("/approvals/:id/approve", s.handlers.ApproveAction)
+		api.POST("/approvals/:id/reject", s.handlers.RejectAction)
+
+		// Alert rules, their state and notification sinks
+		api.GET("/alerts", s.handlers.ListAlerts)
+		api.POST("/alerts/rules", s.handlers.CreateAlert)
+		api.GET("/alerts/rules/:id", s.handlers.GetAlert)
+		api.PUT("/alerts/rules/:id", s.handlers.UpdateAlert)
+		api.DELETE("/alerts/rules/:id", s.handlers.DeleteAlert)
+		api.POST("/alerts/sinks/:name/test", s.handlers.TestAlertSink)
+
+		// Audit log
+		api.GET("/audit", s.handlers.GetAuditLog)
 
 		// Real-time events (SSE)
 		api.GET("/events", s.handlers.StreamEvents)
+		api.GET("/events/recent", s.handlers.GetRecentEvents)
+
+		// WebSocket with subscriptions to metrics, events, logs and Docker events
+		api.GET("/ws", s.handlers.WebSocket)
+
+		// MQTT publisher status
+		api.GET("/mqtt", s.handlers.GetMQTTStatus)
+
+		// Dead-man-switch heartbeat status
+		api.GET("/heartbeat", s.handlers.GetHeartbeatStatus)
 
 		// Settings (authenticated)
 		api.GET("/settings", s.setupHandlers.GetSettings)
 		api.PUT("/settings", s.setupHandlers.UpdateSettings)
 		api.POST("/settings/generate-key", s.setupHandlers.GenerateKey)
 		api.POST("/settings/api-key", s.setupHandlers.SaveKey)
+		api.GET("/settings/integrations", s.setupHandlers.GetIntegrationSettings)
+		api.PUT("/settings/integrations", s.setupHandlers.UpdateIntegrationSettings)
+		api.GET("/settings/cache", s.handlers.GetCacheSettings)
+		api.PUT("/settings/cache", s.handlers.UpdateCacheSettings)
+		api.GET("/settings/labels", s.GetLabelSettings)
+		api.PUT("/settings/labels", s.UpdateLabelSettings)
+		api.GET("/settings/registries", s.GetRegistrySettings)
+		api.PUT("/settings/registries", s.UpdateRegistrySettings)
 	}
 
-	// Settings page (requires auth via query param)
+	// Dashboard and settings pages (sign in with the API key and use a session cookie)
+	s.router.GET("/", s.setupHandlers.DashboardPage)
 	s.router.GET("/settings", s.setupHandlers.SettingsPage)
+	s.router.POST("/settings/login", s.Login)
+	s.router.POST("/settings/logout", s.Logout)
 }
 
-// Run starts the HTTP server
+// Run starts the HTTP servers, one per listener, and blocks until they stop
 func (s *Server) Run() error {
-	s.httpServer = &http.Server{
-		Addr:         s.cfg.Addr(),
-		Handler:      s.router,
-		ReadTimeout:  s.cfg.ReadTimeout,
-		WriteTimeout: s.cfg.WriteTimeout,
+	for _, l := range s.cfg.ListenAddrs() {
+		srv, err := s.newHTTPServer(l)
+		if err != nil {
+			return err
+		}
+		s.httpServers = append(s.httpServers, srv)
+	}
+
+	// Bind every address before serving, so a bad one fails startup
+	listeners := make([]net.Listener, len(s.httpServers))
+	for i, srv := range s.httpServers {
+		ln, err := s.listen(srv.Addr)
+		if err != nil {
+			for _, open := range listeners[:i] {
+				open.Close()
+			}
+			return fmt.Errorf("failed to start server: %w", err)
+		}
+		listeners[i] = ln
 	}
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	errs := make(chan error, len(s.httpServers))
 	go func() {
-		<-quit
-		log.Println("Shutting down server...")
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		if err := s.httpServer.Shutdown(ctx); err != nil {
-			log.Printf("Server forced to shutdown: %v", err)
+		select {
+		case <-quit:
+			log.Println("Shutting down server...")
+		case err := <-errs:
+			// One listener failed; stop the others and report it
+			errs <- err
 		}
+		s.shutdown()
 	}()
 
-	log.Printf("Starting Hivedeck Agent on %s", s.cfg.Addr())
+	s.handlers.Start()
+	if s.pullClient != nil {
+		s.pullClient.Start()
+		log.Printf("Pull mode enabled: polling %s every %s", s.cfg.PullURL, s.cfg.PullInterval)
+	}
+	s.applySandbox()
 
-	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("failed to start server: %w", err)
+	scheme := "http"
+	if s.cfg.TLSEnabled() {
+		scheme = "https"
 	}
 
+	var wg sync.WaitGroup
+	for i, srv := range s.httpServers {
+		paths := "all paths"
+		if p := s.cfg.ListenAddrs()[i].Paths; len(p) > 0 {
+			paths = strings.Join(p, ", ")
+		}
+		log.Printf("Starting Hivedeck Agent on %s://%s (%s)", scheme, srv.Addr, paths)
+
+		wg.Add(1)
+		go func(srv *http.Server, ln net.Listener) {
+			defer wg.Done()
+			if err := s.serve(srv, ln); err != nil && err != http.ErrServerClosed {
+				errs <- fmt.Errorf("server on %s: %w", srv.Addr, err)
+			}
+		}(srv, listeners[i])
+	}
+	wg.Wait()
+
 	// Clean up
+	if s.pullClient != nil {
+		s.pullClient.Stop()
+	}
 	if err := s.handlers.Close(); err != nil {
 		log.Printf("Error closing handlers: %v", err)
 	}
+	if s.reporter != nil {
+		s.reporter.Flush()
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+	}
 
 	log.Println("Server stopped")
 	return nil
 }
 
+// shutdown gracefully stops every HTTP server
+func (s *Server) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, srv := range s.httpServers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Server on %s forced to shutdown: %v", srv.Addr, err)
+		}
+	}
+}
+
+// ReportFatal sends an error that stops the agent to the crash reporter,
+// if one is configured
+func (s *Server) ReportFatal(err error) {
+	if s.reporter != nil {
+		s.reporter.Fatal(err, nil)
+	}
+}
+
 // Router returns the Gin router (for testing)
 func (s *Server) Router() *gin.Engine {
 	return s.router
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// CSRFHeader carries the CSRF token on state-changing requests made
+	// with a session cookie
+	CSRFHeader = "X-CSRF-Token"
+
+	// SessionTTL is how long a settings page session lasts
+	SessionTTL = 12 * time.Hour
+
+	sessionCookie   = "hivedeck_session"
+	csrfCookie      = "hivedeck_csrf"
+	sessionAudience = "hivedeck-session"
+
+	// csrfContextKey holds the CSRF token to embed in a page
+	csrfContextKey = "csrf_token"
+)
+
+// SessionClaims are the claims of a session cookie. CSRF is the token the
+// page must echo in X-CSRF-Token.
+type SessionClaims struct {
+	jwt.RegisteredClaims
+	CSRF string `json:"csrf"`
+}
+
+// NewSession issues a signed session token and its CSRF token
+func (a *AuthService) NewSession(ttl time.Duration) (token, csrf string, err error) {
+	secret, err := a.secret()
+	if err != nil {
+		return "", "", err
+	}
+
+	csrf, err = randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := SessionClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "hivedeck-agent",
+			Audience:  jwt.ClaimStrings{sessionAudience},
+		},
+		CSRF: csrf,
+	}
+
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	return token, csrf, err
+}
+
+// ValidateSession validates a session token. API tokens from
+// GenerateToken are not sessions and are rejected.
+func (a *AuthService) ValidateSession(tokenString string) (*SessionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &SessionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return a.secret()
+	}, a.parserOptions(jwt.WithAudience(sessionAudience))...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*SessionClaims)
+	if !ok || !token.Valid || claims.CSRF == "" {
+		return nil, errors.New("invalid session")
+	}
+	return claims, nil
+}
+
+// sessionFromCookie returns the claims of a valid session cookie
+func sessionFromCookie(c *gin.Context, auth *AuthService) (*SessionClaims, bool) {
+	value, err := c.Cookie(sessionCookie)
+	if err != nil || value == "" {
+		return nil, false
+	}
+	claims, err := auth.ValidateSession(value)
+	if err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// safeMethod reports whether a method cannot change state
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// validCSRF compares a submitted CSRF token with the expected one
+func validCSRF(submitted, expected string) bool {
+	return submitted != "" && subtle.ConstantTimeCompare([]byte(submitted), []byte(expected)) == 1
+}
+
+// setCookie sets an HttpOnly, SameSite=Strict cookie, marked Secure over
+// HTTPS. A negative maxAge deletes it.
+func setCookie(c *gin.Context, name, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   isHTTPS(c.Request),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CSRFMiddleware protects pages used before an API key exists with a
+// double-submit token: a random value in a SameSite cookie that
+// state-changing requests must echo in X-CSRF-Token
+func CSRFMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, _ := c.Cookie(csrfCookie)
+
+		if safeMethod(c.Request.Method) {
+			if token == "" {
+				var err error
+				if token, err = randomToken(); err != nil {
+					abortMessage(c, http.StatusInternalServerError, "failed to create CSRF token", nil)
+					return
+				}
+				setCookie(c, csrfCookie, token, 0)
+			}
+			c.Set(csrfContextKey, token)
+			c.Next()
+			return
+		}
+
+		if !validCSRF(c.GetHeader(CSRFHeader), token) {
+			abortMessage(c, http.StatusForbidden, "missing or invalid CSRF token", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetupModeMiddleware closes the setup pages once an API key is configured,
+// so the unauthenticated setup flow cannot replace the key. The page itself
+// redirects to the sign-in page.
+func SetupModeMiddleware(auth *AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.Configured() {
+			c.Next()
+			return
+		}
+		if c.Request.Method == http.MethodGet {
+			c.Redirect(http.StatusFound, "/settings")
+			c.Abort()
+			return
+		}
+		abortMessage(c, http.StatusGone, "setup is complete: sign in at /settings", nil)
+	}
+}
+
+// Login handles POST /settings/login. A valid API key starts a session for
+// the settings page, so the key never appears in a URL.
+func (s *Server) Login(c *gin.Context) {
+	var req struct {
+		APIKey string `json:"api_key" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: api_key is required")
+		return
+	}
+
+	if !s.auth.ValidateAPIKey(req.APIKey) {
+		respondMessage(c, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+
+	token, csrf, err := s.auth.NewSession(SessionTTL)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	setCookie(c, sessionCookie, token, int(SessionTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{
+		"csrf_token": csrf,
+		"expires_at": time.Now().Add(SessionTTL),
+	})
+}
+
+// Logout handles POST /settings/logout
+func (s *Server) Logout(c *gin.Context) {
+	setCookie(c, sessionCookie, "", -1)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
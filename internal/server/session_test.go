@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+// login signs in to the settings page and returns the session cookie and
+// CSRF token
+func login(t *testing.T, srv *Server) (*http.Cookie, string) {
+	req := httptest.NewRequest("POST", "/settings/login", strings.NewReader(`{"api_key": "test-api-key"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		CSRFToken string `json:"csrf_token"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	return cookies[0], resp.CSRFToken
+}
+
+func TestLogin(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	req := httptest.NewRequest("POST", "/settings/login", strings.NewReader(`{"api_key": "wrong"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+
+	cookie, csrf := login(t, srv)
+	assert.Equal(t, sessionCookie, cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.Len(t, csrf, 64)
+}
+
+func TestSessionAuth(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+	cookie, csrf := login(t, srv)
+
+	// Reads need only the cookie
+	req := httptest.NewRequest("GET", "/api/settings", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// State changes need the CSRF token too
+	req = httptest.NewRequest("POST", "/api/settings/generate-key", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest("POST", "/api/settings/generate-key", nil)
+	req.AddCookie(cookie)
+	req.Header.Set(CSRFHeader, csrf)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestSessionRejectsAPITokens(t *testing.T) {
+	auth := NewAuthService("test-api-key", "test-jwt-secret")
+	token, err := auth.GenerateToken("admin", time.Hour)
+	require.NoError(t, err)
+
+	_, err = auth.ValidateSession(token)
+	assert.Error(t, err)
+}
+
+func TestSettingsPage(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/settings", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Sign in with the agent's API key")
+
+	cookie, csrf := login(t, srv)
+	req := httptest.NewRequest("GET", "/settings", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "const CSRF_TOKEN = '"+csrf+"'")
+}
+
+func TestDashboardPage(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Sign in with the agent's API key")
+
+	cookie, csrf := login(t, srv)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), "const CSRF_TOKEN = '"+csrf+"'")
+	assert.Contains(t, w.Body.String(), "new EventSource('/api/events')")
+}
+
+// setupConfig returns a config in setup mode, as Load does without an API key
+func setupConfig(t *testing.T) *config.Config {
+	cfg := config.LoadWithDefaults()
+	cfg.APIKey, cfg.JWTSecret = "", ""
+	cfg.SetupMode = true
+	cfg.EnvFile = filepath.Join(t.TempDir(), ".env")
+	return cfg
+}
+
+func TestSetupCSRF(t *testing.T) {
+	srv := New(setupConfig(t))
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/setup", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	token := cookies[0].Value
+	assert.Contains(t, w.Body.String(), "const CSRF_TOKEN = '"+token+"'")
+
+	req := httptest.NewRequest("POST", "/setup/generate", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest("POST", "/setup/generate", nil)
+	req.AddCookie(cookies[0])
+	req.Header.Set(CSRFHeader, token)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestSetupAppliesKeyWithoutRestart(t *testing.T) {
+	srv := New(setupConfig(t))
+	newKey := strings.Repeat("k", 40)
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/setup", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	csrfCookie := w.Result().Cookies()[0]
+
+	// No key works before setup, not even an empty-secret JWT
+	req := httptest.NewRequest("GET", "/api/info", nil)
+	req.Header.Set("Authorization", "Bearer "+newKey)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest("POST", "/setup/save", strings.NewReader(`{"api_key": "`+newKey+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CSRFHeader, csrfCookie.Value)
+	req.AddCookie(csrfCookie)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("GET", "/api/agent/cache", nil)
+	req.Header.Set("Authorization", "Bearer "+newKey)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Setup is closed: the page redirects and the key cannot be replaced
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/setup", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/settings", w.Header().Get("Location"))
+
+	req = httptest.NewRequest("POST", "/setup/save", strings.NewReader(`{"api_key": "`+strings.Repeat("x", 40)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CSRFHeader, csrfCookie.Value)
+	req.AddCookie(csrfCookie)
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGone, w.Code)
+}
+
+func TestRedactQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/settings?key=secret&token=abc&lines=10", nil)
+	assert.Equal(t, "key=REDACTED&lines=10&token=REDACTED", redactQuery("/settings", req.URL.Query()))
+
+	req = httptest.NewRequest("GET", "/api/agent/cache?key=metrics:all", nil)
+	assert.Equal(t, "key=metrics%3Aall", redactQuery("/api/agent/cache", req.URL.Query()))
+}
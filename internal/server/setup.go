@@ -2,6 +2,8 @@ package server
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ngenohkevin/hivedeck-agent/config"
@@ -9,24 +11,45 @@ import (
 
 // SetupHandlers handles the setup and settings endpoints
 type SetupHandlers struct {
-	cfg *config.Config
+	cfg  *config.Config
+	auth *AuthService
 }
 
 // NewSetupHandlers creates setup handlers
-func NewSetupHandlers(cfg *config.Config) *SetupHandlers {
-	return &SetupHandlers{cfg: cfg}
+func NewSetupHandlers(cfg *config.Config, auth *AuthService) *SetupHandlers {
+	return &SetupHandlers{cfg: cfg, auth: auth}
 }
 
 // SetupPage serves the initial setup HTML page (no auth required)
 func (h *SetupHandlers) SetupPage(c *gin.Context) {
-	c.Header("Content-Type", "text/html; charset=utf-8")
-	c.String(http.StatusOK, setupPageHTML)
+	h.servePage(c, setupPageHTML, c.GetString(csrfContextKey))
 }
 
-// SettingsPage serves the settings HTML page (requires auth)
+// SettingsPage serves the settings HTML page to a signed-in session, and
+// the sign-in page otherwise
 func (h *SetupHandlers) SettingsPage(c *gin.Context) {
+	claims, ok := sessionFromCookie(c, h.auth)
+	if !ok {
+		h.servePage(c, loginPageHTML, "")
+		return
+	}
+	h.servePage(c, settingsPageHTML, claims.CSRF)
+}
+
+// servePage writes an HTML page with its CSRF token filled in
+func (h *SetupHandlers) servePage(c *gin.Context, page, csrf string) {
+	h.setPageHeaders(c)
 	c.Header("Content-Type", "text/html; charset=utf-8")
-	c.String(http.StatusOK, settingsPageHTML)
+	c.String(http.StatusOK, strings.ReplaceAll(page, "{{csrf_token}}", csrf))
+}
+
+// setPageHeaders applies the page content security policy. The pages handle
+// API keys, so they are never cached.
+func (h *SetupHandlers) setPageHeaders(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if h.cfg.SecurityHeaders && h.cfg.ContentSecurityPolicy != "" {
+		c.Header("Content-Security-Policy", h.cfg.ContentSecurityPolicy)
+	}
 }
 
 // GetSettings returns current settings (requires auth)
@@ -41,9 +64,16 @@ func (h *SetupHandlers) GetSettings(c *gin.Context) {
 		"log_level":        h.cfg.LogLevel,
 		"rate_limit_rps":   h.cfg.RateLimitRPS,
 		"env_file":         h.cfg.EnvFile,
-		"setup_mode":       h.cfg.SetupMode,
+		"setup_mode":       !h.auth.Configured(),
+		"security_headers": gin.H{
+			"enabled":                 h.cfg.SecurityHeaders,
+			"hsts_max_age":            h.cfg.HSTSMaxAge,
+			"frame_options":           h.cfg.FrameOptions,
+			"referrer_policy":         h.cfg.ReferrerPolicy,
+			"content_security_policy": h.cfg.ContentSecurityPolicy,
+		},
 		// Don't expose the actual API key, just indicate if it's set
-		"api_key_configured": h.cfg.APIKey != "",
+		"api_key_configured": h.auth.Configured(),
 	})
 }
 
@@ -51,9 +81,7 @@ func (h *SetupHandlers) GetSettings(c *gin.Context) {
 func (h *SetupHandlers) GenerateKey(c *gin.Context) {
 	apiKey, err := config.GenerateAPIKey()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate API key: " + err.Error(),
-		})
+		respondMessage(c, http.StatusInternalServerError, "Failed to generate API key: "+err.Error())
 		return
 	}
 
@@ -69,33 +97,27 @@ func (h *SetupHandlers) SaveKey(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request: api_key is required",
-		})
+		respondMessage(c, http.StatusBadRequest, "Invalid request: api_key is required")
 		return
 	}
 
-	// Validate API key length
-	if len(req.APIKey) < 32 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "API key must be at least 32 characters",
-		})
+	if err := config.ValidateAPIKey(req.APIKey); err != nil {
+		respondMessage(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Save the API key
+	// Save the API key and apply it right away; this also ends setup mode
 	if err := h.cfg.SaveAPIKey(req.APIKey); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save API key: " + err.Error(),
-		})
+		respondMessage(c, http.StatusInternalServerError, "Failed to save API key: "+err.Error())
 		return
 	}
+	h.auth.SetCredentials(h.cfg.APIKey, h.cfg.JWTSecret)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "API key saved successfully",
 		"api_key":  req.APIKey,
 		"env_file": h.cfg.EnvFile,
-		"note":     "Restart the agent to apply the new API key for authentication",
+		"note":     "The new API key is active now; sign in again with it",
 	})
 }
 
@@ -104,15 +126,31 @@ func (h *SetupHandlers) UpdateSettings(c *gin.Context) {
 	var req struct {
 		AllowedPaths    []string `json:"allowed_paths"`
 		AllowedServices []string `json:"allowed_services"`
+		SecurityHeaders *struct {
+			Enabled               *bool   `json:"enabled"`
+			HSTSMaxAge            *int    `json:"hsts_max_age"`
+			FrameOptions          *string `json:"frame_options"`
+			ReferrerPolicy        *string `json:"referrer_policy"`
+			ContentSecurityPolicy *string `json:"content_security_policy"`
+		} `json:"security_headers"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request",
-		})
+		respondMessage(c, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
+	if sh := req.SecurityHeaders; sh != nil {
+		if sh.HSTSMaxAge != nil && *sh.HSTSMaxAge < 0 {
+			respondMessage(c, http.StatusBadRequest, "hsts_max_age must not be negative")
+			return
+		}
+		if sh.FrameOptions != nil && !validFrameOption(*sh.FrameOptions) {
+			respondMessage(c, http.StatusBadRequest, "frame_options must be DENY, SAMEORIGIN or empty")
+			return
+		}
+	}
+
 	// Update settings in config
 	updates := make(map[string]string)
 
@@ -126,11 +164,32 @@ func (h *SetupHandlers) UpdateSettings(c *gin.Context) {
 		updates["ALLOWED_SERVICES"] = joinSlice(req.AllowedServices)
 	}
 
+	if sh := req.SecurityHeaders; sh != nil {
+		if sh.Enabled != nil {
+			h.cfg.SecurityHeaders = *sh.Enabled
+			updates["SECURITY_HEADERS"] = strconv.FormatBool(*sh.Enabled)
+		}
+		if sh.HSTSMaxAge != nil {
+			h.cfg.HSTSMaxAge = *sh.HSTSMaxAge
+			updates["HSTS_MAX_AGE_SECONDS"] = strconv.Itoa(*sh.HSTSMaxAge)
+		}
+		if sh.FrameOptions != nil {
+			h.cfg.FrameOptions = strings.ToUpper(*sh.FrameOptions)
+			updates["FRAME_OPTIONS"] = h.cfg.FrameOptions
+		}
+		if sh.ReferrerPolicy != nil {
+			h.cfg.ReferrerPolicy = *sh.ReferrerPolicy
+			updates["REFERRER_POLICY"] = *sh.ReferrerPolicy
+		}
+		if sh.ContentSecurityPolicy != nil {
+			h.cfg.ContentSecurityPolicy = *sh.ContentSecurityPolicy
+			updates["CONTENT_SECURITY_POLICY"] = *sh.ContentSecurityPolicy
+		}
+	}
+
 	// Save to .env file
-	if err := config.UpdateEnvFile(h.cfg.EnvFile, updates); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save settings: " + err.Error(),
-		})
+	if err := h.cfg.SaveEnv(updates); err != nil {
+		respondMessage(c, http.StatusInternalServerError, "Failed to save settings: "+err.Error())
 		return
 	}
 
@@ -138,10 +197,20 @@ func (h *SetupHandlers) UpdateSettings(c *gin.Context) {
 		"message":          "Settings updated",
 		"allowed_paths":    h.cfg.AllowedPaths,
 		"allowed_services": h.cfg.AllowedServices,
+		"security_headers": h.cfg.SecurityHeaders,
 		"note":             "Some settings may require restart to take effect",
 	})
 }
 
+// validFrameOption reports whether v is an X-Frame-Options value ("" omits the header)
+func validFrameOption(v string) bool {
+	switch strings.ToUpper(v) {
+	case "", "DENY", "SAMEORIGIN":
+		return true
+	}
+	return false
+}
+
 func joinSlice(s []string) string {
 	result := ""
 	for i, v := range s {
@@ -339,7 +408,7 @@ const setupPageHTML = `<!DOCTYPE html>
         <div class="divider">After saving</div>
 
         <div class="alert alert-info">
-            After saving, restart the agent, then add this server in your Hivedeck dashboard using the API key above.
+            The key takes effect as soon as it is saved. Add this server in your Hivedeck dashboard using the API key above, or sign in to the agent's own dashboard with it.
         </div>
     </div>
 
@@ -347,6 +416,7 @@ const setupPageHTML = `<!DOCTYPE html>
         const apiKeyInput = document.getElementById('apiKey');
         const alertDiv = document.getElementById('alert');
         const saveBtn = document.getElementById('saveBtn');
+        const CSRF_TOKEN = '{{csrf_token}}';
 
         function showAlert(message, type) {
             alertDiv.textContent = message;
@@ -355,7 +425,10 @@ const setupPageHTML = `<!DOCTYPE html>
 
         async function generateKey() {
             try {
-                const res = await fetch('/setup/generate', { method: 'POST' });
+                const res = await fetch('/setup/generate', {
+                    method: 'POST',
+                    headers: { 'X-CSRF-Token': CSRF_TOKEN }
+                });
                 const data = await res.json();
                 if (data.api_key) {
                     apiKeyInput.value = data.api_key;
@@ -394,17 +467,14 @@ const setupPageHTML = `<!DOCTYPE html>
             try {
                 const res = await fetch('/setup/save', {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
+                    headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': CSRF_TOKEN },
                     body: JSON.stringify({ api_key: apiKey })
                 });
                 const data = await res.json();
                 if (res.ok) {
-                    showAlert('API key saved! Restart the agent to apply.', 'success');
+                    showAlert('API key saved! Taking you to the sign-in page...', 'success');
                     saveBtn.textContent = 'Saved!';
-                    setTimeout(() => {
-                        saveBtn.disabled = false;
-                        saveBtn.textContent = 'Save API Key';
-                    }, 3000);
+                    setTimeout(() => window.location.href = '/settings', 3000);
                 } else {
                     showAlert(data.error || 'Failed to save', 'error');
                     saveBtn.disabled = false;
@@ -467,6 +537,11 @@ const settingsPageHTML = `<!DOCTYPE html>
             padding-bottom: 12px;
             border-bottom: 1px solid #e5e7eb;
         }
+        .card h3 {
+            color: #374151;
+            font-size: 15px;
+            margin: 20px 0 12px;
+        }
         .form-group {
             margin-bottom: 16px;
         }
@@ -477,7 +552,7 @@ const settingsPageHTML = `<!DOCTYPE html>
             font-weight: 500;
             margin-bottom: 6px;
         }
-        input[type="text"], textarea {
+        input[type="text"], input[type="password"], textarea {
             width: 100%;
             padding: 10px 14px;
             border: 1px solid #d1d5db;
@@ -485,7 +560,7 @@ const settingsPageHTML = `<!DOCTYPE html>
             font-size: 14px;
             transition: border-color 0.2s;
         }
-        input[type="text"]:focus, textarea:focus {
+        input[type="text"]:focus, input[type="password"]:focus, textarea:focus {
             outline: none;
             border-color: #3b82f6;
             box-shadow: 0 0 0 3px rgba(59, 130, 246, 0.1);
@@ -586,6 +661,8 @@ const settingsPageHTML = `<!DOCTYPE html>
                 <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M5 12h14M5 12a2 2 0 01-2-2V6a2 2 0 012-2h14a2 2 0 012 2v4a2 2 0 01-2 2M5 12a2 2 0 00-2 2v4a2 2 0 002 2h14a2 2 0 002-2v-4a2 2 0 00-2-2m-2-4h.01M17 16h.01" />
             </svg>
             <h1>Agent Settings</h1>
+            <button class="btn-secondary" style="margin-left: auto" onclick="window.location.href = '/'">Dashboard</button>
+            <button class="btn-secondary" onclick="logout()">Sign Out</button>
         </div>
 
         <div id="alert" class="alert hidden"></div>
@@ -604,7 +681,7 @@ const settingsPageHTML = `<!DOCTYPE html>
                     <button class="btn-secondary" onclick="generateKey()">Generate</button>
                     <button class="btn-secondary" onclick="copyKey()">Copy</button>
                 </div>
-                <p class="hint">Generate a new key and save to update. Requires agent restart.</p>
+                <p class="hint">Generate a new key and save to update. It takes effect immediately, so copy it first and sign in again with it.</p>
             </div>
             <div class="btn-row">
                 <button class="btn-primary" onclick="saveApiKey()">Save API Key</button>
@@ -634,11 +711,219 @@ const settingsPageHTML = `<!DOCTYPE html>
                 <button class="btn-primary" onclick="saveServices()">Save Services</button>
             </div>
         </div>
+
+        <div class="card">
+            <h2>Security Headers</h2>
+            <div class="form-group">
+                <label><input type="checkbox" id="securityHeaders"> Send security headers</label>
+                <p class="hint">X-Content-Type-Options, X-Frame-Options, Referrer-Policy, Content-Security-Policy and, over HTTPS, Strict-Transport-Security.</p>
+            </div>
+            <div class="form-group">
+                <label>HSTS Max Age (seconds)</label>
+                <input type="text" id="hstsMaxAge" inputmode="numeric" placeholder="31536000">
+                <p class="hint">0 disables HSTS.</p>
+            </div>
+            <div class="form-group">
+                <label>X-Frame-Options</label>
+                <input type="text" id="frameOptions" placeholder="DENY">
+                <p class="hint">DENY, SAMEORIGIN, or empty to omit.</p>
+            </div>
+            <div class="form-group">
+                <label>Referrer-Policy</label>
+                <input type="text" id="referrerPolicy" placeholder="no-referrer">
+            </div>
+            <div class="form-group">
+                <label>Content-Security-Policy (pages)</label>
+                <textarea id="contentSecurityPolicy"></textarea>
+                <p class="hint">Applies to the setup and settings pages. API responses always deny all content.</p>
+            </div>
+            <div class="btn-row">
+                <button class="btn-primary" onclick="saveSecurityHeaders()">Save Headers</button>
+            </div>
+        </div>
+
+        <div class="card">
+            <h2>Integrations</h2>
+            <p class="hint" id="agentIdentity"></p>
+            <p class="hint">Secret fields are never shown. Leave them blank to keep the current value. Integrations start with the agent, so changes apply after a restart.</p>
+
+            <h3>MQTT</h3>
+            <div class="form-group">
+                <label>Broker</label>
+                <input type="text" id="mqttBroker" placeholder="tcp://broker.lan:1883">
+                <p class="hint">Empty disables MQTT publishing.</p>
+            </div>
+            <div class="form-group">
+                <label>Username</label>
+                <input type="text" id="mqttUsername">
+            </div>
+            <div class="form-group">
+                <label>Password</label>
+                <input type="password" id="mqttPassword" autocomplete="new-password">
+            </div>
+            <div class="form-group">
+                <label>Topic Prefix</label>
+                <input type="text" id="mqttTopicPrefix" placeholder="hivedeck/&lt;hostname&gt;">
+            </div>
+            <div class="form-group">
+                <label>Publish Interval (seconds)</label>
+                <input type="text" id="mqttInterval" inputmode="numeric">
+            </div>
+            <div class="form-group">
+                <label><input type="checkbox" id="mqttDiscovery"> Home Assistant discovery</label>
+            </div>
+
+            <h3>Log Forwarding</h3>
+            <div class="form-group">
+                <label>Destination URL</label>
+                <input type="text" id="logForwardURL" placeholder="https://loki.lan:3100 or udp://syslog.lan:514">
+                <p class="hint">Empty disables log forwarding.</p>
+            </div>
+            <div class="form-group">
+                <label>Username</label>
+                <input type="text" id="logForwardUsername">
+            </div>
+            <div class="form-group">
+                <label>Password</label>
+                <input type="password" id="logForwardPassword" autocomplete="new-password">
+            </div>
+            <div class="form-group">
+                <label>Units</label>
+                <textarea id="logForwardUnits" placeholder="nginx&#10;docker"></textarea>
+                <p class="hint">One systemd unit per line.</p>
+            </div>
+            <div class="form-group">
+                <label>Files</label>
+                <textarea id="logForwardFiles" placeholder="/var/log/app.log"></textarea>
+                <p class="hint">One file per line.</p>
+            </div>
+            <div class="form-group">
+                <label>Labels</label>
+                <textarea id="logForwardLabels" placeholder="env=home&#10;site=rack1"></textarea>
+                <p class="hint">One key=value pair per line.</p>
+            </div>
+
+            <h3>Heartbeat</h3>
+            <div class="form-group">
+                <label>Ping URL</label>
+                <input type="password" id="heartbeatURL" autocomplete="off">
+                <p class="hint" id="heartbeatHint"></p>
+            </div>
+            <div class="form-group">
+                <label>Interval (seconds)</label>
+                <input type="text" id="heartbeatInterval" inputmode="numeric">
+            </div>
+
+            <h3>Crash Reporting</h3>
+            <div class="form-group">
+                <label>Sentry DSN</label>
+                <input type="password" id="sentryDSN" autocomplete="off">
+                <p class="hint" id="sentryHint"></p>
+            </div>
+            <div class="form-group">
+                <label>Webhook URL</label>
+                <input type="password" id="crashWebhookURL" autocomplete="off">
+                <p class="hint" id="crashWebhookHint"></p>
+            </div>
+
+            <h3>Alerts</h3>
+            <div class="form-group">
+                <label>Rules</label>
+                <textarea id="alertRules" placeholder="high-cpu=cpu&gt;90@5m&#10;nginx=service:nginx@1m"></textarea>
+                <p class="hint">One name=rule per line. Rules added through /api/alerts are kept separately.</p>
+            </div>
+            <div class="form-group">
+                <label>Notification Channels</label>
+                <textarea id="alertChannels" placeholder="ops=slack:https://hooks.slack.com/services/...&#10;phone=ntfy:https://ntfy.sh/topic"></textarea>
+                <p class="hint" id="alertChannelsHint"></p>
+            </div>
+            <div class="form-group">
+                <label>Check Interval (seconds)</label>
+                <input type="text" id="alertInterval" inputmode="numeric">
+            </div>
+
+            <h3>Monitors</h3>
+            <div class="form-group">
+                <label>Upstreams</label>
+                <textarea id="upstreams" placeholder="app=http://127.0.0.1:3000/health"></textarea>
+                <p class="hint">One name=URL per line, checked for reverse-proxy health.</p>
+            </div>
+            <div class="form-group">
+                <label>Interval (seconds)</label>
+                <input type="text" id="upstreamInterval" inputmode="numeric">
+            </div>
+            <div class="form-group">
+                <label>Timeout (seconds)</label>
+                <input type="text" id="upstreamTimeout" inputmode="numeric">
+            </div>
+            <div class="form-group">
+                <label><input type="checkbox" id="upstreamInsecure"> Skip TLS verification</label>
+            </div>
+
+            <h3>Scheduled Maintenance</h3>
+            <div class="form-group">
+                <label><input type="checkbox" id="maintenanceEnabled"> Run maintenance on a schedule</label>
+            </div>
+            <div class="form-group">
+                <label>Schedule</label>
+                <input type="text" id="maintenanceSchedule" placeholder="sun 03:30">
+                <p class="hint">daily, or days such as mon,thu, followed by HH:MM.</p>
+            </div>
+            <div class="form-group">
+                <label>Window (minutes)</label>
+                <input type="text" id="maintenanceWindow" inputmode="numeric">
+            </div>
+            <div class="form-group">
+                <label>Steps</label>
+                <textarea id="maintenanceSteps"></textarea>
+                <p class="hint" id="maintenanceStepsHint"></p>
+            </div>
+            <div class="form-group">
+                <label><input type="checkbox" id="maintenanceReboot"> Reboot afterwards when required</label>
+            </div>
+
+            <h3>Webhooks</h3>
+            <div class="form-group">
+                <label>Hooks</label>
+                <textarea id="webhooks" placeholder="redeploy=deploy:/opt/app/compose.yaml&#10;cleanup=task:docker-prune"></textarea>
+                <p class="hint">One name=action:target per line.</p>
+            </div>
+            <div class="form-group">
+                <label>Secrets</label>
+                <textarea id="webhookSecrets" placeholder="redeploy=..."></textarea>
+                <p class="hint" id="webhookSecretsHint"></p>
+            </div>
+
+            <h3>Pull Mode</h3>
+            <div class="form-group">
+                <label>Control Server URL</label>
+                <input type="text" id="pullURL" placeholder="https://hub.lan/api/agents">
+                <p class="hint">For agents behind NAT that poll a central server for commands. Empty disables it.</p>
+            </div>
+            <div class="form-group">
+                <label>Shared Secret</label>
+                <input type="password" id="pullSecret" autocomplete="new-password">
+                <p class="hint" id="pullSecretHint"></p>
+            </div>
+            <div class="form-group">
+                <label>Poll Interval (seconds)</label>
+                <input type="text" id="pullInterval" inputmode="numeric">
+            </div>
+            <div class="form-group">
+                <label>Allowed Commands</label>
+                <textarea id="pullAllowed" placeholder="GET /api/"></textarea>
+                <p class="hint">One method and path prefix per line.</p>
+            </div>
+
+            <div class="btn-row">
+                <button class="btn-primary" onclick="saveIntegrations()">Save Integrations</button>
+            </div>
+        </div>
     </div>
 
     <script>
         const alertDiv = document.getElementById('alert');
-        const API_KEY = new URLSearchParams(window.location.search).get('key') || '';
+        const CSRF_TOKEN = '{{csrf_token}}';
 
         function showAlert(message, type) {
             alertDiv.textContent = message;
@@ -646,17 +931,29 @@ const settingsPageHTML = `<!DOCTYPE html>
             setTimeout(() => alertDiv.className = 'alert hidden', 5000);
         }
 
+        // Requests use the session cookie; state changes also send the CSRF token
         async function fetchWithAuth(url, options = {}) {
             options.headers = options.headers || {};
-            options.headers['Authorization'] = 'Bearer ' + API_KEY;
-            return fetch(url, options);
+            options.headers['X-CSRF-Token'] = CSRF_TOKEN;
+            options.credentials = 'same-origin';
+            const res = await fetch(url, options);
+            if (res.status === 401) {
+                // Session expired: show the sign-in page
+                window.location.reload();
+            }
+            return res;
+        }
+
+        async function logout() {
+            await fetchWithAuth('/settings/logout', { method: 'POST' });
+            window.location.reload();
         }
 
         async function loadSettings() {
             try {
                 const res = await fetchWithAuth('/api/settings');
                 if (!res.ok) {
-                    showAlert('Failed to load settings. Check API key.', 'error');
+                    showAlert('Failed to load settings.', 'error');
                     return;
                 }
                 const data = await res.json();
@@ -672,9 +969,184 @@ const settingsPageHTML = `<!DOCTYPE html>
                 // Update form fields
                 document.getElementById('allowedPaths').value = (data.allowed_paths || []).join('\n');
                 document.getElementById('allowedServices').value = (data.allowed_services || []).join('\n');
+
+                const sh = data.security_headers || {};
+                document.getElementById('securityHeaders').checked = !!sh.enabled;
+                document.getElementById('hstsMaxAge').value = sh.hsts_max_age || 0;
+                document.getElementById('frameOptions').value = sh.frame_options || '';
+                document.getElementById('referrerPolicy').value = sh.referrer_policy || '';
+                document.getElementById('contentSecurityPolicy').value = sh.content_security_policy || '';
             } catch (err) {
                 showAlert('Error loading settings: ' + err.message, 'error');
             }
+            loadIntegrations();
+        }
+
+        function lines(id) {
+            return document.getElementById(id).value.split('\n').map(s => s.trim()).filter(s => s);
+        }
+
+        function secretHint(set) {
+            return set ? 'Configured. Leave blank to keep it.' : 'Not set.';
+        }
+
+        function pairs(obj) {
+            return Object.entries(obj || {}).map(([k, v]) => k + '=' + v).join('\n');
+        }
+
+        function parsePairs(id) {
+            const result = {};
+            lines(id).forEach(pair => {
+                const i = pair.indexOf('=');
+                if (i > 0) result[pair.slice(0, i).trim()] = pair.slice(i + 1).trim();
+            });
+            return result;
+        }
+
+        async function loadIntegrations() {
+            try {
+                const res = await fetchWithAuth('/api/settings/integrations');
+                if (!res.ok) return;
+                const data = await res.json();
+
+                document.getElementById('mqttBroker').value = data.mqtt.broker || '';
+                document.getElementById('mqttUsername').value = data.mqtt.username || '';
+                document.getElementById('mqttTopicPrefix').value = data.mqtt.topic_prefix || '';
+                document.getElementById('mqttInterval').value = data.mqtt.interval_seconds;
+                document.getElementById('mqttDiscovery').checked = !!data.mqtt.discovery;
+
+                const lf = data.log_forwarding;
+                document.getElementById('logForwardURL').value = lf.url || '';
+                document.getElementById('logForwardUsername').value = lf.username || '';
+                document.getElementById('logForwardUnits').value = (lf.units || []).join('\n');
+                document.getElementById('logForwardFiles').value = (lf.files || []).join('\n');
+                document.getElementById('logForwardLabels').value =
+                    Object.entries(lf.labels || {}).map(([k, v]) => k + '=' + v).join('\n');
+
+                document.getElementById('heartbeatInterval').value = data.heartbeat.interval_seconds;
+                document.getElementById('heartbeatHint').textContent = secretHint(data.heartbeat.url_set);
+                document.getElementById('sentryHint').textContent = secretHint(data.crash_reporting.sentry_dsn_set);
+                document.getElementById('crashWebhookHint').textContent = secretHint(data.crash_reporting.webhook_url_set);
+
+                const agent = data.agent;
+                document.getElementById('agentIdentity').textContent = 'Settings for ' + agent.hostname +
+                    (Object.keys(agent.labels || {}).length ? ' (' + pairs(agent.labels).split('\n').join(', ') + ')' : '') + '.';
+
+                const al = data.alerts;
+                document.getElementById('alertRules').value = pairs(al.rules);
+                document.getElementById('alertInterval').value = al.interval_seconds;
+                const channels = Object.entries(al.channels || {}).map(([k, v]) => k + ' (' + v + ')');
+                document.getElementById('alertChannelsHint').textContent = 'One name=type:address per line; types are webhook, slack, telegram and ntfy. ' +
+                    (channels.length ? 'Configured: ' + channels.join(', ') + '. Leave blank to keep them; entering any replaces them all.' : 'None configured.');
+
+                const mon = data.monitors;
+                document.getElementById('upstreams').value = pairs(mon.upstreams);
+                document.getElementById('upstreamInterval').value = mon.interval_seconds;
+                document.getElementById('upstreamTimeout').value = mon.timeout_seconds;
+                document.getElementById('upstreamInsecure').checked = !!mon.insecure;
+
+                const mt = data.maintenance;
+                document.getElementById('maintenanceEnabled').checked = !!mt.enabled;
+                document.getElementById('maintenanceSchedule').value = mt.schedule || '';
+                document.getElementById('maintenanceWindow').value = mt.window_minutes;
+                document.getElementById('maintenanceSteps').value = (mt.steps || []).join('\n');
+                document.getElementById('maintenanceStepsHint').textContent = 'One step per line, run in order: ' + mt.available.join(', ') + '.';
+                document.getElementById('maintenanceReboot').checked = !!mt.reboot;
+
+                const wh = data.webhooks;
+                document.getElementById('webhooks').value = pairs(wh.hooks);
+                document.getElementById('webhookSecretsHint').textContent = 'One name=secret per line. Every hook needs one. ' +
+                    (wh.secrets_set.length ? 'Set for: ' + wh.secrets_set.join(', ') + '. Leave blank to keep them.' : 'None set.');
+
+                const pl = data.pull;
+                document.getElementById('pullURL').value = pl.url || '';
+                document.getElementById('pullSecretHint').textContent = secretHint(pl.secret_set);
+                document.getElementById('pullInterval').value = pl.interval_seconds;
+                document.getElementById('pullAllowed').value = (pl.allowed_commands || []).join('\n');
+            } catch (err) {
+                showAlert('Error loading integrations: ' + err.message, 'error');
+            }
+        }
+
+        async function saveIntegrations() {
+            // Secrets are only sent when entered, so blank fields keep their value
+            function withSecret(obj, key, id) {
+                const v = document.getElementById(id).value.trim();
+                if (v) obj[key] = v;
+                return obj;
+            }
+
+            const labels = parsePairs('logForwardLabels');
+
+            // Channel addresses and webhook secrets are never shown, so only
+            // entered values are sent
+            const alerts = {
+                rules: parsePairs('alertRules'),
+                interval_seconds: parseInt(document.getElementById('alertInterval').value, 10)
+            };
+            if (lines('alertChannels').length) alerts.channels = parsePairs('alertChannels');
+            const webhooks = { hooks: parsePairs('webhooks') };
+            if (lines('webhookSecrets').length) webhooks.secrets = parsePairs('webhookSecrets');
+
+            const body = {
+                mqtt: withSecret({
+                    broker: document.getElementById('mqttBroker').value.trim(),
+                    username: document.getElementById('mqttUsername').value.trim(),
+                    topic_prefix: document.getElementById('mqttTopicPrefix').value.trim(),
+                    interval_seconds: parseInt(document.getElementById('mqttInterval').value, 10),
+                    discovery: document.getElementById('mqttDiscovery').checked
+                }, 'password', 'mqttPassword'),
+                log_forwarding: withSecret({
+                    url: document.getElementById('logForwardURL').value.trim(),
+                    username: document.getElementById('logForwardUsername').value.trim(),
+                    units: lines('logForwardUnits'),
+                    files: lines('logForwardFiles'),
+                    labels: labels
+                }, 'password', 'logForwardPassword'),
+                heartbeat: withSecret({
+                    interval_seconds: parseInt(document.getElementById('heartbeatInterval').value, 10)
+                }, 'url', 'heartbeatURL'),
+                crash_reporting: withSecret(withSecret({}, 'sentry_dsn', 'sentryDSN'), 'webhook_url', 'crashWebhookURL'),
+                alerts: alerts,
+                monitors: {
+                    upstreams: parsePairs('upstreams'),
+                    interval_seconds: parseInt(document.getElementById('upstreamInterval').value, 10),
+                    timeout_seconds: parseInt(document.getElementById('upstreamTimeout').value, 10),
+                    insecure: document.getElementById('upstreamInsecure').checked
+                },
+                maintenance: {
+                    enabled: document.getElementById('maintenanceEnabled').checked,
+                    schedule: document.getElementById('maintenanceSchedule').value.trim(),
+                    window_minutes: parseInt(document.getElementById('maintenanceWindow').value, 10),
+                    steps: lines('maintenanceSteps'),
+                    reboot: document.getElementById('maintenanceReboot').checked
+                },
+                webhooks: webhooks,
+                pull: withSecret({
+                    url: document.getElementById('pullURL').value.trim(),
+                    interval_seconds: parseInt(document.getElementById('pullInterval').value, 10),
+                    allowed_commands: lines('pullAllowed')
+                }, 'secret', 'pullSecret')
+            };
+
+            try {
+                const res = await fetchWithAuth('/api/settings/integrations', {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const data = await res.json();
+                if (res.ok) {
+                    showAlert('Integrations saved. ' + data.note + '.', 'success');
+                    ['mqttPassword', 'logForwardPassword', 'heartbeatURL', 'sentryDSN', 'crashWebhookURL', 'alertChannels', 'webhookSecrets', 'pullSecret']
+                        .forEach(id => document.getElementById(id).value = '');
+                    loadIntegrations();
+                } else {
+                    showAlert(data.message || 'Failed to save integrations', 'error');
+                }
+            } catch (err) {
+                showAlert('Error: ' + err.message, 'error');
+            }
         }
 
         async function generateKey() {
@@ -712,7 +1184,7 @@ const settingsPageHTML = `<!DOCTYPE html>
                 });
                 const data = await res.json();
                 if (res.ok) {
-                    showAlert('API key saved! Restart agent to apply.', 'success');
+                    showAlert('API key saved and active. Copy it now: you will need it to sign in again.', 'success');
                 } else {
                     showAlert(data.error || 'Failed to save', 'error');
                 }
@@ -757,12 +1229,136 @@ const settingsPageHTML = `<!DOCTYPE html>
             }
         }
 
+        async function saveSecurityHeaders() {
+            const maxAge = parseInt(document.getElementById('hstsMaxAge').value, 10);
+            if (isNaN(maxAge) || maxAge < 0) {
+                showAlert('HSTS max age must be a number of seconds', 'error');
+                return;
+            }
+            try {
+                const res = await fetchWithAuth('/api/settings', {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ security_headers: {
+                        enabled: document.getElementById('securityHeaders').checked,
+                        hsts_max_age: maxAge,
+                        frame_options: document.getElementById('frameOptions').value.trim(),
+                        referrer_policy: document.getElementById('referrerPolicy').value.trim(),
+                        content_security_policy: document.getElementById('contentSecurityPolicy').value.trim()
+                    } })
+                });
+                const data = await res.json();
+                if (res.ok) {
+                    showAlert('Security headers saved!', 'success');
+                } else {
+                    showAlert(data.message || 'Failed to save security headers', 'error');
+                }
+            } catch (err) {
+                showAlert('Error: ' + err.message, 'error');
+            }
+        }
+
         // Load settings on page load
-        if (API_KEY) {
-            loadSettings();
-        } else {
-            showAlert('API key required. Add ?key=YOUR_API_KEY to URL', 'error');
+        loadSettings();
+    </script>
+</body>
+</html>`
+
+const loginPageHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Hivedeck Agent Sign In</title>
+    <style>
+        * { box-sizing: border-box; margin: 0; padding: 0; }
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;
+            background: #f3f4f6;
+            min-height: 100vh;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            padding: 20px;
+        }
+        .container {
+            background: white;
+            border-radius: 12px;
+            box-shadow: 0 1px 3px rgba(0, 0, 0, 0.1);
+            padding: 32px;
+            max-width: 420px;
+            width: 100%;
+        }
+        h1 { color: #1f2937; font-size: 22px; margin-bottom: 8px; }
+        .subtitle { color: #6b7280; font-size: 14px; margin-bottom: 24px; }
+        label { display: block; color: #374151; font-size: 14px; font-weight: 500; margin-bottom: 6px; }
+        input {
+            width: 100%;
+            padding: 10px 14px;
+            border: 1px solid #d1d5db;
+            border-radius: 6px;
+            font-size: 14px;
+            font-family: 'Monaco', 'Menlo', monospace;
+            margin-bottom: 16px;
         }
+        input:focus { outline: none; border-color: #3b82f6; box-shadow: 0 0 0 3px rgba(59, 130, 246, 0.1); }
+        button {
+            width: 100%;
+            padding: 10px 20px;
+            border: none;
+            border-radius: 6px;
+            font-size: 14px;
+            font-weight: 500;
+            cursor: pointer;
+            background: #3b82f6;
+            color: white;
+        }
+        button:hover { background: #2563eb; }
+        .alert { padding: 12px 16px; border-radius: 6px; margin-bottom: 16px; font-size: 14px; background: #fee2e2; color: #991b1b; }
+        .hidden { display: none; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>Hivedeck Agent</h1>
+        <p class="subtitle">Sign in with the agent's API key</p>
+        <div id="alert" class="alert hidden"></div>
+        <form id="loginForm">
+            <label for="apiKey">API Key</label>
+            <input type="password" id="apiKey" autocomplete="current-password" autofocus>
+            <button type="submit">Sign In</button>
+        </form>
+    </div>
+
+    <script>
+        const alertDiv = document.getElementById('alert');
+
+        // Keys used to be passed as ?key=; drop them from the address bar and history
+        if (window.location.search) {
+            history.replaceState(null, '', window.location.pathname);
+        }
+
+        document.getElementById('loginForm').addEventListener('submit', async (e) => {
+            e.preventDefault();
+            try {
+                const res = await fetch('/settings/login', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    credentials: 'same-origin',
+                    body: JSON.stringify({ api_key: document.getElementById('apiKey').value.trim() })
+                });
+                if (res.ok) {
+                    window.location.reload();
+                    return;
+                }
+                const data = await res.json();
+                alertDiv.textContent = data.message || 'Sign in failed';
+                alertDiv.className = 'alert';
+            } catch (err) {
+                alertDiv.textContent = 'Error: ' + err.message;
+                alertDiv.className = 'alert';
+            }
+        });
     </script>
 </body>
 </html>`
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/datadir"
+	"github.com/ngenohkevin/hivedeck-agent/internal/energy"
+	"github.com/ngenohkevin/hivedeck-agent/internal/maintenance"
+	"github.com/ngenohkevin/hivedeck-agent/internal/speedtest"
+	"github.com/ngenohkevin/hivedeck-agent/internal/store"
+)
+
+// newStorageManager sorts what the agent keeps in DATA_DIR into categories.
+// History files are trimmed to what the agent loads, expired trash is
+// purged and the database is compacted; the rest is only reported.
+func (h *Handlers) newStorageManager(cfg *config.Config) *datadir.Manager {
+	dir := cfg.DataDir
+	histories := map[string]int{
+		"speedtest.jsonl":   speedtest.MaxHistory,
+		"power.jsonl":       energy.MaxHistory,
+		"maintenance.jsonl": maintenance.MaxHistory,
+	}
+
+	categories := []datadir.Category{
+		{
+			Name:        "database",
+			Description: "SQLite database of persistent state",
+			Paths:       []string{store.FileName, store.FileName + "-wal", store.FileName + "-shm"},
+		},
+		{
+			Name:        "history",
+			Description: "Speed test, power and maintenance history",
+			Paths:       []string{"speedtest.jsonl", "power.jsonl", "maintenance.jsonl"},
+			Prune: func(ctx context.Context) error {
+				var errs []error
+				for file, keep := range histories {
+					errs = append(errs, datadir.TrimLines(filepath.Join(dir, file), keep))
+				}
+				return errors.Join(errs...)
+			},
+		},
+		{
+			Name:        "trash",
+			Description: "Deleted files kept until TRASH_RETENTION_DAYS pass",
+			Paths:       []string{"trash"},
+		},
+		{
+			Name:        "deployments",
+			Description: "Compose files of inline deployments",
+			Paths:       []string{"deploy"},
+		},
+		{
+			Name:        "state",
+			Description: "Alert rules, annotations, integrity baselines and vulnerability reports",
+			Paths:       []string{"alerts.json", "annotations.json", "integrity.json", "vulnerabilities.json"},
+		},
+	}
+	if h.store != nil {
+		categories[0].Prune = h.store.Compact
+	}
+	if trash := h.fileBrowser.Trash(); trash != nil {
+		categories[2].Prune = func(context.Context) error {
+			_, err := trash.PurgeExpired()
+			return err
+		}
+	}
+
+	return datadir.NewManager(dir, categories, datadir.Options{
+		Interval: cfg.StoragePrune,
+		Limit:    cfg.StorageLimit,
+	}, h.eventBus)
+}
+
+// GetStorage handles GET /api/agent/storage
+func (h *Handlers) GetStorage(c *gin.Context) {
+	if h.storage == nil {
+		respondError(c, http.StatusServiceUnavailable, errNoDataDir)
+		return
+	}
+
+	report, err := h.storage.Usage()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// PruneStorage handles POST /api/agent/storage/prune. The body may name
+// the categories to prune; by default all prunable ones are.
+func (h *Handlers) PruneStorage(c *gin.Context) {
+	if h.storage == nil {
+		respondError(c, http.StatusServiceUnavailable, errNoDataDir)
+		return
+	}
+
+	var req struct {
+		Categories []string `json:"categories"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	}
+
+	result, err := h.storage.Prune(c.Request.Context(), req.Categories, datadir.TriggerManual)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.recordAudit(c, "storage.prune", h.cfg.DataDir, len(result.Errors) == 0, "")
+	c.JSON(http.StatusOK, result)
+}
+
+var errNoDataDir = apierror.Unavailable("DATA_DIR is not set")
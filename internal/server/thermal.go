@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/cache"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+	"github.com/ngenohkevin/hivedeck-agent/internal/thermal"
+)
+
+// newThermalMonitor builds the temperature monitor. Hook tasks must be
+// configured tasks; unknown ones are logged and dropped.
+func (h *Handlers) newThermalMonitor(cfg *config.Config) *thermal.Monitor {
+	opts := thermal.Options{
+		Thresholds: thermal.Thresholds{
+			Warning:    float64(cfg.ThermalWarning),
+			Critical:   float64(cfg.ThermalCritical),
+			Hysteresis: float64(cfg.ThermalHysteresis),
+		},
+		Sensors:  cfg.ThermalSensors,
+		Interval: cfg.ThermalInterval,
+		HotTask:  cfg.ThermalHotTask,
+		CoolTask: cfg.ThermalCoolTask,
+	}
+	for _, task := range []*string{&opts.HotTask, &opts.CoolTask} {
+		if *task != "" && !h.taskManager.Exists(*task) {
+			log.Printf("Thermal task %q is not a configured task, ignoring", *task)
+			*task = ""
+		}
+	}
+
+	return thermal.NewMonitor(opts, h.eventBus, h.runThermalTask)
+}
+
+func (h *Handlers) runThermalTask(ctx context.Context, name string) error {
+	result, err := h.taskManager.Run(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("exit code %d: %s", result.ExitCode, result.Error)
+	}
+	return nil
+}
+
+// GetThermal handles GET /api/thermal with the current temperature level
+func (h *Handlers) GetThermal(c *gin.Context) {
+	c.JSON(http.StatusOK, h.thermal.Status())
+}
+
+// SetCPUGovernor handles POST /api/system/cpu/governor. It is refused
+// unless CPU_GOVERNOR_CONTROL is on.
+func (h *Handlers) SetCPUGovernor(c *gin.Context) {
+	if !h.cfg.CPUGovernorControl {
+		respondError(c, http.StatusForbidden, apierror.NotAllowed("CPU governor control is disabled: set CPU_GOVERNOR_CONTROL=true"))
+		return
+	}
+
+	var req struct {
+		Governor string `json:"governor" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	if err := h.setGovernor(c.Request.Context(), req.Governor); err != nil {
+		h.recordAudit(c, "system.cpu_governor", req.Governor, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.cache.Delete(cache.KeyCPU)
+	message := "CPU governor set to " + req.Governor
+	h.recordAudit(c, "system.cpu_governor", req.Governor, true, message)
+	h.publishAction("system.cpu_governor", "system", true, message, gin.H{"governor": req.Governor})
+	c.JSON(http.StatusOK, system.GetCPUFrequency())
+}
+
+// setGovernor switches the CPU governor, through the privilege helper when
+// it is in use
+func (h *Handlers) setGovernor(ctx context.Context, governor string) error {
+	if h.helper != nil {
+		if err := system.CheckGovernor(governor); err != nil {
+			return err
+		}
+		if output, err := h.helper.Run(ctx, "governor", governor); err != nil {
+			return fmt.Errorf("failed to set the CPU governor: %v %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	err := system.SetGovernor(governor)
+	if errors.Is(err, apierror.ErrNotAllowed) && h.privileges.Root && h.sandbox != nil && h.sandbox.Landlock {
+		// Root can write to sysfs, so the sandbox refused it
+		return apierror.NotAllowed("the sandbox does not allow writing to %s: add it to SANDBOX_WRITE_PATHS", system.CPUFreqPath)
+	}
+	return err
+}
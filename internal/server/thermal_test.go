@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/thermal"
+)
+
+func TestGetThermal(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.ThermalHotTask = "fan-on" // Not a configured task
+	cfg.ThermalCoolTask = "pi-temp"
+	srv := New(cfg)
+
+	req := httptest.NewRequest("GET", "/api/thermal", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status thermal.Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.False(t, status.Enabled)
+	assert.Equal(t, thermal.LevelNormal, status.Level)
+	assert.Equal(t, 70.0, status.Thresholds.Warning)
+	assert.Empty(t, status.HotTask)
+	assert.Equal(t, "pi-temp", status.CoolTask)
+}
+
+func TestSetCPUGovernor(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	do := func(cfg *config.Config, body string) int {
+		req := httptest.NewRequest("POST", "/api/system/cpu/governor", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		New(cfg).Router().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, do(cfg, `{"governor": "performance"}`))
+
+	cfg.CPUGovernorControl = true
+	assert.Equal(t, http.StatusBadRequest, do(cfg, `{}`))
+}
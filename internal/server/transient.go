@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
+	"github.com/ngenohkevin/hivedeck-agent/internal/tasks"
+)
+
+// LaunchTask handles POST /api/tasks/:name/launch. The task runs as a
+// transient systemd unit instead of a child of the agent, and the response
+// returns once the unit has started.
+func (h *Handlers) LaunchTask(c *gin.Context) {
+	name := c.Param("name")
+	task, err := h.taskManager.Get(name)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	var req tasks.LaunchRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondMessage(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	launch := systemd.TransientRequest{
+		Task:    task.Name,
+		Command: task.Command,
+		Limits:  systemd.Limits{CPUQuota: req.CPUQuota},
+	}
+	if req.MemoryMax != "" {
+		if launch.Limits.MemoryMax, err = systemd.ParseSize(req.MemoryMax); err != nil {
+			respondError(c, http.StatusBadRequest, apierror.Invalid("memory_max: %v", err))
+			return
+		}
+	}
+	if req.RuntimeMax != "" {
+		if launch.RuntimeMax, err = time.ParseDuration(req.RuntimeMax); err != nil || launch.RuntimeMax <= 0 {
+			respondError(c, http.StatusBadRequest, apierror.Invalid("runtime_max must be a positive duration such as 30m"))
+			return
+		}
+	}
+
+	run := func(ctx context.Context) (interface{}, error) {
+		return h.serviceManager.RunTransient(ctx, launch)
+	}
+	if task.Dangerous {
+		impact := fmt.Sprintf("Runs `%s` on %s as a transient unit: %s", task.Command, hostname(), task.Description)
+		if !h.guardDestructive(c, "task.launch", name, impact, h.cfg.ServiceActionTimeout, run) {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.ServiceActionTimeout)
+	defer cancel()
+	unit, err := h.serviceManager.RunTransient(ctx, launch)
+	if err != nil {
+		h.recordAudit(c, "task.launch", name, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.recordAudit(c, "task.launch", name, true, "started "+unit.Unit)
+	h.publishAction("task.launch", "tasks", true, fmt.Sprintf("Task %s started as %s", name, unit.Unit), unit)
+
+	c.Header("Location", "/api/logs/"+unit.Unit)
+	c.JSON(http.StatusAccepted, unit)
+}
+
+// ListTaskUnits handles GET /api/tasks/units
+func (h *Handlers) ListTaskUnits(c *gin.Context) {
+	units, err := h.serviceManager.ListTransient(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, units)
+}
+
+// StopTaskUnit handles DELETE /api/tasks/units/:unit
+func (h *Handlers) StopTaskUnit(c *gin.Context) {
+	unit := c.Param("unit")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.ServiceActionTimeout)
+	defer cancel()
+	if err := h.serviceManager.StopTransient(ctx, unit); err != nil {
+		h.recordAudit(c, "task.stop", unit, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.recordAudit(c, "task.stop", unit, true, "")
+	c.JSON(http.StatusOK, gin.H{"unit": unit, "message": "Unit stopped"})
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+)
+
+func TestLaunchTask_Validation(t *testing.T) {
+	srv := New(config.LoadWithDefaults())
+
+	do := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Nothing here reaches systemd
+	assert.Equal(t, http.StatusNotFound, do("POST", "/api/tasks/bogus/launch", ""))
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/tasks/df/launch", `{"memory_max":"lots"}`))
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/tasks/df/launch", `{"runtime_max":"-5m"}`))
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/api/tasks/units/nginx.service", ""))
+}
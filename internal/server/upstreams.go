@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUpstreams handles GET /api/upstreams with the result of the latest
+// periodic checks
+func (h *Handlers) GetUpstreams(c *gin.Context) {
+	c.JSON(http.StatusOK, h.upstreams.Status())
+}
+
+// CheckUpstreams handles POST /api/upstreams/check, checking every
+// upstream now
+func (h *Handlers) CheckUpstreams(c *gin.Context) {
+	c.JSON(http.StatusOK, h.upstreams.CheckAll(c.Request.Context()))
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/upstreams"
+)
+
+func TestCheckUpstreams(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer backend.Close()
+
+	cfg := config.LoadWithDefaults()
+	cfg.Upstreams = map[string]string{"app": backend.URL + "/health"}
+	srv := New(cfg)
+
+	do := func(method, path string) upstreams.StatusList {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var list upstreams.StatusList
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		return list
+	}
+
+	// Not checked yet: the server was never started
+	list := do("GET", "/api/upstreams")
+	require.Len(t, list.Upstreams, 1)
+	assert.Nil(t, list.Upstreams[0].CheckedAt)
+
+	list = do("POST", "/api/upstreams/check")
+	require.Len(t, list.Upstreams, 1)
+	assert.True(t, list.Upstreams[0].Up)
+	assert.Equal(t, http.StatusNoContent, list.Upstreams[0].StatusCode)
+	assert.Equal(t, "30s", list.Interval)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListVolumes handles GET /api/docker/volumes
+func (h *Handlers) ListVolumes(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	volumes, err := manager.ListVolumes(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, volumes)
+}
+
+// GetVolume handles GET /api/docker/volumes/:name
+func (h *Handlers) GetVolume(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	volume, err := manager.GetVolume(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, volume)
+}
+
+// ListNetworks handles GET /api/docker/networks
+func (h *Handlers) ListNetworks(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	networks, err := manager.ListNetworks(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, networks)
+}
+
+// GetNetwork handles GET /api/docker/networks/:id, by ID or name
+func (h *Handlers) GetNetwork(c *gin.Context) {
+	manager := h.dockerManager()
+	if manager == nil {
+		respondError(c, http.StatusServiceUnavailable, errDockerUnavailable)
+		return
+	}
+
+	network, err := manager.GetNetwork(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, network)
+}
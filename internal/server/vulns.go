@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/jobs"
+	"github.com/ngenohkevin/hivedeck-agent/internal/vulns"
+)
+
+const (
+	vulnScanJob     = "security.vulnerabilities"
+	vulnScanTimeout = time.Hour
+)
+
+// newVulnScanner creates the vulnerability scanner, scanning the images of
+// running containers when Docker is available and scheduling scans as jobs
+func (h *Handlers) newVulnScanner() *vulns.Scanner {
+	s := vulns.NewScanner(h.cfg.VulnScanInterval, h.cfg.DataDir, h.eventBus)
+	s.SetImages(func(ctx context.Context) (map[string][]string, error) {
+		manager := h.dockerManager()
+		if manager == nil {
+			return nil, nil
+		}
+		list, err := manager.ListContainers(ctx, false)
+		if err != nil {
+			return nil, err
+		}
+		images := map[string][]string{}
+		for _, c := range list.Containers {
+			images[c.Image] = append(images[c.Image], c.Name)
+		}
+		return images, nil
+	})
+	s.OnSchedule(func() { h.submitVulnScan() })
+	return s
+}
+
+// submitVulnScan starts a scan job unless one is running, returning the
+// job and whether it was started
+func (h *Handlers) submitVulnScan() (*jobs.Job, bool) {
+	if job, running := h.jobManager.Active(vulnScanJob); running {
+		return job, false
+	}
+	job := h.jobManager.Submit(vulnScanJob, h.vulns.Available(), vulnScanTimeout, func(ctx context.Context) (interface{}, error) {
+		return h.vulns.Scan(ctx)
+	})
+	return job, true
+}
+
+// GetVulnerabilities handles GET /api/security/vulnerabilities with the
+// latest scan. severity keeps vulnerabilities at least that severe and
+// fixable=true those with an update available; counts are not filtered.
+func (h *Handlers) GetVulnerabilities(c *gin.Context) {
+	severity := c.DefaultQuery("severity", vulns.SeverityUnknown)
+	if !vulns.ValidSeverity(severity) {
+		respondMessage(c, http.StatusBadRequest, "severity must be critical, high, medium, low or unknown")
+		return
+	}
+	fixable := c.Query("fixable") == "true"
+
+	latest := h.vulns.Latest()
+	if latest == nil {
+		respondMessage(c, http.StatusNotFound, "no vulnerability scan has finished yet; start one with POST /api/security/vulnerabilities/scan")
+		return
+	}
+
+	report := *latest
+	report.Targets = make([]vulns.Target, len(latest.Targets))
+	for i, t := range latest.Targets {
+		filtered := []vulns.Vulnerability{}
+		for _, v := range t.Vulnerabilities {
+			if vulns.AtLeast(v.Severity, severity) && (!fixable || v.Fixable) {
+				filtered = append(filtered, v)
+			}
+		}
+		t.Vulnerabilities = filtered
+		report.Targets[i] = t
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ScanVulnerabilities handles POST /api/security/vulnerabilities/scan,
+// starting a scan as a job to poll at /api/operations/:id
+func (h *Handlers) ScanVulnerabilities(c *gin.Context) {
+	if h.vulns.Available() == "" {
+		respondMessage(c, http.StatusServiceUnavailable, "no vulnerability scanner found (install trivy, debsecan or dnf)")
+		return
+	}
+
+	job, started := h.submitVulnScan()
+	if !started {
+		c.JSON(http.StatusConflict, apierror.New(http.StatusConflict, "a vulnerability scan is already running",
+			map[string]interface{}{"job": job}))
+		return
+	}
+	c.Header("Location", "/api/operations/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/vulns"
+)
+
+func TestGetVulnerabilities(t *testing.T) {
+	get := func(srv *Server, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/security/vulnerabilities"+query, nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	cfg := config.LoadWithDefaults()
+	cfg.DataDir = t.TempDir()
+	assert.Equal(t, http.StatusNotFound, get(New(cfg), "").Code)
+
+	saved := vulns.Report{
+		Targets: []vulns.Target{{
+			Name: "host", Type: "host", Scanner: vulns.ScannerTrivy,
+			Vulnerabilities: []vulns.Vulnerability{
+				{ID: "CVE-2024-2961", Package: "libc6", Severity: vulns.SeverityCritical},
+				{ID: "CVE-2023-5678", Package: "openssl", Severity: vulns.SeverityMedium, Fixable: true},
+			},
+			Counts: map[string]int{vulns.SeverityCritical: 1, vulns.SeverityMedium: 1},
+		}},
+		Total: 2,
+	}
+	data, err := json.Marshal(saved)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.DataDir, "vulnerabilities.json"), data, 0600))
+	srv := New(cfg)
+
+	assert.Equal(t, http.StatusBadRequest, get(srv, "?severity=severe").Code)
+
+	for query, want := range map[string]string{"": "", "?severity=high": "CVE-2024-2961", "?fixable=true": "CVE-2023-5678"} {
+		w := get(srv, query)
+		require.Equal(t, http.StatusOK, w.Code)
+		var report vulns.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		found := report.Targets[0].Vulnerabilities
+		if want == "" {
+			assert.Len(t, found, 2)
+			continue
+		}
+		require.Len(t, found, 1, query)
+		assert.Equal(t, want, found[0].ID)
+		assert.Equal(t, 2, report.Total)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/deploy"
+	"github.com/ngenohkevin/hivedeck-agent/internal/jobs"
+	"github.com/ngenohkevin/hivedeck-agent/internal/webhooks"
+)
+
+// loadWebhooks parses WEBHOOKS, leaving out hooks that are misconfigured
+// or name an unknown task
+func (h *Handlers) loadWebhooks(cfg *config.Config) {
+	hooks, err := webhooks.Parse(cfg.Webhooks, cfg.HookSecrets())
+	if err != nil {
+		log.Printf("Webhooks ignored: %v", err)
+	}
+	for name, hook := range hooks {
+		if hook.Action == webhooks.ActionTask && !h.taskManager.Exists(hook.Target) {
+			log.Printf("Webhook %s ignored: unknown task %q", name, hook.Target)
+			delete(hooks, name)
+		}
+	}
+	h.webhooks = hooks
+	h.webhookReplays = webhooks.NewReplayGuard()
+}
+
+// needsDeployer reports whether a webhook deploys compose files
+func (h *Handlers) needsDeployer() bool {
+	for _, hook := range h.webhooks {
+		if hook.Action == webhooks.ActionDeploy {
+			return true
+		}
+	}
+	return false
+}
+
+// TriggerWebhook handles POST /hooks/:name. It needs no API credentials:
+// the timestamp and body must be signed with the webhook's secret, each
+// signed request is accepted once, and the webhook can only start its
+// configured action. The body is not otherwise read.
+func (h *Handlers) TriggerWebhook(c *gin.Context) {
+	name := c.Param("name")
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, webhooks.MaxBody))
+	if err != nil {
+		respondMessage(c, http.StatusRequestEntityTooLarge, "webhook body is too large")
+		return
+	}
+
+	// Unknown names get the same answer, so they cannot be probed
+	timestamp, signature := c.GetHeader(webhooks.TimestampHeader), c.GetHeader(webhooks.SignatureHeader)
+	hook, ok := h.webhooks[name]
+	if !ok || !hook.Verify(timestamp, body, signature) {
+		h.recordAudit(c, "webhook", name, false, "unknown webhook or invalid signature")
+		respondMessage(c, http.StatusUnauthorized, "unknown webhook or invalid signature")
+		return
+	}
+	c.Set("auth_method", "webhook")
+
+	if err := h.webhookReplays.Check(timestamp, signature, time.Now()); err != nil {
+		h.recordAudit(c, "webhook", name, false, err.Error())
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	var job *jobs.Job
+	switch hook.Action {
+	case webhooks.ActionDeploy:
+		job, err = h.startDeploy(c.Request.Context(), deploy.Request{Path: hook.Target})
+	case webhooks.ActionTask:
+		job = h.jobManager.Submit("task.run", hook.Target, taskTimeout, h.taskRunner(hook.Target))
+	}
+	if err != nil {
+		h.recordAudit(c, "webhook", name, false, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.recordAudit(c, "webhook", name, true, hook.Action+" "+hook.Target+" started as "+job.ID)
+	c.Header("Location", "/api/operations/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListWebhooks handles GET /api/webhooks, without the secrets
+func (h *Handlers) ListWebhooks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks.List(h.webhooks)})
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/jobs"
+	"github.com/ngenohkevin/hivedeck-agent/internal/webhooks"
+)
+
+func TestTriggerWebhook(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AllowedTasks = map[string]config.Task{"hello": {Name: "hello", Command: "echo hello"}}
+	cfg.Webhooks = map[string]string{"greet": "task:hello", "nosecret": "task:hello", "ghost": "task:missing"}
+	cfg.WebhookSecrets = "greet=s3cret,ghost=x"
+	srv := New(cfg)
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	trigger := func(name, body, signature string, timestamp ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/hooks/"+name, strings.NewReader(body))
+		req.Header.Set(webhooks.SignatureHeader, signature)
+		req.Header.Set(webhooks.TimestampHeader, now)
+		if len(timestamp) == 1 {
+			req.Header.Set(webhooks.TimestampHeader, timestamp[0])
+		}
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"ref": "refs/heads/main"}`
+	signature := webhooks.Sign([]byte("s3cret"), now, []byte(body))
+
+	assert.Equal(t, http.StatusUnauthorized, trigger("greet", body, "sha256=00").Code)
+	assert.Equal(t, http.StatusUnauthorized, trigger("greet", body+" ", signature).Code)
+	assert.Equal(t, http.StatusUnauthorized, trigger("nosecret", body, signature).Code, "hooks without a secret are dropped")
+	assert.Equal(t, http.StatusUnauthorized, trigger("ghost", body, webhooks.Sign([]byte("x"), now, []byte(body))).Code, "unknown tasks are dropped")
+	assert.Equal(t, http.StatusUnauthorized, trigger("greet", body, signature, "1").Code, "the timestamp is signed")
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	assert.Equal(t, http.StatusUnauthorized, trigger("greet", body, webhooks.Sign([]byte("s3cret"), stale, []byte(body)), stale).Code)
+
+	w := trigger("greet", body, signature)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	id := strings.TrimPrefix(w.Header().Get("Location"), "/api/operations/")
+	require.Eventually(t, func() bool {
+		job, err := srv.handlers.jobManager.Get(id)
+		return err == nil && job.Status == jobs.StatusSucceeded
+	}, 5*time.Second, 20*time.Millisecond)
+
+	entries := srv.handlers.auditLog.List(10, "")
+	assert.Equal(t, "webhook", entries.Entries[0].Action)
+	assert.Equal(t, "webhook", entries.Entries[0].Actor)
+	assert.True(t, entries.Entries[0].Success)
+
+	assert.Equal(t, http.StatusUnauthorized, trigger("greet", body, signature).Code, "replays are refused")
+
+	req := httptest.NewRequest("GET", "/api/webhooks", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w = httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	assert.JSONEq(t, `{"webhooks": [{"name": "greet", "action": "task", "target": "hello"}]}`, w.Body.String())
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/docker"
+	"github.com/ngenohkevin/hivedeck-agent/internal/systemd"
+)
+
+const (
+	wsWriteWait        = 10 * time.Second
+	wsPongWait         = 60 * time.Second
+	wsPingPeriod       = 30 * time.Second
+	wsMaxMessage       = 4096
+	wsMaxSubscriptions = 16
+)
+
+// wsMessage is a frame of the /api/ws protocol. Clients send subscribe,
+// unsubscribe and ping; the agent sends subscribed, unsubscribed, data,
+// error and pong.
+type wsMessage struct {
+	Type    string             `json:"type"`
+	Channel string             `json:"channel,omitempty"`
+	Data    interface{}        `json:"data,omitempty"`
+	Error   *apierror.Response `json:"error,omitempty"`
+}
+
+// wsSource feeds a subscription until ctx is done, passing each message to
+// emit, which returns false once the subscription has ended
+type wsSource func(ctx context.Context, emit func(data interface{}) bool) error
+
+// wsConn is one WebSocket connection and its subscriptions
+type wsConn struct {
+	h    *Handlers
+	conn *websocket.Conn
+	opts metricsOptions
+	ctx  context.Context
+	send chan wsMessage
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc
+}
+
+// WebSocket handles GET /api/ws, multiplexing metrics, agent events,
+// journal logs and Docker events over one connection. The metrics query
+// parameters of /api/metrics apply to the metrics channel.
+func (h *Handlers) WebSocket(c *gin.Context) {
+	opts, ok := parseMetricsOptions(c)
+	if !ok {
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return h.wsOriginAllowed(r, c.GetString("auth_method"))
+		},
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // The upgrader has responded
+	}
+
+	ctx, cancel := context.WithCancel(baseContext(c))
+	ws := &wsConn{
+		h:    h,
+		conn: conn,
+		opts: opts,
+		ctx:  ctx,
+		send: make(chan wsMessage, 64),
+		subs: map[string]context.CancelFunc{},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ws.writeLoop()
+	}()
+	ws.readLoop()
+
+	cancel()
+	<-done
+}
+
+// wsOriginAllowed accepts requests without an Origin (non-browser
+// clients), from the agent's own origin and from origins that CORS allows
+// for /api/ws. A wildcard does not cover session cookies, which a foreign
+// page would otherwise ride on.
+func (h *Handlers) wsOriginAllowed(r *http.Request, authMethod string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	policy := newCORSRules(h.cfg.AllowedOrigins, h.cfg.CORSRoutes).policy(r.URL.Path)
+	return policy.matches(origin) || (policy.any && authMethod != "session")
+}
+
+// readLoop handles client messages until the connection fails or closes
+func (w *wsConn) readLoop() {
+	defer w.unsubscribeAll()
+
+	w.conn.SetReadLimit(wsMaxMessage)
+	w.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	w.conn.SetPongHandler(func(string) error {
+		return w.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := w.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		w.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			w.fail("", apierror.Invalid("invalid message: %v", err))
+			continue
+		}
+
+		switch msg.Type {
+		case "subscribe":
+			w.subscribe(msg.Channel)
+		case "unsubscribe":
+			w.unsubscribe(msg.Channel)
+			w.reply(wsMessage{Type: "unsubscribed", Channel: msg.Channel})
+		case "ping":
+			w.reply(wsMessage{Type: "pong"})
+		default:
+			w.fail(msg.Channel, apierror.Invalid("unknown message type %q: use subscribe, unsubscribe or ping", msg.Type))
+		}
+	}
+}
+
+// writeLoop sends queued messages and keepalive pings. It closes the
+// connection when it ends, which also ends readLoop.
+func (w *wsConn) writeLoop() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+	defer w.conn.Close()
+
+	for {
+		select {
+		case msg := <-w.send:
+			w.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := w.conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			w.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := w.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-w.ctx.Done():
+			w.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			w.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+// reply queues a message unless the connection is closing
+func (w *wsConn) reply(msg wsMessage) bool {
+	select {
+	case w.send <- msg:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+func (w *wsConn) fail(channel string, err error) {
+	_, resp := apierror.From(err, http.StatusInternalServerError)
+	w.reply(wsMessage{Type: "error", Channel: channel, Error: &resp})
+}
+
+func (w *wsConn) subscribe(channel string) {
+	w.mu.Lock()
+	_, exists := w.subs[channel]
+	full := len(w.subs) >= wsMaxSubscriptions
+	w.mu.Unlock()
+
+	if exists {
+		w.reply(wsMessage{Type: "subscribed", Channel: channel})
+		return
+	}
+	if full {
+		w.fail(channel, apierror.Invalid("at most %d subscriptions per connection", wsMaxSubscriptions))
+		return
+	}
+
+	source, err := w.h.wsSource(channel, w.opts)
+	if err != nil {
+		w.fail(channel, err)
+		return
+	}
+	release, err := w.h.conns.acquire(true)
+	if err != nil {
+		w.fail(channel, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	w.mu.Lock()
+	w.subs[channel] = cancel
+	w.mu.Unlock()
+	w.reply(wsMessage{Type: "subscribed", Channel: channel})
+
+	go func() {
+		defer release()
+		emit := func(data interface{}) bool {
+			select {
+			case w.send <- wsMessage{Type: "data", Channel: channel, Data: data}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		err := source(ctx, emit)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The source gave up; the client can subscribe again
+		w.unsubscribe(channel)
+		if err != nil {
+			w.fail(channel, err)
+		}
+	}()
+}
+
+func (w *wsConn) unsubscribe(channel string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if cancel, ok := w.subs[channel]; ok {
+		cancel()
+		delete(w.subs, channel)
+	}
+}
+
+func (w *wsConn) unsubscribeAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for channel, cancel := range w.subs {
+		cancel()
+		delete(w.subs, channel)
+	}
+}
+
+// wsSource returns the feed for a channel: metrics, events, logs or
+// logs:<unit>, and docker:events
+func (h *Handlers) wsSource(channel string, opts metricsOptions) (wsSource, error) {
+	name, unit, _ := strings.Cut(channel, ":")
+	switch {
+	case channel == "metrics":
+		return func(ctx context.Context, emit func(interface{}) bool) error {
+			samples, unsubscribe := h.metricsHub.Subscribe()
+			defer unsubscribe()
+			throttle := sampleThrottle{interval: opts.interval}
+
+			for {
+				select {
+				case sample := <-samples:
+					if sample.err != nil || !throttle.allow(time.Now()) {
+						continue
+					}
+					if !emit(h.metricsResponse(sample.metrics, opts)) {
+						return nil
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}, nil
+
+	case channel == "events":
+		return func(ctx context.Context, emit func(interface{}) bool) error {
+			agentEvents, unsubscribe := h.eventBus.Subscribe()
+			defer unsubscribe()
+
+			for {
+				select {
+				case event := <-agentEvents:
+					if !emit(event) {
+						return nil
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}, nil
+
+	case name == "logs":
+		if !h.cfg.ModuleEnabled(config.ModuleLogs) {
+			return nil, apierror.NotAllowed("module logs is disabled")
+		}
+		return func(ctx context.Context, emit func(interface{}) bool) error {
+			entries := make(chan systemd.JournalEntry, 100)
+			if err := h.journalReader.Follow(ctx, unit, entries); err != nil {
+				return err
+			}
+			for {
+				select {
+				case entry := <-entries:
+					if !emit(entry) {
+						return nil
+					}
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}, nil
+
+	case channel == "docker:events":
+		if !h.cfg.ModuleEnabled(config.ModuleDocker) {
+			return nil, apierror.NotAllowed("module docker is disabled")
+		}
+		manager := h.dockerManager()
+		if manager == nil {
+			return nil, errDockerUnavailable
+		}
+		return func(ctx context.Context, emit func(interface{}) bool) error {
+			events := make(chan docker.Event, 100)
+			errs := make(chan error, 1)
+			go func() { errs <- manager.Events(ctx, events) }()
+
+			for {
+				select {
+				case event := <-events:
+					if !emit(event) {
+						return nil
+					}
+				case err := <-errs:
+					return err
+				}
+			}
+		}, nil
+	}
+
+	return nil, apierror.Invalid("unknown channel %q: use metrics, events, logs, logs:<unit> or docker:events", channel)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+func dialWS(t *testing.T, srv *Server, header http.Header) (*websocket.Conn, *http.Response, error) {
+	ts := httptest.NewServer(srv.Router())
+	t.Cleanup(ts.Close)
+
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Authorization", "Bearer test-api-key")
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/ws", header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp, err
+}
+
+func TestWebSocket_Subscriptions(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.DockerEnabled = false
+	srv := New(cfg)
+	conn, _, err := dialWS(t, srv, nil)
+	require.NoError(t, err)
+
+	var msg wsMessage
+	require.NoError(t, conn.WriteJSON(wsMessage{Type: "ping"}))
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "pong", msg.Type)
+
+	require.NoError(t, conn.WriteJSON(wsMessage{Type: "subscribe", Channel: "bogus"}))
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "error", msg.Type)
+	assert.Contains(t, msg.Error.Message, `unknown channel "bogus"`)
+
+	require.NoError(t, conn.WriteJSON(wsMessage{Type: "subscribe", Channel: "docker:events"}))
+	msg = wsMessage{}
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "docker:events", msg.Channel)
+	assert.Equal(t, "module docker is disabled", msg.Error.Message)
+
+	require.NoError(t, conn.WriteJSON(wsMessage{Type: "subscribe", Channel: "events"}))
+	msg = wsMessage{}
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, wsMessage{Type: "subscribed", Channel: "events"}, msg)
+
+	// The source subscribes to the bus after replying, so publish until an
+	// event arrives
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				srv.handlers.eventBus.Publish(events.Event{Type: "test.ws", Source: "test", Message: "hello"})
+			case <-stop:
+				return
+			}
+		}
+	}()
+	require.NoError(t, conn.ReadJSON(&msg))
+	close(stop)
+	assert.Equal(t, "data", msg.Type)
+	assert.Equal(t, "events", msg.Channel)
+	assert.Equal(t, "hello", msg.Data.(map[string]interface{})["message"])
+
+	require.NoError(t, conn.WriteJSON(wsMessage{Type: "unsubscribe", Channel: "events"}))
+	for msg.Type != "unsubscribed" {
+		require.NoError(t, conn.ReadJSON(&msg))
+	}
+	assert.Equal(t, "events", msg.Channel)
+}
+
+func TestWebSocket_Origin(t *testing.T) {
+	cfg := config.LoadWithDefaults()
+	cfg.AllowedOrigins = []string{"https://dashboard.example.com"}
+	srv := New(cfg)
+
+	_, resp, err := dialWS(t, srv, http.Header{"Origin": {"https://evil.example.com"}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, _, err = dialWS(t, srv, http.Header{"Origin": {"https://dashboard.example.com"}})
+	assert.NoError(t, err)
+}
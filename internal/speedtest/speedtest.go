@@ -0,0 +1,275 @@
+package speedtest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// MaxHistory is the number of results kept in memory
+const MaxHistory = 1000
+
+// Runner executes speed tests and keeps a persistent history of results
+type Runner struct {
+	backend     string
+	server      string
+	historyFile string
+	history     []Result
+	mu          sync.RWMutex
+}
+
+// NewRunner creates a new speed test runner. An empty backend auto-detects
+// an installed client. Results are appended to speedtest.jsonl in dataDir.
+func NewRunner(backend, server, dataDir string) *Runner {
+	r := &Runner{
+		backend: backend,
+		server:  server,
+	}
+
+	if dataDir != "" {
+		r.historyFile = filepath.Join(dataDir, "speedtest.jsonl")
+		r.loadHistory()
+	}
+
+	return r
+}
+
+// Backend returns the backend that will be used for the next run
+func (r *Runner) Backend() string {
+	if r.backend != "" {
+		return r.backend
+	}
+	if _, err := exec.LookPath("speedtest-cli"); err == nil {
+		return BackendSpeedtestCLI
+	}
+	if _, err := exec.LookPath("iperf3"); err == nil && r.server != "" {
+		return BackendIperf3
+	}
+	return ""
+}
+
+// Run executes a speed test and records the result in the history
+func (r *Runner) Run(ctx context.Context) (*Result, error) {
+	start := time.Now()
+
+	var result *Result
+	var err error
+
+	switch backend := r.Backend(); backend {
+	case BackendSpeedtestCLI:
+		result, err = r.runSpeedtestCLI(ctx)
+	case BackendIperf3:
+		result, err = r.runIperf3(ctx)
+	case "":
+		return nil, apierror.Unavailable("no speed test client found (install speedtest-cli or iperf3)")
+	default:
+		return nil, fmt.Errorf("unknown speed test backend: %s", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Timestamp = start
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	r.record(*result)
+	return result, nil
+}
+
+// History returns the most recent results, newest first
+func (r *Runner) History(limit int) *History {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if limit <= 0 || limit > len(r.history) {
+		limit = len(r.history)
+	}
+
+	results := make([]Result, 0, limit)
+	for i := len(r.history) - 1; i >= 0 && len(results) < limit; i-- {
+		results = append(results, r.history[i])
+	}
+
+	return &History{
+		Results: results,
+		Total:   len(r.history),
+	}
+}
+
+func (r *Runner) runSpeedtestCLI(ctx context.Context) (*Result, error) {
+	args := []string{"--json", "--secure"}
+	if r.server != "" {
+		args = append(args, "--server", r.server)
+	}
+
+	output, err := exec.CommandContext(ctx, "speedtest-cli", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("speedtest-cli failed: %w", err)
+	}
+	return parseSpeedtestCLI(output)
+}
+
+// parseSpeedtestCLI converts `speedtest-cli --json` output to a result
+func parseSpeedtestCLI(output []byte) (*Result, error) {
+	var out speedtestCLIOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse speedtest-cli output: %w", err)
+	}
+
+	server := out.Server.Sponsor
+	if out.Server.Name != "" {
+		server = fmt.Sprintf("%s (%s)", out.Server.Sponsor, out.Server.Name)
+	}
+
+	return &Result{
+		Backend:      BackendSpeedtestCLI,
+		Server:       server,
+		DownloadMbps: out.Download / 1e6,
+		UploadMbps:   out.Upload / 1e6,
+		LatencyMs:    out.Ping,
+		ISP:          out.Client.ISP,
+		ExternalIP:   out.Client.IP,
+	}, nil
+}
+
+func (r *Runner) runIperf3(ctx context.Context) (*Result, error) {
+	if r.server == "" {
+		return nil, apierror.Unavailable("iperf3 requires SPEEDTEST_SERVER to be set")
+	}
+
+	host, port, err := net.SplitHostPort(r.server)
+	if err != nil {
+		host, port = r.server, ""
+	}
+
+	// Upload: client sends to server
+	upload, err := r.iperf3(ctx, host, port, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Download: server sends to client (-R)
+	download, err := r.iperf3(ctx, host, port, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return iperf3Result(r.server, upload, download), nil
+}
+
+// iperf3Result combines an upload and a reverse (download) iperf3 run
+func iperf3Result(server string, upload, download *iperf3Output) *Result {
+	var latency float64
+	if len(upload.End.Streams) > 0 {
+		latency = upload.End.Streams[0].Sender.MeanRTT / 1000.0
+	}
+
+	return &Result{
+		Backend:      BackendIperf3,
+		Server:       server,
+		DownloadMbps: download.End.SumReceived.BitsPerSecond / 1e6,
+		UploadMbps:   upload.End.SumReceived.BitsPerSecond / 1e6,
+		LatencyMs:    latency,
+	}
+}
+
+func (r *Runner) iperf3(ctx context.Context, host, port string, reverse bool) (*iperf3Output, error) {
+	args := []string{"-c", host, "-J", "-t", "10"}
+	if port != "" {
+		args = append(args, "-p", port)
+	}
+	if reverse {
+		args = append(args, "-R")
+	}
+
+	// iperf3 exits non-zero on failure but still reports the reason in JSON
+	output, runErr := exec.CommandContext(ctx, "iperf3", args...).Output()
+	return parseIperf3(output, runErr)
+}
+
+// parseIperf3 parses `iperf3 -J` output. runErr is the command's own error,
+// reported when the output does not explain the failure.
+func parseIperf3(output []byte, runErr error) (*iperf3Output, error) {
+	var out iperf3Output
+	if err := json.Unmarshal(output, &out); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("iperf3 failed: %w", runErr)
+		}
+		return nil, fmt.Errorf("failed to parse iperf3 output: %w", err)
+	}
+	if out.Error != "" {
+		return nil, fmt.Errorf("iperf3 failed: %s", out.Error)
+	}
+
+	return &out, nil
+}
+
+func (r *Runner) record(result Result) {
+	r.mu.Lock()
+	r.history = append(r.history, result)
+	if len(r.history) > MaxHistory {
+		r.history = r.history[len(r.history)-MaxHistory:]
+	}
+	r.mu.Unlock()
+
+	if r.historyFile == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.historyFile), 0750); err != nil {
+		log.Printf("Failed to create speed test data directory: %v", err)
+		return
+	}
+
+	f, err := os.OpenFile(r.historyFile, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		log.Printf("Failed to open speed test history: %v", err)
+		return
+	}
+	defer f.Close()
+
+	data, _ := json.Marshal(result)
+	data = append(data, '\n')
+	// A write cut short, e.g. by a crash, leaves a partial last line; start
+	// a new line so this result is not appended to it
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		log.Printf("Failed to write speed test history: %v", err)
+	}
+}
+
+func (r *Runner) loadHistory() {
+	f, err := os.Open(r.historyFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var result Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			continue
+		}
+		r.history = append(r.history, result)
+	}
+
+	if len(r.history) > MaxHistory {
+		r.history = r.history[len(r.history)-MaxHistory:]
+	}
+}
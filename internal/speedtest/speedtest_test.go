@@ -0,0 +1,217 @@
+package speedtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// speedtestCLIJSON is output captured from `speedtest-cli --json --secure`
+const speedtestCLIJSON = `{"download": 93546282.25264835, "upload": 23171427.90478926, "ping": 14.321, "server": {"url": "https://speedtest.safaricom.co.ke:8080/speedtest/upload.php", "lat": "-1.2833", "lon": "36.8167", "name": "Nairobi", "country": "Kenya", "cc": "KE", "sponsor": "Safaricom", "id": "4372", "host": "speedtest.safaricom.co.ke:8080", "d": 3.0962, "latency": 14.321}, "timestamp": "2024-03-10T19:02:11.482051Z", "bytes_sent": 29360128, "bytes_received": 117356772, "share": null, "client": {"ip": "203.0.113.24", "lat": "-1.2841", "lon": "36.8155", "isp": "Safaricom", "isprating": "3.7", "rating": "0", "ispdlavg": "0", "ispulavg": "0", "loggedin": "0", "country": "KE"}}
+`
+
+// iperf3JSON is output captured from `iperf3 -c nas.lan -J -t 10`, with the
+// per-second intervals removed
+const iperf3JSON = `{
+	"start": {
+		"connected": [{"socket": 5, "local_host": "192.168.1.20", "local_port": 50412, "remote_host": "192.168.1.10", "remote_port": 5201}],
+		"version": "iperf 3.12",
+		"system_info": "Linux pi 6.1.21-v8+ #1642 SMP PREEMPT Mon Apr  3 17:24:16 BST 2023 aarch64",
+		"timestamp": {"time": "Sun, 10 Mar 2024 19:05:42 GMT", "timesecs": 1710097542},
+		"connecting_to": {"host": "nas.lan", "port": 5201},
+		"cookie": "x7n2p4kq9vbl3m6tq0dw8cyz5rjf1ehg2sua",
+		"tcp_mss_default": 1448,
+		"sock_bufsize": 0,
+		"sndbuf_actual": 16384,
+		"rcvbuf_actual": 131072,
+		"test_start": {"protocol": "TCP", "num_streams": 1, "blksize": 131072, "omit": 0, "duration": 10, "bytes": 0, "blocks": 0, "reverse": 0, "tos": 0, "target_bitrate": 0}
+	},
+	"intervals": [],
+	"end": {
+		"streams": [{
+			"sender": {"socket": 5, "start": 0, "end": 10.000164, "seconds": 10.000164, "bytes": 1162346496, "bits_per_second": 929862389.2, "retransmits": 12, "max_snd_cwnd": 624776, "max_rtt": 2105, "min_rtt": 412, "mean_rtt": 1287, "sender": true},
+			"receiver": {"socket": 5, "start": 0, "end": 10.003852, "seconds": 10.000164, "bytes": 1159724800, "bits_per_second": 927436452.5, "sender": true}
+		}],
+		"sum_sent": {"start": 0, "end": 10.000164, "seconds": 10.000164, "bytes": 1162346496, "bits_per_second": 929862389.2, "retransmits": 12, "sender": true},
+		"sum_received": {"start": 0, "end": 10.003852, "seconds": 10.003852, "bytes": 1159724800, "bits_per_second": 927436452.5, "sender": true},
+		"cpu_utilization_percent": {"host_total": 21.53, "host_user": 0.61, "host_system": 20.92, "remote_total": 8.44, "remote_user": 0.32, "remote_system": 8.12},
+		"sender_tcp_congestion": "cubic",
+		"receiver_tcp_congestion": "cubic"
+	}
+}
+`
+
+// iperf3ErrorJSON is what iperf3 -J prints when it cannot reach the server
+const iperf3ErrorJSON = `{
+	"start": {"connected": [], "version": "iperf 3.12", "system_info": "Linux pi 6.1.21-v8+ #1642 SMP PREEMPT Mon Apr  3 17:24:16 BST 2023 aarch64"},
+	"intervals": [],
+	"end": {},
+	"error": "error - unable to connect to server: Connection refused"
+}
+`
+
+func TestParseSpeedtestCLI(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    *Result
+		wantErr bool
+	}{
+		{
+			name:   "captured",
+			output: speedtestCLIJSON,
+			want: &Result{
+				Backend:      BackendSpeedtestCLI,
+				Server:       "Safaricom (Nairobi)",
+				DownloadMbps: 93.54628225264835,
+				UploadMbps:   23.17142790478926,
+				LatencyMs:    14.321,
+				ISP:          "Safaricom",
+				ExternalIP:   "203.0.113.24",
+			},
+		},
+		{
+			name:   "server without a name",
+			output: `{"download": 1e6, "upload": 5e5, "ping": 30, "server": {"sponsor": "Example"}, "client": {}}`,
+			want:   &Result{Backend: BackendSpeedtestCLI, Server: "Example", DownloadMbps: 1, UploadMbps: 0.5, LatencyMs: 30},
+		},
+		{name: "truncated", output: speedtestCLIJSON[:len(speedtestCLIJSON)/2], wantErr: true},
+		{name: "not json", output: "ERROR: Unable to connect to servers to test latency.", wantErr: true},
+		{name: "empty", output: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseSpeedtestCLI([]byte(tt.output))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want.DownloadMbps, result.DownloadMbps, 1e-9)
+			assert.InDelta(t, tt.want.UploadMbps, result.UploadMbps, 1e-9)
+			result.DownloadMbps, result.UploadMbps = tt.want.DownloadMbps, tt.want.UploadMbps
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestParseIperf3(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name    string
+		output  string
+		runErr  error
+		wantErr string
+	}{
+		{name: "captured", output: iperf3JSON},
+		{name: "server error", output: iperf3ErrorJSON, runErr: exitErr, wantErr: "unable to connect to server"},
+		{name: "truncated", output: iperf3JSON[:200], wantErr: "failed to parse iperf3 output"},
+		{name: "truncated after failure", output: iperf3JSON[:200], runErr: exitErr, wantErr: "exit status 1"},
+		{name: "no output", runErr: exitErr, wantErr: "exit status 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := parseIperf3([]byte(tt.output), tt.runErr)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 927436452.5, out.End.SumReceived.BitsPerSecond)
+			require.Len(t, out.End.Streams, 1)
+			assert.Equal(t, 1287.0, out.End.Streams[0].Sender.MeanRTT)
+		})
+	}
+}
+
+func TestIperf3Result(t *testing.T) {
+	upload, err := parseIperf3([]byte(iperf3JSON), nil)
+	require.NoError(t, err)
+	download := &iperf3Output{}
+	download.End.SumReceived.BitsPerSecond = 512e6
+
+	result := iperf3Result("nas.lan:5201", upload, download)
+	assert.Equal(t, BackendIperf3, result.Backend)
+	assert.Equal(t, "nas.lan:5201", result.Server)
+	assert.Equal(t, 512.0, result.DownloadMbps)
+	assert.InDelta(t, 927.4364525, result.UploadMbps, 1e-9)
+	assert.InDelta(t, 1.287, result.LatencyMs, 1e-9)
+
+	// A run without streams has no latency rather than failing
+	result = iperf3Result("nas.lan", download, download)
+	assert.Zero(t, result.LatencyMs)
+}
+
+func historyLine(i int) string {
+	ts := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Hour)
+	return fmt.Sprintf(`{"timestamp":%q,"backend":"iperf3","server":"nas.lan","download_mbps":%d,"upload_mbps":1,"latency_ms":1,"duration_ms":20000}`, ts.Format(time.RFC3339), i)
+}
+
+func TestRunner_History(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "speedtest.jsonl")
+
+	lines := []string{
+		historyLine(1),
+		"not json",
+		"",
+		historyLine(2),
+		`{"timestamp":"2024-03-10T05:00:00Z","backend":"iperf3","down`, // cut short by a crash
+	}
+	require.NoError(t, os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0640))
+
+	r := NewRunner(BackendIperf3, "nas.lan", dir)
+	history := r.History(0)
+	assert.Equal(t, 2, history.Total)
+	require.Len(t, history.Results, 2)
+	assert.Equal(t, 2.0, history.Results[0].DownloadMbps, "newest first")
+	assert.Equal(t, 1.0, history.Results[1].DownloadMbps)
+
+	// A new result goes on its own line after the partial one
+	r.record(Result{Backend: BackendIperf3, Server: "nas.lan", DownloadMbps: 3})
+	assert.Equal(t, 3, r.History(0).Total)
+
+	reloaded := NewRunner(BackendIperf3, "nas.lan", dir)
+	history = reloaded.History(1)
+	assert.Equal(t, 3, history.Total)
+	require.Len(t, history.Results, 1)
+	assert.Equal(t, 3.0, history.Results[0].DownloadMbps)
+}
+
+func TestRunner_HistoryLimit(t *testing.T) {
+	dir := t.TempDir()
+
+	var lines []string
+	for i := 0; i < MaxHistory+5; i++ {
+		lines = append(lines, historyLine(i))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "speedtest.jsonl"), []byte(strings.Join(lines, "\n")+"\n"), 0640))
+
+	r := NewRunner(BackendIperf3, "nas.lan", dir)
+	history := r.History(-1)
+	assert.Equal(t, MaxHistory, history.Total)
+	assert.Len(t, history.Results, MaxHistory)
+	assert.Equal(t, float64(MaxHistory+4), history.Results[0].DownloadMbps)
+	assert.Equal(t, 5.0, history.Results[MaxHistory-1].DownloadMbps, "the oldest results are dropped")
+
+	assert.Len(t, r.History(10).Results, 10)
+}
+
+func TestRunner_NoDataDir(t *testing.T) {
+	r := NewRunner(BackendIperf3, "nas.lan", "")
+	r.record(Result{DownloadMbps: 1})
+
+	history := r.History(0)
+	assert.Equal(t, 1, history.Total)
+	assert.Equal(t, 1.0, history.Results[0].DownloadMbps)
+}
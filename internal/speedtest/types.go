@@ -0,0 +1,63 @@
+package speedtest
+
+import "time"
+
+// Backend identifiers
+const (
+	BackendSpeedtestCLI = "speedtest-cli"
+	BackendIperf3       = "iperf3"
+)
+
+// Result represents a completed speed test
+type Result struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Backend      string    `json:"backend"`
+	Server       string    `json:"server"`
+	DownloadMbps float64   `json:"download_mbps"`
+	UploadMbps   float64   `json:"upload_mbps"`
+	LatencyMs    float64   `json:"latency_ms"`
+	ISP          string    `json:"isp,omitempty"`
+	ExternalIP   string    `json:"external_ip,omitempty"`
+	DurationMs   int64     `json:"duration_ms"`
+}
+
+// History contains past speed test results
+type History struct {
+	Results []Result `json:"results"`
+	Total   int      `json:"total"`
+}
+
+// speedtestCLIOutput mirrors the JSON emitted by `speedtest-cli --json`
+type speedtestCLIOutput struct {
+	Download float64 `json:"download"` // bits per second
+	Upload   float64 `json:"upload"`   // bits per second
+	Ping     float64 `json:"ping"`     // milliseconds
+	Server   struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Sponsor string `json:"sponsor"`
+		Host    string `json:"host"`
+	} `json:"server"`
+	Client struct {
+		IP  string `json:"ip"`
+		ISP string `json:"isp"`
+	} `json:"client"`
+}
+
+// iperf3Output mirrors the parts of `iperf3 -J` output used here
+type iperf3Output struct {
+	End struct {
+		SumSent struct {
+			BitsPerSecond float64 `json:"bits_per_second"`
+		} `json:"sum_sent"`
+		SumReceived struct {
+			BitsPerSecond float64 `json:"bits_per_second"`
+		} `json:"sum_received"`
+		Streams []struct {
+			Sender struct {
+				MeanRTT float64 `json:"mean_rtt"` // microseconds
+			} `json:"sender"`
+		} `json:"streams"`
+	} `json:"end"`
+	Error string `json:"error"`
+}
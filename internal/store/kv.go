@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// Get returns the value stored under namespace and key
+func (db *DB) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	var value []byte
+	err := db.QueryRowContext(ctx, `SELECT value FROM kv WHERE namespace = ? AND key = ?`, namespace, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apierror.NotFound("%s/%s not found", namespace, key)
+	}
+	return value, err
+}
+
+// Put stores value under namespace and key, replacing any previous value
+func (db *DB) Put(ctx context.Context, namespace, key string, value []byte) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO kv (namespace, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (namespace, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		namespace, key, value, time.Now().Unix())
+	return err
+}
+
+// Delete removes a key. Deleting a missing key is not an error.
+func (db *DB) Delete(ctx context.Context, namespace, key string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM kv WHERE namespace = ? AND key = ?`, namespace, key)
+	return err
+}
+
+// Keys returns the keys in a namespace, sorted
+func (db *DB) Keys(ctx context.Context, namespace string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT key FROM kv WHERE namespace = ? ORDER BY key`, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetJSON decodes the value under namespace and key into v
+func (db *DB) GetJSON(ctx context.Context, namespace, key string, v interface{}) error {
+	value, err := db.Get(ctx, namespace, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(value, v)
+}
+
+// PutJSON stores v encoded as JSON
+func (db *DB) PutJSON(ctx context.Context, namespace, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return db.Put(ctx, namespace, key, value)
+}
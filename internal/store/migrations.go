@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// migration is one schema change. Migrations are applied in order and
+// never edited once released; add a new one instead.
+type migration struct {
+	name string
+	sql  string
+}
+
+// migrations are numbered from 1 by position
+var migrations = []migration{
+	{
+		name: "kv",
+		sql: `CREATE TABLE kv (
+			namespace  TEXT NOT NULL,
+			key        TEXT NOT NULL,
+			value      BLOB NOT NULL,
+			updated_at INTEGER NOT NULL,
+			PRIMARY KEY (namespace, key)
+		)`,
+	},
+}
+
+func (db *DB) migrate(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	version, err := db.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema is version %d, newer than this agent's %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if err := db.apply(ctx, i+1, migrations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) apply(ctx context.Context, version int, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", version, m.name, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		version, m.name, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", version, err)
+	}
+	return tx.Commit()
+}
+
+// Version returns the number of migrations applied
+func (db *DB) Version(ctx context.Context) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
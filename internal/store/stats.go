@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Stats is the database's size on disk and its contents
+type Stats struct {
+	Path          string           `json:"path"`
+	SchemaVersion int              `json:"schema_version"`
+	SizeBytes     int64            `json:"size_bytes"` // Database, WAL and shared memory files
+	WALBytes      int64            `json:"wal_bytes"`
+	PageSize      int64            `json:"page_size"`
+	Pages         int64            `json:"pages"`
+	FreePages     int64            `json:"free_pages"` // Reusable, or reclaimed by VACUUM
+	Tables        map[string]int64 `json:"tables"`     // Rows per table
+}
+
+// Stats reports the database's size and rows per table
+func (db *DB) Stats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{Path: db.path, Tables: map[string]int64{}}
+
+	var err error
+	if stats.SchemaVersion, err = db.Version(ctx); err != nil {
+		return nil, err
+	}
+	for pragma, dest := range map[string]*int64{
+		"page_size":      &stats.PageSize,
+		"page_count":     &stats.Pages,
+		"freelist_count": &stats.FreePages,
+	} {
+		if err := db.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(dest); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", pragma, err)
+		}
+	}
+
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if info, err := os.Stat(db.path + suffix); err == nil {
+			stats.SizeBytes += info.Size()
+			if suffix == "-wal" {
+				stats.WALBytes = info.Size()
+			}
+		}
+	}
+
+	tables, err := db.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		var rows int64
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+table+`"`).Scan(&rows); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		stats.Tables[table] = rows
+	}
+	return stats, nil
+}
+
+func (db *DB) tables(ctx context.Context) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// Compact returns the WAL to the filesystem and, when at least a quarter
+// of the pages are free, rebuilds the database to release them
+func (db *DB) Compact(ctx context.Context) error {
+	if _, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint: %w", err)
+	}
+
+	var pages, free int64
+	if err := db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+		return err
+	}
+	if err := db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&free); err != nil {
+		return err
+	}
+	if free == 0 || free*4 < pages {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	_, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite" // Pure Go, so the agent still builds with CGO_ENABLED=0
+)
+
+// FileName is the database file in the data directory
+const FileName = "hivedeck.db"
+
+// DB is the agent's embedded SQLite database, shared by the features that
+// keep state across restarts
+type DB struct {
+	*sql.DB
+	path string
+}
+
+// Open opens or creates the database in dataDir and applies any pending
+// migrations
+func Open(ctx context.Context, dataDir string) (*DB, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(dataDir, FileName)
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_pragma=synchronous(NORMAL)"
+	sqlDB, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	// One writer at a time avoids SQLITE_BUSY; the agent's load is light
+	sqlDB.SetMaxOpenConns(1)
+
+	db := &DB{DB: sqlDB, path: path}
+	if err := db.migrate(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Path returns the database file
+func (db *DB) Path() string {
+	return db.path
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestOpenMigrates(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	db, err := Open(ctx, dir)
+	require.NoError(t, err)
+	version, err := db.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), version)
+	require.NoError(t, db.Put(ctx, "test", "a", []byte("1")))
+	require.NoError(t, db.Close())
+
+	// Reopening applies nothing twice and keeps the data
+	db, err = Open(ctx, dir)
+	require.NoError(t, err)
+	value, err := db.Get(ctx, "test", "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	// A database from a newer agent is refused
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', 0)`, len(migrations)+1)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	_, err = Open(ctx, dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer than this agent")
+}
+
+func TestKV(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, t.TempDir())
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Get(ctx, "jobs", "missing")
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+
+	type job struct{ Status string }
+	require.NoError(t, db.PutJSON(ctx, "jobs", "b", job{"running"}))
+	require.NoError(t, db.PutJSON(ctx, "jobs", "b", job{"done"}))
+	require.NoError(t, db.PutJSON(ctx, "jobs", "a", job{"queued"}))
+	require.NoError(t, db.Put(ctx, "other", "c", []byte("x")))
+
+	var got job
+	require.NoError(t, db.GetJSON(ctx, "jobs", "b", &got))
+	assert.Equal(t, "done", got.Status)
+
+	keys, err := db.Keys(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	require.NoError(t, db.Delete(ctx, "jobs", "a"))
+	require.NoError(t, db.Delete(ctx, "jobs", "a"))
+	keys, _ = db.Keys(ctx, "jobs")
+	assert.Equal(t, []string{"b"}, keys)
+
+	stats, err := db.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), stats.SchemaVersion)
+	assert.Equal(t, int64(2), stats.Tables["kv"])
+	assert.Equal(t, int64(len(migrations)), stats.Tables["schema_migrations"])
+	assert.Positive(t, stats.SizeBytes)
+	assert.Positive(t, stats.PageSize)
+}
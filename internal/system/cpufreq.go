@@ -0,0 +1,136 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// CPUFreqPath holds the kernel's cpufreq policies
+const CPUFreqPath = "/sys/devices/system/cpu/cpufreq"
+
+// cpufreqRoot is CPUFreqPath, replaced in tests
+var cpufreqRoot = CPUFreqPath
+
+// GetCPUFrequency reads per-core frequencies and governors from cpufreq.
+// It returns nil when the kernel has no cpufreq driver, as in most VMs.
+func GetCPUFrequency() *CPUFrequency {
+	policies, _ := filepath.Glob(filepath.Join(cpufreqRoot, "policy*"))
+	if len(policies) == 0 {
+		return nil
+	}
+
+	freq := &CPUFrequency{Available: []string{}, Cores: []CoreFrequency{}}
+	governors := map[string]bool{}
+	for _, policy := range policies {
+		governor := readSysfs(policy, "scaling_governor")
+		governors[governor] = true
+		if freq.Driver == "" {
+			freq.Driver = readSysfs(policy, "scaling_driver")
+		}
+		for _, g := range strings.Fields(readSysfs(policy, "scaling_available_governors")) {
+			if !slices.Contains(freq.Available, g) {
+				freq.Available = append(freq.Available, g)
+			}
+		}
+
+		core := CoreFrequency{
+			Governor: governor,
+			MinMhz:   readKHz(policy, "cpuinfo_min_freq"),
+			MaxMhz:   readKHz(policy, "cpuinfo_max_freq"),
+			LimitMhz: readKHz(policy, "scaling_max_freq"),
+		}
+		if core.Mhz = readKHz(policy, "scaling_cur_freq"); core.Mhz == 0 {
+			core.Mhz = readKHz(policy, "cpuinfo_cur_freq")
+		}
+		if core.MaxMhz > 0 && core.LimitMhz > 0 && core.LimitMhz < core.MaxMhz {
+			freq.Limited = true
+		}
+
+		// A policy covers one or more cores that share a clock
+		for _, cpu := range parseCPUList(readSysfs(policy, "affected_cpus")) {
+			core.CPU = cpu
+			freq.Cores = append(freq.Cores, core)
+		}
+	}
+
+	sort.Slice(freq.Cores, func(i, j int) bool { return freq.Cores[i].CPU < freq.Cores[j].CPU })
+	sort.Strings(freq.Available)
+	for g := range governors {
+		freq.Governor = g
+	}
+	if len(governors) > 1 {
+		freq.Governor = "mixed"
+	}
+	return freq
+}
+
+// CheckGovernor checks that every cpufreq policy offers governor. Cores on
+// big.LITTLE boards can have different drivers, so each policy's own list
+// is checked.
+func CheckGovernor(governor string) error {
+	policies, _ := filepath.Glob(filepath.Join(cpufreqRoot, "policy*"))
+	if len(policies) == 0 {
+		return apierror.Unavailable("this host has no CPU frequency scaling")
+	}
+	for _, policy := range policies {
+		available := strings.Fields(readSysfs(policy, "scaling_available_governors"))
+		if !slices.Contains(available, governor) {
+			return apierror.Invalid("governor %q is not available for %s: use one of %s", governor, filepath.Base(policy), strings.Join(available, ", "))
+		}
+	}
+	return nil
+}
+
+// SetGovernor switches every core to governor, which every policy's driver
+// must offer
+func SetGovernor(governor string) error {
+	if err := CheckGovernor(governor); err != nil {
+		return err
+	}
+
+	policies, _ := filepath.Glob(filepath.Join(cpufreqRoot, "policy*"))
+	for _, policy := range policies {
+		if err := os.WriteFile(filepath.Join(policy, "scaling_governor"), []byte(governor), 0644); err != nil {
+			if os.IsPermission(err) {
+				return apierror.NotAllowed("setting the CPU governor needs root: %w", err)
+			}
+			return fmt.Errorf("failed to set governor of %s: %w", filepath.Base(policy), err)
+		}
+	}
+	return nil
+}
+
+func readSysfs(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readKHz reads a frequency in kHz as MHz
+func readKHz(dir, name string) float64 {
+	khz, err := strconv.ParseFloat(readSysfs(dir, name), 64)
+	if err != nil {
+		return 0
+	}
+	return khz / 1000
+}
+
+// parseCPUList parses a list of CPUs such as "0 1 2 3"
+func parseCPUList(s string) []int {
+	var cpus []int
+	for _, f := range strings.Fields(s) {
+		if n, err := strconv.Atoi(f); err == nil {
+			cpus = append(cpus, n)
+		}
+	}
+	return cpus
+}
@@ -0,0 +1,75 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// writePolicy fakes a cpufreq policy directory
+func writePolicy(t *testing.T, root, name string, files map[string]string) {
+	dir := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for file, value := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(value+"\n"), 0644))
+	}
+}
+
+func TestCPUFrequency(t *testing.T) {
+	orig := cpufreqRoot
+	defer func() { cpufreqRoot = orig }()
+	cpufreqRoot = t.TempDir()
+
+	assert.Nil(t, GetCPUFrequency(), "no cpufreq driver")
+	assert.ErrorIs(t, SetGovernor("performance"), apierror.ErrUnavailable)
+
+	// A big.LITTLE board: four small cores share a clock, two big ones are
+	// capped below their maximum
+	writePolicy(t, cpufreqRoot, "policy0", map[string]string{
+		"affected_cpus":               "0 1 2 3",
+		"scaling_governor":            "ondemand",
+		"scaling_available_governors": "ondemand userspace powersave performance schedutil",
+		"scaling_driver":              "cpufreq-dt",
+		"scaling_cur_freq":            "1416000",
+		"scaling_max_freq":            "1416000",
+		"cpuinfo_min_freq":            "408000",
+		"cpuinfo_max_freq":            "1416000",
+	})
+	writePolicy(t, cpufreqRoot, "policy4", map[string]string{
+		"affected_cpus":               "4 5",
+		"scaling_governor":            "ondemand",
+		"scaling_available_governors": "ondemand userspace powersave performance schedutil",
+		"scaling_cur_freq":            "1200000",
+		"scaling_max_freq":            "1200000",
+		"cpuinfo_min_freq":            "408000",
+		"cpuinfo_max_freq":            "1800000",
+	})
+
+	freq := GetCPUFrequency()
+	require.NotNil(t, freq)
+	assert.Equal(t, "ondemand", freq.Governor)
+	assert.Equal(t, []string{"ondemand", "performance", "powersave", "schedutil", "userspace"}, freq.Available)
+	assert.Equal(t, "cpufreq-dt", freq.Driver)
+	assert.True(t, freq.Limited)
+	require.Len(t, freq.Cores, 6)
+	assert.Equal(t, CoreFrequency{CPU: 5, Mhz: 1200, MinMhz: 408, MaxMhz: 1800, LimitMhz: 1200, Governor: "ondemand"}, freq.Cores[5])
+
+	assert.ErrorIs(t, SetGovernor("turbo"), apierror.ErrInvalid)
+	require.NoError(t, SetGovernor("performance"))
+	assert.Equal(t, "performance", GetCPUFrequency().Governor)
+
+	writePolicy(t, cpufreqRoot, "policy4", map[string]string{"scaling_governor": "powersave"})
+	assert.Equal(t, "mixed", GetCPUFrequency().Governor)
+
+	// A governor only one policy offers is refused, leaving both unchanged
+	writePolicy(t, cpufreqRoot, "policy4", map[string]string{"scaling_available_governors": "powersave performance"})
+	err := SetGovernor("schedutil")
+	assert.ErrorIs(t, err, apierror.ErrInvalid)
+	assert.ErrorContains(t, err, "policy4")
+	assert.Equal(t, "performance", readSysfs(filepath.Join(cpufreqRoot, "policy0"), "scaling_governor"))
+}
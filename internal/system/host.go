@@ -15,20 +15,6 @@ func GetHostInfo() (*HostInfo, error) {
 		return nil, fmt.Errorf("failed to get host info: %w", err)
 	}
 
-	// Get temperature sensors
-	var temps []Temperature
-	sensorStats, err := sensors.SensorsTemperatures()
-	if err == nil {
-		for _, sensor := range sensorStats {
-			if sensor.Temperature > 0 {
-				temps = append(temps, Temperature{
-					SensorKey:   sensor.SensorKey,
-					Temperature: sensor.Temperature,
-				})
-			}
-		}
-	}
-
 	return &HostInfo{
 		Hostname:        info.Hostname,
 		OS:              info.OS,
@@ -40,10 +26,28 @@ func GetHostInfo() (*HostInfo, error) {
 		UptimeHuman:     formatUptime(info.Uptime),
 		BootTime:        info.BootTime,
 		Procs:           info.Procs,
-		Temperatures:    temps,
+		Temperatures:    GetTemperatures(),
 	}, nil
 }
 
+// GetTemperatures reads the temperature sensors, skipping ones that report
+// nothing
+func GetTemperatures() []Temperature {
+	var temps []Temperature
+	sensorStats, err := sensors.SensorsTemperatures()
+	if err == nil {
+		for _, sensor := range sensorStats {
+			if sensor.Temperature > 0 {
+				temps = append(temps, Temperature{
+					SensorKey:   sensor.SensorKey,
+					Temperature: sensor.Temperature,
+				})
+			}
+		}
+	}
+	return temps
+}
+
 // formatUptime converts uptime seconds to human readable format
 func formatUptime(seconds uint64) string {
 	duration := time.Duration(seconds) * time.Second
@@ -2,6 +2,7 @@ package system
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v4/cpu"
@@ -9,16 +10,25 @@ import (
 	"github.com/shirou/gopsutil/v4/load"
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/net"
+	"golang.org/x/sync/errgroup"
 )
 
 // Collector handles system metrics collection
-type Collector struct{}
+type Collector struct {
+	throttled func() *bool
+}
 
 // NewCollector creates a new metrics collector
 func NewCollector() *Collector {
 	return &Collector{}
 }
 
+// SetThrottleSource reports whether the CPU is throttled from fn, which
+// returns nil when it is not known
+func (c *Collector) SetThrottleSource(fn func() *bool) {
+	c.throttled = fn
+}
+
 // GetCPUInfo retrieves CPU usage and information
 func (c *Collector) GetCPUInfo() (*CPUInfo, error) {
 	// Get CPU info
@@ -58,6 +68,11 @@ func (c *Collector) GetCPUInfo() (*CPUInfo, error) {
 		usageTotal = percentTotal[0]
 	}
 
+	var throttled *bool
+	if c.throttled != nil {
+		throttled = c.throttled()
+	}
+
 	return &CPUInfo{
 		Cores:       len(cpuInfo),
 		ModelName:   modelName,
@@ -67,6 +82,8 @@ func (c *Collector) GetCPUInfo() (*CPUInfo, error) {
 		LoadAvg1:    loadAvg.Load1,
 		LoadAvg5:    loadAvg.Load5,
 		LoadAvg15:   loadAvg.Load15,
+		Frequency:   GetCPUFrequency(),
+		Throttled:   throttled,
 	}, nil
 }
 
@@ -181,39 +198,84 @@ func (c *Collector) GetNetworkInfo() (*NetworkInfo, error) {
 	}, nil
 }
 
-// GetAllMetrics retrieves all system metrics
+// GetAllMetrics retrieves all system metrics. Sections are collected
+// concurrently; a failing section is reported in Errors rather than failing
+// the whole snapshot. An error is only returned if every section failed.
 func (c *Collector) GetAllMetrics() (*AllMetrics, error) {
-	host, err := GetHostInfo()
-	if err != nil {
-		return nil, err
-	}
+	return collectAll([]metricSection{
+		{"host", func(m *AllMetrics) error {
+			host, err := GetHostInfo()
+			if err == nil {
+				m.Host = *host
+			}
+			return err
+		}},
+		{"cpu", func(m *AllMetrics) error {
+			cpuInfo, err := c.GetCPUInfo()
+			if err == nil {
+				m.CPU = *cpuInfo
+			}
+			return err
+		}},
+		{"memory", func(m *AllMetrics) error {
+			memory, err := c.GetMemoryInfo()
+			if err == nil {
+				m.Memory = *memory
+			}
+			return err
+		}},
+		{"disk", func(m *AllMetrics) error {
+			diskInfo, err := c.GetDiskInfo()
+			if err == nil {
+				m.Disk = *diskInfo
+			}
+			return err
+		}},
+		{"network", func(m *AllMetrics) error {
+			network, err := c.GetNetworkInfo()
+			if err == nil {
+				m.Network = *network
+			}
+			return err
+		}},
+	})
+}
 
-	cpuInfo, err := c.GetCPUInfo()
-	if err != nil {
-		return nil, err
-	}
+// metricSection collects one section of AllMetrics into m
+type metricSection struct {
+	name    string
+	collect func(m *AllMetrics) error
+}
 
-	memory, err := c.GetMemoryInfo()
-	if err != nil {
-		return nil, err
+// collectAll runs every section concurrently. Each section writes only its
+// own field, so they share the result without locking.
+func collectAll(sections []metricSection) (*AllMetrics, error) {
+	metrics := &AllMetrics{
+		Timestamp: time.Now(),
 	}
 
-	diskInfo, err := c.GetDiskInfo()
-	if err != nil {
-		return nil, err
+	var mu sync.Mutex
+	errs := make(map[string]string)
+
+	var g errgroup.Group
+	for _, section := range sections {
+		g.Go(func() error {
+			if err := section.collect(metrics); err != nil {
+				mu.Lock()
+				errs[section.name] = err.Error()
+				mu.Unlock()
+			}
+			return nil
+		})
 	}
+	_ = g.Wait()
 
-	network, err := c.GetNetworkInfo()
-	if err != nil {
-		return nil, err
+	if len(errs) == len(sections) {
+		return nil, fmt.Errorf("failed to collect metrics: %v", errs)
+	}
+	if len(errs) > 0 {
+		metrics.Errors = errs
 	}
 
-	return &AllMetrics{
-		Timestamp: time.Now(),
-		Host:      *host,
-		CPU:       *cpuInfo,
-		Memory:    *memory,
-		Disk:      *diskInfo,
-		Network:   *network,
-	}, nil
+	return metrics, nil
 }
@@ -0,0 +1,75 @@
+package system
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectAll(t *testing.T) {
+	ok := func(m *AllMetrics) error { return nil }
+	fail := func(m *AllMetrics) error { return errors.New("permission denied") }
+
+	metrics, err := collectAll([]metricSection{
+		{"cpu", func(m *AllMetrics) error {
+			m.CPU.Cores = 4
+			return nil
+		}},
+		{"memory", func(m *AllMetrics) error {
+			m.Memory.Total = 1 << 30
+			return nil
+		}},
+		{"disk", ok},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, metrics.CPU.Cores)
+	assert.Equal(t, uint64(1<<30), metrics.Memory.Total)
+	assert.Nil(t, metrics.Errors)
+	assert.False(t, metrics.Timestamp.IsZero())
+
+	// A failing section is reported while the others are still returned
+	metrics, err = collectAll([]metricSection{
+		{"cpu", func(m *AllMetrics) error {
+			m.CPU.Cores = 4
+			return nil
+		}},
+		{"disk", fail},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, metrics.CPU.Cores)
+	assert.Equal(t, map[string]string{"disk": "permission denied"}, metrics.Errors)
+
+	// Only a snapshot where every section failed is an error
+	metrics, err = collectAll([]metricSection{{"cpu", fail}, {"disk", fail}})
+	assert.Error(t, err)
+	assert.Nil(t, metrics)
+}
+
+func TestCollectAll_Concurrent(t *testing.T) {
+	// Each section waits until all have started, which only completes if
+	// they run at the same time
+	var started sync.WaitGroup
+	started.Add(3)
+	wait := func(m *AllMetrics) error {
+		started.Done()
+		done := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("sections ran one at a time")
+		}
+	}
+
+	metrics, err := collectAll([]metricSection{{"cpu", wait}, {"memory", wait}, {"disk", wait}})
+	require.NoError(t, err)
+	assert.Nil(t, metrics.Errors)
+}
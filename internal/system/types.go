@@ -19,14 +19,16 @@ type HostInfo struct {
 
 // CPUInfo contains CPU usage information
 type CPUInfo struct {
-	Cores       int       `json:"cores"`
-	ModelName   string    `json:"model_name"`
-	Mhz         float64   `json:"mhz"`
-	UsageTotal  float64   `json:"usage_total"`
-	UsagePerCPU []float64 `json:"usage_per_cpu"`
-	LoadAvg1    float64   `json:"load_avg_1"`
-	LoadAvg5    float64   `json:"load_avg_5"`
-	LoadAvg15   float64   `json:"load_avg_15"`
+	Cores       int           `json:"cores"`
+	ModelName   string        `json:"model_name"`
+	Mhz         float64       `json:"mhz"`
+	UsageTotal  float64       `json:"usage_total"`
+	UsagePerCPU []float64     `json:"usage_per_cpu"`
+	LoadAvg1    float64       `json:"load_avg_1"`
+	LoadAvg5    float64       `json:"load_avg_5"`
+	LoadAvg15   float64       `json:"load_avg_15"`
+	Frequency   *CPUFrequency `json:"frequency,omitempty"` // Left out without cpufreq, as in most VMs
+	Throttled   *bool         `json:"throttled,omitempty"` // From the thermal monitor, when the platform reports it
 }
 
 // MemoryInfo contains memory usage information
@@ -87,6 +89,8 @@ type AllMetrics struct {
 	Memory    MemoryInfo  `json:"memory"`
 	Disk      DiskInfo    `json:"disk"`
 	Network   NetworkInfo `json:"network"`
+	// Errors maps a section name to its collection error, if any
+	Errors map[string]string `json:"errors,omitempty"`
 }
 
 // Temperature represents CPU/GPU temperature
@@ -94,3 +98,22 @@ type Temperature struct {
 	SensorKey   string  `json:"sensor_key"`
 	Temperature float64 `json:"temperature"`
 }
+
+// CoreFrequency is one core's clock and scaling state
+type CoreFrequency struct {
+	CPU      int     `json:"cpu"`
+	Mhz      float64 `json:"mhz"`
+	MinMhz   float64 `json:"min_mhz,omitempty"`   // Lowest the hardware allows
+	MaxMhz   float64 `json:"max_mhz,omitempty"`   // Highest the hardware allows
+	LimitMhz float64 `json:"limit_mhz,omitempty"` // Highest the governor may use now
+	Governor string  `json:"governor,omitempty"`
+}
+
+// CPUFrequency is the clock and governor of every core
+type CPUFrequency struct {
+	Governor  string          `json:"governor"` // Shared by all cores, or "mixed"
+	Available []string        `json:"available_governors"`
+	Driver    string          `json:"driver,omitempty"`
+	Cores     []CoreFrequency `json:"cores"`
+	Limited   bool            `json:"limited"` // A core's limit is below its maximum, e.g. from thermal capping
+}
@@ -0,0 +1,106 @@
+package systemd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// busConn is the part of a D-Bus connection the pool manages
+type busConn interface {
+	Connected() bool
+	Close()
+}
+
+// connPool keeps one D-Bus connection to systemd open for all calls and
+// dials a new one when it breaks. go-systemd connections are safe for
+// concurrent use, including waiting on several jobs at once.
+type connPool struct {
+	mu   sync.Mutex
+	conn busConn
+
+	// dial opens a new connection; nil dials systemd
+	dial func(ctx context.Context) (busConn, error)
+}
+
+// dialSystemd opens a connection to systemd's D-Bus API
+func dialSystemd(ctx context.Context) (busConn, error) {
+	// godbus closes a connection when its dial context ends, so the
+	// connection must outlive the request that opened it
+	conn, err := dbus.NewWithContext(context.WithoutCancel(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// get returns the shared connection, dialing it if there is none or the
+// previous one was lost
+func (p *connPool) get(ctx context.Context) (*dbus.Conn, error) {
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*dbus.Conn), nil
+}
+
+// do runs fn on the shared connection. If fn fails because the connection
+// dropped, it is retried once on a new connection.
+func (p *connPool) do(ctx context.Context, fn func(*dbus.Conn) error) error {
+	return p.run(ctx, func(conn busConn) error {
+		return fn(conn.(*dbus.Conn))
+	})
+}
+
+func (p *connPool) acquire(ctx context.Context) (busConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil && p.conn.Connected() {
+		return p.conn, nil
+	}
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+
+	dial := p.dial
+	if dial == nil {
+		dial = dialSystemd
+	}
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, apierror.Unavailable("failed to connect to systemd: %w", err)
+	}
+	p.conn = conn
+	return conn, nil
+}
+
+func (p *connPool) run(ctx context.Context, fn func(busConn) error) error {
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	if err = fn(conn); err == nil || conn.Connected() || ctx.Err() != nil {
+		return err
+	}
+
+	if conn, err = p.acquire(ctx); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// Close closes the shared connection
+func (p *connPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
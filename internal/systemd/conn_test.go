@@ -0,0 +1,177 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// fakeConn is a connection the test can drop
+type fakeConn struct {
+	dropped atomic.Bool
+	closed  atomic.Bool
+}
+
+func (c *fakeConn) Connected() bool { return !c.dropped.Load() && !c.closed.Load() }
+func (c *fakeConn) Close()          { c.closed.Store(true) }
+
+// fakeDialer hands out new fake connections and records them
+type fakeDialer struct {
+	mu    sync.Mutex
+	conns []*fakeConn
+	err   error
+}
+
+func (d *fakeDialer) dial(ctx context.Context) (busConn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return nil, d.err
+	}
+	conn := &fakeConn{}
+	d.conns = append(d.conns, conn)
+	return conn, nil
+}
+
+func (d *fakeDialer) dialed() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.conns)
+}
+
+func TestConnPool_ReusesConnection(t *testing.T) {
+	d := &fakeDialer{}
+	p := &connPool{dial: d.dial}
+
+	var used []busConn
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.run(context.Background(), func(conn busConn) error {
+			used = append(used, conn)
+			return nil
+		}))
+	}
+
+	assert.Equal(t, 1, d.dialed())
+	assert.Same(t, used[0], used[2])
+
+	p.Close()
+	assert.True(t, d.conns[0].closed.Load())
+}
+
+func TestConnPool_RedialsDroppedConnection(t *testing.T) {
+	d := &fakeDialer{}
+	p := &connPool{dial: d.dial}
+
+	first, err := p.acquire(context.Background())
+	require.NoError(t, err)
+	d.conns[0].dropped.Store(true)
+
+	second, err := p.acquire(context.Background())
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.Equal(t, 2, d.dialed())
+	assert.True(t, d.conns[0].closed.Load(), "the dropped connection is closed")
+}
+
+func TestConnPool_RetriesOnDroppedConnection(t *testing.T) {
+	d := &fakeDialer{}
+	p := &connPool{dial: d.dial}
+
+	calls := 0
+	err := p.run(context.Background(), func(conn busConn) error {
+		calls++
+		if calls == 1 {
+			conn.(*fakeConn).dropped.Store(true)
+			return errors.New("connection reset")
+		}
+		assert.Same(t, d.conns[1], conn)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, d.dialed())
+}
+
+func TestConnPool_NoRetry(t *testing.T) {
+	d := &fakeDialer{}
+	p := &connPool{dial: d.dial}
+
+	// A failure on a live connection is the call's own error
+	calls := 0
+	failed := errors.New("unit not found")
+	err := p.run(context.Background(), func(conn busConn) error {
+		calls++
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, 1, calls)
+
+	// A cancelled call is not retried even if the connection dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = p.run(ctx, func(conn busConn) error {
+		calls++
+		conn.(*fakeConn).dropped.Store(true)
+		cancel()
+		return context.Canceled
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, d.dialed())
+}
+
+func TestConnPool_DialError(t *testing.T) {
+	d := &fakeDialer{err: errors.New("no such file or directory")}
+	p := &connPool{dial: d.dial}
+
+	err := p.run(context.Background(), func(conn busConn) error {
+		t.Fatal("must not run without a connection")
+		return nil
+	})
+	assert.True(t, errors.Is(err, apierror.ErrUnavailable))
+
+	// The next call dials again once systemd is reachable
+	d.err = nil
+	require.NoError(t, p.run(context.Background(), func(conn busConn) error { return nil }))
+	assert.Equal(t, 1, d.dialed())
+}
+
+func TestConnPool_Concurrent(t *testing.T) {
+	d := &fakeDialer{}
+	p := &connPool{dial: d.dial}
+
+	var wg sync.WaitGroup
+	var calls atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := p.run(context.Background(), func(conn busConn) error {
+				calls.Add(1)
+				if i%10 == 0 {
+					// Drop the connection under the other callers
+					conn.(*fakeConn).dropped.Store(true)
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(50), calls.Load())
+	assert.LessOrEqual(t, d.dialed(), 6, "at most one dial per drop plus the first")
+
+	// Every replaced connection was closed; only the current one is open
+	for _, conn := range d.conns[:d.dialed()-1] {
+		assert.True(t, conn.closed.Load())
+	}
+}
@@ -0,0 +1,167 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// journalctl is the command Follow runs
+var journalctl = "journalctl"
+
+// How long Follow waits before restarting journalctl, doubling up to the
+// maximum while it keeps exiting. A follower that ran for the maximum
+// starts again from the minimum.
+var (
+	followRestartMin = time.Second
+	followRestartMax = time.Minute
+)
+
+// followWaitDelay bounds how long reaping a killed journalctl may wait
+// for its output to close
+const followWaitDelay = 5 * time.Second
+
+// maxJournalLine is the longest JSON entry a follower reads
+const maxJournalLine = 1 << 20
+
+// activeFollowers counts running journalctl -f processes and native
+// followers
+var activeFollowers atomic.Int64
+
+// ActiveFollowers returns how many journalctl processes or native readers
+// are following the journal
+func ActiveFollowers() int {
+	return int(activeFollowers.Load())
+}
+
+// Follow streams journal entries in real time until ctx is done, starting
+// with the last few. It returns once following has started.
+//
+// Without the native reader it runs journalctl in its own process group,
+// which is killed and reaped when ctx is done. If journalctl exits before
+// that, it is restarted after the last entry sent, with backoff.
+func (r *JournalReader) Follow(ctx context.Context, unit string, entryChan chan<- JournalEntry) error {
+	f := &follower{reader: r, unit: unit, out: entryChan}
+	if r.native {
+		err := followNative(ctx, unit, entryChan)
+		if err == nil {
+			return nil
+		}
+		logNativeFallback("follow "+f.name(), err)
+	}
+
+	cmd, stdout, err := f.start(ctx)
+	if err != nil {
+		return err
+	}
+	go f.run(ctx, cmd, stdout)
+	return nil
+}
+
+// follower runs journalctl -f for one Follow call
+type follower struct {
+	reader    *JournalReader
+	unit      string
+	out       chan<- JournalEntry
+	cursor    string // Of the last entry sent
+	restarted bool
+}
+
+func (f *follower) args() []string {
+	args := []string{"--output=json", "--no-pager", "-f"}
+	if f.unit != "" {
+		args = append(args, "-u", f.unit)
+	}
+	switch {
+	case f.cursor != "":
+		args = append(args, "--after-cursor", f.cursor)
+	case f.restarted:
+		args = append(args, "-n", "0") // Do not repeat the initial lines
+	}
+	return args
+}
+
+func (f *follower) start(ctx context.Context) (*exec.Cmd, io.Reader, error) {
+	cmd := exec.CommandContext(ctx, journalctl, f.args()...)
+	cmd.SysProcAttr = followerAttrs()
+	cmd.Cancel = func() error { return killGroup(cmd.Process) }
+	cmd.WaitDelay = followWaitDelay
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start journalctl: %w", err)
+	}
+	activeFollowers.Add(1)
+	return cmd, stdout, nil
+}
+
+// run relays entries from journalctl, restarting it whenever it exits,
+// until ctx is done
+func (f *follower) run(ctx context.Context, cmd *exec.Cmd, stdout io.Reader) {
+	backoff := followRestartMin
+	for {
+		if cmd != nil {
+			started := time.Now()
+			f.relay(ctx, stdout)
+			killGroup(cmd.Process) // It may still run if its output could not be read
+			err := cmd.Wait()
+			activeFollowers.Add(-1)
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) >= followRestartMax {
+				backoff = followRestartMin
+			}
+			log.Printf("journalctl following %s exited (%v), restarting in %s", f.name(), err, backoff)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, followRestartMax)
+
+		f.restarted = true
+		var err error
+		if cmd, stdout, err = f.start(ctx); err != nil {
+			log.Printf("Failed to restart journalctl following %s: %v", f.name(), err)
+			cmd = nil
+		}
+	}
+}
+
+// relay sends entries until journalctl's output ends or ctx is done
+func (f *follower) relay(ctx context.Context, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalLine)
+	for scanner.Scan() {
+		entry, err := f.reader.parseJSONLine(scanner.Bytes(), false)
+		if err != nil {
+			continue
+		}
+		select {
+		case f.out <- *entry:
+			if entry.Cursor != "" {
+				f.cursor = entry.Cursor
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (f *follower) name() string {
+	if f.unit == "" {
+		return "the journal"
+	}
+	return f.unit
+}
@@ -0,0 +1,19 @@
+//go:build linux
+
+package systemd
+
+import (
+	"os"
+	"syscall"
+)
+
+// followerAttrs puts journalctl in its own process group, so killing the
+// group takes anything it started along with it
+func followerAttrs() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killGroup kills the process group led by p
+func killGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
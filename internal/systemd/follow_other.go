@@ -0,0 +1,16 @@
+//go:build !linux
+
+package systemd
+
+import (
+	"os"
+	"syscall"
+)
+
+func followerAttrs() *syscall.SysProcAttr {
+	return nil
+}
+
+func killGroup(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,57 @@
+package systemd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJournalctl replaces journalctl with a script for the test
+func fakeJournalctl(t *testing.T, script string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	path := filepath.Join(t.TempDir(), "journalctl")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+
+	oldCmd, oldMin := journalctl, followRestartMin
+	journalctl, followRestartMin = path, 10*time.Millisecond
+	t.Cleanup(func() { journalctl, followRestartMin = oldCmd, oldMin })
+}
+
+func TestFollowRestarts(t *testing.T) {
+	// The first run exits after one entry; the restart stays up with a child
+	fakeJournalctl(t, `echo "{\"MESSAGE\":\"$*\",\"__CURSOR\":\"c$$\"}"
+case "$*" in *after-cursor*) sleep 60 ;; esac
+`)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	entries := make(chan JournalEntry, 10)
+	require.NoError(t, NewJournalReader().Follow(ctx, "nginx.service", entries))
+
+	first := <-entries
+	assert.Equal(t, "--output=json --no-pager -f -u nginx.service", first.Message)
+	second := <-entries
+	assert.Equal(t, "--output=json --no-pager -f -u nginx.service --after-cursor "+first.Cursor, second.Message)
+	assert.Equal(t, 1, ActiveFollowers())
+
+	cancel()
+	assert.Eventually(t, func() bool { return ActiveFollowers() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFollowStartError(t *testing.T) {
+	oldCmd := journalctl
+	journalctl = filepath.Join(t.TempDir(), "missing")
+	t.Cleanup(func() { journalctl = oldCmd })
+
+	err := NewJournalReader().Follow(t.Context(), "", make(chan JournalEntry))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to start journalctl")
+	assert.Equal(t, 0, ActiveFollowers())
+}
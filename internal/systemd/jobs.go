@@ -0,0 +1,112 @@
+package systemd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// jobRetention is how long a finished job's result can be polled
+const jobRetention = time.Hour
+
+// JobRunning is the state of a job systemd has not finished
+const JobRunning = "running"
+
+// Job is a systemd job that was still queued or running when the action
+// that started it stopped waiting. State is JobRunning until systemd reports
+// a result: done, canceled, timeout, failed, dependency or skipped.
+type Job struct {
+	ID         int        `json:"id"`
+	Service    string     `json:"service"`
+	Action     string     `json:"action"`
+	State      string     `json:"state"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// jobTracker keeps jobs that outlived their action's wait
+type jobTracker struct {
+	mu   sync.Mutex
+	jobs map[int]*Job
+}
+
+// track records a job and waits in the background for its result
+func (t *jobTracker) track(job *Job, result <-chan string) {
+	t.mu.Lock()
+	if t.jobs == nil {
+		t.jobs = make(map[int]*Job)
+	}
+	t.prune()
+	t.jobs[job.ID] = job
+	t.mu.Unlock()
+
+	go func() {
+		state := <-result
+		now := time.Now()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		job.State = state
+		job.FinishedAt = &now
+	}()
+}
+
+// get returns a copy of a tracked job
+func (t *jobTracker) get(id int) (Job, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// prune drops results older than jobRetention. The caller holds mu.
+func (t *jobTracker) prune() {
+	for id, job := range t.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > jobRetention {
+			delete(t.jobs, id)
+		}
+	}
+}
+
+// GetJob returns a job started by an action on the named service that was
+// still pending when the action returned
+func (m *Manager) GetJob(ctx context.Context, name string, id int) (*Job, error) {
+	if !m.IsAllowed(name) {
+		return nil, apierror.NotAllowed("service '%s' is not in allowed list", name)
+	}
+
+	job, ok := m.jobs.get(id)
+	if !ok || job.Service != name {
+		return nil, apierror.NotFound("job %d not found for service '%s'", id, name)
+	}
+	if job.State != JobRunning {
+		return &job, nil
+	}
+
+	// The result arrives on the connection that queued the job. If that
+	// connection was lost, ask systemd whether the job is still queued.
+	var queued bool
+	err := m.conns.do(ctx, func(conn *dbus.Conn) error {
+		jobs, err := conn.ListJobsContext(ctx)
+		for _, j := range jobs {
+			if int(j.Id) == id {
+				queued = true
+			}
+		}
+		return err
+	})
+	if err == nil && !queued {
+		if job, ok = m.jobs.get(id); ok && job.State == JobRunning {
+			job.State = "unknown"
+		}
+	}
+	return &job, nil
+}
@@ -0,0 +1,50 @@
+package systemd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestJobTracker(t *testing.T) {
+	var tracker jobTracker
+	result := make(chan string, 1)
+	tracker.track(&Job{ID: 42, Service: "nginx", Action: "restart", State: JobRunning, StartedAt: time.Now()}, result)
+
+	job, ok := tracker.get(42)
+	require.True(t, ok)
+	assert.Equal(t, JobRunning, job.State)
+	assert.Nil(t, job.FinishedAt)
+
+	result <- "done"
+	require.Eventually(t, func() bool {
+		job, _ := tracker.get(42)
+		return job.State == "done"
+	}, time.Second, 5*time.Millisecond)
+
+	job, _ = tracker.get(42)
+	assert.NotNil(t, job.FinishedAt)
+
+	_, ok = tracker.get(7)
+	assert.False(t, ok)
+}
+
+func TestGetJob_Scope(t *testing.T) {
+	m := NewManager([]string{"nginx", "redis"})
+	m.jobs.track(&Job{ID: 42, Service: "nginx", Action: "start", State: "done"}, make(chan string))
+
+	job, err := m.GetJob(context.Background(), "nginx", 42)
+	require.NoError(t, err)
+	assert.Equal(t, "done", job.State)
+
+	// Jobs are only visible through the service they belong to
+	_, err = m.GetJob(context.Background(), "redis", 42)
+	assert.ErrorIs(t, err, apierror.ErrNotFound)
+	_, err = m.GetJob(context.Background(), "sshd", 42)
+	assert.ErrorIs(t, err, apierror.ErrNotAllowed)
+}
@@ -2,24 +2,89 @@ package systemd
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os/exec"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// Journal backends
+const (
+	BackendSDJournal  = "sdjournal"
+	BackendJournalctl = "journalctl"
 )
 
-// JournalReader reads systemd journal logs
-type JournalReader struct{}
+// errNativeUnsupported means the native reader cannot serve a query, so
+// journalctl should
+var errNativeUnsupported = errors.New("not supported by the native journal reader")
+
+// JournalReader reads systemd journal logs. Built with the sdjournal tag,
+// it reads the journal through libsystemd when it can and falls back to
+// journalctl otherwise.
+type JournalReader struct {
+	native bool
+}
 
 // NewJournalReader creates a new journal reader
 func NewJournalReader() *JournalReader {
-	return &JournalReader{}
+	return &JournalReader{native: nativeAvailable()}
 }
 
-// Query reads journal entries based on the query parameters
+// Backend returns the backend queries use when they can
+func (r *JournalReader) Backend() string {
+	if r.native {
+		return BackendSDJournal
+	}
+	return BackendJournalctl
+}
+
+// Query reads journal entries based on the query parameters. Without a
+// cursor it returns the last Lines entries; After and Before page forwards
+// and backwards from an entry's cursor.
 func (r *JournalReader) Query(ctx context.Context, query JournalQuery) (*LogStream, error) {
+	if query.After != "" && query.Before != "" {
+		return nil, apierror.Invalid("set at most one of after and before")
+	}
+	if query.Lines <= 0 {
+		query.Lines = 100
+	}
+
+	if r.native {
+		entries, err := queryNative(ctx, query)
+		switch {
+		case err == nil:
+			return newLogStream(query, entries, BackendSDJournal), nil
+		case errors.Is(err, apierror.ErrInvalid):
+			return nil, err
+		case !errors.Is(err, errNativeUnsupported):
+			logNativeFallback("query", err)
+		}
+	}
+
+	entries, err := r.queryJournalctl(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return newLogStream(query, entries, BackendJournalctl), nil
+}
+
+func newLogStream(query JournalQuery, entries []JournalEntry, backend string) *LogStream {
+	if query.Structured {
+		entries = structure(entries)
+	}
+	return &LogStream{Entries: entries, Unit: query.Unit, Backend: backend}
+}
+
+func (r *JournalReader) queryJournalctl(ctx context.Context, query JournalQuery) ([]JournalEntry, error) {
 	args := []string{"--output=json", "--no-pager"}
 
 	if query.Unit != "" {
@@ -30,12 +95,6 @@ func (r *JournalReader) Query(ctx context.Context, query JournalQuery) (*LogStre
 		args = append(args, "-p", strconv.Itoa(query.Priority))
 	}
 
-	lines := query.Lines
-	if lines <= 0 {
-		lines = 100
-	}
-	args = append(args, "-n", strconv.Itoa(lines))
-
 	if query.Since != "" {
 		args = append(args, "--since", query.Since)
 	}
@@ -44,69 +103,64 @@ func (r *JournalReader) Query(ctx context.Context, query JournalQuery) (*LogStre
 		args = append(args, "--until", query.Until)
 	}
 
-	cmd := exec.CommandContext(ctx, "journalctl", args...)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read journal: %w", err)
-	}
-
-	entries, err := r.parseJSONOutput(output)
-	if err != nil {
-		return nil, err
+	// journalctl has no limit for a page after a cursor, so reading stops
+	// after Lines entries
+	switch {
+	case query.After != "":
+		args = append(args, "--after-cursor="+query.After)
+	case query.Before != "":
+		args = append(args, "--after-cursor="+query.Before, "--reverse")
+	default:
+		args = append(args, "-n", strconv.Itoa(query.Lines))
 	}
 
-	return &LogStream{
-		Entries: entries,
-		Unit:    query.Unit,
-	}, nil
-}
-
-// Follow streams journal entries in real-time
-func (r *JournalReader) Follow(ctx context.Context, unit string, entryChan chan<- JournalEntry) error {
-	args := []string{"--output=json", "--no-pager", "-f"}
-
-	if unit != "" {
-		args = append(args, "-u", unit)
-	}
-
-	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, journalctl, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
-
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start journalctl: %w", err)
+		return nil, fmt.Errorf("failed to read journal: %w", err)
 	}
 
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			entry, err := r.parseJSONLine(scanner.Bytes())
-			if err != nil {
-				continue
-			}
-			select {
-			case entryChan <- *entry:
-			case <-ctx.Done():
-				return
-			}
+	entries := []JournalEntry{}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalLine)
+	for len(entries) < query.Lines && scanner.Scan() {
+		entry, err := r.parseJSONLine(scanner.Bytes(), query.AllFields)
+		if err != nil {
+			continue
 		}
-	}()
+		entries = append(entries, *entry)
+	}
+	full := len(entries) >= query.Lines
+	if full {
+		cancel() // Stop journalctl; the page is complete
+	}
 
-	go func() {
-		<-ctx.Done()
-		cmd.Process.Kill()
-	}()
+	if err := cmd.Wait(); err != nil && !full {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to read journal: %s", msg)
+		}
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
 
-	return nil
+	if query.Before != "" {
+		slices.Reverse(entries)
+	}
+	return entries, nil
 }
 
 // GetRecentLogs returns recent log entries for a unit
 func (r *JournalReader) GetRecentLogs(ctx context.Context, unit string, lines int) ([]JournalEntry, error) {
 	stream, err := r.Query(ctx, JournalQuery{
-		Unit:  unit,
-		Lines: lines,
+		Unit:     unit,
+		Priority: -1,
+		Lines:    lines,
 	})
 	if err != nil {
 		return nil, err
@@ -114,76 +168,112 @@ func (r *JournalReader) GetRecentLogs(ctx context.Context, unit string, lines in
 	return stream.Entries, nil
 }
 
-func (r *JournalReader) parseJSONOutput(output []byte) ([]JournalEntry, error) {
-	var entries []JournalEntry
-
-	// Each line is a separate JSON object
-	scanner := bufio.NewScanner(
-		&byteReader{data: output},
-	)
+// parseJSONLine parses an entry from journalctl's JSON output
+func (r *JournalReader) parseJSONLine(line []byte, allFields bool) (*JournalEntry, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, err
+	}
 
-	for scanner.Scan() {
-		entry, err := r.parseJSONLine(scanner.Bytes())
-		if err != nil {
-			continue
+	fields := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if v, ok := jsonFieldValue(value); ok {
+			fields[name] = v
 		}
-		entries = append(entries, *entry)
 	}
-
-	return entries, nil
+	entry := newJournalEntry(fields, allFields)
+	return &entry, nil
 }
 
-func (r *JournalReader) parseJSONLine(line []byte) (*JournalEntry, error) {
-	var raw map[string]interface{}
-	if err := json.Unmarshal(line, &raw); err != nil {
-		return nil, err
+// jsonFieldValue decodes a field from journalctl's JSON output. Binary
+// values are arrays of bytes; a field set more than once is an array of
+// its values, of which the first is used.
+func jsonFieldValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		if len(v) == 0 {
+			return "", false
+		}
+		if _, isByte := v[0].(float64); !isByte {
+			return jsonFieldValue(v[0])
+		}
+		b := make([]byte, 0, len(v))
+		for _, c := range v {
+			n, ok := c.(float64)
+			if !ok {
+				return "", false
+			}
+			b = append(b, byte(n))
+		}
+		return string(b), true
 	}
+	return "", false
+}
 
-	entry := &JournalEntry{}
+// newJournalEntry builds an entry from its journal fields
+func newJournalEntry(fields map[string]string, allFields bool) JournalEntry {
+	entry := JournalEntry{
+		Unit:     fields["_SYSTEMD_UNIT"],
+		Message:  fields["MESSAGE"],
+		PID:      fields["_PID"],
+		Hostname: fields["_HOSTNAME"],
+		Cursor:   fields["__CURSOR"],
+	}
 
 	// Parse timestamp (microseconds since epoch)
-	if ts, ok := raw["__REALTIME_TIMESTAMP"].(string); ok {
-		if usec, err := strconv.ParseInt(ts, 10, 64); err == nil {
-			entry.Timestamp = time.UnixMicro(usec)
-		}
+	if usec, err := strconv.ParseInt(fields["__REALTIME_TIMESTAMP"], 10, 64); err == nil {
+		entry.Timestamp = time.UnixMicro(usec)
 	}
 
-	if unit, ok := raw["_SYSTEMD_UNIT"].(string); ok {
-		entry.Unit = unit
+	if p, err := strconv.Atoi(fields["PRIORITY"]); err == nil {
+		entry.Priority = p
 	}
 
-	if msg, ok := raw["MESSAGE"].(string); ok {
-		entry.Message = msg
+	if allFields {
+		entry.Fields = fields
 	}
+	return entry
+}
 
-	if prio, ok := raw["PRIORITY"].(string); ok {
-		if p, err := strconv.Atoi(prio); err == nil {
-			entry.Priority = p
+// parseJournalTime parses the --since and --until forms the native reader
+// understands: RFC 3339, "2006-01-02 15:04:05" and shorter, today,
+// yesterday, now, and durations relative to now such as -1h. The zero time
+// means no bound.
+func parseJournalTime(s string, now time.Time) (time.Time, error) {
+	switch s {
+	case "":
+		return time.Time{}, nil
+	case "now":
+		return now, nil
+	case "today", "yesterday":
+		y, m, d := now.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+		if s == "yesterday" {
+			day = day.AddDate(0, 0, -1)
 		}
+		return day, nil
 	}
 
-	if pid, ok := raw["_PID"].(string); ok {
-		entry.PID = pid
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
 	}
-
-	if hostname, ok := raw["_HOSTNAME"].(string); ok {
-		entry.Hostname = hostname
+	for _, layout := range []string{time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
 	}
-
-	return entry, nil
-}
-
-// byteReader implements io.Reader for a byte slice
-type byteReader struct {
-	data []byte
-	pos  int
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if d, err := time.ParseDuration(s); err == nil {
+			return now.Add(d), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("time %q: %w", s, errNativeUnsupported)
 }
 
-func (r *byteReader) Read(p []byte) (n int, err error) {
-	if r.pos >= len(r.data) {
-		return 0, fmt.Errorf("EOF")
-	}
-	n = copy(p, r.data[r.pos:])
-	r.pos += n
-	return n, nil
+// logNativeFallback notes that the native reader failed and journalctl is
+// used instead
+func logNativeFallback(what string, err error) {
+	log.Printf("Native journal reader failed to %s, using journalctl: %v", what, err)
 }
@@ -0,0 +1,27 @@
+//go:build !(linux && cgo && sdjournal)
+
+package systemd
+
+import (
+	"context"
+	"time"
+)
+
+// Built without the sdjournal tag, the journal is always read through
+// journalctl
+
+func nativeAvailable() bool {
+	return false
+}
+
+func queryNative(context.Context, JournalQuery) ([]JournalEntry, error) {
+	return nil, errNativeUnsupported
+}
+
+func followNative(context.Context, string, chan<- JournalEntry) error {
+	return errNativeUnsupported
+}
+
+func levelStatsNative(context.Context, string, time.Time) (*LogLevelStats, error) {
+	return nil, errNativeUnsupported
+}
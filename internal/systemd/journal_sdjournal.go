@@ -0,0 +1,271 @@
+//go:build linux && cgo && sdjournal
+
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// nativeWait is how long a native follower waits for new entries before
+// checking whether it should stop
+const nativeWait = 250 * time.Millisecond
+
+// followBacklog is how many entries a follow starts with, as journalctl -f
+const followBacklog = 10
+
+// nativeAvailable reports whether libsystemd can open the journal
+func nativeAvailable() bool {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return false
+	}
+	j.Close()
+	return true
+}
+
+// openJournal opens the journal, matching entries for unit and at least
+// as important as priority
+func openJournal(unit string, priority int) (*sdjournal.Journal, error) {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	if err := addMatches(j, unit, priority); err != nil {
+		j.Close()
+		return nil, fmt.Errorf("failed to filter journal: %w", err)
+	}
+	return j, nil
+}
+
+// addMatches filters as journalctl -u and -p do: the unit's own messages
+// or systemd's about it, and priorities up to priority
+func addMatches(j *sdjournal.Journal, unit string, priority int) error {
+	var matches []string
+	if unit != "" {
+		unit = unitName(unit)
+		matches = append(matches, "_SYSTEMD_UNIT="+unit, "|", "UNIT="+unit, "_PID=1", "&")
+	}
+	if priority >= 0 && priority <= 7 {
+		for p := 0; p <= priority; p++ {
+			matches = append(matches, "PRIORITY="+strconv.Itoa(p))
+		}
+	}
+
+	for _, m := range matches {
+		var err error
+		switch m {
+		case "|":
+			err = j.AddDisjunction()
+		case "&":
+			err = j.AddConjunction()
+		default:
+			err = j.AddMatch(m)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unitName adds .service to a unit name without a type, as journalctl -u
+// does
+func unitName(unit string) string {
+	if strings.Contains(unit, ".") {
+		return unit
+	}
+	return unit + ".service"
+}
+
+// queryNative serves a query through libsystemd. Times it cannot parse
+// are left to journalctl.
+func queryNative(ctx context.Context, query JournalQuery) ([]JournalEntry, error) {
+	now := time.Now()
+	since, err := parseJournalTime(query.Since, now)
+	if err != nil {
+		return nil, err
+	}
+	until, err := parseJournalTime(query.Until, now)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := openJournal(query.Unit, query.Priority)
+	if err != nil {
+		return nil, err
+	}
+	defer j.Close()
+
+	cursor := query.After + query.Before
+	forward := query.After != ""
+	switch {
+	case cursor != "":
+		if err := j.SeekCursor(cursor); err != nil {
+			return nil, apierror.Invalid("invalid cursor: %v", err)
+		}
+	case !until.IsZero():
+		err = j.SeekRealtimeUsec(uint64(until.UnixMicro()) + 1)
+	default:
+		err = j.SeekTail()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to seek journal: %w", err)
+	}
+
+	entries := []JournalEntry{}
+	for len(entries) < query.Lines && ctx.Err() == nil {
+		var n uint64
+		if forward {
+			n, err = j.Next()
+		} else {
+			n, err = j.Previous()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+		if cursor != "" && j.TestCursor(cursor) == nil {
+			continue // The entry the page starts from
+		}
+
+		entry, err := readEntry(j, query.AllFields)
+		if err != nil {
+			return nil, err
+		}
+		if forward && !until.IsZero() && entry.Timestamp.After(until) {
+			break
+		}
+		if !forward && !since.IsZero() && entry.Timestamp.Before(since) {
+			break
+		}
+		if entry.Timestamp.Before(since) || (!until.IsZero() && entry.Timestamp.After(until)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !forward {
+		slices.Reverse(entries)
+	}
+	return entries, nil
+}
+
+// readEntry reads the entry at the journal's position
+func readEntry(j *sdjournal.Journal, allFields bool) (JournalEntry, error) {
+	e, err := j.GetEntry()
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to read journal entry: %w", err)
+	}
+	e.Fields["__CURSOR"] = e.Cursor
+	e.Fields["__REALTIME_TIMESTAMP"] = strconv.FormatUint(e.RealtimeTimestamp, 10)
+	e.Fields["__MONOTONIC_TIMESTAMP"] = strconv.FormatUint(e.MonotonicTimestamp, 10)
+	return newJournalEntry(e.Fields, allFields), nil
+}
+
+// followNative follows the journal through libsystemd until ctx is done
+func followNative(ctx context.Context, unit string, out chan<- JournalEntry) error {
+	j, err := openJournal(unit, -1)
+	if err != nil {
+		return err
+	}
+	if err := j.SeekTail(); err != nil {
+		j.Close()
+		return fmt.Errorf("failed to seek journal: %w", err)
+	}
+
+	activeFollowers.Add(1)
+	go func() {
+		defer activeFollowers.Add(-1)
+		defer j.Close()
+
+		// The backlog starts at the current entry, after which Next moves on
+		back, _ := j.PreviousSkip(followBacklog)
+		current := back > 0
+		for ctx.Err() == nil {
+			if !current {
+				n, err := j.Next()
+				if err != nil {
+					return
+				}
+				if n == 0 {
+					j.Wait(nativeWait)
+					continue
+				}
+			}
+			current = false
+
+			entry, err := readEntry(j, false)
+			if err != nil {
+				continue
+			}
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// levelStatsNative counts a unit's warnings and errors in the last day,
+// newest first, reading only their priority and time
+func levelStatsNative(ctx context.Context, unit string, now time.Time) (*LogLevelStats, error) {
+	j, err := openJournal(unit, 4)
+	if err != nil {
+		return nil, err
+	}
+	defer j.Close()
+	if err := j.SeekTail(); err != nil {
+		return nil, fmt.Errorf("failed to seek journal: %w", err)
+	}
+
+	stats := &LogLevelStats{}
+	since := now.Add(-24 * time.Hour)
+	for ctx.Err() == nil {
+		n, err := j.Previous()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+		usec, err := j.GetRealtimeUsec()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal entry: %w", err)
+		}
+		at := time.UnixMicro(int64(usec))
+		if at.Before(since) {
+			break
+		}
+		value, err := j.GetDataValue("PRIORITY")
+		if err != nil {
+			continue
+		}
+		priority, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		if !stats.add(priority, at, now) {
+			break
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,116 @@
+package systemd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestParseJSONLine(t *testing.T) {
+	r := &JournalReader{}
+	line := `{"__CURSOR":"s=1;i=2","__REALTIME_TIMESTAMP":"1700000000000000","_SYSTEMD_UNIT":"nginx.service","MESSAGE":[104,105],"PRIORITY":"3","_PID":"42","SYSLOG_IDENTIFIER":["nginx","nginx2"]}`
+
+	entry, err := r.parseJSONLine([]byte(line), false)
+	require.NoError(t, err)
+	assert.Equal(t, "hi", entry.Message, "binary values are byte arrays")
+	assert.Equal(t, "nginx.service", entry.Unit)
+	assert.Equal(t, 3, entry.Priority)
+	assert.Equal(t, "s=1;i=2", entry.Cursor)
+	assert.Equal(t, time.UnixMicro(1700000000000000), entry.Timestamp)
+	assert.Nil(t, entry.Fields)
+
+	entry, err = r.parseJSONLine([]byte(line), true)
+	require.NoError(t, err)
+	assert.Equal(t, "nginx", entry.Fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "42", entry.Fields["_PID"])
+}
+
+func TestParseJournalTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"":                     {},
+		"now":                  now,
+		"today":                time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+		"yesterday":            time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC),
+		"-1h":                  now.Add(-time.Hour),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"2024-05-01 12:00":     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		"2024-05-01T12:00:00Z": time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseJournalTime(in, now)
+		require.NoError(t, err, in)
+		assert.True(t, want.Equal(got), "%s: %s", in, got)
+	}
+
+	_, err := parseJournalTime("5 min ago", now)
+	assert.ErrorIs(t, err, errNativeUnsupported, "left to journalctl")
+}
+
+func TestQueryCursors(t *testing.T) {
+	// Prints its arguments and a numbered entry per line, more than asked for
+	fakeJournalctl(t, `for i in 1 2 3 4 5; do echo "{\"MESSAGE\":\"$*\",\"__CURSOR\":\"c$i\"}"; done`)
+	r := &JournalReader{}
+
+	stream, err := r.Query(t.Context(), JournalQuery{Unit: "nginx", Priority: -1, Lines: 3, After: "c0"})
+	require.NoError(t, err)
+	assert.Equal(t, BackendJournalctl, stream.Backend)
+	require.Len(t, stream.Entries, 3, "reading stops at a full page")
+	assert.Equal(t, "--output=json --no-pager -u nginx --after-cursor=c0", stream.Entries[0].Message)
+	assert.Equal(t, "c1", stream.Entries[0].Cursor)
+
+	stream, err = r.Query(t.Context(), JournalQuery{Priority: -1, Lines: 2, Before: "c9"})
+	require.NoError(t, err)
+	assert.Equal(t, "--output=json --no-pager --after-cursor=c9 --reverse", stream.Entries[0].Message)
+	assert.Equal(t, []string{"c2", "c1"}, []string{stream.Entries[0].Cursor, stream.Entries[1].Cursor}, "oldest first")
+
+	stream, err = r.Query(t.Context(), JournalQuery{Priority: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "--output=json --no-pager -p 3 -n 100", stream.Entries[0].Message)
+	assert.Len(t, stream.Entries, 5)
+
+	_, err = r.Query(t.Context(), JournalQuery{After: "c1", Before: "c2"})
+	assert.ErrorIs(t, err, apierror.ErrInvalid)
+}
+
+func TestQueryError(t *testing.T) {
+	fakeJournalctl(t, `echo "Failed to seek to cursor: Invalid argument" >&2; exit 1`)
+
+	_, err := (&JournalReader{}).Query(t.Context(), JournalQuery{After: "bogus"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to seek to cursor")
+}
+
+func TestLevelStats(t *testing.T) {
+	// Newest first: two errors and a warning now, a warning two hours ago,
+	// and an error from two days ago that journalctl would not return
+	fakeJournalctl(t, `now=$(date +%s)
+echo "{\"PRIORITY\":\"3\",\"__REALTIME_TIMESTAMP\":\"${now}000000\"}"
+echo "{\"PRIORITY\":\"4\",\"__REALTIME_TIMESTAMP\":\"${now}000000\"}"
+echo "{\"PRIORITY\":\"2\",\"__REALTIME_TIMESTAMP\":\"$((now - 60))000000\"}"
+echo "{\"PRIORITY\":\"4\",\"__REALTIME_TIMESTAMP\":\"$((now - 7200))000000\"}"
+echo "{\"PRIORITY\":\"3\",\"__REALTIME_TIMESTAMP\":\"$((now - 172800))000000\"}"
+echo "not json"
+`)
+
+	stats, err := (&JournalReader{}).LevelStats(t.Context(), "nginx")
+	require.NoError(t, err)
+	assert.Equal(t, &LogLevelStats{
+		LastHour: LevelCounts{Errors: 2, Warnings: 1},
+		LastDay:  LevelCounts{Errors: 2, Warnings: 2},
+	}, stats)
+}
+
+func TestLevelStatsCap(t *testing.T) {
+	now := time.Now()
+	stats := &LogLevelStats{}
+	for i := 0; i < maxLevelEntries; i++ {
+		require.True(t, stats.add(3, now, now))
+	}
+	assert.False(t, stats.add(4, now, now))
+	assert.True(t, stats.Capped)
+	assert.Equal(t, maxLevelEntries, stats.LastHour.Errors)
+}
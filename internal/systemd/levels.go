@@ -0,0 +1,114 @@
+package systemd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// maxLevelEntries caps how many warnings and errors LevelStats counts, so
+// a unit flooding the journal costs a bounded scan
+const maxLevelEntries = 100000
+
+// LevelStats counts a unit's errors (priorities 0-3) and warnings (4) in
+// the last hour and day. Entries are read newest first, so when there are
+// more than can be counted, the last hour is still complete.
+func (r *JournalReader) LevelStats(ctx context.Context, unit string) (*LogLevelStats, error) {
+	now := time.Now()
+	if r.native {
+		stats, err := levelStatsNative(ctx, unit, now)
+		switch {
+		case err == nil:
+			return stats, nil
+		case errors.Is(err, apierror.ErrInvalid):
+			return nil, err
+		case !errors.Is(err, errNativeUnsupported):
+			logNativeFallback("count "+unit+" logs", err)
+		}
+	}
+	return r.levelStatsJournalctl(ctx, unit, now)
+}
+
+func (r *JournalReader) levelStatsJournalctl(ctx context.Context, unit string, now time.Time) (*LogLevelStats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, journalctl, "--output=json", "--output-fields=PRIORITY", "--no-pager",
+		"-u", unit, "-p", "warning", "--reverse", "--since", "@"+strconv.FormatInt(now.Add(-24*time.Hour).Unix(), 10))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	stats := &LogLevelStats{}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalLine)
+	for scanner.Scan() {
+		var fields struct {
+			Priority  string `json:"PRIORITY"`
+			Timestamp string `json:"__REALTIME_TIMESTAMP"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			continue
+		}
+		priority, err := strconv.Atoi(fields.Priority)
+		if err != nil {
+			continue
+		}
+		usec, err := strconv.ParseInt(fields.Timestamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		if !stats.add(priority, time.UnixMicro(usec), now) {
+			cancel() // Stop journalctl; the rest is not counted
+			break
+		}
+	}
+
+	if err := cmd.Wait(); err != nil && !stats.Capped {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to read journal: %s", msg)
+		}
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return stats, nil
+}
+
+// add counts an entry at priority logged at, reporting false once the
+// cap is reached
+func (s *LogLevelStats) add(priority int, at, now time.Time) bool {
+	if s.LastDay.Errors+s.LastDay.Warnings >= maxLevelEntries {
+		s.Capped = true
+		return false
+	}
+	if priority > 4 || now.Sub(at) > 24*time.Hour {
+		return true
+	}
+
+	s.LastDay.add(priority)
+	if now.Sub(at) <= time.Hour {
+		s.LastHour.add(priority)
+	}
+	return true
+}
+
+func (c *LevelCounts) add(priority int) {
+	if priority <= 3 {
+		c.Errors++
+	} else {
+		c.Warnings++
+	}
+}
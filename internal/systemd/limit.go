@@ -0,0 +1,100 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// LimitScope names the transient scope a limited process is moved into
+func LimitScope(pid uint32) string {
+	return fmt.Sprintf("hivedeck-limit-%d.scope", pid)
+}
+
+// LimitProcess moves a running process into a transient scope with the
+// given limits, as systemd-run --scope does for new commands. If the
+// process already has one, its limits are changed instead. The scope ends
+// when the process exits.
+func (m *Manager) LimitProcess(ctx context.Context, pid uint32, limits Limits) (*ProcessLimit, error) {
+	if limits.CPUQuota < 0 || (limits.CPUQuota == 0 && limits.MemoryMax == 0) {
+		return nil, apierror.Invalid("set cpu_quota or memory_max")
+	}
+
+	var props []dbus.Property
+	if limits.CPUQuota > 0 {
+		props = append(props, dbus.Property{
+			Name:  "CPUQuotaPerSecUSec",
+			Value: godbus.MakeVariant(uint64(limits.CPUQuota) * 10000),
+		})
+	}
+	if limits.MemoryMax > 0 {
+		props = append(props, dbus.Property{Name: "MemoryMax", Value: godbus.MakeVariant(limits.MemoryMax)})
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultActionTimeout)
+		defer cancel()
+	}
+
+	unit := LimitScope(pid)
+	result := &ProcessLimit{PID: pid, Unit: unit, Limits: limits}
+	err := m.conns.do(ctx, func(conn *dbus.Conn) error {
+		state, err := conn.GetUnitPropertyContext(ctx, unit, "ActiveState")
+		if err == nil && state.Value.Value() == "active" {
+			if err := conn.SetUnitPropertiesContext(ctx, unit, true, props...); err != nil {
+				return fmt.Errorf("failed to update %s: %w", unit, err)
+			}
+			result.Updated = true
+			return nil
+		}
+
+		props = append(props,
+			dbus.PropDescription(fmt.Sprintf("Resource limits for PID %d", pid)),
+			dbus.PropPids(pid),
+		)
+		done := make(chan string, 1)
+		if _, err := conn.StartTransientUnitContext(ctx, unit, "fail", props, done); err != nil {
+			return fmt.Errorf("failed to create %s: %w", unit, err)
+		}
+		select {
+		case status := <-done:
+			if status != "done" {
+				return fmt.Errorf("failed to create %s: %s", unit, status)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("timed out creating %s", unit)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Message = fmt.Sprintf("process %d limited in %s", pid, unit)
+	return result, nil
+}
+
+// ParseSize parses a size such as 512M or 2G, in powers of 1024 as systemd
+// reads MemoryMax
+func ParseSize(s string) (uint64, error) {
+	digits := strings.TrimSpace(s)
+	shift := 0
+	if digits != "" {
+		if i := strings.IndexByte("KMGT", digits[len(digits)-1]); i >= 0 {
+			shift = 10 * (i + 1)
+			digits = digits[:len(digits)-1]
+		}
+	}
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil || n > (1<<(64-shift))-1 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
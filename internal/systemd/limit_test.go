@@ -0,0 +1,39 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]uint64{
+		"1048576": 1 << 20,
+		"512M":    512 << 20,
+		"2G":      2 << 30,
+		" 64K ":   64 << 10,
+	} {
+		got, err := ParseSize(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "M", "1.5G", "-1", "10P", "99999999999T"} {
+		_, err := ParseSize(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestLimitProcess_NoLimits(t *testing.T) {
+	m := NewManager(nil)
+	_, err := m.LimitProcess(context.Background(), 1234, Limits{})
+	assert.True(t, errors.Is(err, apierror.ErrInvalid))
+	_, err = m.LimitProcess(context.Background(), 1234, Limits{CPUQuota: -5})
+	assert.True(t, errors.Is(err, apierror.ErrInvalid))
+	assert.Equal(t, "hivedeck-limit-1234.scope", LimitScope(1234))
+}
@@ -2,17 +2,29 @@ package systemd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/coreos/go-systemd/v22/dbus"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
 )
 
+// DefaultActionTimeout bounds how long start/stop/restart wait for systemd
+// when the caller's context has no deadline
+const DefaultActionTimeout = 30 * time.Second
+
 // Manager handles systemd service operations
 type Manager struct {
 	allowedServices map[string]bool
 	allowAll        bool
+	conns           connPool
+	jobs            jobTracker
+
+	// helper runs actions through the privilege helper instead of D-Bus
+	helper func(ctx context.Context, args ...string) ([]byte, error)
 }
 
 // NewManager creates a new systemd manager
@@ -48,14 +60,28 @@ func (m *Manager) IsAllowed(name string) bool {
 	return m.allowedServices[name]
 }
 
+// SetHelper makes service actions run through the privilege helper, which is
+// called with "service", the action and the unit name, or "daemon-reload"
+func (m *Manager) SetHelper(run func(ctx context.Context, args ...string) ([]byte, error)) {
+	m.helper = run
+}
+
+// Close closes the D-Bus connection shared by the manager's calls
+func (m *Manager) Close() {
+	m.conns.Close()
+}
+
 // List returns all systemd services
 func (m *Manager) List(ctx context.Context) (*ServiceList, error) {
-	conn, err := dbus.NewWithContext(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to systemd: %w", err)
-	}
-	defer conn.Close()
+	var list *ServiceList
+	err := m.conns.do(ctx, func(conn *dbus.Conn) (err error) {
+		list, err = m.list(ctx, conn)
+		return err
+	})
+	return list, err
+}
 
+func (m *Manager) list(ctx context.Context, conn *dbus.Conn) (*ServiceList, error) {
 	units, err := conn.ListUnitsContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list units: %w", err)
@@ -108,23 +134,23 @@ func (m *Manager) List(ctx context.Context) (*ServiceList, error) {
 // Get returns information about a specific service
 func (m *Manager) Get(ctx context.Context, name string) (*ServiceInfo, error) {
 	if !m.IsAllowed(name) {
-		return nil, fmt.Errorf("service '%s' is not in allowed list", name)
-	}
-
-	conn, err := dbus.NewWithContext(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to systemd: %w", err)
+		return nil, apierror.NotAllowed("service '%s' is not in allowed list", name)
 	}
-	defer conn.Close()
 
 	unitName := name
 	if !strings.HasSuffix(unitName, ".service") {
 		unitName = name + ".service"
 	}
 
-	props, err := conn.GetUnitPropertiesContext(ctx, unitName)
+	var props map[string]interface{}
+	err := m.conns.do(ctx, func(conn *dbus.Conn) (err error) {
+		if props, err = conn.GetUnitPropertiesContext(ctx, unitName); err != nil {
+			return fmt.Errorf("failed to get service properties: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get service properties: %w", err)
+		return nil, err
 	}
 
 	info := &ServiceInfo{
@@ -135,6 +161,9 @@ func (m *Manager) Get(ctx context.Context, name string) (*ServiceInfo, error) {
 		info.Description = desc
 	}
 	if loadState, ok := props["LoadState"].(string); ok {
+		if loadState == "not-found" {
+			return nil, apierror.NotFound("service '%s' not found", name)
+		}
 		info.LoadState = loadState
 	}
 	if activeState, ok := props["ActiveState"].(string); ok {
@@ -178,41 +207,46 @@ func (m *Manager) Restart(ctx context.Context, name string) (*ServiceAction, err
 
 func (m *Manager) doAction(ctx context.Context, name, action string) (*ServiceAction, error) {
 	if !m.IsAllowed(name) {
-		return &ServiceAction{
-			Name:    name,
-			Action:  action,
-			Success: false,
-			Message: fmt.Sprintf("service '%s' is not in allowed list", name),
-		}, nil
-	}
-
-	conn, err := dbus.NewWithContext(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to systemd: %w", err)
+		return nil, apierror.NotAllowed("service '%s' is not in allowed list", name)
 	}
-	defer conn.Close()
 
 	unitName := name
 	if !strings.HasSuffix(unitName, ".service") {
 		unitName = name + ".service"
 	}
 
+	// Wait for the job result for at most DefaultActionTimeout unless the
+	// caller set its own deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultActionTimeout)
+		defer cancel()
+	}
+
+	if m.helper != nil {
+		return m.helperAction(ctx, name, unitName, action)
+	}
+
+	// Jobs are not retried on a new connection; a broken connection is
+	// replaced on the next call
+	conn, err := m.conns.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resultChan := make(chan string, 1)
+	started := time.Now()
 
+	var jobID int
 	switch action {
 	case "start":
-		_, err = conn.StartUnitContext(ctx, unitName, "replace", resultChan)
+		jobID, err = conn.StartUnitContext(ctx, unitName, "replace", resultChan)
 	case "stop":
-		_, err = conn.StopUnitContext(ctx, unitName, "replace", resultChan)
+		jobID, err = conn.StopUnitContext(ctx, unitName, "replace", resultChan)
 	case "restart":
-		_, err = conn.RestartUnitContext(ctx, unitName, "replace", resultChan)
+		jobID, err = conn.RestartUnitContext(ctx, unitName, "replace", resultChan)
 	default:
-		return &ServiceAction{
-			Name:    name,
-			Action:  action,
-			Success: false,
-			Message: fmt.Sprintf("unknown action: %s", action),
-		}, nil
+		return nil, apierror.Invalid("unknown action: %s", action)
 	}
 
 	if err != nil {
@@ -224,7 +258,7 @@ func (m *Manager) doAction(ctx context.Context, name, action string) (*ServiceAc
 		}, nil
 	}
 
-	// Wait for result with timeout
+	// Wait for result
 	select {
 	case result := <-resultChan:
 		success := result == "done"
@@ -234,20 +268,49 @@ func (m *Manager) doAction(ctx context.Context, name, action string) (*ServiceAc
 			Action:  action,
 			Success: success,
 			Message: msg,
-		}, nil
-	case <-time.After(30 * time.Second):
-		return &ServiceAction{
-			Name:    name,
-			Action:  action,
-			Success: false,
-			Message: "operation timed out",
+			JobID:   jobID,
 		}, nil
 	case <-ctx.Done():
+		// systemd keeps working on the job; hand back its ID so the
+		// result can be polled rather than reporting a failure
+		m.jobs.track(&Job{
+			ID:        jobID,
+			Service:   name,
+			Action:    action,
+			State:     JobRunning,
+			StartedAt: started,
+		}, resultChan)
+
+		msg := "stopped waiting"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			msg = "timed out waiting"
+		}
 		return &ServiceAction{
 			Name:    name,
 			Action:  action,
 			Success: false,
-			Message: "operation cancelled",
+			Pending: true,
+			Message: fmt.Sprintf("%s for service %s %s; job %d is still running", msg, name, action, jobID),
+			JobID:   jobID,
 		}, nil
 	}
 }
+
+// helperAction runs an action with systemctl through the privilege helper.
+// systemctl waits for the job, so there is no job ID to poll if it times out.
+func (m *Manager) helperAction(ctx context.Context, name, unitName, action string) (*ServiceAction, error) {
+	output, err := m.helper(ctx, "service", action, unitName)
+	result := &ServiceAction{
+		Name:    name,
+		Action:  action,
+		Success: err == nil,
+		Message: fmt.Sprintf("service %s %s: done", name, action),
+	}
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to %s service: %v %s", action, err, strings.TrimSpace(string(output)))
+		if ctx.Err() != nil {
+			result.Message = fmt.Sprintf("timed out waiting for service %s %s", name, action)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestActionThroughHelper(t *testing.T) {
+	m := NewManager([]string{"nginx"})
+
+	var got []string
+	m.SetHelper(func(ctx context.Context, args ...string) ([]byte, error) {
+		got = args
+		return nil, nil
+	})
+	result, err := m.Restart(context.Background(), "nginx")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, []string{"service", "restart", "nginx.service"}, got)
+
+	m.SetHelper(func(ctx context.Context, args ...string) ([]byte, error) {
+		return []byte("sudo: a password is required"), errors.New("exit status 1")
+	})
+	result, err = m.Stop(context.Background(), "nginx")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Message, "a password is required")
+
+	// The allowlist applies before the helper is called
+	_, err = m.Start(context.Background(), "sshd")
+	assert.Error(t, err)
+}
+
+func TestUnitFileActionThroughHelper(t *testing.T) {
+	m := NewManager([]string{"nginx"})
+
+	var calls [][]string
+	m.SetHelper(func(ctx context.Context, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, nil
+	})
+	result, err := m.Enable(context.Background(), "nginx")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "enable", result.Action)
+
+	_, err = m.Mask(context.Background(), "nginx.service")
+	require.NoError(t, err)
+	require.NoError(t, m.DaemonReload(context.Background()))
+
+	assert.Equal(t, [][]string{
+		{"service", "enable", "nginx.service"},
+		{"service", "mask", "nginx.service"},
+		{"daemon-reload"},
+	}, calls)
+
+	_, err = m.Disable(context.Background(), "sshd")
+	assert.ErrorIs(t, err, apierror.ErrNotAllowed)
+}
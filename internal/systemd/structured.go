@@ -0,0 +1,75 @@
+package systemd
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// messageKeys are the keys JSON loggers put the message under, in order
+// of preference
+var messageKeys = []string{"msg", "message", "MESSAGE"}
+
+// continuation matches lines that carry on a stack trace rather than
+// start an entry: Java and Node frames, Python tracebacks, Go panics and
+// exception lines
+var continuation = regexp.MustCompile(`^(\s|at |Caused by: |\.\.\. \d+ more|Traceback \(most recent call last\):|During handling of the above exception|The above exception was the direct cause|goroutine \d+ \[|[\w.$]+(Error|Exception)(: |$))`)
+
+// mergeWindow is how far apart a continuation may be from the entry it
+// belongs to
+const mergeWindow = time.Second
+
+// Structure parses a JSON message into the entry. The message is taken
+// from msg or message and every other key goes into ExtraFields. Entries
+// whose message is not a JSON object are returned unchanged.
+func Structure(entry JournalEntry) JournalEntry {
+	msg := strings.TrimSpace(entry.Message)
+	if !strings.HasPrefix(msg, "{") {
+		return entry
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(msg), &fields); err != nil {
+		return entry
+	}
+
+	for _, key := range messageKeys {
+		if m, ok := fields[key].(string); ok {
+			entry.Message = m
+			delete(fields, key)
+			break
+		}
+	}
+	if len(fields) > 0 {
+		entry.ExtraFields = fields
+	}
+	return entry
+}
+
+// structure parses JSON messages and merges multi-line stack traces, which
+// programs log one line per entry, into the entry they follow
+func structure(entries []JournalEntry) []JournalEntry {
+	merged := entries[:0]
+	for _, entry := range entries {
+		if n := len(merged); n > 0 && continues(merged[n-1], entry) {
+			prev := &merged[n-1]
+			prev.Message += "\n" + entry.Message
+			prev.Priority = min(prev.Priority, entry.Priority)
+			prev.Cursor = entry.Cursor // Paging resumes after the last line
+			continue
+		}
+		merged = append(merged, Structure(entry))
+	}
+	return merged
+}
+
+// continues reports whether entry carries on prev's stack trace
+func continues(prev, entry JournalEntry) bool {
+	if prev.Unit != entry.Unit || prev.PID != entry.PID {
+		return false
+	}
+	if d := entry.Timestamp.Sub(prev.Timestamp); d < 0 || d > mergeWindow {
+		return false
+	}
+	return continuation.MatchString(entry.Message)
+}
@@ -0,0 +1,66 @@
+package systemd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructure(t *testing.T) {
+	entry := Structure(JournalEntry{Message: `{"level":"warn","msg":"slow query","ms":812,"db":{"name":"app"}}`})
+	assert.Equal(t, "slow query", entry.Message)
+	assert.Equal(t, map[string]interface{}{
+		"level": "warn",
+		"ms":    float64(812),
+		"db":    map[string]interface{}{"name": "app"},
+	}, entry.ExtraFields)
+
+	// Without a message key the JSON is kept as the message
+	entry = Structure(JournalEntry{Message: `{"event":"login"}`})
+	assert.Equal(t, `{"event":"login"}`, entry.Message)
+	assert.Equal(t, map[string]interface{}{"event": "login"}, entry.ExtraFields)
+
+	for _, msg := range []string{"plain text", "{not json", `["an", "array"]`} {
+		entry = Structure(JournalEntry{Message: msg})
+		assert.Equal(t, msg, entry.Message)
+		assert.Nil(t, entry.ExtraFields)
+	}
+}
+
+func TestStructureMergesStackTraces(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	line := func(ms int, pid, msg string, priority int) JournalEntry {
+		return JournalEntry{
+			Timestamp: at.Add(time.Duration(ms) * time.Millisecond),
+			Unit:      "app.service",
+			PID:       pid,
+			Message:   msg,
+			Priority:  priority,
+			Cursor:    msg,
+		}
+	}
+
+	entries := structure([]JournalEntry{
+		line(0, "10", "Request failed", 3),
+		line(1, "10", "java.lang.IllegalStateException: closed", 6),
+		line(1, "10", "\tat app.Db.query(Db.java:42)", 6),
+		line(2, "10", "Caused by: java.io.IOException: reset", 6),
+		line(2, "10", "\t... 12 more", 6),
+		line(3, "11", "\tat other.Process(Other.java:1)", 6), // Another process
+		line(5000, "10", "\tat late.Frame(Late.java:1)", 6),  // Too late
+		line(5001, "10", `{"msg":"recovered","attempt":2}`, 6),
+		line(5002, "10", "Traceback (most recent call last):", 3),
+		line(5002, "10", `  File "app.py", line 3, in <module>`, 3),
+		line(5003, "10", "ValueError: bad input", 3),
+	})
+
+	assert.Len(t, entries, 4)
+	assert.Equal(t, "Request failed\njava.lang.IllegalStateException: closed\n\tat app.Db.query(Db.java:42)\nCaused by: java.io.IOException: reset\n\t... 12 more", entries[0].Message)
+	assert.Equal(t, 3, entries[0].Priority)
+	assert.Equal(t, "\t... 12 more", entries[0].Cursor)
+	assert.Equal(t, "11", entries[1].PID)
+	assert.Equal(t, "\tat late.Frame(Late.java:1)", entries[2].Message)
+	assert.Equal(t, "recovered\nTraceback (most recent call last):\n  File \"app.py\", line 3, in <module>\nValueError: bad input", entries[3].Message)
+	assert.Equal(t, map[string]interface{}{"attempt": float64(2)}, entries[3].ExtraFields)
+}
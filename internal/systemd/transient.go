@@ -0,0 +1,220 @@
+package systemd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+const (
+	// transientPrefix starts the name of every unit the agent launches
+	transientPrefix = "hivedeck-task-"
+
+	// transientDescription precedes the task name in a unit's description,
+	// which is how listing finds the task again
+	transientDescription = "Hivedeck task "
+)
+
+// TransientUnitName returns a new unit name for a run of task. Characters
+// systemd does not allow in unit names become dashes.
+func TransientUnitName(task string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, task)
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s%s-%d.service", transientPrefix, name, time.Now().UnixNano())
+	}
+	return transientPrefix + name + "-" + hex.EncodeToString(b) + ".service"
+}
+
+// IsTransientUnit reports whether unit was launched by the agent
+func IsTransientUnit(unit string) bool {
+	return strings.HasPrefix(unit, transientPrefix) && strings.HasSuffix(unit, ".service")
+}
+
+// transientProperties describes a unit that runs command with bash. The
+// unit is unloaded once it stops, whether it succeeded or failed.
+func transientProperties(req TransientRequest) []dbus.Property {
+	props := []dbus.Property{
+		dbus.PropDescription(transientDescription + req.Task),
+		dbus.PropExecStart([]string{"/bin/bash", "-c", req.Command}, false),
+		{Name: "CollectMode", Value: godbus.MakeVariant("inactive-or-failed")},
+	}
+	if req.Limits.CPUQuota > 0 {
+		props = append(props, dbus.Property{
+			Name:  "CPUQuotaPerSecUSec",
+			Value: godbus.MakeVariant(uint64(req.Limits.CPUQuota) * 10000),
+		})
+	}
+	if req.Limits.MemoryMax > 0 {
+		props = append(props, dbus.Property{Name: "MemoryMax", Value: godbus.MakeVariant(req.Limits.MemoryMax)})
+	}
+	if req.RuntimeMax > 0 {
+		props = append(props, dbus.Property{
+			Name:  "RuntimeMaxUSec",
+			Value: godbus.MakeVariant(uint64(req.RuntimeMax / time.Microsecond)),
+		})
+	}
+	return props
+}
+
+// RunTransient launches a command as a transient service, as systemd-run
+// does. The unit belongs to systemd, so it keeps running if the agent
+// restarts, and its output goes to the journal under the unit's name.
+func (m *Manager) RunTransient(ctx context.Context, req TransientRequest) (*TransientUnit, error) {
+	if req.Limits.CPUQuota < 0 || req.RuntimeMax < 0 {
+		return nil, apierror.Invalid("limits must not be negative")
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultActionTimeout)
+		defer cancel()
+	}
+
+	unit := TransientUnitName(req.Task)
+	err := m.conns.do(ctx, func(conn *dbus.Conn) error {
+		done := make(chan string, 1)
+		if _, err := conn.StartTransientUnitContext(ctx, unit, "fail", transientProperties(req), done); err != nil {
+			return fmt.Errorf("failed to create %s: %w", unit, err)
+		}
+		select {
+		case status := <-done:
+			if status != "done" {
+				return fmt.Errorf("failed to start %s: %s", unit, status)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("timed out starting %s", unit)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransientUnit{
+		Unit:      unit,
+		Task:      req.Task,
+		Command:   req.Command,
+		State:     "active",
+		Limits:    req.Limits,
+		StartedAt: time.Now(),
+	}, nil
+}
+
+// ListTransient returns the agent's transient units that are still loaded.
+// Finished units are unloaded, so only their journal remains.
+func (m *Manager) ListTransient(ctx context.Context) (*TransientList, error) {
+	var units []dbus.UnitStatus
+	err := m.conns.do(ctx, func(conn *dbus.Conn) (err error) {
+		if units, err = conn.ListUnitsByPatternsContext(ctx, nil, []string{transientPrefix + "*.service"}); err != nil {
+			return fmt.Errorf("failed to list units: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &TransientList{Units: []TransientUnit{}}
+	for _, u := range units {
+		unit, err := m.transientUnit(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		list.Units = append(list.Units, unit)
+	}
+	sort.Slice(list.Units, func(i, j int) bool { return list.Units[i].StartedAt.Before(list.Units[j].StartedAt) })
+	list.Total = len(list.Units)
+	return list, nil
+}
+
+// transientUnit fills in a listed unit's details
+func (m *Manager) transientUnit(ctx context.Context, u dbus.UnitStatus) (TransientUnit, error) {
+	unit := TransientUnit{
+		Unit:     u.Name,
+		Task:     strings.TrimPrefix(u.Description, transientDescription),
+		State:    u.ActiveState,
+		SubState: u.SubState,
+	}
+
+	var props map[string]interface{}
+	err := m.conns.do(ctx, func(conn *dbus.Conn) (err error) {
+		if props, err = conn.GetUnitTypePropertiesContext(ctx, u.Name, "Service"); err != nil {
+			return fmt.Errorf("failed to read %s: %w", u.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return unit, err
+	}
+	if pid, ok := props["MainPID"].(uint32); ok {
+		unit.MainPID = pid
+	}
+	if mem, ok := props["MemoryCurrent"].(uint64); ok && mem != ^uint64(0) {
+		unit.Memory = mem
+	}
+	if started, ok := props["ExecMainStartTimestamp"].(uint64); ok && started > 0 {
+		unit.StartedAt = time.UnixMicro(int64(started))
+	}
+	if quota, ok := props["CPUQuotaPerSecUSec"].(uint64); ok && quota != ^uint64(0) {
+		unit.Limits.CPUQuota = int(quota / 10000)
+	}
+	if limit, ok := props["MemoryMax"].(uint64); ok && limit != ^uint64(0) {
+		unit.Limits.MemoryMax = limit
+	}
+	if execStart, ok := props["ExecStart"].([][]interface{}); ok && len(execStart) > 0 && len(execStart[0]) > 1 {
+		if argv, ok := execStart[0][1].([]string); ok && len(argv) == 3 {
+			unit.Command = argv[2]
+		}
+	}
+	return unit, nil
+}
+
+// StopTransient stops one of the agent's transient units. Stopping also
+// unloads it.
+func (m *Manager) StopTransient(ctx context.Context, unit string) error {
+	if !IsTransientUnit(unit) {
+		return apierror.NotAllowed("'%s' was not launched by the agent", unit)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultActionTimeout)
+		defer cancel()
+	}
+
+	return m.conns.do(ctx, func(conn *dbus.Conn) error {
+		done := make(chan string, 1)
+		if _, err := conn.StopUnitContext(ctx, unit, "replace", done); err != nil {
+			if strings.Contains(err.Error(), "not loaded") {
+				return apierror.NotFound("unit '%s' is not running", unit)
+			}
+			return fmt.Errorf("failed to stop %s: %w", unit, err)
+		}
+		select {
+		case status := <-done:
+			if status != "done" {
+				return fmt.Errorf("failed to stop %s: %s", unit, status)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("timed out stopping %s", unit)
+		}
+		return nil
+	})
+}
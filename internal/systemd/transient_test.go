@@ -0,0 +1,69 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+func TestTransientUnitName(t *testing.T) {
+	unit := TransientUnitName("clear cache/all")
+	assert.Regexp(t, `^hivedeck-task-clear-cache-all-[0-9a-f]{8}\.service$`, unit)
+	assert.True(t, IsTransientUnit(unit))
+	assert.NotEqual(t, unit, TransientUnitName("clear cache/all"))
+
+	assert.False(t, IsTransientUnit("nginx.service"))
+	assert.False(t, IsTransientUnit("hivedeck-limit-1234.scope"))
+}
+
+func TestTransientProperties(t *testing.T) {
+	props := transientProperties(TransientRequest{
+		Task:       "backup",
+		Command:    "tar czf /tmp/etc.tgz /etc",
+		Limits:     Limits{CPUQuota: 50, MemoryMax: 256 << 20},
+		RuntimeMax: 10 * time.Minute,
+	})
+
+	values := make(map[string]interface{})
+	for _, p := range props {
+		values[p.Name] = p.Value.Value()
+	}
+	assert.Equal(t, "Hivedeck task backup", values["Description"])
+	assert.Equal(t, "inactive-or-failed", values["CollectMode"])
+	assert.Equal(t, uint64(500000), values["CPUQuotaPerSecUSec"])
+	assert.Equal(t, uint64(256<<20), values["MemoryMax"])
+	assert.Equal(t, uint64(600000000), values["RuntimeMaxUSec"])
+	assert.Contains(t, values, "ExecStart")
+
+	props = transientProperties(TransientRequest{Task: "backup", Command: "true"})
+	assert.Len(t, props, 3, "no limits unless asked")
+}
+
+func TestTransientValidation(t *testing.T) {
+	m := NewManager(nil)
+	_, err := m.RunTransient(context.Background(), TransientRequest{Task: "x", Command: "true", RuntimeMax: -time.Second})
+	assert.True(t, errors.Is(err, apierror.ErrInvalid))
+
+	err = m.StopTransient(context.Background(), "nginx.service")
+	assert.True(t, errors.Is(err, apierror.ErrNotAllowed))
+}
+
+func TestTransientUnit_Error(t *testing.T) {
+	// A failed property lookup is reported rather than listing the unit
+	// with zero values
+	d := &fakeDialer{err: errors.New("connection refused")}
+	m := NewManager(nil)
+	m.conns.dial = d.dial
+
+	_, err := m.transientUnit(context.Background(), dbus.UnitStatus{Name: "hivedeck-task-backup-0a1b2c3d.service"})
+	assert.True(t, errors.Is(err, apierror.ErrUnavailable))
+
+	_, err = m.ListTransient(context.Background())
+	assert.True(t, errors.Is(err, apierror.ErrUnavailable))
+}
@@ -16,6 +16,22 @@ type ServiceInfo struct {
 	StartedAt   time.Time `json:"started_at,omitempty"`
 	Memory      uint64    `json:"memory"`
 	Tasks       uint64    `json:"tasks"`
+
+	// Logs counts the unit's recent errors and warnings, when requested
+	Logs *LogLevelStats `json:"logs,omitempty"`
+}
+
+// LevelCounts counts journal entries by severity
+type LevelCounts struct {
+	Errors   int `json:"errors"`   // Priorities emerg to err (0-3)
+	Warnings int `json:"warnings"` // Priority warning (4)
+}
+
+// LogLevelStats counts a unit's errors and warnings in the journal
+type LogLevelStats struct {
+	LastHour LevelCounts `json:"last_hour"`
+	LastDay  LevelCounts `json:"last_day"`
+	Capped   bool        `json:"capped,omitempty"` // Counting stopped at the limit, so LastDay is too low
 }
 
 // ServiceList contains a list of services
@@ -27,32 +43,93 @@ type ServiceList struct {
 // ServiceAction represents an action on a service
 type ServiceAction struct {
 	Name    string `json:"name"`
-	Action  string `json:"action"` // start, stop, restart, enable, disable
+	Action  string `json:"action"` // start, stop, restart, enable, disable, mask, unmask
 	Success bool   `json:"success"`
+	Pending bool   `json:"pending,omitempty"` // The job outlived the wait; poll it by JobID
 	Message string `json:"message"`
+	JobID   int    `json:"job_id,omitempty"`
+	// Changes lists the links an enable, disable, mask or unmask made
+	Changes []UnitFileChange `json:"changes,omitempty"`
 }
 
 // JournalEntry represents a single log entry
 type JournalEntry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Unit      string    `json:"unit"`
-	Message   string    `json:"message"`
-	Priority  int       `json:"priority"`
-	PID       string    `json:"pid"`
-	Hostname  string    `json:"hostname"`
+	Timestamp time.Time         `json:"timestamp"`
+	Unit      string            `json:"unit"`
+	Message   string            `json:"message"`
+	Priority  int               `json:"priority"`
+	PID       string            `json:"pid"`
+	Hostname  string            `json:"hostname"`
+	Cursor    string            `json:"cursor,omitempty"` // Journal position, to resume after it
+	Fields    map[string]string `json:"fields,omitempty"` // Every field of the entry, with JournalQuery.AllFields
+
+	// ExtraFields holds the keys of a JSON message besides the message
+	// itself, with JournalQuery.Structured
+	ExtraFields map[string]interface{} `json:"extra_fields,omitempty"`
 }
 
 // JournalQuery represents parameters for log queries
 type JournalQuery struct {
-	Unit     string `json:"unit,omitempty"`
-	Priority int    `json:"priority,omitempty"` // 0-7, -1 for all
-	Lines    int    `json:"lines,omitempty"`
-	Since    string `json:"since,omitempty"`
-	Until    string `json:"until,omitempty"`
+	Unit      string `json:"unit,omitempty"`
+	Priority  int    `json:"priority,omitempty"` // 0-7, -1 for all
+	Lines     int    `json:"lines,omitempty"`
+	Since     string `json:"since,omitempty"`
+	Until     string `json:"until,omitempty"`
+	After     string `json:"after,omitempty"`  // Cursor; the first Lines entries after it
+	Before    string `json:"before,omitempty"` // Cursor; the last Lines entries before it
+	AllFields bool   `json:"all_fields,omitempty"`
+
+	// Structured parses JSON messages and merges multi-line stack traces
+	// into one entry, so a page may hold fewer than Lines entries
+	Structured bool `json:"structured,omitempty"`
 }
 
 // LogStream represents a stream of log entries
 type LogStream struct {
 	Entries []JournalEntry `json:"entries"`
 	Unit    string         `json:"unit,omitempty"`
+	Backend string         `json:"backend"` // sdjournal or journalctl
+}
+
+// Limits are resource limits for a process scope. Zero leaves a limit
+// unchanged.
+type Limits struct {
+	CPUQuota  int    `json:"cpu_quota,omitempty"`  // Percent of one CPU; 200 allows two
+	MemoryMax uint64 `json:"memory_max,omitempty"` // Bytes
+}
+
+// ProcessLimit is the result of limiting a process
+type ProcessLimit struct {
+	PID     uint32 `json:"pid"`
+	Unit    string `json:"unit"`
+	Limits  Limits `json:"limits"`
+	Updated bool   `json:"updated"` // The process already had a scope
+	Message string `json:"message"`
+}
+
+// TransientRequest is a command to launch as a transient service
+type TransientRequest struct {
+	Task       string
+	Command    string
+	Limits     Limits
+	RuntimeMax time.Duration // Zero runs until the command exits
+}
+
+// TransientUnit is a command the agent launched as a transient service
+type TransientUnit struct {
+	Unit      string    `json:"unit"`
+	Task      string    `json:"task"`
+	Command   string    `json:"command,omitempty"`
+	State     string    `json:"state"`
+	SubState  string    `json:"sub_state,omitempty"`
+	MainPID   uint32    `json:"main_pid,omitempty"`
+	Memory    uint64    `json:"memory,omitempty"`
+	Limits    Limits    `json:"limits"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// TransientList is the agent's running transient units
+type TransientList struct {
+	Units []TransientUnit `json:"units"`
+	Total int             `json:"total"`
 }
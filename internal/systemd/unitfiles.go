@@ -0,0 +1,152 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+)
+
+// UnitFileChange is a symlink systemd created or removed
+type UnitFileChange struct {
+	Type        string `json:"type"` // symlink or unlink
+	Filename    string `json:"filename"`
+	Destination string `json:"destination,omitempty"`
+}
+
+// Enable makes a service start at boot, as systemctl enable does. It does
+// not start the service now.
+func (m *Manager) Enable(ctx context.Context, name string) (*ServiceAction, error) {
+	return m.unitFileAction(ctx, name, "enable")
+}
+
+// Disable stops a service starting at boot. It does not stop it now.
+func (m *Manager) Disable(ctx context.Context, name string) (*ServiceAction, error) {
+	return m.unitFileAction(ctx, name, "disable")
+}
+
+// Mask links a service to /dev/null so nothing can start it, not even as a
+// dependency. A running service keeps running.
+func (m *Manager) Mask(ctx context.Context, name string) (*ServiceAction, error) {
+	return m.unitFileAction(ctx, name, "mask")
+}
+
+// Unmask undoes Mask
+func (m *Manager) Unmask(ctx context.Context, name string) (*ServiceAction, error) {
+	return m.unitFileAction(ctx, name, "unmask")
+}
+
+// unitFileAction changes a service's unit file links, then reloads systemd
+// so it sees them, as systemctl does
+func (m *Manager) unitFileAction(ctx context.Context, name, action string) (*ServiceAction, error) {
+	if !m.IsAllowed(name) {
+		return nil, apierror.NotAllowed("service '%s' is not in allowed list", name)
+	}
+	switch action {
+	case "enable", "disable", "mask", "unmask":
+	default:
+		return nil, apierror.Invalid("unknown action: %s", action)
+	}
+
+	unitName := name
+	if !strings.HasSuffix(unitName, ".service") {
+		unitName = name + ".service"
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultActionTimeout)
+		defer cancel()
+	}
+
+	if m.helper != nil {
+		return m.helperAction(ctx, name, unitName, action)
+	}
+
+	var changes []UnitFileChange
+	err := m.conns.do(ctx, func(conn *dbus.Conn) (err error) {
+		files := []string{unitName}
+		changes = nil
+		switch action {
+		case "enable":
+			var result []dbus.EnableUnitFileChange
+			if _, result, err = conn.EnableUnitFilesContext(ctx, files, false, false); err == nil {
+				for _, c := range result {
+					changes = append(changes, UnitFileChange(c))
+				}
+			}
+		case "disable":
+			var result []dbus.DisableUnitFileChange
+			if result, err = conn.DisableUnitFilesContext(ctx, files, false); err == nil {
+				for _, c := range result {
+					changes = append(changes, UnitFileChange(c))
+				}
+			}
+		case "mask":
+			var result []dbus.MaskUnitFileChange
+			if result, err = conn.MaskUnitFilesContext(ctx, files, false, false); err == nil {
+				for _, c := range result {
+					changes = append(changes, UnitFileChange(c))
+				}
+			}
+		case "unmask":
+			var result []dbus.UnmaskUnitFileChange
+			if result, err = conn.UnmaskUnitFilesContext(ctx, files, false); err == nil {
+				for _, c := range result {
+					changes = append(changes, UnitFileChange(c))
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return conn.ReloadContext(ctx)
+	})
+	if err != nil {
+		return &ServiceAction{
+			Name:    name,
+			Action:  action,
+			Success: false,
+			Message: fmt.Sprintf("failed to %s service: %v", action, err),
+		}, nil
+	}
+
+	msg := fmt.Sprintf("service %s %s: done", name, action)
+	if len(changes) == 0 {
+		msg = fmt.Sprintf("service %s %s: nothing to change", name, action)
+	}
+	return &ServiceAction{
+		Name:    name,
+		Action:  action,
+		Success: true,
+		Message: msg,
+		Changes: changes,
+	}, nil
+}
+
+// DaemonReload makes systemd reread its unit files, as systemctl
+// daemon-reload does after a unit file is edited
+func (m *Manager) DaemonReload(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultActionTimeout)
+		defer cancel()
+	}
+
+	if m.helper != nil {
+		if output, err := m.helper(ctx, "daemon-reload"); err != nil {
+			return fmt.Errorf("failed to reload systemd: %v %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	return m.conns.do(ctx, func(conn *dbus.Conn) error {
+		if err := conn.ReloadContext(ctx); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+		return nil
+	})
+}
@@ -3,11 +3,12 @@ package tasks
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"os/exec"
 	"time"
 
 	"github.com/ngenohkevin/hivedeck-agent/config"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
 )
 
 // Manager handles task execution
@@ -44,7 +45,7 @@ func (m *Manager) List() *TaskList {
 func (m *Manager) Get(name string) (*Task, error) {
 	t, ok := m.tasks[name]
 	if !ok {
-		return nil, fmt.Errorf("task '%s' not found", name)
+		return nil, apierror.NotFound("task '%s' not found", name)
 	}
 
 	return &Task{
@@ -59,7 +60,7 @@ func (m *Manager) Get(name string) (*Task, error) {
 func (m *Manager) Run(ctx context.Context, name string) (*TaskResult, error) {
 	t, ok := m.tasks[name]
 	if !ok {
-		return nil, fmt.Errorf("task '%s' not found", name)
+		return nil, apierror.NotFound("task '%s' not found", name)
 	}
 
 	startTime := time.Now()
@@ -27,3 +27,10 @@ type TaskResult struct {
 	StartedAt time.Time     `json:"started_at"`
 	Duration  time.Duration `json:"duration"`
 }
+
+// LaunchRequest sets limits for a task launched as a transient unit
+type LaunchRequest struct {
+	CPUQuota   int    `json:"cpu_quota,omitempty"`   // Percent of one CPU
+	MemoryMax  string `json:"memory_max,omitempty"`  // Bytes, or e.g. "512M"
+	RuntimeMax string `json:"runtime_max,omitempty"` // e.g. "30m"; stopped when it runs longer
+}
@@ -0,0 +1,268 @@
+package thermal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+// taskTimeout bounds a hook task
+const taskTimeout = time.Minute
+
+// readTemps reads the sensors; replaced in tests
+var readTemps = system.GetTemperatures
+
+// Options configures a Monitor
+type Options struct {
+	Thresholds
+	Sensors  []string // Substrings of sensor keys to watch; empty watches all
+	Interval time.Duration
+	HotTask  string // Run when the temperature reaches the warning level
+	CoolTask string // Run when it is back to normal
+}
+
+// RunTask runs a task by name
+type RunTask func(ctx context.Context, name string) error
+
+// Monitor raises events when the hottest sensor crosses the thresholds and
+// runs hook tasks, e.g. to switch a fan on and off
+type Monitor struct {
+	opts    Options
+	bus     *events.Bus
+	runTask RunTask
+
+	mu            sync.Mutex
+	status        Status
+	throttleCount uint64
+	haveCount     bool
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewMonitor creates a thermal monitor
+func NewMonitor(opts Options, bus *events.Bus, runTask RunTask) *Monitor {
+	if opts.Interval <= 0 {
+		opts.Interval = 15 * time.Second
+	}
+
+	return &Monitor{
+		opts:    opts,
+		bus:     bus,
+		runTask: runTask,
+		status: Status{
+			Level:      LevelNormal,
+			Thresholds: opts.Thresholds,
+			HotTask:    opts.HotTask,
+			CoolTask:   opts.CoolTask,
+		},
+		stop: make(chan struct{}),
+	}
+}
+
+// Start checks immediately and then every interval until Stop is called
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	m.status.Enabled = true
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			m.Check()
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the checks
+func (m *Monitor) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// Status returns the latest state
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Check reads the sensors once and acts on level changes
+func (m *Monitor) Check() {
+	sensor, temp, ok := m.hottest()
+	if !ok {
+		return
+	}
+	throttled, throttleKnown := m.throttled()
+	now := time.Now()
+
+	m.mu.Lock()
+	prev := m.status.Level
+	level := nextLevel(prev, temp, m.opts.Thresholds)
+	wasThrottled := m.status.Throttled != nil && *m.status.Throttled
+
+	m.status.Temperature = temp
+	m.status.Sensor = sensor
+	m.status.CheckedAt = &now
+	m.status.Level = level
+	if level != prev {
+		m.status.Since = &now
+	}
+	if throttleKnown {
+		m.status.Throttled = &throttled
+	}
+	status := m.status
+	m.mu.Unlock()
+
+	if throttleKnown && throttled && !wasThrottled {
+		m.publish("thermal.throttled", events.SeverityWarning,
+			fmt.Sprintf("CPU is being throttled at %.1f°C", temp), status)
+	}
+	if level == prev {
+		return
+	}
+
+	switch level {
+	case LevelCritical:
+		m.publish("thermal.critical", events.SeverityCritical,
+			fmt.Sprintf("%s reached %.1f°C (critical at %.0f°C)", sensor, temp, m.opts.Critical), status)
+	case LevelWarning:
+		m.publish("thermal.warning", events.SeverityWarning,
+			fmt.Sprintf("%s reached %.1f°C (warning at %.0f°C)", sensor, temp, m.opts.Warning), status)
+	case LevelNormal:
+		m.publish("thermal.normal", events.SeverityInfo,
+			fmt.Sprintf("%s is back to %.1f°C", sensor, temp), status)
+	}
+
+	// The hot task runs once on the way up; the cool task once on the way
+	// back down
+	if prev == LevelNormal && m.opts.HotTask != "" {
+		m.run(m.opts.HotTask)
+	} else if level == LevelNormal && m.opts.CoolTask != "" {
+		m.run(m.opts.CoolTask)
+	}
+}
+
+// nextLevel applies the thresholds. Moving down a level needs the
+// temperature to fall Hysteresis below that level's threshold.
+func nextLevel(current string, temp float64, t Thresholds) string {
+	level := LevelNormal
+	if t.Critical > 0 && temp >= t.Critical {
+		level = LevelCritical
+	} else if temp >= t.Warning {
+		level = LevelWarning
+	}
+
+	if rank(level) < rank(current) {
+		if current == LevelCritical && temp > t.Critical-t.Hysteresis {
+			return LevelCritical
+		}
+		if level == LevelNormal && temp > t.Warning-t.Hysteresis {
+			return LevelWarning
+		}
+	}
+	return level
+}
+
+func rank(level string) int {
+	switch level {
+	case LevelCritical:
+		return 2
+	case LevelWarning:
+		return 1
+	}
+	return 0
+}
+
+// hottest returns the hottest watched sensor
+func (m *Monitor) hottest() (string, float64, bool) {
+	var name string
+	var max float64
+	found := false
+	for _, t := range readTemps() {
+		if !m.watched(t.SensorKey) {
+			continue
+		}
+		if !found || t.Temperature > max {
+			name, max, found = t.SensorKey, t.Temperature, true
+		}
+	}
+	return name, max, found
+}
+
+func (m *Monitor) watched(key string) bool {
+	if len(m.opts.Sensors) == 0 {
+		return true
+	}
+	for _, s := range m.opts.Sensors {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// throttled reports whether the CPU is being slowed down, from the
+// Raspberry Pi firmware or the x86 throttle counters
+func (m *Monitor) throttled() (bool, bool) {
+	if flags, ok := readPiThrottled(); ok {
+		return flags&piThrottledNow != 0, true
+	}
+
+	count, ok := readThrottleCount()
+	if !ok {
+		return false, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev, had := m.throttleCount, m.haveCount
+	m.throttleCount, m.haveCount = count, true
+	if !had {
+		return false, true
+	}
+	return count > prev, true
+}
+
+func (m *Monitor) run(task string) {
+	run := &TaskRun{Task: task, At: time.Now(), Success: true}
+	if m.runTask == nil {
+		run.Success, run.Error = false, "tasks are not available"
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), taskTimeout)
+		err := m.runTask(ctx, task)
+		cancel()
+		if err != nil {
+			run.Success, run.Error = false, err.Error()
+			log.Printf("Thermal task %s failed: %v", task, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.status.LastTask = run
+	m.mu.Unlock()
+}
+
+func (m *Monitor) publish(eventType, severity, message string, status Status) {
+	if m.bus == nil {
+		return
+	}
+	m.bus.Publish(events.Event{
+		Type:     eventType,
+		Severity: severity,
+		Source:   "thermal",
+		Message:  message,
+		Data:     status,
+	})
+}
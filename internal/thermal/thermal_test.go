@@ -0,0 +1,113 @@
+package thermal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+	"github.com/ngenohkevin/hivedeck-agent/internal/system"
+)
+
+func TestNextLevel(t *testing.T) {
+	th := Thresholds{Warning: 70, Critical: 80, Hysteresis: 5}
+
+	tests := []struct {
+		current string
+		temp    float64
+		want    string
+	}{
+		{LevelNormal, 60, LevelNormal},
+		{LevelNormal, 70, LevelWarning},
+		{LevelNormal, 85, LevelCritical},
+		{LevelWarning, 67, LevelWarning}, // Within hysteresis
+		{LevelWarning, 65, LevelNormal},
+		{LevelCritical, 76, LevelCritical},
+		{LevelCritical, 74, LevelWarning},
+		{LevelCritical, 66, LevelWarning},
+		{LevelCritical, 60, LevelNormal},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, nextLevel(tt.current, tt.temp, th), "%s at %.0f", tt.current, tt.temp)
+	}
+
+	// No critical threshold
+	assert.Equal(t, LevelWarning, nextLevel(LevelNormal, 120, Thresholds{Warning: 70}))
+}
+
+func TestParsePiThrottled(t *testing.T) {
+	flags, ok := parsePiThrottled("throttled=0x50005\n")
+	require.True(t, ok)
+	assert.Equal(t, uint64(0x50005), flags)
+	assert.NotZero(t, flags&piThrottledNow)
+
+	_, ok = parsePiThrottled("error")
+	assert.False(t, ok)
+}
+
+func TestMonitor_Check(t *testing.T) {
+	origTemps, origPi, origCount := readTemps, readPiThrottled, readThrottleCount
+	defer func() {
+		readTemps, readPiThrottled, readThrottleCount = origTemps, origPi, origCount
+	}()
+
+	temp := 50.0
+	readTemps = func() []system.Temperature {
+		return []system.Temperature{
+			{SensorKey: "cpu_thermal", Temperature: temp},
+			{SensorKey: "nvme_composite", Temperature: 95},
+		}
+	}
+	readPiThrottled = func() (uint64, bool) { return 0, false }
+	count := uint64(10)
+	readThrottleCount = func() (uint64, bool) { return count, true }
+
+	var ran []string
+	bus := events.NewBus(events.DefaultCapacity)
+	m := NewMonitor(Options{
+		Thresholds: Thresholds{Warning: 70, Critical: 80, Hysteresis: 5},
+		Sensors:    []string{"cpu"},
+		HotTask:    "fan-on",
+		CoolTask:   "fan-off",
+	}, bus, func(_ context.Context, name string) error {
+		ran = append(ran, name)
+		return nil
+	})
+
+	m.Check()
+	s := m.Status()
+	assert.Equal(t, LevelNormal, s.Level)
+	assert.Equal(t, "cpu_thermal", s.Sensor)
+	require.NotNil(t, s.Throttled)
+	assert.False(t, *s.Throttled)
+
+	temp = 82
+	count = 12
+	m.Check()
+	s = m.Status()
+	assert.Equal(t, LevelCritical, s.Level)
+	assert.True(t, *s.Throttled)
+	assert.Equal(t, []string{"fan-on"}, ran)
+	require.NotNil(t, s.LastTask)
+	assert.True(t, s.LastTask.Success)
+
+	// Cooling within the hysteresis band keeps the fan on
+	temp = 68
+	m.Check()
+	assert.Equal(t, LevelWarning, m.Status().Level)
+	assert.Equal(t, []string{"fan-on"}, ran)
+
+	temp = 60
+	m.Check()
+	assert.Equal(t, LevelNormal, m.Status().Level)
+	assert.Equal(t, []string{"fan-on", "fan-off"}, ran)
+
+	list := bus.Recent(10, "thermal.")
+	var types []string
+	for _, e := range list.Events {
+		types = append(types, e.Type)
+	}
+	assert.ElementsMatch(t, []string{"thermal.critical", "thermal.throttled", "thermal.warning", "thermal.normal"}, types)
+}
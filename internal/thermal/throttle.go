@@ -0,0 +1,59 @@
+package thermal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Raspberry Pi get_throttled flags that mean the CPU is slowed right now:
+// frequency capped, throttled, or at the soft temperature limit
+const piThrottledNow = 0x2 | 0x4 | 0x8
+
+// readPiThrottled returns the flags from vcgencmd get_throttled
+var readPiThrottled = func() (uint64, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "vcgencmd", "get_throttled").Output()
+	if err != nil {
+		return 0, false
+	}
+	return parsePiThrottled(string(out))
+}
+
+// parsePiThrottled parses "throttled=0x50005"
+func parsePiThrottled(out string) (uint64, bool) {
+	value, ok := strings.CutPrefix(strings.TrimSpace(out), "throttled=")
+	if !ok {
+		return 0, false
+	}
+	flags, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+	return flags, err == nil
+}
+
+// throttleCountGlob matches the per-CPU thermal throttle counters on x86
+var throttleCountGlob = "/sys/devices/system/cpu/cpu*/thermal_throttle/core_throttle_count"
+
+// readThrottleCount sums the x86 thermal throttle counters
+var readThrottleCount = func() (uint64, bool) {
+	paths, _ := filepath.Glob(throttleCountGlob)
+	if len(paths) == 0 {
+		return 0, false
+	}
+
+	var total uint64
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		n, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		total += n
+	}
+	return total, true
+}
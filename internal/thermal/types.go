@@ -0,0 +1,42 @@
+package thermal
+
+import "time"
+
+// Temperature levels
+const (
+	LevelNormal   = "normal"
+	LevelWarning  = "warning"
+	LevelCritical = "critical"
+)
+
+// Thresholds are temperatures in °C. A level is left only once the
+// temperature is Hysteresis below its threshold. A zero Critical disables
+// the critical level.
+type Thresholds struct {
+	Warning    float64 `json:"warning"`
+	Critical   float64 `json:"critical,omitempty"`
+	Hysteresis float64 `json:"hysteresis"`
+}
+
+// TaskRun is the result of a hook task
+type TaskRun struct {
+	Task    string    `json:"task"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Status is the current thermal state
+type Status struct {
+	Enabled     bool       `json:"enabled"`
+	Level       string     `json:"level"`
+	Temperature float64    `json:"temperature"` // Hottest watched sensor
+	Sensor      string     `json:"sensor,omitempty"`
+	Thresholds  Thresholds `json:"thresholds"`
+	Throttled   *bool      `json:"throttled,omitempty"` // When the platform reports it
+	Since       *time.Time `json:"since,omitempty"`     // When Level last changed
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	HotTask     string     `json:"hot_task,omitempty"`
+	CoolTask    string     `json:"cool_task,omitempty"`
+	LastTask    *TaskRun   `json:"last_task,omitempty"`
+}
@@ -0,0 +1,237 @@
+package upstreams
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+const (
+	// failThreshold is how many checks in a row must fail before an
+	// upstream is reported down, so one slow response does not alert
+	failThreshold = 2
+	// latencySamples is how many successful checks AvgLatency covers
+	latencySamples = 20
+)
+
+type upstream struct {
+	url     string // Status.URL has any password redacted
+	status  Status
+	samples []float64
+}
+
+// Monitor checks upstream URLs, such as the backends behind a reverse proxy,
+// every interval. An upstream is up when it answers with a status below
+// 500; redirects are not followed.
+type Monitor struct {
+	interval time.Duration
+	http     *http.Client
+	bus      *events.Bus
+
+	mu        sync.Mutex
+	upstreams []*upstream
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// Options configures a Monitor
+type Options struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	Insecure bool // Skip TLS verification, for self-signed internal certificates
+}
+
+// NewMonitor creates a monitor for name=URL pairs. State changes are
+// published to bus.
+func NewMonitor(urls map[string]string, opts Options, bus *events.Bus) (*Monitor, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	m := &Monitor{
+		interval: opts.Interval,
+		http: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		bus:  bus,
+		stop: make(chan struct{}),
+	}
+
+	for name, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("upstream %s: invalid URL %q", name, raw)
+		}
+		// Upstreams start up, so the first failed checks raise an event
+		m.upstreams = append(m.upstreams, &upstream{url: raw, status: Status{Name: name, URL: u.Redacted(), Up: true}})
+	}
+	sort.Slice(m.upstreams, func(i, j int) bool { return m.upstreams[i].status.Name < m.upstreams[j].status.Name })
+
+	return m, nil
+}
+
+// Start checks immediately and then every interval until Stop is called
+func (m *Monitor) Start() {
+	if len(m.upstreams) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.CheckAll(context.Background())
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the checks
+func (m *Monitor) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// CheckAll checks every upstream concurrently and returns the result
+func (m *Monitor) CheckAll(ctx context.Context) *StatusList {
+	var wg sync.WaitGroup
+	for _, u := range m.upstreams {
+		wg.Add(1)
+		go func(u *upstream) {
+			defer wg.Done()
+			m.check(ctx, u)
+		}(u)
+	}
+	wg.Wait()
+	return m.Status()
+}
+
+// Status returns the result of the latest checks
+func (m *Monitor) Status() *StatusList {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := &StatusList{
+		Upstreams: make([]Status, 0, len(m.upstreams)),
+		Interval:  m.interval.String(),
+	}
+	for _, u := range m.upstreams {
+		list.Upstreams = append(list.Upstreams, u.status)
+		if !u.status.Up {
+			list.Down++
+		}
+	}
+	list.Total = len(list.Upstreams)
+	return list
+}
+
+func (m *Monitor) check(ctx context.Context, u *upstream) {
+	code, latency, err := m.probe(ctx, u.url)
+	now := time.Now()
+
+	m.mu.Lock()
+	s := &u.status
+	wasUp := s.Up
+	s.CheckedAt = &now
+	s.StatusCode = code
+	s.Latency = latency
+	s.Error = ""
+
+	if err == nil && code >= 500 {
+		err = fmt.Errorf("returned %d", code)
+	}
+	if err != nil {
+		s.Error = err.Error()
+		s.Failures++
+		if s.Failures >= failThreshold {
+			s.Up = false
+		}
+	} else {
+		s.Failures = 0
+		s.Up = true
+		u.samples = append(u.samples, latency)
+		if len(u.samples) > latencySamples {
+			u.samples = u.samples[1:]
+		}
+		var sum float64
+		for _, sample := range u.samples {
+			sum += sample
+		}
+		s.AvgLatency = sum / float64(len(u.samples))
+	}
+
+	changed := s.Up != wasUp
+	if changed {
+		s.Since = &now
+	}
+	status := *s
+	m.mu.Unlock()
+
+	if changed {
+		m.publish(status)
+	}
+}
+
+func (m *Monitor) probe(ctx context.Context, target string) (int, float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", "hivedeck-agent")
+
+	start := time.Now()
+	resp, err := m.http.Do(req)
+	latency := float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		return 0, latency, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	return resp.StatusCode, latency, nil
+}
+
+func (m *Monitor) publish(s Status) {
+	if m.bus == nil {
+		return
+	}
+
+	event := events.Event{
+		Type:     "upstream.up",
+		Severity: events.SeverityInfo,
+		Source:   "upstreams",
+		Message:  fmt.Sprintf("upstream %s is back up", s.Name),
+		Data:     s,
+	}
+	if !s.Up {
+		event.Type = "upstream.down"
+		event.Severity = events.SeverityWarning
+		event.Message = fmt.Sprintf("upstream %s is down: %s", s.Name, s.Error)
+	}
+	m.bus.Publish(event)
+}
@@ -0,0 +1,86 @@
+package upstreams
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+func TestNewMonitor_InvalidURL(t *testing.T) {
+	_, err := NewMonitor(map[string]string{"app": "ftp://host"}, Options{}, nil)
+	assert.Error(t, err)
+	_, err = NewMonitor(map[string]string{"app": "http://"}, Options{}, nil)
+	assert.Error(t, err)
+}
+
+func TestMonitor_CheckAll(t *testing.T) {
+	var failing atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer backend.Close()
+
+	bus := events.NewBus(events.DefaultCapacity)
+	m, err := NewMonitor(map[string]string{
+		"app": "http://user:secret@" + backend.Listener.Addr().String() + "/",
+	}, Options{Timeout: time.Second}, bus)
+	require.NoError(t, err)
+
+	// A redirect, e.g. to a login page, means the backend is answering
+	list := m.CheckAll(context.Background())
+	require.Len(t, list.Upstreams, 1)
+	s := list.Upstreams[0]
+	assert.True(t, s.Up)
+	assert.Equal(t, http.StatusFound, s.StatusCode)
+	assert.NotContains(t, s.URL, "secret")
+	assert.Greater(t, s.AvgLatency, 0.0)
+	assert.Equal(t, 0, list.Down)
+
+	// One failure is not enough to report it down
+	failing.Store(true)
+	s = m.CheckAll(context.Background()).Upstreams[0]
+	assert.True(t, s.Up)
+	assert.Equal(t, 1, s.Failures)
+	assert.Equal(t, "returned 502", s.Error)
+
+	list = m.CheckAll(context.Background())
+	assert.False(t, list.Upstreams[0].Up)
+	assert.Equal(t, 1, list.Down)
+	require.NotNil(t, list.Upstreams[0].Since)
+
+	failing.Store(false)
+	assert.True(t, m.CheckAll(context.Background()).Upstreams[0].Up)
+
+	recent := bus.Recent(0, "upstream")
+	require.Equal(t, 2, recent.Total)
+	assert.Equal(t, "upstream.up", recent.Events[0].Type)
+	assert.Equal(t, "upstream.down", recent.Events[1].Type)
+	assert.Equal(t, events.SeverityWarning, recent.Events[1].Severity)
+}
+
+func TestMonitor_Unreachable(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	addr := backend.URL
+	backend.Close()
+
+	m, err := NewMonitor(map[string]string{"gone": addr}, Options{Timeout: time.Second}, nil)
+	require.NoError(t, err)
+
+	m.CheckAll(context.Background())
+	s := m.CheckAll(context.Background()).Upstreams[0]
+	assert.False(t, s.Up)
+	assert.Zero(t, s.StatusCode)
+	assert.NotEmpty(t, s.Error)
+}
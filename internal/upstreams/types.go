@@ -0,0 +1,25 @@
+package upstreams
+
+import "time"
+
+// Status is the health of one upstream
+type Status struct {
+	Name       string     `json:"name"`
+	URL        string     `json:"url"`
+	Up         bool       `json:"up"`
+	StatusCode int        `json:"status_code,omitempty"`
+	Latency    float64    `json:"latency_ms"`     // Last check
+	AvgLatency float64    `json:"avg_latency_ms"` // Over recent successful checks
+	Error      string     `json:"error,omitempty"`
+	Failures   int        `json:"consecutive_failures"`
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+	Since      *time.Time `json:"since,omitempty"` // When Up last changed
+}
+
+// StatusList is the health of every upstream
+type StatusList struct {
+	Upstreams []Status `json:"upstreams"`
+	Total     int      `json:"total"`
+	Down      int      `json:"down"`
+	Interval  string   `json:"interval"`
+}
@@ -0,0 +1,152 @@
+package vulns
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// severityRank orders severities, most severe first
+var severityRank = map[string]int{
+	SeverityCritical: 0,
+	SeverityHigh:     1,
+	SeverityMedium:   2,
+	SeverityLow:      3,
+	SeverityUnknown:  4,
+}
+
+// ValidSeverity reports whether s is a known severity
+func ValidSeverity(s string) bool {
+	_, ok := severityRank[s]
+	return ok
+}
+
+// AtLeast reports whether severity is as severe as min
+func AtLeast(severity, min string) bool {
+	return severityRank[severity] <= severityRank[min]
+}
+
+// trivyReport is the part of "trivy --format json" output used here
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string
+			PkgName          string
+			InstalledVersion string
+			FixedVersion     string
+			Severity         string
+			Title            string
+		}
+	}
+}
+
+// parseTrivy parses a trivy JSON report
+func parseTrivy(data []byte) ([]Vulnerability, error) {
+	var report trivyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy output: %w", err)
+	}
+
+	vulns := []Vulnerability{}
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			severity := strings.ToLower(v.Severity)
+			if !ValidSeverity(severity) {
+				severity = SeverityUnknown
+			}
+			vulns = append(vulns, Vulnerability{
+				ID:        v.VulnerabilityID,
+				Package:   v.PkgName,
+				Installed: v.InstalledVersion,
+				FixedIn:   v.FixedVersion,
+				Fixable:   v.FixedVersion != "",
+				Severity:  severity,
+				Title:     v.Title,
+			})
+		}
+	}
+	return vulns, nil
+}
+
+// parseDebsecan parses debsecan's summary format, e.g.
+// "CVE-2023-4911 libc6 (fixed, remotely exploitable, high urgency)".
+// With --suite, "fixed" marks issues with an update available.
+func parseDebsecan(out string) []Vulnerability {
+	vulns := []Vulnerability{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "CVE-") {
+			continue
+		}
+		v := Vulnerability{ID: fields[0], Package: fields[1], Severity: SeverityUnknown}
+
+		_, flags, _ := strings.Cut(line, "(")
+		for _, flag := range strings.Split(strings.TrimSuffix(strings.TrimSpace(flags), ")"), ",") {
+			switch flag = strings.TrimSpace(flag); flag {
+			case "fixed":
+				v.Fixable = true
+			case "high urgency":
+				v.Severity = SeverityHigh
+			case "medium urgency":
+				v.Severity = SeverityMedium
+			case "low urgency":
+				v.Severity = SeverityLow
+			}
+		}
+		vulns = append(vulns, v)
+	}
+	return vulns
+}
+
+// dnfSeverities maps advisory severities
+var dnfSeverities = map[string]string{
+	"critical":  SeverityCritical,
+	"important": SeverityHigh,
+	"moderate":  SeverityMedium,
+	"low":       SeverityLow,
+}
+
+// parseDnf parses "dnf updateinfo list --security --with-cve", e.g.
+// "CVE-2024-0553 Moderate/Sec. gnutls-3.8.3-1.fc39.x86_64". Each line is
+// a CVE fixed by an available update.
+func parseDnf(out string) []Vulnerability {
+	vulns := []Vulnerability{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "CVE-") {
+			continue
+		}
+		v := Vulnerability{ID: fields[0], Fixable: true, Severity: SeverityUnknown}
+		for _, f := range fields[1:] {
+			level, _, _ := strings.Cut(f, "/")
+			if s, ok := dnfSeverities[strings.ToLower(level)]; ok {
+				v.Severity = s
+				continue
+			}
+			if name, version, ok := splitNEVRA(f); ok {
+				v.Package, v.FixedIn = name, version
+			}
+		}
+		if v.Package != "" {
+			vulns = append(vulns, v)
+		}
+	}
+	return vulns
+}
+
+// splitNEVRA splits "gnutls-3.8.3-1.fc39.x86_64" into "gnutls" and
+// "3.8.3-1.fc39"
+func splitNEVRA(s string) (string, string, bool) {
+	if dot := strings.LastIndexByte(s, '.'); dot > 0 {
+		s = s[:dot]
+	}
+	release := strings.LastIndexByte(s, '-')
+	if release <= 0 {
+		return "", "", false
+	}
+	version := strings.LastIndexByte(s[:release], '-')
+	if version <= 0 {
+		return "", "", false
+	}
+	return s[:version], s[version+1:], true
+}
@@ -0,0 +1,356 @@
+package vulns
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/apierror"
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+// firstScanDelay is how long after start a scan runs when none is due yet
+// or one is overdue, to keep it away from boot
+const firstScanDelay = 5 * time.Minute
+
+// Replaced in tests
+var (
+	runCommand = defaultRunCommand
+	lookPath   = exec.LookPath
+	osRelease  = "/etc/os-release"
+)
+
+// defaultRunCommand returns stdout alone, as scanners print JSON there
+func defaultRunCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
+
+var errUnavailable = apierror.Unavailable("no vulnerability scanner found (install trivy, debsecan or dnf)")
+
+// ImagesFunc returns the images of running containers, each with the
+// names of the containers using it
+type ImagesFunc func(ctx context.Context) (map[string][]string, error)
+
+// Scanner looks up known CVEs in the host's packages and in running
+// container images, on a schedule and on request
+type Scanner struct {
+	interval   time.Duration
+	reportFile string
+	bus        *events.Bus
+	images     ImagesFunc
+	onSchedule func()
+
+	mu     sync.Mutex
+	latest *Report
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewScanner creates a scanner. The latest report is kept in
+// vulnerabilities.json in dataDir.
+func NewScanner(interval time.Duration, dataDir string, bus *events.Bus) *Scanner {
+	s := &Scanner{interval: interval, bus: bus, stop: make(chan struct{})}
+	if dataDir != "" {
+		s.reportFile = filepath.Join(dataDir, "vulnerabilities.json")
+		s.load()
+	}
+	return s
+}
+
+// SetImages sets how running container images are found; without it only
+// the host is scanned
+func (s *Scanner) SetImages(fn ImagesFunc) {
+	s.images = fn
+}
+
+// OnSchedule sets the function called when a scan is due, which is
+// expected to run Scan
+func (s *Scanner) OnSchedule(fn func()) {
+	s.onSchedule = fn
+}
+
+// Start calls the OnSchedule function every interval, counted from the
+// last scan, until Stop is called
+func (s *Scanner) Start() {
+	if s.interval <= 0 || s.onSchedule == nil {
+		return
+	}
+
+	go func() {
+		for {
+			delay := firstScanDelay
+			if latest := s.Latest(); latest != nil {
+				if due := time.Until(latest.FinishedAt.Add(s.interval)); due > delay {
+					delay = due
+				}
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+				s.onSchedule()
+			case <-s.stop:
+				timer.Stop()
+				return
+			}
+
+			// Wait a full interval after scheduling, in case the scan is
+			// still running
+			select {
+			case <-time.After(s.interval):
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the schedule
+func (s *Scanner) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Latest returns the last completed report, or nil
+func (s *Scanner) Latest() *Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest
+}
+
+// Available returns the scanner used for the host, or "" when none is
+// installed
+func (s *Scanner) Available() string {
+	for _, name := range []string{ScannerTrivy, ScannerDebsecan, ScannerDnf} {
+		if _, err := lookPath(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// Scan checks the host and, with trivy, each running container image.
+// A target that fails is reported with its error rather than failing the
+// scan. New critical or high vulnerabilities raise an event.
+func (s *Scanner) Scan(ctx context.Context) (*Report, error) {
+	scanner := s.Available()
+	if scanner == "" {
+		return nil, errUnavailable
+	}
+
+	report := &Report{StartedAt: time.Now()}
+	report.Targets = append(report.Targets, s.scanHost(ctx, scanner))
+
+	if scanner == ScannerTrivy && s.images != nil {
+		images, err := s.images(ctx)
+		if err != nil {
+			log.Printf("Vulnerability scan: failed to list container images: %v", err)
+		}
+		refs := make([]string, 0, len(images))
+		for ref := range images {
+			refs = append(refs, ref)
+		}
+		sort.Strings(refs)
+		for _, ref := range refs {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			target := Target{Name: ref, Type: "image", Containers: images[ref], Scanner: ScannerTrivy}
+			out, err := runCommand(ctx, "trivy", "image", "--quiet", "--format", "json", "--scanners", "vuln", ref)
+			if err == nil {
+				target.Vulnerabilities, err = parseTrivy(out)
+			}
+			report.Targets = append(report.Targets, finish(target, err))
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	report.Counts = map[string]int{}
+	for _, t := range report.Targets {
+		for severity, n := range t.Counts {
+			report.Counts[severity] += n
+			report.Total += n
+		}
+	}
+	report.FinishedAt = time.Now()
+
+	s.mu.Lock()
+	previous := s.latest
+	s.latest = report
+	s.mu.Unlock()
+
+	s.save(report)
+	s.publish(previous, report)
+	return report, nil
+}
+
+// scanHost lists the vulnerabilities in the host's packages
+func (s *Scanner) scanHost(ctx context.Context, scanner string) Target {
+	target := Target{Name: "host", Type: "host", Scanner: scanner}
+
+	var out []byte
+	var err error
+	switch scanner {
+	case ScannerTrivy:
+		out, err = runCommand(ctx, "trivy", "rootfs", "--quiet", "--format", "json", "--scanners", "vuln",
+			"--pkg-types", "os", "/")
+		if err == nil {
+			target.Vulnerabilities, err = parseTrivy(out)
+		}
+	case ScannerDebsecan:
+		args := []string{}
+		if codename := releaseCodename(); codename != "" {
+			args = append(args, "--suite", codename)
+		}
+		if out, err = runCommand(ctx, "debsecan", args...); err == nil {
+			target.Vulnerabilities = parseDebsecan(string(out))
+		}
+	case ScannerDnf:
+		if out, err = runCommand(ctx, "dnf", "-q", "updateinfo", "list", "--security", "--with-cve"); err == nil {
+			target.Vulnerabilities = parseDnf(string(out))
+		}
+	}
+	return finish(target, err)
+}
+
+// finish sorts and counts a target's vulnerabilities, or records err
+func finish(target Target, err error) Target {
+	if err != nil {
+		target.Error = err.Error()
+	}
+	if target.Vulnerabilities == nil {
+		target.Vulnerabilities = []Vulnerability{}
+	}
+
+	vulns := target.Vulnerabilities
+	sort.SliceStable(vulns, func(i, j int) bool {
+		if vulns[i].Severity != vulns[j].Severity {
+			return severityRank[vulns[i].Severity] < severityRank[vulns[j].Severity]
+		}
+		if vulns[i].ID != vulns[j].ID {
+			return vulns[i].ID > vulns[j].ID // Newest first
+		}
+		return vulns[i].Package < vulns[j].Package
+	})
+
+	target.Counts = map[string]int{}
+	for _, v := range vulns {
+		target.Counts[v.Severity]++
+	}
+	return target
+}
+
+// releaseCodename returns VERSION_CODENAME from os-release
+func releaseCodename() string {
+	f, err := os.Open(osRelease)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "VERSION_CODENAME="); ok {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// publish raises a security.vulnerabilities event for critical and high
+// vulnerabilities that were not in the previous report
+func (s *Scanner) publish(previous, report *Report) {
+	if s.bus == nil {
+		return
+	}
+
+	known := map[string]bool{}
+	if previous != nil {
+		for _, t := range previous.Targets {
+			for _, v := range t.Vulnerabilities {
+				known[t.Name+" "+v.Package+" "+v.ID] = true
+			}
+		}
+	}
+
+	severity := events.SeverityWarning
+	var found []string
+	for _, t := range report.Targets {
+		for _, v := range t.Vulnerabilities {
+			if !AtLeast(v.Severity, SeverityHigh) || known[t.Name+" "+v.Package+" "+v.ID] {
+				continue
+			}
+			if v.Severity == SeverityCritical {
+				severity = events.SeverityCritical
+			}
+			found = append(found, fmt.Sprintf("%s in %s (%s)", v.ID, v.Package, t.Name))
+		}
+	}
+	if len(found) == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("%d new critical or high vulnerabilities: ", len(found))
+	if len(found) > 5 {
+		message += strings.Join(found[:5], ", ") + fmt.Sprintf(" and %d more", len(found)-5)
+	} else {
+		message += strings.Join(found, ", ")
+	}
+	s.bus.Publish(events.Event{
+		Type:     "security.vulnerabilities",
+		Severity: severity,
+		Source:   "vulnerabilities",
+		Message:  message,
+		Data:     map[string]interface{}{"total": report.Total, "counts": report.Counts, "new": found},
+	})
+}
+
+// load reads the saved report
+func (s *Scanner) load() {
+	data, err := os.ReadFile(s.reportFile)
+	if err != nil {
+		return
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		log.Printf("Ignoring saved vulnerability report: %v", err)
+		return
+	}
+	s.latest = &report
+}
+
+// save writes the report for the next start
+func (s *Scanner) save(report *Report) {
+	if s.reportFile == "" {
+		return
+	}
+	data, err := json.Marshal(report)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(s.reportFile), 0750); err == nil {
+			err = os.WriteFile(s.reportFile, data, 0600)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to save vulnerability report: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package vulns
+
+import "time"
+
+// Severities, from most to least severe
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityUnknown  = "unknown"
+)
+
+// Scanners
+const (
+	ScannerTrivy    = "trivy"
+	ScannerDebsecan = "debsecan"
+	ScannerDnf      = "dnf"
+)
+
+// Vulnerability is a known CVE affecting a package
+type Vulnerability struct {
+	ID        string `json:"id"`
+	Package   string `json:"package"`
+	Installed string `json:"installed,omitempty"`
+	FixedIn   string `json:"fixed_in,omitempty"`
+	// Fixable is set when an update fixes it, even if the version is unknown
+	Fixable  bool   `json:"fixable"`
+	Severity string `json:"severity"`
+	Title    string `json:"title,omitempty"`
+}
+
+// Target is the host or a container image and its vulnerabilities
+type Target struct {
+	Name            string          `json:"name"` // "host" or an image reference
+	Type            string          `json:"type"` // host or image
+	Containers      []string        `json:"containers,omitempty"`
+	Scanner         string          `json:"scanner"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	Counts          map[string]int  `json:"counts"` // By severity
+	Error           string          `json:"error,omitempty"`
+}
+
+// Report is the result of a scan
+type Report struct {
+	Targets    []Target       `json:"targets"`
+	Total      int            `json:"total"`
+	Counts     map[string]int `json:"counts"` // By severity
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+}
@@ -0,0 +1,174 @@
+package vulns
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ngenohkevin/hivedeck-agent/internal/events"
+)
+
+const trivyOutput = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "nginx:1.25",
+  "Results": [
+    {
+      "Target": "nginx:1.25 (debian 12.4)",
+      "Class": "os-pkgs",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2023-5678", "PkgName": "openssl", "InstalledVersion": "3.0.11-1", "FixedVersion": "3.0.13-1", "Severity": "MEDIUM", "Title": "openssl: slow DH checks"},
+        {"VulnerabilityID": "CVE-2024-2961", "PkgName": "libc6", "InstalledVersion": "2.36-9", "FixedVersion": "", "Severity": "CRITICAL"}
+      ]
+    },
+    {"Target": "usr/bin/app", "Class": "lang-pkgs"}
+  ]
+}`
+
+func TestParseTrivy(t *testing.T) {
+	vulns, err := parseTrivy([]byte(trivyOutput))
+	require.NoError(t, err)
+	require.Len(t, vulns, 2)
+	assert.Equal(t, Vulnerability{
+		ID: "CVE-2023-5678", Package: "openssl", Installed: "3.0.11-1", FixedIn: "3.0.13-1",
+		Fixable: true, Severity: SeverityMedium, Title: "openssl: slow DH checks",
+	}, vulns[0])
+	assert.Equal(t, SeverityCritical, vulns[1].Severity)
+	assert.False(t, vulns[1].Fixable)
+
+	_, err = parseTrivy([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestParseDebsecan(t *testing.T) {
+	vulns := parseDebsecan(`CVE-2023-4911 libc6 (fixed, remotely exploitable, high urgency)
+CVE-2014-9471 coreutils (low urgency)
+TEMP-0000000-A1B2C3 zlib1g
+CVE-2022-0001 linux-image-amd64
+`)
+	require.Len(t, vulns, 3)
+	assert.Equal(t, Vulnerability{ID: "CVE-2023-4911", Package: "libc6", Fixable: true, Severity: SeverityHigh}, vulns[0])
+	assert.Equal(t, SeverityLow, vulns[1].Severity)
+	assert.False(t, vulns[1].Fixable)
+	assert.Equal(t, SeverityUnknown, vulns[2].Severity)
+}
+
+func TestParseDnf(t *testing.T) {
+	vulns := parseDnf(`CVE-2024-0553 Moderate/Sec.  gnutls-3.8.3-1.fc39.x86_64
+CVE-2024-1086 Important/Sec. kernel-core-6.7.5-200.fc39.x86_64
+FEDORA-2024-1 bugfix foo-1-1.noarch
+`)
+	require.Len(t, vulns, 2)
+	assert.Equal(t, Vulnerability{ID: "CVE-2024-0553", Package: "gnutls", FixedIn: "3.8.3-1.fc39", Fixable: true, Severity: SeverityMedium}, vulns[0])
+	assert.Equal(t, "kernel-core", vulns[1].Package)
+	assert.Equal(t, SeverityHigh, vulns[1].Severity)
+}
+
+func TestAtLeast(t *testing.T) {
+	assert.True(t, AtLeast(SeverityCritical, SeverityHigh))
+	assert.True(t, AtLeast(SeverityHigh, SeverityHigh))
+	assert.False(t, AtLeast(SeverityMedium, SeverityHigh))
+	assert.True(t, AtLeast(SeverityUnknown, SeverityUnknown))
+	assert.False(t, ValidSeverity("severe"))
+}
+
+func stubCommands(t *testing.T, installed map[string]bool, run func(name string, args ...string) ([]byte, error)) {
+	t.Helper()
+	origRun, origLook := runCommand, lookPath
+	t.Cleanup(func() { runCommand, lookPath = origRun, origLook })
+
+	lookPath = func(name string) (string, error) {
+		if installed[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+	runCommand = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		return run(name, args...)
+	}
+}
+
+func TestScanTrivy(t *testing.T) {
+	var commands []string
+	stubCommands(t, map[string]bool{"trivy": true, "dnf": true}, func(name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		if args[len(args)-1] == "broken:latest" {
+			return nil, errors.New("trivy: image not found")
+		}
+		return []byte(trivyOutput), nil
+	})
+
+	dir := t.TempDir()
+	bus := events.NewBus(events.DefaultCapacity)
+	s := NewScanner(0, dir, bus)
+	s.SetImages(func(context.Context) (map[string][]string, error) {
+		return map[string][]string{"nginx:1.25": {"web"}, "broken:latest": {"job"}}, nil
+	})
+	assert.Equal(t, ScannerTrivy, s.Available())
+
+	report, err := s.Scan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Targets, 3)
+	assert.Equal(t, []string{
+		"trivy rootfs --quiet --format json --scanners vuln --pkg-types os /",
+		"trivy image --quiet --format json --scanners vuln broken:latest",
+		"trivy image --quiet --format json --scanners vuln nginx:1.25",
+	}, commands)
+
+	host := report.Targets[0]
+	assert.Equal(t, "host", host.Type)
+	assert.Equal(t, "CVE-2024-2961", host.Vulnerabilities[0].ID, "most severe first")
+	assert.Equal(t, "trivy: image not found", report.Targets[1].Error)
+	assert.Empty(t, report.Targets[1].Vulnerabilities)
+	assert.Equal(t, []string{"web"}, report.Targets[2].Containers)
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, map[string]int{SeverityCritical: 2, SeverityMedium: 2}, report.Counts)
+
+	list := bus.Recent(10, "security.")
+	require.Len(t, list.Events, 1)
+	assert.Equal(t, events.SeverityCritical, list.Events[0].Severity)
+	assert.Equal(t, "2 new critical or high vulnerabilities: CVE-2024-2961 in libc6 (host), CVE-2024-2961 in libc6 (nginx:1.25)", list.Events[0].Message)
+
+	// Known vulnerabilities are not raised again, and the report survives a restart
+	_, err = s.Scan(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, bus.Recent(10, "security.").Events, 1)
+
+	_, err = os.Stat(filepath.Join(dir, "vulnerabilities.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, NewScanner(0, dir, nil).Latest().Total)
+}
+
+func TestScanDebsecan(t *testing.T) {
+	orig := osRelease
+	defer func() { osRelease = orig }()
+	osRelease = filepath.Join(t.TempDir(), "os-release")
+	require.NoError(t, os.WriteFile(osRelease, []byte("ID=debian\nVERSION_CODENAME=\"bookworm\"\n"), 0644))
+
+	stubCommands(t, map[string]bool{"debsecan": true}, func(name string, args ...string) ([]byte, error) {
+		assert.Equal(t, []string{"--suite", "bookworm"}, args)
+		return []byte("CVE-2023-4911 libc6 (fixed, high urgency)\n"), nil
+	})
+
+	s := NewScanner(0, "", nil)
+	s.SetImages(func(context.Context) (map[string][]string, error) {
+		t.Fatal("images need trivy")
+		return nil, nil
+	})
+	report, err := s.Scan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Targets, 1)
+	assert.Equal(t, ScannerDebsecan, report.Targets[0].Scanner)
+	assert.Equal(t, 1, report.Counts[SeverityHigh])
+}
+
+func TestScanUnavailable(t *testing.T) {
+	stubCommands(t, nil, nil)
+	_, err := NewScanner(0, "", nil).Scan(context.Background())
+	assert.ErrorIs(t, err, errUnavailable)
+}
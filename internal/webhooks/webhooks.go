@@ -0,0 +1,129 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions a webhook can trigger
+const (
+	ActionDeploy = "deploy" // Target is a compose file
+	ActionTask   = "task"   // Target is a task name
+)
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+// timestamp, a dot and the body
+const SignatureHeader = "X-Hivedeck-Signature"
+
+// TimestampHeader carries the Unix time the request was signed at
+const TimestampHeader = "X-Hivedeck-Timestamp"
+
+// MaxAge is how far a request's timestamp may be from the agent's clock
+const MaxAge = 5 * time.Minute
+
+// MaxBody is the largest request body accepted
+const MaxBody = 1 << 20
+
+// Hook is an inbound webhook mapped to one action
+type Hook struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Target string `json:"target"`
+	secret []byte
+}
+
+// Parse builds hooks from name=action:target pairs and the secrets by
+// name. Every hook needs a secret.
+func Parse(hooks, secrets map[string]string) (map[string]*Hook, error) {
+	result := make(map[string]*Hook, len(hooks))
+	var errs []error
+	for name, spec := range hooks {
+		action, target, _ := strings.Cut(spec, ":")
+		if action != ActionDeploy && action != ActionTask {
+			errs = append(errs, fmt.Errorf("webhook %s: action must be deploy:<compose file> or task:<name>", name))
+			continue
+		}
+		if target == "" {
+			errs = append(errs, fmt.Errorf("webhook %s: %s needs a target", name, action))
+			continue
+		}
+		secret := secrets[name]
+		if secret == "" {
+			errs = append(errs, fmt.Errorf("webhook %s has no secret in WEBHOOK_SECRETS", name))
+			continue
+		}
+		result[name] = &Hook{Name: name, Action: action, Target: target, secret: []byte(secret)}
+	}
+	return result, errors.Join(errs...)
+}
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body sent at
+// timestamp
+func (h *Hook) Verify(timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(h.secret, timestamp, body)), []byte(signature))
+}
+
+// ReplayGuard rejects signed requests that are stale or were seen before.
+// A signature covers the timestamp, so a request cannot be made fresh
+// without the secret, and one within MaxAge is remembered until it expires.
+type ReplayGuard struct {
+	seen map[string]time.Time
+	mu   sync.Mutex
+}
+
+// NewReplayGuard creates an empty replay guard
+func NewReplayGuard() *ReplayGuard {
+	return &ReplayGuard{seen: make(map[string]time.Time)}
+}
+
+// Check accepts a verified request once, if timestamp is within MaxAge of now
+func (g *ReplayGuard) Check(timestamp, signature string, now time.Time) error {
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s must be a Unix time", TimestampHeader)
+	}
+	sent := time.Unix(sec, 0)
+	if now.Sub(sent) > MaxAge || sent.Sub(now) > MaxAge {
+		return fmt.Errorf("request timestamp is more than %s from the agent's clock", MaxAge)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for sig, expires := range g.seen {
+		if now.After(expires) {
+			delete(g.seen, sig)
+		}
+	}
+	if _, ok := g.seen[signature]; ok {
+		return errors.New("request was already delivered")
+	}
+	g.seen[signature] = sent.Add(MaxAge)
+	return nil
+}
+
+// List returns hooks sorted by name
+func List(hooks map[string]*Hook) []*Hook {
+	list := make([]*Hook, 0, len(hooks))
+	for _, h := range hooks {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,58 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	hooks, err := Parse(map[string]string{
+		"web":     "deploy:/opt/apps/web/compose.yaml",
+		"backup":  "task:backup-db",
+		"nosec":   "task:update",
+		"bad":     "reboot:now",
+		"missing": "deploy:",
+	}, map[string]string{"web": "s3cret", "backup": "other", "bad": "x", "missing": "x"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "webhook nosec has no secret")
+	assert.Contains(t, err.Error(), "webhook bad: action must be")
+	assert.Contains(t, err.Error(), "webhook missing: deploy needs a target")
+
+	require.Len(t, hooks, 2)
+	assert.Equal(t, &Hook{Name: "web", Action: ActionDeploy, Target: "/opt/apps/web/compose.yaml", secret: []byte("s3cret")}, hooks["web"])
+	list := List(hooks)
+	assert.Equal(t, "backup", list[0].Name)
+}
+
+func TestVerify(t *testing.T) {
+	hook := &Hook{Name: "web", secret: []byte("It's a Secret to Everybody")}
+	body := []byte("Hello, World!")
+
+	signature := Sign(hook.secret, "1760000000", body)
+	assert.True(t, hook.Verify("1760000000", body, signature))
+	assert.False(t, hook.Verify("1760000001", body, signature), "the timestamp is signed")
+	assert.False(t, hook.Verify("1760000000", []byte("Hello, World?"), signature))
+	assert.False(t, hook.Verify("1760000000", body, ""))
+}
+
+func TestReplayGuard(t *testing.T) {
+	g := NewReplayGuard()
+	now := time.Unix(1760000000, 0)
+
+	require.NoError(t, g.Check("1760000000", "sha256=aa", now))
+	assert.ErrorContains(t, g.Check("1760000000", "sha256=aa", now.Add(time.Minute)), "already delivered")
+	require.NoError(t, g.Check("1759999900", "sha256=bb", now), "clock skew within MaxAge")
+
+	assert.Error(t, g.Check("1759999000", "sha256=cc", now), "too old")
+	assert.Error(t, g.Check("1760001000", "sha256=dd", now), "too far ahead")
+	assert.Error(t, g.Check("yesterday", "sha256=ee", now))
+
+	// Signatures are forgotten once they would be too old anyway
+	require.NoError(t, g.Check("1760000400", "sha256=ff", now.Add(400*time.Second)))
+	assert.NotContains(t, g.seen, "sha256=aa")
+	assert.Contains(t, g.seen, "sha256=ff")
+}
@@ -1,29 +1,131 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/ngenohkevin/hivedeck-agent/config"
+	"github.com/ngenohkevin/hivedeck-agent/internal/crash"
+	"github.com/ngenohkevin/hivedeck-agent/internal/helper"
 	"github.com/ngenohkevin/hivedeck-agent/internal/server"
 )
 
+// Set at build time via -ldflags (see Makefile)
+var (
+	Version   = "dev"
+	BuildTime = ""
+)
+
 func main() {
+	// "hivedeck-agent helper ..." runs one privileged command for an
+	// unprivileged agent (via sudo) and exits. It reads no configuration.
+	if len(os.Args) > 1 && os.Args[1] == helper.Command {
+		os.Exit(helper.Main(os.Args[2:]))
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
+		// The crash reporter normally comes from the config, so read its
+		// settings from the environment instead
+		if reporter, _ := crash.NewReporter(crash.OptionsFromEnv(Version)); reporter != nil {
+			reporter.Fatal(fmt.Errorf("failed to load config: %w", err), nil)
+		}
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	cfg.Version = Version
+	cfg.BuildTime = BuildTime
+
+	// "hivedeck-agent encrypt-secrets" encrypts the secrets in .env and exits
+	if len(os.Args) > 1 && os.Args[1] == "encrypt-secrets" {
+		keys, err := cfg.EncryptEnvFile()
+		if err != nil {
+			log.Fatalf("Failed to encrypt secrets: %v", err)
+		}
+		log.Printf("Encrypted %d secret(s) in %s: %s", len(keys), cfg.EnvFile, strings.Join(keys, ", "))
+		return
+	}
+
+	// "hivedeck-agent sudoers [user]" prints sudoers rules for the helper
+	if len(os.Args) > 1 && os.Args[1] == "sudoers" {
+		user := "hivedeck"
+		if len(os.Args) > 2 {
+			user = os.Args[2]
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			log.Fatalf("Failed to find the agent executable: %v", err)
+		}
+		fmt.Print(helper.Sudoers(user, exe, cfg.AllowedServices, true, true, cfg.CPUGovernorControl))
+		return
+	}
+
+	// "hivedeck-agent --bootstrap" configures the API key and exits, for
+	// provisioning without the setup page
+	if len(os.Args) > 1 && (os.Args[1] == "--bootstrap" || os.Args[1] == "bootstrap") {
+		os.Exit(bootstrap(cfg, os.Args[2:]))
+	}
 
 	// Check if in setup mode
 	if cfg.SetupMode {
 		log.Printf("⚠️  No API key configured - starting in SETUP MODE")
 		log.Printf("📋 Open http://%s/setup to configure the agent", cfg.Addr())
-		log.Printf("🔒 After setup, restart the agent to enable authentication")
+		log.Printf("🔒 Authentication is enabled as soon as the key is saved")
 	}
 
 	// Create and run server
 	srv := server.New(cfg)
 	if err := srv.Run(); err != nil {
+		srv.ReportFatal(err)
 		log.Fatalf("Server error: %v", err)
 	}
 }
+
+// bootstrap saves an API key to .env: the one read from -key-file ("-" for
+// stdin) or a generated one. The key is printed to stdout when it was
+// generated, so scripts can capture it. An existing key is kept unless
+// -force is given.
+func bootstrap(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	keyFile := flags.String("key-file", "", `read the key from this file, or "-" for stdin, instead of generating one`)
+	force := flags.Bool("force", false, "replace an API key that is already configured")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var key string
+	if *keyFile != "" {
+		var data []byte
+		var err error
+		if *keyFile == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(*keyFile)
+		}
+		if err != nil {
+			log.Printf("Failed to read API key: %v", err)
+			return 1
+		}
+		key = strings.TrimSpace(string(data))
+	}
+
+	key, saved, err := cfg.Bootstrap(key, *force)
+	if err != nil {
+		log.Printf("Bootstrap failed: %v", err)
+		return 1
+	}
+	if !saved {
+		log.Printf("An API key is already configured (from %s); use --force to replace it", cfg.APIKeySource)
+		return 0
+	}
+
+	log.Printf("API key saved to %s", cfg.EnvFile)
+	if *keyFile == "" {
+		fmt.Println(key)
+	}
+	return 0
+}